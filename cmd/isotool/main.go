@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+const usage = `Usage: isotool <command> [options]
+
+Commands:
+  inspect <iso>                      print the metadata of the ISO as JSON
+  extract [-tar] <iso> <dir> <dest>  extract a directory of the ISO to dest, or as a tar archive
+                                     to the file dest, "-" for the standard output
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "inspect":
+		err = inspect(os.Args[2:])
+	case "extract":
+		err = extract(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "isotool %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func inspect(args []string) error {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected an ISO path")
+	}
+	data, err := isoeditor.InspectJSON(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Println(string(data))
+	return err
+}
+
+func extract(args []string) error {
+	flags := flag.NewFlagSet("extract", flag.ExitOnError)
+	asTar := flags.Bool("tar", false, "write a tar archive instead of extracting the files")
+	_ = flags.Parse(args)
+	if flags.NArg() != 3 {
+		return fmt.Errorf("expected an ISO path, a directory of the ISO and a destination")
+	}
+	isoPath, dirPath, dest := flags.Arg(0), flags.Arg(1), flags.Arg(2)
+	if !*asTar {
+		return isoeditor.ExtractISODirectory(isoPath, dirPath, dest)
+	}
+
+	var out io.WriteCloser = os.Stdout
+	if dest != "-" {
+		f, err := os.Create(dest)
+		if err != nil {
+			return err
+		}
+		out = f
+	}
+	if err := isoeditor.ExtractISODirectoryTar(isoPath, dirPath, out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
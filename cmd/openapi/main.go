@@ -0,0 +1,28 @@
+// Command openapi writes the OpenAPI document of the HTTP API of the image service to the file of
+// its argument, or to the standard output
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/openshift/assisted-image-service/internal/handlers"
+)
+
+func main() {
+	spec, err := handlers.OpenAPISpec()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "openapi: %v\n", err)
+		os.Exit(1)
+	}
+	spec = append(spec, '\n')
+	if len(os.Args) > 1 {
+		err = os.WriteFile(os.Args[1], spec, 0o644)
+	} else {
+		_, err = os.Stdout.Write(spec)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "openapi: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -193,7 +193,13 @@ var _ = Describe("Image integration tests", func() {
 				Expect(err).NotTo(HaveOccurred())
 
 				mdw := middleware.New(middleware.Config{})
-				imageServer = httptest.NewServer(handlers.NewImageHandler(imageStore, asc, 1, mdw))
+				imageServer = httptest.NewServer(handlers.NewImageHandler(handlers.ImageHandlerOptions{
+					ImageStore:            imageStore,
+					AssistedServiceClient: asc,
+					MaxRequests:           1,
+					Metrics:               mdw,
+					ISOMD5:                isoeditor.ISOMD5Keep,
+				}))
 				imageClient = imageServer.Client()
 			})
 
@@ -281,7 +287,12 @@ var _ = BeforeSuite(func() {
 	imageDir, err = os.MkdirTemp("", "imagesTest")
 	Expect(err).To(BeNil())
 
-	imageStore, err = imagestore.NewImageStore(isoeditor.NewEditor(imageDir, isoeditor.NewNmstateHandler(imageDir, &isoeditor.CommonExecuter{})), imageDir, imageServiceBaseURL, false, versions, "", map[string]string{}, map[string]string{})
+	imageStore, err = imagestore.NewImageStore(imagestore.ImageStoreOptions{
+		Editor:              isoeditor.NewEditor(imageDir, isoeditor.NewNmstateHandler(imageDir, &isoeditor.CommonExecuter{})),
+		DataDir:             imageDir,
+		ImageServiceBaseURL: imageServiceBaseURL,
+		Versions:            versions,
+	})
 	Expect(err).NotTo(HaveOccurred())
 
 	err = imageStore.Populate(context.Background())
@@ -0,0 +1,16 @@
+package grpcserver
+
+import (
+	"io"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestGRPCServer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	log.SetOutput(io.Discard)
+	RunSpecs(t, "grpcserver")
+}
@@ -0,0 +1,171 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	imageservicev1 "github.com/openshift/assisted-image-service/pkg/api/imageservice/v1"
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+)
+
+const defaultArch = "x86_64"
+
+// Server implements the gRPC API of the image service. The images are served by the HTTP
+// handlers, so that both APIs customize them the same way.
+type Server struct {
+	imageservicev1.UnimplementedImageServiceServer
+	imageStore    imagestore.ImageStore
+	images        http.Handler
+	bootArtifacts http.Handler
+	// token authenticates the calls managing the store, which are refused when it's empty
+	token string
+}
+
+var _ imageservicev1.ImageServiceServer = &Server{}
+
+// NewServer returns the gRPC server of the image service, serving the images with the handlers
+// of the /images and /boot-artifacts paths
+func NewServer(is imagestore.ImageStore, images, bootArtifacts http.Handler, token string) *Server {
+	return &Server{
+		imageStore:    is,
+		images:        images,
+		bootArtifacts: bootArtifacts,
+		token:         token,
+	}
+}
+
+func (s *Server) GetImage(req *imageservicev1.GetImageRequest, stream imageservicev1.ImageService_GetImageServer) error {
+	query := url.Values{"version": {req.Version}, "type": {req.Type}}
+	if req.Arch != "" {
+		query.Set("arch", req.Arch)
+	}
+	setCredentials(query, req.Credentials)
+	return serve(stream, s.images, "/images/"+url.PathEscape(req.ImageId), query, req.Offset)
+}
+
+func (s *Server) GetInitrd(req *imageservicev1.GetInitrdRequest, stream imageservicev1.ImageService_GetInitrdServer) error {
+	query := url.Values{"version": {req.Version}}
+	if req.Arch != "" {
+		query.Set("arch", req.Arch)
+	}
+	setCredentials(query, req.Credentials)
+	return serve(stream, s.images, "/images/"+url.PathEscape(req.ImageId)+"/pxe-initrd", query, req.Offset)
+}
+
+func (s *Server) GetBootArtifact(req *imageservicev1.GetBootArtifactRequest, stream imageservicev1.ImageService_GetBootArtifactServer) error {
+	query := url.Values{"version": {req.Version}}
+	if req.Arch != "" {
+		query.Set("arch", req.Arch)
+	}
+	return serve(stream, s.bootArtifacts, "/boot-artifacts/"+url.PathEscape(req.Artifact), query, req.Offset)
+}
+
+func (s *Server) GetVersion(ctx context.Context, req *imageservicev1.GetVersionRequest) (*imageservicev1.GetVersionResponse, error) {
+	if req.Version == "" {
+		return nil, status.Error(codes.InvalidArgument, "version is required")
+	}
+	arch := req.Arch
+	if arch == "" {
+		arch = defaultArch
+	}
+	configured := s.imageStore.HaveVersion(req.Version, arch)
+	return &imageservicev1.GetVersionResponse{
+		Configured: configured,
+		Available:  configured && s.imageStore.Available(req.Version, arch),
+	}, nil
+}
+
+func (s *Server) AddBaseISO(ctx context.Context, req *imageservicev1.AddBaseISORequest) (*imageservicev1.AddBaseISOResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if req.OpenshiftVersion == "" || req.Arch == "" || req.Url == "" {
+		return nil, status.Error(codes.InvalidArgument, "openshift_version, arch and url are required")
+	}
+
+	version, err := s.imageStore.AddBaseISO(ctx, req.OpenshiftVersion, req.Arch, req.Url, nil)
+	switch {
+	case errors.Is(err, imagestore.ErrInvalidBaseISO):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, imagestore.ErrVersionExists):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case err != nil:
+		log.WithError(err).Errorf("Failed to add base ISO %s-%s", req.OpenshiftVersion, req.Arch)
+		return nil, status.Error(codes.Internal, "failed to add the base ISO")
+	}
+	return &imageservicev1.AddBaseISOResponse{
+		OpenshiftVersion: req.OpenshiftVersion,
+		Arch:             req.Arch,
+		Version:          version,
+	}, nil
+}
+
+func (s *Server) Prune(ctx context.Context, req *imageservicev1.PruneRequest) (*imageservicev1.PruneResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if req.TtlSeconds <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "ttl_seconds must be positive")
+	}
+	if err := s.imageStore.Prune(time.Duration(req.TtlSeconds)*time.Second, req.DryRun); err != nil {
+		log.WithError(err).Error("Failed to prune the base images")
+		return nil, status.Error(codes.Internal, "failed to prune the base images")
+	}
+	return &imageservicev1.PruneResponse{}, nil
+}
+
+// authorize checks the bearer token of the calls managing the store
+func (s *Server) authorize(ctx context.Context) error {
+	if s.token == "" {
+		return status.Error(codes.PermissionDenied, "store management is disabled")
+	}
+	var token string
+	if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+		token = strings.TrimPrefix(values[0], "Bearer ")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		return status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return nil
+}
+
+func setCredentials(query url.Values, credentials *imageservicev1.Credentials) {
+	if credentials.GetApiKey() != "" {
+		query.Set("api_key", credentials.GetApiKey())
+	}
+	if credentials.GetImageToken() != "" {
+		query.Set("image_token", credentials.GetImageToken())
+	}
+}
+
+// serve streams the response of the handler to the GET request of the path
+func serve(stream chunkStream, handler http.Handler, path string, query url.Values, offset int64) error {
+	u := &url.URL{Path: path, RawQuery: query.Encode()}
+	r, err := http.NewRequestWithContext(stream.Context(), http.MethodGet, u.String(), nil)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if values := metadata.ValueFromIncomingContext(stream.Context(), "authorization"); len(values) > 0 {
+		r.Header.Set("Authorization", values[0])
+	}
+	if offset < 0 {
+		return status.Error(codes.InvalidArgument, "offset must not be negative")
+	} else if offset > 0 {
+		r.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	w := newStreamWriter(stream)
+	handler.ServeHTTP(w, r)
+	return w.status()
+}
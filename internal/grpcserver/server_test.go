@@ -0,0 +1,193 @@
+package grpcserver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	imageservicev1 "github.com/openshift/assisted-image-service/pkg/api/imageservice/v1"
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		ctrl           *gomock.Controller
+		mockImageStore *imagestore.MockImageStore
+		grpcServer     *grpc.Server
+		conn           *grpc.ClientConn
+		client         imageservicev1.ImageServiceClient
+		lastRequest    *http.Request
+		content        = bytes.Repeat([]byte("image"), maxChunkSize)
+		token          = "secret"
+	)
+
+	images := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRequest = r
+		if r.URL.Query().Get("version") == "4.7" {
+			http.Error(w, "version for 4.7 x86_64, not found", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		http.ServeContent(w, r, "image.iso", time.Time{}, bytes.NewReader(content))
+	})
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockImageStore = imagestore.NewMockImageStore(ctrl)
+		lastRequest = nil
+
+		listener := bufconn.Listen(1 << 20)
+		grpcServer = grpc.NewServer()
+		imageservicev1.RegisterImageServiceServer(grpcServer, NewServer(mockImageStore, images, images, token))
+		go func() {
+			defer GinkgoRecover()
+			Expect(grpcServer.Serve(listener)).To(Succeed())
+		}()
+
+		var err error
+		conn, err = grpc.Dial("bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		client = imageservicev1.NewImageServiceClient(conn)
+	})
+
+	AfterEach(func() {
+		conn.Close()
+		grpcServer.Stop()
+	})
+
+	receive := func(stream interface {
+		Recv() (*imageservicev1.Chunk, error)
+	}) ([]byte, int) {
+		var data []byte
+		chunks := 0
+		for {
+			chunk, err := stream.Recv()
+			if err == io.EOF {
+				return data, chunks
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(chunk.Data)).To(BeNumerically("<=", maxChunkSize))
+			data = append(data, chunk.Data...)
+			chunks++
+		}
+	}
+
+	It("streams the image served by the handler", func() {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer sso")
+		stream, err := client.GetImage(ctx, &imageservicev1.GetImageRequest{
+			ImageId:     "bf25292a-dddd-49dc-ab9c-3fb4c1f07071",
+			Version:     "4.11",
+			Type:        "minimal",
+			Credentials: &imageservicev1.Credentials{ApiKey: "key"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		data, chunks := receive(stream)
+		Expect(data).To(Equal(content))
+		Expect(chunks).To(BeNumerically(">", 1))
+
+		header, err := stream.Header()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(header.Get("etag")).To(Equal([]string{`"etag"`}))
+		Expect(header.Get("content-length")).To(Equal([]string{"5242880"}))
+
+		Expect(lastRequest.URL.Path).To(Equal("/images/bf25292a-dddd-49dc-ab9c-3fb4c1f07071"))
+		Expect(lastRequest.URL.Query().Get("type")).To(Equal("minimal"))
+		Expect(lastRequest.URL.Query().Get("api_key")).To(Equal("key"))
+		Expect(lastRequest.URL.Query().Has("arch")).To(BeFalse())
+		Expect(lastRequest.Header.Get("Authorization")).To(Equal("Bearer sso"))
+	})
+
+	It("resumes the streams from the offset", func() {
+		stream, err := client.GetInitrd(context.Background(), &imageservicev1.GetInitrdRequest{
+			ImageId: "bf25292a-dddd-49dc-ab9c-3fb4c1f07071",
+			Version: "4.11",
+			Arch:    "arm64",
+			Offset:  int64(len(content) - 10),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		data, _ := receive(stream)
+		Expect(data).To(Equal(content[len(content)-10:]))
+		Expect(lastRequest.URL.Path).To(Equal("/images/bf25292a-dddd-49dc-ab9c-3fb4c1f07071/pxe-initrd"))
+		Expect(lastRequest.URL.Query().Get("arch")).To(Equal("arm64"))
+	})
+
+	It("returns the errors of the handler as the status of the call", func() {
+		stream, err := client.GetBootArtifact(context.Background(), &imageservicev1.GetBootArtifactRequest{
+			Artifact: "rootfs",
+			Version:  "4.7",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = stream.Recv()
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(status.Convert(err).Message()).To(Equal("version for 4.7 x86_64, not found"))
+		Expect(lastRequest.URL.Path).To(Equal("/boot-artifacts/rootfs"))
+	})
+
+	It("tells whether a version is available", func() {
+		mockImageStore.EXPECT().HaveVersion("4.11", "x86_64").Return(true)
+		mockImageStore.EXPECT().Available("4.11", "x86_64").Return(false)
+		resp, err := client.GetVersion(context.Background(), &imageservicev1.GetVersionRequest{Version: "4.11"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Configured).To(BeTrue())
+		Expect(resp.Available).To(BeFalse())
+	})
+
+	Context("managing the store", func() {
+		var authenticated context.Context
+
+		BeforeEach(func() {
+			authenticated = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+		})
+
+		It("adds a base ISO", func() {
+			mockImageStore.EXPECT().AddBaseISO(gomock.Any(), "4.14", "x86_64", "https://example.com/custom.iso", nil).Return("custom-0123456789ab", nil)
+			resp, err := client.AddBaseISO(authenticated, &imageservicev1.AddBaseISORequest{
+				OpenshiftVersion: "4.14",
+				Arch:             "x86_64",
+				Url:              "https://example.com/custom.iso",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Version).To(Equal("custom-0123456789ab"))
+		})
+
+		It("fails to add a base ISO with an existing version", func() {
+			mockImageStore.EXPECT().AddBaseISO(gomock.Any(), "4.14", "x86_64", "https://example.com/custom.iso", nil).Return("", imagestore.ErrVersionExists)
+			_, err := client.AddBaseISO(authenticated, &imageservicev1.AddBaseISORequest{
+				OpenshiftVersion: "4.14",
+				Arch:             "x86_64",
+				Url:              "https://example.com/custom.iso",
+			})
+			Expect(status.Code(err)).To(Equal(codes.AlreadyExists))
+		})
+
+		It("prunes the base images", func() {
+			mockImageStore.EXPECT().Prune(time.Hour, true).Return(nil)
+			_, err := client.Prune(authenticated, &imageservicev1.PruneRequest{TtlSeconds: 3600, DryRun: true})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("requires the token", func() {
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+			_, err := client.Prune(ctx, &imageservicev1.PruneRequest{TtlSeconds: 3600})
+			Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+			_, err = client.Prune(context.Background(), &imageservicev1.PruneRequest{TtlSeconds: 3600})
+			Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+		})
+	})
+})
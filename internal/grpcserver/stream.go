@@ -0,0 +1,130 @@
+package grpcserver
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	imageservicev1 "github.com/openshift/assisted-image-service/pkg/api/imageservice/v1"
+)
+
+// maxChunkSize bounds the data of the chunks, well below the default maximum message size
+const maxChunkSize = 1 << 20
+
+// maxErrorSize bounds the body of the error responses kept as the message of the status
+const maxErrorSize = 4096
+
+// streamedHeaders are the headers of the responses sent as the header metadata of the streams
+var streamedHeaders = []string{"Content-Length", "ETag", "Digest", "Last-Modified"}
+
+// chunkStream is the server side of the image streams
+type chunkStream interface {
+	Send(*imageservicev1.Chunk) error
+	grpc.ServerStream
+}
+
+// streamWriter is an http.ResponseWriter sending the body of successful responses as chunks of
+// the stream, and keeping the body of the other responses for the status of the call
+type streamWriter struct {
+	stream chunkStream
+	header http.Header
+	code   int
+	body   bytes.Buffer
+	err    error
+}
+
+var _ http.ResponseWriter = &streamWriter{}
+
+func newStreamWriter(stream chunkStream) *streamWriter {
+	return &streamWriter{stream: stream, header: http.Header{}}
+}
+
+func (w *streamWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *streamWriter) success() bool {
+	return w.code == http.StatusOK || w.code == http.StatusPartialContent
+}
+
+func (w *streamWriter) WriteHeader(code int) {
+	if w.code != 0 {
+		return
+	}
+	w.code = code
+	if !w.success() {
+		return
+	}
+	md := metadata.MD{}
+	for _, key := range streamedHeaders {
+		if value := w.header.Get(key); value != "" {
+			md.Set(key, value)
+		}
+	}
+	w.err = w.stream.SendHeader(md)
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.err != nil {
+		return 0, w.err
+	}
+	if !w.success() {
+		if remaining := maxErrorSize - w.body.Len(); remaining > 0 {
+			w.body.Write(p[:min(len(p), remaining)])
+		}
+		return len(p), nil
+	}
+	for written := 0; written < len(p); {
+		n := min(len(p)-written, maxChunkSize)
+		if w.err = w.stream.Send(&imageservicev1.Chunk{Data: p[written : written+n]}); w.err != nil {
+			return written, w.err
+		}
+		written += n
+	}
+	return len(p), nil
+}
+
+// status returns the status of the call for the response
+func (w *streamWriter) status() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.code == 0 || w.success() {
+		return nil
+	}
+	message := strings.TrimSpace(w.body.String())
+	if message == "" {
+		message = http.StatusText(w.code)
+	}
+	return status.Error(httpStatusCode(w.code), message)
+}
+
+// httpStatusCode returns the status code of the calls for the HTTP status of the responses
+func httpStatusCode(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusRequestedRangeNotSatisfiable:
+		return codes.OutOfRange
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	// the base image is still downloading
+	case http.StatusAccepted, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
@@ -5,11 +5,9 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
-	"strings"
 
 	"github.com/go-chi/chi/v5"
 
@@ -84,7 +82,7 @@ func (c *AssistedServiceClient) ramdiskContent(imageServiceRequest *http.Request
 		return nil, 0, nil
 	}
 
-	ramdiskBytes, err = io.ReadAll(resp.Body)
+	ramdiskBytes, err = readStreamContent(imageServiceRequest, resp.Body)
 	if err != nil {
 		return nil, http.StatusInternalServerError, fmt.Errorf("failed to read response body: %v", err)
 	}
@@ -122,7 +120,7 @@ func (c *AssistedServiceClient) ignitionContent(imageServiceRequest *http.Reques
 	if resp.StatusCode != http.StatusOK {
 		return nil, "", resp.StatusCode, fmt.Errorf("ignition request to %s returned status %d", req.URL.String(), resp.StatusCode)
 	}
-	ignitionBytes, err := io.ReadAll(resp.Body)
+	ignitionBytes, err := readStreamContent(imageServiceRequest, resp.Body)
 	if err != nil {
 		return nil, "", http.StatusInternalServerError, fmt.Errorf("failed to read response body: %v", err)
 	}
@@ -132,10 +130,37 @@ func (c *AssistedServiceClient) ignitionContent(imageServiceRequest *http.Reques
 
 const infraEnvPathFormat = "/api/assisted-install/v2/infra-envs/%s"
 
-// discoveryKernelArguments returns the kernel arguments data on success (if exists) and the error and the corresponding http status code
+// authorizeInfraEnv checks that the credentials of the image service request give access to the
+// infra-env, for the images that aren't built from its content. The returned code should only be
+// used if an error is also returned.
+func (c *AssistedServiceClient) authorizeInfraEnv(imageServiceRequest *http.Request, infraEnvID string) (int, error) {
+	u := url.URL{
+		Scheme: c.assistedServiceScheme,
+		Host:   c.assistedServiceHost,
+		Path:   fmt.Sprintf(infraEnvPathFormat, infraEnvID),
+	}
+
+	req, err := http.NewRequestWithContext(imageServiceRequest.Context(), "GET", u.String(), nil)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	setRequestAuth(imageServiceRequest, req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("infra-env request to %s returned status %d", req.URL.String(), resp.StatusCode)
+	}
+	return 0, nil
+}
+
+// discoveryKernelArguments returns the kernel arguments operations on success (if exists) and the error and the corresponding http status code
 // The code is also returned to ensure issues with authentication from the assisted service request are communicated back to the image service user
 // The returned code should only be used if an error is also returned
-func (c *AssistedServiceClient) discoveryKernelArguments(imageServiceRequest *http.Request, infraEnvID string) ([]byte, int, error) {
+func (c *AssistedServiceClient) discoveryKernelArguments(imageServiceRequest *http.Request, infraEnvID string) (isoeditor.KernelArguments, int, error) {
 
 	u := url.URL{
 		Scheme: c.assistedServiceScheme,
@@ -165,11 +190,11 @@ func (c *AssistedServiceClient) discoveryKernelArguments(imageServiceRequest *ht
 		return nil, http.StatusInternalServerError, fmt.Errorf("failed to decode infra-env input: %v", err)
 	}
 	if infraEnv.KernelArguments != nil {
-		kargs, err := isoeditor.StrToKargs(*infraEnv.KernelArguments)
+		kargs, err := isoeditor.ParseKernelArguments(*infraEnv.KernelArguments)
 		if err != nil {
 			return nil, http.StatusInternalServerError, err
 		}
-		return []byte(" " + strings.Join(kargs, " ") + "\n"), 0, nil
+		return kargs, 0, nil
 	}
 	return nil, 0, nil
 }
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// throttleChunkSize is the most written to a throttled response at once
+const throttleChunkSize = 32 * 1024
+
+// bandwidth is a token bucket of bytes, refilled at its rate up to a second worth of bytes
+type bandwidth struct {
+	rate    float64
+	lock    sync.Mutex
+	tokens  float64
+	updated time.Time
+	now     func() time.Time
+}
+
+func newBandwidth(bytesPerSecond int64) *bandwidth {
+	b := &bandwidth{
+		rate: float64(bytesPerSecond),
+		now:  time.Now,
+	}
+	b.tokens = b.rate
+	b.updated = b.now()
+	return b
+}
+
+// reserve takes n bytes from the bucket, and returns how long to wait before sending them
+func (b *bandwidth) reserve(n int) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.now()
+	b.tokens = math.Min(b.rate, b.tokens+now.Sub(b.updated).Seconds()*b.rate)
+	b.updated = now
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// throttledWriter writes the response no faster than its stream and the global bandwidths
+type throttledWriter struct {
+	http.ResponseWriter
+	ctx    context.Context
+	stream *bandwidth
+	global *bandwidth
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > throttleChunkSize {
+			chunk = chunk[:throttleChunkSize]
+		}
+		wait := w.stream.reserve(len(chunk))
+		if global := w.global.reserve(len(chunk)); global > wait {
+			wait = global
+		}
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-w.ctx.Done():
+				timer.Stop()
+				return written, w.ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		n, err := w.ResponseWriter.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// WithBandwidthLimit returns middleware that will limit the rate at which the responses are
+// written to bytesPerSecondPerStream for each of them, and to bytesPerSecond for all of them.
+// Zero means no limit.
+func WithBandwidthLimit(bytesPerSecond, bytesPerSecondPerStream int64) func(http.Handler) http.Handler {
+	var global *bandwidth
+	if bytesPerSecond > 0 {
+		global = newBandwidth(bytesPerSecond)
+	}
+
+	return func(next http.Handler) http.Handler {
+		if bytesPerSecond <= 0 && bytesPerSecondPerStream <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tw := &throttledWriter{ResponseWriter: w, ctx: r.Context(), global: global}
+			if bytesPerSecondPerStream > 0 {
+				tw.stream = newBandwidth(bytesPerSecondPerStream)
+			}
+			next.ServeHTTP(tw, r)
+		})
+	}
+}
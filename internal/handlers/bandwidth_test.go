@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithBandwidthLimit", func() {
+	content := bytes.Repeat([]byte("a"), 3*throttleChunkSize)
+	contentHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	})
+
+	It("reserves the bytes of the bucket", func() {
+		now := time.Now()
+		b := newBandwidth(1000)
+		b.now = func() time.Time { return now }
+		b.updated = now
+
+		Expect(b.reserve(1000)).To(BeZero())
+		Expect(b.reserve(500)).To(Equal(500 * time.Millisecond))
+		now = now.Add(time.Second)
+		Expect(b.reserve(750)).To(Equal(250 * time.Millisecond))
+		now = now.Add(10 * time.Second)
+		Expect(b.reserve(1000)).To(BeZero())
+		Expect(b.reserve(1)).To(Equal(time.Millisecond))
+	})
+
+	It("throttles each stream", func() {
+		handler := WithBandwidthLimit(0, 2*throttleChunkSize)(contentHandler)
+
+		start := time.Now()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		Expect(w.Body.Bytes()).To(Equal(content))
+		Expect(time.Since(start)).To(BeNumerically(">=", 400*time.Millisecond))
+	})
+
+	It("throttles all the streams together", func() {
+		handler := WithBandwidthLimit(4*throttleChunkSize, 0)(contentHandler)
+
+		start := time.Now()
+		done := make(chan struct{})
+		for i := 0; i < 2; i++ {
+			go func() {
+				defer GinkgoRecover()
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+				Expect(w.Body.Bytes()).To(Equal(content))
+				done <- struct{}{}
+			}()
+		}
+		Eventually(done).Should(Receive())
+		Eventually(done).Should(Receive())
+		Expect(time.Since(start)).To(BeNumerically(">=", 400*time.Millisecond))
+	})
+
+	It("stops writing when the request is cancelled", func() {
+		handler := WithBandwidthLimit(0, throttleChunkSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := io.Copy(w, bytes.NewReader(content))
+			Expect(err).To(MatchError(context.Canceled))
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+		Expect(w.Body.Len()).To(Equal(throttleChunkSize))
+	})
+
+	It("doesn't wrap the handler without limits", func() {
+		w := httptest.NewRecorder()
+		WithBandwidthLimit(0, 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, throttled := w.(*throttledWriter)
+			Expect(throttled).To(BeFalse())
+		})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+})
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+	"github.com/openshift/assisted-image-service/pkg/servers"
+	log "github.com/sirupsen/logrus"
+)
+
+// BaseISOHandler registers custom base ISOs, uploaded in the body of the requests or downloaded
+// from the url of a JSON body, under the version of the path. The requests carry the token, so
+// they are only accepted over TLS or on the local listeners.
+type BaseISOHandler struct {
+	ImageStore imagestore.ImageStore
+	// Token authenticates the requests as a bearer token
+	Token string
+}
+
+var _ http.Handler = &BaseISOHandler{}
+
+var baseISOPathRegexp = regexp.MustCompile(`^/base-isos/([^/]+)/([^/]+)$`)
+
+// baseISORequest is the JSON body of the requests adding an ISO from a URL
+type baseISORequest struct {
+	URL string `json:"url"`
+}
+
+// baseISOResponse describes the added version
+type baseISOResponse struct {
+	OpenshiftVersion string `json:"openshift_version"`
+	CPUArchitecture  string `json:"cpu_architecture"`
+	Version          string `json:"version"`
+}
+
+func (h *BaseISOHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.Header().Set("Allow", http.MethodPut)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if r.TLS == nil && !servers.IsLocal(r.Context()) {
+		httpErrorf(w, http.StatusForbidden, "base ISOs can only be added over https")
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if h.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	match := baseISOPathRegexp.FindStringSubmatch(r.URL.Path)
+	if match == nil {
+		httpErrorf(w, http.StatusNotFound, "malformed base ISO path: %s", r.URL.Path)
+		return
+	}
+	openshiftVersion, arch := match[1], match[2]
+
+	var isoURL string
+	var iso io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		req := &baseISORequest{}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(req); err != nil || req.URL == "" {
+			httpErrorf(w, http.StatusBadRequest, "the JSON body must have the url of the ISO")
+			return
+		}
+		isoURL, iso = req.URL, nil
+	}
+
+	version, err := h.ImageStore.AddBaseISO(r.Context(), openshiftVersion, arch, isoURL, iso)
+	switch {
+	case errors.Is(err, imagestore.ErrInvalidBaseISO):
+		httpErrorf(w, http.StatusBadRequest, "%v", err)
+		return
+	case errors.Is(err, imagestore.ErrVersionExists):
+		httpErrorf(w, http.StatusConflict, "%v", err)
+		return
+	case err != nil:
+		log.WithError(err).Errorf("Failed to add base ISO %s-%s", openshiftVersion, arch)
+		httpErrorf(w, http.StatusInternalServerError, "failed to add the base ISO")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&baseISOResponse{OpenshiftVersion: openshiftVersion, CPUArchitecture: arch, Version: version}); err != nil {
+		log.WithError(err).Error("Failed to write the response")
+	}
+}
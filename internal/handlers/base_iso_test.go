@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+)
+
+var _ = Describe("BaseISOHandler", func() {
+	var (
+		ctrl           *gomock.Controller
+		mockImageStore *imagestore.MockImageStore
+		server         *httptest.Server
+		client         *http.Client
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockImageStore = imagestore.NewMockImageStore(ctrl)
+		server = httptest.NewTLSServer(&BaseISOHandler{ImageStore: mockImageStore, Token: "secret"})
+		client = server.Client()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	put := func(path, token, contentType, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPut, server.URL+path, strings.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := client.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	It("adds an uploaded ISO", func() {
+		mockImageStore.EXPECT().AddBaseISO(gomock.Any(), "custom-4.16", "x86_64", "", gomock.Any()).
+			DoAndReturn(func(_ interface{}, _, _, _ string, iso io.Reader) (string, error) {
+				Expect(io.ReadAll(iso)).To(Equal([]byte("iso content")))
+				return "custom-0123456789ab", nil
+			})
+		resp := put("/base-isos/custom-4.16/x86_64", "secret", "application/octet-stream", "iso content")
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		added := map[string]string{}
+		Expect(json.NewDecoder(resp.Body).Decode(&added)).To(Succeed())
+		Expect(added).To(Equal(map[string]string{
+			"openshift_version": "custom-4.16",
+			"cpu_architecture":  "x86_64",
+			"version":           "custom-0123456789ab",
+		}))
+	})
+
+	It("adds an ISO from a URL", func() {
+		mockImageStore.EXPECT().AddBaseISO(gomock.Any(), "custom-4.16", "x86_64", "https://example.com/custom.iso", nil).
+			Return("custom-0123456789ab", nil)
+		resp := put("/base-isos/custom-4.16/x86_64", "secret", "application/json", `{"url": "https://example.com/custom.iso"}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+	})
+
+	It("requires the url of a JSON body", func() {
+		resp := put("/base-isos/custom-4.16/x86_64", "secret", "application/json", `{}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("rejects plain http", func() {
+		plain := httptest.NewServer(&BaseISOHandler{ImageStore: mockImageStore, Token: "secret"})
+		defer plain.Close()
+		req, err := http.NewRequest(http.MethodPut, plain.URL+"/base-isos/custom-4.16/x86_64", strings.NewReader("iso content"))
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := plain.Client().Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("requires the token", func() {
+		Expect(put("/base-isos/custom-4.16/x86_64", "", "", "iso content").StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(put("/base-isos/custom-4.16/x86_64", "wrong", "", "iso content").StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("only supports PUT", func() {
+		resp, err := client.Get(server.URL + "/base-isos/custom-4.16/x86_64")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("rejects malformed paths", func() {
+		Expect(put("/base-isos/custom-4.16", "secret", "", "iso content").StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("maps the errors of the image store", func() {
+		for err, status := range map[error]int{
+			fmt.Errorf("%w: no boot files", imagestore.ErrInvalidBaseISO): http.StatusBadRequest,
+			fmt.Errorf("%w: custom-4.16", imagestore.ErrVersionExists):    http.StatusConflict,
+			fmt.Errorf("disk full"): http.StatusInternalServerError,
+		} {
+			mockImageStore.EXPECT().AddBaseISO(gomock.Any(), "custom-4.16", "x86_64", "", gomock.Any()).Return("", err)
+			Expect(put("/base-isos/custom-4.16/x86_64", "secret", "", "iso content").StatusCode).To(Equal(status))
+		}
+	})
+})
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/openshift/assisted-image-service/pkg/servers"
+)
+
+// baseURLKey is the context key of the URL of the image service the responses refer to
+type baseURLKey struct{}
+
+// WithBaseURL returns middleware setting the URL of the image service that the scripts, parameter
+// files, descriptors and build callbacks refer to: baseURL when set, otherwise the URL the request
+// was sent to, as forwarded by the trusted proxies
+func WithBaseURL(baseURL string, proxies TrustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			base := strings.TrimSuffix(baseURL, "/")
+			if base == "" {
+				base = forwardedBaseURL(r, proxies)
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), baseURLKey{}, base)))
+		})
+	}
+}
+
+// requestBaseURL returns the URL of the image service as set by WithBaseURL, or the URL the request
+// was sent to without it
+func requestBaseURL(r *http.Request) string {
+	if base, ok := r.Context().Value(baseURLKey{}).(string); ok {
+		return base
+	}
+	return forwardedBaseURL(r, nil)
+}
+
+// forwardedBaseURL returns the URL the request was sent to. The X-Forwarded-Proto and
+// X-Forwarded-Host headers are only honored for the requests of the trusted proxies and of the
+// local listeners, the clients could forge them otherwise.
+func forwardedBaseURL(r *http.Request, proxies TrustedProxies) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	host := r.Host
+	if servers.IsLocal(r.Context()) || proxies.contains(remoteHost(r)) {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if forwardedHost := r.Header.Get("X-Forwarded-Host"); forwardedHost != "" {
+			host = forwardedHost
+		}
+	}
+	return fmt.Sprintf("%s://%s", scheme, host)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -54,6 +55,22 @@ func getArtifactFilePath(artifact string) string {
 	return filePath
 }
 
+// openBootArtifact reads the kernel or the rootfs in place from the ISO. The kernel of s390x ISOs
+// may be named kernel.img rather than vmlinuz.
+func openBootArtifact(isoFileName, artifact string) (io.ReadSeekCloser, error) {
+	artifacts, err := isoeditor.ExtractBootArtifacts(isoFileName)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case artifact == "vmlinuz":
+		return artifacts.Kernel.Open()
+	case artifact == "rootfs.img" && artifacts.Rootfs != nil:
+		return artifacts.Rootfs.Open()
+	}
+	return nil, fmt.Errorf("%s not found in %s", artifact, isoFileName)
+}
+
 func (b *BootArtifactsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet && r.Method != http.MethodHead {
 		log.Error("Only GET and HEAD methods are supported with this endpoint.")
@@ -73,15 +90,32 @@ func (b *BootArtifactsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		httpErrorf(w, http.StatusNotFound, "Failed to parse artifact: %v", err)
 		return
 	}
+	compress, ok := zstdRequested(w, r)
+	if !ok {
+		return
+	}
 
-	isoFileName := b.ImageStore.PathForParams(imagestore.ImageTypeFull, version, arch)
-	fileReader, err := isoeditor.GetFileFromISO(isoFileName, getArtifactFilePath(artifact))
+	tokenArtifact := tokenArtifactPXE
+	if artifact == "rootfs.img" {
+		tokenArtifact = tokenArtifactRootfs
+	}
+	if err := checkTokenScope(r, scopedRequest{version: version, arch: arch, artifact: tokenArtifact}); err != nil {
+		httpErrorf(w, http.StatusForbidden, "%v", err)
+		return
+	}
 
-	if err != nil && arch == "s390x" && artifact == "vmlinuz" {
-		// Reading with artifact name as kernel.img for s390x if vmlinuz is not present
-		fileReader, err = isoeditor.GetFileFromISO(isoFileName, getArtifactFilePath("kernel.img"))
+	if !b.ImageStore.Available(version, arch) {
+		respondVersionDownloading(w, version, arch)
+		return
 	}
 
+	isoFileName := b.ImageStore.PathForParams(imagestore.ImageTypeFull, version, arch)
+	var fileReader io.ReadSeekCloser
+	if artifact == "generic.ins" {
+		fileReader, err = isoeditor.GetFileFromISO(isoFileName, getArtifactFilePath(artifact))
+	} else {
+		fileReader, err = openBootArtifact(isoFileName, artifact)
+	}
 	if err != nil {
 		httpErrorf(w, http.StatusInternalServerError, "Error creating file reader stream: %v", err)
 		return
@@ -95,7 +129,11 @@ func (b *BootArtifactsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	}
 
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", artifact))
-	http.ServeContent(w, r, artifact, fileInfo.ModTime(), fileReader)
+	if compress {
+		serveZstd(w, r, fileInfo.ModTime(), fileReader, imageETag(isoFileName, []byte(artifact)))
+		return
+	}
+	serveImage(w, r, artifact, fileInfo.ModTime(), fileReader, imageETag(isoFileName, []byte(artifact)))
 }
 
 func (b *BootArtifactsHandler) parseQueryParams(values url.Values) (string, string, error) {
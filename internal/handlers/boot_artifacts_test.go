@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/golang/mock/gomock"
+	"github.com/klauspost/compress/zstd"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
@@ -49,6 +50,7 @@ var _ = Describe("ServeHTTP", func() {
 
 		mockImage := func(version, imageType, arch string) {
 			mockImageStore.EXPECT().HaveVersion(version, arch).Return(true).AnyTimes()
+			mockImageStore.EXPECT().Available(version, arch).Return(true).AnyTimes()
 			imageFile := fullImageFilename
 			mockImageStore.EXPECT().PathForParams(imageType, version, arch).Return(imageFile).AnyTimes()
 		}
@@ -108,6 +110,44 @@ var _ = Describe("ServeHTTP", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(resp.StatusCode).To(Equal(http.StatusOK))
 			Expect(resp.Header.Get("Content-Disposition")).To(Equal("attachment; filename=rootfs.img"))
+			Expect(resp.ContentLength).To(Equal(int64(len("this is rootfs"))))
+			Expect(resp.Header.Get("ETag")).NotTo(BeEmpty())
+		})
+
+		It("streams the artifacts compressed with zstd", func() {
+			mockImage("4.8", imagestore.ImageTypeFull, defaultArch)
+			path := fmt.Sprintf("/boot-artifacts/%s?version=4.8&compress=zstd", rootfsArtifact)
+			resp, err := client.Get(server.URL + path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Encoding")).To(Equal("zstd"))
+			Expect(resp.Header.Get("ETag")).To(HaveSuffix(`-zstd"`))
+			dec, err := zstd.NewReader(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			defer dec.Close()
+			Expect(io.ReadAll(dec)).To(Equal([]byte("this is rootfs")))
+		})
+
+		It("fails for unsupported compressions", func() {
+			path := fmt.Sprintf("/boot-artifacts/%s?version=4.8&compress=gzip", rootfsArtifact)
+			mockImageStore.EXPECT().HaveVersion("4.8", defaultArch).Return(true)
+			resp, err := client.Get(server.URL + path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("rejects the artifacts out of the scope of the token", func() {
+			mockImageStore.EXPECT().HaveVersion("4.8", defaultArch).Return(true).Times(2)
+			token := unsignedJWT(map[string]interface{}{"artifacts": []string{"rootfs"}})
+			resp, err := client.Get(server.URL + "/boot-artifacts/kernel?version=4.8&image_token=" + token)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+
+			mockImageStore.EXPECT().Available("4.8", defaultArch).Return(true)
+			mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, "4.8", defaultArch).Return(fullImageFilename)
+			resp, err = client.Get(server.URL + "/boot-artifacts/rootfs?version=4.8&image_token=" + token)
+			Expect(err).NotTo(HaveOccurred())
+			expectSuccessfulResponse(resp, []byte("this is rootfs"), "rootfs.img")
 		})
 
 		It("fails for a non-existent version", func() {
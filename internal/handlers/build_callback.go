@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// callbackAttempts is how many times a callback is POSTed until it's answered with 2xx
+const callbackAttempts = 3
+
+// parseCallbackURL checks that the callback URL of a build is an absolute HTTP URL
+func parseCallbackURL(callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return fmt.Errorf("invalid callback_url: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("callback_url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// notify POSTs the finished job to its callback URL, with the absolute URL of its image, retrying
+// with a doubling interval when the callback fails
+func (h *BuildHandler) notify(job buildJob) {
+	if job.DownloadURL != "" {
+		job.DownloadURL = job.baseURL + job.DownloadURL
+	}
+	body, err := json.Marshal(&job)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to marshal the callback of build %s", job.ID)
+		return
+	}
+
+	interval := h.callbackRetryInterval
+	for attempt := 1; ; attempt++ {
+		err = h.postCallback(job.callbackURL, body)
+		if err == nil {
+			return
+		}
+		if attempt == callbackAttempts {
+			log.WithError(err).Errorf("Failed to notify the callback of build %s", job.ID)
+			return
+		}
+		log.WithError(err).Warnf("Failed to notify the callback of build %s, retrying in %s", job.ID, interval)
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
+
+func (h *BuildHandler) postCallback(callbackURL string, body []byte) error {
+	resp, err := h.callbackClient.Post(callbackURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("callback responded with %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// dialCallback dials the address of a callback, refusing the internal addresses it resolves to
+// unless its host is one of callbackHosts
+func (h *BuildHandler) dialCallback(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, Control: refuseInternalAddress}
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		for _, allowed := range h.callbackHosts {
+			if host == allowed {
+				dialer.Control = nil
+				break
+			}
+		}
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// refuseInternalAddress is the dialer control refusing the loopback, private, link-local,
+// multicast and unspecified addresses, which it's called with once the host is resolved
+func refuseInternalAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("callbacks to the internal address %s aren't allowed", host)
+	}
+	return nil
+}
@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+// Statuses of the build jobs
+const (
+	buildStatusPending   = "pending"
+	buildStatusRunning   = "running"
+	buildStatusSucceeded = "succeeded"
+	buildStatusFailed    = "failed"
+)
+
+// maxConcurrentBuilds is the number of jobs building at once, the others are pending until then
+const maxConcurrentBuilds = 4
+
+// maxPendingBuilds is the number of jobs waiting to build, the requests enqueuing more are
+// answered with 503 and Retry-After
+const maxPendingBuilds = 64
+
+var buildPathRegexp = regexp.MustCompile(`^/builds/([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})(/image)?$`)
+
+// buildRequest is the JSON body of the requests enqueuing a build
+type buildRequest struct {
+	ImageID string `json:"image_id"`
+	Version string `json:"version"`
+	Type    string `json:"type"`
+	Arch    string `json:"arch"`
+	// CallbackURL is notified when the build finishes
+	CallbackURL string `json:"callback_url"`
+}
+
+// buildJob is the status of a build, as returned to the clients polling it
+type buildJob struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+	Digest      string     `json:"digest,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+
+	imageID     string
+	path        string
+	etag        string
+	modTime     time.Time
+	callbackURL string
+	// baseURL is the URL of the service the build was requested from
+	baseURL string
+}
+
+// BuildHandler builds customized ISOs in the background. A POST to /builds fetches the
+// customization of the image with the credentials of the request, then enqueues a build and
+// returns its job, which is polled at /builds/<id> until it has a download URL. The builds are
+// kept in the directory until ttl after they finish, and the job ID grants access to the image.
+// The finished jobs are also POSTed to the callback URL of their request. The v2 image requests,
+// whose body describes the customization of the image, are also served, or built as jobs.
+type BuildHandler struct {
+	ImageStore          imagestore.ImageStore
+	GenerateImageStream isoeditor.StreamGeneratorFunc
+	client              *AssistedServiceClient
+	// nmstateHandler converts the nmstate network configs of the v2 requests
+	nmstateHandler        isoeditor.NmstateHandler
+	dir                   string
+	ttl                   time.Duration
+	callbackClient        *http.Client
+	callbackRetryInterval time.Duration
+	// callbackHosts may be reached by the callbacks on internal addresses
+	callbackHosts []string
+	slots         chan struct{}
+	maxPending    int
+	lock          sync.Mutex
+	pending       int
+	jobs          map[string]*buildJob
+}
+
+var _ http.Handler = &BuildHandler{}
+
+// NewBuildHandler returns the handler of the builds of the ISOs, whose implanted checksum is
+// handled according to isoMD5. The builds of previous runs are removed from dir. The callbacks
+// may only reach loopback, private and link-local addresses on callbackHosts.
+func NewBuildHandler(is imagestore.ImageStore, assistedServiceClient *AssistedServiceClient, nmstateHandler isoeditor.NmstateHandler, isoMD5 isoeditor.ISOMD5Mode, kargsPolicy isoeditor.KargsConflictPolicy, dir string, ttl time.Duration, callbackHosts []string) (*BuildHandler, error) {
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to remove the previous builds: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create the build directory: %w", err)
+	}
+	h := &BuildHandler{
+		ImageStore:            is,
+		GenerateImageStream:   isoeditor.ISOMD5StreamGenerator(isoeditor.RHCOSStreamGenerator(kargsPolicy), isoMD5),
+		client:                assistedServiceClient,
+		nmstateHandler:        nmstateHandler,
+		dir:                   dir,
+		ttl:                   ttl,
+		callbackRetryInterval: 5 * time.Second,
+		callbackHosts:         callbackHosts,
+		slots:                 make(chan struct{}, maxConcurrentBuilds),
+		maxPending:            maxPendingBuilds,
+		jobs:                  map[string]*buildJob{},
+	}
+	h.callbackClient = &http.Client{
+		Timeout: 30 * time.Second,
+		// the callbacks are dialed directly, so that their addresses are checked
+		Transport: &http.Transport{DialContext: h.dialCallback},
+		// the callbacks may not redirect to other URLs
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return h, nil
+}
+
+func (h *BuildHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if match := v2ImagePathRegexp.FindStringSubmatch(r.URL.Path); match != nil {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		h.serveV2Image(w, r, match[1])
+		return
+	}
+
+	if r.URL.Path == "/builds" || r.URL.Path == "/builds/" {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		h.create(w, r)
+		return
+	}
+
+	match := buildPathRegexp.FindStringSubmatch(r.URL.Path)
+	if match == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	h.lock.Lock()
+	job, ok := h.jobs[match[1]]
+	var snapshot buildJob
+	if ok {
+		snapshot = *job
+	}
+	h.lock.Unlock()
+	if !ok {
+		httpErrorf(w, http.StatusNotFound, "build %s not found", match[1])
+		return
+	}
+
+	if match[2] == "" {
+		writeBuildJob(w, http.StatusOK, &snapshot)
+		return
+	}
+	h.serveBuild(w, r, &snapshot)
+}
+
+func (h *BuildHandler) create(w http.ResponseWriter, r *http.Request) {
+	req := &buildRequest{}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(req); err != nil {
+		httpErrorf(w, http.StatusBadRequest, "invalid build request: %v", err)
+		return
+	}
+	if _, err := uuid.Parse(req.ImageID); err != nil {
+		httpErrorf(w, http.StatusBadRequest, "invalid image_id '%s'", req.ImageID)
+		return
+	}
+	if req.Version == "" {
+		httpErrorf(w, http.StatusBadRequest, "'version' is required")
+		return
+	}
+	if req.Type != imagestore.ImageTypeFull && req.Type != imagestore.ImageTypeMinimal {
+		httpErrorf(w, http.StatusBadRequest, "invalid value '%s' for 'type'", req.Type)
+		return
+	}
+	if req.Arch == "" {
+		req.Arch = defaultArch
+	}
+	if req.CallbackURL != "" {
+		if err := parseCallbackURL(req.CallbackURL); err != nil {
+			httpErrorf(w, http.StatusBadRequest, "%v", err)
+			return
+		}
+	}
+	params := &imageDownloadParams{
+		imageID:   req.ImageID,
+		version:   req.Version,
+		imageType: req.Type,
+		arch:      req.Arch,
+	}
+
+	if !h.ImageStore.HaveVersion(params.version, params.arch) {
+		httpErrorf(w, http.StatusBadRequest, "version %s %s not found", params.version, params.arch)
+		return
+	}
+	err := checkTokenScope(r, scopedRequest{
+		imageID:  params.imageID,
+		version:  params.version,
+		arch:     params.arch,
+		artifact: tokenArtifactISO,
+	})
+	if err != nil {
+		httpErrorf(w, http.StatusForbidden, "%v", err)
+		return
+	}
+	c, statusCode, err := fetchISOCustomization(h.client, r, params)
+	if err != nil {
+		httpErrorf(w, statusCode, "%v", err)
+		return
+	}
+	h.enqueue(w, r, params, c, req.CallbackURL)
+}
+
+// enqueue starts building the image of the request with its customization, fetched with the
+// credentials of the request, and responds with its job, unless the images of the version aren't
+// available yet or too many jobs are pending
+func (h *BuildHandler) enqueue(w http.ResponseWriter, r *http.Request, params *imageDownloadParams, c *isoCustomization, callbackURL string) {
+	if !h.ImageStore.Available(params.version, params.arch) {
+		respondOverLimit(w, retryAfter, "The images of version %s %s are being downloaded, retry later", params.version, params.arch)
+		return
+	}
+
+	id := uuid.NewString()
+	job := &buildJob{
+		ID:          id,
+		Status:      buildStatusPending,
+		CreatedAt:   time.Now(),
+		imageID:     params.imageID,
+		path:        filepath.Join(h.dir, id+".iso"),
+		callbackURL: callbackURL,
+		baseURL:     requestBaseURL(r),
+	}
+	h.lock.Lock()
+	if h.pending >= h.maxPending {
+		h.lock.Unlock()
+		respondOverLimit(w, retryAfter, "Too many pending builds, retry later")
+		return
+	}
+	h.pending++
+	h.jobs[id] = job
+	snapshot := *job
+	h.lock.Unlock()
+
+	go h.run(job, params, c)
+
+	w.Header().Set("Location", "/builds/"+id)
+	writeBuildJob(w, http.StatusAccepted, &snapshot)
+}
+
+func (h *BuildHandler) run(job *buildJob, params *imageDownloadParams, c *isoCustomization) {
+	h.slots <- struct{}{}
+	defer func() { <-h.slots }()
+
+	h.lock.Lock()
+	h.pending--
+	job.Status = buildStatusRunning
+	h.lock.Unlock()
+
+	err := h.build(job, params, c)
+
+	h.lock.Lock()
+	finished := time.Now()
+	job.FinishedAt = &finished
+	if err != nil {
+		log.WithError(err).Errorf("Failed to build the image of build %s", job.ID)
+		job.Status = buildStatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = buildStatusSucceeded
+		job.DownloadURL = fmt.Sprintf("/builds/%s/image", job.ID)
+	}
+	snapshot := *job
+	h.lock.Unlock()
+
+	if snapshot.callbackURL != "" {
+		go h.notify(snapshot)
+	}
+}
+
+func (h *BuildHandler) build(job *buildJob, params *imageDownloadParams, c *isoCustomization) error {
+	isoPath := h.ImageStore.PathForParams(params.imageType, params.version, params.arch)
+	isoReader, err := h.GenerateImageStream(isoPath, c.ignition, c.ramdisk, c.kargs)
+	if err != nil {
+		return fmt.Errorf("failed to create image stream: %w", err)
+	}
+	defer isoReader.Close()
+
+	f, err := os.CreateTemp(h.dir, job.ID+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create the image file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(f, hash), isoReader); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write the image: %w", err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("failed to write the image: %w", err)
+	}
+	if err = os.Rename(f.Name(), job.path); err != nil {
+		return fmt.Errorf("failed to write the image: %w", err)
+	}
+
+	etag := imageETag(isoPath, c.ignition.Config, c.ramdisk, kargsETagInput(c.kargs))
+	digest := base64.StdEncoding.EncodeToString(hash.Sum(nil))
+	imageDigests.add(etag, digest)
+	h.lock.Lock()
+	job.etag = etag
+	job.modTime = c.modTime()
+	job.Digest = "sha-256=" + digest
+	h.lock.Unlock()
+	return nil
+}
+
+func (h *BuildHandler) serveBuild(w http.ResponseWriter, r *http.Request, job *buildJob) {
+	switch job.Status {
+	case buildStatusPending, buildStatusRunning:
+		w.Header().Set("Retry-After", retryAfter)
+		http.Error(w, fmt.Sprintf("Build %s is %s, retry later", job.ID, job.Status), http.StatusAccepted)
+		return
+	case buildStatusFailed:
+		httpErrorf(w, http.StatusConflict, "build %s failed: %s", job.ID, job.Error)
+		return
+	}
+
+	f, err := os.Open(job.path)
+	if err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "failed to open the image of build %s: %v", job.ID, err)
+		return
+	}
+	defer f.Close()
+
+	fileName := fmt.Sprintf("%s-discovery.iso", job.imageID)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	serveImage(w, r, fileName, job.modTime, f, job.etag)
+}
+
+// StartExpiring removes the expired jobs every interval until ctx is done
+func (h *BuildHandler) StartExpiring(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.expire()
+		}
+	}
+}
+
+// expire removes the jobs, and their images, finished more than ttl ago
+func (h *BuildHandler) expire() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for id, job := range h.jobs {
+		if job.FinishedAt != nil && time.Since(*job.FinishedAt) > h.ttl {
+			delete(h.jobs, id)
+			if err := os.Remove(job.path); err != nil && !os.IsNotExist(err) {
+				log.WithError(err).Warnf("Failed to remove the image of build %s", id)
+			}
+		}
+	}
+}
+
+func writeBuildJob(w http.ResponseWriter, code int, job *buildJob) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.WithError(err).Error("Failed to write the build job")
+	}
+}
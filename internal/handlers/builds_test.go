@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+var _ = Describe("BuildHandler", func() {
+	var (
+		ctrl           *gomock.Controller
+		mockImageStore *imagestore.MockImageStore
+		assistedServer *ghttp.Server
+		handler        *BuildHandler
+		server         *httptest.Server
+		client         *http.Client
+		dir            string
+		isoFile        string
+		imageID        = "bf25292a-dddd-49dc-ab9c-3fb4c1f07071"
+		isoContent     = "someisocontent"
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockImageStore = imagestore.NewMockImageStore(ctrl)
+		assistedServer = ghttp.NewServer()
+		u, err := url.Parse(assistedServer.URL())
+		Expect(err).NotTo(HaveOccurred())
+		asc, err := NewAssistedServiceClient(u.Scheme, u.Host, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		dir, err = os.MkdirTemp("", "builds_test")
+		Expect(err).NotTo(HaveOccurred())
+		isoFile = filepath.Join(dir, "base.iso")
+		Expect(os.WriteFile(isoFile, []byte(isoContent), 0600)).To(Succeed())
+
+		handler, err = NewBuildHandler(mockImageStore, asc, nil, isoeditor.ISOMD5Keep, isoeditor.KargsConflictKeepAll, filepath.Join(dir, "builds"), time.Hour, []string{"127.0.0.1"})
+		Expect(err).NotTo(HaveOccurred())
+		handler.GenerateImageStream = func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
+			defer GinkgoRecover()
+			Expect(isoPath).To(Equal(isoFile))
+			Expect(ignition.Config).To(Equal([]byte("someignitioncontent")))
+			return os.Open(isoPath)
+		}
+		server = httptest.NewServer(handler)
+		client = server.Client()
+	})
+
+	AfterEach(func() {
+		server.Close()
+		assistedServer.Close()
+		os.RemoveAll(dir)
+	})
+
+	appendCustomizationHandlers := func(query string) {
+		assistedServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(fileRouteFormat, imageID), query),
+				ghttp.RespondWith(http.StatusOK, "someignitioncontent", http.Header{"Last-Modified": {"Fri, 22 Apr 2022 18:11:09 GMT"}}),
+			),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(infraEnvPathFormat, imageID)),
+				ghttp.RespondWith(http.StatusOK, "{}"),
+			),
+		)
+	}
+
+	postBuild := func(body, query string) *http.Response {
+		resp, err := client.Post(server.URL+"/builds"+query, "application/json", strings.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	getJob := func(id string) *buildJob {
+		resp, err := client.Get(server.URL + "/builds/" + id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		job := &buildJob{}
+		Expect(json.NewDecoder(resp.Body).Decode(job)).To(Succeed())
+		return job
+	}
+
+	enqueue := func(query string) *buildJob {
+		resp := postBuild(fmt.Sprintf(`{"image_id": "%s", "version": "4.11", "type": "full-iso"}`, imageID), query)
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+		job := &buildJob{}
+		Expect(json.NewDecoder(resp.Body).Decode(job)).To(Succeed())
+		Expect(resp.Header.Get("Location")).To(Equal("/builds/" + job.ID))
+		return job
+	}
+
+	// blockBuilds makes the builds wait for the returned channel to be closed
+	blockBuilds := func() chan struct{} {
+		release := make(chan struct{})
+		generate := handler.GenerateImageStream
+		handler.GenerateImageStream = func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
+			<-release
+			return generate(isoPath, ignition, ramdiskBytes, kargs)
+		}
+		return release
+	}
+
+	finished := func(id string) func() string {
+		return func() string {
+			return getJob(id).Status
+		}
+	}
+
+	It("builds the image in the background", func() {
+		mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(true)
+		mockImageStore.EXPECT().Available("4.11", defaultArch).Return(true)
+		mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, "4.11", defaultArch).Return(isoFile)
+		appendCustomizationHandlers("api_key=secret&discovery_iso_type=full-iso&file_name=discovery.ign")
+
+		job := enqueue("?api_key=secret")
+		Expect(job.Status).To(BeElementOf(buildStatusPending, buildStatusRunning))
+		Eventually(finished(job.ID)).Should(Equal(buildStatusSucceeded))
+
+		job = getJob(job.ID)
+		Expect(job.DownloadURL).To(Equal(fmt.Sprintf("/builds/%s/image", job.ID)))
+		Expect(job.FinishedAt).NotTo(BeNil())
+
+		resp, err := client.Get(server.URL + job.DownloadURL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Disposition")).To(Equal(fmt.Sprintf("attachment; filename=%s-discovery.iso", imageID)))
+		Expect(resp.Header.Get("Last-Modified")).To(Equal("Fri, 22 Apr 2022 18:11:09 GMT"))
+		Expect(resp.Header.Get("ETag")).NotTo(BeEmpty())
+		content, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal(isoContent))
+	})
+
+	It("asks to retry while the images of the version are downloading", func() {
+		mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(true)
+		mockImageStore.EXPECT().Available("4.11", defaultArch).Return(false)
+		appendCustomizationHandlers("discovery_iso_type=full-iso&file_name=discovery.ign")
+
+		resp := postBuild(fmt.Sprintf(`{"image_id": "%s", "version": "4.11", "type": "full-iso"}`, imageID), "")
+		Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(resp.Header.Get("Retry-After")).To(Equal(retryAfter))
+		Expect(handler.jobs).To(BeEmpty())
+	})
+
+	It("rejects the builds assisted service doesn't authorize", func() {
+		mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(true)
+		assistedServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(fileRouteFormat, imageID)),
+				ghttp.RespondWith(http.StatusUnauthorized, ""),
+			),
+		)
+
+		resp := postBuild(fmt.Sprintf(`{"image_id": "%s", "version": "4.11", "type": "full-iso"}`, imageID), "")
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(handler.jobs).To(BeEmpty())
+	})
+
+	It("reports the failed builds", func() {
+		mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(true)
+		mockImageStore.EXPECT().Available("4.11", defaultArch).Return(true)
+		mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, "4.11", defaultArch).Return(isoFile)
+		appendCustomizationHandlers("discovery_iso_type=full-iso&file_name=discovery.ign")
+		handler.GenerateImageStream = func(string, *isoeditor.IgnitionContent, []byte, isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
+			return nil, fmt.Errorf("no space left")
+		}
+
+		job := enqueue("")
+		Eventually(finished(job.ID)).Should(Equal(buildStatusFailed))
+		Expect(getJob(job.ID).Error).To(ContainSubstring("no space left"))
+
+		resp, err := client.Get(fmt.Sprintf("%s/builds/%s/image", server.URL, job.ID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+	})
+
+	It("asks to retry the download of unfinished builds", func() {
+		mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(true)
+		mockImageStore.EXPECT().Available("4.11", defaultArch).Return(true)
+		mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, "4.11", defaultArch).Return(isoFile)
+		appendCustomizationHandlers("discovery_iso_type=full-iso&file_name=discovery.ign")
+		defer close(blockBuilds())
+
+		job := enqueue("")
+		resp, err := client.Get(fmt.Sprintf("%s/builds/%s/image", server.URL, job.ID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+		Expect(resp.Header.Get("Retry-After")).To(Equal(retryAfter))
+	})
+
+	It("removes the expired builds", func() {
+		mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(true)
+		mockImageStore.EXPECT().Available("4.11", defaultArch).Return(true)
+		mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, "4.11", defaultArch).Return(isoFile)
+		appendCustomizationHandlers("discovery_iso_type=full-iso&file_name=discovery.ign")
+
+		job := enqueue("")
+		Eventually(finished(job.ID)).Should(Equal(buildStatusSucceeded))
+		Expect(filepath.Join(dir, "builds", job.ID+".iso")).To(BeAnExistingFile())
+
+		handler.ttl = 0
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go handler.StartExpiring(ctx, 10*time.Millisecond)
+		Eventually(func() int {
+			resp, err := client.Get(server.URL + "/builds/" + job.ID)
+			Expect(err).NotTo(HaveOccurred())
+			return resp.StatusCode
+		}).Should(Equal(http.StatusNotFound))
+		Expect(filepath.Join(dir, "builds", job.ID+".iso")).NotTo(BeAnExistingFile())
+	})
+
+	It("rejects the builds over the pending ones", func() {
+		mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(true).Times(3)
+		mockImageStore.EXPECT().Available("4.11", defaultArch).Return(true).Times(3)
+		mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, "4.11", defaultArch).Return(isoFile).Times(2)
+		for i := 0; i < 3; i++ {
+			appendCustomizationHandlers("discovery_iso_type=full-iso&file_name=discovery.ign")
+		}
+		defer close(blockBuilds())
+		handler.slots = make(chan struct{}, 1)
+		handler.maxPending = 1
+
+		running := enqueue("")
+		Eventually(finished(running.ID)).Should(Equal(buildStatusRunning))
+		pending := enqueue("")
+		Expect(pending.Status).To(Equal(buildStatusPending))
+
+		resp := postBuild(fmt.Sprintf(`{"image_id": "%s", "version": "4.11", "type": "full-iso"}`, imageID), "")
+		Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(resp.Header.Get("Retry-After")).To(Equal(retryAfter))
+	})
+
+	It("is served behind the limits of the images", func() {
+		images := &ImageHandler{builds: handler, limiter: newStreamLimiter(StreamLimits{MaxRequestsPerMinutePerClient: 1})}
+		limited := httptest.NewServer(images.router(1))
+		defer limited.Close()
+
+		path := "/builds/" + uuid.NewString()
+		resp, err := limited.Client().Get(limited.URL + path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		resp, err = limited.Client().Get(limited.URL + path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusServiceUnavailable))
+	})
+
+	Context("with a callback", func() {
+		var callbackServer *ghttp.Server
+
+		BeforeEach(func() {
+			callbackServer = ghttp.NewServer()
+			handler.callbackRetryInterval = 10 * time.Millisecond
+		})
+
+		AfterEach(func() {
+			callbackServer.Close()
+		})
+
+		enqueueWithCallback := func() *buildJob {
+			resp := postBuild(fmt.Sprintf(`{"image_id": "%s", "version": "4.11", "type": "full-iso", "callback_url": "%s/done"}`, imageID, callbackServer.URL()), "")
+			Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+			job := &buildJob{}
+			Expect(json.NewDecoder(resp.Body).Decode(job)).To(Succeed())
+			return job
+		}
+
+		It("notifies the successful builds with the URL and digest of the image", func() {
+			mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(true)
+			mockImageStore.EXPECT().Available("4.11", defaultArch).Return(true)
+			mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, "4.11", defaultArch).Return(isoFile)
+			appendCustomizationHandlers("discovery_iso_type=full-iso&file_name=discovery.ign")
+
+			notified := make(chan *buildJob, 1)
+			callbackServer.AppendHandlers(
+				ghttp.RespondWith(http.StatusInternalServerError, ""),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("POST", "/done"),
+					ghttp.VerifyContentType("application/json"),
+					func(w http.ResponseWriter, r *http.Request) {
+						job := &buildJob{}
+						Expect(json.NewDecoder(r.Body).Decode(job)).To(Succeed())
+						notified <- job
+					},
+				),
+			)
+
+			job := enqueueWithCallback()
+			var notification *buildJob
+			Eventually(notified).Should(Receive(&notification))
+			Expect(notification.ID).To(Equal(job.ID))
+			Expect(notification.Status).To(Equal(buildStatusSucceeded))
+			Expect(notification.DownloadURL).To(Equal(fmt.Sprintf("%s/builds/%s/image", server.URL, job.ID)))
+			sum := sha256.Sum256([]byte(isoContent))
+			Expect(notification.Digest).To(Equal("sha-256=" + base64.StdEncoding.EncodeToString(sum[:])))
+
+			resp, err := client.Head(notification.DownloadURL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Header.Get("Digest")).To(Equal(notification.Digest))
+		})
+
+		It("notifies the failed builds", func() {
+			mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(true)
+			mockImageStore.EXPECT().Available("4.11", defaultArch).Return(true)
+			mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, "4.11", defaultArch).Return(isoFile)
+			appendCustomizationHandlers("discovery_iso_type=full-iso&file_name=discovery.ign")
+			handler.GenerateImageStream = func(string, *isoeditor.IgnitionContent, []byte, isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
+				return nil, fmt.Errorf("no space left")
+			}
+
+			notified := make(chan *buildJob, 1)
+			callbackServer.AppendHandlers(func(w http.ResponseWriter, r *http.Request) {
+				job := &buildJob{}
+				Expect(json.NewDecoder(r.Body).Decode(job)).To(Succeed())
+				notified <- job
+			})
+
+			job := enqueueWithCallback()
+			var notification *buildJob
+			Eventually(notified).Should(Receive(&notification))
+			Expect(notification.ID).To(Equal(job.ID))
+			Expect(notification.Status).To(Equal(buildStatusFailed))
+			Expect(notification.Error).NotTo(BeEmpty())
+			Expect(notification.DownloadURL).To(BeEmpty())
+		})
+	})
+
+	It("refuses the callbacks to internal addresses", func() {
+		callbackServer := ghttp.NewServer()
+		defer callbackServer.Close()
+		handler.callbackHosts = nil
+
+		err := handler.postCallback(callbackServer.URL()+"/done", []byte("{}"))
+		Expect(err).To(MatchError(ContainSubstring("internal address 127.0.0.1")))
+		Expect(callbackServer.ReceivedRequests()).To(BeEmpty())
+
+		u, err := url.Parse(callbackServer.URL())
+		Expect(err).NotTo(HaveOccurred())
+		err = handler.postCallback(fmt.Sprintf("http://localhost:%s/done", u.Port()), []byte("{}"))
+		Expect(err).To(MatchError(ContainSubstring("internal address")))
+	})
+
+	DescribeTable("rejects invalid build requests",
+		func(body string) {
+			mockImageStore.EXPECT().HaveVersion(gomock.Any(), gomock.Any()).Return(false).AnyTimes()
+			resp := postBuild(body, "")
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		},
+		Entry("malformed JSON", `{`),
+		Entry("invalid image_id", `{"image_id": "foo", "version": "4.11", "type": "full-iso"}`),
+		Entry("missing version", fmt.Sprintf(`{"image_id": "%s", "type": "full-iso"}`, imageID)),
+		Entry("invalid type", fmt.Sprintf(`{"image_id": "%s", "version": "4.11", "type": "foo"}`, imageID)),
+		Entry("relative callback_url", fmt.Sprintf(`{"image_id": "%s", "version": "4.11", "type": "full-iso", "callback_url": "/done"}`, imageID)),
+		Entry("non HTTP callback_url", fmt.Sprintf(`{"image_id": "%s", "version": "4.11", "type": "full-iso", "callback_url": "file:///done"}`, imageID)),
+		Entry("unknown version", fmt.Sprintf(`{"image_id": "%s", "version": "4.7", "type": "full-iso"}`, imageID)),
+	)
+
+	It("rejects the builds out of the scope of the token", func() {
+		mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(true)
+		token := unsignedJWT(map[string]interface{}{"infra_env_id": imageID, "artifacts": []string{"pxe"}})
+		resp := postBuild(fmt.Sprintf(`{"image_id": "%s", "version": "4.11", "type": "full-iso"}`, imageID), "?image_token="+token)
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("fails for unknown builds", func() {
+		resp, err := client.Get(server.URL + "/builds/2b4c3d5e-dddd-49dc-ab9c-3fb4c1f07071")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("only allows POST to enqueue builds", func() {
+		resp, err := client.Get(server.URL + "/builds")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+
+		req, err := http.NewRequest(http.MethodPut, server.URL+"/builds/2b4c3d5e-dddd-49dc-ab9c-3fb4c1f07071", bytes.NewReader(nil))
+		Expect(err).NotTo(HaveOccurred())
+		resp, err = client.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
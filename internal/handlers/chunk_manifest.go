@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+)
+
+// chunkManifest lists the chunks of an artifact with their digests, so that the clients can
+// download them in parallel with Range and If-Range requests and verify them
+type chunkManifest struct {
+	Name      string   `json:"name"`
+	Size      int64    `json:"size"`
+	SHA256    string   `json:"sha256"`
+	ETag      string   `json:"etag"`
+	ChunkSize int64    `json:"chunk_size"`
+	URLs      []string `json:"urls"`
+	Chunks    []chunk  `json:"chunks"`
+}
+
+type chunk struct {
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length"`
+	SHA256 string `json:"sha256"`
+}
+
+// chunks renders the chunk manifest of the artifact, the chunks being the pieces of its hashes
+func chunks(name, etag string, hashes *artifactHashes, urls []string) ([]byte, error) {
+	manifest := chunkManifest{
+		Name:      name,
+		Size:      hashes.size,
+		SHA256:    hex.EncodeToString(hashes.sha256),
+		ETag:      etag,
+		ChunkSize: descriptorPieceLength,
+		URLs:      urls,
+		Chunks:    []chunk{},
+	}
+	for i, piece := range hashes.sha256Pieces {
+		offset := int64(i) * descriptorPieceLength
+		length := int64(descriptorPieceLength)
+		if offset+length > hashes.size {
+			length = hashes.size - offset
+		}
+		manifest.Chunks = append(manifest.Chunks, chunk{Offset: offset, Length: length, SHA256: hex.EncodeToString(piece)})
+	}
+	return json.Marshal(manifest)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	log "github.com/sirupsen/logrus"
+)
+
+// compressZstd is the compress query parameter of the artifacts streamed compressed with zstd
+const compressZstd = "zstd"
+
+// zstdRequested tells whether the artifact is requested compressed with zstd. The other
+// compressions are answered with 400, in which case ok is false.
+func zstdRequested(w http.ResponseWriter, r *http.Request) (compress bool, ok bool) {
+	switch compression := r.URL.Query().Get("compress"); compression {
+	case "":
+		return false, true
+	case compressZstd:
+		return true, true
+	default:
+		httpErrorf(w, http.StatusBadRequest, "unsupported compression %q, expected %s", compression, compressZstd)
+		return false, false
+	}
+}
+
+// setZstdEncoding sets the headers of a response compressed with zstd, whose size isn't known
+// until it has been written
+func setZstdEncoding(w http.ResponseWriter) {
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Encoding", compressZstd)
+}
+
+// serveZstd streams the content compressed with zstd, answering the conditional and HEAD requests
+// but not the Range ones since the compressed content isn't seekable
+func serveZstd(w http.ResponseWriter, r *http.Request, modTime time.Time, content io.Reader, etag string) {
+	// the compressed content is another representation of the artifact
+	etag = strings.TrimSuffix(etag, `"`) + "-" + compressZstd + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	if match := r.Header.Get("If-None-Match"); match == etag || match == "*" {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	setZstdEncoding(w)
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	enc, err := zstd.NewWriter(w)
+	if err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "failed to compress the artifact: %v", err)
+		return
+	}
+	if _, err = io.Copy(enc, content); err == nil {
+		err = enc.Close()
+	}
+	if err != nil {
+		log.WithError(err).Warnf("failed to stream %s compressed with zstd", r.URL.Path)
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+)
+
+// the ignition openstack provider reads the config drive with this label
+const configDriveVolumeLabel = "config-2"
+
+// configDriveHandler serves an OpenStack config drive carrying the discovery ignition of the image,
+// for the platforms that can attach a second device to the hosts but can't serve the ignition
+type configDriveHandler struct {
+	client  *AssistedServiceClient
+	workDir string
+}
+
+var _ http.Handler = &configDriveHandler{}
+
+func (h *configDriveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	imageID := chi.URLParam(r, "image_id")
+
+	// the discovery ignition of the disk images is the one of the full ISO
+	ignition, lastModified, code, err := h.client.ignitionContent(r, imageID, imagestore.ImageTypeFull)
+	if err != nil {
+		httpErrorf(w, code, "error retrieving ignition content: %v", err)
+		return
+	}
+	metaData, err := json.Marshal(map[string]string{"uuid": imageID})
+	if err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "failed to create the config drive: %v", err)
+		return
+	}
+
+	modTime, err := http.ParseTime(lastModified)
+	if err != nil {
+		log.Warnf("Error parsing last modified time %s: %v", lastModified, err)
+		modTime = time.Now()
+	}
+	files := map[string][]byte{
+		"openstack/latest/user_data":      ignition.Config,
+		"openstack/latest/meta_data.json": metaData,
+	}
+	serveSeedISO(w, r, h.workDir, configDriveVolumeLabel, fmt.Sprintf("%s-config-drive.iso", imageID), files, modTime)
+}
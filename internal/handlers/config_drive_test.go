@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+var _ = Describe("configDriveHandler", func() {
+	var (
+		imageID         = "bf25292a-dddd-49dc-ab9c-3fb4c1f07071"
+		assistedServer  *ghttp.Server
+		ignitionContent = []byte(`{"ignition":{"version":"3.1.0"}}`)
+		server          *httptest.Server
+		workDir         string
+	)
+
+	BeforeEach(func() {
+		var err error
+		workDir, err = os.MkdirTemp("", "configDriveTest")
+		Expect(err).NotTo(HaveOccurred())
+
+		assistedServer = ghttp.NewServer()
+		u, err := url.Parse(assistedServer.URL())
+		Expect(err).NotTo(HaveOccurred())
+		asc, err := NewAssistedServiceClient(u.Scheme, u.Host, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		handler := &ImageHandler{
+			configDrive: &configDriveHandler{client: asc, workDir: workDir},
+			limiter:     newStreamLimiter(StreamLimits{}),
+		}
+		server = httptest.NewServer(handler.router(1))
+	})
+
+	AfterEach(func() {
+		assistedServer.Close()
+		server.Close()
+		Expect(os.RemoveAll(workDir)).To(Succeed())
+	})
+
+	It("serves a config drive with the ignition as user data", func() {
+		assistedServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(fileRouteFormat, imageID), "discovery_iso_type=full-iso&file_name=discovery.ign"),
+				ghttp.RespondWith(http.StatusOK, ignitionContent, http.Header{"Last-Modified": []string{"Fri, 22 Apr 2022 18:11:09 GMT"}}),
+			),
+		)
+
+		resp, err := server.Client().Get(fmt.Sprintf("%s/images/%s/config-drive", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Disposition")).To(Equal(fmt.Sprintf("attachment; filename=%s-config-drive.iso", imageID)))
+		Expect(resp.Header.Get("Last-Modified")).To(Equal("Fri, 22 Apr 2022 18:11:09 GMT"))
+		content, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		isoPath := filepath.Join(workDir, "received.iso")
+		Expect(os.WriteFile(isoPath, content, 0600)).To(Succeed())
+
+		volumeID, err := isoeditor.VolumeIdentifier(isoPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.TrimRight(volumeID, "\x00")).To(Equal(configDriveVolumeLabel))
+		Expect(isoeditor.ReadFileFromISO(isoPath, "/openstack/latest/user_data")).To(Equal(ignitionContent))
+		Expect(isoeditor.ReadFileFromISO(isoPath, "/openstack/latest/meta_data.json")).To(MatchJSON(fmt.Sprintf(`{"uuid": "%s"}`, imageID)))
+	})
+
+	It("fails when the ignition can't be retrieved", func() {
+		assistedServer.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, nil))
+		resp, err := server.Client().Get(fmt.Sprintf("%s/images/%s/config-drive", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
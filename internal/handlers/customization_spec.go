@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
+)
+
+// customizationSpec is the JSON body of the v2 image requests, describing the customization of
+// the image on top of the one of its infra-env
+type customizationSpec struct {
+	Version string `json:"version"`
+	Type    string `json:"type"`
+	Arch    string `json:"arch"`
+	// Ignition is an ignition or Butane config merged into the one of the infra-env
+	Ignition string `json:"ignition"`
+	// KernelArguments are appended to the ones of the infra-env
+	KernelArguments []string `json:"kernel_arguments"`
+	// CABundle is trusted by ignition and by the live environment
+	CABundle      string              `json:"ca_bundle"`
+	StaticNetwork []staticNetworkSpec `json:"static_network"`
+	Hosts         []hostSpec          `json:"hosts"`
+	SystemdUnits  []systemdUnitSpec   `json:"systemd_units"`
+	// Async builds the image as a job instead of streaming it
+	Async       bool   `json:"async"`
+	CallbackURL string `json:"callback_url"`
+}
+
+type staticNetworkSpec struct {
+	NetworkYAML     string            `json:"network_yaml"`
+	Keyfiles        map[string]string `json:"keyfiles"`
+	MACInterfaceMap map[string]string `json:"mac_interface_map"`
+}
+
+type hostSpec struct {
+	MACAddresses    []string          `json:"mac_addresses"`
+	Hostname        string            `json:"hostname"`
+	Keyfiles        map[string]string `json:"keyfiles"`
+	KernelArguments []string          `json:"kernel_arguments"`
+}
+
+type systemdUnitSpec struct {
+	Name     string `json:"name"`
+	Contents string `json:"contents"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// validate checks the spec and fills its defaults, the customizations needing the ramdisk are
+// only supported by the minimal ISOs
+func (s *customizationSpec) validate() error {
+	if s.Version == "" {
+		return fmt.Errorf("'version' is required")
+	}
+	if s.Type != imagestore.ImageTypeFull && s.Type != imagestore.ImageTypeMinimal {
+		return fmt.Errorf("invalid value '%s' for 'type'", s.Type)
+	}
+	if s.Arch == "" {
+		s.Arch = defaultArch
+	}
+	for _, karg := range s.KernelArguments {
+		if karg == "" || strings.ContainsAny(karg, " \t\r\n") {
+			return fmt.Errorf("invalid kernel argument %q", karg)
+		}
+	}
+	if s.Ignition != "" && isoeditor.IsButane([]byte(s.Ignition)) {
+		ignition, err := isoeditor.ButaneToIgnition([]byte(s.Ignition))
+		if err != nil {
+			return err
+		}
+		s.Ignition = string(ignition)
+	}
+	if s.Ignition != "" {
+		if err := isoeditor.ValidateIgnition([]byte(s.Ignition)); err != nil {
+			return err
+		}
+	}
+	if s.CABundle != "" {
+		if err := isoeditor.ValidateCABundle([]byte(s.CABundle)); err != nil {
+			return err
+		}
+	}
+	if s.Type != imagestore.ImageTypeMinimal && len(s.StaticNetwork)+len(s.Hosts)+len(s.SystemdUnits) > 0 {
+		return fmt.Errorf("'static_network', 'hosts' and 'systemd_units' require a %s image", imagestore.ImageTypeMinimal)
+	}
+	for i, host := range s.Hosts {
+		for _, karg := range host.KernelArguments {
+			if karg == "" || strings.ContainsAny(karg, " \t\r\n") {
+				return fmt.Errorf("invalid kernel argument %q of host %d", karg, i)
+			}
+		}
+	}
+	if s.CallbackURL != "" {
+		if !s.Async {
+			return fmt.Errorf("'callback_url' requires 'async'")
+		}
+		if err := parseCallbackURL(s.CallbackURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// params returns the download parameters of the image of the spec
+func (s *customizationSpec) params(imageID string) *imageDownloadParams {
+	return &imageDownloadParams{
+		imageID:   imageID,
+		version:   s.Version,
+		imageType: s.Type,
+		arch:      s.Arch,
+	}
+}
+
+// apply adds the customizations of the spec to the ones of the infra-env. The files of the live
+// environment are appended to the ramdisk as another archive, which the kernel unpacks on top of
+// the previous ones.
+func (s *customizationSpec) apply(c *isoCustomization, params *imageDownloadParams, nmstateHandler isoeditor.NmstateHandler) error {
+	var err error
+	if s.Ignition != "" {
+		if c.ignition.Config, err = isoeditor.MergeIgnition(c.ignition.Config, []byte(s.Ignition)); err != nil {
+			return fmt.Errorf("failed to merge the ignition config: %w", err)
+		}
+	}
+
+	var entries []cpio.Entry
+	if s.CABundle != "" {
+		if c.ignition.Config, err = isoeditor.AddCABundleToIgnition(c.ignition.Config, []byte(s.CABundle)); err != nil {
+			return fmt.Errorf("failed to add the CA bundle: %w", err)
+		}
+		if params.imageType == imagestore.ImageTypeMinimal {
+			if entries, err = isoeditor.CABundleEntries([]byte(s.CABundle)); err != nil {
+				return err
+			}
+		}
+	}
+	if len(s.StaticNetwork) > 0 {
+		hosts := make([]isoeditor.HostStaticNetworkConfig, len(s.StaticNetwork))
+		for i, n := range s.StaticNetwork {
+			hosts[i] = isoeditor.HostStaticNetworkConfig{NetworkYAML: n.NetworkYAML, Keyfiles: n.Keyfiles, MACInterfaceMap: n.MACInterfaceMap}
+		}
+		networkEntries, err := isoeditor.StaticNetworkEntries(hosts, nmstateHandler)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, networkEntries...)
+	}
+	if len(s.Hosts) > 0 {
+		hosts := make([]isoeditor.HostOverlay, len(s.Hosts))
+		for i, h := range s.Hosts {
+			hosts[i] = isoeditor.HostOverlay{MACAddresses: h.MACAddresses, Hostname: h.Hostname, Keyfiles: h.Keyfiles, Kargs: strings.Join(h.KernelArguments, " ")}
+		}
+		hostEntries, err := isoeditor.HostOverlayEntries(hosts)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, hostEntries...)
+	}
+	if len(s.SystemdUnits) > 0 {
+		units := make([]isoeditor.SystemdUnit, len(s.SystemdUnits))
+		for i, u := range s.SystemdUnits {
+			units[i] = isoeditor.SystemdUnit{Name: u.Name, Contents: u.Contents, Enabled: u.Enabled}
+		}
+		unitEntries, err := isoeditor.SystemdUnitEntries(units)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, unitEntries...)
+	}
+	if len(entries) > 0 {
+		archive, err := cpio.Archive(entries...)
+		if err != nil {
+			return fmt.Errorf("failed to archive the ramdisk: %w", err)
+		}
+		c.ramdisk = append(c.ramdisk, archive...)
+	}
+
+	if len(s.KernelArguments) > 0 {
+		c.kargs = append(c.kargs, isoeditor.AppendKernelArguments(s.KernelArguments)...)
+	}
+	return nil
+}
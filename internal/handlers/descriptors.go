@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	descriptorMetalink = "metalink"
+	descriptorTorrent  = "torrent"
+	descriptorChunks   = "chunks"
+
+	// descriptorPieceLength is the length of the pieces hashed in the descriptors
+	descriptorPieceLength = 4 << 20
+	// maxDescriptorErrorSize bounds the error bodies of the artifacts copied to the responses
+	maxDescriptorErrorSize = 64 << 10
+)
+
+// artifactHashes are the hashes of an artifact, and of its pieces of descriptorPieceLength
+type artifactHashes struct {
+	size         int64
+	sha256       []byte
+	sha256Pieces [][]byte
+	sha1Pieces   [][]byte
+}
+
+// hashesCache holds the hashes of the artifacts described, by ETag, the oldest dropped first
+type hashesCache struct {
+	lock   sync.Mutex
+	hashes map[string]*artifactHashes
+	order  []string
+}
+
+func (c *hashesCache) get(etag string) (*artifactHashes, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	hashes, ok := c.hashes[etag]
+	return hashes, ok
+}
+
+func (c *hashesCache) add(etag string, hashes *artifactHashes) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.hashes[etag]; ok {
+		return
+	}
+	if len(c.order) >= maxImageDigests {
+		delete(c.hashes, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.hashes[etag] = hashes
+	c.order = append(c.order, etag)
+}
+
+// hashingResponseWriter hashes the body of a successful response, and keeps the body of the
+// others so that they can be returned as they are
+type hashingResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+
+	hashes *artifactHashes
+	whole  hash.Hash
+	piece  [2]hash.Hash
+	// bytes of the current piece
+	pieceSize int
+}
+
+func newHashingResponseWriter() *hashingResponseWriter {
+	return &hashingResponseWriter{
+		header: http.Header{},
+		hashes: &artifactHashes{},
+		whole:  sha256.New(),
+		// the pieces of the torrents are hashed with SHA-1
+		piece: [2]hash.Hash{sha256.New(), sha1.New()},
+	}
+}
+
+func (w *hashingResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *hashingResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *hashingResponseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.status != http.StatusOK {
+		if w.body.Len() < maxDescriptorErrorSize {
+			w.body.Write(p)
+		}
+		return len(p), nil
+	}
+	written := len(p)
+	w.hashes.size += int64(written)
+	w.whole.Write(p)
+	for len(p) > 0 {
+		n := descriptorPieceLength - w.pieceSize
+		if n > len(p) {
+			n = len(p)
+		}
+		w.piece[0].Write(p[:n])
+		w.piece[1].Write(p[:n])
+		w.pieceSize += n
+		p = p[n:]
+		if w.pieceSize == descriptorPieceLength {
+			w.endPiece()
+		}
+	}
+	return written, nil
+}
+
+func (w *hashingResponseWriter) endPiece() {
+	w.hashes.sha256Pieces = append(w.hashes.sha256Pieces, w.piece[0].Sum(nil))
+	w.hashes.sha1Pieces = append(w.hashes.sha1Pieces, w.piece[1].Sum(nil))
+	w.piece[0].Reset()
+	w.piece[1].Reset()
+	w.pieceSize = 0
+}
+
+// sum returns the hashes of the body once it has been written in full
+func (w *hashingResponseWriter) sum() *artifactHashes {
+	if w.pieceSize > 0 {
+		w.endPiece()
+	}
+	w.hashes.sha256 = w.whole.Sum(nil)
+	return w.hashes
+}
+
+// descriptors serves the metalink, torrent and chunk manifest descriptors of the artifacts of the handler
+type descriptors struct {
+	next       http.Handler
+	mirrorURLs []string
+	hashes     *hashesCache
+	hashing    singleflight.Group
+}
+
+// WithDescriptors returns middleware serving, instead of the artifact requested, its metalink 4
+// (RFC 5854), torrent or chunk manifest descriptor when the descriptor query parameter is
+// metalink, torrent or chunks. The descriptors list the URL of the request and the same path and query on each of the
+// mirrorURLs, with the hashes of the artifact and of its pieces. The artifact is read once to
+// hash it, then its hashes are kept by ETag.
+func WithDescriptors(mirrorURLs []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return &descriptors{
+			next:       next,
+			mirrorURLs: mirrorURLs,
+			hashes:     &hashesCache{hashes: map[string]*artifactHashes{}},
+		}
+	}
+}
+
+func (d *descriptors) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	descriptor := query.Get("descriptor")
+	if !query.Has("descriptor") {
+		d.next.ServeHTTP(w, r)
+		return
+	}
+	if descriptor != descriptorMetalink && descriptor != descriptorTorrent && descriptor != descriptorChunks {
+		httpErrorf(w, http.StatusBadRequest, "invalid descriptor %s, must be %s, %s or %s", descriptor, descriptorMetalink, descriptorTorrent, descriptorChunks)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	query.Del("descriptor")
+
+	// the headers of the artifact tell whether its hashes are known
+	head := newHashingResponseWriter()
+	d.next.ServeHTTP(head, d.artifactRequest(r, http.MethodHead, query))
+	if head.status != http.StatusOK {
+		copyResponse(w, r, head)
+		return
+	}
+	etag := head.header.Get("ETag")
+	hashes, ok := d.hashes.get(etag)
+	if !ok {
+		key := etag
+		if key == "" {
+			key = r.URL.Path + "?" + query.Encode()
+		}
+		result, err, _ := d.hashing.Do(key, func() (interface{}, error) {
+			get := newHashingResponseWriter()
+			d.next.ServeHTTP(get, d.artifactRequest(r, http.MethodGet, query))
+			if get.status != http.StatusOK {
+				return get, nil
+			}
+			hashes := get.sum()
+			if etag != "" && get.header.Get("ETag") == etag {
+				d.hashes.add(etag, hashes)
+				imageDigests.add(etag, base64.StdEncoding.EncodeToString(hashes.sha256))
+			}
+			return hashes, nil
+		})
+		if err != nil {
+			httpErrorf(w, http.StatusInternalServerError, "failed to hash the artifact: %v", err)
+			return
+		}
+		if failed, ok := result.(*hashingResponseWriter); ok {
+			copyResponse(w, r, failed)
+			return
+		}
+		hashes = result.(*artifactHashes)
+	}
+
+	name := path.Base(r.URL.Path)
+	if _, params, err := mime.ParseMediaType(head.header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		name = params["filename"]
+	}
+	modTime, err := http.ParseTime(head.header.Get("Last-Modified"))
+	if err != nil {
+		modTime = time.Now()
+	}
+	artifactPath := r.URL.Path
+	if len(query) > 0 {
+		artifactPath += "?" + query.Encode()
+	}
+	urls := []string{requestBaseURL(r) + artifactPath}
+	for _, mirror := range d.mirrorURLs {
+		urls = append(urls, strings.TrimSuffix(mirror, "/")+artifactPath)
+	}
+
+	var body []byte
+	switch descriptor {
+	case descriptorMetalink:
+		w.Header().Set("Content-Type", "application/metalink4+xml")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.meta4", name))
+		body, err = metalink(name, modTime, hashes, urls)
+	case descriptorTorrent:
+		w.Header().Set("Content-Type", "application/x-bittorrent")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.torrent", name))
+		body = torrent(name, modTime, hashes, urls)
+	case descriptorChunks:
+		w.Header().Set("Content-Type", "application/json")
+		body, err = chunks(name, etag, hashes, urls)
+	}
+	if err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "failed to create the %s descriptor: %v", descriptor, err)
+		return
+	}
+	w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err = w.Write(body); err != nil {
+		log.WithError(err).Warnf("failed to write the %s descriptor of %s", descriptor, r.URL.Path)
+	}
+}
+
+// artifactRequest is the request of the artifact described by r, ignoring its conditions and range
+func (d *descriptors) artifactRequest(r *http.Request, method string, query url.Values) *http.Request {
+	req := r.Clone(r.Context())
+	req.Method = method
+	req.URL.RawQuery = query.Encode()
+	for _, header := range []string{"Range", "If-Range", "If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since"} {
+		req.Header.Del(header)
+	}
+	return req
+}
+
+// copyResponse writes the response recorded by rec, e.g. a 202 or an error of the artifact
+func copyResponse(w http.ResponseWriter, r *http.Request, rec *hashingResponseWriter) {
+	for key, values := range rec.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(rec.status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(rec.body.Bytes())
+	}
+}
+
+// The elements of the metalink 4 documents
+type metalinkDocument struct {
+	XMLName   xml.Name     `xml:"urn:ietf:params:xml:ns:metalink metalink"`
+	Generator string       `xml:"generator"`
+	Published string       `xml:"published"`
+	File      metalinkFile `xml:"file"`
+}
+
+type metalinkFile struct {
+	Name   string         `xml:"name,attr"`
+	Size   int64          `xml:"size"`
+	Hash   metalinkHash   `xml:"hash"`
+	Pieces metalinkPieces `xml:"pieces"`
+	URLs   []metalinkURL  `xml:"url"`
+}
+
+type metalinkHash struct {
+	Type  string `xml:"type,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type metalinkPieces struct {
+	Length int64          `xml:"length,attr"`
+	Type   string         `xml:"type,attr"`
+	Hashes []metalinkHash `xml:"hash"`
+}
+
+type metalinkURL struct {
+	Priority int    `xml:"priority,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// metalink renders the metalink 4 document of the artifact, the URLs in order of priority
+func metalink(name string, modTime time.Time, hashes *artifactHashes, urls []string) ([]byte, error) {
+	file := metalinkFile{
+		Name:   name,
+		Size:   hashes.size,
+		Hash:   metalinkHash{Type: "sha-256", Value: hex.EncodeToString(hashes.sha256)},
+		Pieces: metalinkPieces{Length: descriptorPieceLength, Type: "sha-256"},
+	}
+	for _, piece := range hashes.sha256Pieces {
+		file.Pieces.Hashes = append(file.Pieces.Hashes, metalinkHash{Value: hex.EncodeToString(piece)})
+	}
+	for i, u := range urls {
+		file.URLs = append(file.URLs, metalinkURL{Priority: i + 1, Value: u})
+	}
+	document, err := xml.MarshalIndent(metalinkDocument{
+		Generator: "assisted-image-service",
+		Published: modTime.UTC().Format(time.RFC3339),
+		File:      file,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(document, '\n')...), nil
+}
+
+// torrent renders the trackerless torrent of the artifact, with the URLs as web seeds (BEP 19)
+func torrent(name string, modTime time.Time, hashes *artifactHashes, urls []string) []byte {
+	var buf bytes.Buffer
+	bencode(&buf, map[string]interface{}{
+		"created by":    "assisted-image-service",
+		"creation date": modTime.Unix(),
+		"url-list":      urls,
+		"info": map[string]interface{}{
+			"name":         name,
+			"length":       hashes.size,
+			"piece length": int64(descriptorPieceLength),
+			"pieces":       string(bytes.Join(hashes.sha1Pieces, nil)),
+		},
+	})
+	return buf.Bytes()
+}
+
+// bencode writes the value, made of strings, integers, lists and dictionaries, bencoded
+func bencode(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		fmt.Fprintf(buf, "%d:%s", len(v), v)
+	case int64:
+		fmt.Fprintf(buf, "i%de", v)
+	case []string:
+		buf.WriteByte('l')
+		for _, s := range v {
+			bencode(buf, s)
+		}
+		buf.WriteByte('e')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('d')
+		for _, key := range keys {
+			bencode(buf, key)
+			bencode(buf, v[key])
+		}
+		buf.WriteByte('e')
+	default:
+		panic(fmt.Sprintf("can't bencode %T", value))
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithDescriptors", func() {
+	var (
+		content  []byte
+		etag     string
+		gets     int
+		status   int
+		server   *httptest.Server
+		modTime  = time.Date(2022, 4, 22, 18, 11, 9, 0, time.UTC)
+		mirror   = "https://mirror.example.com/"
+		artifact = "/images/bf25292a-dddd-49dc-ab9c-3fb4c1f07071"
+	)
+
+	BeforeEach(func() {
+		content = bytes.Repeat([]byte("0123456789abcdef"), (descriptorPieceLength+1024)/16)
+		etag = fmt.Sprintf("%q", "descriptorstest")
+		gets = 0
+		status = http.StatusOK
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Query().Has("descriptor")).To(BeFalse())
+			if status != http.StatusOK {
+				w.Header().Set("Retry-After", "60")
+				w.WriteHeader(status)
+				return
+			}
+			if r.Method == http.MethodGet {
+				gets++
+			}
+			w.Header().Set("Content-Disposition", "attachment; filename=full.iso")
+			serveImage(w, r, "full.iso", modTime, bytes.NewReader(content), etag)
+		})
+		server = httptest.NewServer(WithDescriptors([]string{mirror})(next))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	get := func(query string) *http.Response {
+		resp, err := server.Client().Get(fmt.Sprintf("%s%s?%s", server.URL, artifact, query))
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	It("serves the metalink of the artifact", func() {
+		resp := get("version=4.11&type=full-iso&descriptor=metalink")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/metalink4+xml"))
+		Expect(resp.Header.Get("Content-Disposition")).To(Equal("attachment; filename=full.iso.meta4"))
+
+		document := metalinkDocument{}
+		Expect(xml.NewDecoder(resp.Body).Decode(&document)).To(Succeed())
+		Expect(document.Published).To(Equal("2022-04-22T18:11:09Z"))
+		Expect(document.File.Name).To(Equal("full.iso"))
+		Expect(document.File.Size).To(Equal(int64(len(content))))
+		sum := sha256.Sum256(content)
+		Expect(document.File.Hash).To(Equal(metalinkHash{Type: "sha-256", Value: hex.EncodeToString(sum[:])}))
+		Expect(document.File.Pieces.Length).To(Equal(int64(descriptorPieceLength)))
+		Expect(document.File.Pieces.Hashes).To(HaveLen(2))
+		lastPiece := sha256.Sum256(content[descriptorPieceLength:])
+		Expect(document.File.Pieces.Hashes[1].Value).To(Equal(hex.EncodeToString(lastPiece[:])))
+		Expect(document.File.URLs).To(Equal([]metalinkURL{
+			{Priority: 1, Value: fmt.Sprintf("%s%s?type=full-iso&version=4.11", server.URL, artifact)},
+			{Priority: 2, Value: fmt.Sprintf("https://mirror.example.com%s?type=full-iso&version=4.11", artifact)},
+		}))
+	})
+
+	It("serves the torrent of the artifact", func() {
+		resp := get("version=4.11&type=full-iso&descriptor=torrent")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/x-bittorrent"))
+		Expect(resp.Header.Get("Content-Disposition")).To(Equal("attachment; filename=full.iso.torrent"))
+
+		torrent, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		firstPiece := sha1.Sum(content[:descriptorPieceLength]) //nolint:gosec
+		lastPiece := sha1.Sum(content[descriptorPieceLength:])  //nolint:gosec
+		selfURL := fmt.Sprintf("%s%s?type=full-iso&version=4.11", server.URL, artifact)
+		mirrorURL := fmt.Sprintf("https://mirror.example.com%s?type=full-iso&version=4.11", artifact)
+		Expect(string(torrent)).To(Equal(fmt.Sprintf(
+			"d10:created by22:assisted-image-service13:creation datei%de4:infod6:lengthi%de4:name8:full.iso12:piece lengthi%de6:pieces40:%s%se8:url-listl%d:%s%d:%see",
+			modTime.Unix(), len(content), descriptorPieceLength, firstPiece[:], lastPiece[:], len(selfURL), selfURL, len(mirrorURL), mirrorURL,
+		)))
+	})
+
+	It("serves the chunk manifest of the artifact", func() {
+		resp := get("version=4.11&descriptor=chunks")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+
+		manifest := chunkManifest{}
+		Expect(json.NewDecoder(resp.Body).Decode(&manifest)).To(Succeed())
+		Expect(manifest.Name).To(Equal("full.iso"))
+		Expect(manifest.Size).To(Equal(int64(len(content))))
+		Expect(manifest.ETag).To(Equal(etag))
+		Expect(manifest.URLs).To(HaveLen(2))
+		Expect(manifest.Chunks).To(HaveLen(2))
+		Expect(manifest.Chunks[1].Offset).To(Equal(int64(descriptorPieceLength)))
+		Expect(manifest.Chunks[1].Length).To(Equal(int64(len(content) - descriptorPieceLength)))
+
+		// the chunks are downloaded with Range requests and verified
+		req, err := http.NewRequest(http.MethodGet, manifest.URLs[0], nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", manifest.Chunks[1].Offset, manifest.Chunks[1].Offset+manifest.Chunks[1].Length-1))
+		req.Header.Set("If-Range", manifest.ETag)
+		resp, err = server.Client().Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusPartialContent))
+		data, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		sum := sha256.Sum256(data)
+		Expect(hex.EncodeToString(sum[:])).To(Equal(manifest.Chunks[1].SHA256))
+	})
+
+	It("reads the artifact once", func() {
+		Expect(get("descriptor=metalink").StatusCode).To(Equal(http.StatusOK))
+		Expect(get("descriptor=torrent").StatusCode).To(Equal(http.StatusOK))
+		Expect(gets).To(Equal(1))
+
+		// the digest is known once the artifact has been hashed
+		resp, err := server.Client().Head(server.URL + artifact)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Digest")).To(HavePrefix("sha-256="))
+	})
+
+	It("returns the responses of the artifact other than 200", func() {
+		status = http.StatusAccepted
+		resp := get("descriptor=metalink")
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+		Expect(resp.Header.Get("Retry-After")).To(Equal("60"))
+	})
+
+	It("fails for unknown descriptors", func() {
+		resp := get("descriptor=magnet")
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.TrimSpace(string(body))).To(HavePrefix("invalid descriptor magnet"))
+	})
+
+	It("serves the artifact without descriptor", func() {
+		resp := get("version=4.11")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(io.ReadAll(resp.Body)).To(Equal(content))
+	})
+})
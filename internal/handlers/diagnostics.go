@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openshift/assisted-image-service/pkg/logging"
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+// streamInfo is a response being written, as reported by /debug/streams
+type streamInfo struct {
+	Method string `json:"method"`
+	// Path is redacted, as it may contain the token of the request
+	Path string `json:"path"`
+	// Client is the peer of the request, and ForwardedFor the addresses its proxies forwarded
+	Client       string    `json:"client"`
+	ForwardedFor string    `json:"forwarded_for,omitempty"`
+	Started      time.Time `json:"started"`
+	Written      int64     `json:"written"`
+}
+
+type activeStream struct {
+	info    streamInfo
+	written atomic.Int64
+}
+
+// streamRegistry holds the responses being written by the handlers wrapped by WithStreamTracking
+type streamRegistry struct {
+	lock    sync.Mutex
+	next    uint64
+	streams map[uint64]*activeStream
+}
+
+var activeStreams = &streamRegistry{streams: map[uint64]*activeStream{}}
+
+func (s *streamRegistry) add(stream *activeStream) uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.next++
+	s.streams[s.next] = stream
+	return s.next
+}
+
+func (s *streamRegistry) remove(id uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.streams, id)
+}
+
+// list returns the streams, the oldest first
+func (s *streamRegistry) list() []streamInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	streams := make([]streamInfo, 0, len(s.streams))
+	for _, stream := range s.streams {
+		info := stream.info
+		info.Written = stream.written.Load()
+		streams = append(streams, info)
+	}
+	sort.Slice(streams, func(i, j int) bool {
+		return streams[i].Started.Before(streams[j].Started)
+	})
+	return streams
+}
+
+// trackedWriter counts the bytes written to the response of a stream
+type trackedWriter struct {
+	http.ResponseWriter
+	stream *activeStream
+}
+
+func (w *trackedWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.stream.written.Add(int64(n))
+	return n, err
+}
+
+func (w *trackedWriter) ReadFrom(r io.Reader) (int64, error) {
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = overlay.Copy(struct{ io.Writer }{w.ResponseWriter}, r)
+	}
+	w.stream.written.Add(n)
+	return n, err
+}
+
+func (w *trackedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *trackedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// WithStreamTracking returns middleware that lists the responses of next being written in the
+// diagnostics served by NewDiagnosticsHandler
+func WithStreamTracking(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stream := &activeStream{info: streamInfo{
+			Method:       r.Method,
+			Path:         logging.Redact(r.URL.Path),
+			Client:       remoteHost(r),
+			ForwardedFor: strings.Join(r.Header.Values("X-Forwarded-For"), ", "),
+			Started:      time.Now(),
+		}}
+		id := activeStreams.add(stream)
+		defer activeStreams.remove(id)
+		next.ServeHTTP(&trackedWriter{ResponseWriter: w, stream: stream}, r)
+	})
+}
+
+// runtimeStats are the memory and GC statistics served by /debug/runtime
+type runtimeStats struct {
+	Goroutines   int                     `json:"goroutines"`
+	HeapAlloc    uint64                  `json:"heap_alloc"`
+	HeapInuse    uint64                  `json:"heap_inuse"`
+	HeapIdle     uint64                  `json:"heap_idle"`
+	HeapReleased uint64                  `json:"heap_released"`
+	HeapObjects  uint64                  `json:"heap_objects"`
+	Sys          uint64                  `json:"sys"`
+	NextGC       uint64                  `json:"next_gc"`
+	NumGC        uint32                  `json:"num_gc"`
+	LastGC       time.Time               `json:"last_gc"`
+	PauseTotal   time.Duration           `json:"pause_total_ns"`
+	BufferPool   overlay.BufferPoolStats `json:"buffer_pool"`
+}
+
+func readRuntimeStats() runtimeStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return runtimeStats{
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    m.HeapAlloc,
+		HeapInuse:    m.HeapInuse,
+		HeapIdle:     m.HeapIdle,
+		HeapReleased: m.HeapReleased,
+		HeapObjects:  m.HeapObjects,
+		Sys:          m.Sys,
+		NextGC:       m.NextGC,
+		NumGC:        m.NumGC,
+		LastGC:       time.Unix(0, int64(m.LastGC)).UTC(),
+		PauseTotal:   time.Duration(m.PauseTotalNs),
+		BufferPool:   overlay.GetBufferPoolStats(),
+	}
+}
+
+// defaultCPUProfileDuration is how long the CPU is profiled without the seconds parameter
+const defaultCPUProfileDuration = 30 * time.Second
+
+// serveProfile serves the profiles of runtime/pprof in the format of net/http/pprof, so that
+// they can be read by go tool pprof. net/http/pprof isn't used as it registers its handlers on
+// the default mux, which is served on the public ports.
+func serveProfile(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/debug/pprof/")
+	if name == "" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, profile := range pprof.Profiles() {
+			fmt.Fprintf(w, "%s: %d\n", profile.Name(), profile.Count())
+		}
+		fmt.Fprintln(w, "profile: CPU profile, for ?seconds=30 by default")
+		return
+	}
+
+	if name == "profile" {
+		duration := defaultCPUProfileDuration
+		if seconds, err := strconv.Atoi(r.URL.Query().Get("seconds")); err == nil && seconds > 0 {
+			duration = time.Duration(seconds) * time.Second
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if err := pprof.StartCPUProfile(w); err != nil {
+			w.Header().Del("Content-Type")
+			httpErrorf(w, http.StatusInternalServerError, "failed to profile the CPU: %v", err)
+			return
+		}
+		select {
+		case <-time.After(duration):
+		case <-r.Context().Done():
+		}
+		pprof.StopCPUProfile()
+		return
+	}
+
+	profile := pprof.Lookup(name)
+	if profile == nil {
+		httpErrorf(w, http.StatusNotFound, "unknown profile %s", name)
+		return
+	}
+	debug, _ := strconv.Atoi(r.URL.Query().Get("debug"))
+	if name == "heap" && r.URL.Query().Get("gc") != "" {
+		runtime.GC()
+	}
+	if debug == 0 {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	_ = profile.WriteTo(w, debug)
+}
+
+// streamsReport is the content of /debug/streams
+type streamsReport struct {
+	Streams []streamInfo `json:"streams"`
+	// FileDescriptors maps the open file descriptors to what they refer to, a file path or a
+	// socket, when the process can list them
+	FileDescriptors map[string]string `json:"file_descriptors,omitempty"`
+	Error           string            `json:"error,omitempty"`
+}
+
+// procFDDir lists the file descriptors of the process on Linux
+const procFDDir = "/proc/self/fd"
+
+func openFileDescriptors() (map[string]string, error) {
+	entries, err := os.ReadDir(procFDDir)
+	if err != nil {
+		return nil, err
+	}
+	fds := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		// the descriptor reading the directory is closed by now
+		if target, err := os.Readlink(filepath.Join(procFDDir, entry.Name())); err == nil {
+			fds[entry.Name()] = target
+		}
+	}
+	return fds, nil
+}
+
+func writeDiagnostics(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
+
+// NewDiagnosticsHandler returns the handler of the diagnostics of the service: the profiles of
+// runtime/pprof under /debug/pprof/, the memory and GC statistics at /debug/runtime, and the
+// streams being written with the open file descriptors at /debug/streams. It exposes the
+// internals of the service and must only be reachable by its operators.
+func NewDiagnosticsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", serveProfile)
+	mux.HandleFunc("/debug/runtime", func(w http.ResponseWriter, r *http.Request) {
+		writeDiagnostics(w, readRuntimeStats())
+	})
+	mux.HandleFunc("/debug/streams", func(w http.ResponseWriter, r *http.Request) {
+		report := streamsReport{Streams: activeStreams.list()}
+		fds, err := openFileDescriptors()
+		if err != nil {
+			report.Error = err.Error()
+		}
+		report.FileDescriptors = fds
+		writeDiagnostics(w, report)
+	})
+	return mux
+}
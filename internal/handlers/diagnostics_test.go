@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewDiagnosticsHandler", func() {
+	var diagnostics *httptest.Server
+
+	BeforeEach(func() {
+		diagnostics = httptest.NewServer(NewDiagnosticsHandler())
+	})
+
+	AfterEach(func() {
+		diagnostics.Close()
+	})
+
+	get := func(path string) *http.Response {
+		resp, err := diagnostics.Client().Get(diagnostics.URL + path)
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	It("lists the streams being written", func() {
+		written := make(chan struct{})
+		release := make(chan struct{})
+		streaming := httptest.NewServer(WithStreamTracking(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := io.Copy(w, strings.NewReader("0123456789"))
+			Expect(err).NotTo(HaveOccurred())
+			close(written)
+			<-release
+		})))
+		defer streaming.Close()
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			resp, err := streaming.Client().Get(streaming.URL + "/bytoken/secret/images/stream")
+			Expect(err).NotTo(HaveOccurred())
+			resp.Body.Close()
+		}()
+		<-written
+
+		resp := get("/debug/streams")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		report := streamsReport{}
+		Expect(json.NewDecoder(resp.Body).Decode(&report)).To(Succeed())
+		Expect(report.Streams).To(HaveLen(1))
+		Expect(report.Streams[0].Path).To(Equal("/bytoken/<redacted>/images/stream"))
+		Expect(report.Streams[0].Client).To(Equal("127.0.0.1"))
+		Expect(report.Streams[0].Written).To(Equal(int64(10)))
+		Expect(report.FileDescriptors).NotTo(BeEmpty())
+
+		close(release)
+		<-done
+		Eventually(activeStreams.list).Should(BeEmpty())
+	})
+
+	It("serves the runtime statistics", func() {
+		resp := get("/debug/runtime")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		stats := runtimeStats{}
+		Expect(json.NewDecoder(resp.Body).Decode(&stats)).To(Succeed())
+		Expect(stats.Goroutines).To(BeNumerically(">", 0))
+		Expect(stats.HeapAlloc).To(BeNumerically(">", 0))
+	})
+
+	It("serves the pprof profiles", func() {
+		resp := get("/debug/pprof/goroutine?debug=1")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(HavePrefix("goroutine profile:"))
+
+		resp = get("/debug/pprof/heap")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/octet-stream"))
+
+		Expect(get("/debug/pprof/unknown").StatusCode).To(Equal(http.StatusNotFound))
+	})
+})
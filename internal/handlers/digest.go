@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxImageDigests bounds the digests kept by imageDigests
+const maxImageDigests = 4096
+
+// digestCache holds the SHA-256 of the images served in full, by ETag. Computing the digest of a
+// customized image means reading all of it, so it is learned while serving it.
+type digestCache struct {
+	lock    sync.Mutex
+	digests map[string]string
+	// order of insertion, the oldest digests are dropped first
+	order []string
+}
+
+var imageDigests = &digestCache{digests: map[string]string{}}
+
+func (c *digestCache) get(etag string) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	digest, ok := c.digests[etag]
+	return digest, ok
+}
+
+func (c *digestCache) add(etag, digest string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.digests[etag]; ok {
+		return
+	}
+	if len(c.order) >= maxImageDigests {
+		delete(c.digests, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.digests[etag] = digest
+	c.order = append(c.order, etag)
+}
+
+// hashingReader hashes the content read from the start of the image
+type hashingReader struct {
+	io.ReadSeeker
+	hash hash.Hash
+	// bytes hashed, while the content is read sequentially from the start
+	hashed     int64
+	sequential bool
+	size       int64
+}
+
+func (h *hashingReader) Read(p []byte) (int, error) {
+	n, err := h.ReadSeeker.Read(p)
+	if h.sequential {
+		h.hash.Write(p[:n])
+		h.hashed += int64(n)
+	}
+	return n, err
+}
+
+func (h *hashingReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := h.ReadSeeker.Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+	if whence == io.SeekEnd && offset == 0 {
+		h.size = pos
+	}
+	// http.ServeContent seeks to the end for the size, then back to the start
+	h.sequential = pos == 0
+	h.hash.Reset()
+	h.hashed = 0
+	return pos, nil
+}
+
+// serveImage serves the content with http.ServeContent, answering Range, conditional and HEAD
+// requests with the size of the content, its ETag and, when it is known, its digest
+func serveImage(w http.ResponseWriter, r *http.Request, name string, modTime time.Time, content io.ReadSeeker, etag string) {
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("ETag", etag)
+	digest, ok := imageDigests.get(etag)
+	if ok {
+		w.Header().Set("Digest", "sha-256="+digest)
+		http.ServeContent(&sendfileWriter{ResponseWriter: w}, r, name, modTime, content)
+		return
+	}
+	if r.Method != http.MethodGet || r.Header.Get("Range") != "" {
+		http.ServeContent(&sendfileWriter{ResponseWriter: w}, r, name, modTime, content)
+		return
+	}
+	// the first download is read to compute the digest
+	hr := &hashingReader{ReadSeeker: content, hash: sha256.New(), sequential: true, size: -1}
+	http.ServeContent(w, r, name, modTime, hr)
+	if hr.sequential && hr.hashed == hr.size {
+		imageDigests.add(etag, base64.StdEncoding.EncodeToString(hr.hash.Sum(nil)))
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"github.com/ulikunitz/xz"
+
+	"github.com/openshift/assisted-image-service/pkg/diskeditor"
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+)
+
+// compressionXZ is the compression query parameter of the disk images compressed with xz
+const compressionXZ = "xz"
+
+// diskImageHandler serves the disk image of the version with the discovery ignition of the image
+// embedded in its boot partition, named <image_id>-discovery.<extension>
+type diskImageHandler struct {
+	ImageStore imagestore.ImageStore
+	client     *AssistedServiceClient
+	editor     diskeditor.Editor
+	workDir    string
+	imageType  string
+	extension  string
+}
+
+var _ http.Handler = &diskImageHandler{}
+
+func (h *diskImageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	imageID := chi.URLParam(r, "image_id")
+
+	version := r.URL.Query().Get("version")
+	if version == "" {
+		httpErrorf(w, http.StatusBadRequest, "'version' parameter required for %s download", h.imageType)
+		return
+	}
+
+	arch := r.URL.Query().Get("arch")
+	if arch == "" {
+		arch = defaultArch
+	}
+
+	compression := r.URL.Query().Get("compression")
+	if compression != "" && compression != compressionXZ {
+		httpErrorf(w, http.StatusBadRequest, "unsupported compression %q, expected %s", compression, compressionXZ)
+		return
+	}
+
+	if !h.ImageStore.HaveVersion(version, arch) {
+		httpErrorf(w, http.StatusNotFound, "version for %s %s, not found", version, arch)
+		return
+	}
+
+	// the discovery ignition of the disk images is the one of the full ISO, fetching it
+	// authenticates the request before the usage of the version is recorded
+	ignition, lastModified, code, err := h.client.ignitionContent(r, imageID, imagestore.ImageTypeFull)
+	if err != nil {
+		httpErrorf(w, code, "error retrieving ignition content: %v", err)
+		return
+	}
+
+	if !h.ImageStore.Available(version, arch) {
+		respondVersionDownloading(w, version, arch)
+		return
+	}
+
+	imagePath := h.ImageStore.PathForParams(h.imageType, version, arch)
+	if _, err := os.Stat(imagePath); err != nil {
+		httpErrorf(w, http.StatusNotFound, "no %s image for version %s %s", h.imageType, version, arch)
+		return
+	}
+
+	output, err := os.CreateTemp(h.workDir, "disk-image")
+	if err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "failed to create the %s image: %v", h.imageType, err)
+		return
+	}
+	output.Close()
+	defer os.Remove(output.Name())
+
+	if err = h.editor.EmbedIgnition(imagePath, output.Name(), ignition.Config); err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "failed to embed the ignition in the %s image: %v", h.imageType, err)
+		return
+	}
+
+	fileName := fmt.Sprintf("%s-discovery.%s", imageID, h.extension)
+	outputPath := output.Name()
+	etag := imageETag(imagePath, ignition.Config)
+	if compression == compressionXZ {
+		outputPath = output.Name() + "." + compressionXZ
+		defer os.Remove(outputPath)
+		if err = compressXZ(output.Name(), outputPath); err != nil {
+			httpErrorf(w, http.StatusInternalServerError, "failed to compress the %s image: %v", h.imageType, err)
+			return
+		}
+		fileName += "." + compressionXZ
+		etag = imageETag(imagePath, ignition.Config, []byte(compressionXZ))
+	}
+
+	f, err := os.Open(outputPath)
+	if err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "failed to open the %s image: %v", h.imageType, err)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	modTime, err := http.ParseTime(lastModified)
+	if err != nil {
+		log.Warnf("Error parsing last modified time %s: %v", lastModified, err)
+		modTime = time.Now()
+	}
+	serveImage(w, r, fileName, modTime, f, etag)
+}
+
+func compressXZ(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	xzWriter, err := xz.NewWriter(out)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(xzWriter, in); err != nil {
+		return err
+	}
+	if err = xzWriter.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
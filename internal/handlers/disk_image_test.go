@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/ulikunitz/xz"
+
+	"github.com/openshift/assisted-image-service/pkg/diskeditor"
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+)
+
+var _ = Describe("diskImageHandler", func() {
+	var (
+		ctrl            *gomock.Controller
+		mockImageStore  *imagestore.MockImageStore
+		mockEditor      *diskeditor.MockEditor
+		imageID         = "bf25292a-dddd-49dc-ab9c-3fb4c1f07071"
+		assistedServer  *ghttp.Server
+		ignitionContent = []byte(`{"ignition":{"version":"3.1.0"}}`)
+		server          *httptest.Server
+		workDir         string
+		qcow2Path       string
+	)
+
+	BeforeEach(func() {
+		var err error
+		workDir, err = os.MkdirTemp("", "diskImageTest")
+		Expect(err).NotTo(HaveOccurred())
+		qcow2Path = filepath.Join(workDir, "rhcos-qcow2-4.9-49.84.202110081407-0-x86_64.qcow2")
+
+		ctrl = gomock.NewController(GinkgoT())
+		mockImageStore = imagestore.NewMockImageStore(ctrl)
+		mockEditor = diskeditor.NewMockEditor(ctrl)
+
+		assistedServer = ghttp.NewServer()
+		u, err := url.Parse(assistedServer.URL())
+		Expect(err).NotTo(HaveOccurred())
+		asc, err := NewAssistedServiceClient(u.Scheme, u.Host, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		handler := &ImageHandler{
+			qcow2: &diskImageHandler{
+				ImageStore: mockImageStore,
+				client:     asc,
+				editor:     mockEditor,
+				workDir:    workDir,
+				imageType:  imagestore.ImageTypeQcow2,
+				extension:  "qcow2",
+			},
+			raw: &diskImageHandler{
+				ImageStore: mockImageStore,
+				client:     asc,
+				editor:     mockEditor,
+				workDir:    workDir,
+				imageType:  imagestore.ImageTypeRaw,
+				extension:  "img",
+			},
+			limiter: newStreamLimiter(StreamLimits{}),
+		}
+		server = httptest.NewServer(handler.router(1))
+	})
+
+	AfterEach(func() {
+		assistedServer.Close()
+		server.Close()
+		Expect(os.RemoveAll(workDir)).To(Succeed())
+	})
+
+	mockImage := func(available bool) {
+		mockImageStore.EXPECT().HaveVersion("4.9", "x86_64").Return(true).AnyTimes()
+		mockImageStore.EXPECT().Available("4.9", "x86_64").Return(available).AnyTimes()
+		mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeQcow2, "4.9", "x86_64").Return(qcow2Path).AnyTimes()
+	}
+
+	withIgnition := func() {
+		assistedServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(fileRouteFormat, imageID), "discovery_iso_type=full-iso&file_name=discovery.ign"),
+				ghttp.RespondWith(http.StatusOK, ignitionContent, http.Header{"Last-Modified": []string{"Fri, 22 Apr 2022 18:11:09 GMT"}}),
+			),
+		)
+	}
+
+	get := func() *http.Response {
+		resp, err := server.Client().Get(fmt.Sprintf("%s/images/%s/qcow2?version=4.9&arch=x86_64", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	It("serves the qcow2 image with the ignition embedded", func() {
+		mockImage(true)
+		Expect(os.WriteFile(qcow2Path, []byte("QFI\xfbbase"), 0600)).To(Succeed())
+		withIgnition()
+		var outputPath string
+		mockEditor.EXPECT().EmbedIgnition(qcow2Path, gomock.Any(), ignitionContent).DoAndReturn(func(_, output string, _ []byte) error {
+			outputPath = output
+			return os.WriteFile(output, []byte("QFI\xfbcustomized"), 0600)
+		})
+
+		resp := get()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Disposition")).To(Equal(fmt.Sprintf("attachment; filename=%s-discovery.qcow2", imageID)))
+		Expect(resp.Header.Get("Last-Modified")).To(Equal("Fri, 22 Apr 2022 18:11:09 GMT"))
+		Expect(resp.Header.Get("ETag")).To(Equal(imageETag(qcow2Path, ignitionContent)))
+		Expect(io.ReadAll(resp.Body)).To(Equal([]byte("QFI\xfbcustomized")))
+		Expect(outputPath).NotTo(BeAnExistingFile())
+	})
+
+	It("serves the raw image compressed with xz", func() {
+		rawPath := filepath.Join(workDir, "rhcos-raw-4.9-49.84.202110081407-0-x86_64.raw")
+		mockImageStore.EXPECT().HaveVersion("4.9", "x86_64").Return(true)
+		mockImageStore.EXPECT().Available("4.9", "x86_64").Return(true)
+		mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeRaw, "4.9", "x86_64").Return(rawPath)
+		Expect(os.WriteFile(rawPath, []byte("base"), 0600)).To(Succeed())
+		withIgnition()
+		mockEditor.EXPECT().EmbedIgnition(rawPath, gomock.Any(), ignitionContent).DoAndReturn(func(_, output string, _ []byte) error {
+			return os.WriteFile(output, []byte("customized"), 0600)
+		})
+
+		resp, err := server.Client().Get(fmt.Sprintf("%s/images/%s/raw?version=4.9&arch=x86_64&compression=xz", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Disposition")).To(Equal(fmt.Sprintf("attachment; filename=%s-discovery.img.xz", imageID)))
+		Expect(resp.Header.Get("ETag")).To(Equal(imageETag(rawPath, ignitionContent, []byte("xz"))))
+		xzReader, err := xz.NewReader(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(io.ReadAll(xzReader)).To(Equal([]byte("customized")))
+		Expect(os.ReadDir(workDir)).To(HaveLen(1))
+	})
+
+	It("rejects the unsupported compressions", func() {
+		resp, err := server.Client().Get(fmt.Sprintf("%s/images/%s/raw?version=4.9&compression=gzip", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("fails when the ignition can't be embedded", func() {
+		mockImage(true)
+		Expect(os.WriteFile(qcow2Path, []byte("QFI\xfbbase"), 0600)).To(Succeed())
+		withIgnition()
+		mockEditor.EXPECT().EmbedIgnition(qcow2Path, gomock.Any(), ignitionContent).Return(errors.New("no boot partition"))
+
+		resp := get()
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+		entries, err := os.ReadDir(workDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("returns not found when the version has no qcow2 image", func() {
+		mockImage(true)
+		withIgnition()
+		Expect(get().StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("returns not found for unknown versions", func() {
+		mockImageStore.EXPECT().HaveVersion("4.9", "x86_64").Return(false)
+		Expect(get().StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("asks to retry while the images of the version are downloaded", func() {
+		mockImage(false)
+		withIgnition()
+		resp := get()
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+		Expect(resp.Header.Get("Retry-After")).To(Equal(retryAfter))
+	})
+
+	It("doesn't record the usage of the version when the request fails to authenticate", func() {
+		mockImageStore.EXPECT().HaveVersion("4.9", "x86_64").Return(true)
+		assistedServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(fileRouteFormat, imageID)),
+				ghttp.RespondWith(http.StatusUnauthorized, ""),
+			),
+		)
+		Expect(get().StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("requires a version", func() {
+		resp, err := server.Client().Get(fmt.Sprintf("%s/images/%s/qcow2", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
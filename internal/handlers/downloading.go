@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// retryAfter is the Retry-After, in seconds, of the requests of versions being downloaded
+const retryAfter = "30"
+
+var errVersionDownloading = errors.New("the images of the version are being downloaded")
+
+// respondVersionDownloading asks the client to retry once the images of the version are downloaded
+func respondVersionDownloading(w http.ResponseWriter, version, arch string) {
+	log.Infof("The images of version %s %s are being downloaded", version, arch)
+	w.Header().Set("Retry-After", retryAfter)
+	http.Error(w, fmt.Sprintf("The images of version %s %s are being downloaded, retry later", version, arch), http.StatusAccepted)
+}
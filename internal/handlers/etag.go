@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+// imageETag returns a strong ETag of an image generated from the base image and the inputs of
+// its customization. The base image is identified by its file name, which holds its version,
+// build and architecture, and the digest of its content, so that the replicas agree on the ETags
+// and an image replaced in place changes them. The generation being deterministic, the same ETag
+// means the same bytes: clients and proxies revalidate their copies with If-None-Match, and
+// interrupted downloads resume with Range and If-Range requests.
+func imageETag(basePath string, inputs ...[]byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", filepath.Base(basePath))
+	if digest, err := baseImageDigest(basePath); err == nil {
+		fmt.Fprintf(h, "%x\n", digest)
+	}
+	for _, input := range inputs {
+		fmt.Fprintf(h, "%d\n", len(input))
+		h.Write(input)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// baseImageDigests are the digests of the base images, by path. They are computed once per
+// size and modification time of the image, and dropped with the image.
+var baseImageDigests = struct {
+	lock      sync.Mutex
+	entries   map[string]*imageDigest
+	computing singleflight.Group
+}{entries: map[string]*imageDigest{}}
+
+type imageDigest struct {
+	size    int64
+	modTime time.Time
+	digest  []byte
+}
+
+// baseImageDigest returns the sha256 of the content of the base image
+func baseImageDigest(basePath string) ([]byte, error) {
+	info, err := os.Stat(basePath)
+	if err != nil {
+		baseImageDigests.lock.Lock()
+		delete(baseImageDigests.entries, basePath)
+		baseImageDigests.lock.Unlock()
+		return nil, err
+	}
+	baseImageDigests.lock.Lock()
+	entry, ok := baseImageDigests.entries[basePath]
+	baseImageDigests.lock.Unlock()
+	if ok && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		return entry.digest, nil
+	}
+
+	key := fmt.Sprintf("%s\n%d\n%d", basePath, info.Size(), info.ModTime().UnixNano())
+	digest, err, _ := baseImageDigests.computing.Do(key, func() (interface{}, error) {
+		f, err := os.Open(basePath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return nil, err
+		}
+		digest := h.Sum(nil)
+		baseImageDigests.lock.Lock()
+		baseImageDigests.entries[basePath] = &imageDigest{size: info.Size(), modTime: info.ModTime(), digest: digest}
+		baseImageDigests.lock.Unlock()
+		return digest, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return digest.([]byte), nil
+}
+
+// kargsETagInput is the input of the ETags for the kernel arguments operations of a customization
+func kargsETagInput(kargs isoeditor.KernelArguments) []byte {
+	if len(kargs) == 0 {
+		return nil
+	}
+	// the operations only hold strings
+	data, _ := json.Marshal(kargs)
+	return data
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("imageETag", func() {
+	var dirs []string
+
+	BeforeEach(func() {
+		dirs = nil
+		for i := 0; i < 2; i++ {
+			dir, err := os.MkdirTemp("", "etagTest")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(dir, "rhcos-full-iso-4.8-48.84-x86_64.iso"), []byte("isocontent"), 0600)).To(Succeed())
+			dirs = append(dirs, dir)
+		}
+	})
+
+	AfterEach(func() {
+		for _, dir := range dirs {
+			os.RemoveAll(dir)
+		}
+	})
+
+	It("is the same for the same customization of the same image", func() {
+		etag := imageETag(filepath.Join(dirs[0], "rhcos-full-iso-4.8-48.84-x86_64.iso"), []byte("ignition"), nil)
+		Expect(etag).To(MatchRegexp(`^"[0-9a-f]{32}"$`))
+		Expect(imageETag(filepath.Join(dirs[1], "rhcos-full-iso-4.8-48.84-x86_64.iso"), []byte("ignition"), nil)).To(Equal(etag))
+	})
+
+	It("changes with the customization", func() {
+		isoPath := filepath.Join(dirs[0], "rhcos-full-iso-4.8-48.84-x86_64.iso")
+		etag := imageETag(isoPath, []byte("ignition"), nil)
+		Expect(imageETag(isoPath, []byte("other"), nil)).NotTo(Equal(etag))
+		Expect(imageETag(isoPath, nil, []byte("ignition"))).NotTo(Equal(etag))
+		Expect(os.WriteFile(isoPath, []byte("rebuilt"), 0600)).To(Succeed())
+		Expect(imageETag(isoPath, []byte("ignition"), nil)).NotTo(Equal(etag))
+	})
+
+	It("changes when the image is replaced with one of the same size", func() {
+		isoPath := filepath.Join(dirs[0], "rhcos-full-iso-4.8-48.84-x86_64.iso")
+		etag := imageETag(isoPath, []byte("ignition"), nil)
+		Expect(os.WriteFile(isoPath, []byte("isoContent"), 0600)).To(Succeed())
+		// the modification time may have the granularity of the file system
+		Expect(os.Chtimes(isoPath, time.Now(), time.Now().Add(time.Minute))).To(Succeed())
+		Expect(imageETag(isoPath, []byte("ignition"), nil)).NotTo(Equal(etag))
+	})
+})
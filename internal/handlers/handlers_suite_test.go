@@ -18,6 +18,14 @@ func TestHandlers(t *testing.T) {
 	RunSpecs(t, "handlers")
 }
 
+const testGrubConfig = `
+menuentry 'RHEL CoreOS (Live)' --class fedora --class gnu-linux --class gnu --class os {
+	linux /images/pxeboot/vmlinuz random.trust_cpu=on coreos.liveiso=rhcos-411.86.202210041459-0 ignition.firstboot ignition.platform.id=metal
+###################### COREOS_KARG_EMBED_AREA
+	initrd /images/pxeboot/initrd.img /images/ignition.img
+}
+`
+
 func createTestISO() string {
 	filesDir, err := os.MkdirTemp("", "isotest")
 	Expect(err).ToNot(HaveOccurred())
@@ -31,6 +39,8 @@ func createTestISO() string {
 	Expect(os.WriteFile(filepath.Join(filesDir, "images/pxeboot/rootfs.img"), []byte("this is rootfs"), 0600)).To(Succeed())
 	Expect(os.WriteFile(filepath.Join(filesDir, "images/pxeboot/vmlinuz"), []byte("this is kernel"), 0600)).To(Succeed())
 	Expect(os.WriteFile(filepath.Join(filesDir, "images/pxeboot/initrd.img"), []byte("this is initrd"), 0600)).To(Succeed())
+	Expect(os.MkdirAll(filepath.Join(filesDir, "EFI/redhat"), 0755)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "EFI/redhat/grub.cfg"), []byte(testGrubConfig), 0600)).To(Succeed())
 	Expect(os.WriteFile(filepath.Join(filesDir, "generic.ins"), []byte("this is generic.ins"), 0600)).To(Succeed())
 	Expect(os.WriteFile(filepath.Join(filesDir, "images/initrd.addrsize"), []byte{
 		1, 2, 3, 4, 5, 6, 7, 8, 1, 2, 3, 4, 5, 6, 7, 8}, 0600)).To(Succeed())
@@ -5,10 +5,11 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	metricsmiddleware "github.com/slok/go-http-metrics/middleware"
-	stdmiddleware "github.com/slok/go-http-metrics/middleware/std"
 
+	"github.com/openshift/assisted-image-service/pkg/diskeditor"
 	"github.com/openshift/assisted-image-service/pkg/imagestore"
 	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+	"github.com/openshift/assisted-image-service/pkg/uki"
 )
 
 const defaultArch = "x86_64"
@@ -19,69 +20,228 @@ type ImageHandler struct {
 	byID                http.Handler
 	byToken             http.Handler
 	initrd              http.Handler
+	ipxeScript          http.Handler
+	pxeBundle           http.Handler
 	s390xInitrdAddrsize http.Handler
+	s390xBoot           http.Handler
+	qcow2               http.Handler
+	raw                 http.Handler
+	ova                 http.Handler
+	configDrive         http.Handler
+	noCloud             http.Handler
+	uki                 http.Handler
+	netboot             http.Handler
+	builds              http.Handler
+	v2Images            http.Handler
+	limiter             *streamLimiter
 }
 
-func NewImageHandler(is imagestore.ImageStore, assistedServiceClient *AssistedServiceClient, maxRequests int64, mdw metricsmiddleware.Middleware) http.Handler {
+// ImageHandlerOptions configure the handler of the image downloads
+type ImageHandlerOptions struct {
+	ImageStore            imagestore.ImageStore
+	AssistedServiceClient *AssistedServiceClient
+	// MaxRequests is the number of requests handled concurrently
+	MaxRequests int64
+	Metrics     metricsmiddleware.Middleware
+	// RootfsVerifier checks the rootfs URL of minimal ISOs before serving them, when set
+	RootfsVerifier *RootfsURLVerifier
+	// ISOMD5 tells how the implanted checksum of the ISOs is handled
+	ISOMD5 isoeditor.ISOMD5Mode
+	// KargsConflictPolicy tells how the kernel arguments of the ISOs setting a parameter more
+	// than once are handled
+	KargsConflictPolicy isoeditor.KargsConflictPolicy
+	// Limits reject the requests over them
+	Limits StreamLimits
+	// DiskEditor edits the disk images and UKIBuilder builds the unified kernel images, in WorkDir
+	DiskEditor diskeditor.Editor
+	UKIBuilder uki.Builder
+	WorkDir    string
+	// NetbootOutputDir is where the netboot trees can be written, when set
+	NetbootOutputDir string
+	// ISOCache keeps the customized ISOs, when set
+	ISOCache *ISOCache
+	// Builds serves the /builds and /v2/images APIs behind the limits of the images, when set
+	Builds *BuildHandler
+}
+
+// NewImageHandler returns the handler of the image downloads
+func NewImageHandler(options ImageHandlerOptions) http.Handler {
+	generateImageStream := isoeditor.ISOMD5StreamGenerator(isoeditor.RHCOSStreamGenerator(options.KargsConflictPolicy), options.ISOMD5)
+	limiter := newStreamLimiter(options.Limits)
 	h := ImageHandler{
-		long: stdmiddleware.Handler("/images/:imageID", mdw,
+		long: MeasuredHandler("/images/:imageID", options.Metrics,
 			&isoHandler{
-				ImageStore:          is,
-				GenerateImageStream: isoeditor.NewRHCOSStreamReader,
-				client:              assistedServiceClient,
+				ImageStore:          options.ImageStore,
+				GenerateImageStream: generateImageStream,
+				client:              options.AssistedServiceClient,
+				rootfsVerifier:      options.RootfsVerifier,
+				cache:               options.ISOCache,
+				limiter:             limiter,
 				urlParser:           parseLongURL,
 			},
 		),
-		byAPIKey: stdmiddleware.Handler("/byapikey/:token", mdw,
+		byAPIKey: MeasuredHandler("/byapikey/:token", options.Metrics,
 			&isoHandler{
-				ImageStore:          is,
-				GenerateImageStream: isoeditor.NewRHCOSStreamReader,
-				client:              assistedServiceClient,
+				ImageStore:          options.ImageStore,
+				GenerateImageStream: generateImageStream,
+				client:              options.AssistedServiceClient,
+				rootfsVerifier:      options.RootfsVerifier,
+				cache:               options.ISOCache,
+				limiter:             limiter,
 				urlParser:           parseShortURL,
 			},
 		),
-		byID: stdmiddleware.Handler("/byid/:token", mdw,
+		byID: MeasuredHandler("/byid/:token", options.Metrics,
 			&isoHandler{
-				ImageStore:          is,
-				GenerateImageStream: isoeditor.NewRHCOSStreamReader,
-				client:              assistedServiceClient,
+				ImageStore:          options.ImageStore,
+				GenerateImageStream: generateImageStream,
+				client:              options.AssistedServiceClient,
+				rootfsVerifier:      options.RootfsVerifier,
+				cache:               options.ISOCache,
+				limiter:             limiter,
 				urlParser:           parseShortURL,
 			},
 		),
-		byToken: stdmiddleware.Handler("/bytoken/:token", mdw,
+		byToken: MeasuredHandler("/bytoken/:token", options.Metrics,
 			&isoHandler{
-				ImageStore:          is,
-				GenerateImageStream: isoeditor.NewRHCOSStreamReader,
-				client:              assistedServiceClient,
+				ImageStore:          options.ImageStore,
+				GenerateImageStream: generateImageStream,
+				client:              options.AssistedServiceClient,
+				rootfsVerifier:      options.RootfsVerifier,
+				cache:               options.ISOCache,
+				limiter:             limiter,
 				urlParser:           parseShortURL,
 			},
 		),
-		initrd: stdmiddleware.Handler("/images/:imageID/pxe-initrd", mdw,
+		initrd: MeasuredHandler("/images/:imageID/pxe-initrd", options.Metrics,
 			&initrdHandler{
-				ImageStore: is,
-				client:     assistedServiceClient,
+				ImageStore: options.ImageStore,
+				client:     options.AssistedServiceClient,
+			},
+		),
+		ipxeScript: MeasuredHandler("/images/:imageID/pxe-script", options.Metrics,
+			&ipxeScriptHandler{
+				ImageStore: options.ImageStore,
+				client:     options.AssistedServiceClient,
 			},
 		),
-		s390xInitrdAddrsize: stdmiddleware.Handler("/images/:imageID/s390x-initrd-addrsize", mdw,
+		pxeBundle: MeasuredHandler("/images/:imageID/pxe-bundle", options.Metrics,
+			&pxeBundleHandler{
+				ImageStore: options.ImageStore,
+				client:     options.AssistedServiceClient,
+			},
+		),
+		s390xInitrdAddrsize: MeasuredHandler("/images/:imageID/s390x-initrd-addrsize", options.Metrics,
 			&initrdAddrSizeHandler{
-				ImageStore: is,
-				client:     assistedServiceClient,
+				ImageStore: options.ImageStore,
+				client:     options.AssistedServiceClient,
 			},
 		),
+		s390xBoot: MeasuredHandler("/images/:imageID/s390x-boot", options.Metrics,
+			&s390xBootHandler{
+				ImageStore: options.ImageStore,
+				client:     options.AssistedServiceClient,
+			},
+		),
+		qcow2: MeasuredHandler("/images/:imageID/qcow2", options.Metrics,
+			&diskImageHandler{
+				ImageStore: options.ImageStore,
+				client:     options.AssistedServiceClient,
+				editor:     options.DiskEditor,
+				workDir:    options.WorkDir,
+				imageType:  imagestore.ImageTypeQcow2,
+				extension:  "qcow2",
+			},
+		),
+		raw: MeasuredHandler("/images/:imageID/raw", options.Metrics,
+			&diskImageHandler{
+				ImageStore: options.ImageStore,
+				client:     options.AssistedServiceClient,
+				editor:     options.DiskEditor,
+				workDir:    options.WorkDir,
+				imageType:  imagestore.ImageTypeRaw,
+				extension:  "img",
+			},
+		),
+		ova: MeasuredHandler("/images/:imageID/ova", options.Metrics,
+			&diskImageHandler{
+				ImageStore: options.ImageStore,
+				client:     options.AssistedServiceClient,
+				editor:     options.DiskEditor,
+				workDir:    options.WorkDir,
+				imageType:  imagestore.ImageTypeOVA,
+				extension:  "ova",
+			},
+		),
+		configDrive: MeasuredHandler("/images/:imageID/config-drive", options.Metrics,
+			&configDriveHandler{
+				client:  options.AssistedServiceClient,
+				workDir: options.WorkDir,
+			},
+		),
+		noCloud: MeasuredHandler("/images/:imageID/nocloud-seed", options.Metrics,
+			&noCloudHandler{
+				client:  options.AssistedServiceClient,
+				workDir: options.WorkDir,
+			},
+		),
+		uki: MeasuredHandler("/images/:imageID/uki", options.Metrics,
+			&ukiHandler{
+				ImageStore: options.ImageStore,
+				client:     options.AssistedServiceClient,
+				builder:    options.UKIBuilder,
+				workDir:    options.WorkDir,
+			},
+		),
+		netboot: MeasuredHandler("/images/:imageID/netboot", options.Metrics,
+			&netbootHandler{
+				ImageStore: options.ImageStore,
+				client:     options.AssistedServiceClient,
+				outputDir:  options.NetbootOutputDir,
+			},
+		),
+		limiter: limiter,
+	}
+	if options.Builds != nil {
+		h.builds = MeasuredHandler("/builds", options.Metrics, options.Builds)
+		h.v2Images = MeasuredHandler("/v2/images/:imageID", options.Metrics, options.Builds)
 	}
 
-	return h.router(maxRequests)
+	return h.router(options.MaxRequests)
 }
 
 func (h *ImageHandler) router(maxRequests int64) *chi.Mux {
 	router := chi.NewRouter()
+	router.Use(h.limiter.limitRate)
 	router.Use(WithRequestLimit(maxRequests))
-	router.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/pxe-initrd", h.initrd)
-	router.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/s390x-initrd-addrsize", h.s390xInitrdAddrsize)
-	router.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}", h.long)
-	router.Handle("/byid/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/{version}/{arch}/{filename}", h.byID)
-	router.Handle("/byapikey/{api_key}/{version}/{arch}/{filename}", h.byAPIKey)
-	router.Handle("/bytoken/{token}/{version}/{arch}/{filename}", h.byToken)
+	router.Use(h.limiter.limitMemory)
+	iso := router.With(h.limiter.limitStreams, withTokenScope(tokenArtifactISO))
+	pxe := router.With(withTokenScope(tokenArtifactPXE))
+	disk := router.With(h.limiter.limitStreams, withTokenScope(tokenArtifactDisk))
+	pxe.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/pxe-initrd", h.initrd)
+	pxe.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/pxe-script", h.ipxeScript)
+	pxe.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/pxe-bundle", h.pxeBundle)
+	pxe.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/s390x-initrd-addrsize", h.s390xInitrdAddrsize)
+	pxe.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/s390x-boot", h.s390xBoot)
+	pxe.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/uki", h.uki)
+	pxe.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/netboot", h.netboot)
+	disk.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/qcow2", h.qcow2)
+	disk.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/raw", h.raw)
+	disk.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/ova", h.ova)
+	disk.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/config-drive", h.configDrive)
+	disk.Method(http.MethodPost, "/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/nocloud-seed", h.noCloud)
+	iso.Handle("/images/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}", h.long)
+	iso.Handle("/byid/{image_id:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}}/{version}/{arch}/{filename}", h.byID)
+	iso.Handle("/byapikey/{api_key}/{version}/{arch}/{filename}", h.byAPIKey)
+	iso.Handle("/bytoken/{token}/{version}/{arch}/{filename}", h.byToken)
+	if h.builds != nil {
+		router.Handle("/builds", h.builds)
+		router.Handle("/builds/*", h.builds)
+		router.With(h.limiter.limitStreams).Handle("/builds/{build_id}/image", h.builds)
+	}
+	if h.v2Images != nil {
+		router.With(h.limiter.limitStreams).Handle("/v2/images/{image_id}", h.v2Images)
+	}
 
 	return router
 }
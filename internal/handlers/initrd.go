@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -37,12 +38,20 @@ func (h *initrdHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if arch == "" {
 		arch = defaultArch
 	}
+	compress, ok := zstdRequested(w, r)
+	if !ok {
+		return
+	}
 
-	initrdReader, lastModified, code, err := initrdOverlayReader(h.ImageStore, h.client, r, arch)
-	if err != nil {
+	initrdReader, lastModified, etag, code, err := initrdOverlayReader(h.ImageStore, h.client, r, arch)
+	if errors.Is(err, errVersionDownloading) {
+		respondVersionDownloading(w, version, arch)
+		return
+	} else if err != nil {
 		httpErrorf(w, code, err.Error())
 		return
 	}
+	initrdReader = overlay.WithContext(r.Context(), initrdReader)
 	defer initrdReader.Close()
 
 	fileName := fmt.Sprintf("%s-initrd.img", imageID)
@@ -52,66 +61,77 @@ func (h *initrdHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		log.Warnf("Error parsing last modified time %s: %v", lastModified, err)
 		modTime = time.Now()
 	}
-	http.ServeContent(w, r, fileName, modTime, initrdReader)
+	if compress {
+		serveZstd(w, r, modTime, initrdReader, etag)
+		return
+	}
+	serveImage(w, r, fileName, modTime, initrdReader, etag)
 }
 
-func initrdOverlayReader(imageStore imagestore.ImageStore, client *AssistedServiceClient, r *http.Request, arch string) (overlay.OverlayReader, string, int, error) {
+// initrdOverlayReader returns the initrd customized for the image, its last modification time and
+// its ETag
+func initrdOverlayReader(imageStore imagestore.ImageStore, client *AssistedServiceClient, r *http.Request, arch string) (overlay.OverlayReader, string, string, int, error) {
 	imageID := chi.URLParam(r, "image_id")
 
 	version := r.URL.Query().Get("version")
 	if version == "" {
-		return nil, "", http.StatusBadRequest, fmt.Errorf("'version' parameter required for initrd download")
+		return nil, "", "", http.StatusBadRequest, fmt.Errorf("'version' parameter required for initrd download")
 	}
 
 	// check if image is available for given version and architecture
 	if !imageStore.HaveVersion(version, arch) {
-		return nil, "", http.StatusBadRequest, fmt.Errorf("version for %s %s, not found ", version, arch)
+		return nil, "", "", http.StatusBadRequest, fmt.Errorf("version for %s %s, not found ", version, arch)
 	}
 
-	isoPath := imageStore.PathForParams(imagestore.ImageTypeFull, version, arch)
-
+	// assisted service authenticates the request before the usage of the version is recorded
 	ignition, lastModified, code, err := client.ignitionContent(r, imageID, "")
 	if err != nil {
-		return nil, "", code, fmt.Errorf("error retrieving ignition content: %v", err)
+		return nil, "", "", code, fmt.Errorf("error retrieving ignition content: %v", err)
 	}
 
-	initrdReader, err := isoeditor.NewInitRamFSStreamReaderFromISO(isoPath, ignition)
+	if !imageStore.Available(version, arch) {
+		return nil, "", "", http.StatusAccepted, errVersionDownloading
+	}
+	isoPath := imageStore.PathForParams(imagestore.ImageTypeFull, version, arch)
+
+	// the ignition archive uses the compression of the initrd of the release
+	initrdReader, err := isoeditor.NewInitRamFSStreamReaderFromISOWithCompression(isoPath, ignition, "")
 	if err != nil {
-		return nil, "", http.StatusInternalServerError, fmt.Errorf("failed to get initrd: %v", err)
+		return nil, "", "", http.StatusInternalServerError, fmt.Errorf("failed to get initrd: %v", err)
 	}
 
 	ramdisk, statusCode, err := client.ramdiskContent(r, imageID)
 	if err != nil {
-		return nil, "", statusCode, fmt.Errorf("error retrieving ramdisk content: %v", err)
+		return nil, "", "", statusCode, fmt.Errorf("error retrieving ramdisk content: %v", err)
 	}
 
 	// the content will be nil if no static networking is configured
 	if ramdisk != nil {
 		initrdReader, err = overlay.NewAppendReader(initrdReader, bytes.NewReader(ramdisk))
 		if err != nil {
-			return nil, "", http.StatusInternalServerError, fmt.Errorf("failed to create append reader for initrd: %v", err)
+			return nil, "", "", http.StatusInternalServerError, fmt.Errorf("failed to create append reader for initrd: %v", err)
 		}
 
 		versionOK, err := common.VersionGreaterOrEqual(version, isoeditor.MinimalVersionForNmstatectl)
 		if err != nil {
-			return nil, "", http.StatusInternalServerError, err
+			return nil, "", "", http.StatusInternalServerError, err
 		}
 
 		if versionOK {
 			nmstatectlPath, err := imageStore.NmstatectlPathForParams(version, arch)
 			if err != nil {
-				return nil, "", http.StatusInternalServerError, err
+				return nil, "", "", http.StatusInternalServerError, err
 			}
 			nmstateImgContent, err := os.Open(nmstatectlPath)
 			if err != nil {
-				return nil, "", http.StatusInternalServerError, fmt.Errorf("failed to read nmstate img: %v", err)
+				return nil, "", "", http.StatusInternalServerError, fmt.Errorf("failed to read nmstate img: %v", err)
 			}
 			initrdReader, err = overlay.NewAppendReader(initrdReader, nmstateImgContent)
 			if err != nil {
-				return nil, "", http.StatusInternalServerError, fmt.Errorf("failed to create append reader for initrd: %v", err)
+				return nil, "", "", http.StatusInternalServerError, fmt.Errorf("failed to create append reader for initrd: %v", err)
 			}
 		}
 	}
 
-	return initrdReader, lastModified, 0, nil
+	return initrdReader, lastModified, imageETag(isoPath, ignition.Config, ramdisk), 0, nil
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -29,15 +30,18 @@ func (h *initrdAddrSizeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	isoPath := h.ImageStore.PathForParams(imagestore.ImageTypeFull, version, "s390x")
-
-	initrdReader, lastModified, code, err := initrdOverlayReader(h.ImageStore, h.client, r, "s390x")
-	if err != nil {
+	initrdReader, lastModified, initrdETag, code, err := initrdOverlayReader(h.ImageStore, h.client, r, "s390x")
+	if errors.Is(err, errVersionDownloading) {
+		respondVersionDownloading(w, version, "s390x")
+		return
+	} else if err != nil {
 		httpErrorf(w, code, err.Error())
 		return
 	}
 	defer initrdReader.Close()
 
+	isoPath := h.ImageStore.PathForParams(imagestore.ImageTypeFull, version, "s390x")
+
 	fileName := fmt.Sprintf("%s-initrd.addrsize", imageID)
 	newAddrsizeFile, err := isoeditor.NewInitrdAddrsizeReaderFromISO(isoPath, initrdReader)
 	if err != nil {
@@ -53,5 +57,5 @@ func (h *initrdAddrSizeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 	}
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
 
-	http.ServeContent(w, r, fileName, modTime, newAddrsizeFile)
+	serveImage(w, r, fileName, modTime, newAddrsizeFile, imageETag(isoPath, []byte(fileName), []byte(initrdETag)))
 }
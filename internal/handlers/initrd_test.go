@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 
 	"github.com/golang/mock/gomock"
+	"github.com/klauspost/compress/zstd"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/ghttp"
@@ -92,6 +93,7 @@ var _ = Describe("ServeHTTP", func() {
 
 	mockImage := func(version, arch string) {
 		mockImageStore.EXPECT().HaveVersion(version, arch).Return(true).AnyTimes()
+		mockImageStore.EXPECT().Available(version, arch).Return(true).AnyTimes()
 		mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, version, arch).Return(imageFilename).AnyTimes()
 	}
 
@@ -138,6 +140,22 @@ var _ = Describe("ServeHTTP", func() {
 		expectSuccessfulResponse(resp, append(initrdContent, ignitionArchiveBytes...))
 	})
 
+	It("streams the initrd compressed with zstd", func() {
+		mockImage("4.9", "x86_64")
+		withNoMinimalInitrd()
+		resp, err := client.Get(fmt.Sprintf("%s/images/%s/pxe-initrd?version=4.9&compress=zstd", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Encoding")).To(Equal("zstd"))
+		Expect(resp.Header.Get("Content-Disposition")).To(Equal(fmt.Sprintf("attachment; filename=%s-initrd.img", imageID)))
+		dec, err := zstd.NewReader(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		defer dec.Close()
+		content, err := io.ReadAll(dec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content).To(HavePrefix(string(initrdContent)))
+	})
+
 	It("uses the default arch", func() {
 		mockImage("4.9", "x86_64")
 		withNoMinimalInitrd()
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+)
+
+// ipxeScriptHandler renders the iPXE script booting the image from the kernel, initrd and rootfs
+// served by the image service, with the kernel arguments of its ISO
+type ipxeScriptHandler struct {
+	ImageStore imagestore.ImageStore
+	client     *AssistedServiceClient
+}
+
+var _ http.Handler = &ipxeScriptHandler{}
+
+func (h *ipxeScriptHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	imageID := chi.URLParam(r, "image_id")
+
+	version := r.URL.Query().Get("version")
+	if version == "" {
+		httpErrorf(w, http.StatusBadRequest, "'version' parameter required for iPXE script download")
+		return
+	}
+
+	arch := r.URL.Query().Get("arch")
+	if arch == "" {
+		arch = defaultArch
+	}
+	if arch == "s390x" {
+		httpErrorf(w, http.StatusBadRequest, "iPXE is not supported for s390x architecture")
+		return
+	}
+
+	if !h.ImageStore.HaveVersion(version, arch) {
+		httpErrorf(w, http.StatusBadRequest, "version for %s %s, not found", version, arch)
+		return
+	}
+
+	// assisted service authenticates the request before the usage of the version is recorded
+	infraEnvKargs, statusCode, err := infraEnvKernelArguments(h.client, r, imageID, version, arch)
+	if err != nil {
+		httpErrorf(w, statusCode, "Failed to get the kernel arguments: %v", err)
+		return
+	}
+
+	if !h.ImageStore.Available(version, arch) {
+		respondVersionDownloading(w, version, arch)
+		return
+	}
+
+	isoPath := h.ImageStore.PathForParams(imagestore.ImageTypeFull, version, arch)
+	kargs, err := applyPXEKernelArguments(isoPath, infraEnvKargs)
+	if err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "Failed to get the kernel arguments: %v", err)
+		return
+	}
+
+	artifactsQuery := url.Values{"version": {version}, "arch": {arch}}
+	initrdQuery := url.Values{"version": {version}, "arch": {arch}}
+	// the initrd embeds the ignition of the image, the script passes on the credentials it was fetched with
+	for _, param := range []string{"api_key", "image_token"} {
+		if value := r.URL.Query().Get(param); value != "" {
+			initrdQuery.Set(param, value)
+		}
+	}
+	baseURL := requestBaseURL(r)
+	rootfsURL := fmt.Sprintf("%s/boot-artifacts/rootfs?%s", baseURL, artifactsQuery.Encode())
+	script := ipxeScript(
+		fmt.Sprintf("%s/boot-artifacts/kernel?%s", baseURL, artifactsQuery.Encode()),
+		[]pxeInitrd{{name: "initrd", url: fmt.Sprintf("%s/images/%s/pxe-initrd?%s", baseURL, imageID, initrdQuery.Encode())}},
+		append([]string{"coreos.live.rootfs_url=" + rootfsURL}, kargs...),
+	)
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.ipxe", imageID))
+	if _, err := w.Write([]byte(script)); err != nil {
+		log.Warnf("Failed to write the iPXE script: %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+var _ = Describe("iPXE script ServeHTTP", func() {
+	var (
+		ctrl           *gomock.Controller
+		mockImageStore *imagestore.MockImageStore
+		assistedServer *ghttp.Server
+		handler        *ImageHandler
+		server         *httptest.Server
+		client         *http.Client
+		isoFile        string
+		imageID        = "bf25292a-dddd-49dc-ab9c-3fb4c1f07071"
+	)
+
+	BeforeEach(func() {
+		isoFile = createTestISO()
+
+		ctrl = gomock.NewController(GinkgoT())
+		mockImageStore = imagestore.NewMockImageStore(ctrl)
+		assistedServer = ghttp.NewServer()
+		u, err := url.Parse(assistedServer.URL())
+		Expect(err).NotTo(HaveOccurred())
+		asc, err := NewAssistedServiceClient(u.Scheme, u.Host, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		handler = &ImageHandler{
+			ipxeScript: &ipxeScriptHandler{
+				ImageStore: mockImageStore,
+				client:     asc,
+			},
+		}
+		server = httptest.NewServer(handler.router(1))
+		client = server.Client()
+	})
+
+	AfterEach(func() {
+		os.Remove(isoFile)
+		server.Close()
+		assistedServer.Close()
+	})
+
+	mockImage := func(version, arch string) {
+		mockImageStore.EXPECT().HaveVersion(version, arch).Return(true)
+		mockImageStore.EXPECT().Available(version, arch).Return(true)
+		mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, version, arch).Return(isoFile)
+	}
+
+	setInfraEnvKargs := func(args ...string) {
+		response := "{}"
+		if len(args) > 0 {
+			kargs, err := isoeditor.KargsToStr(args)
+			Expect(err).NotTo(HaveOccurred())
+			b, err := json.Marshal(map[string]string{"kernel_arguments": kargs})
+			Expect(err).NotTo(HaveOccurred())
+			response = string(b)
+		}
+		assistedServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(infraEnvPathFormat, imageID)),
+				ghttp.RespondWith(http.StatusOK, response),
+			),
+		)
+	}
+
+	It("renders the script booting the artifacts of the image", func() {
+		mockImage("4.11", "arm64")
+		setInfraEnvKargs("console=ttyS0", "ip={{.InfraEnvID}}")
+
+		resp, err := client.Get(fmt.Sprintf("%s/images/%s/pxe-script?version=4.11&arch=arm64&api_key=secret", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/plain"))
+		script, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+
+		artifacts := "arch=arm64&version=4.11"
+		Expect(string(script)).To(Equal(fmt.Sprintf(`#!ipxe
+initrd --name initrd %[1]s/images/%[2]s/pxe-initrd?api_key=secret&%[3]s
+kernel %[1]s/boot-artifacts/kernel?%[3]s initrd=initrd coreos.live.rootfs_url=%[1]s/boot-artifacts/rootfs?%[3]s random.trust_cpu=on ignition.firstboot ignition.platform.id=metal console=ttyS0 ip=%[2]s
+boot
+`, server.URL, imageID, artifacts)))
+	})
+
+	getKernelLine := func(server *httptest.Server, header http.Header) string {
+		mockImage("4.11", defaultArch)
+		setInfraEnvKargs()
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/images/%s/pxe-script?version=4.11", server.URL, imageID), nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header = header
+		resp, err := server.Client().Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		script, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		for _, line := range strings.Split(string(script), "\n") {
+			if strings.HasPrefix(line, "kernel ") {
+				return line
+			}
+		}
+		Fail("no kernel line in the script")
+		return ""
+	}
+
+	forwarded := http.Header{"X-Forwarded-Proto": {"https"}, "X-Forwarded-Host": {"images.example.com"}}
+
+	It("uses the scheme and host the request was forwarded with by a trusted proxy", func() {
+		proxies, err := ParseTrustedProxies([]string{"127.0.0.1", "::1"})
+		Expect(err).NotTo(HaveOccurred())
+		proxied := httptest.NewServer(WithBaseURL("", proxies)(handler.router(1)))
+		defer proxied.Close()
+
+		Expect(getKernelLine(proxied, forwarded)).To(HavePrefix("kernel https://images.example.com/boot-artifacts/kernel?arch=x86_64&version=4.11 "))
+	})
+
+	It("ignores the forwarded scheme and host of the other clients", func() {
+		u, err := url.Parse(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getKernelLine(server, forwarded)).To(HavePrefix(fmt.Sprintf("kernel http://%s/boot-artifacts/kernel?", u.Host)))
+	})
+
+	It("uses the configured base URL", func() {
+		configured := httptest.NewServer(WithBaseURL("https://images.example.com/", nil)(handler.router(1)))
+		defer configured.Close()
+
+		Expect(getKernelLine(configured, http.Header{"X-Forwarded-Host": {"evil.example.com"}})).To(HavePrefix("kernel https://images.example.com/boot-artifacts/kernel?"))
+	})
+
+	It("fails when no version is supplied", func() {
+		resp, err := client.Get(fmt.Sprintf("%s/images/%s/pxe-script", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("fails for a non-existent version", func() {
+		mockImageStore.EXPECT().HaveVersion("4.7", defaultArch).Return(false)
+		resp, err := client.Get(fmt.Sprintf("%s/images/%s/pxe-script?version=4.7", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("fails for s390x", func() {
+		resp, err := client.Get(fmt.Sprintf("%s/images/%s/pxe-script?version=4.11&arch=s390x", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("passes on the status of a failed infra-env request", func() {
+		mockImage("4.11", defaultArch)
+		assistedServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(infraEnvPathFormat, imageID)),
+				ghttp.RespondWith(http.StatusUnauthorized, ""),
+			),
+		)
+		resp, err := client.Get(fmt.Sprintf("%s/images/%s/pxe-script?version=4.11", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+})
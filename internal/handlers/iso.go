@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/openshift/assisted-image-service/pkg/imagestore"
 	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+	"github.com/openshift/assisted-image-service/pkg/overlay"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -14,6 +16,12 @@ type isoHandler struct {
 	ImageStore          imagestore.ImageStore
 	GenerateImageStream isoeditor.StreamGeneratorFunc
 	client              *AssistedServiceClient
+	// checks the rootfs URL of minimal ISOs before serving them, when set
+	rootfsVerifier *RootfsURLVerifier
+	// serves the ISOs already generated for the same customization, when set
+	cache *ISOCache
+	// counts the ISOs generated to fill the cache as streams
+	limiter *streamLimiter
 	// second arg is an HTTP response code to use when the error != nil
 	urlParser func(*http.Request) (*imageDownloadParams, int, error)
 }
@@ -45,50 +53,118 @@ func (h *isoHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ignition, lastModified, statusCode, err := h.client.ignitionContent(r, params.imageID, params.imageType)
+	// assisted service authenticates the request before the usage of the version is recorded
+	c, statusCode, err := fetchISOCustomization(h.client, r, params)
 	if err != nil {
-		log.Errorf("Error retrieving ignition content: %v\n", err)
-		w.WriteHeader(statusCode)
+		httpErrorf(w, statusCode, "%v", err)
 		return
 	}
 
-	var ramdisk []byte
-	if params.imageType == imagestore.ImageTypeMinimal {
-		ramdisk, statusCode, err = h.client.ramdiskContent(r, params.imageID)
-		if err != nil {
-			log.Errorf("Error retrieving ramdisk content: %v\n", err)
-			w.WriteHeader(statusCode)
-			return
-		}
+	if !h.ImageStore.Available(params.version, params.arch) {
+		respondVersionDownloading(w, params.version, params.arch)
+		return
 	}
 
-	var kargs []byte
-	kargs, statusCode, err = h.client.discoveryKernelArguments(r, params.imageID)
-	if err != nil {
-		log.Errorf("Error retrieving kernel arguments content: %v\n", err)
-		w.WriteHeader(statusCode)
-		return
+	isoPath := h.ImageStore.PathForParams(params.imageType, params.version, params.arch)
+	if params.imageType == imagestore.ImageTypeMinimal && h.rootfsVerifier != nil {
+		if err = h.rootfsVerifier.VerifyISO(r.Context(), isoPath); err != nil {
+			httpErrorf(w, http.StatusServiceUnavailable, "Hosts booting the minimal ISO would fail to download the rootfs: %v", err)
+			return
+		}
 	}
 
-	if kargs != nil && params.arch == "s390x" {
-		httpErrorf(w, http.StatusBadRequest, "kargs cannot be modified in s390x architecture ISOs")
-		return
+	fileName := fmt.Sprintf("%s-discovery.iso", params.imageID)
+	etag := imageETag(isoPath, c.ignition.Config, c.ramdisk, kargsETagInput(c.kargs))
+	if h.cache != nil {
+		if cached, ok := h.cache.open(cacheKey(etag)); ok {
+			defer cached.Close()
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+			serveImage(w, r, fileName, c.modTime(), cached, etag)
+			return
+		}
+		h.cache.fill(cacheKey(etag), h.limiter, func() (isoeditor.ImageReader, error) {
+			return h.GenerateImageStream(isoPath, c.ignition, c.ramdisk, c.kargs)
+		})
 	}
 
-	isoReader, err := h.GenerateImageStream(h.ImageStore.PathForParams(params.imageType, params.version, params.arch), ignition, ramdisk, kargs)
+	isoReader, err := h.GenerateImageStream(isoPath, c.ignition, c.ramdisk, c.kargs)
 	if err != nil {
 		log.Errorf("Error creating image stream: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		if statusCode := streamErrorStatus(err); statusCode == http.StatusBadRequest {
+			httpErrorf(w, statusCode, "%v", err)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
 		return
 	}
+	// stop generating the stream as soon as the client goes away
+	isoReader = overlay.WithContext(r.Context(), isoReader)
 	defer isoReader.Close()
 
-	fileName := fmt.Sprintf("%s-discovery.iso", params.imageID)
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
-	modTime, err := http.ParseTime(lastModified)
+	serveImage(w, r, fileName, c.modTime(), isoReader, etag)
+}
+
+// streamErrorStatus returns the status code to respond with when the stream of a customized ISO
+// can't be generated: 400 when the ISO can't hold the kernel arguments, 500 otherwise
+func streamErrorStatus(err error) int {
+	var tooLong *isoeditor.ErrKargsTooLong
+	var secureIPL *isoeditor.ErrS390xSecureIPL
+	if errors.As(err, &tooLong) || errors.As(err, &secureIPL) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// isoCustomization is what customizes the base ISO for an infra-env
+type isoCustomization struct {
+	ignition     *isoeditor.IgnitionContent
+	ramdisk      []byte
+	kargs        isoeditor.KernelArguments
+	lastModified string
+}
+
+// fetchISOCustomization retrieves the customization of the ISO from assisted service, with the
+// credentials of the request. The second return value is the HTTP response code to use when the
+// error != nil.
+func fetchISOCustomization(client *AssistedServiceClient, r *http.Request, params *imageDownloadParams) (*isoCustomization, int, error) {
+	ignition, lastModified, statusCode, err := client.ignitionContent(r, params.imageID, params.imageType)
+	if err != nil {
+		return nil, statusCode, fmt.Errorf("error retrieving ignition content: %w", err)
+	}
+	c := &isoCustomization{ignition: ignition, lastModified: lastModified}
+
+	if params.imageType == imagestore.ImageTypeMinimal {
+		c.ramdisk, statusCode, err = client.ramdiskContent(r, params.imageID)
+		if err != nil {
+			return nil, statusCode, fmt.Errorf("error retrieving ramdisk content: %w", err)
+		}
+	}
+
+	c.kargs, statusCode, err = client.discoveryKernelArguments(r, params.imageID)
+	if err != nil {
+		return nil, statusCode, fmt.Errorf("error retrieving kernel arguments content: %w", err)
+	}
+
+	if len(c.kargs) > 0 {
+		c.kargs, err = c.kargs.Render(map[string]string{
+			isoeditor.KargsVarInfraEnvID:       params.imageID,
+			isoeditor.KargsVarOpenshiftVersion: params.version,
+			isoeditor.KargsVarCPUArchitecture:  params.arch,
+		})
+		if err != nil {
+			return nil, http.StatusInternalServerError, fmt.Errorf("failed to render kernel arguments: %w", err)
+		}
+	}
+	return c, 0, nil
+}
+
+// modTime is the last modification time of the customization, or now when it's unknown
+func (c *isoCustomization) modTime() time.Time {
+	modTime, err := http.ParseTime(c.lastModified)
 	if err != nil {
-		log.Warnf("Error parsing last modified time %s: %v", lastModified, err)
-		modTime = time.Now()
+		log.Warnf("Error parsing last modified time %s: %v", c.lastModified, err)
+		return time.Now()
 	}
-	http.ServeContent(w, r, fileName, modTime, isoReader)
+	return modTime
 }
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+// isoCacheTempPrefix starts the names of the ISOs being written to the cache
+const isoCacheTempPrefix = ".tmp-"
+
+// maxISOCacheFills bounds the ISOs generated at once to fill the cache
+const maxISOCacheFills = 4
+
+// ISOCache keeps the customized ISOs on disk, keyed by their ETag: the digest of the base ISO,
+// which identifies its version, architecture and type, and of the ignition, ramdisk and kernel
+// arguments customizing it. The identical requests, e.g. of the hosts of a fleet booting the same
+// infra-env, are then served from the disk rather than regenerated. The least recently served
+// ISOs are evicted to stay within maxSize bytes, unless it is 0.
+type ISOCache struct {
+	dir     string
+	maxSize int64
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	// of *isoCacheEntry, the most recently served first
+	lru  *list.List
+	size int64
+
+	filling singleflight.Group
+	fills   chan struct{}
+}
+
+type isoCacheEntry struct {
+	key  string
+	size int64
+}
+
+// NewISOCache returns a cache of the customized ISOs in dir. The ISOs cached by previous runs are
+// removed, since the configuration generating them may have changed.
+func NewISOCache(dir string, maxSize int64) (*ISOCache, error) {
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to clear the ISO cache %s: %w", dir, err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create the ISO cache %s: %w", dir, err)
+	}
+	return &ISOCache{
+		dir:     dir,
+		maxSize: maxSize,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+		fills:   make(chan struct{}, maxISOCacheFills),
+	}, nil
+}
+
+// cacheKey returns the key of the ISO with the ETag
+func cacheKey(etag string) string {
+	return strings.Trim(etag, `"`)
+}
+
+func (c *ISOCache) path(key string) string {
+	return filepath.Join(c.dir, key+".iso")
+}
+
+// open returns the cached ISO of the key, marking it as recently served
+func (c *ISOCache) open(key string) (*os.File, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	// the file stays readable if the ISO is evicted while it is served
+	f, err := os.Open(c.path(key))
+	if err != nil {
+		log.WithError(err).Warnf("Failed to open the cached ISO %s", key)
+		c.remove(element)
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	return f, true
+}
+
+// fill caches the ISO of the key in the background, generating it once however many requests
+// miss it meanwhile. The generation counts as a stream of the limiter, and at most
+// maxISOCacheFills ISOs are generated at once: the ISOs missed over the limits are cached by a
+// later request.
+func (c *ISOCache) fill(key string, limiter *streamLimiter, generate func() (isoeditor.ImageReader, error)) {
+	if !limiter.takeBackgroundStream() {
+		return
+	}
+	select {
+	case c.fills <- struct{}{}:
+	default:
+		limiter.releaseBackgroundStream()
+		return
+	}
+	go func() {
+		defer func() {
+			<-c.fills
+			limiter.releaseBackgroundStream()
+		}()
+		_, _, _ = c.filling.Do(key, func() (interface{}, error) {
+			c.lock.Lock()
+			_, ok := c.entries[key]
+			c.lock.Unlock()
+			if ok {
+				return nil, nil
+			}
+			if err := c.write(key, generate); err != nil {
+				log.WithError(err).Warnf("Failed to cache the ISO %s", key)
+			}
+			return nil, nil
+		})
+	}()
+}
+
+func (c *ISOCache) write(key string, generate func() (isoeditor.ImageReader, error)) error {
+	iso, err := generate()
+	if err != nil {
+		return err
+	}
+	defer iso.Close()
+
+	tmp, err := os.CreateTemp(c.dir, isoCacheTempPrefix+key+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	var reader io.Reader = iso
+	if c.maxSize > 0 {
+		// the ISOs that can't fit are not cached
+		reader = io.LimitReader(iso, c.maxSize+1)
+	}
+	size, err := io.Copy(tmp, reader)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return err
+	}
+	if c.maxSize > 0 && size > c.maxSize {
+		log.Infof("The ISO %s is larger than the cache of %d bytes", key, c.maxSize)
+		return nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		return err
+	}
+	c.entries[key] = c.lru.PushFront(&isoCacheEntry{key: key, size: size})
+	c.size += size
+	for c.maxSize > 0 && c.size > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+	return nil
+}
+
+// remove evicts the ISO of the element, with the lock held
+func (c *ISOCache) remove(element *list.Element) {
+	entry := c.lru.Remove(element).(*isoCacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+	if err := os.Remove(c.path(entry.key)); err != nil && !os.IsNotExist(err) {
+		log.WithError(err).Warnf("Failed to evict the cached ISO %s", entry.key)
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+var _ = Describe("ISOCache", func() {
+	var (
+		dir       string
+		isoFile   string
+		generated atomic.Int32
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "isoCacheTest")
+		Expect(err).NotTo(HaveOccurred())
+		isoFile = filepath.Join(dir, "customized.iso")
+		Expect(os.WriteFile(isoFile, []byte("customizedisocontent"), 0600)).To(Succeed())
+		generated.Store(0)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	generate := func() (isoeditor.ImageReader, error) {
+		generated.Add(1)
+		return os.Open(isoFile)
+	}
+
+	newCache := func(maxSize int64) *ISOCache {
+		cache, err := NewISOCache(filepath.Join(dir, "cache"), maxSize)
+		Expect(err).NotTo(HaveOccurred())
+		return cache
+	}
+
+	cached := func(cache *ISOCache, key string) func() bool {
+		return func() bool {
+			f, ok := cache.open(key)
+			if ok {
+				f.Close()
+			}
+			return ok
+		}
+	}
+
+	It("serves the ISOs once they are generated", func() {
+		cache := newCache(0)
+		_, ok := cache.open("key1")
+		Expect(ok).To(BeFalse())
+
+		cache.fill("key1", nil, generate)
+		Eventually(cached(cache, "key1")).Should(BeTrue())
+		f, ok := cache.open("key1")
+		Expect(ok).To(BeTrue())
+		defer f.Close()
+		Expect(io.ReadAll(f)).To(Equal([]byte("customizedisocontent")))
+
+		cache.fill("key1", nil, generate)
+		Consistently(generated.Load).Should(Equal(int32(1)))
+	})
+
+	It("evicts the least recently served ISOs", func() {
+		cache := newCache(50)
+		for _, key := range []string{"key1", "key2"} {
+			cache.fill(key, nil, generate)
+			Eventually(cached(cache, key)).Should(BeTrue())
+		}
+		// key1 is served more recently than key2
+		Expect(cached(cache, "key1")()).To(BeTrue())
+
+		cache.fill("key3", nil, generate)
+		Eventually(cached(cache, "key3")).Should(BeTrue())
+		Expect(cached(cache, "key2")()).To(BeFalse())
+		Expect(cached(cache, "key1")()).To(BeTrue())
+		Expect(os.ReadDir(filepath.Join(dir, "cache"))).To(HaveLen(2))
+	})
+
+	It("doesn't cache the ISOs larger than the cache", func() {
+		cache := newCache(10)
+		cache.fill("key1", nil, generate)
+		Eventually(generated.Load).Should(Equal(int32(1)))
+		Consistently(cached(cache, "key1")).Should(BeFalse())
+		Eventually(func() ([]os.DirEntry, error) { return os.ReadDir(filepath.Join(dir, "cache")) }).Should(BeEmpty())
+	})
+
+	It("bounds the ISOs generated at once", func() {
+		cache := newCache(0)
+		release := make(chan struct{})
+		blocking := func() (isoeditor.ImageReader, error) {
+			generated.Add(1)
+			<-release
+			return os.Open(isoFile)
+		}
+		for i := 0; i <= maxISOCacheFills; i++ {
+			cache.fill(fmt.Sprintf("key%d", i), nil, blocking)
+		}
+		Eventually(generated.Load).Should(Equal(int32(maxISOCacheFills)))
+		Consistently(generated.Load).Should(Equal(int32(maxISOCacheFills)))
+		close(release)
+		Eventually(cached(cache, "key0")).Should(BeTrue())
+		Expect(cached(cache, fmt.Sprintf("key%d", maxISOCacheFills))()).To(BeFalse())
+	})
+
+	It("counts the ISOs generated against the streams of the limiter", func() {
+		cache := newCache(0)
+		limiter := newStreamLimiter(StreamLimits{MaxStreams: 1})
+		Expect(limiter.takeBackgroundStream()).To(BeTrue())
+		cache.fill("key1", limiter, generate)
+		Consistently(generated.Load).Should(Equal(int32(0)))
+
+		limiter.releaseBackgroundStream()
+		cache.fill("key1", limiter, generate)
+		Eventually(cached(cache, "key1")).Should(BeTrue())
+		Eventually(func() int {
+			limiter.lock.Lock()
+			defer limiter.lock.Unlock()
+			return limiter.streams
+		}).Should(Equal(0))
+	})
+
+	It("removes the ISOs of the previous runs", func() {
+		Expect(os.MkdirAll(filepath.Join(dir, "cache"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "cache", "key1.iso"), []byte("stale"), 0600)).To(Succeed())
+		cache := newCache(0)
+		Expect(cached(cache, "key1")()).To(BeFalse())
+		Expect(os.ReadDir(filepath.Join(dir, "cache"))).To(BeEmpty())
+	})
+})
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,6 +10,8 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/golang/mock/gomock"
@@ -73,6 +77,7 @@ var _ = Describe("ServeHTTP", func() {
 
 		mockImage := func(version, imageType, arch string) {
 			mockImageStore.EXPECT().HaveVersion(version, arch).Return(true).AnyTimes()
+			mockImageStore.EXPECT().Available(version, arch).Return(true).AnyTimes()
 
 			var imageFile string
 			switch imageType {
@@ -155,7 +160,7 @@ var _ = Describe("ServeHTTP", func() {
 					Expect(err).NotTo(HaveOccurred())
 
 					initrdContent = nil
-					mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes, kargs []byte) (isoeditor.ImageReader, error) {
+					mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
 						defer GinkgoRecover()
 						Expect(ignition.Config).To(Equal([]byte(ignitionContent)))
 						if isoPath == minImageFilename {
@@ -199,6 +204,86 @@ var _ = Describe("ServeHTTP", func() {
 					expectSuccessfulResponse(resp, []byte("someisocontent"))
 				})
 
+				It("resumes the downloads with range requests", func() {
+					mockImage("4.8", imagestore.ImageTypeFull, defaultArch)
+					path := fmt.Sprintf("/byid/%s/4.8/x86_64/full.iso", imageID)
+					initIgnitionHandler("discovery_iso_type=full-iso&file_name=discovery.ign")
+					setInfraenvKargsHandlerSuccess()
+					resp, err := client.Get(server.URL + path)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(resp.Header.Get("Accept-Ranges")).To(Equal("bytes"))
+					etag := resp.Header.Get("ETag")
+					Expect(etag).NotTo(BeEmpty())
+
+					getRange := func(ifRange string) *http.Response {
+						initIgnitionHandler("discovery_iso_type=full-iso&file_name=discovery.ign")
+						setInfraenvKargsHandlerSuccess()
+						req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+						Expect(err).NotTo(HaveOccurred())
+						req.Header.Set("Range", "bytes=4-")
+						req.Header.Set("If-Range", ifRange)
+						resp, err := client.Do(req)
+						Expect(err).NotTo(HaveOccurred())
+						return resp
+					}
+
+					resp = getRange(etag)
+					Expect(resp.StatusCode).To(Equal(http.StatusPartialContent))
+					Expect(resp.Header.Get("Content-Range")).To(Equal("bytes 4-13/14"))
+					Expect(io.ReadAll(resp.Body)).To(Equal([]byte("isocontent")))
+
+					// the image changed since the interrupted download
+					resp = getRange(`"other"`)
+					Expect(resp.StatusCode).To(Equal(http.StatusOK))
+					Expect(io.ReadAll(resp.Body)).To(Equal([]byte("someisocontent")))
+				})
+
+				It("answers HEAD with the size and the digest of the image", func() {
+					mockImage("4.8", imagestore.ImageTypeFull, defaultArch)
+					path := fmt.Sprintf("/byid/%s/4.8/x86_64/full.iso", imageID)
+					head := func() *http.Response {
+						initIgnitionHandler("discovery_iso_type=full-iso&file_name=discovery.ign")
+						setInfraenvKargsHandlerSuccess()
+						resp, err := client.Head(server.URL + path)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(resp.StatusCode).To(Equal(http.StatusOK))
+						Expect(resp.ContentLength).To(Equal(int64(len("someisocontent"))))
+						Expect(resp.Header.Get("ETag")).NotTo(BeEmpty())
+						return resp
+					}
+					// the digest is learned when the image is first served
+					Expect(head().Header.Get("Digest")).To(BeEmpty())
+
+					initIgnitionHandler("discovery_iso_type=full-iso&file_name=discovery.ign")
+					setInfraenvKargsHandlerSuccess()
+					resp, err := client.Get(server.URL + path)
+					Expect(err).NotTo(HaveOccurred())
+					expectSuccessfulResponse(resp, []byte("someisocontent"))
+
+					sum := sha256.Sum256([]byte("someisocontent"))
+					Expect(head().Header.Get("Digest")).To(Equal("sha-256=" + base64.StdEncoding.EncodeToString(sum[:])))
+				})
+
+				It("answers the requests for a cached image with 304", func() {
+					mockImage("4.8", imagestore.ImageTypeFull, defaultArch)
+					path := fmt.Sprintf("/byid/%s/4.8/x86_64/full.iso", imageID)
+					get := func(ifNoneMatch string) *http.Response {
+						initIgnitionHandler("discovery_iso_type=full-iso&file_name=discovery.ign")
+						setInfraenvKargsHandlerSuccess()
+						req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+						Expect(err).NotTo(HaveOccurred())
+						if ifNoneMatch != "" {
+							req.Header.Set("If-None-Match", ifNoneMatch)
+						}
+						resp, err := client.Do(req)
+						Expect(err).NotTo(HaveOccurred())
+						return resp
+					}
+					etag := get("").Header.Get("ETag")
+					Expect(get(etag).StatusCode).To(Equal(http.StatusNotModified))
+					Expect(get(`"other"`).StatusCode).To(Equal(http.StatusOK))
+				})
+
 				It("uses the arch parameter", func() {
 					initIgnitionHandler("discovery_iso_type=full-iso&file_name=discovery.ign")
 					mockImage("4.8", imagestore.ImageTypeFull, "arm64")
@@ -250,6 +335,18 @@ var _ = Describe("ServeHTTP", func() {
 					Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
 				})
 
+				It("asks to retry while the version is being downloaded", func() {
+					initIgnitionHandler("discovery_iso_type=full-iso&file_name=discovery.ign")
+					setInfraenvKargsHandlerSuccess()
+					mockImageStore.EXPECT().HaveVersion("4.8", defaultArch).Return(true)
+					mockImageStore.EXPECT().Available("4.8", defaultArch).Return(false)
+					path := fmt.Sprintf("/byid/%s/4.8/x86_64/full.iso", imageID)
+					resp, err := client.Get(server.URL + path)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+					Expect(resp.Header.Get("Retry-After")).To(Equal("30"))
+				})
+
 				It("fails when no type is supplied", func() {
 					mockImageStore.EXPECT().HaveVersion("4.8", defaultArch).Return(true)
 					path := fmt.Sprintf("/byid/%s/4.8/x86_64/", imageID)
@@ -286,14 +383,14 @@ var _ = Describe("ServeHTTP", func() {
 					Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
 				})
 
-				It("fails when kargs are supplied for an s390x image", func() {
+				It("returns an s390x image with kargs", func() {
 					initIgnitionHandler("discovery_iso_type=full-iso&file_name=discovery.ign")
 					mockImage("4.11", imagestore.ImageTypeFull, "s390x")
 					path := fmt.Sprintf("/byid/%s/4.11/s390x/full.iso", imageID)
 					setInfraenvKargsHandlerSuccess("arg")
 					resp, err := client.Get(server.URL + path)
 					Expect(err).NotTo(HaveOccurred())
-					Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+					expectSuccessfulResponse(resp, []byte("someisocontent"))
 				})
 			})
 
@@ -310,7 +407,7 @@ var _ = Describe("ServeHTTP", func() {
 				u, err := url.Parse(assistedServer.URL())
 				Expect(err).NotTo(HaveOccurred())
 
-				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes, kargs []byte) (isoeditor.ImageReader, error) {
+				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
 					defer GinkgoRecover()
 					Expect(ignition.Config).To(Equal([]byte(ignitionContent)))
 					return os.Open(isoPath)
@@ -354,7 +451,7 @@ var _ = Describe("ServeHTTP", func() {
 				u, err := url.Parse(assistedServer.URL())
 				Expect(err).NotTo(HaveOccurred())
 
-				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes, kargs []byte) (isoeditor.ImageReader, error) {
+				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
 					defer GinkgoRecover()
 					Expect(ignition.Config).To(Equal([]byte(ignitionContent)))
 					return os.Open(isoPath)
@@ -394,7 +491,7 @@ var _ = Describe("ServeHTTP", func() {
 				u, err := url.Parse(assistedServer.URL())
 				Expect(err).NotTo(HaveOccurred())
 
-				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes, kargs []byte) (isoeditor.ImageReader, error) {
+				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
 					defer GinkgoRecover()
 					Expect(ignition.Config).To(Equal([]byte(ignitionContent)))
 					return os.Open(isoPath)
@@ -432,9 +529,9 @@ var _ = Describe("ServeHTTP", func() {
 				u, err := url.Parse(assistedServer.URL())
 				Expect(err).NotTo(HaveOccurred())
 
-				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes, kargs []byte) (isoeditor.ImageReader, error) {
+				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
 					defer GinkgoRecover()
-					Expect(kargs).To(Equal([]byte(" " + strings.Join(kernelArguments, " ") + "\n")))
+					Expect(kargs).To(Equal(isoeditor.AppendKernelArguments(kernelArguments)))
 					return os.Open(isoPath)
 				}
 
@@ -459,6 +556,107 @@ var _ = Describe("ServeHTTP", func() {
 				Expect(resp.StatusCode).To(Equal(http.StatusOK))
 			})
 
+			It("passes replace and delete kargs", func() {
+				initIgnitionHandler("discovery_iso_type=full-iso&file_name=discovery.ign")
+				assistedServer.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("GET", fmt.Sprintf(infraEnvPathFormat, imageID)),
+						ghttp.RespondWith(http.StatusOK, `{"kernel_arguments": "[{\"operation\": \"replace\", \"value\": \"console=ttyS0\"}, {\"operation\": \"delete\", \"value\": \"quiet\"}]"}`, header),
+					),
+				)
+				u, err := url.Parse(assistedServer.URL())
+				Expect(err).NotTo(HaveOccurred())
+
+				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
+					defer GinkgoRecover()
+					Expect(kargs).To(Equal(isoeditor.KernelArguments{
+						{Operation: isoeditor.KargsOperationReplace, Value: "console=ttyS0"},
+						{Operation: isoeditor.KargsOperationDelete, Value: "quiet"},
+					}))
+					return os.Open(isoPath)
+				}
+
+				asc, err := NewAssistedServiceClient(u.Scheme, u.Host, "")
+				Expect(err).NotTo(HaveOccurred())
+
+				handler := &ImageHandler{
+					byID: &isoHandler{
+						ImageStore:          mockImageStore,
+						GenerateImageStream: mockImageStream,
+						client:              asc,
+						urlParser:           parseShortURL,
+					},
+				}
+				server := httptest.NewServer(handler.router(1))
+				defer server.Close()
+
+				mockImage("4.8", imagestore.ImageTypeFull, defaultArch)
+				path := fmt.Sprintf("/byid/%s/4.8/x86_64/full.iso", imageID)
+				resp, err := server.Client().Get(server.URL + path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+
+			It("renders templated kargs", func() {
+				initIgnitionHandler("discovery_iso_type=full-iso&file_name=discovery.ign")
+				setInfraenvKargsHandlerSuccess("infraenv={{.InfraEnvID}}", "arch={{.CPUArchitecture}}")
+				u, err := url.Parse(assistedServer.URL())
+				Expect(err).NotTo(HaveOccurred())
+
+				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
+					defer GinkgoRecover()
+					Expect(kargs).To(Equal(isoeditor.AppendKernelArguments([]string{"infraenv=" + imageID, "arch=x86_64"})))
+					return os.Open(isoPath)
+				}
+
+				asc, err := NewAssistedServiceClient(u.Scheme, u.Host, "")
+				Expect(err).NotTo(HaveOccurred())
+
+				handler := &ImageHandler{
+					byID: &isoHandler{
+						ImageStore:          mockImageStore,
+						GenerateImageStream: mockImageStream,
+						client:              asc,
+						urlParser:           parseShortURL,
+					},
+				}
+				server := httptest.NewServer(handler.router(1))
+				defer server.Close()
+
+				mockImage("4.8", imagestore.ImageTypeFull, defaultArch)
+				path := fmt.Sprintf("/byid/%s/4.8/x86_64/full.iso", imageID)
+				resp, err := server.Client().Get(server.URL + path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+
+			It("fails with unresolved kargs placeholders", func() {
+				initIgnitionHandler("discovery_iso_type=full-iso&file_name=discovery.ign")
+				setInfraenvKargsHandlerSuccess("url={{.RootfsURL}}")
+				u, err := url.Parse(assistedServer.URL())
+				Expect(err).NotTo(HaveOccurred())
+
+				asc, err := NewAssistedServiceClient(u.Scheme, u.Host, "")
+				Expect(err).NotTo(HaveOccurred())
+
+				handler := &ImageHandler{
+					byID: &isoHandler{
+						ImageStore:          mockImageStore,
+						GenerateImageStream: isoeditor.NewRHCOSStreamReader,
+						client:              asc,
+						urlParser:           parseShortURL,
+					},
+				}
+				server := httptest.NewServer(handler.router(1))
+				defer server.Close()
+
+				mockImage("4.8", imagestore.ImageTypeFull, defaultArch)
+				path := fmt.Sprintf("/byid/%s/4.8/x86_64/full.iso", imageID)
+				resp, err := server.Client().Get(server.URL + path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+			})
+
 			It("passes image_token param through to assisted requests header", func() {
 				assistedPath := fmt.Sprintf(fileRouteFormat, imageID)
 				// generated at https://jwt.io/ with payload:
@@ -478,7 +676,7 @@ var _ = Describe("ServeHTTP", func() {
 				u, err := url.Parse(assistedServer.URL())
 				Expect(err).NotTo(HaveOccurred())
 
-				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes, kargs []byte) (isoeditor.ImageReader, error) {
+				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
 					defer GinkgoRecover()
 					Expect(ignition.Config).To(Equal([]byte(ignitionContent)))
 					return os.Open(isoPath)
@@ -530,7 +728,8 @@ var _ = Describe("ServeHTTP", func() {
 				server := httptest.NewServer(handler.router(1))
 				defer server.Close()
 
-				mockImage("4.8", imagestore.ImageTypeFull, defaultArch)
+				// the usage of the version isn't recorded for the requests that fail to authenticate
+				mockImageStore.EXPECT().HaveVersion("4.8", defaultArch).Return(true)
 				path := fmt.Sprintf("/byid/%s/4.8/x86_64/full.iso", imageID)
 				resp, err := server.Client().Get(server.URL + path)
 				Expect(err).NotTo(HaveOccurred())
@@ -590,7 +789,7 @@ var _ = Describe("ServeHTTP", func() {
 					Expect(err).NotTo(HaveOccurred())
 
 					initrdContent = nil
-					mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes, kargs []byte) (isoeditor.ImageReader, error) {
+					mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
 						defer GinkgoRecover()
 						Expect(ignition.Config).To(Equal([]byte(ignitionContent)))
 						if isoPath == minImageFilename {
@@ -722,14 +921,14 @@ var _ = Describe("ServeHTTP", func() {
 					Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
 				})
 
-				It("fails when kargs are supplied for an s390x image", func() {
+				It("returns an s390x image with kargs", func() {
 					initIgnitionHandler("discovery_iso_type=full-iso&file_name=discovery.ign")
 					mockImage("4.11", imagestore.ImageTypeFull, "s390x")
 					path := fmt.Sprintf("/images/%s?version=4.11&type=full-iso&arch=s390x", imageID)
 					setInfraenvKargsHandlerSuccess("arg")
 					resp, err := client.Get(server.URL + path)
 					Expect(err).NotTo(HaveOccurred())
-					Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+					expectSuccessfulResponse(resp, []byte("someisocontent"))
 				})
 			})
 
@@ -746,7 +945,7 @@ var _ = Describe("ServeHTTP", func() {
 				u, err := url.Parse(assistedServer.URL())
 				Expect(err).NotTo(HaveOccurred())
 
-				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes, kargs []byte) (isoeditor.ImageReader, error) {
+				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
 					defer GinkgoRecover()
 					Expect(ignition.Config).To(Equal([]byte(ignitionContent)))
 					return os.Open(isoPath)
@@ -790,7 +989,7 @@ var _ = Describe("ServeHTTP", func() {
 				u, err := url.Parse(assistedServer.URL())
 				Expect(err).NotTo(HaveOccurred())
 
-				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes, kargs []byte) (isoeditor.ImageReader, error) {
+				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
 					defer GinkgoRecover()
 					Expect(ignition.Config).To(Equal([]byte(ignitionContent)))
 					return os.Open(isoPath)
@@ -828,9 +1027,9 @@ var _ = Describe("ServeHTTP", func() {
 				u, err := url.Parse(assistedServer.URL())
 				Expect(err).NotTo(HaveOccurred())
 
-				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes, kargs []byte) (isoeditor.ImageReader, error) {
+				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
 					defer GinkgoRecover()
-					Expect(kargs).To(Equal([]byte(" " + strings.Join(kernelArguments, " ") + "\n")))
+					Expect(kargs).To(Equal(isoeditor.AppendKernelArguments(kernelArguments)))
 					return os.Open(isoPath)
 				}
 
@@ -869,7 +1068,7 @@ var _ = Describe("ServeHTTP", func() {
 				u, err := url.Parse(assistedServer.URL())
 				Expect(err).NotTo(HaveOccurred())
 
-				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes, kargs []byte) (isoeditor.ImageReader, error) {
+				mockImageStream := func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
 					defer GinkgoRecover()
 					Expect(ignition.Config).To(Equal([]byte(ignitionContent)))
 					return os.Open(isoPath)
@@ -969,6 +1168,136 @@ var _ = Describe("ServeHTTP", func() {
 	})
 })
 
+// createS390xKargsTestISO creates an s390x ISO whose kernel arguments are in its boot image and
+// its parameter file, with the boot image signed for secure IPL if signed
+func createS390xKargsTestISO(signed bool) string {
+	filesDir, err := os.MkdirTemp("", "isotest")
+	Expect(err).ToNot(HaveOccurred())
+	defer os.RemoveAll(filesDir)
+
+	temp, err := os.CreateTemp("", "handlers-test")
+	Expect(err).ToNot(HaveOccurred())
+	isoFile := temp.Name()
+	Expect(temp.Close()).To(Succeed())
+	Expect(os.Remove(isoFile)).To(Succeed())
+
+	kargs := "coreos.liveiso=rhcos-415 ignition.firstboot ignition.platform.id=metal\n"
+	area := kargs + strings.Repeat(" ", 128-len(kargs))
+	cdboot := "kernel" + area
+	if signed {
+		cdboot += "~Module signature appended~\n"
+	}
+	Expect(os.MkdirAll(filepath.Join(filesDir, "coreos"), 0755)).To(Succeed())
+	Expect(os.MkdirAll(filepath.Join(filesDir, "images/pxeboot"), 0755)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "generic.ins"), []byte(testGenericIns), 0600)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "images/cdboot.img"), []byte(cdboot), 0600)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "images/generic.prm"), []byte(area), 0600)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "images/ignition.img"), make([]byte, 256*1024), 0600)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "images/pxeboot/initrd.img"), []byte("this is initrd"), 0600)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "images/initrd.addrsize"), []byte{
+		1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0, 0, 0, 0, 0, 1}, 0600)).To(Succeed())
+	kargsConfig := `{"default": "` + strings.TrimSpace(kargs) + `", "size": 128, "files": [` +
+		`{"path": "images/cdboot.img", "offset": 6, "end": "\n", "pad": " "}, {"path": "images/generic.prm", "end": "\n", "pad": " "}]}`
+	Expect(os.WriteFile(filepath.Join(filesDir, "coreos/kargs.json"), []byte(kargsConfig), 0600)).To(Succeed())
+
+	cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", "s390x", "-o", isoFile, filesDir)
+	Expect(cmd.Run()).To(Succeed())
+	return isoFile
+}
+
+var _ = Describe("ServeHTTP of s390x ISOs", func() {
+	var (
+		ctrl           *gomock.Controller
+		mockImageStore *imagestore.MockImageStore
+		assistedServer *ghttp.Server
+		server         *httptest.Server
+		isoFile        string
+		imageID        = "bf25292a-dddd-49dc-ab9c-3fb4c1f07071"
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockImageStore = imagestore.NewMockImageStore(ctrl)
+		assistedServer = ghttp.NewServer()
+		u, err := url.Parse(assistedServer.URL())
+		Expect(err).NotTo(HaveOccurred())
+		asc, err := NewAssistedServiceClient(u.Scheme, u.Host, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		handler := &ImageHandler{
+			long: &isoHandler{
+				ImageStore:          mockImageStore,
+				GenerateImageStream: isoeditor.RHCOSStreamGenerator(isoeditor.KargsConflictKeepAll),
+				client:              asc,
+				urlParser:           parseLongURL,
+			},
+		}
+		server = httptest.NewServer(handler.router(1))
+	})
+
+	AfterEach(func() {
+		os.Remove(isoFile)
+		server.Close()
+		assistedServer.Close()
+	})
+
+	get := func() *http.Response {
+		mockImageStore.EXPECT().HaveVersion("4.15", "s390x").Return(true)
+		mockImageStore.EXPECT().Available("4.15", "s390x").Return(true)
+		mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, "4.15", "s390x").Return(isoFile)
+		assistedServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(fileRouteFormat, imageID), "discovery_iso_type=full-iso&file_name=discovery.ign"),
+				ghttp.RespondWith(http.StatusOK, "someignitioncontent"),
+			),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(infraEnvPathFormat, imageID)),
+				ghttp.RespondWith(http.StatusOK, `{"kernel_arguments": "[{\"operation\": \"append\", \"value\": \"ip=dhcp\"}]"}`),
+			),
+		)
+		resp, err := server.Client().Get(fmt.Sprintf("%s/images/%s?version=4.15&type=full-iso&arch=s390x", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	It("edits the kernel arguments of the boot image and the parameter file", func() {
+		isoFile = createS390xKargsTestISO(false)
+		resp := get()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		streamed, err := os.CreateTemp("", "handlers-test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.Remove(streamed.Name())
+		_, err = io.Copy(streamed, resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(streamed.Close()).To(Succeed())
+		info, err := os.Stat(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Stat(streamed.Name())).To(HaveField("Size()", info.Size()))
+
+		expected := "coreos.liveiso=rhcos-415 ignition.firstboot ignition.platform.id=metal ip=dhcp\n"
+		expected += strings.Repeat(" ", 128-len(expected))
+		prm, err := isoeditor.ReadFileFromISO(streamed.Name(), "/images/generic.prm")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(prm)).To(Equal(expected))
+		cdboot, err := isoeditor.ReadFileFromISO(streamed.Name(), "/images/cdboot.img")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(cdboot)).To(Equal("kernel" + expected))
+		addrsize, err := isoeditor.ReadFileFromISO(streamed.Name(), "/images/initrd.addrsize")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrsize).To(Equal([]byte{1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0, 0, 0, 0, 0, byte(len("this is initrd"))}))
+	})
+
+	It("refuses to edit the kernel arguments of ISOs built for secure IPL", func() {
+		isoFile = createS390xKargsTestISO(true)
+		resp := get()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(ContainSubstring("secure IPL"))
+	})
+})
+
 var _ = Describe("readiness handler", func() {
 	It("Not ready to Ready", func() {
 		readinessHandler := NewReadinessHandler()
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	metricsmiddleware "github.com/slok/go-http-metrics/middleware"
+
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+// MeasuredHandler is like the std handler of go-http-metrics, but the response writer it passes
+// to h keeps supporting io.ReaderFrom so that the images can still be sent with sendfile
+func MeasuredHandler(handlerID string, mdw metricsmiddleware.Middleware, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mw := &measuredWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		mdw.Measure(handlerID, &measuredReporter{w: mw, r: r}, func() {
+			h.ServeHTTP(mw, r)
+		})
+	})
+}
+
+type measuredReporter struct {
+	w *measuredWriter
+	r *http.Request
+}
+
+func (m *measuredReporter) Method() string           { return m.r.Method }
+func (m *measuredReporter) Context() context.Context { return m.r.Context() }
+func (m *measuredReporter) URLPath() string          { return m.r.URL.Path }
+func (m *measuredReporter) StatusCode() int          { return m.w.statusCode }
+func (m *measuredReporter) BytesWritten() int64      { return m.w.bytesWritten }
+
+// measuredWriter records the status and the size of the response
+type measuredWriter struct {
+	http.ResponseWriter
+	statusCode   int
+	bytesWritten int64
+}
+
+func (w *measuredWriter) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *measuredWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.bytesWritten += int64(n)
+	return n, err
+}
+
+func (w *measuredWriter) ReadFrom(r io.Reader) (int64, error) {
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = overlay.Copy(struct{ io.Writer }{w.ResponseWriter}, r)
+	}
+	w.bytesWritten += n
+	return n, err
+}
+
+func (w *measuredWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *measuredWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -5,36 +5,73 @@ import (
 	"strings"
 	"time"
 
+	"github.com/openshift/assisted-image-service/pkg/servers"
 	"github.com/rs/cors"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sync/semaphore"
 )
 
-func WithCORSMiddleware(handler http.Handler, domains string) http.Handler {
-	domainList := strings.Split(strings.ReplaceAll(domains, " ", ""), ",")
+// CORSOptions configure the responses to the cross-origin requests, e.g. of browser-based consoles
+// fetching the images or triggering their builds
+type CORSOptions struct {
+	// AllowedOrigins, "*" allows any origin
+	AllowedOrigins []string
+	// AllowedMethods are GET and HEAD when empty
+	AllowedMethods []string
+	// AllowedHeaders are Authorization and Content-Type when empty
+	AllowedHeaders []string
+	// ExposedHeaders are readable by the scripts of the origins, defaultCORSExposedHeaders when
+	// empty
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long the preflight responses are cached, 10 minutes when zero
+	MaxAge time.Duration
+}
+
+// defaultCORSExposedHeaders are the headers a console needs to name, verify and poll the downloads
+var defaultCORSExposedHeaders = []string{"Content-Disposition", "Content-Length", "ETag", "Digest", "Location", "Retry-After"}
+
+// ParseCORSOrigins splits a comma separated list of origins
+func ParseCORSOrigins(origins string) []string {
+	return strings.Split(strings.ReplaceAll(origins, " ", ""), ",")
+}
+
+func WithCORSMiddleware(handler http.Handler, options CORSOptions) http.Handler {
+	methods := options.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodHead, http.MethodGet}
+	}
+	headers := options.AllowedHeaders
+	if len(headers) == 0 {
+		headers = []string{"Authorization", "Content-Type"}
+	}
+	exposedHeaders := options.ExposedHeaders
+	if len(exposedHeaders) == 0 {
+		exposedHeaders = defaultCORSExposedHeaders
+	}
+	maxAge := options.MaxAge
+	if maxAge == 0 {
+		maxAge = 10 * time.Minute
+	}
 
 	corsHandler := cors.New(cors.Options{
-		Debug: false,
-		AllowedMethods: []string{
-			http.MethodHead,
-			http.MethodGet,
-		},
-		AllowedOrigins: domainList,
-		AllowedHeaders: []string{
-			"Authorization",
-			"Content-Type",
-		},
-		MaxAge: int((10 * time.Minute).Seconds()),
+		Debug:            false,
+		AllowedMethods:   methods,
+		AllowedOrigins:   options.AllowedOrigins,
+		AllowedHeaders:   headers,
+		ExposedHeaders:   exposedHeaders,
+		AllowCredentials: options.AllowCredentials,
+		MaxAge:           int(maxAge.Seconds()),
 	})
 	return corsHandler.Handler(handler)
 }
 
 func WithInitrdViaHTTP(handler http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Check plain HTTP requests
-		if r.TLS == nil {
-			if !strings.HasSuffix(r.URL.Path, "/pxe-initrd") {
-				// Only "/pxe-initrd" is allowed to be fetched
+		// Check plain HTTP requests, except the ones of the local listeners
+		if r.TLS == nil && !servers.IsLocal(r.Context()) {
+			if !strings.HasSuffix(r.URL.Path, "/pxe-initrd") && !strings.HasSuffix(r.URL.Path, "/pxe-script") {
+				// Only "/pxe-initrd" and "/pxe-script" are allowed to be fetched
 				http.NotFound(w, r)
 				return
 			}
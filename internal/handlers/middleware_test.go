@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -22,7 +23,7 @@ var _ = Describe("WithCORSMiddleware", func() {
 		})
 		allowedURLs := "https://test.example.com, https://other.example.com"
 
-		server = httptest.NewServer(WithCORSMiddleware(baseHandler, allowedURLs))
+		server = httptest.NewServer(WithCORSMiddleware(baseHandler, CORSOptions{AllowedOrigins: ParseCORSOrigins(allowedURLs)}))
 		client = server.Client()
 	})
 
@@ -72,6 +73,64 @@ var _ = Describe("WithCORSMiddleware", func() {
 		respHeaderValue = doRequestWithOrigin(http.MethodHead, "")
 		Expect(respHeaderValue).To(Equal(""))
 	})
+
+	preflight := func(method string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, server.URL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Origin", "https://test.example.com")
+		req.Header.Set("Access-Control-Request-Method", method)
+		resp, err := client.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	It("allows GET and HEAD by default", func() {
+		resp := preflight(http.MethodGet)
+		Expect(resp.Header.Get("Access-Control-Allow-Origin")).To(Equal("https://test.example.com"))
+		Expect(resp.Header.Get("Access-Control-Max-Age")).To(Equal("600"))
+
+		resp = preflight(http.MethodPost)
+		Expect(resp.Header.Get("Access-Control-Allow-Origin")).To(Equal(""))
+	})
+
+	It("exposes the download headers", func() {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Origin", "https://test.example.com")
+		resp, err := client.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Header.Get("Access-Control-Expose-Headers")).To(ContainSubstring("Content-Disposition"))
+		Expect(resp.Header.Get("Access-Control-Expose-Headers")).To(ContainSubstring("Retry-After"))
+	})
+
+	Context("with configured options", func() {
+		BeforeEach(func() {
+			server.Close()
+			server = httptest.NewServer(WithCORSMiddleware(http.NotFoundHandler(), CORSOptions{
+				AllowedOrigins:   []string{"https://test.example.com"},
+				AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+				AllowedHeaders:   []string{"X-Custom"},
+				AllowCredentials: true,
+				MaxAge:           time.Minute,
+			}))
+			client = server.Client()
+		})
+
+		It("uses them", func() {
+			req, err := http.NewRequest(http.MethodOptions, server.URL, nil)
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Set("Origin", "https://test.example.com")
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			req.Header.Set("Access-Control-Request-Headers", "X-Custom")
+			resp, err := client.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Header.Get("Access-Control-Allow-Origin")).To(Equal("https://test.example.com"))
+			Expect(resp.Header.Get("Access-Control-Allow-Methods")).To(Equal(http.MethodPost))
+			Expect(resp.Header.Get("Access-Control-Allow-Headers")).To(Equal("X-Custom"))
+			Expect(resp.Header.Get("Access-Control-Allow-Credentials")).To(Equal("true"))
+			Expect(resp.Header.Get("Access-Control-Max-Age")).To(Equal("60"))
+		})
+	})
 })
 
 var _ = Describe("WithInitrdViaHTTPMiddleware", func() {
@@ -118,6 +177,9 @@ var _ = Describe("WithInitrdViaHTTPMiddleware", func() {
 		respStatus = doRequestWithPath("/images/a7acfb01-d89f-40c8-82d7-02b20cf00173/pxe-initrd", map[string]string{"arch": "no-such-arch"})
 		Expect(respStatus).To(Equal(200))
 
+		respStatus = doRequestWithPath("/images/a7acfb01-d89f-40c8-82d7-02b20cf00173/pxe-script", map[string]string{"version": "4.9"})
+		Expect(respStatus).To(Equal(200))
+
 		respStatus = doRequestWithPath("/images/foo/", map[string]string{})
 		Expect(respStatus).To(Equal(404))
 
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+)
+
+// Names of the files of the netboot trees, in addition to the ones of the PXE bundles
+const (
+	netbootCmdline        = "cmdline"
+	netbootPxelinuxConfig = "pxelinux.cfg/default"
+)
+
+// netbootHandler serves a netboot tree, the artifacts of the PXE bundle with their kernel arguments
+// and a pxelinux config, as a tar archive. When outputDir is set, the tree can also be written
+// to outputDir/<image_id> with POST, e.g. to the root of a TFTP server.
+type netbootHandler struct {
+	ImageStore imagestore.ImageStore
+	client     *AssistedServiceClient
+	outputDir  string
+}
+
+var _ http.Handler = &netbootHandler{}
+
+func (h *netbootHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	allowed := []string{http.MethodGet, http.MethodHead}
+	if h.outputDir != "" {
+		allowed = append(allowed, http.MethodPost)
+	}
+	if !slices.Contains(allowed, r.Method) {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	imageID := chi.URLParam(r, "image_id")
+	compress, ok := zstdRequested(w, r)
+	if !ok {
+		return
+	}
+	artifacts, ok := openPXEArtifacts(w, r, h.ImageStore, h.client)
+	if !ok {
+		return
+	}
+	defer artifacts.Close()
+
+	ipxePrefix, grubPrefix := configPrefixes(r)
+	kernel, initrd, rootfs := pxeBundleKernel, pxeBundleInitrd, pxeBundleRootfs
+	files := []*pxeBundleFile{
+		{name: kernel, content: artifacts.kernel},
+		{name: initrd, content: artifacts.initrd},
+		{name: rootfs, content: artifacts.rootfs},
+		{name: netbootCmdline, content: strings.NewReader(strings.Join(artifacts.kargs, " ") + "\n")},
+		{name: pxeBundleIPXEScript, content: strings.NewReader(ipxeScript(ipxePrefix+kernel, []pxeInitrd{
+			{name: "initrd", url: ipxePrefix + initrd},
+			{name: "rootfs", url: ipxePrefix + rootfs},
+		}, artifacts.kargs))},
+		{name: pxeBundleGrubConfig, content: strings.NewReader(grubNetbootConfig(grubPrefix+kernel, []string{grubPrefix + initrd, grubPrefix + rootfs}, artifacts.kargs))},
+		// pxelinux loads the files relative to its own location, or from the URLs of lpxelinux
+		{name: netbootPxelinuxConfig, content: strings.NewReader(pxelinuxConfig(ipxePrefix+kernel, []string{ipxePrefix + initrd, ipxePrefix + rootfs}, artifacts.kargs))},
+	}
+
+	if r.Method != http.MethodPost {
+		serveTar(w, r, fmt.Sprintf("%s-netboot.tar", imageID), files, artifacts.modTime, compress)
+		return
+	}
+	dir := filepath.Join(h.outputDir, imageID)
+	if err := writeNetbootTree(dir, files, artifacts.modTime); err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "Failed to write the netboot tree to %s: %v", dir, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeNetbootTree writes the files to dir, replacing the previous tree once they are all written
+func writeNetbootTree(dir string, files []*pxeBundleFile, modTime time.Time) error {
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	// the tree is served by other services, e.g. a TFTP server
+	if err = os.Chmod(tmpDir, 0755); err != nil {
+		return err
+	}
+	for _, file := range files {
+		path := filepath.Join(tmpDir, file.name)
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err = writeNetbootFile(path, file.content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+		if err = os.Chtimes(path, modTime, modTime); err != nil {
+			return err
+		}
+	}
+	if err = os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Rename(tmpDir, dir)
+}
+
+func writeNetbootFile(path string, content io.Reader) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+)
+
+var _ = Describe("netboot ServeHTTP", func() {
+	var (
+		ctrl           *gomock.Controller
+		mockImageStore *imagestore.MockImageStore
+		assistedServer *ghttp.Server
+		asc            *AssistedServiceClient
+		server         *httptest.Server
+		isoFile        string
+		outputDir      string
+		imageID        = "bf25292a-dddd-49dc-ab9c-3fb4c1f07071"
+		kargs          = "random.trust_cpu=on ignition.firstboot ignition.platform.id=metal"
+	)
+
+	BeforeEach(func() {
+		var err error
+		isoFile = createTestISO()
+		outputDir, err = os.MkdirTemp("", "netbootTest")
+		Expect(err).NotTo(HaveOccurred())
+
+		ctrl = gomock.NewController(GinkgoT())
+		mockImageStore = imagestore.NewMockImageStore(ctrl)
+		assistedServer = ghttp.NewServer()
+		u, err := url.Parse(assistedServer.URL())
+		Expect(err).NotTo(HaveOccurred())
+		asc, err = NewAssistedServiceClient(u.Scheme, u.Host, "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.Remove(isoFile)
+		server.Close()
+		assistedServer.Close()
+		Expect(os.RemoveAll(outputDir)).To(Succeed())
+	})
+
+	startServer := func(outputDir string) {
+		handler := &ImageHandler{
+			netboot: &netbootHandler{
+				ImageStore: mockImageStore,
+				client:     asc,
+				outputDir:  outputDir,
+			},
+		}
+		server = httptest.NewServer(handler.router(1))
+	}
+
+	mockImage := func(version, arch string) {
+		mockImageStore.EXPECT().HaveVersion(version, arch).Return(true)
+		mockImageStore.EXPECT().Available(version, arch).Return(true)
+		mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, version, arch).Return(isoFile).Times(2)
+		assistedServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(fileRouteFormat, imageID), "file_name=discovery.ign"),
+				ghttp.RespondWith(http.StatusOK, "someignitioncontent", http.Header{"Last-Modified": {"Fri, 22 Apr 2022 18:11:09 GMT"}}),
+			),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf("/api/assisted-install/v2/infra-envs/%s/downloads/minimal-initrd", imageID)),
+				ghttp.RespondWith(http.StatusNoContent, []byte{}),
+			),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(infraEnvPathFormat, imageID)),
+				ghttp.RespondWith(http.StatusOK, "{}"),
+			),
+		)
+	}
+
+	expectTree := func(files map[string]string) {
+		Expect(files).To(HaveLen(7))
+		Expect(files["vmlinuz"]).To(Equal("this is kernel"))
+		Expect(files["initrd.img"]).To(HavePrefix("this is initrd"))
+		Expect(files["rootfs.img"]).To(Equal("this is rootfs"))
+		Expect(files["cmdline"]).To(Equal(kargs + "\n"))
+		Expect(files["boot.ipxe"]).To(ContainSubstring("kernel vmlinuz initrd=initrd initrd=rootfs"))
+		Expect(files["grub.cfg"]).To(ContainSubstring("\tinitrd /initrd.img /rootfs.img\n"))
+		Expect(files["pxelinux.cfg/default"]).To(Equal(fmt.Sprintf(`DEFAULT discovery
+PROMPT 0
+LABEL discovery
+	KERNEL vmlinuz
+	APPEND initrd=initrd.img,rootfs.img %s
+`, kargs)))
+	}
+
+	It("serves the tree as a tar archive", func() {
+		startServer("")
+		mockImage("4.11", defaultArch)
+		resp, err := server.Client().Get(fmt.Sprintf("%s/images/%s/netboot?version=4.11", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Disposition")).To(Equal(fmt.Sprintf("attachment; filename=%s-netboot.tar", imageID)))
+
+		files := map[string]string{}
+		tr := tar.NewReader(resp.Body)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			data, err := io.ReadAll(tr)
+			Expect(err).NotTo(HaveOccurred())
+			files[header.Name] = string(data)
+		}
+		expectTree(files)
+	})
+
+	It("writes the tree to the output directory, replacing the previous one", func() {
+		startServer(outputDir)
+		treeDir := filepath.Join(outputDir, imageID)
+		Expect(os.MkdirAll(treeDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(treeDir, "stale"), []byte("stale"), 0600)).To(Succeed())
+
+		mockImage("4.11", defaultArch)
+		resp, err := server.Client().Post(fmt.Sprintf("%s/images/%s/netboot?version=4.11", server.URL, imageID), "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+		files := map[string]string{}
+		Expect(filepath.Walk(treeDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			name, err := filepath.Rel(treeDir, path)
+			files[name] = string(data)
+			return err
+		})).To(Succeed())
+		expectTree(files)
+		Expect(os.ReadDir(outputDir)).To(HaveLen(1))
+	})
+
+	It("rejects writing the tree without an output directory", func() {
+		startServer("")
+		resp, err := server.Client().Post(fmt.Sprintf("%s/images/%s/netboot?version=4.11", server.URL, imageID), "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+		Expect(resp.Header.Get("Allow")).To(Equal("GET, HEAD"))
+	})
+})
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// maxNoCloudSeedSize bounds the body of the seed requests
+	maxNoCloudSeedSize = 1 << 20
+	// cloud-init looks for the NoCloud seed on the filesystem with this label
+	noCloudVolumeLabel = "cidata"
+)
+
+// noCloudHandler builds cloud-init NoCloud seed ISOs, for the helper VMs running next to the
+// discovery hosts of an image that don't boot CoreOS
+type noCloudHandler struct {
+	client  *AssistedServiceClient
+	workDir string
+}
+
+var _ http.Handler = &noCloudHandler{}
+
+// noCloudSeed is the JSON body of the seed requests, the content of the files of the seed
+type noCloudSeed struct {
+	UserData      string `json:"user_data"`
+	MetaData      string `json:"meta_data,omitempty"`
+	NetworkConfig string `json:"network_config,omitempty"`
+}
+
+// files returns the content of the files of the seed by name. Without meta-data, the instance-id
+// is derived from the content of the seed, so that cloud-init runs again when it changes.
+func (s *noCloudSeed) files() map[string][]byte {
+	files := map[string][]byte{"user-data": []byte(s.UserData), "meta-data": []byte(s.MetaData)}
+	if s.MetaData == "" {
+		sum := sha256.Sum256([]byte(s.UserData + "\x00" + s.NetworkConfig))
+		files["meta-data"] = []byte(fmt.Sprintf("instance-id: iid-%s\n", hex.EncodeToString(sum[:8])))
+	}
+	if s.NetworkConfig != "" {
+		files["network-config"] = []byte(s.NetworkConfig)
+	}
+	return files
+}
+
+func (h *noCloudHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// the seed doesn't carry content of the image, but is only built for the users of its infra-env
+	if code, err := h.client.authorizeInfraEnv(r, chi.URLParam(r, "image_id")); err != nil {
+		httpErrorf(w, code, "error retrieving infra-env: %v", err)
+		return
+	}
+	seed := &noCloudSeed{}
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxNoCloudSeedSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(seed); err != nil {
+		httpErrorf(w, http.StatusBadRequest, "invalid NoCloud seed: %v", err)
+		return
+	}
+	if seed.UserData == "" {
+		httpErrorf(w, http.StatusBadRequest, "invalid NoCloud seed: user_data is required")
+		return
+	}
+
+	serveSeedISO(w, r, h.workDir, noCloudVolumeLabel, "seed.iso", seed.files(), time.Now())
+}
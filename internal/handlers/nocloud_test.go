@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+var _ = Describe("noCloudHandler", func() {
+	var (
+		imageID        = "bf25292a-dddd-49dc-ab9c-3fb4c1f07071"
+		assistedServer *ghttp.Server
+		server         *httptest.Server
+		workDir        string
+	)
+
+	BeforeEach(func() {
+		var err error
+		workDir, err = os.MkdirTemp("", "nocloudTest")
+		Expect(err).NotTo(HaveOccurred())
+
+		assistedServer = ghttp.NewServer()
+		u, err := url.Parse(assistedServer.URL())
+		Expect(err).NotTo(HaveOccurred())
+		asc, err := NewAssistedServiceClient(u.Scheme, u.Host, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		handler := &ImageHandler{
+			noCloud: &noCloudHandler{client: asc, workDir: workDir},
+			limiter: newStreamLimiter(StreamLimits{}),
+		}
+		server = httptest.NewServer(handler.router(1))
+	})
+
+	AfterEach(func() {
+		assistedServer.Close()
+		server.Close()
+		Expect(os.RemoveAll(workDir)).To(Succeed())
+	})
+
+	authorize := func() {
+		assistedServer.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("GET", fmt.Sprintf(infraEnvPathFormat, imageID)),
+			ghttp.VerifyHeader(http.Header{"Authorization": []string{"Bearer mytoken"}}),
+			ghttp.RespondWith(http.StatusOK, `{"id": "bf25292a-dddd-49dc-ab9c-3fb4c1f07071"}`),
+		))
+	}
+
+	post := func(body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/images/%s/nocloud-seed", server.URL, imageID), strings.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("Authorization", "Bearer mytoken")
+		resp, err := server.Client().Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	readSeed := func(resp *http.Response) string {
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Disposition")).To(Equal("attachment; filename=seed.iso"))
+		isoPath := filepath.Join(workDir, "received.iso")
+		content, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(isoPath, content, 0600)).To(Succeed())
+		return isoPath
+	}
+
+	It("builds the seed ISO", func() {
+		authorize()
+		isoPath := readSeed(post(`{"user_data": "#cloud-config\nhostname: helper\n", "meta_data": "instance-id: helper\n", "network_config": "version: 2\n"}`))
+
+		Expect(isoeditor.VolumeIdentifier(isoPath)).To(Equal(noCloudVolumeLabel))
+		Expect(isoeditor.ReadFileFromISO(isoPath, "/user-data")).To(Equal([]byte("#cloud-config\nhostname: helper\n")))
+		Expect(isoeditor.ReadFileFromISO(isoPath, "/meta-data")).To(Equal([]byte("instance-id: helper\n")))
+		Expect(isoeditor.ReadFileFromISO(isoPath, "/network-config")).To(Equal([]byte("version: 2\n")))
+
+		entries, err := os.ReadDir(workDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("derives the instance-id from the seed without meta-data", func() {
+		authorize()
+		isoPath := readSeed(post(`{"user_data": "#cloud-config\n"}`))
+		metaData, err := isoeditor.ReadFileFromISO(isoPath, "/meta-data")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(metaData)).To(MatchRegexp(`^instance-id: iid-[0-9a-f]{16}\n$`))
+		_, err = isoeditor.ReadFileFromISO(isoPath, "/network-config")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("requires the user data", func() {
+		authorize()
+		Expect(post(`{"meta_data": "instance-id: helper\n"}`).StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("rejects unknown fields", func() {
+		authorize()
+		Expect(post(`{"user_data": "#cloud-config\n", "vendor_data": ""}`).StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("forwards the status of the infra-env request when it's denied", func() {
+		assistedServer.AppendHandlers(ghttp.RespondWith(http.StatusUnauthorized, nil))
+		Expect(post(`{"user_data": "#cloud-config\n"}`).StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("only accepts POST", func() {
+		resp, err := server.Client().Get(fmt.Sprintf("%s/images/%s/nocloud-seed", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+		Expect(resp.Header.Get("Allow")).To(Equal(http.MethodPost))
+		Expect(assistedServer.ReceivedRequests()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,648 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+)
+
+// The types of the OpenAPI 3.0 document of the HTTP API, limited to what the API uses
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Headers     map[string]*openAPIHeader    `json:"headers,omitempty"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIHeader struct {
+	Description string         `json:"description"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+}
+
+func stringSchema(description string, enum ...string) *openAPISchema {
+	return &openAPISchema{Type: "string", Description: description, Enum: enum}
+}
+
+func arraySchema(description string, items *openAPISchema) *openAPISchema {
+	return &openAPISchema{Type: "array", Description: description, Items: items}
+}
+
+// mapSchema is an object with string values, keyed by the keys described
+func mapSchema(description string) *openAPISchema {
+	return &openAPISchema{Type: "object", Description: description, AdditionalProperties: stringSchema("")}
+}
+
+func refSchema(name string) *openAPISchema {
+	return &openAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+func pathParam(name, description string, enum ...string) *openAPIParameter {
+	return &openAPIParameter{Name: name, In: "path", Required: true, Schema: stringSchema(description, enum...)}
+}
+
+func queryParam(name, description string, required bool, enum ...string) *openAPIParameter {
+	return &openAPIParameter{Name: name, In: "query", Description: description, Required: required, Schema: stringSchema("", enum...)}
+}
+
+func content(mediaType string, schema *openAPISchema) map[string]*openAPIMediaType {
+	return map[string]*openAPIMediaType{mediaType: {Schema: schema}}
+}
+
+var (
+	binarySchema = &openAPISchema{Type: "string", Format: "binary"}
+
+	imageIDParam    = pathParam("image_id", "ID of the image, usually the infra-env ID")
+	versionParam    = queryParam("version", "OpenShift version of the base image", true)
+	archParam       = queryParam("arch", "CPU architecture of the base image, x86_64 by default", false)
+	apiKeyParam     = queryParam("api_key", "API key authenticating the request to assisted service", false)
+	imageTokenParam = queryParam("image_token", "Image token authenticating the request to assisted service", false)
+	compressParam   = queryParam("compress", "Streams the artifact compressed, with this Content-Encoding and without Range support", false, compressZstd)
+	descriptorParam = queryParam("descriptor", "Serves the descriptor of the artifact, listing its mirrors and hashes, instead of the artifact", false, descriptorMetalink, descriptorTorrent, descriptorChunks)
+
+	retryResponse = &openAPIResponse{
+		Description: "The images of the version are being downloaded",
+		Headers: map[string]*openAPIHeader{
+			"Retry-After": {Description: "Seconds to wait before retrying", Schema: stringSchema("")},
+		},
+	}
+	overLimitResponse = &openAPIResponse{
+		Description: "Too many requests or streams, or the rootfs of a minimal ISO can't be downloaded",
+		Headers: map[string]*openAPIHeader{
+			"Retry-After": {Description: "Seconds to wait before retrying", Schema: stringSchema("")},
+		},
+	}
+)
+
+// textResponse is a response with a plain text body, such as the errors
+func textResponse(description string) *openAPIResponse {
+	return &openAPIResponse{Description: description, Content: content("text/plain", stringSchema(""))}
+}
+
+// downloadResponses are the responses of the image downloads
+func downloadResponses(description, mediaType string) map[string]*openAPIResponse {
+	return map[string]*openAPIResponse{
+		"200": {
+			Description: description,
+			Headers: map[string]*openAPIHeader{
+				"ETag":   {Description: "Same for the same customization of the same base image", Schema: stringSchema("")},
+				"Digest": {Description: "sha-256 of the image, once it has been served in full", Schema: stringSchema("")},
+			},
+			Content: content(mediaType, binarySchema),
+		},
+		"202": retryResponse,
+		"206": {Description: "The range of the image requested with Range", Content: content(mediaType, binarySchema)},
+		"304": {Description: "The image matches If-None-Match"},
+		"400": textResponse("Invalid request"),
+		"403": textResponse("The request is out of the scope of its token"),
+		"404": textResponse("Unknown version"),
+		"503": overLimitResponse,
+	}
+}
+
+// describedDownloadResponses are the responses of the downloads of the artifacts with descriptors
+func describedDownloadResponses(description, mediaType string) map[string]*openAPIResponse {
+	responses := downloadResponses(description, mediaType)
+	responses["200"].Content["application/metalink4+xml"] = &openAPIMediaType{Schema: stringSchema("The metalink 4 descriptor")}
+	responses["200"].Content["application/x-bittorrent"] = &openAPIMediaType{Schema: binarySchema}
+	responses["200"].Content["application/json"] = &openAPIMediaType{Schema: refSchema("ChunkManifest")}
+	return responses
+}
+
+// shortURLParams are the parameters of the short image URLs, after their credential
+var shortURLParams = []*openAPIParameter{
+	pathParam("version", "OpenShift version of the base image"),
+	pathParam("arch", "CPU architecture of the base image"),
+	pathParam("filename", "full.iso for the ISO including the rootfs, minimal.iso for the ISO without it", "full.iso", "minimal.iso"),
+}
+
+// apiOperations documents the routes of the HTTP API, by path and method
+var apiOperations = map[string]map[string]*openAPIOperation{
+	"/byid/{image_id}/{version}/{arch}/{filename}": {
+		http.MethodGet: {
+			OperationID: "DownloadImageByID",
+			Summary:     "Downloads the customized ISO of an image",
+			Parameters:  append([]*openAPIParameter{imageIDParam}, append(shortURLParams, apiKeyParam, imageTokenParam, descriptorParam)...),
+			Responses:   describedDownloadResponses("The ISO", "application/octet-stream"),
+		},
+	},
+	"/bytoken/{token}/{version}/{arch}/{filename}": {
+		http.MethodGet: {
+			OperationID: "DownloadImageByToken",
+			Summary:     "Downloads the customized ISO of an image with an image token",
+			Parameters:  append([]*openAPIParameter{pathParam("token", "JWT with the infra_env_id or sub of the image")}, append(shortURLParams, descriptorParam)...),
+			Responses:   describedDownloadResponses("The ISO", "application/octet-stream"),
+		},
+	},
+	"/byapikey/{api_key}/{version}/{arch}/{filename}": {
+		http.MethodGet: {
+			OperationID: "DownloadImageByAPIKey",
+			Summary:     "Downloads the customized ISO of an image with an API key",
+			Parameters:  append([]*openAPIParameter{pathParam("api_key", "JWT with the infra_env_id or sub of the image")}, append(shortURLParams, descriptorParam)...),
+			Responses:   describedDownloadResponses("The ISO", "application/octet-stream"),
+		},
+	},
+	"/images/{image_id}": {
+		http.MethodGet: {
+			OperationID: "DownloadImage",
+			Summary:     "Downloads the customized ISO of an image (deprecated, use the short URLs)",
+			Parameters: []*openAPIParameter{
+				imageIDParam, versionParam,
+				queryParam("type", "Type of the ISO", true, imagestore.ImageTypeFull, imagestore.ImageTypeMinimal),
+				archParam, apiKeyParam, imageTokenParam, descriptorParam,
+			},
+			Responses: describedDownloadResponses("The ISO", "application/octet-stream"),
+		},
+	},
+	"/images/{image_id}/pxe-initrd": {
+		http.MethodGet: {
+			OperationID: "DownloadInitrd",
+			Summary:     "Downloads the customized initrd of an image",
+			Parameters:  []*openAPIParameter{imageIDParam, versionParam, archParam, compressParam, apiKeyParam, imageTokenParam},
+			Responses:   downloadResponses("The initrd", "application/octet-stream"),
+		},
+	},
+	"/images/{image_id}/pxe-script": {
+		http.MethodGet: {
+			OperationID: "GetIPXEScript",
+			Summary:     "Renders the iPXE script booting the artifacts of an image",
+			Parameters:  []*openAPIParameter{imageIDParam, versionParam, archParam, apiKeyParam, imageTokenParam},
+			Responses: map[string]*openAPIResponse{
+				"200": textResponse("The iPXE script"),
+				"202": retryResponse,
+				"400": textResponse("Invalid request"),
+				"403": textResponse("The request is out of the scope of its token"),
+			},
+		},
+	},
+	"/images/{image_id}/pxe-bundle": {
+		http.MethodGet: {
+			OperationID: "DownloadPXEBundle",
+			Summary:     "Downloads the PXE artifacts of an image, with an iPXE script and a grub config, as a tar archive",
+			Parameters: []*openAPIParameter{
+				imageIDParam, versionParam, archParam,
+				queryParam("base_url", "URL the files of the archive are served from, relative by default", false),
+				compressParam, apiKeyParam, imageTokenParam,
+			},
+			Responses: map[string]*openAPIResponse{
+				"200": {Description: "The tar archive", Content: content("application/x-tar", binarySchema)},
+				"202": retryResponse,
+				"400": textResponse("Invalid request"),
+				"403": textResponse("The request is out of the scope of its token"),
+			},
+		},
+	},
+	"/images/{image_id}/s390x-initrd-addrsize": {
+		http.MethodGet: {
+			OperationID: "DownloadInitrdAddrSize",
+			Summary:     "Downloads the initrd.addrsize file of the customized initrd of an s390x image",
+			Parameters:  []*openAPIParameter{imageIDParam, versionParam, archParam, apiKeyParam, imageTokenParam},
+			Responses:   downloadResponses("The initrd.addrsize file", "application/octet-stream"),
+		},
+	},
+	"/images/{image_id}/s390x-boot": {
+		http.MethodGet: {
+			OperationID: "DownloadS390xBootFiles",
+			Summary:     "Downloads the generic.ins of an s390x image and the files it loads, the kernel, the customized initrd and a parameter file with the kernel arguments, as a tar archive for z/VM reader and LPAR FTP boots",
+			Parameters:  []*openAPIParameter{imageIDParam, versionParam, compressParam, apiKeyParam, imageTokenParam},
+			Responses: map[string]*openAPIResponse{
+				"200": {Description: "The tar archive", Content: content("application/x-tar", binarySchema)},
+				"202": retryResponse,
+				"400": textResponse("Invalid request"),
+				"403": textResponse("The request is out of the scope of its token"),
+			},
+		},
+	},
+	"/images/{image_id}/qcow2": {
+		http.MethodGet: {
+			OperationID: "DownloadQcow2",
+			Summary:     "Downloads the qcow2 disk image of a version with the discovery ignition of an image embedded",
+			Parameters:  []*openAPIParameter{imageIDParam, versionParam, archParam, apiKeyParam, imageTokenParam},
+			Responses:   downloadResponses("The qcow2 image", "application/octet-stream"),
+		},
+	},
+	"/images/{image_id}/raw": {
+		http.MethodGet: {
+			OperationID: "DownloadRawImage",
+			Summary:     "Downloads the raw metal disk image of a version with the discovery ignition of an image embedded, to be written to a device",
+			Parameters: []*openAPIParameter{
+				imageIDParam, versionParam, archParam,
+				queryParam("compression", "Compression of the image, uncompressed by default", false, compressionXZ),
+				apiKeyParam, imageTokenParam,
+			},
+			Responses: downloadResponses("The raw image", "application/octet-stream"),
+		},
+	},
+	"/images/{image_id}/ova": {
+		http.MethodGet: {
+			OperationID: "DownloadOVA",
+			Summary:     "Downloads the vSphere OVA of a version with the discovery ignition of an image in its OVF environment",
+			Parameters:  []*openAPIParameter{imageIDParam, versionParam, archParam, apiKeyParam, imageTokenParam},
+			Responses:   downloadResponses("The OVA", "application/x-tar"),
+		},
+	},
+	"/images/{image_id}/config-drive": {
+		http.MethodGet: {
+			OperationID: "DownloadConfigDrive",
+			Summary:     "Downloads an OpenStack config drive ISO carrying the discovery ignition of an image",
+			Parameters:  []*openAPIParameter{imageIDParam, apiKeyParam, imageTokenParam},
+			Responses: map[string]*openAPIResponse{
+				"200": {Description: "The config drive, labeled config-2", Content: content("application/octet-stream", binarySchema)},
+				"403": textResponse("The request is out of the scope of its token"),
+			},
+		},
+	},
+	"/images/{image_id}/nocloud-seed": {
+		http.MethodPost: {
+			OperationID: "CreateNoCloudSeed",
+			Summary:     "Downloads a cloud-init NoCloud seed ISO, for helper VMs running next to the hosts of an image that don't boot CoreOS",
+			Parameters:  []*openAPIParameter{imageIDParam, apiKeyParam, imageTokenParam},
+			RequestBody: &openAPIRequestBody{Required: true, Content: content("application/json", refSchema("NoCloudSeed"))},
+			Responses: map[string]*openAPIResponse{
+				"200": {Description: "The seed ISO, labeled cidata", Content: content("application/octet-stream", binarySchema)},
+				"400": textResponse("Invalid seed"),
+				"403": textResponse("The request is out of the scope of its token"),
+			},
+		},
+	},
+	"/images/{image_id}/uki": {
+		http.MethodGet: {
+			OperationID: "DownloadUKI",
+			Summary:     "Downloads an unsigned unified kernel image of the kernel, the customized initrd with the rootfs and the kernel arguments of an image, for UEFI HTTP boot",
+			Parameters:  []*openAPIParameter{imageIDParam, versionParam, archParam, apiKeyParam, imageTokenParam},
+			Responses:   downloadResponses("The unified kernel image", "application/efi"),
+		},
+	},
+	"/images/{image_id}/netboot": {
+		http.MethodGet: {
+			OperationID: "DownloadNetbootTree",
+			Summary:     "Downloads the netboot tree of an image, its PXE artifacts with the kernel arguments, an iPXE script, a grub config and a pxelinux config, as a tar archive",
+			Parameters: []*openAPIParameter{
+				imageIDParam, versionParam, archParam,
+				queryParam("base_url", "URL the files of the tree are served from, relative by default", false),
+				compressParam, apiKeyParam, imageTokenParam,
+			},
+			Responses: map[string]*openAPIResponse{
+				"200": {Description: "The tar archive", Content: content("application/x-tar", binarySchema)},
+				"202": retryResponse,
+				"400": textResponse("Invalid request"),
+				"403": textResponse("The request is out of the scope of its token"),
+			},
+		},
+		http.MethodPost: {
+			OperationID: "WriteNetbootTree",
+			Summary:     "Writes the netboot tree of an image to the NETBOOT_OUTPUT_DIR/{image_id} directory of the service",
+			Parameters: []*openAPIParameter{
+				imageIDParam, versionParam, archParam,
+				queryParam("base_url", "URL the files of the tree are served from, relative by default", false),
+				apiKeyParam, imageTokenParam,
+			},
+			Responses: map[string]*openAPIResponse{
+				"204": {Description: "The tree is written"},
+				"202": retryResponse,
+				"400": textResponse("Invalid request"),
+				"403": textResponse("The request is out of the scope of its token"),
+				"405": {Description: "NETBOOT_OUTPUT_DIR isn't set"},
+			},
+		},
+	},
+	"/boot-artifacts/{artifact}": {
+		http.MethodGet: {
+			OperationID: "DownloadBootArtifact",
+			Summary:     "Downloads a boot artifact of a base image",
+			Parameters: []*openAPIParameter{
+				pathParam("artifact", "The boot artifact, ins-file only for s390x", "kernel", "rootfs", "ins-file"),
+				versionParam, archParam, compressParam, apiKeyParam, imageTokenParam, descriptorParam,
+			},
+			Responses: describedDownloadResponses("The boot artifact", "application/octet-stream"),
+		},
+	},
+	"/base-isos/{version}/{arch}": {
+		http.MethodPut: {
+			OperationID: "AddBaseISO",
+			Summary:     "Adds a custom base ISO, uploaded or downloaded from a URL, with a bearer token",
+			Parameters: []*openAPIParameter{
+				pathParam("version", "OpenShift version of the base ISO"),
+				pathParam("arch", "CPU architecture of the base ISO"),
+			},
+			RequestBody: &openAPIRequestBody{
+				Required: true,
+				Content: map[string]*openAPIMediaType{
+					"application/octet-stream": {Schema: binarySchema},
+					"application/json":         {Schema: refSchema("BaseISORequest")},
+				},
+			},
+			Responses: map[string]*openAPIResponse{
+				"201": {Description: "The added version", Content: content("application/json", refSchema("BaseISOResponse"))},
+				"400": textResponse("Invalid ISO"),
+				"401": {Description: "Invalid token"},
+				"403": textResponse("Not over https"),
+				"409": textResponse("The version already exists"),
+			},
+		},
+	},
+	"/builds": {
+		http.MethodPost: {
+			OperationID: "CreateBuild",
+			Summary:     "Enqueues the build of the customized ISO of an image",
+			Parameters:  []*openAPIParameter{apiKeyParam, imageTokenParam},
+			RequestBody: &openAPIRequestBody{Required: true, Content: content("application/json", refSchema("BuildRequest"))},
+			Responses: map[string]*openAPIResponse{
+				"202": {
+					Description: "The enqueued build",
+					Headers: map[string]*openAPIHeader{
+						"Location": {Description: "Path of the build", Schema: stringSchema("")},
+					},
+					Content: content("application/json", refSchema("Build")),
+				},
+				"400": textResponse("Invalid request"),
+				"401": textResponse("The request isn't authorized by assisted service"),
+				"403": textResponse("The request is out of the scope of its token, or isn't authorized by assisted service"),
+				"503": {Description: "The version is downloading, or too many requests or builds are pending", Headers: retryResponse.Headers},
+			},
+		},
+	},
+	"/builds/{build_id}": {
+		http.MethodGet: {
+			OperationID: "GetBuild",
+			Summary:     "Returns the status of a build",
+			Parameters:  []*openAPIParameter{pathParam("build_id", "ID of the build")},
+			Responses: map[string]*openAPIResponse{
+				"200": {Description: "The build", Content: content("application/json", refSchema("Build"))},
+				"404": textResponse("Unknown build"),
+				"503": overLimitResponse,
+			},
+		},
+	},
+	"/builds/{build_id}/image": {
+		http.MethodGet: {
+			OperationID: "DownloadBuild",
+			Summary:     "Downloads the ISO of a successful build",
+			Parameters:  []*openAPIParameter{pathParam("build_id", "ID of the build")},
+			Responses: map[string]*openAPIResponse{
+				"200": {Description: "The ISO", Content: content("application/octet-stream", binarySchema)},
+				"202": textResponse("The build is pending or running"),
+				"404": textResponse("Unknown build"),
+				"409": textResponse("The build failed"),
+				"503": overLimitResponse,
+			},
+		},
+	},
+	"/v2/images/{image_id}": {
+		http.MethodPost: {
+			OperationID: "CustomizeImage",
+			Summary:     "Downloads the ISO of an image customized by a spec, or enqueues its build when the spec is async",
+			Parameters:  []*openAPIParameter{imageIDParam, apiKeyParam, imageTokenParam},
+			RequestBody: &openAPIRequestBody{Required: true, Content: content("application/json", refSchema("CustomizationSpec"))},
+			Responses: map[string]*openAPIResponse{
+				"200": {
+					Description: "The ISO",
+					Headers: map[string]*openAPIHeader{
+						"ETag":   {Description: "Same for the same customization of the same base image", Schema: stringSchema("")},
+						"Digest": {Description: "sha-256 of the image, once it has been served in full", Schema: stringSchema("")},
+					},
+					Content: content("application/octet-stream", binarySchema),
+				},
+				"202": {
+					Description: "The enqueued build of an async spec, or the images of the version are being downloaded",
+					Headers: map[string]*openAPIHeader{
+						"Location":    {Description: "Path of the build", Schema: stringSchema("")},
+						"Retry-After": {Description: "Seconds to wait before retrying", Schema: stringSchema("")},
+					},
+					Content: content("application/json", refSchema("Build")),
+				},
+				"400": textResponse("Invalid spec"),
+				"403": textResponse("The request is out of the scope of its token"),
+				"404": textResponse("Unknown version"),
+				"503": overLimitResponse,
+			},
+		},
+	},
+	"/health": {
+		http.MethodGet: {
+			OperationID: "GetHealth",
+			Summary:     "Tells whether the service is ready to serve the images",
+			Responses: map[string]*openAPIResponse{
+				"200": {Description: "Ready"},
+				"503": {Description: "Not ready"},
+			},
+		},
+	},
+	"/live": {
+		http.MethodGet: {
+			OperationID: "GetLiveness",
+			Summary:     "Tells whether the service is alive",
+			Responses: map[string]*openAPIResponse{
+				"200": {Description: "Alive"},
+			},
+		},
+	},
+	"/openapi.json": {
+		http.MethodGet: {
+			OperationID: "GetOpenAPI",
+			Summary:     "Returns this OpenAPI document",
+			Responses: map[string]*openAPIResponse{
+				"200": {Description: "The OpenAPI document", Content: content("application/json", &openAPISchema{Type: "object"})},
+			},
+		},
+	},
+}
+
+// apiSchemas are the JSON bodies of the HTTP API
+var apiSchemas = map[string]*openAPISchema{
+	"ChunkManifest": {
+		Type: "object",
+		Properties: map[string]*openAPISchema{
+			"name":       stringSchema("File name of the artifact"),
+			"size":       {Type: "integer", Format: "int64"},
+			"sha256":     stringSchema("Hex SHA-256 of the artifact"),
+			"etag":       stringSchema("ETag of the artifact, for the If-Range of the chunk requests"),
+			"chunk_size": {Type: "integer", Format: "int64"},
+			"urls":       arraySchema("URLs of the artifact, in order of preference", stringSchema("")),
+			"chunks": arraySchema("Chunks of the artifact", &openAPISchema{
+				Type: "object",
+				Properties: map[string]*openAPISchema{
+					"offset": {Type: "integer", Format: "int64"},
+					"length": {Type: "integer", Format: "int64"},
+					"sha256": stringSchema("Hex SHA-256 of the chunk"),
+				},
+			}),
+		},
+	},
+	"BaseISORequest": {
+		Type:     "object",
+		Required: []string{"url"},
+		Properties: map[string]*openAPISchema{
+			"url": stringSchema("URL the base ISO is downloaded from"),
+		},
+	},
+	"BaseISOResponse": {
+		Type: "object",
+		Properties: map[string]*openAPISchema{
+			"openshift_version": stringSchema(""),
+			"cpu_architecture":  stringSchema(""),
+			"version":           stringSchema("Version of the base ISO, derived from its checksum"),
+		},
+	},
+	"BuildRequest": {
+		Type:     "object",
+		Required: []string{"image_id", "version", "type"},
+		Properties: map[string]*openAPISchema{
+			"image_id":     stringSchema("ID of the image, usually the infra-env ID"),
+			"version":      stringSchema("OpenShift version of the base image"),
+			"type":         stringSchema("Type of the ISO", imagestore.ImageTypeFull, imagestore.ImageTypeMinimal),
+			"arch":         stringSchema("CPU architecture of the base image, x86_64 by default"),
+			"callback_url": stringSchema("URL the build is POSTed to once it's finished"),
+		},
+	},
+	"NoCloudSeed": {
+		Type:     "object",
+		Required: []string{"user_data"},
+		Properties: map[string]*openAPISchema{
+			"user_data":      stringSchema("Content of the user-data file"),
+			"meta_data":      stringSchema("Content of the meta-data file, an instance-id derived from the seed by default"),
+			"network_config": stringSchema("Content of the network-config file, none by default"),
+		},
+	},
+	"CustomizationSpec": {
+		Type:     "object",
+		Required: []string{"version", "type"},
+		Properties: map[string]*openAPISchema{
+			"version":          stringSchema("OpenShift version of the base image"),
+			"type":             stringSchema("Type of the ISO", imagestore.ImageTypeFull, imagestore.ImageTypeMinimal),
+			"arch":             stringSchema("CPU architecture of the base image, x86_64 by default"),
+			"ignition":         stringSchema("Ignition or Butane config merged into the one of the image"),
+			"kernel_arguments": arraySchema("Kernel arguments appended to the ones of the image", stringSchema("")),
+			"ca_bundle":        stringSchema("PEM encoded certificates trusted by ignition and the live environment"),
+			"static_network":   arraySchema("Static network configuration of the hosts, minimal ISOs only", refSchema("StaticNetwork")),
+			"hosts":            arraySchema("Per-host identities, minimal ISOs only", refSchema("Host")),
+			"systemd_units":    arraySchema("Units added to the live initramfs, minimal ISOs only", refSchema("SystemdUnit")),
+			"async":            {Type: "boolean", Description: "Builds the ISO as a job instead of streaming it"},
+			"callback_url":     stringSchema("URL the build of an async spec is POSTed to once it's finished"),
+		},
+	},
+	"StaticNetwork": {
+		Type:     "object",
+		Required: []string{"mac_interface_map"},
+		Properties: map[string]*openAPISchema{
+			"network_yaml":      stringSchema("nmstate YAML of the host, converted to keyfiles"),
+			"keyfiles":          mapSchema("NetworkManager keyfiles by file name, used without network_yaml"),
+			"mac_interface_map": mapSchema("Interface names of the config by MAC address"),
+		},
+	},
+	"Host": {
+		Type:     "object",
+		Required: []string{"mac_addresses"},
+		Properties: map[string]*openAPISchema{
+			"mac_addresses":    arraySchema("", stringSchema("")),
+			"hostname":         stringSchema(""),
+			"keyfiles":         mapSchema("NetworkManager keyfiles by file name"),
+			"kernel_arguments": arraySchema("", stringSchema("")),
+		},
+	},
+	"SystemdUnit": {
+		Type:     "object",
+		Required: []string{"name", "contents"},
+		Properties: map[string]*openAPISchema{
+			"name":     stringSchema("Name of the unit, e.g. firmware-check.service"),
+			"contents": stringSchema(""),
+			"enabled":  {Type: "boolean", Description: "Links the unit from the targets of its [Install] section"},
+		},
+	},
+	"Build": {
+		Type:     "object",
+		Required: []string{"id", "status", "created_at"},
+		Properties: map[string]*openAPISchema{
+			"id":           stringSchema(""),
+			"status":       stringSchema("", buildStatusPending, buildStatusRunning, buildStatusSucceeded, buildStatusFailed),
+			"error":        stringSchema("Why the build failed"),
+			"download_url": stringSchema("URL of the ISO of a successful build"),
+			"digest":       stringSchema("sha-256 of the ISO of a successful build"),
+			"created_at":   {Type: "string", Format: "date-time"},
+			"finished_at":  {Type: "string", Format: "date-time"},
+		},
+	},
+}
+
+// OpenAPISpec returns the OpenAPI document of the HTTP API
+func OpenAPISpec() ([]byte, error) {
+	return json.MarshalIndent(&openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:       "Assisted Image Service",
+			Description: "Serves the customized images of assisted installer",
+			Version:     "v1",
+		},
+		Paths:      apiOperations,
+		Components: openAPIComponents{Schemas: apiSchemas},
+	}, "", "  ")
+}
+
+// NewOpenAPIHandler returns the handler serving the OpenAPI document of the HTTP API
+func NewOpenAPIHandler() (http.Handler, error) {
+	spec, err := OpenAPISpec()
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(spec); err != nil {
+			log.WithError(err).Error("Failed to write the OpenAPI document")
+		}
+	}), nil
+}
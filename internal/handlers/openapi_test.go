@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// chiPathToOpenAPI drops the regexps of the params of a chi route pattern
+func chiPathToOpenAPI(pattern string) string {
+	var path strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '{' {
+			path.WriteByte(pattern[i])
+			continue
+		}
+		// the regexp of the param may have braces too
+		end, depth := i, 0
+		for ; end < len(pattern); end++ {
+			if pattern[end] == '{' {
+				depth++
+			} else if pattern[end] == '}' {
+				if depth--; depth == 0 {
+					break
+				}
+			}
+		}
+		name, _, _ := strings.Cut(pattern[i+1:end], ":")
+		path.WriteString("{" + name + "}")
+		i = end
+	}
+	return path.String()
+}
+
+var _ = Describe("OpenAPI", func() {
+	It("documents the routes of the image handler", func() {
+		handler := &ImageHandler{}
+		var routes []string
+		Expect(chi.Walk(handler.router(1), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			if method == http.MethodGet {
+				routes = append(routes, chiPathToOpenAPI(route))
+			}
+			return nil
+		})).To(Succeed())
+
+		Expect(routes).NotTo(BeEmpty())
+		for _, route := range routes {
+			Expect(apiOperations).To(HaveKey(route))
+			Expect(apiOperations[route]).To(HaveKey(http.MethodGet))
+		}
+	})
+
+	It("is the document of the generated client", func() {
+		spec, err := OpenAPISpec()
+		Expect(err).NotTo(HaveOccurred())
+		committed, err := os.ReadFile("../../pkg/client/openapi.json")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(committed)).To(Equal(string(spec)+"\n"), "run go generate ./pkg/client")
+	})
+
+	It("is served as JSON", func() {
+		handler, err := NewOpenAPIHandler()
+		Expect(err).NotTo(HaveOccurred())
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		body, err := io.ReadAll(w.Body)
+		Expect(err).NotTo(HaveOccurred())
+		var doc map[string]interface{}
+		Expect(json.Unmarshal(body, &doc)).To(Succeed())
+		Expect(doc).To(HaveKeyWithValue("openapi", "3.0.3"))
+		Expect(doc["paths"]).To(HaveKey("/builds/{build_id}"))
+	})
+})
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+// pxeInitrd is an initrd loaded by a PXE boot config, under the name the kernel arguments use
+type pxeInitrd struct {
+	name string
+	url  string
+}
+
+// pxeKernelArguments returns the kernel arguments of the ISO, without the one telling the live
+// system to find the rootfs on the boot media, with the discovery kernel arguments operations of
+// the infra-env applied. On failure, it returns the status code to respond with.
+func pxeKernelArguments(client *AssistedServiceClient, r *http.Request, imageID, isoPath, version, arch string) ([]string, int, error) {
+	infraEnvKargs, statusCode, err := infraEnvKernelArguments(client, r, imageID, version, arch)
+	if err != nil {
+		return nil, statusCode, err
+	}
+	kargs, err := applyPXEKernelArguments(isoPath, infraEnvKargs)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return kargs, 0, nil
+}
+
+// applyPXEKernelArguments returns the kernel arguments of the ISO, without the one telling the
+// live system to find the rootfs on the boot media, with the infra-env operations applied
+func applyPXEKernelArguments(isoPath string, infraEnvKargs isoeditor.KernelArguments) ([]string, error) {
+	isoKargs, err := isoeditor.ExtractKargs(isoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the kernel arguments of the image: %w", err)
+	}
+
+	var kargs []string
+	for _, karg := range isoKargs {
+		if strings.HasPrefix(karg, "coreos.liveiso=") {
+			continue
+		}
+		kargs = append(kargs, karg)
+	}
+	kargs, err = infraEnvKargs.Apply(kargs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply the kernel arguments: %w", err)
+	}
+	return kargs, nil
+}
+
+// infraEnvKernelArguments returns the discovery kernel arguments operations of the infra-env,
+// rendered for the version and architecture. On failure, it returns the status code to respond with.
+func infraEnvKernelArguments(client *AssistedServiceClient, r *http.Request, imageID, version, arch string) (isoeditor.KernelArguments, int, error) {
+	infraEnvKargs, statusCode, err := client.discoveryKernelArguments(r, imageID)
+	if err != nil {
+		return nil, statusCode, fmt.Errorf("error retrieving kernel arguments content: %w", err)
+	}
+	infraEnvKargs, err = infraEnvKargs.Render(map[string]string{
+		isoeditor.KargsVarInfraEnvID:       imageID,
+		isoeditor.KargsVarOpenshiftVersion: version,
+		isoeditor.KargsVarCPUArchitecture:  arch,
+	})
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to render kernel arguments: %w", err)
+	}
+	return infraEnvKargs, 0, nil
+}
+
+// ipxeScript renders the iPXE script booting the kernel with the initrds and kernel arguments
+func ipxeScript(kernelURL string, initrds []pxeInitrd, kargs []string) string {
+	var script strings.Builder
+	fmt.Fprintln(&script, "#!ipxe")
+	var initrdKargs []string
+	for _, initrd := range initrds {
+		fmt.Fprintf(&script, "initrd --name %s %s\n", initrd.name, initrd.url)
+		initrdKargs = append(initrdKargs, "initrd="+initrd.name)
+	}
+	fmt.Fprintf(&script, "kernel %s %s\n", kernelURL, strings.Join(append(initrdKargs, kargs...), " "))
+	fmt.Fprintln(&script, "boot")
+	return script.String()
+}
+
+// grubNetbootConfig renders the grub config booting the kernel with the initrds and kernel arguments
+func grubNetbootConfig(kernelPath string, initrdPaths []string, kargs []string) string {
+	var config strings.Builder
+	fmt.Fprintln(&config, "set timeout=0")
+	fmt.Fprintln(&config, "menuentry 'RHCOS discovery' {")
+	fmt.Fprintf(&config, "\tlinux %s %s\n", kernelPath, strings.Join(kargs, " "))
+	fmt.Fprintf(&config, "\tinitrd %s\n", strings.Join(initrdPaths, " "))
+	fmt.Fprintln(&config, "}")
+	return config.String()
+}
+
+// pxelinuxConfig renders the pxelinux config booting the kernel with the initrds and kernel arguments
+func pxelinuxConfig(kernelPath string, initrdPaths []string, kargs []string) string {
+	var config strings.Builder
+	fmt.Fprintln(&config, "DEFAULT discovery")
+	fmt.Fprintln(&config, "PROMPT 0")
+	fmt.Fprintln(&config, "LABEL discovery")
+	fmt.Fprintf(&config, "\tKERNEL %s\n", kernelPath)
+	fmt.Fprintf(&config, "\tAPPEND %s\n", strings.Join(append([]string{"initrd=" + strings.Join(initrdPaths, ",")}, kargs...), " "))
+	return config.String()
+}
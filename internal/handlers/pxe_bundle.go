@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/klauspost/compress/zstd"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+// Names of the files of the PXE bundles
+const (
+	pxeBundleKernel     = "vmlinuz"
+	pxeBundleInitrd     = "initrd.img"
+	pxeBundleRootfs     = "rootfs.img"
+	pxeBundleIPXEScript = "boot.ipxe"
+	pxeBundleGrubConfig = "grub.cfg"
+)
+
+// tarBlockSize is the size of the headers of the tar archives, to which the files are padded
+const tarBlockSize = 512
+
+// pxeBundleHandler serves a tar archive of the kernel, the initrd customized for the image and
+// the rootfs, with an iPXE script and a grub config booting them
+type pxeBundleHandler struct {
+	ImageStore imagestore.ImageStore
+	client     *AssistedServiceClient
+}
+
+var _ http.Handler = &pxeBundleHandler{}
+
+type pxeBundleFile struct {
+	name    string
+	content io.ReadSeeker
+	size    int64
+}
+
+// pxeArtifacts are the kernel, the initrd customized for an image and the rootfs of its version,
+// with the PXE kernel arguments of the image
+type pxeArtifacts struct {
+	kernel  io.ReadSeekCloser
+	initrd  io.ReadSeekCloser
+	rootfs  io.ReadSeekCloser
+	kargs   []string
+	modTime time.Time
+}
+
+// openPXEArtifacts opens the PXE artifacts of the image of the request, responding with the error
+// when they can't be opened
+func openPXEArtifacts(w http.ResponseWriter, r *http.Request, imageStore imagestore.ImageStore, client *AssistedServiceClient) (*pxeArtifacts, bool) {
+	imageID := chi.URLParam(r, "image_id")
+	version := r.URL.Query().Get("version")
+
+	arch := r.URL.Query().Get("arch")
+	if arch == "" {
+		arch = defaultArch
+	}
+	if arch == "s390x" {
+		httpErrorf(w, http.StatusBadRequest, "PXE bundles are not supported for s390x architecture")
+		return nil, false
+	}
+
+	initrdReader, lastModified, _, code, err := initrdOverlayReader(imageStore, client, r, arch)
+	if errors.Is(err, errVersionDownloading) {
+		respondVersionDownloading(w, version, arch)
+		return nil, false
+	} else if err != nil {
+		httpErrorf(w, code, err.Error())
+		return nil, false
+	}
+	a := &pxeArtifacts{initrd: overlay.WithContext(r.Context(), initrdReader)}
+
+	isoPath := imageStore.PathForParams(imagestore.ImageTypeFull, version, arch)
+	var statusCode int
+	a.kargs, statusCode, err = pxeKernelArguments(client, r, imageID, isoPath, version, arch)
+	if err != nil {
+		a.Close()
+		httpErrorf(w, statusCode, "Failed to get the kernel arguments: %v", err)
+		return nil, false
+	}
+
+	a.kernel, err = openBootArtifact(isoPath, "vmlinuz")
+	if err != nil {
+		a.Close()
+		httpErrorf(w, http.StatusInternalServerError, "Failed to open the kernel: %v", err)
+		return nil, false
+	}
+	a.rootfs, err = openBootArtifact(isoPath, "rootfs.img")
+	if err != nil {
+		a.Close()
+		httpErrorf(w, http.StatusInternalServerError, "Failed to open the rootfs: %v", err)
+		return nil, false
+	}
+
+	a.modTime, err = http.ParseTime(lastModified)
+	if err != nil {
+		log.Warnf("Error parsing last modified time %s: %v", lastModified, err)
+		a.modTime = time.Now()
+	}
+	a.modTime = a.modTime.Truncate(time.Second)
+	return a, true
+}
+
+func (a *pxeArtifacts) Close() {
+	for _, f := range []io.Closer{a.kernel, a.initrd, a.rootfs} {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
+
+// configPrefixes returns the prefixes of the files referenced by the iPXE script and by the grub
+// config of the request. They are relative to their own location unless told where they're hosted.
+func configPrefixes(r *http.Request) (string, string) {
+	if baseURL := strings.TrimSuffix(r.URL.Query().Get("base_url"), "/"); baseURL != "" {
+		return baseURL + "/", baseURL + "/"
+	}
+	return "", "/"
+}
+
+func (h *pxeBundleHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	imageID := chi.URLParam(r, "image_id")
+	compress, ok := zstdRequested(w, r)
+	if !ok {
+		return
+	}
+	artifacts, ok := openPXEArtifacts(w, r, h.ImageStore, h.client)
+	if !ok {
+		return
+	}
+	defer artifacts.Close()
+
+	ipxePrefix, grubPrefix := configPrefixes(r)
+	// the rootfs is appended to the initrd rather than fetched from a URL, so the bundle is self-contained
+	script := ipxeScript(ipxePrefix+pxeBundleKernel, []pxeInitrd{
+		{name: "initrd", url: ipxePrefix + pxeBundleInitrd},
+		{name: "rootfs", url: ipxePrefix + pxeBundleRootfs},
+	}, artifacts.kargs)
+	grubConfig := grubNetbootConfig(grubPrefix+pxeBundleKernel, []string{grubPrefix + pxeBundleInitrd, grubPrefix + pxeBundleRootfs}, artifacts.kargs)
+
+	files := []*pxeBundleFile{
+		{name: pxeBundleKernel, content: artifacts.kernel},
+		{name: pxeBundleInitrd, content: artifacts.initrd},
+		{name: pxeBundleRootfs, content: artifacts.rootfs},
+		{name: pxeBundleIPXEScript, content: strings.NewReader(script)},
+		{name: pxeBundleGrubConfig, content: strings.NewReader(grubConfig)},
+	}
+	serveTar(w, r, fmt.Sprintf("%s-pxe.tar", imageID), files, artifacts.modTime, compress)
+}
+
+// serveTar streams a tar archive of the files, with its size computed upfront unless it is
+// compressed with zstd
+func serveTar(w http.ResponseWriter, r *http.Request, fileName string, files []*pxeBundleFile, modTime time.Time, compress bool) {
+	// two zero blocks end the archive
+	archiveSize := int64(2 * tarBlockSize)
+	for _, file := range files {
+		if err := file.measure(); err != nil {
+			httpErrorf(w, http.StatusInternalServerError, "Failed to get the size of %s: %v", file.name, err)
+			return
+		}
+		archiveSize += tarBlockSize + (file.size+tarBlockSize-1)/tarBlockSize*tarBlockSize
+	}
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	w.Header().Set("Content-Length", strconv.FormatInt(archiveSize, 10))
+	if compress {
+		setZstdEncoding(w)
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	var out io.Writer = w
+	if compress {
+		enc, err := zstd.NewWriter(w)
+		if err != nil {
+			httpErrorf(w, http.StatusInternalServerError, "Failed to compress %s: %v", fileName, err)
+			return
+		}
+		defer func() {
+			if err := enc.Close(); err != nil {
+				log.Errorf("Failed to write %s: %v", fileName, err)
+			}
+		}()
+		out = enc
+	}
+	tw := tar.NewWriter(out)
+	for _, file := range files {
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     file.name,
+			Size:     file.size,
+			Mode:     0644,
+			ModTime:  modTime,
+			Format:   tar.FormatUSTAR,
+		})
+		if err == nil {
+			_, err = io.Copy(tw, file.content)
+		}
+		if err != nil {
+			log.Errorf("Failed to write %s to %s: %v", file.name, fileName, err)
+			return
+		}
+	}
+	if err := tw.Close(); err != nil {
+		log.Errorf("Failed to write %s: %v", fileName, err)
+	}
+}
+
+// measure sets the size of the file, leaving its content at the start
+func (f *pxeBundleFile) measure() error {
+	var err error
+	f.size, err = f.content.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = f.content.Seek(0, io.SeekStart)
+	}
+	return err
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+
+	"github.com/golang/mock/gomock"
+	"github.com/klauspost/compress/zstd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+)
+
+var _ = Describe("PXE bundle ServeHTTP", func() {
+	var (
+		ctrl           *gomock.Controller
+		mockImageStore *imagestore.MockImageStore
+		assistedServer *ghttp.Server
+		server         *httptest.Server
+		client         *http.Client
+		isoFile        string
+		imageID        = "bf25292a-dddd-49dc-ab9c-3fb4c1f07071"
+		kargs          = "random.trust_cpu=on ignition.firstboot ignition.platform.id=metal"
+	)
+
+	BeforeEach(func() {
+		isoFile = createTestISO()
+
+		ctrl = gomock.NewController(GinkgoT())
+		mockImageStore = imagestore.NewMockImageStore(ctrl)
+		assistedServer = ghttp.NewServer()
+		u, err := url.Parse(assistedServer.URL())
+		Expect(err).NotTo(HaveOccurred())
+		asc, err := NewAssistedServiceClient(u.Scheme, u.Host, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		handler := &ImageHandler{
+			pxeBundle: &pxeBundleHandler{
+				ImageStore: mockImageStore,
+				client:     asc,
+			},
+		}
+		server = httptest.NewServer(handler.router(1))
+		client = server.Client()
+	})
+
+	AfterEach(func() {
+		os.Remove(isoFile)
+		server.Close()
+		assistedServer.Close()
+	})
+
+	mockImage := func(version, arch string) {
+		mockImageStore.EXPECT().HaveVersion(version, arch).Return(true)
+		mockImageStore.EXPECT().Available(version, arch).Return(true)
+		mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, version, arch).Return(isoFile).Times(2)
+		assistedServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(fileRouteFormat, imageID), "file_name=discovery.ign"),
+				ghttp.RespondWith(http.StatusOK, "someignitioncontent", http.Header{"Last-Modified": {"Fri, 22 Apr 2022 18:11:09 GMT"}}),
+			),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf("/api/assisted-install/v2/infra-envs/%s/downloads/minimal-initrd", imageID)),
+				ghttp.RespondWith(http.StatusNoContent, []byte{}),
+			),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(infraEnvPathFormat, imageID)),
+				ghttp.RespondWith(http.StatusOK, "{}"),
+			),
+		)
+	}
+
+	readBundle := func(resp *http.Response) map[string]string {
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/x-tar"))
+		Expect(resp.Header.Get("Content-Disposition")).To(Equal(fmt.Sprintf("attachment; filename=%s-pxe.tar", imageID)))
+		content, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(int64(len(content))).To(Equal(resp.ContentLength))
+
+		files := map[string]string{}
+		tr := tar.NewReader(bytes.NewReader(content))
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			data, err := io.ReadAll(tr)
+			Expect(err).NotTo(HaveOccurred())
+			files[header.Name] = string(data)
+		}
+		return files
+	}
+
+	It("bundles the artifacts of the image with the boot configs", func() {
+		mockImage("4.11", defaultArch)
+		resp, err := client.Get(fmt.Sprintf("%s/images/%s/pxe-bundle?version=4.11", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		files := readBundle(resp)
+
+		Expect(files).To(HaveLen(5))
+		Expect(files["vmlinuz"]).To(Equal("this is kernel"))
+		Expect(files["initrd.img"]).To(HavePrefix("this is initrd"))
+		Expect(files["rootfs.img"]).To(Equal("this is rootfs"))
+		Expect(files["boot.ipxe"]).To(Equal(fmt.Sprintf(`#!ipxe
+initrd --name initrd initrd.img
+initrd --name rootfs rootfs.img
+kernel vmlinuz initrd=initrd initrd=rootfs %s
+boot
+`, kargs)))
+		Expect(files["grub.cfg"]).To(ContainSubstring(fmt.Sprintf("\tlinux /vmlinuz %s\n\tinitrd /initrd.img /rootfs.img\n", kargs)))
+	})
+
+	It("references the files from the base URL", func() {
+		mockImage("4.11", "arm64")
+		resp, err := client.Get(fmt.Sprintf("%s/images/%s/pxe-bundle?version=4.11&arch=arm64&base_url=http://tftp.example.com/rhcos/", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		files := readBundle(resp)
+
+		Expect(files["boot.ipxe"]).To(ContainSubstring("kernel http://tftp.example.com/rhcos/vmlinuz initrd=initrd"))
+		Expect(files["grub.cfg"]).To(ContainSubstring("\tinitrd http://tftp.example.com/rhcos/initrd.img http://tftp.example.com/rhcos/rootfs.img\n"))
+	})
+
+	It("compresses the bundle with zstd", func() {
+		mockImage("4.11", defaultArch)
+		resp, err := client.Get(fmt.Sprintf("%s/images/%s/pxe-bundle?version=4.11&compress=zstd", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Encoding")).To(Equal("zstd"))
+		dec, err := zstd.NewReader(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		defer dec.Close()
+		tr := tar.NewReader(dec)
+		header, err := tr.Next()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(header.Name).To(Equal("vmlinuz"))
+		Expect(io.ReadAll(tr)).To(Equal([]byte("this is kernel")))
+	})
+
+	It("fails when no version is supplied", func() {
+		resp, err := client.Get(fmt.Sprintf("%s/images/%s/pxe-bundle", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("fails for s390x", func() {
+		resp, err := client.Get(fmt.Sprintf("%s/images/%s/pxe-bundle?version=4.11&arch=s390x", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+// RootfsURLVerifier checks that the rootfs URL of minimal ISOs can be downloaded before serving
+// them, as hosts booting the ISO otherwise hang in the initramfs waiting for the rootfs
+type RootfsURLVerifier struct {
+	client *http.Client
+}
+
+// NewRootfsURLVerifier returns a verifier giving up after timeout. Requests go through proxyURL
+// when set, or through the proxy of the environment (HTTP_PROXY, HTTPS_PROXY and NO_PROXY).
+func NewRootfsURLVerifier(timeout time.Duration, proxyURL string) (*RootfsURLVerifier, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rootfs URL verification proxy %s: %w", proxyURL, err)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	return &RootfsURLVerifier{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		},
+	}, nil
+}
+
+// VerifyISO checks the rootfs URL of the minimal ISO at isoPath
+func (v *RootfsURLVerifier) VerifyISO(ctx context.Context, isoPath string) error {
+	rootFSURL, err := isoeditor.GetRootfsURL(isoPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the rootfs URL of %s: %w", isoPath, err)
+	}
+	return v.Verify(ctx, rootFSURL)
+}
+
+// Verify checks that rootFSURL can be downloaded. Servers not supporting HEAD requests are asked
+// for the first byte of the rootfs instead.
+func (v *RootfsURLVerifier) Verify(ctx context.Context, rootFSURL string) error {
+	statusCode, err := v.request(ctx, http.MethodHead, rootFSURL)
+	if err == nil && (statusCode == http.StatusMethodNotAllowed || statusCode == http.StatusNotImplemented) {
+		statusCode, err = v.request(ctx, http.MethodGet, rootFSURL)
+	}
+	if err != nil {
+		return fmt.Errorf("rootfs URL %s is not reachable: %w", rootFSURL, err)
+	}
+	if statusCode < 200 || statusCode >= 300 {
+		return fmt.Errorf("rootfs URL %s is not downloadable: %d %s", rootFSURL, statusCode, http.StatusText(statusCode))
+	}
+	return nil
+}
+
+func (v *RootfsURLVerifier) request(ctx context.Context, method, rootFSURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rootFSURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	return resp.StatusCode, nil
+}
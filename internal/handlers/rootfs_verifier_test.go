@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RootfsURLVerifier", func() {
+	var (
+		server   *httptest.Server
+		verifier *RootfsURLVerifier
+		handler  http.HandlerFunc
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+		var err error
+		verifier, err = NewRootfsURLVerifier(time.Second, "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("succeeds when the rootfs can be downloaded", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal(http.MethodHead))
+			w.WriteHeader(http.StatusOK)
+		}
+		Expect(verifier.Verify(context.Background(), server.URL+"/rootfs.img")).To(Succeed())
+	})
+
+	It("falls back to a ranged GET when HEAD isn't supported", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			Expect(r.Header.Get("Range")).To(Equal("bytes=0-0"))
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write([]byte("r"))
+		}
+		Expect(verifier.Verify(context.Background(), server.URL+"/rootfs.img")).To(Succeed())
+	})
+
+	It("fails when the rootfs is missing", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		err := verifier.Verify(context.Background(), server.URL+"/rootfs.img")
+		Expect(err).To(MatchError(ContainSubstring("is not downloadable: 404 Not Found")))
+	})
+
+	It("fails when the server doesn't answer in time", func() {
+		verifier, err := NewRootfsURLVerifier(10*time.Millisecond, "")
+		Expect(err).NotTo(HaveOccurred())
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}
+		err = verifier.Verify(context.Background(), server.URL+"/rootfs.img")
+		Expect(err).To(MatchError(ContainSubstring("is not reachable")))
+	})
+
+	It("uses the configured proxy", func() {
+		proxied := false
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			proxied = r.URL.Host == "rootfs.example.com"
+			w.WriteHeader(http.StatusOK)
+		}
+		verifier, err := NewRootfsURLVerifier(time.Second, server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verifier.Verify(context.Background(), "http://rootfs.example.com/rootfs.img")).To(Succeed())
+		Expect(proxied).To(BeTrue())
+	})
+
+	It("fails for ISOs without a rootfs URL", func() {
+		err := verifier.VerifyISO(context.Background(), "/nonexistent.iso")
+		Expect(err).To(MatchError(ContainSubstring("failed to read the rootfs URL")))
+	})
+})
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+// s390xBootHandler serves a tar archive of the files to boot an s390x image from the z/VM reader
+// or from an FTP server of the HMC: the generic.ins of its ISO and the files it loads, with the
+// initrd customized for the image and a parameter file with its kernel arguments
+type s390xBootHandler struct {
+	ImageStore imagestore.ImageStore
+	client     *AssistedServiceClient
+}
+
+var _ http.Handler = &s390xBootHandler{}
+
+func (h *s390xBootHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	imageID := chi.URLParam(r, "image_id")
+	version := r.URL.Query().Get("version")
+	compress, ok := zstdRequested(w, r)
+	if !ok {
+		return
+	}
+
+	initrdReader, lastModified, _, code, err := initrdOverlayReader(h.ImageStore, h.client, r, isoeditor.S390XCPUArchitecture)
+	if errors.Is(err, errVersionDownloading) {
+		respondVersionDownloading(w, version, isoeditor.S390XCPUArchitecture)
+		return
+	} else if err != nil {
+		httpErrorf(w, code, err.Error())
+		return
+	}
+	defer initrdReader.Close()
+
+	isoPath := h.ImageStore.PathForParams(imagestore.ImageTypeFull, version, isoeditor.S390XCPUArchitecture)
+	ins, err := isoeditor.ReadFileFromISO(isoPath, isoeditor.S390xInsPath)
+	if err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "Failed to read %s: %v", isoeditor.S390xInsPath, err)
+		return
+	}
+
+	files := []*pxeBundleFile{{name: path.Base(isoeditor.S390xInsPath), content: bytes.NewReader(ins)}}
+	for _, insFile := range isoeditor.S390xInsFiles(ins) {
+		// the files are loaded relative to the .ins file, at the root of the archive
+		name := strings.TrimPrefix(path.Clean("/"+insFile), "/")
+		var content io.ReadSeeker
+		switch {
+		case path.Base(name) == "initrd.img":
+			content = overlay.WithContext(r.Context(), initrdReader)
+		case path.Base(name) == "initrd.addrsize":
+			content, err = isoeditor.NewInitrdAddrsizeReaderFromISO(isoPath, initrdReader)
+			if err != nil {
+				httpErrorf(w, http.StatusInternalServerError, "Failed to get initrd.addrsize: %v", err)
+				return
+			}
+		case strings.HasSuffix(name, ".prm"):
+			parmFile, statusCode, err := h.parmFile(r, imageID, isoPath, name)
+			if err != nil {
+				httpErrorf(w, statusCode, "Failed to generate the parameter file: %v", err)
+				return
+			}
+			content = bytes.NewReader(parmFile)
+		default:
+			isoFile, err := isoeditor.GetFileFromISO(isoPath, name)
+			if err != nil {
+				httpErrorf(w, http.StatusInternalServerError, "Failed to open %s: %v", name, err)
+				return
+			}
+			defer isoFile.Close()
+			content = isoFile
+		}
+		files = append(files, &pxeBundleFile{name: name, content: content})
+	}
+
+	modTime, err := http.ParseTime(lastModified)
+	if err != nil {
+		log.Warnf("Error parsing last modified time %s: %v", lastModified, err)
+		modTime = time.Now()
+	}
+	serveTar(w, r, fmt.Sprintf("%s-s390x-boot.tar", imageID), files, modTime.Truncate(time.Second), compress)
+}
+
+// parmFile generates the parameter file of the image from the one of the ISO. The live system
+// fetches the rootfs from the image service rather than from the boot media, and the discovery
+// kernel arguments operations of the infra-env are applied. On failure, it returns the status code to
+// respond with.
+func (h *s390xBootHandler) parmFile(r *http.Request, imageID, isoPath, parmFilePath string) ([]byte, int, error) {
+	version := r.URL.Query().Get("version")
+	isoParmFile, err := isoeditor.ReadFileFromISO(isoPath, parmFilePath)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to read %s: %w", parmFilePath, err)
+	}
+	infraEnvKargs, statusCode, err := infraEnvKernelArguments(h.client, r, imageID, version, isoeditor.S390XCPUArchitecture)
+	if err != nil {
+		return nil, statusCode, err
+	}
+
+	artifactsQuery := url.Values{"version": {version}, "arch": {isoeditor.S390XCPUArchitecture}}
+	kargs := []string{fmt.Sprintf("coreos.live.rootfs_url=%s/boot-artifacts/rootfs?%s", requestBaseURL(r), artifactsQuery.Encode())}
+	for _, karg := range isoeditor.ParseS390xParmFile(isoParmFile) {
+		if strings.HasPrefix(karg, "coreos.liveiso=") || strings.HasPrefix(karg, "coreos.live.rootfs_url=") {
+			continue
+		}
+		kargs = append(kargs, karg)
+	}
+	kargs, err = infraEnvKargs.Apply(kargs)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to apply the kernel arguments: %w", err)
+	}
+	parmFile, err := isoeditor.GenerateS390xParmFile(kargs)
+	var tooLong *isoeditor.ErrKargsTooLong
+	if errors.As(err, &tooLong) {
+		return nil, http.StatusBadRequest, err
+	} else if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return parmFile, 0, nil
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+)
+
+const testGenericIns = `* for a description of the format, see the lxboot documentation
+images/kernel.img 0x00000000
+images/initrd.img 0x02000000
+images/genericdvd.prm 0x00010480
+images/initrd.addrsize 0x00010408
+`
+
+func createS390xTestISO() string {
+	filesDir, err := os.MkdirTemp("", "isotest")
+	Expect(err).ToNot(HaveOccurred())
+	defer os.RemoveAll(filesDir)
+
+	temp, err := os.CreateTemp("", "handlers-test")
+	Expect(err).ToNot(HaveOccurred())
+
+	isoFile := temp.Name()
+	Expect(os.MkdirAll(filepath.Join(filesDir, "images/pxeboot"), 0755)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "generic.ins"), []byte(testGenericIns), 0600)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "images/kernel.img"), []byte("this is kernel"), 0600)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "images/initrd.img"), []byte("this is initrd"), 0600)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "images/pxeboot/initrd.img"), []byte("this is initrd"), 0600)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "images/genericdvd.prm"), []byte("rd.neednet=1 coreos.inst.install_dev=/dev/dasda\ncoreos.liveiso=rhcos-411 ignition.firstboot ignition.platform.id=metal\n"), 0600)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "images/initrd.addrsize"), []byte{
+		1, 2, 3, 4, 5, 6, 7, 8, 1, 2, 3, 4, 5, 6, 7, 8}, 0600)).To(Succeed())
+
+	cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-o", isoFile, filesDir)
+	Expect(cmd.Run()).To(Succeed())
+	return isoFile
+}
+
+var _ = Describe("s390x boot files ServeHTTP", func() {
+	var (
+		ctrl           *gomock.Controller
+		mockImageStore *imagestore.MockImageStore
+		assistedServer *ghttp.Server
+		server         *httptest.Server
+		isoFile        string
+		imageID        = "bf25292a-dddd-49dc-ab9c-3fb4c1f07071"
+	)
+
+	BeforeEach(func() {
+		isoFile = createS390xTestISO()
+		ctrl = gomock.NewController(GinkgoT())
+		mockImageStore = imagestore.NewMockImageStore(ctrl)
+		assistedServer = ghttp.NewServer()
+		u, err := url.Parse(assistedServer.URL())
+		Expect(err).NotTo(HaveOccurred())
+		asc, err := NewAssistedServiceClient(u.Scheme, u.Host, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		handler := &ImageHandler{
+			s390xBoot: &s390xBootHandler{
+				ImageStore: mockImageStore,
+				client:     asc,
+			},
+		}
+		server = httptest.NewServer(handler.router(1))
+	})
+
+	AfterEach(func() {
+		os.Remove(isoFile)
+		server.Close()
+		assistedServer.Close()
+	})
+
+	mockImage := func(infraEnv string) {
+		mockImageStore.EXPECT().HaveVersion("4.11", "s390x").Return(true)
+		mockImageStore.EXPECT().Available("4.11", "s390x").Return(true)
+		mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, "4.11", "s390x").Return(isoFile).Times(2)
+		assistedServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(fileRouteFormat, imageID), "file_name=discovery.ign"),
+				ghttp.RespondWith(http.StatusOK, "someignitioncontent", http.Header{"Last-Modified": {"Fri, 22 Apr 2022 18:11:09 GMT"}}),
+			),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf("/api/assisted-install/v2/infra-envs/%s/downloads/minimal-initrd", imageID)),
+				ghttp.RespondWith(http.StatusNoContent, []byte{}),
+			),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(infraEnvPathFormat, imageID)),
+				ghttp.RespondWith(http.StatusOK, infraEnv),
+			),
+		)
+	}
+
+	It("serves the generic.ins with the files it loads", func() {
+		mockImage(`{"kernel_arguments": "[{\"operation\": \"append\", \"value\": \"ip=dhcp\"}]"}`)
+		resp, err := server.Client().Get(fmt.Sprintf("%s/images/%s/s390x-boot?version=4.11", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Disposition")).To(Equal(fmt.Sprintf("attachment; filename=%s-s390x-boot.tar", imageID)))
+
+		var names []string
+		files := map[string]string{}
+		tr := tar.NewReader(resp.Body)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			data, err := io.ReadAll(tr)
+			Expect(err).NotTo(HaveOccurred())
+			names = append(names, header.Name)
+			files[header.Name] = string(data)
+		}
+		Expect(names).To(Equal([]string{"generic.ins", "images/kernel.img", "images/initrd.img", "images/genericdvd.prm", "images/initrd.addrsize"}))
+		Expect(files["generic.ins"]).To(Equal(testGenericIns))
+		Expect(files["images/kernel.img"]).To(Equal("this is kernel"))
+		Expect(files["images/initrd.img"]).To(HavePrefix("this is initrd"))
+		Expect(files["images/genericdvd.prm"]).To(Equal(fmt.Sprintf(
+			"coreos.live.rootfs_url=%s/boot-artifacts/rootfs?arch=s390x&version=4.11\nrd.neednet=1 coreos.inst.install_dev=/dev/dasda ignition.firstboot\nignition.platform.id=metal ip=dhcp\n",
+			server.URL,
+		)))
+		addrsize := []byte(files["images/initrd.addrsize"])
+		Expect(addrsize).To(HaveLen(16))
+		Expect(addrsize[:8]).To(Equal([]byte{1, 2, 3, 4, 5, 6, 7, 8}))
+		Expect(addrsize[15]).To(Equal(byte(len(files["images/initrd.img"]))))
+	})
+
+	It("fails when the kernel arguments don't fit in the parameter file", func() {
+		mockImage(fmt.Sprintf(`{"kernel_arguments": "[{\"operation\": \"append\", \"value\": \"karg=%0900d\"}]"}`, 0))
+		resp, err := server.Client().Get(fmt.Sprintf("%s/images/%s/s390x-boot?version=4.11", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
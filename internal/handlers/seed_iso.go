@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+// serveSeedISO builds an ISO with the given volume label and files, by path relative to its root,
+// in workDir and serves it as fileName. Seed ISOs are small, they are built for each request.
+func serveSeedISO(w http.ResponseWriter, r *http.Request, workDir, label, fileName string, files map[string][]byte, modTime time.Time) {
+	iso, err := isoeditor.CreateSeedISO(workDir, label, files, modTime)
+	if err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "failed to create the %s ISO: %v", label, err)
+		return
+	}
+	defer iso.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	http.ServeContent(w, r, fileName, modTime, iso)
+}
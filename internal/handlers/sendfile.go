@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+// sendfileWriter writes the content copied by http.ServeContent range by range, so that the
+// untouched regions of the base ISOs are sent by the kernel with sendfile and only the modified
+// ranges are copied through memory, instead of the whole image
+type sendfileWriter struct {
+	http.ResponseWriter
+}
+
+func (w *sendfileWriter) ReadFrom(src io.Reader) (int64, error) {
+	// http.ServeContent copies the requested range through an io.LimitedReader
+	if lr, ok := src.(*io.LimitedReader); ok {
+		if rs, ok := lr.R.(io.ReadSeeker); ok {
+			n, err := overlay.WriteRange(w.ResponseWriter, rs, lr.N)
+			lr.N -= n
+			return n, err
+		}
+	}
+	return overlay.Copy(w.ResponseWriter, src)
+}
+
+func (w *sendfileWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
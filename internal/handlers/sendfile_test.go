@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+// fileCountingRecorder counts the copies of files it is given, as the network connections do
+// with sendfile
+type fileCountingRecorder struct {
+	*httptest.ResponseRecorder
+	files int
+}
+
+func (w *fileCountingRecorder) ReadFrom(r io.Reader) (int64, error) {
+	if lr, ok := r.(*io.LimitedReader); ok {
+		if _, ok := lr.R.(*os.File); ok {
+			w.files++
+		}
+	}
+	return w.ResponseRecorder.Body.ReadFrom(r)
+}
+
+var _ = Describe("sendfileWriter", func() {
+	var (
+		dir    string
+		reader overlay.OverlayReader
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "sendfileTest")
+		Expect(err).NotTo(HaveOccurred())
+		basePath := filepath.Join(dir, "base.iso")
+		Expect(os.WriteFile(basePath, []byte("0123456789abcdefghij"), 0600)).To(Succeed())
+		base, err := os.Open(basePath)
+		Expect(err).NotTo(HaveOccurred())
+		reader, err = overlay.NewOverlayReader(base, overlay.Overlay{Reader: strings.NewReader("XYZ"), Offset: 8, Length: 3})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(reader.Close()).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	serve := func(rangeHeader string) *fileCountingRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/images/test", nil)
+		req.Header.Set("Range", rangeHeader)
+		w := &fileCountingRecorder{ResponseRecorder: httptest.NewRecorder()}
+		serveImage(w, req, "test.iso", time.Now(), reader, `"sendfiletest"`)
+		return w
+	}
+
+	It("hands the untouched regions of the base ISO to the response writer", func() {
+		w := serve("bytes=4-15")
+		Expect(w.Code).To(Equal(http.StatusPartialContent))
+		Expect(w.Body.String()).To(Equal("4567XYZbcdef"))
+		Expect(w.files).To(Equal(2))
+	})
+
+	It("serves the ranges within the modified regions", func() {
+		w := serve("bytes=9-10")
+		Expect(w.Code).To(Equal(http.StatusPartialContent))
+		Expect(w.Body.String()).To(Equal("YZ"))
+		Expect(w.files).To(Equal(0))
+	})
+})
+
+var _ = Describe("MeasuredHandler", func() {
+	It("measures the responses copied with ReadFrom", func() {
+		rec := &fileCountingRecorder{ResponseRecorder: httptest.NewRecorder()}
+		w := &measuredWriter{ResponseWriter: rec, statusCode: http.StatusOK}
+
+		_, err := w.Write([]byte("abc"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.ReadFrom(strings.NewReader("defg"))).To(Equal(int64(4)))
+		Expect(w.bytesWritten).To(Equal(int64(7)))
+		Expect(rec.Body.String()).To(Equal("abcdefg"))
+	})
+})
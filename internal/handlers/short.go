@@ -15,6 +15,11 @@ var jwtPayloadRegexp = regexp.MustCompile(`^.+\.(.+)\..+`)
 type payload struct {
 	Sub        string `json:"sub"`          // used by OCM tokens
 	InfraEnvID string `json:"infra_env_id"` // used by local auth tokens
+
+	// claims restricting what the token may fetch, see checkTokenScope
+	OpenshiftVersions []string `json:"openshift_versions,omitempty"`
+	CPUArchitectures  []string `json:"cpu_architectures,omitempty"`
+	Artifacts         []string `json:"artifacts,omitempty"`
 }
 
 // parseShortURL parses short-style URLs, where URL path segments are used to
@@ -63,29 +68,42 @@ func parseShortURL(r *http.Request) (*imageDownloadParams, int, error) {
 // this service. The JWT will be verified and evaluated for authn and authz by
 // assisted-service.
 func idFromJWT(jwt string) (string, error) {
+	p, err := payloadFromJWT(jwt)
+	if err != nil {
+		return "", err
+	}
+
+	if id := p.infraEnvID(); id != "" {
+		return id, nil
+	}
+
+	return "", fmt.Errorf("InfraEnv ID not found in token")
+}
+
+// payloadFromJWT decodes the JWT payload, without verifying its signature
+func payloadFromJWT(jwt string) (*payload, error) {
 	match := jwtPayloadRegexp.FindStringSubmatch(jwt)
 
 	if len(match) != 2 {
-		return "", fmt.Errorf("failed to parse JWT from URL")
+		return nil, fmt.Errorf("failed to parse JWT from URL")
 	}
 
 	decoded, err := base64.RawStdEncoding.DecodeString(match[1])
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	var p payload
 	err = json.Unmarshal(decoded, &p)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return &p, nil
+}
 
-	switch {
-	case p.Sub != "":
-		return p.Sub, nil
-	case p.InfraEnvID != "":
-		return p.InfraEnvID, nil
+func (p *payload) infraEnvID() string {
+	if p.Sub != "" {
+		return p.Sub
 	}
-
-	return "", fmt.Errorf("InfraEnv ID not found in token")
+	return p.InfraEnvID
 }
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openshift/assisted-image-service/pkg/servers"
+)
+
+// maxRateLimitedClients bounds the request rates kept, the clients that made no request for the
+// longest time are forgotten beyond it
+const maxRateLimitedClients = 10000
+
+// StreamLimits caps the ISO streams served simultaneously and the rate of the requests of the
+// clients. The requests over the limits are answered with 503 and Retry-After rather than
+// queued. Zero means no limit.
+type StreamLimits struct {
+	MaxStreams          int
+	MaxStreamsPerClient int
+	// MaxRequestsPerMinutePerClient is the rate of the requests of a client, which may also make
+	// that many requests at once
+	MaxRequestsPerMinutePerClient int
+	// MaxMemoryPerStream bounds the content fetched from assisted service that a stream keeps in
+	// memory, the ignition and the ramdisk, the rest being read from the ISOs with a fixed buffer
+	MaxMemoryPerStream int64
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For tells the address of their
+	// clients, the limits apply to the address of the peer for the requests of the others
+	TrustedProxies TrustedProxies
+}
+
+// TrustedProxies are the networks of the reverse proxies in front of the service
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses the CIDRs, or the single addresses, of the trusted proxies
+func ParseTrustedProxies(values []string) (TrustedProxies, error) {
+	var proxies TrustedProxies
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy address %q", value)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy network %q: %w", value, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+func (p TrustedProxies) contains(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientAddress returns the address of the client of the request. When the peer is a trusted
+// proxy, or a local listener client, it's the last address of X-Forwarded-For that isn't a trusted
+// proxy, the ones before it can be forged by the client.
+func (p TrustedProxies) clientAddress(r *http.Request) string {
+	address := remoteHost(r)
+	if !servers.IsLocal(r.Context()) && !p.contains(address) {
+		return address
+	}
+	forwarded := r.Header.Values("X-Forwarded-For")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hops := strings.Split(forwarded[i], ",")
+		for j := len(hops) - 1; j >= 0; j-- {
+			hop := strings.TrimSpace(hops[j])
+			if hop == "" {
+				continue
+			}
+			address = hop
+			if !p.contains(hop) {
+				return address
+			}
+		}
+	}
+	return address
+}
+
+// remoteHost returns the address of the peer of the request
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+type streamLimiter struct {
+	StreamLimits
+	lock          sync.Mutex
+	streams       int
+	clientStreams map[string]int
+	// clientRates holds the elements of rateOrder, the most recently used rates first
+	clientRates map[string]*list.Element
+	rateOrder   *list.List
+	now         func() time.Time
+}
+
+// requestRate is the token bucket of the requests of a client
+type requestRate struct {
+	client  string
+	tokens  float64
+	updated time.Time
+}
+
+func newStreamLimiter(limits StreamLimits) *streamLimiter {
+	return &streamLimiter{
+		StreamLimits:  limits,
+		clientStreams: map[string]int{},
+		clientRates:   map[string]*list.Element{},
+		rateOrder:     list.New(),
+		now:           time.Now,
+	}
+}
+
+func respondOverLimit(w http.ResponseWriter, retryAfter string, format string, a ...interface{}) {
+	w.Header().Set("Retry-After", retryAfter)
+	httpErrorf(w, http.StatusServiceUnavailable, format, a...)
+}
+
+// limitRate rejects the requests of the clients over their rate
+func (l *streamLimiter) limitRate(next http.Handler) http.Handler {
+	if l == nil || l.MaxRequestsPerMinutePerClient <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := l.TrustedProxies.clientAddress(r)
+		if wait := l.takeRequest(client); wait > 0 {
+			respondOverLimit(w, strconv.Itoa(int(math.Ceil(wait.Seconds()))), "Too many requests from %s, retry later", client)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// takeRequest takes a request from the bucket of the client, or returns the time until it can
+func (l *streamLimiter) takeRequest(client string) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	capacity := float64(l.MaxRequestsPerMinutePerClient)
+	perSecond := capacity / 60
+	now := l.now()
+	elem, ok := l.clientRates[client]
+	if ok {
+		l.rateOrder.MoveToFront(elem)
+	} else {
+		if len(l.clientRates) >= maxRateLimitedClients {
+			oldest := l.rateOrder.Back()
+			l.rateOrder.Remove(oldest)
+			delete(l.clientRates, oldest.Value.(*requestRate).client)
+		}
+		elem = l.rateOrder.PushFront(&requestRate{client: client, tokens: capacity, updated: now})
+		l.clientRates[client] = elem
+	}
+	rate := elem.Value.(*requestRate)
+	rate.tokens = math.Min(capacity, rate.tokens+now.Sub(rate.updated).Seconds()*perSecond)
+	rate.updated = now
+	if rate.tokens < 1 {
+		return time.Duration((1 - rate.tokens) / perSecond * float64(time.Second))
+	}
+	rate.tokens--
+	return 0
+}
+
+// limitStreams rejects the streams over the global or the per-client caps
+func (l *streamLimiter) limitStreams(next http.Handler) http.Handler {
+	if l == nil || (l.MaxStreams <= 0 && l.MaxStreamsPerClient <= 0) {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := l.TrustedProxies.clientAddress(r)
+		l.lock.Lock()
+		overGlobal := l.MaxStreams > 0 && l.streams >= l.MaxStreams
+		overClient := l.MaxStreamsPerClient > 0 && l.clientStreams[client] >= l.MaxStreamsPerClient
+		if !overGlobal && !overClient {
+			l.streams++
+			l.clientStreams[client]++
+		}
+		l.lock.Unlock()
+		if overGlobal {
+			respondOverLimit(w, retryAfter, "Too many simultaneous ISO streams, retry later")
+			return
+		} else if overClient {
+			respondOverLimit(w, retryAfter, "Too many simultaneous ISO streams from %s, retry later", client)
+			return
+		}
+
+		defer func() {
+			l.lock.Lock()
+			l.streams--
+			if l.clientStreams[client]--; l.clientStreams[client] == 0 {
+				delete(l.clientStreams, client)
+			}
+			l.lock.Unlock()
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// takeBackgroundStream counts an ISO generated in the background, e.g. to fill the ISO cache,
+// against the global cap. It returns false when the cap is reached.
+func (l *streamLimiter) takeBackgroundStream() bool {
+	if l == nil || l.MaxStreams <= 0 {
+		return true
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.streams >= l.MaxStreams {
+		return false
+	}
+	l.streams++
+	return true
+}
+
+// releaseBackgroundStream releases a stream taken with takeBackgroundStream
+func (l *streamLimiter) releaseBackgroundStream() {
+	if l == nil || l.MaxStreams <= 0 {
+		return
+	}
+	l.lock.Lock()
+	l.streams--
+	l.lock.Unlock()
+}
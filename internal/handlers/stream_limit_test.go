@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("streamLimiter", func() {
+	request := func(remoteAddr string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/images/foo", nil)
+		r.RemoteAddr = remoteAddr
+		return r
+	}
+
+	Context("limitStreams", func() {
+		var (
+			release chan struct{}
+			started chan struct{}
+			handler http.Handler
+		)
+
+		newHandler := func(limits StreamLimits) {
+			release = make(chan struct{})
+			started = make(chan struct{}, 10)
+			handler = newStreamLimiter(limits).limitStreams(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				started <- struct{}{}
+				<-release
+			}))
+		}
+
+		// startStream serves a request which blocks until release is closed
+		startStream := func(remoteAddr string) chan *httptest.ResponseRecorder {
+			done := make(chan *httptest.ResponseRecorder, 1)
+			go func() {
+				defer GinkgoRecover()
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, request(remoteAddr))
+				done <- w
+			}()
+			Eventually(started).Should(Receive())
+			return done
+		}
+
+		It("rejects the streams over the global cap", func() {
+			newHandler(StreamLimits{MaxStreams: 2})
+			done := []chan *httptest.ResponseRecorder{startStream("10.0.0.1:1000"), startStream("10.0.0.2:1000")}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, request("10.0.0.3:1000"))
+			Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(w.Header().Get("Retry-After")).To(Equal(retryAfter))
+
+			close(release)
+			for _, d := range done {
+				Expect((<-d).Code).To(Equal(http.StatusOK))
+			}
+			release = make(chan struct{})
+			d := startStream("10.0.0.3:1000")
+			close(release)
+			Expect((<-d).Code).To(Equal(http.StatusOK))
+		})
+
+		It("rejects the streams over the per-client cap", func() {
+			newHandler(StreamLimits{MaxStreamsPerClient: 1})
+			done := []chan *httptest.ResponseRecorder{startStream("10.0.0.1:1000"), startStream("10.0.0.2:1000")}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, request("10.0.0.1:2000"))
+			Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(w.Header().Get("Retry-After")).To(Equal(retryAfter))
+
+			close(release)
+			for _, d := range done {
+				Expect((<-d).Code).To(Equal(http.StatusOK))
+			}
+		})
+
+		It("doesn't limit the streams without caps", func() {
+			newHandler(StreamLimits{})
+			var done []chan *httptest.ResponseRecorder
+			for i := 0; i < 5; i++ {
+				done = append(done, startStream("10.0.0.1:1000"))
+			}
+			close(release)
+			for _, d := range done {
+				Expect((<-d).Code).To(Equal(http.StatusOK))
+			}
+		})
+	})
+
+	Context("limitRate", func() {
+		var (
+			limiter *streamLimiter
+			handler http.Handler
+			now     time.Time
+		)
+
+		BeforeEach(func() {
+			now = time.Now()
+			limiter = newStreamLimiter(StreamLimits{MaxRequestsPerMinutePerClient: 2})
+			limiter.now = func() time.Time { return now }
+			handler = limiter.limitRate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		})
+
+		serve := func(remoteAddr string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, request(remoteAddr))
+			return w
+		}
+
+		It("rejects the requests over the rate of the client", func() {
+			Expect(serve("10.0.0.1:1000").Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.1:1000").Code).To(Equal(http.StatusOK))
+
+			w := serve("10.0.0.1:1000")
+			Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(w.Header().Get("Retry-After")).To(Equal("30"))
+
+			Expect(serve("10.0.0.2:1000").Code).To(Equal(http.StatusOK))
+
+			now = now.Add(20 * time.Second)
+			w = serve("10.0.0.1:1000")
+			Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(w.Header().Get("Retry-After")).To(Equal("10"))
+
+			now = now.Add(10 * time.Second)
+			Expect(serve("10.0.0.1:1000").Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.1:1000").Code).To(Equal(http.StatusServiceUnavailable))
+		})
+
+		It("forgets the least recently seen clients beyond the cap", func() {
+			Expect(serve("10.0.0.1:1000").Code).To(Equal(http.StatusOK))
+			Expect(serve("10.0.0.1:1000").Code).To(Equal(http.StatusOK))
+			for i := 1; i < maxRateLimitedClients; i++ {
+				Expect(serve(fmt.Sprintf("10.1.%d.%d:1000", i/256, i%256)).Code).To(Equal(http.StatusOK))
+			}
+			Expect(limiter.clientRates).To(HaveLen(maxRateLimitedClients))
+			Expect(serve("10.0.0.1:1000").Code).To(Equal(http.StatusServiceUnavailable))
+
+			Expect(serve("10.0.0.2:1000").Code).To(Equal(http.StatusOK))
+			Expect(limiter.clientRates).To(HaveLen(maxRateLimitedClients))
+			Expect(limiter.clientRates).To(HaveKey("10.0.0.1"))
+			Expect(limiter.clientRates).NotTo(HaveKey("10.1.0.1"))
+		})
+	})
+
+	Context("clientAddress", func() {
+		var proxies TrustedProxies
+
+		BeforeEach(func() {
+			var err error
+			proxies, err = ParseTrustedProxies([]string{"10.0.0.0/24", "192.168.1.1"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the host of the remote address", func() {
+			Expect(proxies.clientAddress(request("10.1.0.1:1000"))).To(Equal("10.1.0.1"))
+		})
+
+		It("ignores X-Forwarded-For from untrusted peers", func() {
+			r := request("10.1.0.1:1000")
+			r.Header.Add("X-Forwarded-For", "1.2.3.4")
+			Expect(proxies.clientAddress(r)).To(Equal("10.1.0.1"))
+			Expect(TrustedProxies(nil).clientAddress(r)).To(Equal("10.1.0.1"))
+		})
+
+		It("returns the address added to X-Forwarded-For by the trusted proxies", func() {
+			r := request("10.0.0.1:1000")
+			r.Header.Add("X-Forwarded-For", "1.2.3.4")
+			r.Header.Add("X-Forwarded-For", "5.6.7.8, 10.1.1.1, 192.168.1.1")
+			Expect(proxies.clientAddress(r)).To(Equal("10.1.1.1"))
+		})
+
+		It("rejects invalid proxies", func() {
+			_, err := ParseTrustedProxies([]string{"10.0.0.0/33"})
+			Expect(err).To(HaveOccurred())
+			_, err = ParseTrustedProxies([]string{"proxy"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// streamMemoryKey is the context key of the memory limit of the streams of the requests
+type streamMemoryKey struct{}
+
+// limitMemory sets the memory limit of the streams of the requests, see
+// StreamLimits.MaxMemoryPerStream
+func (l *streamLimiter) limitMemory(next http.Handler) http.Handler {
+	if l == nil || l.MaxMemoryPerStream <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), streamMemoryKey{}, l.MaxMemoryPerStream)))
+	})
+}
+
+// readStreamContent reads the body of a response of assisted service that customizes the stream
+// of the request, which is kept in memory while the stream is served. It fails once the body
+// exceeds the memory limit of the streams.
+func readStreamContent(imageServiceRequest *http.Request, body io.Reader) ([]byte, error) {
+	limit, ok := imageServiceRequest.Context().Value(streamMemoryKey{}).(int64)
+	if !ok {
+		return io.ReadAll(body)
+	}
+	content, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("the content exceeds the memory limit of %d bytes per stream", limit)
+	}
+	return content, nil
+}
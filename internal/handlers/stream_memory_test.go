@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("readStreamContent", func() {
+	read := func(limits StreamLimits, content string) ([]byte, error) {
+		var data []byte
+		var err error
+		handler := newStreamLimiter(limits).limitMemory(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, err = readStreamContent(r, strings.NewReader(content))
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/images/test", nil))
+		return data, err
+	}
+
+	It("reads the content within the memory limit of the streams", func() {
+		Expect(read(StreamLimits{MaxMemoryPerStream: 10}, "0123456789")).To(Equal([]byte("0123456789")))
+	})
+
+	It("fails beyond the memory limit of the streams", func() {
+		_, err := read(StreamLimits{MaxMemoryPerStream: 10}, "0123456789a")
+		Expect(err).To(MatchError("the content exceeds the memory limit of 10 bytes per stream"))
+	})
+
+	It("reads the content without limit", func() {
+		Expect(read(StreamLimits{}, strings.Repeat("0", 1000))).To(HaveLen(1000))
+	})
+})
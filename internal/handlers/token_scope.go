@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/assisted-image-service/pkg/logging"
+)
+
+// Artifact types the image tokens may be restricted to with the artifacts claim
+const (
+	tokenArtifactISO    = "iso"
+	tokenArtifactPXE    = "pxe"
+	tokenArtifactRootfs = "rootfs"
+	tokenArtifactDisk   = "disk"
+)
+
+// scopedRequest is what a request fetches, as checked against the claims of its token
+type scopedRequest struct {
+	imageID  string
+	version  string
+	arch     string
+	artifact string
+}
+
+// requestToken returns the image token, the api key or the bearer token of the request, which may
+// be JWTs, as forwarded to assisted service by setRequestAuth
+func requestToken(r *http.Request) string {
+	for _, token := range []string{
+		chi.URLParam(r, "token"),
+		chi.URLParam(r, "api_key"),
+		r.URL.Query().Get("image_token"),
+		r.URL.Query().Get("api_key"),
+		bearerToken(r),
+	} {
+		if token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// bearerToken returns the token of the bearer Authorization header of the request
+func bearerToken(r *http.Request) string {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// checkTokenScope checks that the infra-env, version, arch and artifact type of the request are
+// allowed by the claims of its token. Like idFromJWT, it doesn't verify the signature of the
+// token, which assisted service does when the image is customized. The denied requests, and the
+// allowed requests of tokens with scope claims, are logged as audit events.
+func checkTokenScope(r *http.Request, req scopedRequest) error {
+	token := requestToken(r)
+	if token == "" {
+		return nil
+	}
+	p, err := payloadFromJWT(token)
+	if err != nil {
+		// not a JWT, assisted service authenticates it
+		return nil
+	}
+
+	var reason string
+	switch {
+	case req.imageID != "" && p.infraEnvID() != "" && !strings.EqualFold(req.imageID, p.infraEnvID()):
+		reason = fmt.Sprintf("the token is for infra-env %s", p.infraEnvID())
+	case len(p.OpenshiftVersions) > 0 && !slices.Contains(p.OpenshiftVersions, req.version):
+		reason = fmt.Sprintf("the token doesn't allow version %s", req.version)
+	case len(p.CPUArchitectures) > 0 && !slices.Contains(p.CPUArchitectures, req.arch):
+		reason = fmt.Sprintf("the token doesn't allow architecture %s", req.arch)
+	case len(p.Artifacts) > 0 && !slices.Contains(p.Artifacts, req.artifact):
+		reason = fmt.Sprintf("the token doesn't allow %s artifacts", req.artifact)
+	}
+
+	scoped := len(p.OpenshiftVersions) > 0 || len(p.CPUArchitectures) > 0 || len(p.Artifacts) > 0
+	if reason != "" || scoped {
+		log.WithFields(log.Fields{
+			"audit":        "image_token_scope",
+			"allowed":      reason == "",
+			"reason":       reason,
+			"infra_env_id": p.infraEnvID(),
+			"image_id":     req.imageID,
+			"version":      req.version,
+			"arch":         req.arch,
+			"artifact":     req.artifact,
+			"path":         logging.Redact(r.URL.Path),
+			"remote_addr":  r.RemoteAddr,
+		}).Info("Image token scope check")
+	}
+	if reason != "" {
+		return fmt.Errorf("request out of the scope of the token: %s", reason)
+	}
+	return nil
+}
+
+// withTokenScope rejects the requests of the route out of the scope of their token with 403
+func withTokenScope(artifact string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			version := chi.URLParam(r, "version")
+			if version == "" {
+				version = r.URL.Query().Get("version")
+			}
+			arch := chi.URLParam(r, "arch")
+			if arch == "" {
+				arch = r.URL.Query().Get("arch")
+			}
+			if arch == "" {
+				arch = defaultArch
+			}
+			err := checkTokenScope(r, scopedRequest{
+				imageID:  chi.URLParam(r, "image_id"),
+				version:  version,
+				arch:     arch,
+				artifact: artifact,
+			})
+			if err != nil {
+				httpErrorf(w, http.StatusForbidden, "%v", err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
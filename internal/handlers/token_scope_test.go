@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+// unsignedJWT returns a JWT with the claims, the image service doesn't verify its signature
+func unsignedJWT(claims map[string]interface{}) string {
+	header := base64.RawStdEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload, err := json.Marshal(claims)
+	Expect(err).NotTo(HaveOccurred())
+	return header + "." + base64.RawStdEncoding.EncodeToString(payload) + ".signature"
+}
+
+var _ = Describe("withTokenScope", func() {
+	const imageID = "bf25292a-dddd-49dc-ab9c-3fb4c1f07071"
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		handler := &ImageHandler{
+			long:                ok,
+			byAPIKey:            ok,
+			byID:                ok,
+			byToken:             ok,
+			initrd:              ok,
+			ipxeScript:          ok,
+			pxeBundle:           ok,
+			s390xInitrdAddrsize: ok,
+		}
+		server = httptest.NewServer(handler.router(1))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	DescribeTable("checks the requests against the claims of their token",
+		func(path func() string, expectedStatus int) {
+			resp, err := server.Client().Get(server.URL + path())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(expectedStatus))
+		},
+		Entry("allows requests without token", func() string {
+			return "/images/" + imageID + "?version=4.11&type=full-iso"
+		}, http.StatusOK),
+		Entry("allows opaque tokens", func() string {
+			return "/images/" + imageID + "?version=4.11&type=full-iso&api_key=opaque"
+		}, http.StatusOK),
+		Entry("allows tokens of the infra-env", func() string {
+			return "/images/" + imageID + "?version=4.11&image_token=" + unsignedJWT(map[string]interface{}{"sub": imageID})
+		}, http.StatusOK),
+		Entry("rejects tokens of another infra-env", func() string {
+			return "/images/" + imageID + "?version=4.11&image_token=" + unsignedJWT(map[string]interface{}{"infra_env_id": "dc6e8127-53b5-49c3-9adc-6a8c2c35e6a9"})
+		}, http.StatusForbidden),
+		Entry("allows the versions of the token", func() string {
+			return "/images/" + imageID + "?version=4.11&image_token=" + unsignedJWT(map[string]interface{}{"openshift_versions": []string{"4.10", "4.11"}})
+		}, http.StatusOK),
+		Entry("rejects the other versions", func() string {
+			return "/images/" + imageID + "?version=4.12&image_token=" + unsignedJWT(map[string]interface{}{"openshift_versions": []string{"4.10", "4.11"}})
+		}, http.StatusForbidden),
+		Entry("checks the default arch", func() string {
+			return "/images/" + imageID + "?version=4.11&image_token=" + unsignedJWT(map[string]interface{}{"cpu_architectures": []string{"arm64"}})
+		}, http.StatusForbidden),
+		Entry("checks the arch of short URLs", func() string {
+			return "/bytoken/" + unsignedJWT(map[string]interface{}{"sub": imageID, "cpu_architectures": []string{"arm64"}}) + "/4.11/arm64/full.iso"
+		}, http.StatusOK),
+		Entry("allows the artifacts of the token", func() string {
+			return "/images/" + imageID + "/pxe-initrd?version=4.11&api_key=" + unsignedJWT(map[string]interface{}{"artifacts": []string{"pxe"}})
+		}, http.StatusOK),
+		Entry("rejects the other artifacts", func() string {
+			return "/images/" + imageID + "?version=4.11&api_key=" + unsignedJWT(map[string]interface{}{"artifacts": []string{"pxe"}})
+		}, http.StatusForbidden),
+	)
+
+	DescribeTable("checks the claims of the bearer tokens",
+		func(claims map[string]interface{}, expectedStatus int) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/images/"+imageID+"?version=4.11&type=full-iso", nil)
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Set("Authorization", "Bearer "+unsignedJWT(claims))
+			resp, err := server.Client().Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(expectedStatus))
+		},
+		Entry("allows the artifacts of the token", map[string]interface{}{"sub": imageID, "artifacts": []string{"iso"}}, http.StatusOK),
+		Entry("rejects tokens of another infra-env", map[string]interface{}{"infra_env_id": "dc6e8127-53b5-49c3-9adc-6a8c2c35e6a9"}, http.StatusForbidden),
+		Entry("rejects the other versions", map[string]interface{}{"openshift_versions": []string{"4.10"}}, http.StatusForbidden),
+		Entry("rejects the other artifacts", map[string]interface{}{"artifacts": []string{"pxe"}}, http.StatusForbidden),
+	)
+})
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+	"github.com/openshift/assisted-image-service/pkg/uki"
+)
+
+// ukiHandler serves a Unified Kernel Image of the kernel, the initrd customized for the image with
+// the rootfs appended, and the PXE kernel arguments, for UEFI HTTP boot
+type ukiHandler struct {
+	ImageStore imagestore.ImageStore
+	client     *AssistedServiceClient
+	builder    uki.Builder
+	workDir    string
+}
+
+var _ http.Handler = &ukiHandler{}
+
+func (h *ukiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	imageID := chi.URLParam(r, "image_id")
+	version := r.URL.Query().Get("version")
+
+	arch := r.URL.Query().Get("arch")
+	if arch == "" {
+		arch = defaultArch
+	}
+	if !uki.IsSupported(arch) {
+		httpErrorf(w, http.StatusBadRequest, "unified kernel images are not supported for %s architecture", arch)
+		return
+	}
+
+	initrdReader, lastModified, initrdETag, code, err := initrdOverlayReader(h.ImageStore, h.client, r, arch)
+	if errors.Is(err, errVersionDownloading) {
+		respondVersionDownloading(w, version, arch)
+		return
+	} else if err != nil {
+		httpErrorf(w, code, err.Error())
+		return
+	}
+	initrdReader = overlay.WithContext(r.Context(), initrdReader)
+	defer initrdReader.Close()
+
+	isoPath := h.ImageStore.PathForParams(imagestore.ImageTypeFull, version, arch)
+	kargs, statusCode, err := pxeKernelArguments(h.client, r, imageID, isoPath, version, arch)
+	if err != nil {
+		httpErrorf(w, statusCode, "Failed to get the kernel arguments: %v", err)
+		return
+	}
+
+	tmpDir, err := os.MkdirTemp(h.workDir, "uki")
+	if err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "Failed to create the unified kernel image: %v", err)
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+	kernelPath := filepath.Join(tmpDir, "vmlinuz")
+	if err = appendBootArtifact(kernelPath, isoPath, "vmlinuz"); err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "Failed to extract the kernel: %v", err)
+		return
+	}
+	// the rootfs is appended to the initrd, the image has a single initrd
+	initrdPath := filepath.Join(tmpDir, "initrd.img")
+	if err = appendToFile(initrdPath, initrdReader); err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "Failed to write the initrd: %v", err)
+		return
+	}
+	if err = appendBootArtifact(initrdPath, isoPath, "rootfs.img"); err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "Failed to append the rootfs: %v", err)
+		return
+	}
+
+	outputPath := filepath.Join(tmpDir, "uki.efi")
+	if err = h.builder.Build(kernelPath, initrdPath, kargs, arch, outputPath); err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	f, err := os.Open(outputPath)
+	if err != nil {
+		httpErrorf(w, http.StatusInternalServerError, "Failed to open the unified kernel image: %v", err)
+		return
+	}
+	defer f.Close()
+
+	fileName := fmt.Sprintf("%s-discovery.efi", imageID)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	modTime, err := http.ParseTime(lastModified)
+	if err != nil {
+		log.Warnf("Error parsing last modified time %s: %v", lastModified, err)
+		modTime = time.Now()
+	}
+	serveImage(w, r, fileName, modTime, f, imageETag(isoPath, []byte(initrdETag), []byte(strings.Join(kargs, " "))))
+}
+
+// appendBootArtifact appends the boot artifact of the ISO to the file at path
+func appendBootArtifact(path, isoPath, artifact string) error {
+	reader, err := openBootArtifact(isoPath, artifact)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return appendToFile(path, reader)
+}
+
+// appendToFile appends the content to the file at path, creating it if needed
+func appendToFile(path string, content io.Reader) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
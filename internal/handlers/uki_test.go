@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+	"github.com/openshift/assisted-image-service/pkg/uki"
+)
+
+var _ = Describe("UKI ServeHTTP", func() {
+	var (
+		ctrl           *gomock.Controller
+		mockImageStore *imagestore.MockImageStore
+		mockBuilder    *uki.MockBuilder
+		assistedServer *ghttp.Server
+		server         *httptest.Server
+		client         *http.Client
+		isoFile        string
+		workDir        string
+		imageID        = "bf25292a-dddd-49dc-ab9c-3fb4c1f07071"
+		kargs          = "random.trust_cpu=on ignition.firstboot ignition.platform.id=metal"
+	)
+
+	BeforeEach(func() {
+		var err error
+		isoFile = createTestISO()
+		workDir, err = os.MkdirTemp("", "ukiTest")
+		Expect(err).NotTo(HaveOccurred())
+
+		ctrl = gomock.NewController(GinkgoT())
+		mockImageStore = imagestore.NewMockImageStore(ctrl)
+		mockBuilder = uki.NewMockBuilder(ctrl)
+		assistedServer = ghttp.NewServer()
+		u, err := url.Parse(assistedServer.URL())
+		Expect(err).NotTo(HaveOccurred())
+		asc, err := NewAssistedServiceClient(u.Scheme, u.Host, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		handler := &ImageHandler{
+			uki: &ukiHandler{
+				ImageStore: mockImageStore,
+				client:     asc,
+				builder:    mockBuilder,
+				workDir:    workDir,
+			},
+		}
+		server = httptest.NewServer(handler.router(1))
+		client = server.Client()
+	})
+
+	AfterEach(func() {
+		os.Remove(isoFile)
+		server.Close()
+		assistedServer.Close()
+		Expect(os.RemoveAll(workDir)).To(Succeed())
+	})
+
+	It("builds the image from the kernel, the initrd with the rootfs and the kernel arguments", func() {
+		mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(true)
+		mockImageStore.EXPECT().Available("4.11", defaultArch).Return(true)
+		mockImageStore.EXPECT().PathForParams(imagestore.ImageTypeFull, "4.11", defaultArch).Return(isoFile).Times(2)
+		assistedServer.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(fileRouteFormat, imageID), "file_name=discovery.ign"),
+				ghttp.RespondWith(http.StatusOK, "someignitioncontent", http.Header{"Last-Modified": {"Fri, 22 Apr 2022 18:11:09 GMT"}}),
+			),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf("/api/assisted-install/v2/infra-envs/%s/downloads/minimal-initrd", imageID)),
+				ghttp.RespondWith(http.StatusNoContent, []byte{}),
+			),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(infraEnvPathFormat, imageID)),
+				ghttp.RespondWith(http.StatusOK, "{}"),
+			),
+		)
+		mockBuilder.EXPECT().Build(gomock.Any(), gomock.Any(), strings.Fields(kargs), defaultArch, gomock.Any()).DoAndReturn(
+			func(kernelPath, initrdPath string, _ []string, _, outputPath string) error {
+				Expect(os.ReadFile(kernelPath)).To(Equal([]byte("this is kernel")))
+				initrd, err := os.ReadFile(initrdPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(initrd)).To(HavePrefix("this is initrd"))
+				Expect(string(initrd)).To(HaveSuffix("this is rootfs"))
+				return os.WriteFile(outputPath, []byte("MZuki"), 0600)
+			})
+
+		resp, err := client.Get(fmt.Sprintf("%s/images/%s/uki?version=4.11", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Disposition")).To(Equal(fmt.Sprintf("attachment; filename=%s-discovery.efi", imageID)))
+		Expect(resp.Header.Get("ETag")).NotTo(BeEmpty())
+		Expect(io.ReadAll(resp.Body)).To(Equal([]byte("MZuki")))
+		Expect(os.ReadDir(workDir)).To(BeEmpty())
+	})
+
+	It("fails when no version is supplied", func() {
+		resp, err := client.Get(fmt.Sprintf("%s/images/%s/uki", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("fails for the architectures without UEFI", func() {
+		resp, err := client.Get(fmt.Sprintf("%s/images/%s/uki?version=4.11&arch=s390x", server.URL, imageID))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
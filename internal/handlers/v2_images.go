@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+// maxCustomizationSpecSize bounds the body of the v2 image requests, which may carry the network
+// configuration of many hosts
+const maxCustomizationSpecSize = 16 << 20
+
+var v2ImagePathRegexp = regexp.MustCompile(`^/v2/images/([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+
+// serveV2Image streams the image customized by the spec in the body of the request, or enqueues
+// its build when the spec is async
+func (h *BuildHandler) serveV2Image(w http.ResponseWriter, r *http.Request, imageID string) {
+	spec := &customizationSpec{}
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxCustomizationSpecSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(spec); err != nil {
+		httpErrorf(w, http.StatusBadRequest, "invalid customization spec: %v", err)
+		return
+	}
+	if err := spec.validate(); err != nil {
+		httpErrorf(w, http.StatusBadRequest, "invalid customization spec: %v", err)
+		return
+	}
+	params := spec.params(imageID)
+
+	if !h.ImageStore.HaveVersion(params.version, params.arch) {
+		httpErrorf(w, http.StatusNotFound, "version %s %s not found", params.version, params.arch)
+		return
+	}
+	err := checkTokenScope(r, scopedRequest{
+		imageID:  params.imageID,
+		version:  params.version,
+		arch:     params.arch,
+		artifact: tokenArtifactISO,
+	})
+	if err != nil {
+		httpErrorf(w, http.StatusForbidden, "%v", err)
+		return
+	}
+	c, statusCode, err := fetchISOCustomization(h.client, r, params)
+	if err != nil {
+		httpErrorf(w, statusCode, "%v", err)
+		return
+	}
+	if err = spec.apply(c, params, h.nmstateHandler); err != nil {
+		httpErrorf(w, http.StatusBadRequest, "failed to customize the image: %v", err)
+		return
+	}
+	if spec.Async {
+		h.enqueue(w, r, params, c, spec.CallbackURL)
+		return
+	}
+	if !h.ImageStore.Available(params.version, params.arch) {
+		respondVersionDownloading(w, params.version, params.arch)
+		return
+	}
+
+	isoPath := h.ImageStore.PathForParams(params.imageType, params.version, params.arch)
+	isoReader, err := h.GenerateImageStream(isoPath, c.ignition, c.ramdisk, c.kargs)
+	if err != nil {
+		httpErrorf(w, streamErrorStatus(err), "failed to create image stream: %v", err)
+		return
+	}
+	// stop generating the stream as soon as the client goes away
+	isoReader = overlay.WithContext(r.Context(), isoReader)
+	defer isoReader.Close()
+
+	fileName := fmt.Sprintf("%s-discovery.iso", params.imageID)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	serveImage(w, r, fileName, c.modTime(), isoReader, imageETag(isoPath, c.ignition.Config, c.ramdisk, kargsETagInput(c.kargs)))
+}
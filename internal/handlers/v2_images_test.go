@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/openshift/assisted-image-service/pkg/imagestore"
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
+)
+
+var _ = Describe("v2 images", func() {
+	var (
+		ctrl           *gomock.Controller
+		mockImageStore *imagestore.MockImageStore
+		assistedServer *ghttp.Server
+		handler        *BuildHandler
+		server         *httptest.Server
+		client         *http.Client
+		dir            string
+		isoFile        string
+		generated      chan [3][]byte
+		imageID        = "bf25292a-dddd-49dc-ab9c-3fb4c1f07071"
+		baseIgnition   = `{"ignition": {"version": "3.1.0"}, "storage": {"files": [{"path": "/etc/base"}]}}`
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockImageStore = imagestore.NewMockImageStore(ctrl)
+		assistedServer = ghttp.NewServer()
+		u, err := url.Parse(assistedServer.URL())
+		Expect(err).NotTo(HaveOccurred())
+		asc, err := NewAssistedServiceClient(u.Scheme, u.Host, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		dir, err = os.MkdirTemp("", "v2_images_test")
+		Expect(err).NotTo(HaveOccurred())
+		isoFile = filepath.Join(dir, "base.iso")
+		Expect(os.WriteFile(isoFile, []byte("someisocontent"), 0600)).To(Succeed())
+
+		handler, err = NewBuildHandler(mockImageStore, asc, nil, isoeditor.ISOMD5Keep, isoeditor.KargsConflictKeepAll, filepath.Join(dir, "builds"), time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		generated = make(chan [3][]byte, 1)
+		handler.GenerateImageStream = func(isoPath string, ignition *isoeditor.IgnitionContent, ramdiskBytes []byte, kargs isoeditor.KernelArguments) (isoeditor.ImageReader, error) {
+			generated <- [3][]byte{ignition.Config, ramdiskBytes, []byte(kargsValues(kargs))}
+			return os.Open(isoPath)
+		}
+		server = httptest.NewServer(handler)
+		client = server.Client()
+	})
+
+	AfterEach(func() {
+		server.Close()
+		assistedServer.Close()
+		os.RemoveAll(dir)
+	})
+
+	appendCustomizationHandlers := func(imageType string) {
+		assistedServer.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("GET", fmt.Sprintf(fileRouteFormat, imageID), fmt.Sprintf("discovery_iso_type=%s&file_name=discovery.ign", imageType)),
+			ghttp.RespondWith(http.StatusOK, baseIgnition, http.Header{"Last-Modified": {"Fri, 22 Apr 2022 18:11:09 GMT"}}),
+		))
+		if imageType == imagestore.ImageTypeMinimal {
+			assistedServer.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf("/api/assisted-install/v2/infra-envs/%s/downloads/minimal-initrd", imageID)),
+				ghttp.RespondWith(http.StatusNoContent, nil),
+			))
+		}
+		assistedServer.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("GET", fmt.Sprintf(infraEnvPathFormat, imageID)),
+			ghttp.RespondWith(http.StatusOK, `{"kernel_arguments": "[{\"operation\": \"append\", \"value\": \"infra=env\"}]"}`),
+		))
+	}
+
+	mockImage := func(imageType string) {
+		mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(true)
+		mockImageStore.EXPECT().Available("4.11", defaultArch).Return(true)
+		mockImageStore.EXPECT().PathForParams(imageType, "4.11", defaultArch).Return(isoFile)
+	}
+
+	post := func(body string) *http.Response {
+		resp, err := client.Post(server.URL+"/v2/images/"+imageID, "application/json", strings.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	It("streams the image customized by the spec", func() {
+		mockImage(imagestore.ImageTypeFull)
+		appendCustomizationHandlers(imagestore.ImageTypeFull)
+
+		resp := post(`{
+			"version": "4.11",
+			"type": "full-iso",
+			"ignition": "{\"ignition\": {\"version\": \"3.1.0\"}, \"storage\": {\"files\": [{\"path\": \"/etc/spec\"}]}}",
+			"kernel_arguments": ["spec=arg", "quiet"]
+		}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Disposition")).To(Equal(fmt.Sprintf("attachment; filename=%s-discovery.iso", imageID)))
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal("someisocontent"))
+
+		var g [3][]byte
+		Expect(generated).To(Receive(&g))
+		Expect(string(g[0])).To(ContainSubstring("/etc/base"))
+		Expect(string(g[0])).To(ContainSubstring("/etc/spec"))
+		Expect(g[1]).To(BeEmpty())
+		Expect(string(g[2])).To(Equal("infra=env spec=arg quiet"))
+	})
+
+	It("adds the live files of minimal images to the ramdisk", func() {
+		mockImage(imagestore.ImageTypeMinimal)
+		appendCustomizationHandlers(imagestore.ImageTypeMinimal)
+
+		resp := post(`{
+			"version": "4.11",
+			"type": "minimal-iso",
+			"hosts": [{"mac_addresses": ["52:54:00:aa:bb:01"], "hostname": "worker-0"}],
+			"systemd_units": [{"name": "check.service", "contents": "[Service]\nExecStart=/bin/true\n"}]
+		}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		var g [3][]byte
+		Expect(generated).To(Receive(&g))
+		files, err := cpio.ListFiles(bytes.NewReader(g[1]))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(ContainElement(HaveSuffix("etc/assisted/hosts/52:54:00:aa:bb:01/hostname")))
+		Expect(files).To(ContainElement(HaveSuffix("etc/systemd/system/check.service")))
+	})
+
+	It("builds the image as a job when async", func() {
+		mockImage(imagestore.ImageTypeFull)
+		appendCustomizationHandlers(imagestore.ImageTypeFull)
+
+		resp := post(`{"version": "4.11", "type": "full-iso", "kernel_arguments": ["spec=arg"], "async": true}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+		job := &buildJob{}
+		Expect(json.NewDecoder(resp.Body).Decode(job)).To(Succeed())
+		Expect(resp.Header.Get("Location")).To(Equal("/builds/" + job.ID))
+
+		var g [3][]byte
+		Eventually(generated).Should(Receive(&g))
+		Expect(string(g[2])).To(Equal("infra=env spec=arg"))
+		Eventually(func() string {
+			handler.lock.Lock()
+			defer handler.lock.Unlock()
+			return handler.jobs[job.ID].Status
+		}).Should(Equal(buildStatusSucceeded))
+	})
+
+	It("asks to retry while the images are downloading", func() {
+		mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(true)
+		mockImageStore.EXPECT().Available("4.11", defaultArch).Return(false)
+		appendCustomizationHandlers(imagestore.ImageTypeFull)
+
+		resp := post(`{"version": "4.11", "type": "full-iso"}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+		Expect(resp.Header.Get("Retry-After")).To(Equal(retryAfter))
+	})
+
+	It("fails for a missing version", func() {
+		mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(false)
+
+		resp := post(`{"version": "4.11", "type": "full-iso"}`)
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("only allows POST", func() {
+		resp, err := client.Get(server.URL + "/v2/images/" + imageID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+		Expect(resp.Header.Get("Allow")).To(Equal(http.MethodPost))
+	})
+
+	It("is served behind the limits of the images", func() {
+		images := &ImageHandler{v2Images: handler, limiter: newStreamLimiter(StreamLimits{MaxRequestsPerMinutePerClient: 1})}
+		limited := httptest.NewServer(images.router(1))
+		defer limited.Close()
+		for _, code := range []int{http.StatusBadRequest, http.StatusServiceUnavailable} {
+			resp, err := limited.Client().Post(limited.URL+"/v2/images/"+imageID, "application/json", strings.NewReader(`{"version": `))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(code))
+		}
+
+		filtered := httptest.NewServer(WithInitrdViaHTTP(images.router(1)))
+		defer filtered.Close()
+		resp, err := filtered.Client().Post(filtered.URL+"/v2/images/"+imageID, "application/json", strings.NewReader(`{"version": `))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("limits the memory of the customization", func() {
+		mockImageStore.EXPECT().HaveVersion("4.11", defaultArch).Return(true).Times(2)
+		// the customization fails on the ignition, over the limit
+		for i := 0; i < 2; i++ {
+			assistedServer.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", fmt.Sprintf(fileRouteFormat, imageID)),
+				ghttp.RespondWith(http.StatusOK, baseIgnition),
+			))
+		}
+		images := &ImageHandler{v2Images: handler, limiter: newStreamLimiter(StreamLimits{MaxMemoryPerStream: 10})}
+		limited := httptest.NewServer(images.router(1))
+		defer limited.Close()
+
+		for _, body := range []string{`{"version": "4.11", "type": "full-iso"}`, `{"version": "4.11", "type": "full-iso", "async": true}`} {
+			resp, err := limited.Client().Post(limited.URL+"/v2/images/"+imageID, "application/json", strings.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+		}
+		Expect(generated).NotTo(Receive())
+	})
+
+	DescribeTable("rejects invalid specs",
+		func(body string) {
+			Expect(post(body).StatusCode).To(Equal(http.StatusBadRequest))
+		},
+		Entry("malformed", `{"version": `),
+		Entry("unknown field", `{"version": "4.11", "type": "full-iso", "kargs": "a"}`),
+		Entry("no version", `{"type": "full-iso"}`),
+		Entry("invalid type", `{"version": "4.11", "type": "full"}`),
+		Entry("kargs with spaces", `{"version": "4.11", "type": "full-iso", "kernel_arguments": ["a b"]}`),
+		Entry("invalid ignition", `{"version": "4.11", "type": "full-iso", "ignition": "{\"ignition\": {}}"}`),
+		Entry("invalid CA bundle", `{"version": "4.11", "type": "full-iso", "ca_bundle": "nope"}`),
+		Entry("ramdisk files in a full image", `{"version": "4.11", "type": "full-iso", "systemd_units": [{"name": "a.service"}]}`),
+		Entry("callback without async", `{"version": "4.11", "type": "full-iso", "callback_url": "http://example.com"}`),
+		Entry("relative callback", `{"version": "4.11", "type": "full-iso", "async": true, "callback_url": "/callback"}`),
+	)
+})
+
+func kargsValues(kargs isoeditor.KernelArguments) string {
+	values := make([]string, 0, len(kargs))
+	for _, karg := range kargs {
+		values = append(values, karg.Value)
+	}
+	return strings.Join(values, " ")
+}
@@ -2,23 +2,36 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
+	"github.com/openshift/assisted-image-service/internal/grpcserver"
 	"github.com/openshift/assisted-image-service/internal/handlers"
+	imageservicev1 "github.com/openshift/assisted-image-service/pkg/api/imageservice/v1"
+	"github.com/openshift/assisted-image-service/pkg/diskeditor"
 	"github.com/openshift/assisted-image-service/pkg/imagestore"
 	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+	"github.com/openshift/assisted-image-service/pkg/objectstore"
+	"github.com/openshift/assisted-image-service/pkg/overlay"
 	"github.com/openshift/assisted-image-service/pkg/servers"
+	"github.com/openshift/assisted-image-service/pkg/uki"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	metrics "github.com/slok/go-http-metrics/metrics/prometheus"
 	"github.com/slok/go-http-metrics/middleware"
-	stdmiddleware "github.com/slok/go-http-metrics/middleware/std"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var Options struct {
@@ -29,18 +42,40 @@ var Options struct {
 	HTTPSKeyFile          string `envconfig:"HTTPS_KEY_FILE"`
 	HTTPSCertFile         string `envconfig:"HTTPS_CERT_FILE"`
 
+	// HTTPSClientCAFile requires the clients of the HTTPS and gRPC listeners to present a
+	// certificate signed by a CA of this bundle
+	HTTPSClientCAFile string `envconfig:"HTTPS_CLIENT_CA_FILE"`
+	// HTTPSClientAllowedSANs restricts the client certificates to these comma separated
+	// subject alternative names
+	HTTPSClientAllowedSANs string `envconfig:"HTTPS_CLIENT_ALLOWED_SANS"`
+
+	// UnixSocket is the path of a Unix domain socket also serving plain HTTP, e.g. to a reverse
+	// proxy on the same host, with the UnixSocketMode octal permissions
+	UnixSocket     string `envconfig:"UNIX_SOCKET"`
+	UnixSocketMode string `envconfig:"UNIX_SOCKET_MODE" default:"0660"`
+
 	// Deprecated - use ASSISTED_SERVICE_API_TRUSTED_CA_FILE instead
 	HTTPSCAFile string `envconfig:"HTTPS_CA_FILE"`
 
-	ListenPort            string `envconfig:"LISTEN_PORT" default:"8080"`
-	HTTPListenPort        string `envconfig:"HTTP_LISTEN_PORT"`
-	MaxConcurrentRequests int64  `envconfig:"MAX_CONCURRENT_REQUESTS" default:"400"`
-	RHCOSVersions         string `envconfig:"RHCOS_VERSIONS"`
-	OSImages              string `envconfig:"OS_IMAGES"`
-	AllowedDomains        string `envconfig:"ALLOWED_DOMAINS"`
-	InsecureSkipVerify    bool   `envconfig:"INSECURE_SKIP_VERIFY" default:"false"`
-	ImageServiceBaseURL   string `envconfig:"IMAGE_SERVICE_BASE_URL"`
-	LogLevel              string `envconfig:"LOGLEVEL" default:"info"`
+	ListenPort                    string `envconfig:"LISTEN_PORT" default:"8080"`
+	HTTPListenPort                string `envconfig:"HTTP_LISTEN_PORT"`
+	MaxConcurrentRequests         int64  `envconfig:"MAX_CONCURRENT_REQUESTS" default:"400"`
+	MaxISOStreams                 int    `envconfig:"MAX_ISO_STREAMS" default:"0"`
+	MaxISOStreamsPerClient        int    `envconfig:"MAX_ISO_STREAMS_PER_CLIENT" default:"0"`
+	MaxRequestsPerMinutePerClient int    `envconfig:"MAX_REQUESTS_PER_MINUTE_PER_CLIENT" default:"0"`
+	MaxMemoryPerStream            int64  `envconfig:"MAX_MEMORY_PER_STREAM" default:"67108864"`
+	MaxBytesPerSecond             int64  `envconfig:"MAX_BYTES_PER_SECOND" default:"0"`
+	MaxBytesPerSecondPerStream    int64  `envconfig:"MAX_BYTES_PER_SECOND_PER_STREAM" default:"0"`
+	RHCOSVersions                 string `envconfig:"RHCOS_VERSIONS"`
+	OSImages                      string `envconfig:"OS_IMAGES"`
+	AllowedDomains                string `envconfig:"ALLOWED_DOMAINS"`
+	InsecureSkipVerify            bool   `envconfig:"INSECURE_SKIP_VERIFY" default:"false"`
+	ImageServiceBaseURL           string `envconfig:"IMAGE_SERVICE_BASE_URL"`
+	LogLevel                      string `envconfig:"LOGLEVEL" default:"info"`
+
+	// TrustedProxies are the comma separated addresses or CIDRs of the reverse proxies whose
+	// X-Forwarded-For header tells the address of the clients the limits apply to
+	TrustedProxies []string `envconfig:"TRUSTED_PROXIES"`
 
 	// This is a path to a CA file that will be trusted when fetching OS Images
 	// intended for scenarios where the OS images are served from a service that uses a custom CA
@@ -58,6 +93,99 @@ var Options struct {
 	// OSImagesRequestQueryParams contains a JSON encoded representation of any
 	// query parameters to be sent with every request to download an OS image.
 	OSImagesRequestQueryParams string `envconfig:"OS_IMAGES_REQUEST_QUERY_PARAMS" default:""`
+	// OSImagesPullSecretFile is a path to a pull secret holding the credentials of the
+	// registries of the OS images with oci:// URLs
+	OSImagesPullSecretFile string `envconfig:"OS_IMAGES_PULL_SECRET_FILE" default:""`
+	// OSImagesSignatureKeysFile is a path to OpenPGP public keys. When set, the ISOs are only
+	// admitted if they match the checksums of a sha256sum file signed by one of the keys.
+	OSImagesSignatureKeysFile string `envconfig:"OS_IMAGES_SIGNATURE_KEYS_FILE" default:""`
+
+	// VerifyRootfsURL enables checking that the rootfs URL of minimal ISOs can be downloaded
+	// before serving them, through RootfsURLVerifyProxy or the proxy of the environment
+	VerifyRootfsURL        bool          `envconfig:"VERIFY_ROOTFS_URL" default:"false"`
+	RootfsURLVerifyTimeout time.Duration `envconfig:"ROOTFS_URL_VERIFY_TIMEOUT" default:"10s"`
+	RootfsURLVerifyProxy   string        `envconfig:"ROOTFS_URL_VERIFY_PROXY" default:""`
+
+	// ISOMD5 tells what to do with the checksum implanted in the ISOs, which customization
+	// invalidates: keep it, implant the checksum of the customized ISO, or blank it
+	ISOMD5 string `envconfig:"ISO_MD5" default:"keep"`
+	// KargsConflictPolicy tells how the kernel arguments setting a parameter more than once
+	// are handled when customizing the ISOs
+	KargsConflictPolicy string `envconfig:"KARGS_CONFLICT_POLICY" default:"keep-all"`
+
+	// The CORS responses to the origins of AllowedDomains, for browser-based consoles
+	CORSAllowedMethods   []string      `envconfig:"CORS_ALLOWED_METHODS" default:"GET,HEAD"`
+	CORSAllowedHeaders   []string      `envconfig:"CORS_ALLOWED_HEADERS" default:"Authorization,Content-Type"`
+	CORSExposedHeaders   []string      `envconfig:"CORS_EXPOSED_HEADERS"`
+	CORSAllowCredentials bool          `envconfig:"CORS_ALLOW_CREDENTIALS" default:"false"`
+	CORSMaxAge           time.Duration `envconfig:"CORS_MAX_AGE" default:"10m"`
+
+	// BuildDir keeps the ISOs built with the /builds API until BuildTTL after their build,
+	// DataTempDir/builds by default
+	BuildDir string        `envconfig:"BUILD_DIR"`
+	BuildTTL time.Duration `envconfig:"BUILD_TTL" default:"1h"`
+	// BuildCallbackHosts are the comma separated hosts the callbacks of the builds may reach on
+	// loopback, private or link-local addresses
+	BuildCallbackHosts []string `envconfig:"BUILD_CALLBACK_ALLOWED_HOSTS"`
+
+	// The images are also kept in this S3 compatible bucket when ObjectStoreEndpoint is set, so
+	// that the replicas share them without a RWX volume. DataDir is then a local cache.
+	ObjectStoreEndpoint        string `envconfig:"OBJECT_STORE_ENDPOINT"`
+	ObjectStoreRegion          string `envconfig:"OBJECT_STORE_REGION" default:"us-east-1"`
+	ObjectStoreBucket          string `envconfig:"OBJECT_STORE_BUCKET"`
+	ObjectStoreAccessKeyID     string `envconfig:"OBJECT_STORE_ACCESS_KEY_ID"`
+	ObjectStoreSecretAccessKey string `envconfig:"OBJECT_STORE_SECRET_ACCESS_KEY"`
+	ObjectStorePrefix          string `envconfig:"OBJECT_STORE_PREFIX"`
+	ObjectStoreVirtualHosted   bool   `envconfig:"OBJECT_STORE_VIRTUAL_HOSTED" default:"false"`
+
+	// ImagePruneInterval enables removing the images of the versions that are no longer
+	// configured and, if ImagePruneTTL is set, of the versions not requested within the TTL
+	ImagePruneInterval time.Duration `envconfig:"IMAGE_PRUNE_INTERVAL" default:"0"`
+	ImagePruneTTL      time.Duration `envconfig:"IMAGE_PRUNE_TTL" default:"0"`
+	ImagePruneDryRun   bool          `envconfig:"IMAGE_PRUNE_DRY_RUN" default:"false"`
+
+	// ImageDiskBudget caps the bytes of the images in DataDir, the least recently served
+	// versions are evicted to stay within it and downloaded again when requested
+	ImageDiskBudget int64 `envconfig:"IMAGE_DISK_BUDGET" default:"0"`
+
+	// OSImagesLazyDownload defers the download of the OS images to the first request of their
+	// version, which is answered with 202 and Retry-After until the images are ready
+	OSImagesLazyDownload bool `envconfig:"OS_IMAGES_LAZY_DOWNLOAD" default:"false"`
+
+	// SharedDataDir tells that the replicas share DataDir, in which case an image is only
+	// downloaded by the replica holding its lease file
+	SharedDataDir bool `envconfig:"SHARED_DATA_DIR" default:"false"`
+
+	// BaseISOUploadToken enables adding custom base ISOs with PUT /base-isos/{version}/{arch},
+	// authenticated with this bearer token over https or on the local listeners
+	BaseISOUploadToken string `envconfig:"BASE_ISO_UPLOAD_TOKEN"`
+
+	// UKIStubPath is the systemd stub the unified kernel images are built with, the default stub
+	// of ukify when empty
+	UKIStubPath string `envconfig:"UKI_STUB_PATH"`
+
+	// NetbootOutputDir enables writing the netboot trees of the images to NetbootOutputDir/<image_id>
+	// with POST /images/{image_id}/netboot, e.g. for a TFTP server sharing the directory
+	NetbootOutputDir string `envconfig:"NETBOOT_OUTPUT_DIR"`
+
+	// ISOCacheDir enables keeping the customized ISOs in this directory, so that identical requests
+	// are served from the disk, the least recently served ones being evicted beyond ISOCacheMaxSize
+	// bytes unless it is 0
+	ISOCacheDir     string `envconfig:"ISO_CACHE_DIR"`
+	ISOCacheMaxSize int64  `envconfig:"ISO_CACHE_MAX_SIZE" default:"0"`
+
+	// DescriptorMirrorURLs are the base URLs of the mirrors of the service listed, after the
+	// service itself, in the metalink and torrent descriptors of the artifacts
+	DescriptorMirrorURLs []string `envconfig:"DESCRIPTOR_MIRROR_URLS"`
+
+	// GRPCListenPort enables the gRPC API on this port, with TLS when HTTPSKeyFile and
+	// HTTPSCertFile are set. Its calls managing the store are authenticated with BaseISOUploadToken,
+	// and disabled without TLS.
+	GRPCListenPort string `envconfig:"GRPC_LISTEN_PORT"`
+
+	// DebugListenPort enables the diagnostics of the service, the pprof profiles, the runtime
+	// statistics and the active streams, on this port only
+	DebugListenPort string `envconfig:"DEBUG_LISTEN_PORT"`
 }
 
 func unmarshallJSONMap(jsonMap string) (map[string]string, error) {
@@ -111,16 +239,38 @@ func main() {
 		log.Fatalf("Failed to unmarshal OSImageDownloadQueryParams: %v\n", err)
 	}
 
-	is, err := imagestore.NewImageStore(
-		isoeditor.NewEditor(Options.DataTempDir, isoeditor.NewNmstateHandler(Options.DataTempDir, &isoeditor.CommonExecuter{})),
-		Options.DataDir,
-		Options.ImageServiceBaseURL,
-		Options.InsecureSkipVerify,
-		versions,
-		Options.OSImageDownloadTrustedCAFile,
-		osImageDownloadHeadersMap,
-		osImageDownloadQueryParamsMap)
+	var objectStore imagestore.ObjectStore
+	if Options.ObjectStoreEndpoint != "" {
+		objectStore, err = objectstore.NewClient(objectstore.Config{
+			Endpoint:        Options.ObjectStoreEndpoint,
+			Region:          Options.ObjectStoreRegion,
+			Bucket:          Options.ObjectStoreBucket,
+			AccessKeyID:     Options.ObjectStoreAccessKeyID,
+			SecretAccessKey: Options.ObjectStoreSecretAccessKey,
+			Prefix:          Options.ObjectStorePrefix,
+			VirtualHosted:   Options.ObjectStoreVirtualHosted,
+		}, nil)
+		if err != nil {
+			log.Fatalf("Failed to create object store client: %v\n", err)
+		}
+	}
 
+	is, err := imagestore.NewImageStore(imagestore.ImageStoreOptions{
+		Editor:                       isoeditor.NewEditor(Options.DataTempDir, isoeditor.NewNmstateHandler(Options.DataTempDir, &isoeditor.CommonExecuter{})),
+		DataDir:                      Options.DataDir,
+		ImageServiceBaseURL:          Options.ImageServiceBaseURL,
+		InsecureSkipVerify:           Options.InsecureSkipVerify,
+		Versions:                     versions,
+		OSImageDownloadTrustedCAFile: Options.OSImageDownloadTrustedCAFile,
+		OSImageDownloadHeaders:       osImageDownloadHeadersMap,
+		OSImageDownloadQueryParams:   osImageDownloadQueryParamsMap,
+		OSImagePullSecretFile:        Options.OSImagesPullSecretFile,
+		OSImageSignatureKeysFile:     Options.OSImagesSignatureKeysFile,
+		ObjectStore:                  objectStore,
+		DiskBudget:                   Options.ImageDiskBudget,
+		Lazy:                         Options.OSImagesLazyDownload,
+		SharedDataDir:                Options.SharedDataDir,
+	})
 	if err != nil {
 		log.Fatalf("Failed to create image store: %v\n", err)
 	}
@@ -133,6 +283,9 @@ func main() {
 			log.Fatalf("Failed to populate image store: %v\n", err)
 		}
 		readinessHandler.Enable()
+		if Options.ImagePruneInterval > 0 {
+			imagestore.StartPruning(context.Background(), is, Options.ImagePruneInterval, Options.ImagePruneTTL, Options.ImagePruneDryRun)
+		}
 	}()
 
 	reg := prometheus.NewRegistry()
@@ -142,6 +295,9 @@ func main() {
 		DurationBuckets: []float64{.1, 1, 10, 50, 100, 300, 600},
 		SizeBuckets:     []float64{100, 1e6, 5e8, 1e9, 1e10},
 	}
+	registerBufferPoolMetrics(reg)
+	registerPruneMetrics(reg)
+	registerDiskMetrics(reg)
 	mdw := middleware.New(middleware.Config{
 		Recorder: metrics.NewRecorder(metricsConfig),
 	})
@@ -151,20 +307,102 @@ func main() {
 		log.Fatalf("Failed to create AssistedServiceClient: %v\n", err)
 	}
 
-	imageHandler := handlers.NewImageHandler(is, asc, Options.MaxConcurrentRequests, mdw)
-	imageHandler = readinessHandler.WithMiddleware(imageHandler)
-	if Options.AllowedDomains != "" {
-		imageHandler = handlers.WithCORSMiddleware(imageHandler, Options.AllowedDomains)
+	var rootfsVerifier *handlers.RootfsURLVerifier
+	if Options.VerifyRootfsURL {
+		rootfsVerifier, err = handlers.NewRootfsURLVerifier(Options.RootfsURLVerifyTimeout, Options.RootfsURLVerifyProxy)
+		if err != nil {
+			log.Fatalf("Failed to create RootfsURLVerifier: %v\n", err)
+		}
 	}
 
-	var bootArtifactsHandler http.Handler = &handlers.BootArtifactsHandler{ImageStore: is}
-	bootArtifactsHandler = readinessHandler.WithMiddleware(bootArtifactsHandler)
-	if Options.AllowedDomains != "" {
-		bootArtifactsHandler = handlers.WithCORSMiddleware(bootArtifactsHandler, Options.AllowedDomains)
+	isoMD5, err := isoeditor.ParseISOMD5Mode(Options.ISOMD5)
+	if err != nil {
+		log.Fatalf("Failed to parse ISO_MD5: %v\n", err)
+	}
+	kargsPolicy, err := isoeditor.ParseKargsConflictPolicy(Options.KargsConflictPolicy)
+	if err != nil {
+		log.Fatalf("Failed to parse KARGS_CONFLICT_POLICY: %v\n", err)
+	}
+	var isoCache *handlers.ISOCache
+	if Options.ISOCacheDir != "" {
+		isoCache, err = handlers.NewISOCache(Options.ISOCacheDir, Options.ISOCacheMaxSize)
+		if err != nil {
+			log.Fatalf("Failed to create the ISO cache: %v\n", err)
+		}
+	}
+	if Options.BuildDir == "" {
+		Options.BuildDir = filepath.Join(Options.DataTempDir, "builds")
+	}
+	buildHandler, err := handlers.NewBuildHandler(is, asc, isoeditor.NewNmstateHandler(Options.DataTempDir, &isoeditor.CommonExecuter{}), isoMD5, kargsPolicy, Options.BuildDir, Options.BuildTTL, Options.BuildCallbackHosts)
+	if err != nil {
+		log.Fatalf("failed to create the build handler: %v", err)
+	}
+	go buildHandler.StartExpiring(context.Background(), time.Minute)
+	trustedProxies, err := handlers.ParseTrustedProxies(Options.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Failed to parse TRUSTED_PROXIES: %v\n", err)
 	}
+	imageHandler := handlers.NewImageHandler(handlers.ImageHandlerOptions{
+		ImageStore:            is,
+		AssistedServiceClient: asc,
+		MaxRequests:           Options.MaxConcurrentRequests,
+		Metrics:               mdw,
+		RootfsVerifier:        rootfsVerifier,
+		ISOMD5:                isoMD5,
+		KargsConflictPolicy:   kargsPolicy,
+		Limits: handlers.StreamLimits{
+			MaxStreams:                    Options.MaxISOStreams,
+			MaxStreamsPerClient:           Options.MaxISOStreamsPerClient,
+			MaxRequestsPerMinutePerClient: Options.MaxRequestsPerMinutePerClient,
+			MaxMemoryPerStream:            Options.MaxMemoryPerStream,
+			TrustedProxies:                trustedProxies,
+		},
+		DiskEditor:       diskeditor.NewEditor(Options.DataTempDir, &isoeditor.CommonExecuter{}),
+		UKIBuilder:       uki.NewBuilder(Options.DataTempDir, Options.UKIStubPath, &isoeditor.CommonExecuter{}),
+		WorkDir:          Options.DataTempDir,
+		NetbootOutputDir: Options.NetbootOutputDir,
+		ISOCache:         isoCache,
+		Builds:           buildHandler,
+	})
+	withCORS := func(handler http.Handler) http.Handler {
+		if Options.AllowedDomains == "" {
+			return handler
+		}
+		return handlers.WithCORSMiddleware(handler, handlers.CORSOptions{
+			AllowedOrigins:   handlers.ParseCORSOrigins(Options.AllowedDomains),
+			AllowedMethods:   Options.CORSAllowedMethods,
+			AllowedHeaders:   Options.CORSAllowedHeaders,
+			ExposedHeaders:   Options.CORSExposedHeaders,
+			AllowCredentials: Options.CORSAllowCredentials,
+			MaxAge:           Options.CORSMaxAge,
+		})
+	}
+	// the image and boot artifact downloads share the global bandwidth
+	withBandwidthLimit := handlers.WithBandwidthLimit(Options.MaxBytesPerSecond, Options.MaxBytesPerSecondPerStream)
+	// the artifacts are hashed for their descriptors without being throttled
+	withDescriptors := handlers.WithDescriptors(Options.DescriptorMirrorURLs)
+	// the scripts, parameter files, descriptors and build callbacks refer to the service by this URL
+	withBaseURL := handlers.WithBaseURL(Options.ImageServiceBaseURL, trustedProxies)
+	imageHandler = withBaseURL(handlers.WithStreamTracking(withBandwidthLimit(readinessHandler.WithMiddleware(withDescriptors(imageHandler)))))
+	grpcImageHandler := imageHandler
+	imageHandler = withCORS(imageHandler)
+
+	var bootArtifactsHandler http.Handler = &handlers.BootArtifactsHandler{ImageStore: is}
+	bootArtifactsHandler = withBaseURL(handlers.WithStreamTracking(withBandwidthLimit(readinessHandler.WithMiddleware(withDescriptors(bootArtifactsHandler)))))
+	grpcBootArtifactsHandler := bootArtifactsHandler
+	bootArtifactsHandler = withCORS(bootArtifactsHandler)
 
-	http.Handle("/boot-artifacts/", stdmiddleware.Handler("", mdw, bootArtifactsHandler))
+	http.Handle("/boot-artifacts/", handlers.MeasuredHandler("", mdw, bootArtifactsHandler))
+	if Options.BaseISOUploadToken != "" {
+		baseISOHandler := readinessHandler.WithMiddleware(&handlers.BaseISOHandler{ImageStore: is, Token: Options.BaseISOUploadToken})
+		http.Handle("/base-isos/", handlers.MeasuredHandler("", mdw, baseISOHandler))
+	}
 
+	openAPIHandler, err := handlers.NewOpenAPIHandler()
+	if err != nil {
+		log.Fatalf("failed to create the OpenAPI handler: %v", err)
+	}
+	http.Handle("/openapi.json", withCORS(openAPIHandler))
 	http.Handle("/health", readinessHandler)
 	http.Handle("/live", handlers.NewLivenessHandler())
 	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
@@ -175,9 +413,25 @@ func main() {
 
 	// Run listen on http and https ports if HTTPSCertFile/HTTPSKeyFile set
 	serverInfo := servers.New(Options.HTTPListenPort, Options.ListenPort, Options.HTTPSKeyFile, Options.HTTPSCertFile)
+	if Options.UnixSocket != "" {
+		mode, err := strconv.ParseUint(Options.UnixSocketMode, 8, 32)
+		if err != nil {
+			log.Fatalf("Failed to parse UNIX_SOCKET_MODE: %v\n", err)
+		}
+		listener, err := servers.UnixListener(Options.UnixSocket, os.FileMode(mode))
+		if err != nil {
+			log.Fatalf("Failed to listen on the Unix socket: %v\n", err)
+		}
+		serverInfo.AddListeners(listener)
+	}
+	activatedListeners, err := servers.ActivatedListeners()
+	if err != nil {
+		log.Fatalf("Failed to use the sockets passed by systemd: %v\n", err)
+	}
+	serverInfo.AddListeners(activatedListeners...)
 	if serverInfo.HasBothHandlers {
 		// Make sure we filter requests when both http+https ports are open
-		// Allow only pxe-initrd via HTTP in imageHandler
+		// Allow only pxe-initrd and pxe-script via HTTP in imageHandler
 		imageHandler = handlers.WithInitrdViaHTTP(imageHandler)
 	}
 	http.Handle("/images/", imageHandler)
@@ -185,8 +439,148 @@ func main() {
 	http.Handle("/byid/", imageHandler)
 	http.Handle("/bytoken/", imageHandler)
 	http.Handle("/s390x-initrd-addrsize", imageHandler)
+	http.Handle("/builds", imageHandler)
+	http.Handle("/builds/", imageHandler)
+	http.Handle("/v2/images/", imageHandler)
 
+	var clientTLSConfig *tls.Config
+	if Options.HTTPSClientCAFile != "" {
+		if serverInfo.HTTPS == nil {
+			log.Fatal("HTTPS_CLIENT_CA_FILE requires HTTPS_KEY_FILE and HTTPS_CERT_FILE")
+		}
+		clientTLSConfig, err = servers.ClientCertTLSConfig(Options.HTTPSClientCAFile, strings.Split(Options.HTTPSClientAllowedSANs, ","))
+		if err != nil {
+			log.Fatalf("Failed to configure client certificate authentication: %v\n", err)
+		}
+		serverInfo.HTTPS.TLSConfig = clientTLSConfig
+	}
 	serverInfo.ListenAndServe()
+
+	var grpcServer *grpc.Server
+	if Options.GRPCListenPort != "" {
+		var opts []grpc.ServerOption
+		// the token of the calls managing the store isn't sent in clear text
+		grpcToken := Options.BaseISOUploadToken
+		if Options.HTTPSKeyFile != "" && Options.HTTPSCertFile != "" {
+			cert, err := tls.LoadX509KeyPair(Options.HTTPSCertFile, Options.HTTPSKeyFile)
+			if err != nil {
+				log.Fatalf("Failed to load the gRPC TLS credentials: %v\n", err)
+			}
+			tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+			if clientTLSConfig != nil {
+				tlsConfig = clientTLSConfig.Clone()
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		} else if grpcToken != "" {
+			log.Warnf("The gRPC API is served without TLS, its calls managing the store are disabled")
+			grpcToken = ""
+		}
+		grpcServer = grpc.NewServer(opts...)
+		imageservicev1.RegisterImageServiceServer(grpcServer,
+			grpcserver.NewServer(is, grpcImageHandler, grpcBootArtifactsHandler, grpcToken))
+		listener, err := net.Listen("tcp", ":"+Options.GRPCListenPort)
+		if err != nil {
+			log.Fatalf("Failed to listen on the gRPC port: %v\n", err)
+		}
+		go func() {
+			log.Infof("Starting gRPC handler on %s...", listener.Addr())
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatalf("gRPC listener closed: %v", err)
+			}
+		}()
+	}
+
+	var debugServer *http.Server
+	if Options.DebugListenPort != "" {
+		debugServer = &http.Server{
+			Addr:              ":" + Options.DebugListenPort,
+			Handler:           handlers.NewDiagnosticsHandler(),
+			ReadHeaderTimeout: 3 * time.Second,
+		}
+		go func() {
+			log.Infof("Starting diagnostics handler on %s...", debugServer.Addr)
+			if err := debugServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Diagnostics listener closed: %v", err)
+			}
+		}()
+	}
+
 	<-stop
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+	if debugServer != nil {
+		_ = debugServer.Close()
+	}
 	serverInfo.Shutdown()
 }
+
+func registerBufferPoolMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "assisted_image_service_buffer_pool_gets_total",
+			Help: "Number of copy buffers taken from the pool",
+		}, func() float64 { return float64(overlay.GetBufferPoolStats().Gets) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "assisted_image_service_buffer_pool_allocations_total",
+			Help: "Number of copy buffers allocated because the pool was empty",
+		}, func() float64 { return float64(overlay.GetBufferPoolStats().Allocations) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "assisted_image_service_buffer_pool_in_use",
+			Help: "Number of copy buffers currently in use",
+		}, func() float64 { return float64(overlay.GetBufferPoolStats().InUse) }),
+	)
+}
+
+func registerPruneMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "assisted_image_service_pruned_files_total",
+			Help: "Number of files removed from the data directory",
+		}, func() float64 { return float64(imagestore.GetPruneStats().RemovedFiles) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "assisted_image_service_pruned_bytes_total",
+			Help: "Number of bytes freed in the data directory",
+		}, func() float64 { return float64(imagestore.GetPruneStats().RemovedBytes) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "assisted_image_service_prunable_files",
+			Help: "Number of files the last prune removed, or would have removed in dry-run mode",
+		}, func() float64 { return float64(imagestore.GetPruneStats().PrunableFiles) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "assisted_image_service_prunable_bytes",
+			Help: "Number of bytes the last prune freed, or would have freed in dry-run mode",
+		}, func() float64 { return float64(imagestore.GetPruneStats().PrunableBytes) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "assisted_image_service_pruned_versions",
+			Help: "Number of versions pruned for not being requested within the TTL",
+		}, func() float64 { return float64(imagestore.GetPruneStats().PrunedVersions) }),
+	)
+}
+
+func registerDiskMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "assisted_image_service_disk_budget_bytes",
+			Help: "Disk budget of the images, 0 when unlimited",
+		}, func() float64 { return float64(imagestore.GetDiskStats().Budget) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "assisted_image_service_disk_budget_used_bytes",
+			Help: "Bytes of the images in the data directory",
+		}, func() float64 { return float64(imagestore.GetDiskStats().Used) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "assisted_image_service_disk_budget_free_bytes",
+			Help: "Bytes left in the disk budget of the images",
+		}, func() float64 {
+			stats := imagestore.GetDiskStats()
+			if stats.Budget <= 0 || stats.Used > stats.Budget {
+				return 0
+			}
+			return float64(stats.Budget - stats.Used)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "assisted_image_service_disk_budget_evictions_total",
+			Help: "Number of versions evicted to stay within the disk budget",
+		}, func() float64 { return float64(imagestore.GetDiskStats().Evictions) }),
+	)
+}
@@ -0,0 +1,4 @@
+// Package imageservicev1 is the gRPC API of the image service
+package imageservicev1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative image_service.proto
@@ -0,0 +1,392 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: image_service.proto
+
+package imageservicev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	ImageService_GetImage_FullMethodName        = "/assisted.imageservice.v1.ImageService/GetImage"
+	ImageService_GetInitrd_FullMethodName       = "/assisted.imageservice.v1.ImageService/GetInitrd"
+	ImageService_GetBootArtifact_FullMethodName = "/assisted.imageservice.v1.ImageService/GetBootArtifact"
+	ImageService_GetVersion_FullMethodName      = "/assisted.imageservice.v1.ImageService/GetVersion"
+	ImageService_AddBaseISO_FullMethodName      = "/assisted.imageservice.v1.ImageService/AddBaseISO"
+	ImageService_Prune_FullMethodName           = "/assisted.imageservice.v1.ImageService/Prune"
+)
+
+// ImageServiceClient is the client API for ImageService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ImageServiceClient interface {
+	// GetImage streams the discovery ISO of an infra-env, like GET /images/{image_id}
+	GetImage(ctx context.Context, in *GetImageRequest, opts ...grpc.CallOption) (ImageService_GetImageClient, error)
+	// GetInitrd streams the initrd of an infra-env, like GET /images/{image_id}/pxe-initrd
+	GetInitrd(ctx context.Context, in *GetInitrdRequest, opts ...grpc.CallOption) (ImageService_GetInitrdClient, error)
+	// GetBootArtifact streams an artifact of a base image, like GET /boot-artifacts/{artifact}
+	GetBootArtifact(ctx context.Context, in *GetBootArtifactRequest, opts ...grpc.CallOption) (ImageService_GetBootArtifactClient, error)
+	// GetVersion tells whether a base image is configured and available
+	GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*GetVersionResponse, error)
+	// AddBaseISO registers a custom base ISO downloaded from a URL, like PUT /base-isos/{version}/{arch}.
+	// It requires the upload token as a bearer token in the authorization metadata.
+	AddBaseISO(ctx context.Context, in *AddBaseISORequest, opts ...grpc.CallOption) (*AddBaseISOResponse, error)
+	// Prune removes the base images unused for longer than the TTL. It requires the upload token
+	// as a bearer token in the authorization metadata.
+	Prune(ctx context.Context, in *PruneRequest, opts ...grpc.CallOption) (*PruneResponse, error)
+}
+
+type imageServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewImageServiceClient(cc grpc.ClientConnInterface) ImageServiceClient {
+	return &imageServiceClient{cc}
+}
+
+func (c *imageServiceClient) GetImage(ctx context.Context, in *GetImageRequest, opts ...grpc.CallOption) (ImageService_GetImageClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ImageService_ServiceDesc.Streams[0], ImageService_GetImage_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &imageServiceGetImageClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ImageService_GetImageClient interface {
+	Recv() (*Chunk, error)
+	grpc.ClientStream
+}
+
+type imageServiceGetImageClient struct {
+	grpc.ClientStream
+}
+
+func (x *imageServiceGetImageClient) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *imageServiceClient) GetInitrd(ctx context.Context, in *GetInitrdRequest, opts ...grpc.CallOption) (ImageService_GetInitrdClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ImageService_ServiceDesc.Streams[1], ImageService_GetInitrd_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &imageServiceGetInitrdClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ImageService_GetInitrdClient interface {
+	Recv() (*Chunk, error)
+	grpc.ClientStream
+}
+
+type imageServiceGetInitrdClient struct {
+	grpc.ClientStream
+}
+
+func (x *imageServiceGetInitrdClient) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *imageServiceClient) GetBootArtifact(ctx context.Context, in *GetBootArtifactRequest, opts ...grpc.CallOption) (ImageService_GetBootArtifactClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ImageService_ServiceDesc.Streams[2], ImageService_GetBootArtifact_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &imageServiceGetBootArtifactClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ImageService_GetBootArtifactClient interface {
+	Recv() (*Chunk, error)
+	grpc.ClientStream
+}
+
+type imageServiceGetBootArtifactClient struct {
+	grpc.ClientStream
+}
+
+func (x *imageServiceGetBootArtifactClient) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *imageServiceClient) GetVersion(ctx context.Context, in *GetVersionRequest, opts ...grpc.CallOption) (*GetVersionResponse, error) {
+	out := new(GetVersionResponse)
+	err := c.cc.Invoke(ctx, ImageService_GetVersion_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageServiceClient) AddBaseISO(ctx context.Context, in *AddBaseISORequest, opts ...grpc.CallOption) (*AddBaseISOResponse, error) {
+	out := new(AddBaseISOResponse)
+	err := c.cc.Invoke(ctx, ImageService_AddBaseISO_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageServiceClient) Prune(ctx context.Context, in *PruneRequest, opts ...grpc.CallOption) (*PruneResponse, error) {
+	out := new(PruneResponse)
+	err := c.cc.Invoke(ctx, ImageService_Prune_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ImageServiceServer is the server API for ImageService service.
+// All implementations must embed UnimplementedImageServiceServer
+// for forward compatibility
+type ImageServiceServer interface {
+	// GetImage streams the discovery ISO of an infra-env, like GET /images/{image_id}
+	GetImage(*GetImageRequest, ImageService_GetImageServer) error
+	// GetInitrd streams the initrd of an infra-env, like GET /images/{image_id}/pxe-initrd
+	GetInitrd(*GetInitrdRequest, ImageService_GetInitrdServer) error
+	// GetBootArtifact streams an artifact of a base image, like GET /boot-artifacts/{artifact}
+	GetBootArtifact(*GetBootArtifactRequest, ImageService_GetBootArtifactServer) error
+	// GetVersion tells whether a base image is configured and available
+	GetVersion(context.Context, *GetVersionRequest) (*GetVersionResponse, error)
+	// AddBaseISO registers a custom base ISO downloaded from a URL, like PUT /base-isos/{version}/{arch}.
+	// It requires the upload token as a bearer token in the authorization metadata.
+	AddBaseISO(context.Context, *AddBaseISORequest) (*AddBaseISOResponse, error)
+	// Prune removes the base images unused for longer than the TTL. It requires the upload token
+	// as a bearer token in the authorization metadata.
+	Prune(context.Context, *PruneRequest) (*PruneResponse, error)
+	mustEmbedUnimplementedImageServiceServer()
+}
+
+// UnimplementedImageServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedImageServiceServer struct {
+}
+
+func (UnimplementedImageServiceServer) GetImage(*GetImageRequest, ImageService_GetImageServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetImage not implemented")
+}
+func (UnimplementedImageServiceServer) GetInitrd(*GetInitrdRequest, ImageService_GetInitrdServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetInitrd not implemented")
+}
+func (UnimplementedImageServiceServer) GetBootArtifact(*GetBootArtifactRequest, ImageService_GetBootArtifactServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetBootArtifact not implemented")
+}
+func (UnimplementedImageServiceServer) GetVersion(context.Context, *GetVersionRequest) (*GetVersionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVersion not implemented")
+}
+func (UnimplementedImageServiceServer) AddBaseISO(context.Context, *AddBaseISORequest) (*AddBaseISOResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddBaseISO not implemented")
+}
+func (UnimplementedImageServiceServer) Prune(context.Context, *PruneRequest) (*PruneResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Prune not implemented")
+}
+func (UnimplementedImageServiceServer) mustEmbedUnimplementedImageServiceServer() {}
+
+// UnsafeImageServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ImageServiceServer will
+// result in compilation errors.
+type UnsafeImageServiceServer interface {
+	mustEmbedUnimplementedImageServiceServer()
+}
+
+func RegisterImageServiceServer(s grpc.ServiceRegistrar, srv ImageServiceServer) {
+	s.RegisterService(&ImageService_ServiceDesc, srv)
+}
+
+func _ImageService_GetImage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetImageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImageServiceServer).GetImage(m, &imageServiceGetImageServer{stream})
+}
+
+type ImageService_GetImageServer interface {
+	Send(*Chunk) error
+	grpc.ServerStream
+}
+
+type imageServiceGetImageServer struct {
+	grpc.ServerStream
+}
+
+func (x *imageServiceGetImageServer) Send(m *Chunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ImageService_GetInitrd_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetInitrdRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImageServiceServer).GetInitrd(m, &imageServiceGetInitrdServer{stream})
+}
+
+type ImageService_GetInitrdServer interface {
+	Send(*Chunk) error
+	grpc.ServerStream
+}
+
+type imageServiceGetInitrdServer struct {
+	grpc.ServerStream
+}
+
+func (x *imageServiceGetInitrdServer) Send(m *Chunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ImageService_GetBootArtifact_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetBootArtifactRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImageServiceServer).GetBootArtifact(m, &imageServiceGetBootArtifactServer{stream})
+}
+
+type ImageService_GetBootArtifactServer interface {
+	Send(*Chunk) error
+	grpc.ServerStream
+}
+
+type imageServiceGetBootArtifactServer struct {
+	grpc.ServerStream
+}
+
+func (x *imageServiceGetBootArtifactServer) Send(m *Chunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ImageService_GetVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServiceServer).GetVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ImageService_GetVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServiceServer).GetVersion(ctx, req.(*GetVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageService_AddBaseISO_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddBaseISORequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServiceServer).AddBaseISO(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ImageService_AddBaseISO_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServiceServer).AddBaseISO(ctx, req.(*AddBaseISORequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageService_Prune_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PruneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageServiceServer).Prune(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ImageService_Prune_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageServiceServer).Prune(ctx, req.(*PruneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ImageService_ServiceDesc is the grpc.ServiceDesc for ImageService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ImageService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "assisted.imageservice.v1.ImageService",
+	HandlerType: (*ImageServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetVersion",
+			Handler:    _ImageService_GetVersion_Handler,
+		},
+		{
+			MethodName: "AddBaseISO",
+			Handler:    _ImageService_AddBaseISO_Handler,
+		},
+		{
+			MethodName: "Prune",
+			Handler:    _ImageService_Prune_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetImage",
+			Handler:       _ImageService_GetImage_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetInitrd",
+			Handler:       _ImageService_GetInitrd_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetBootArtifact",
+			Handler:       _ImageService_GetBootArtifact_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "image_service.proto",
+}
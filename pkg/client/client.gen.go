@@ -0,0 +1,4910 @@
+// Package client provides primitives to interact with the openapi HTTP API.
+//
+// Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.3.0 DO NOT EDIT.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/oapi-codegen/runtime"
+)
+
+// Defines values for BuildStatus.
+const (
+	Failed    BuildStatus = "failed"
+	Pending   BuildStatus = "pending"
+	Running   BuildStatus = "running"
+	Succeeded BuildStatus = "succeeded"
+)
+
+// Defines values for BuildRequestType.
+const (
+	BuildRequestTypeFullIso    BuildRequestType = "full-iso"
+	BuildRequestTypeMinimalIso BuildRequestType = "minimal-iso"
+)
+
+// Defines values for CustomizationSpecType.
+const (
+	CustomizationSpecTypeFullIso    CustomizationSpecType = "full-iso"
+	CustomizationSpecTypeMinimalIso CustomizationSpecType = "minimal-iso"
+)
+
+// Defines values for DownloadBootArtifactParamsCompress.
+const (
+	DownloadBootArtifactParamsCompressZstd DownloadBootArtifactParamsCompress = "zstd"
+)
+
+// Defines values for DownloadBootArtifactParamsDescriptor.
+const (
+	DownloadBootArtifactParamsDescriptorChunks   DownloadBootArtifactParamsDescriptor = "chunks"
+	DownloadBootArtifactParamsDescriptorMetalink DownloadBootArtifactParamsDescriptor = "metalink"
+	DownloadBootArtifactParamsDescriptorTorrent  DownloadBootArtifactParamsDescriptor = "torrent"
+)
+
+// Defines values for DownloadBootArtifactParamsArtifact.
+const (
+	InsFile DownloadBootArtifactParamsArtifact = "ins-file"
+	Kernel  DownloadBootArtifactParamsArtifact = "kernel"
+	Rootfs  DownloadBootArtifactParamsArtifact = "rootfs"
+)
+
+// Defines values for DownloadImageByAPIKeyParamsDescriptor.
+const (
+	DownloadImageByAPIKeyParamsDescriptorChunks   DownloadImageByAPIKeyParamsDescriptor = "chunks"
+	DownloadImageByAPIKeyParamsDescriptorMetalink DownloadImageByAPIKeyParamsDescriptor = "metalink"
+	DownloadImageByAPIKeyParamsDescriptorTorrent  DownloadImageByAPIKeyParamsDescriptor = "torrent"
+)
+
+// Defines values for DownloadImageByAPIKeyParamsFilename.
+const (
+	DownloadImageByAPIKeyParamsFilenameFullIso    DownloadImageByAPIKeyParamsFilename = "full.iso"
+	DownloadImageByAPIKeyParamsFilenameMinimalIso DownloadImageByAPIKeyParamsFilename = "minimal.iso"
+)
+
+// Defines values for DownloadImageByIDParamsDescriptor.
+const (
+	DownloadImageByIDParamsDescriptorChunks   DownloadImageByIDParamsDescriptor = "chunks"
+	DownloadImageByIDParamsDescriptorMetalink DownloadImageByIDParamsDescriptor = "metalink"
+	DownloadImageByIDParamsDescriptorTorrent  DownloadImageByIDParamsDescriptor = "torrent"
+)
+
+// Defines values for DownloadImageByIDParamsFilename.
+const (
+	DownloadImageByIDParamsFilenameFullIso    DownloadImageByIDParamsFilename = "full.iso"
+	DownloadImageByIDParamsFilenameMinimalIso DownloadImageByIDParamsFilename = "minimal.iso"
+)
+
+// Defines values for DownloadImageByTokenParamsDescriptor.
+const (
+	DownloadImageByTokenParamsDescriptorChunks   DownloadImageByTokenParamsDescriptor = "chunks"
+	DownloadImageByTokenParamsDescriptorMetalink DownloadImageByTokenParamsDescriptor = "metalink"
+	DownloadImageByTokenParamsDescriptorTorrent  DownloadImageByTokenParamsDescriptor = "torrent"
+)
+
+// Defines values for DownloadImageByTokenParamsFilename.
+const (
+	DownloadImageByTokenParamsFilenameFullIso    DownloadImageByTokenParamsFilename = "full.iso"
+	DownloadImageByTokenParamsFilenameMinimalIso DownloadImageByTokenParamsFilename = "minimal.iso"
+)
+
+// Defines values for DownloadImageParamsType.
+const (
+	DownloadImageParamsTypeFullIso    DownloadImageParamsType = "full-iso"
+	DownloadImageParamsTypeMinimalIso DownloadImageParamsType = "minimal-iso"
+)
+
+// Defines values for DownloadImageParamsDescriptor.
+const (
+	Chunks   DownloadImageParamsDescriptor = "chunks"
+	Metalink DownloadImageParamsDescriptor = "metalink"
+	Torrent  DownloadImageParamsDescriptor = "torrent"
+)
+
+// Defines values for DownloadNetbootTreeParamsCompress.
+const (
+	DownloadNetbootTreeParamsCompressZstd DownloadNetbootTreeParamsCompress = "zstd"
+)
+
+// Defines values for DownloadPXEBundleParamsCompress.
+const (
+	DownloadPXEBundleParamsCompressZstd DownloadPXEBundleParamsCompress = "zstd"
+)
+
+// Defines values for DownloadInitrdParamsCompress.
+const (
+	DownloadInitrdParamsCompressZstd DownloadInitrdParamsCompress = "zstd"
+)
+
+// Defines values for DownloadRawImageParamsCompression.
+const (
+	Xz DownloadRawImageParamsCompression = "xz"
+)
+
+// Defines values for DownloadS390xBootFilesParamsCompress.
+const (
+	Zstd DownloadS390xBootFilesParamsCompress = "zstd"
+)
+
+// BaseISORequest defines model for BaseISORequest.
+type BaseISORequest struct {
+	// Url URL the base ISO is downloaded from
+	Url string `json:"url"`
+}
+
+// BaseISOResponse defines model for BaseISOResponse.
+type BaseISOResponse struct {
+	CpuArchitecture  *string `json:"cpu_architecture,omitempty"`
+	OpenshiftVersion *string `json:"openshift_version,omitempty"`
+
+	// Version Version of the base ISO, derived from its checksum
+	Version *string `json:"version,omitempty"`
+}
+
+// Build defines model for Build.
+type Build struct {
+	CreatedAt time.Time `json:"created_at"`
+
+	// Digest sha-256 of the ISO of a successful build
+	Digest *string `json:"digest,omitempty"`
+
+	// DownloadUrl URL of the ISO of a successful build
+	DownloadUrl *string `json:"download_url,omitempty"`
+
+	// Error Why the build failed
+	Error      *string     `json:"error,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Id         string      `json:"id"`
+	Status     BuildStatus `json:"status"`
+}
+
+// BuildStatus defines model for Build.Status.
+type BuildStatus string
+
+// BuildRequest defines model for BuildRequest.
+type BuildRequest struct {
+	// Arch CPU architecture of the base image, x86_64 by default
+	Arch *string `json:"arch,omitempty"`
+
+	// CallbackUrl URL the build is POSTed to once it's finished
+	CallbackUrl *string `json:"callback_url,omitempty"`
+
+	// ImageId ID of the image, usually the infra-env ID
+	ImageId string `json:"image_id"`
+
+	// Type Type of the ISO
+	Type BuildRequestType `json:"type"`
+
+	// Version OpenShift version of the base image
+	Version string `json:"version"`
+}
+
+// BuildRequestType Type of the ISO
+type BuildRequestType string
+
+// ChunkManifest defines model for ChunkManifest.
+type ChunkManifest struct {
+	ChunkSize *int64 `json:"chunk_size,omitempty"`
+
+	// Chunks Chunks of the artifact
+	Chunks *[]struct {
+		Length *int64 `json:"length,omitempty"`
+		Offset *int64 `json:"offset,omitempty"`
+
+		// Sha256 Hex SHA-256 of the chunk
+		Sha256 *string `json:"sha256,omitempty"`
+	} `json:"chunks,omitempty"`
+
+	// Etag ETag of the artifact, for the If-Range of the chunk requests
+	Etag *string `json:"etag,omitempty"`
+
+	// Name File name of the artifact
+	Name *string `json:"name,omitempty"`
+
+	// Sha256 Hex SHA-256 of the artifact
+	Sha256 *string `json:"sha256,omitempty"`
+	Size   *int64  `json:"size,omitempty"`
+
+	// Urls URLs of the artifact, in order of preference
+	Urls *[]string `json:"urls,omitempty"`
+}
+
+// CustomizationSpec defines model for CustomizationSpec.
+type CustomizationSpec struct {
+	// Arch CPU architecture of the base image, x86_64 by default
+	Arch *string `json:"arch,omitempty"`
+
+	// Async Builds the ISO as a job instead of streaming it
+	Async *bool `json:"async,omitempty"`
+
+	// CaBundle PEM encoded certificates trusted by ignition and the live environment
+	CaBundle *string `json:"ca_bundle,omitempty"`
+
+	// CallbackUrl URL the build of an async spec is POSTed to once it's finished
+	CallbackUrl *string `json:"callback_url,omitempty"`
+
+	// Hosts Per-host identities, minimal ISOs only
+	Hosts *[]Host `json:"hosts,omitempty"`
+
+	// Ignition Ignition or Butane config merged into the one of the image
+	Ignition *string `json:"ignition,omitempty"`
+
+	// KernelArguments Kernel arguments appended to the ones of the image
+	KernelArguments *[]string `json:"kernel_arguments,omitempty"`
+
+	// StaticNetwork Static network configuration of the hosts, minimal ISOs only
+	StaticNetwork *[]StaticNetwork `json:"static_network,omitempty"`
+
+	// SystemdUnits Units added to the live initramfs, minimal ISOs only
+	SystemdUnits *[]SystemdUnit `json:"systemd_units,omitempty"`
+
+	// Type Type of the ISO
+	Type CustomizationSpecType `json:"type"`
+
+	// Version OpenShift version of the base image
+	Version string `json:"version"`
+}
+
+// CustomizationSpecType Type of the ISO
+type CustomizationSpecType string
+
+// Host defines model for Host.
+type Host struct {
+	Hostname        *string   `json:"hostname,omitempty"`
+	KernelArguments *[]string `json:"kernel_arguments,omitempty"`
+
+	// Keyfiles NetworkManager keyfiles by file name
+	Keyfiles     *map[string]string `json:"keyfiles,omitempty"`
+	MacAddresses []string           `json:"mac_addresses"`
+}
+
+// NoCloudSeed defines model for NoCloudSeed.
+type NoCloudSeed struct {
+	// MetaData Content of the meta-data file, an instance-id derived from the seed by default
+	MetaData *string `json:"meta_data,omitempty"`
+
+	// NetworkConfig Content of the network-config file, none by default
+	NetworkConfig *string `json:"network_config,omitempty"`
+
+	// UserData Content of the user-data file
+	UserData string `json:"user_data"`
+}
+
+// StaticNetwork defines model for StaticNetwork.
+type StaticNetwork struct {
+	// Keyfiles NetworkManager keyfiles by file name, used without network_yaml
+	Keyfiles *map[string]string `json:"keyfiles,omitempty"`
+
+	// MacInterfaceMap Interface names of the config by MAC address
+	MacInterfaceMap map[string]string `json:"mac_interface_map"`
+
+	// NetworkYaml nmstate YAML of the host, converted to keyfiles
+	NetworkYaml *string `json:"network_yaml,omitempty"`
+}
+
+// SystemdUnit defines model for SystemdUnit.
+type SystemdUnit struct {
+	Contents string `json:"contents"`
+
+	// Enabled Links the unit from the targets of its [Install] section
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Name Name of the unit, e.g. firmware-check.service
+	Name string `json:"name"`
+}
+
+// DownloadBootArtifactParams defines parameters for DownloadBootArtifact.
+type DownloadBootArtifactParams struct {
+	// Version OpenShift version of the base image
+	Version string `form:"version" json:"version"`
+
+	// Arch CPU architecture of the base image, x86_64 by default
+	Arch *string `form:"arch,omitempty" json:"arch,omitempty"`
+
+	// Compress Streams the artifact compressed, with this Content-Encoding and without Range support
+	Compress *DownloadBootArtifactParamsCompress `form:"compress,omitempty" json:"compress,omitempty"`
+
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+
+	// Descriptor Serves the descriptor of the artifact, listing its mirrors and hashes, instead of the artifact
+	Descriptor *DownloadBootArtifactParamsDescriptor `form:"descriptor,omitempty" json:"descriptor,omitempty"`
+}
+
+// DownloadBootArtifactParamsCompress defines parameters for DownloadBootArtifact.
+type DownloadBootArtifactParamsCompress string
+
+// DownloadBootArtifactParamsDescriptor defines parameters for DownloadBootArtifact.
+type DownloadBootArtifactParamsDescriptor string
+
+// DownloadBootArtifactParamsArtifact defines parameters for DownloadBootArtifact.
+type DownloadBootArtifactParamsArtifact string
+
+// CreateBuildParams defines parameters for CreateBuild.
+type CreateBuildParams struct {
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// DownloadImageByAPIKeyParams defines parameters for DownloadImageByAPIKey.
+type DownloadImageByAPIKeyParams struct {
+	// Descriptor Serves the descriptor of the artifact, listing its mirrors and hashes, instead of the artifact
+	Descriptor *DownloadImageByAPIKeyParamsDescriptor `form:"descriptor,omitempty" json:"descriptor,omitempty"`
+}
+
+// DownloadImageByAPIKeyParamsDescriptor defines parameters for DownloadImageByAPIKey.
+type DownloadImageByAPIKeyParamsDescriptor string
+
+// DownloadImageByAPIKeyParamsFilename defines parameters for DownloadImageByAPIKey.
+type DownloadImageByAPIKeyParamsFilename string
+
+// DownloadImageByIDParams defines parameters for DownloadImageByID.
+type DownloadImageByIDParams struct {
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+
+	// Descriptor Serves the descriptor of the artifact, listing its mirrors and hashes, instead of the artifact
+	Descriptor *DownloadImageByIDParamsDescriptor `form:"descriptor,omitempty" json:"descriptor,omitempty"`
+}
+
+// DownloadImageByIDParamsDescriptor defines parameters for DownloadImageByID.
+type DownloadImageByIDParamsDescriptor string
+
+// DownloadImageByIDParamsFilename defines parameters for DownloadImageByID.
+type DownloadImageByIDParamsFilename string
+
+// DownloadImageByTokenParams defines parameters for DownloadImageByToken.
+type DownloadImageByTokenParams struct {
+	// Descriptor Serves the descriptor of the artifact, listing its mirrors and hashes, instead of the artifact
+	Descriptor *DownloadImageByTokenParamsDescriptor `form:"descriptor,omitempty" json:"descriptor,omitempty"`
+}
+
+// DownloadImageByTokenParamsDescriptor defines parameters for DownloadImageByToken.
+type DownloadImageByTokenParamsDescriptor string
+
+// DownloadImageByTokenParamsFilename defines parameters for DownloadImageByToken.
+type DownloadImageByTokenParamsFilename string
+
+// DownloadImageParams defines parameters for DownloadImage.
+type DownloadImageParams struct {
+	// Version OpenShift version of the base image
+	Version string `form:"version" json:"version"`
+
+	// Type Type of the ISO
+	Type DownloadImageParamsType `form:"type" json:"type"`
+
+	// Arch CPU architecture of the base image, x86_64 by default
+	Arch *string `form:"arch,omitempty" json:"arch,omitempty"`
+
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+
+	// Descriptor Serves the descriptor of the artifact, listing its mirrors and hashes, instead of the artifact
+	Descriptor *DownloadImageParamsDescriptor `form:"descriptor,omitempty" json:"descriptor,omitempty"`
+}
+
+// DownloadImageParamsType defines parameters for DownloadImage.
+type DownloadImageParamsType string
+
+// DownloadImageParamsDescriptor defines parameters for DownloadImage.
+type DownloadImageParamsDescriptor string
+
+// DownloadConfigDriveParams defines parameters for DownloadConfigDrive.
+type DownloadConfigDriveParams struct {
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// DownloadNetbootTreeParams defines parameters for DownloadNetbootTree.
+type DownloadNetbootTreeParams struct {
+	// Version OpenShift version of the base image
+	Version string `form:"version" json:"version"`
+
+	// Arch CPU architecture of the base image, x86_64 by default
+	Arch *string `form:"arch,omitempty" json:"arch,omitempty"`
+
+	// BaseUrl URL the files of the tree are served from, relative by default
+	BaseUrl *string `form:"base_url,omitempty" json:"base_url,omitempty"`
+
+	// Compress Streams the artifact compressed, with this Content-Encoding and without Range support
+	Compress *DownloadNetbootTreeParamsCompress `form:"compress,omitempty" json:"compress,omitempty"`
+
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// DownloadNetbootTreeParamsCompress defines parameters for DownloadNetbootTree.
+type DownloadNetbootTreeParamsCompress string
+
+// WriteNetbootTreeParams defines parameters for WriteNetbootTree.
+type WriteNetbootTreeParams struct {
+	// Version OpenShift version of the base image
+	Version string `form:"version" json:"version"`
+
+	// Arch CPU architecture of the base image, x86_64 by default
+	Arch *string `form:"arch,omitempty" json:"arch,omitempty"`
+
+	// BaseUrl URL the files of the tree are served from, relative by default
+	BaseUrl *string `form:"base_url,omitempty" json:"base_url,omitempty"`
+
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// CreateNoCloudSeedParams defines parameters for CreateNoCloudSeed.
+type CreateNoCloudSeedParams struct {
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// DownloadOVAParams defines parameters for DownloadOVA.
+type DownloadOVAParams struct {
+	// Version OpenShift version of the base image
+	Version string `form:"version" json:"version"`
+
+	// Arch CPU architecture of the base image, x86_64 by default
+	Arch *string `form:"arch,omitempty" json:"arch,omitempty"`
+
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// DownloadPXEBundleParams defines parameters for DownloadPXEBundle.
+type DownloadPXEBundleParams struct {
+	// Version OpenShift version of the base image
+	Version string `form:"version" json:"version"`
+
+	// Arch CPU architecture of the base image, x86_64 by default
+	Arch *string `form:"arch,omitempty" json:"arch,omitempty"`
+
+	// BaseUrl URL the files of the archive are served from, relative by default
+	BaseUrl *string `form:"base_url,omitempty" json:"base_url,omitempty"`
+
+	// Compress Streams the artifact compressed, with this Content-Encoding and without Range support
+	Compress *DownloadPXEBundleParamsCompress `form:"compress,omitempty" json:"compress,omitempty"`
+
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// DownloadPXEBundleParamsCompress defines parameters for DownloadPXEBundle.
+type DownloadPXEBundleParamsCompress string
+
+// DownloadInitrdParams defines parameters for DownloadInitrd.
+type DownloadInitrdParams struct {
+	// Version OpenShift version of the base image
+	Version string `form:"version" json:"version"`
+
+	// Arch CPU architecture of the base image, x86_64 by default
+	Arch *string `form:"arch,omitempty" json:"arch,omitempty"`
+
+	// Compress Streams the artifact compressed, with this Content-Encoding and without Range support
+	Compress *DownloadInitrdParamsCompress `form:"compress,omitempty" json:"compress,omitempty"`
+
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// DownloadInitrdParamsCompress defines parameters for DownloadInitrd.
+type DownloadInitrdParamsCompress string
+
+// GetIPXEScriptParams defines parameters for GetIPXEScript.
+type GetIPXEScriptParams struct {
+	// Version OpenShift version of the base image
+	Version string `form:"version" json:"version"`
+
+	// Arch CPU architecture of the base image, x86_64 by default
+	Arch *string `form:"arch,omitempty" json:"arch,omitempty"`
+
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// DownloadQcow2Params defines parameters for DownloadQcow2.
+type DownloadQcow2Params struct {
+	// Version OpenShift version of the base image
+	Version string `form:"version" json:"version"`
+
+	// Arch CPU architecture of the base image, x86_64 by default
+	Arch *string `form:"arch,omitempty" json:"arch,omitempty"`
+
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// DownloadRawImageParams defines parameters for DownloadRawImage.
+type DownloadRawImageParams struct {
+	// Version OpenShift version of the base image
+	Version string `form:"version" json:"version"`
+
+	// Arch CPU architecture of the base image, x86_64 by default
+	Arch *string `form:"arch,omitempty" json:"arch,omitempty"`
+
+	// Compression Compression of the image, uncompressed by default
+	Compression *DownloadRawImageParamsCompression `form:"compression,omitempty" json:"compression,omitempty"`
+
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// DownloadRawImageParamsCompression defines parameters for DownloadRawImage.
+type DownloadRawImageParamsCompression string
+
+// DownloadS390xBootFilesParams defines parameters for DownloadS390xBootFiles.
+type DownloadS390xBootFilesParams struct {
+	// Version OpenShift version of the base image
+	Version string `form:"version" json:"version"`
+
+	// Compress Streams the artifact compressed, with this Content-Encoding and without Range support
+	Compress *DownloadS390xBootFilesParamsCompress `form:"compress,omitempty" json:"compress,omitempty"`
+
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// DownloadS390xBootFilesParamsCompress defines parameters for DownloadS390xBootFiles.
+type DownloadS390xBootFilesParamsCompress string
+
+// DownloadInitrdAddrSizeParams defines parameters for DownloadInitrdAddrSize.
+type DownloadInitrdAddrSizeParams struct {
+	// Version OpenShift version of the base image
+	Version string `form:"version" json:"version"`
+
+	// Arch CPU architecture of the base image, x86_64 by default
+	Arch *string `form:"arch,omitempty" json:"arch,omitempty"`
+
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// DownloadUKIParams defines parameters for DownloadUKI.
+type DownloadUKIParams struct {
+	// Version OpenShift version of the base image
+	Version string `form:"version" json:"version"`
+
+	// Arch CPU architecture of the base image, x86_64 by default
+	Arch *string `form:"arch,omitempty" json:"arch,omitempty"`
+
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// CustomizeImageParams defines parameters for CustomizeImage.
+type CustomizeImageParams struct {
+	// ApiKey API key authenticating the request to assisted service
+	ApiKey *string `form:"api_key,omitempty" json:"api_key,omitempty"`
+
+	// ImageToken Image token authenticating the request to assisted service
+	ImageToken *string `form:"image_token,omitempty" json:"image_token,omitempty"`
+}
+
+// AddBaseISOJSONRequestBody defines body for AddBaseISO for application/json ContentType.
+type AddBaseISOJSONRequestBody = BaseISORequest
+
+// CreateBuildJSONRequestBody defines body for CreateBuild for application/json ContentType.
+type CreateBuildJSONRequestBody = BuildRequest
+
+// CreateNoCloudSeedJSONRequestBody defines body for CreateNoCloudSeed for application/json ContentType.
+type CreateNoCloudSeedJSONRequestBody = NoCloudSeed
+
+// CustomizeImageJSONRequestBody defines body for CustomizeImage for application/json ContentType.
+type CustomizeImageJSONRequestBody = CustomizationSpec
+
+// RequestEditorFn  is the function signature for the RequestEditor callback function
+type RequestEditorFn func(ctx context.Context, req *http.Request) error
+
+// Doer performs HTTP requests.
+//
+// The standard http.Client implements this interface.
+type HttpRequestDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client which conforms to the OpenAPI3 specification for this service.
+type Client struct {
+	// The endpoint of the server conforming to this interface, with scheme,
+	// https://api.deepmap.com for example. This can contain a path relative
+	// to the server, such as https://api.deepmap.com/dev-test, and all the
+	// paths in the swagger spec will be appended to the server.
+	Server string
+
+	// Doer for performing requests, typically a *http.Client with any
+	// customized settings, such as certificate chains.
+	Client HttpRequestDoer
+
+	// A list of callbacks for modifying requests which are generated before sending over
+	// the network.
+	RequestEditors []RequestEditorFn
+}
+
+// ClientOption allows setting custom parameters during construction
+type ClientOption func(*Client) error
+
+// Creates a new Client, with reasonable defaults
+func NewClient(server string, opts ...ClientOption) (*Client, error) {
+	// create a client with sane default values
+	client := Client{
+		Server: server,
+	}
+	// mutate client and add all optional params
+	for _, o := range opts {
+		if err := o(&client); err != nil {
+			return nil, err
+		}
+	}
+	// ensure the server URL always has a trailing slash
+	if !strings.HasSuffix(client.Server, "/") {
+		client.Server += "/"
+	}
+	// create httpClient, if not already present
+	if client.Client == nil {
+		client.Client = &http.Client{}
+	}
+	return &client, nil
+}
+
+// WithHTTPClient allows overriding the default Doer, which is
+// automatically created using http.Client. This is useful for tests.
+func WithHTTPClient(doer HttpRequestDoer) ClientOption {
+	return func(c *Client) error {
+		c.Client = doer
+		return nil
+	}
+}
+
+// WithRequestEditorFn allows setting up a callback function, which will be
+// called right before sending the request. This can be used to mutate the request.
+func WithRequestEditorFn(fn RequestEditorFn) ClientOption {
+	return func(c *Client) error {
+		c.RequestEditors = append(c.RequestEditors, fn)
+		return nil
+	}
+}
+
+// The interface specification for the client above.
+type ClientInterface interface {
+	// AddBaseISOWithBody request with any body
+	AddBaseISOWithBody(ctx context.Context, version string, arch string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	AddBaseISO(ctx context.Context, version string, arch string, body AddBaseISOJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadBootArtifact request
+	DownloadBootArtifact(ctx context.Context, artifact DownloadBootArtifactParamsArtifact, params *DownloadBootArtifactParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// CreateBuildWithBody request with any body
+	CreateBuildWithBody(ctx context.Context, params *CreateBuildParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	CreateBuild(ctx context.Context, params *CreateBuildParams, body CreateBuildJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetBuild request
+	GetBuild(ctx context.Context, buildId string, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadBuild request
+	DownloadBuild(ctx context.Context, buildId string, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadImageByAPIKey request
+	DownloadImageByAPIKey(ctx context.Context, apiKey string, version string, arch string, filename DownloadImageByAPIKeyParamsFilename, params *DownloadImageByAPIKeyParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadImageByID request
+	DownloadImageByID(ctx context.Context, imageId string, version string, arch string, filename DownloadImageByIDParamsFilename, params *DownloadImageByIDParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadImageByToken request
+	DownloadImageByToken(ctx context.Context, token string, version string, arch string, filename DownloadImageByTokenParamsFilename, params *DownloadImageByTokenParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetHealth request
+	GetHealth(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadImage request
+	DownloadImage(ctx context.Context, imageId string, params *DownloadImageParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadConfigDrive request
+	DownloadConfigDrive(ctx context.Context, imageId string, params *DownloadConfigDriveParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadNetbootTree request
+	DownloadNetbootTree(ctx context.Context, imageId string, params *DownloadNetbootTreeParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// WriteNetbootTree request
+	WriteNetbootTree(ctx context.Context, imageId string, params *WriteNetbootTreeParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// CreateNoCloudSeedWithBody request with any body
+	CreateNoCloudSeedWithBody(ctx context.Context, imageId string, params *CreateNoCloudSeedParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	CreateNoCloudSeed(ctx context.Context, imageId string, params *CreateNoCloudSeedParams, body CreateNoCloudSeedJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadOVA request
+	DownloadOVA(ctx context.Context, imageId string, params *DownloadOVAParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadPXEBundle request
+	DownloadPXEBundle(ctx context.Context, imageId string, params *DownloadPXEBundleParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadInitrd request
+	DownloadInitrd(ctx context.Context, imageId string, params *DownloadInitrdParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetIPXEScript request
+	GetIPXEScript(ctx context.Context, imageId string, params *GetIPXEScriptParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadQcow2 request
+	DownloadQcow2(ctx context.Context, imageId string, params *DownloadQcow2Params, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadRawImage request
+	DownloadRawImage(ctx context.Context, imageId string, params *DownloadRawImageParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadS390xBootFiles request
+	DownloadS390xBootFiles(ctx context.Context, imageId string, params *DownloadS390xBootFilesParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadInitrdAddrSize request
+	DownloadInitrdAddrSize(ctx context.Context, imageId string, params *DownloadInitrdAddrSizeParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DownloadUKI request
+	DownloadUKI(ctx context.Context, imageId string, params *DownloadUKIParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetLiveness request
+	GetLiveness(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetOpenAPI request
+	GetOpenAPI(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// CustomizeImageWithBody request with any body
+	CustomizeImageWithBody(ctx context.Context, imageId string, params *CustomizeImageParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	CustomizeImage(ctx context.Context, imageId string, params *CustomizeImageParams, body CustomizeImageJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+}
+
+func (c *Client) AddBaseISOWithBody(ctx context.Context, version string, arch string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewAddBaseISORequestWithBody(c.Server, version, arch, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) AddBaseISO(ctx context.Context, version string, arch string, body AddBaseISOJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewAddBaseISORequest(c.Server, version, arch, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadBootArtifact(ctx context.Context, artifact DownloadBootArtifactParamsArtifact, params *DownloadBootArtifactParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadBootArtifactRequest(c.Server, artifact, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) CreateBuildWithBody(ctx context.Context, params *CreateBuildParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewCreateBuildRequestWithBody(c.Server, params, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) CreateBuild(ctx context.Context, params *CreateBuildParams, body CreateBuildJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewCreateBuildRequest(c.Server, params, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) GetBuild(ctx context.Context, buildId string, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetBuildRequest(c.Server, buildId)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadBuild(ctx context.Context, buildId string, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadBuildRequest(c.Server, buildId)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadImageByAPIKey(ctx context.Context, apiKey string, version string, arch string, filename DownloadImageByAPIKeyParamsFilename, params *DownloadImageByAPIKeyParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadImageByAPIKeyRequest(c.Server, apiKey, version, arch, filename, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadImageByID(ctx context.Context, imageId string, version string, arch string, filename DownloadImageByIDParamsFilename, params *DownloadImageByIDParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadImageByIDRequest(c.Server, imageId, version, arch, filename, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadImageByToken(ctx context.Context, token string, version string, arch string, filename DownloadImageByTokenParamsFilename, params *DownloadImageByTokenParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadImageByTokenRequest(c.Server, token, version, arch, filename, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) GetHealth(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetHealthRequest(c.Server)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadImage(ctx context.Context, imageId string, params *DownloadImageParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadImageRequest(c.Server, imageId, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadConfigDrive(ctx context.Context, imageId string, params *DownloadConfigDriveParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadConfigDriveRequest(c.Server, imageId, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadNetbootTree(ctx context.Context, imageId string, params *DownloadNetbootTreeParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadNetbootTreeRequest(c.Server, imageId, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) WriteNetbootTree(ctx context.Context, imageId string, params *WriteNetbootTreeParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewWriteNetbootTreeRequest(c.Server, imageId, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) CreateNoCloudSeedWithBody(ctx context.Context, imageId string, params *CreateNoCloudSeedParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewCreateNoCloudSeedRequestWithBody(c.Server, imageId, params, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) CreateNoCloudSeed(ctx context.Context, imageId string, params *CreateNoCloudSeedParams, body CreateNoCloudSeedJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewCreateNoCloudSeedRequest(c.Server, imageId, params, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadOVA(ctx context.Context, imageId string, params *DownloadOVAParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadOVARequest(c.Server, imageId, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadPXEBundle(ctx context.Context, imageId string, params *DownloadPXEBundleParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadPXEBundleRequest(c.Server, imageId, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadInitrd(ctx context.Context, imageId string, params *DownloadInitrdParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadInitrdRequest(c.Server, imageId, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) GetIPXEScript(ctx context.Context, imageId string, params *GetIPXEScriptParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetIPXEScriptRequest(c.Server, imageId, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadQcow2(ctx context.Context, imageId string, params *DownloadQcow2Params, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadQcow2Request(c.Server, imageId, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadRawImage(ctx context.Context, imageId string, params *DownloadRawImageParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadRawImageRequest(c.Server, imageId, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadS390xBootFiles(ctx context.Context, imageId string, params *DownloadS390xBootFilesParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadS390xBootFilesRequest(c.Server, imageId, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadInitrdAddrSize(ctx context.Context, imageId string, params *DownloadInitrdAddrSizeParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadInitrdAddrSizeRequest(c.Server, imageId, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DownloadUKI(ctx context.Context, imageId string, params *DownloadUKIParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDownloadUKIRequest(c.Server, imageId, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) GetLiveness(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetLivenessRequest(c.Server)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) GetOpenAPI(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetOpenAPIRequest(c.Server)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) CustomizeImageWithBody(ctx context.Context, imageId string, params *CustomizeImageParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewCustomizeImageRequestWithBody(c.Server, imageId, params, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) CustomizeImage(ctx context.Context, imageId string, params *CustomizeImageParams, body CustomizeImageJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewCustomizeImageRequest(c.Server, imageId, params, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+// NewAddBaseISORequest calls the generic AddBaseISO builder with application/json body
+func NewAddBaseISORequest(server string, version string, arch string, body AddBaseISOJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewAddBaseISORequestWithBody(server, version, arch, "application/json", bodyReader)
+}
+
+// NewAddBaseISORequestWithBody generates requests for AddBaseISO with any type of body
+func NewAddBaseISORequestWithBody(server string, version string, arch string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "version", runtime.ParamLocationPath, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam1 string
+
+	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "arch", runtime.ParamLocationPath, arch)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/base-isos/%s/%s", pathParam0, pathParam1)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("PUT", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewDownloadBootArtifactRequest generates requests for DownloadBootArtifact
+func NewDownloadBootArtifactRequest(server string, artifact DownloadBootArtifactParamsArtifact, params *DownloadBootArtifactParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "artifact", runtime.ParamLocationPath, artifact)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/boot-artifacts/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "version", runtime.ParamLocationQuery, params.Version); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if params.Arch != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "arch", runtime.ParamLocationQuery, *params.Arch); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.Compress != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "compress", runtime.ParamLocationQuery, *params.Compress); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.Descriptor != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "descriptor", runtime.ParamLocationQuery, *params.Descriptor); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewCreateBuildRequest calls the generic CreateBuild builder with application/json body
+func NewCreateBuildRequest(server string, params *CreateBuildParams, body CreateBuildJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewCreateBuildRequestWithBody(server, params, "application/json", bodyReader)
+}
+
+// NewCreateBuildRequestWithBody generates requests for CreateBuild with any type of body
+func NewCreateBuildRequestWithBody(server string, params *CreateBuildParams, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/builds")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewGetBuildRequest generates requests for GetBuild
+func NewGetBuildRequest(server string, buildId string) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "build_id", runtime.ParamLocationPath, buildId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/builds/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDownloadBuildRequest generates requests for DownloadBuild
+func NewDownloadBuildRequest(server string, buildId string) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "build_id", runtime.ParamLocationPath, buildId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/builds/%s/image", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDownloadImageByAPIKeyRequest generates requests for DownloadImageByAPIKey
+func NewDownloadImageByAPIKeyRequest(server string, apiKey string, version string, arch string, filename DownloadImageByAPIKeyParamsFilename, params *DownloadImageByAPIKeyParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "api_key", runtime.ParamLocationPath, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam1 string
+
+	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "version", runtime.ParamLocationPath, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam2 string
+
+	pathParam2, err = runtime.StyleParamWithLocation("simple", false, "arch", runtime.ParamLocationPath, arch)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam3 string
+
+	pathParam3, err = runtime.StyleParamWithLocation("simple", false, "filename", runtime.ParamLocationPath, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/byapikey/%s/%s/%s/%s", pathParam0, pathParam1, pathParam2, pathParam3)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if params.Descriptor != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "descriptor", runtime.ParamLocationQuery, *params.Descriptor); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDownloadImageByIDRequest generates requests for DownloadImageByID
+func NewDownloadImageByIDRequest(server string, imageId string, version string, arch string, filename DownloadImageByIDParamsFilename, params *DownloadImageByIDParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam1 string
+
+	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "version", runtime.ParamLocationPath, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam2 string
+
+	pathParam2, err = runtime.StyleParamWithLocation("simple", false, "arch", runtime.ParamLocationPath, arch)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam3 string
+
+	pathParam3, err = runtime.StyleParamWithLocation("simple", false, "filename", runtime.ParamLocationPath, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/byid/%s/%s/%s/%s", pathParam0, pathParam1, pathParam2, pathParam3)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.Descriptor != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "descriptor", runtime.ParamLocationQuery, *params.Descriptor); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDownloadImageByTokenRequest generates requests for DownloadImageByToken
+func NewDownloadImageByTokenRequest(server string, token string, version string, arch string, filename DownloadImageByTokenParamsFilename, params *DownloadImageByTokenParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "token", runtime.ParamLocationPath, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam1 string
+
+	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "version", runtime.ParamLocationPath, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam2 string
+
+	pathParam2, err = runtime.StyleParamWithLocation("simple", false, "arch", runtime.ParamLocationPath, arch)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam3 string
+
+	pathParam3, err = runtime.StyleParamWithLocation("simple", false, "filename", runtime.ParamLocationPath, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/bytoken/%s/%s/%s/%s", pathParam0, pathParam1, pathParam2, pathParam3)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if params.Descriptor != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "descriptor", runtime.ParamLocationQuery, *params.Descriptor); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewGetHealthRequest generates requests for GetHealth
+func NewGetHealthRequest(server string) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/health")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDownloadImageRequest generates requests for DownloadImage
+func NewDownloadImageRequest(server string, imageId string, params *DownloadImageParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/images/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "version", runtime.ParamLocationQuery, params.Version); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "type", runtime.ParamLocationQuery, params.Type); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if params.Arch != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "arch", runtime.ParamLocationQuery, *params.Arch); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.Descriptor != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "descriptor", runtime.ParamLocationQuery, *params.Descriptor); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDownloadConfigDriveRequest generates requests for DownloadConfigDrive
+func NewDownloadConfigDriveRequest(server string, imageId string, params *DownloadConfigDriveParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/images/%s/config-drive", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDownloadNetbootTreeRequest generates requests for DownloadNetbootTree
+func NewDownloadNetbootTreeRequest(server string, imageId string, params *DownloadNetbootTreeParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/images/%s/netboot", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "version", runtime.ParamLocationQuery, params.Version); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if params.Arch != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "arch", runtime.ParamLocationQuery, *params.Arch); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.BaseUrl != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "base_url", runtime.ParamLocationQuery, *params.BaseUrl); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.Compress != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "compress", runtime.ParamLocationQuery, *params.Compress); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewWriteNetbootTreeRequest generates requests for WriteNetbootTree
+func NewWriteNetbootTreeRequest(server string, imageId string, params *WriteNetbootTreeParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/images/%s/netboot", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "version", runtime.ParamLocationQuery, params.Version); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if params.Arch != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "arch", runtime.ParamLocationQuery, *params.Arch); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.BaseUrl != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "base_url", runtime.ParamLocationQuery, *params.BaseUrl); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewCreateNoCloudSeedRequest calls the generic CreateNoCloudSeed builder with application/json body
+func NewCreateNoCloudSeedRequest(server string, imageId string, params *CreateNoCloudSeedParams, body CreateNoCloudSeedJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewCreateNoCloudSeedRequestWithBody(server, imageId, params, "application/json", bodyReader)
+}
+
+// NewCreateNoCloudSeedRequestWithBody generates requests for CreateNoCloudSeed with any type of body
+func NewCreateNoCloudSeedRequestWithBody(server string, imageId string, params *CreateNoCloudSeedParams, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/images/%s/nocloud-seed", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewDownloadOVARequest generates requests for DownloadOVA
+func NewDownloadOVARequest(server string, imageId string, params *DownloadOVAParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/images/%s/ova", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "version", runtime.ParamLocationQuery, params.Version); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if params.Arch != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "arch", runtime.ParamLocationQuery, *params.Arch); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDownloadPXEBundleRequest generates requests for DownloadPXEBundle
+func NewDownloadPXEBundleRequest(server string, imageId string, params *DownloadPXEBundleParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/images/%s/pxe-bundle", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "version", runtime.ParamLocationQuery, params.Version); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if params.Arch != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "arch", runtime.ParamLocationQuery, *params.Arch); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.BaseUrl != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "base_url", runtime.ParamLocationQuery, *params.BaseUrl); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.Compress != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "compress", runtime.ParamLocationQuery, *params.Compress); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDownloadInitrdRequest generates requests for DownloadInitrd
+func NewDownloadInitrdRequest(server string, imageId string, params *DownloadInitrdParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/images/%s/pxe-initrd", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "version", runtime.ParamLocationQuery, params.Version); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if params.Arch != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "arch", runtime.ParamLocationQuery, *params.Arch); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.Compress != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "compress", runtime.ParamLocationQuery, *params.Compress); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewGetIPXEScriptRequest generates requests for GetIPXEScript
+func NewGetIPXEScriptRequest(server string, imageId string, params *GetIPXEScriptParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/images/%s/pxe-script", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "version", runtime.ParamLocationQuery, params.Version); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if params.Arch != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "arch", runtime.ParamLocationQuery, *params.Arch); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDownloadQcow2Request generates requests for DownloadQcow2
+func NewDownloadQcow2Request(server string, imageId string, params *DownloadQcow2Params) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/images/%s/qcow2", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "version", runtime.ParamLocationQuery, params.Version); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if params.Arch != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "arch", runtime.ParamLocationQuery, *params.Arch); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDownloadRawImageRequest generates requests for DownloadRawImage
+func NewDownloadRawImageRequest(server string, imageId string, params *DownloadRawImageParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/images/%s/raw", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "version", runtime.ParamLocationQuery, params.Version); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if params.Arch != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "arch", runtime.ParamLocationQuery, *params.Arch); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.Compression != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "compression", runtime.ParamLocationQuery, *params.Compression); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDownloadS390xBootFilesRequest generates requests for DownloadS390xBootFiles
+func NewDownloadS390xBootFilesRequest(server string, imageId string, params *DownloadS390xBootFilesParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/images/%s/s390x-boot", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "version", runtime.ParamLocationQuery, params.Version); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if params.Compress != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "compress", runtime.ParamLocationQuery, *params.Compress); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDownloadInitrdAddrSizeRequest generates requests for DownloadInitrdAddrSize
+func NewDownloadInitrdAddrSizeRequest(server string, imageId string, params *DownloadInitrdAddrSizeParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/images/%s/s390x-initrd-addrsize", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "version", runtime.ParamLocationQuery, params.Version); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if params.Arch != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "arch", runtime.ParamLocationQuery, *params.Arch); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDownloadUKIRequest generates requests for DownloadUKI
+func NewDownloadUKIRequest(server string, imageId string, params *DownloadUKIParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/images/%s/uki", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "version", runtime.ParamLocationQuery, params.Version); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if params.Arch != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "arch", runtime.ParamLocationQuery, *params.Arch); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewGetLivenessRequest generates requests for GetLiveness
+func NewGetLivenessRequest(server string) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/live")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewGetOpenAPIRequest generates requests for GetOpenAPI
+func NewGetOpenAPIRequest(server string) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/openapi.json")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewCustomizeImageRequest calls the generic CustomizeImage builder with application/json body
+func NewCustomizeImageRequest(server string, imageId string, params *CustomizeImageParams, body CustomizeImageJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewCustomizeImageRequestWithBody(server, imageId, params, "application/json", bodyReader)
+}
+
+// NewCustomizeImageRequestWithBody generates requests for CustomizeImage with any type of body
+func NewCustomizeImageRequestWithBody(server string, imageId string, params *CustomizeImageParams, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "image_id", runtime.ParamLocationPath, imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/v2/images/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if params.ApiKey != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "api_key", runtime.ParamLocationQuery, *params.ApiKey); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		if params.ImageToken != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "image_token", runtime.ParamLocationQuery, *params.ImageToken); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+func (c *Client) applyEditors(ctx context.Context, req *http.Request, additionalEditors []RequestEditorFn) error {
+	for _, r := range c.RequestEditors {
+		if err := r(ctx, req); err != nil {
+			return err
+		}
+	}
+	for _, r := range additionalEditors {
+		if err := r(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClientWithResponses builds on ClientInterface to offer response payloads
+type ClientWithResponses struct {
+	ClientInterface
+}
+
+// NewClientWithResponses creates a new ClientWithResponses, which wraps
+// Client with return type handling
+func NewClientWithResponses(server string, opts ...ClientOption) (*ClientWithResponses, error) {
+	client, err := NewClient(server, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientWithResponses{client}, nil
+}
+
+// WithBaseURL overrides the baseURL.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) error {
+		newBaseURL, err := url.Parse(baseURL)
+		if err != nil {
+			return err
+		}
+		c.Server = newBaseURL.String()
+		return nil
+	}
+}
+
+// ClientWithResponsesInterface is the interface specification for the client with responses above.
+type ClientWithResponsesInterface interface {
+	// AddBaseISOWithBodyWithResponse request with any body
+	AddBaseISOWithBodyWithResponse(ctx context.Context, version string, arch string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AddBaseISOResponse, error)
+
+	AddBaseISOWithResponse(ctx context.Context, version string, arch string, body AddBaseISOJSONRequestBody, reqEditors ...RequestEditorFn) (*AddBaseISOResponse, error)
+
+	// DownloadBootArtifactWithResponse request
+	DownloadBootArtifactWithResponse(ctx context.Context, artifact DownloadBootArtifactParamsArtifact, params *DownloadBootArtifactParams, reqEditors ...RequestEditorFn) (*DownloadBootArtifactResponse, error)
+
+	// CreateBuildWithBodyWithResponse request with any body
+	CreateBuildWithBodyWithResponse(ctx context.Context, params *CreateBuildParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*CreateBuildResponse, error)
+
+	CreateBuildWithResponse(ctx context.Context, params *CreateBuildParams, body CreateBuildJSONRequestBody, reqEditors ...RequestEditorFn) (*CreateBuildResponse, error)
+
+	// GetBuildWithResponse request
+	GetBuildWithResponse(ctx context.Context, buildId string, reqEditors ...RequestEditorFn) (*GetBuildResponse, error)
+
+	// DownloadBuildWithResponse request
+	DownloadBuildWithResponse(ctx context.Context, buildId string, reqEditors ...RequestEditorFn) (*DownloadBuildResponse, error)
+
+	// DownloadImageByAPIKeyWithResponse request
+	DownloadImageByAPIKeyWithResponse(ctx context.Context, apiKey string, version string, arch string, filename DownloadImageByAPIKeyParamsFilename, params *DownloadImageByAPIKeyParams, reqEditors ...RequestEditorFn) (*DownloadImageByAPIKeyResponse, error)
+
+	// DownloadImageByIDWithResponse request
+	DownloadImageByIDWithResponse(ctx context.Context, imageId string, version string, arch string, filename DownloadImageByIDParamsFilename, params *DownloadImageByIDParams, reqEditors ...RequestEditorFn) (*DownloadImageByIDResponse, error)
+
+	// DownloadImageByTokenWithResponse request
+	DownloadImageByTokenWithResponse(ctx context.Context, token string, version string, arch string, filename DownloadImageByTokenParamsFilename, params *DownloadImageByTokenParams, reqEditors ...RequestEditorFn) (*DownloadImageByTokenResponse, error)
+
+	// GetHealthWithResponse request
+	GetHealthWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetHealthResponse, error)
+
+	// DownloadImageWithResponse request
+	DownloadImageWithResponse(ctx context.Context, imageId string, params *DownloadImageParams, reqEditors ...RequestEditorFn) (*DownloadImageResponse, error)
+
+	// DownloadConfigDriveWithResponse request
+	DownloadConfigDriveWithResponse(ctx context.Context, imageId string, params *DownloadConfigDriveParams, reqEditors ...RequestEditorFn) (*DownloadConfigDriveResponse, error)
+
+	// DownloadNetbootTreeWithResponse request
+	DownloadNetbootTreeWithResponse(ctx context.Context, imageId string, params *DownloadNetbootTreeParams, reqEditors ...RequestEditorFn) (*DownloadNetbootTreeResponse, error)
+
+	// WriteNetbootTreeWithResponse request
+	WriteNetbootTreeWithResponse(ctx context.Context, imageId string, params *WriteNetbootTreeParams, reqEditors ...RequestEditorFn) (*WriteNetbootTreeResponse, error)
+
+	// CreateNoCloudSeedWithBodyWithResponse request with any body
+	CreateNoCloudSeedWithBodyWithResponse(ctx context.Context, imageId string, params *CreateNoCloudSeedParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*CreateNoCloudSeedResponse, error)
+
+	CreateNoCloudSeedWithResponse(ctx context.Context, imageId string, params *CreateNoCloudSeedParams, body CreateNoCloudSeedJSONRequestBody, reqEditors ...RequestEditorFn) (*CreateNoCloudSeedResponse, error)
+
+	// DownloadOVAWithResponse request
+	DownloadOVAWithResponse(ctx context.Context, imageId string, params *DownloadOVAParams, reqEditors ...RequestEditorFn) (*DownloadOVAResponse, error)
+
+	// DownloadPXEBundleWithResponse request
+	DownloadPXEBundleWithResponse(ctx context.Context, imageId string, params *DownloadPXEBundleParams, reqEditors ...RequestEditorFn) (*DownloadPXEBundleResponse, error)
+
+	// DownloadInitrdWithResponse request
+	DownloadInitrdWithResponse(ctx context.Context, imageId string, params *DownloadInitrdParams, reqEditors ...RequestEditorFn) (*DownloadInitrdResponse, error)
+
+	// GetIPXEScriptWithResponse request
+	GetIPXEScriptWithResponse(ctx context.Context, imageId string, params *GetIPXEScriptParams, reqEditors ...RequestEditorFn) (*GetIPXEScriptResponse, error)
+
+	// DownloadQcow2WithResponse request
+	DownloadQcow2WithResponse(ctx context.Context, imageId string, params *DownloadQcow2Params, reqEditors ...RequestEditorFn) (*DownloadQcow2Response, error)
+
+	// DownloadRawImageWithResponse request
+	DownloadRawImageWithResponse(ctx context.Context, imageId string, params *DownloadRawImageParams, reqEditors ...RequestEditorFn) (*DownloadRawImageResponse, error)
+
+	// DownloadS390xBootFilesWithResponse request
+	DownloadS390xBootFilesWithResponse(ctx context.Context, imageId string, params *DownloadS390xBootFilesParams, reqEditors ...RequestEditorFn) (*DownloadS390xBootFilesResponse, error)
+
+	// DownloadInitrdAddrSizeWithResponse request
+	DownloadInitrdAddrSizeWithResponse(ctx context.Context, imageId string, params *DownloadInitrdAddrSizeParams, reqEditors ...RequestEditorFn) (*DownloadInitrdAddrSizeResponse, error)
+
+	// DownloadUKIWithResponse request
+	DownloadUKIWithResponse(ctx context.Context, imageId string, params *DownloadUKIParams, reqEditors ...RequestEditorFn) (*DownloadUKIResponse, error)
+
+	// GetLivenessWithResponse request
+	GetLivenessWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetLivenessResponse, error)
+
+	// GetOpenAPIWithResponse request
+	GetOpenAPIWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetOpenAPIResponse, error)
+
+	// CustomizeImageWithBodyWithResponse request with any body
+	CustomizeImageWithBodyWithResponse(ctx context.Context, imageId string, params *CustomizeImageParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*CustomizeImageResponse, error)
+
+	CustomizeImageWithResponse(ctx context.Context, imageId string, params *CustomizeImageParams, body CustomizeImageJSONRequestBody, reqEditors ...RequestEditorFn) (*CustomizeImageResponse, error)
+}
+
+type AddBaseISOResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON201      *BaseISOResponse
+}
+
+// Status returns HTTPResponse.Status
+func (r AddBaseISOResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r AddBaseISOResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadBootArtifactResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *ChunkManifest
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadBootArtifactResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadBootArtifactResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type CreateBuildResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON202      *Build
+}
+
+// Status returns HTTPResponse.Status
+func (r CreateBuildResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r CreateBuildResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetBuildResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *Build
+}
+
+// Status returns HTTPResponse.Status
+func (r GetBuildResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetBuildResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadBuildResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadBuildResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadBuildResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadImageByAPIKeyResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *ChunkManifest
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadImageByAPIKeyResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadImageByAPIKeyResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadImageByIDResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *ChunkManifest
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadImageByIDResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadImageByIDResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadImageByTokenResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *ChunkManifest
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadImageByTokenResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadImageByTokenResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetHealthResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r GetHealthResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetHealthResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadImageResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *ChunkManifest
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadImageResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadImageResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadConfigDriveResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadConfigDriveResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadConfigDriveResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadNetbootTreeResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadNetbootTreeResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadNetbootTreeResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type WriteNetbootTreeResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r WriteNetbootTreeResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r WriteNetbootTreeResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type CreateNoCloudSeedResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r CreateNoCloudSeedResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r CreateNoCloudSeedResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadOVAResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadOVAResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadOVAResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadPXEBundleResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadPXEBundleResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadPXEBundleResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadInitrdResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadInitrdResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadInitrdResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetIPXEScriptResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r GetIPXEScriptResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetIPXEScriptResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadQcow2Response struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadQcow2Response) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadQcow2Response) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadRawImageResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadRawImageResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadRawImageResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadS390xBootFilesResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadS390xBootFilesResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadS390xBootFilesResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadInitrdAddrSizeResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadInitrdAddrSizeResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadInitrdAddrSizeResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DownloadUKIResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r DownloadUKIResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DownloadUKIResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetLivenessResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r GetLivenessResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetLivenessResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetOpenAPIResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *map[string]interface{}
+}
+
+// Status returns HTTPResponse.Status
+func (r GetOpenAPIResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetOpenAPIResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type CustomizeImageResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON202      *Build
+}
+
+// Status returns HTTPResponse.Status
+func (r CustomizeImageResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r CustomizeImageResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+// AddBaseISOWithBodyWithResponse request with arbitrary body returning *AddBaseISOResponse
+func (c *ClientWithResponses) AddBaseISOWithBodyWithResponse(ctx context.Context, version string, arch string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*AddBaseISOResponse, error) {
+	rsp, err := c.AddBaseISOWithBody(ctx, version, arch, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAddBaseISOResponse(rsp)
+}
+
+func (c *ClientWithResponses) AddBaseISOWithResponse(ctx context.Context, version string, arch string, body AddBaseISOJSONRequestBody, reqEditors ...RequestEditorFn) (*AddBaseISOResponse, error) {
+	rsp, err := c.AddBaseISO(ctx, version, arch, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAddBaseISOResponse(rsp)
+}
+
+// DownloadBootArtifactWithResponse request returning *DownloadBootArtifactResponse
+func (c *ClientWithResponses) DownloadBootArtifactWithResponse(ctx context.Context, artifact DownloadBootArtifactParamsArtifact, params *DownloadBootArtifactParams, reqEditors ...RequestEditorFn) (*DownloadBootArtifactResponse, error) {
+	rsp, err := c.DownloadBootArtifact(ctx, artifact, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadBootArtifactResponse(rsp)
+}
+
+// CreateBuildWithBodyWithResponse request with arbitrary body returning *CreateBuildResponse
+func (c *ClientWithResponses) CreateBuildWithBodyWithResponse(ctx context.Context, params *CreateBuildParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*CreateBuildResponse, error) {
+	rsp, err := c.CreateBuildWithBody(ctx, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCreateBuildResponse(rsp)
+}
+
+func (c *ClientWithResponses) CreateBuildWithResponse(ctx context.Context, params *CreateBuildParams, body CreateBuildJSONRequestBody, reqEditors ...RequestEditorFn) (*CreateBuildResponse, error) {
+	rsp, err := c.CreateBuild(ctx, params, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCreateBuildResponse(rsp)
+}
+
+// GetBuildWithResponse request returning *GetBuildResponse
+func (c *ClientWithResponses) GetBuildWithResponse(ctx context.Context, buildId string, reqEditors ...RequestEditorFn) (*GetBuildResponse, error) {
+	rsp, err := c.GetBuild(ctx, buildId, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetBuildResponse(rsp)
+}
+
+// DownloadBuildWithResponse request returning *DownloadBuildResponse
+func (c *ClientWithResponses) DownloadBuildWithResponse(ctx context.Context, buildId string, reqEditors ...RequestEditorFn) (*DownloadBuildResponse, error) {
+	rsp, err := c.DownloadBuild(ctx, buildId, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadBuildResponse(rsp)
+}
+
+// DownloadImageByAPIKeyWithResponse request returning *DownloadImageByAPIKeyResponse
+func (c *ClientWithResponses) DownloadImageByAPIKeyWithResponse(ctx context.Context, apiKey string, version string, arch string, filename DownloadImageByAPIKeyParamsFilename, params *DownloadImageByAPIKeyParams, reqEditors ...RequestEditorFn) (*DownloadImageByAPIKeyResponse, error) {
+	rsp, err := c.DownloadImageByAPIKey(ctx, apiKey, version, arch, filename, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadImageByAPIKeyResponse(rsp)
+}
+
+// DownloadImageByIDWithResponse request returning *DownloadImageByIDResponse
+func (c *ClientWithResponses) DownloadImageByIDWithResponse(ctx context.Context, imageId string, version string, arch string, filename DownloadImageByIDParamsFilename, params *DownloadImageByIDParams, reqEditors ...RequestEditorFn) (*DownloadImageByIDResponse, error) {
+	rsp, err := c.DownloadImageByID(ctx, imageId, version, arch, filename, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadImageByIDResponse(rsp)
+}
+
+// DownloadImageByTokenWithResponse request returning *DownloadImageByTokenResponse
+func (c *ClientWithResponses) DownloadImageByTokenWithResponse(ctx context.Context, token string, version string, arch string, filename DownloadImageByTokenParamsFilename, params *DownloadImageByTokenParams, reqEditors ...RequestEditorFn) (*DownloadImageByTokenResponse, error) {
+	rsp, err := c.DownloadImageByToken(ctx, token, version, arch, filename, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadImageByTokenResponse(rsp)
+}
+
+// GetHealthWithResponse request returning *GetHealthResponse
+func (c *ClientWithResponses) GetHealthWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetHealthResponse, error) {
+	rsp, err := c.GetHealth(ctx, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetHealthResponse(rsp)
+}
+
+// DownloadImageWithResponse request returning *DownloadImageResponse
+func (c *ClientWithResponses) DownloadImageWithResponse(ctx context.Context, imageId string, params *DownloadImageParams, reqEditors ...RequestEditorFn) (*DownloadImageResponse, error) {
+	rsp, err := c.DownloadImage(ctx, imageId, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadImageResponse(rsp)
+}
+
+// DownloadConfigDriveWithResponse request returning *DownloadConfigDriveResponse
+func (c *ClientWithResponses) DownloadConfigDriveWithResponse(ctx context.Context, imageId string, params *DownloadConfigDriveParams, reqEditors ...RequestEditorFn) (*DownloadConfigDriveResponse, error) {
+	rsp, err := c.DownloadConfigDrive(ctx, imageId, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadConfigDriveResponse(rsp)
+}
+
+// DownloadNetbootTreeWithResponse request returning *DownloadNetbootTreeResponse
+func (c *ClientWithResponses) DownloadNetbootTreeWithResponse(ctx context.Context, imageId string, params *DownloadNetbootTreeParams, reqEditors ...RequestEditorFn) (*DownloadNetbootTreeResponse, error) {
+	rsp, err := c.DownloadNetbootTree(ctx, imageId, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadNetbootTreeResponse(rsp)
+}
+
+// WriteNetbootTreeWithResponse request returning *WriteNetbootTreeResponse
+func (c *ClientWithResponses) WriteNetbootTreeWithResponse(ctx context.Context, imageId string, params *WriteNetbootTreeParams, reqEditors ...RequestEditorFn) (*WriteNetbootTreeResponse, error) {
+	rsp, err := c.WriteNetbootTree(ctx, imageId, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWriteNetbootTreeResponse(rsp)
+}
+
+// CreateNoCloudSeedWithBodyWithResponse request with arbitrary body returning *CreateNoCloudSeedResponse
+func (c *ClientWithResponses) CreateNoCloudSeedWithBodyWithResponse(ctx context.Context, imageId string, params *CreateNoCloudSeedParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*CreateNoCloudSeedResponse, error) {
+	rsp, err := c.CreateNoCloudSeedWithBody(ctx, imageId, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCreateNoCloudSeedResponse(rsp)
+}
+
+func (c *ClientWithResponses) CreateNoCloudSeedWithResponse(ctx context.Context, imageId string, params *CreateNoCloudSeedParams, body CreateNoCloudSeedJSONRequestBody, reqEditors ...RequestEditorFn) (*CreateNoCloudSeedResponse, error) {
+	rsp, err := c.CreateNoCloudSeed(ctx, imageId, params, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCreateNoCloudSeedResponse(rsp)
+}
+
+// DownloadOVAWithResponse request returning *DownloadOVAResponse
+func (c *ClientWithResponses) DownloadOVAWithResponse(ctx context.Context, imageId string, params *DownloadOVAParams, reqEditors ...RequestEditorFn) (*DownloadOVAResponse, error) {
+	rsp, err := c.DownloadOVA(ctx, imageId, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadOVAResponse(rsp)
+}
+
+// DownloadPXEBundleWithResponse request returning *DownloadPXEBundleResponse
+func (c *ClientWithResponses) DownloadPXEBundleWithResponse(ctx context.Context, imageId string, params *DownloadPXEBundleParams, reqEditors ...RequestEditorFn) (*DownloadPXEBundleResponse, error) {
+	rsp, err := c.DownloadPXEBundle(ctx, imageId, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadPXEBundleResponse(rsp)
+}
+
+// DownloadInitrdWithResponse request returning *DownloadInitrdResponse
+func (c *ClientWithResponses) DownloadInitrdWithResponse(ctx context.Context, imageId string, params *DownloadInitrdParams, reqEditors ...RequestEditorFn) (*DownloadInitrdResponse, error) {
+	rsp, err := c.DownloadInitrd(ctx, imageId, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadInitrdResponse(rsp)
+}
+
+// GetIPXEScriptWithResponse request returning *GetIPXEScriptResponse
+func (c *ClientWithResponses) GetIPXEScriptWithResponse(ctx context.Context, imageId string, params *GetIPXEScriptParams, reqEditors ...RequestEditorFn) (*GetIPXEScriptResponse, error) {
+	rsp, err := c.GetIPXEScript(ctx, imageId, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetIPXEScriptResponse(rsp)
+}
+
+// DownloadQcow2WithResponse request returning *DownloadQcow2Response
+func (c *ClientWithResponses) DownloadQcow2WithResponse(ctx context.Context, imageId string, params *DownloadQcow2Params, reqEditors ...RequestEditorFn) (*DownloadQcow2Response, error) {
+	rsp, err := c.DownloadQcow2(ctx, imageId, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadQcow2Response(rsp)
+}
+
+// DownloadRawImageWithResponse request returning *DownloadRawImageResponse
+func (c *ClientWithResponses) DownloadRawImageWithResponse(ctx context.Context, imageId string, params *DownloadRawImageParams, reqEditors ...RequestEditorFn) (*DownloadRawImageResponse, error) {
+	rsp, err := c.DownloadRawImage(ctx, imageId, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadRawImageResponse(rsp)
+}
+
+// DownloadS390xBootFilesWithResponse request returning *DownloadS390xBootFilesResponse
+func (c *ClientWithResponses) DownloadS390xBootFilesWithResponse(ctx context.Context, imageId string, params *DownloadS390xBootFilesParams, reqEditors ...RequestEditorFn) (*DownloadS390xBootFilesResponse, error) {
+	rsp, err := c.DownloadS390xBootFiles(ctx, imageId, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadS390xBootFilesResponse(rsp)
+}
+
+// DownloadInitrdAddrSizeWithResponse request returning *DownloadInitrdAddrSizeResponse
+func (c *ClientWithResponses) DownloadInitrdAddrSizeWithResponse(ctx context.Context, imageId string, params *DownloadInitrdAddrSizeParams, reqEditors ...RequestEditorFn) (*DownloadInitrdAddrSizeResponse, error) {
+	rsp, err := c.DownloadInitrdAddrSize(ctx, imageId, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadInitrdAddrSizeResponse(rsp)
+}
+
+// DownloadUKIWithResponse request returning *DownloadUKIResponse
+func (c *ClientWithResponses) DownloadUKIWithResponse(ctx context.Context, imageId string, params *DownloadUKIParams, reqEditors ...RequestEditorFn) (*DownloadUKIResponse, error) {
+	rsp, err := c.DownloadUKI(ctx, imageId, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDownloadUKIResponse(rsp)
+}
+
+// GetLivenessWithResponse request returning *GetLivenessResponse
+func (c *ClientWithResponses) GetLivenessWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetLivenessResponse, error) {
+	rsp, err := c.GetLiveness(ctx, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetLivenessResponse(rsp)
+}
+
+// GetOpenAPIWithResponse request returning *GetOpenAPIResponse
+func (c *ClientWithResponses) GetOpenAPIWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetOpenAPIResponse, error) {
+	rsp, err := c.GetOpenAPI(ctx, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetOpenAPIResponse(rsp)
+}
+
+// CustomizeImageWithBodyWithResponse request with arbitrary body returning *CustomizeImageResponse
+func (c *ClientWithResponses) CustomizeImageWithBodyWithResponse(ctx context.Context, imageId string, params *CustomizeImageParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*CustomizeImageResponse, error) {
+	rsp, err := c.CustomizeImageWithBody(ctx, imageId, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCustomizeImageResponse(rsp)
+}
+
+func (c *ClientWithResponses) CustomizeImageWithResponse(ctx context.Context, imageId string, params *CustomizeImageParams, body CustomizeImageJSONRequestBody, reqEditors ...RequestEditorFn) (*CustomizeImageResponse, error) {
+	rsp, err := c.CustomizeImage(ctx, imageId, params, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCustomizeImageResponse(rsp)
+}
+
+// ParseAddBaseISOResponse parses an HTTP response from a AddBaseISOWithResponse call
+func ParseAddBaseISOResponse(rsp *http.Response) (*AddBaseISOResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &AddBaseISOResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 201:
+		var dest BaseISOResponse
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON201 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseDownloadBootArtifactResponse parses an HTTP response from a DownloadBootArtifactWithResponse call
+func ParseDownloadBootArtifactResponse(rsp *http.Response) (*DownloadBootArtifactResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadBootArtifactResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest ChunkManifest
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case rsp.StatusCode == 200:
+		// Content-type (application/x-bittorrent) unsupported
+
+	}
+
+	return response, nil
+}
+
+// ParseCreateBuildResponse parses an HTTP response from a CreateBuildWithResponse call
+func ParseCreateBuildResponse(rsp *http.Response) (*CreateBuildResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CreateBuildResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 202:
+		var dest Build
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON202 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseGetBuildResponse parses an HTTP response from a GetBuildWithResponse call
+func ParseGetBuildResponse(rsp *http.Response) (*GetBuildResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetBuildResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest Build
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseDownloadBuildResponse parses an HTTP response from a DownloadBuildWithResponse call
+func ParseDownloadBuildResponse(rsp *http.Response) (*DownloadBuildResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadBuildResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseDownloadImageByAPIKeyResponse parses an HTTP response from a DownloadImageByAPIKeyWithResponse call
+func ParseDownloadImageByAPIKeyResponse(rsp *http.Response) (*DownloadImageByAPIKeyResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadImageByAPIKeyResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest ChunkManifest
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case rsp.StatusCode == 200:
+		// Content-type (application/x-bittorrent) unsupported
+
+	}
+
+	return response, nil
+}
+
+// ParseDownloadImageByIDResponse parses an HTTP response from a DownloadImageByIDWithResponse call
+func ParseDownloadImageByIDResponse(rsp *http.Response) (*DownloadImageByIDResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadImageByIDResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest ChunkManifest
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case rsp.StatusCode == 200:
+		// Content-type (application/x-bittorrent) unsupported
+
+	}
+
+	return response, nil
+}
+
+// ParseDownloadImageByTokenResponse parses an HTTP response from a DownloadImageByTokenWithResponse call
+func ParseDownloadImageByTokenResponse(rsp *http.Response) (*DownloadImageByTokenResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadImageByTokenResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest ChunkManifest
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case rsp.StatusCode == 200:
+		// Content-type (application/x-bittorrent) unsupported
+
+	}
+
+	return response, nil
+}
+
+// ParseGetHealthResponse parses an HTTP response from a GetHealthWithResponse call
+func ParseGetHealthResponse(rsp *http.Response) (*GetHealthResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetHealthResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseDownloadImageResponse parses an HTTP response from a DownloadImageWithResponse call
+func ParseDownloadImageResponse(rsp *http.Response) (*DownloadImageResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadImageResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest ChunkManifest
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case rsp.StatusCode == 200:
+		// Content-type (application/x-bittorrent) unsupported
+
+	}
+
+	return response, nil
+}
+
+// ParseDownloadConfigDriveResponse parses an HTTP response from a DownloadConfigDriveWithResponse call
+func ParseDownloadConfigDriveResponse(rsp *http.Response) (*DownloadConfigDriveResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadConfigDriveResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseDownloadNetbootTreeResponse parses an HTTP response from a DownloadNetbootTreeWithResponse call
+func ParseDownloadNetbootTreeResponse(rsp *http.Response) (*DownloadNetbootTreeResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadNetbootTreeResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseWriteNetbootTreeResponse parses an HTTP response from a WriteNetbootTreeWithResponse call
+func ParseWriteNetbootTreeResponse(rsp *http.Response) (*WriteNetbootTreeResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &WriteNetbootTreeResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseCreateNoCloudSeedResponse parses an HTTP response from a CreateNoCloudSeedWithResponse call
+func ParseCreateNoCloudSeedResponse(rsp *http.Response) (*CreateNoCloudSeedResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CreateNoCloudSeedResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseDownloadOVAResponse parses an HTTP response from a DownloadOVAWithResponse call
+func ParseDownloadOVAResponse(rsp *http.Response) (*DownloadOVAResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadOVAResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseDownloadPXEBundleResponse parses an HTTP response from a DownloadPXEBundleWithResponse call
+func ParseDownloadPXEBundleResponse(rsp *http.Response) (*DownloadPXEBundleResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadPXEBundleResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseDownloadInitrdResponse parses an HTTP response from a DownloadInitrdWithResponse call
+func ParseDownloadInitrdResponse(rsp *http.Response) (*DownloadInitrdResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadInitrdResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseGetIPXEScriptResponse parses an HTTP response from a GetIPXEScriptWithResponse call
+func ParseGetIPXEScriptResponse(rsp *http.Response) (*GetIPXEScriptResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetIPXEScriptResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseDownloadQcow2Response parses an HTTP response from a DownloadQcow2WithResponse call
+func ParseDownloadQcow2Response(rsp *http.Response) (*DownloadQcow2Response, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadQcow2Response{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseDownloadRawImageResponse parses an HTTP response from a DownloadRawImageWithResponse call
+func ParseDownloadRawImageResponse(rsp *http.Response) (*DownloadRawImageResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadRawImageResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseDownloadS390xBootFilesResponse parses an HTTP response from a DownloadS390xBootFilesWithResponse call
+func ParseDownloadS390xBootFilesResponse(rsp *http.Response) (*DownloadS390xBootFilesResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadS390xBootFilesResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseDownloadInitrdAddrSizeResponse parses an HTTP response from a DownloadInitrdAddrSizeWithResponse call
+func ParseDownloadInitrdAddrSizeResponse(rsp *http.Response) (*DownloadInitrdAddrSizeResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadInitrdAddrSizeResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseDownloadUKIResponse parses an HTTP response from a DownloadUKIWithResponse call
+func ParseDownloadUKIResponse(rsp *http.Response) (*DownloadUKIResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DownloadUKIResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseGetLivenessResponse parses an HTTP response from a GetLivenessWithResponse call
+func ParseGetLivenessResponse(rsp *http.Response) (*GetLivenessResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetLivenessResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseGetOpenAPIResponse parses an HTTP response from a GetOpenAPIWithResponse call
+func ParseGetOpenAPIResponse(rsp *http.Response) (*GetOpenAPIResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetOpenAPIResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest map[string]interface{}
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseCustomizeImageResponse parses an HTTP response from a CustomizeImageWithResponse call
+func ParseCustomizeImageResponse(rsp *http.Response) (*CustomizeImageResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CustomizeImageResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 202:
+		var dest Build
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON202 = &dest
+
+	}
+
+	return response, nil
+}
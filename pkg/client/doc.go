@@ -0,0 +1,7 @@
+// Package client is the Go client of the HTTP API of the image service, generated from the OpenAPI
+// document it serves at /openapi.json. The Authorization header of the requests can be set with a
+// RequestEditorFn.
+package client
+
+//go:generate go run ../../cmd/openapi openapi.json
+//go:generate oapi-codegen -generate types,client -package client -o client.gen.go openapi.json
@@ -0,0 +1,171 @@
+package diskeditor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	diskfs "github.com/diskfs/go-diskfs"
+	"github.com/diskfs/go-diskfs/partition/gpt"
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+	"github.com/pkg/errors"
+)
+
+const (
+	// RHCOS disk images read the ignition config from this path on the boot partition
+	bootPartitionLabel = "boot"
+	ignitionDir        = "/ignition"
+	ignitionConfigPath = ignitionDir + "/config.ign"
+)
+
+var qcow2Magic = []byte{'Q', 'F', 'I', 0xfb}
+
+//go:generate mockgen -package=diskeditor -destination=mock_editor.go . Editor
+type Editor interface {
+	EmbedIgnition(imagePath, outputPath string, ignition []byte) error
+}
+
+type editor struct {
+	workDir  string
+	executer isoeditor.Executer
+}
+
+// NewEditor returns an editor for RHCOS qcow2, raw and OVA disk images. qemu-img is used to
+// convert qcow2 images and debugfs to write to the ext4 boot partition.
+func NewEditor(workDir string, executer isoeditor.Executer) Editor {
+	return &editor{workDir: workDir, executer: executer}
+}
+
+// EmbedIgnition writes a copy of the disk image to outputPath with the ignition config
+// written to the boot partition, in the same format as the input image. The ignition of OVAs is
+// set in their OVF environment instead.
+func (e *editor) EmbedIgnition(imagePath, outputPath string, ignition []byte) error {
+	if isoeditor.IsButane(ignition) {
+		var err error
+		if ignition, err = isoeditor.ButaneToIgnition(ignition); err != nil {
+			return err
+		}
+	}
+	if err := isoeditor.ValidateIgnition(ignition); err != nil {
+		return err
+	}
+
+	ova, err := isOVA(imagePath)
+	if err != nil {
+		return err
+	}
+	if ova {
+		return embedIgnitionInOVA(imagePath, outputPath, ignition)
+	}
+
+	tmpDir, err := os.MkdirTemp(e.workDir, "diskeditor")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	isQcow2, err := isQcow2Image(imagePath)
+	if err != nil {
+		return err
+	}
+
+	rawPath := outputPath
+	if isQcow2 {
+		rawPath = filepath.Join(tmpDir, "image.raw")
+		if _, err = e.executer.Execute(fmt.Sprintf("qemu-img convert -f qcow2 -O raw %s %s", imagePath, rawPath), tmpDir); err != nil {
+			return errors.Wrap(err, "failed to convert qcow2 image to raw")
+		}
+	} else if err = copyFile(imagePath, rawPath); err != nil {
+		return err
+	}
+
+	ignitionPath := filepath.Join(tmpDir, "config.ign")
+	if err = os.WriteFile(ignitionPath, ignition, 0600); err != nil {
+		return err
+	}
+	if err = e.writeToBootPartition(rawPath, ignitionPath); err != nil {
+		return err
+	}
+
+	if isQcow2 {
+		if _, err = e.executer.Execute(fmt.Sprintf("qemu-img convert -f raw -O qcow2 %s %s", rawPath, outputPath), tmpDir); err != nil {
+			return errors.Wrap(err, "failed to convert raw image to qcow2")
+		}
+	}
+	return nil
+}
+
+func (e *editor) writeToBootPartition(rawPath, ignitionPath string) error {
+	offset, err := bootPartitionOffset(rawPath)
+	if err != nil {
+		return err
+	}
+	device := fmt.Sprintf("%s?offset=%d", rawPath, offset)
+
+	// mkdir fails when the directory already exists, which is fine as the write would then fail instead
+	_, _ = e.executer.Execute(fmt.Sprintf("debugfs -w -R 'mkdir %s' '%s'", ignitionDir, device), e.workDir)
+	_, _ = e.executer.Execute(fmt.Sprintf("debugfs -w -R 'rm %s' '%s'", ignitionConfigPath, device), e.workDir)
+	if _, err = e.executer.Execute(fmt.Sprintf("debugfs -w -R 'write %s %s' '%s'", ignitionPath, ignitionConfigPath, device), e.workDir); err != nil {
+		return errors.Wrap(err, "failed to write ignition to boot partition")
+	}
+	return nil
+}
+
+func bootPartitionOffset(rawPath string) (int64, error) {
+	d, err := diskfs.Open(rawPath, diskfs.WithOpenMode(diskfs.ReadOnly))
+	if err != nil {
+		return 0, err
+	}
+	defer d.File.Close()
+
+	table, err := d.GetPartitionTable()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read partition table")
+	}
+	gptTable, ok := table.(*gpt.Table)
+	if !ok {
+		return 0, fmt.Errorf("disk image %s doesn't have a GPT partition table", rawPath)
+	}
+	for _, p := range gptTable.Partitions {
+		if p.Name == bootPartitionLabel {
+			return p.GetStart(), nil
+		}
+	}
+	return 0, fmt.Errorf("no %s partition found in disk image %s", bootPartitionLabel, rawPath)
+}
+
+func isQcow2Image(imagePath string) (bool, error) {
+	f, err := os.Open(imagePath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(qcow2Magic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false, errors.Wrapf(err, "failed to read disk image %s", imagePath)
+	}
+	return bytes.Equal(magic, qcow2Magic), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	// disk images are mostly empty, keep them sparse
+	if _, err = overlay.WriteSparse(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
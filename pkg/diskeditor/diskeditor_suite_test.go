@@ -0,0 +1,13 @@
+package diskeditor
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDiskEditor(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "diskeditor")
+}
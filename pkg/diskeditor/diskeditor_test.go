@@ -0,0 +1,99 @@
+package diskeditor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	diskfs "github.com/diskfs/go-diskfs"
+	"github.com/diskfs/go-diskfs/partition/gpt"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+var _ = Describe("EmbedIgnition", func() {
+	const (
+		bootStartSector = 20480
+		bootEndSector   = 122879
+	)
+	var (
+		workDir   string
+		imagePath string
+		ignition  = []byte(`{"ignition": {"version": "3.2.0"}}`)
+	)
+
+	BeforeEach(func() {
+		var err error
+		workDir, err = os.MkdirTemp("", "diskeditor")
+		Expect(err).NotTo(HaveOccurred())
+
+		imagePath = filepath.Join(workDir, "rhcos.raw")
+		d, err := diskfs.Create(imagePath, 64*1024*1024, diskfs.Raw, diskfs.SectorSizeDefault)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Partition(&gpt.Table{Partitions: []*gpt.Partition{
+			{Start: 2048, End: bootStartSector - 1, Type: gpt.EFISystemPartition, Name: "EFI-SYSTEM"},
+			{Start: bootStartSector, End: bootEndSector, Type: gpt.LinuxFilesystem, Name: "boot"},
+		}})).To(Succeed())
+		Expect(d.File.Close()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(workDir)).To(Succeed())
+	})
+
+	It("writes the ignition to the boot partition of a raw image", func() {
+		if _, err := exec.LookPath("debugfs"); err != nil {
+			Skip("debugfs is not available")
+		}
+		offset := bootStartSector * 512
+		sizeKiB := (bootEndSector - bootStartSector + 1) / 2
+		mkfs := exec.Command("mkfs.ext4", "-q", "-F", "-L", "boot", "-E", fmt.Sprintf("offset=%d", offset), imagePath, fmt.Sprintf("%dk", sizeKiB))
+		Expect(mkfs.Run()).To(Succeed())
+
+		outputPath := filepath.Join(workDir, "output.raw")
+		editor := NewEditor(workDir, &isoeditor.CommonExecuter{})
+		Expect(editor.EmbedIgnition(imagePath, outputPath, ignition)).To(Succeed())
+
+		cat := exec.Command("debugfs", "-R", "cat "+ignitionConfigPath, fmt.Sprintf("%s?offset=%d", outputPath, offset))
+		content, err := cat.Output()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content).To(Equal(ignition))
+	})
+
+	It("converts qcow2 images", func() {
+		qcow2Path := filepath.Join(workDir, "rhcos.qcow2")
+		Expect(os.WriteFile(qcow2Path, append(qcow2Magic, 0, 0, 0, 3), 0600)).To(Succeed())
+
+		ctrl := gomock.NewController(GinkgoT())
+		defer ctrl.Finish()
+		executer := isoeditor.NewMockExecuter(ctrl)
+		executer.EXPECT().Execute(gomock.Any(), gomock.Any()).DoAndReturn(func(command, workDir string) (string, error) {
+			Expect(command).To(HavePrefix("qemu-img convert -f qcow2 -O raw " + qcow2Path))
+			return "", errors.New("qemu-img failed")
+		})
+
+		err := NewEditor(workDir, executer).EmbedIgnition(qcow2Path, filepath.Join(workDir, "output.qcow2"), ignition)
+		Expect(err).To(MatchError(ContainSubstring("failed to convert qcow2 image to raw")))
+	})
+
+	It("fails without a boot partition", func() {
+		d, err := diskfs.Open(imagePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Partition(&gpt.Table{Partitions: []*gpt.Partition{
+			{Start: 2048, End: bootEndSector, Type: gpt.LinuxFilesystem, Name: "root"},
+		}})).To(Succeed())
+		Expect(d.File.Close()).To(Succeed())
+
+		err = NewEditor(workDir, &isoeditor.CommonExecuter{}).EmbedIgnition(imagePath, filepath.Join(workDir, "output.raw"), ignition)
+		Expect(err).To(MatchError(ContainSubstring("no boot partition found")))
+	})
+
+	It("fails with an invalid ignition", func() {
+		err := NewEditor(workDir, &isoeditor.CommonExecuter{}).EmbedIgnition(imagePath, filepath.Join(workDir, "output.raw"), []byte(`{}`))
+		Expect(err).To(HaveOccurred())
+	})
+})
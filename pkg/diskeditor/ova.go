@@ -0,0 +1,192 @@
+package diskeditor
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// the ignition vmware provider reads these properties of the OVF environment
+	ignitionDataKey     = "guestinfo.ignition.config.data"
+	ignitionEncodingKey = "guestinfo.ignition.config.data.encoding"
+	guestInfoTransport  = "com.vmware.guestInfo"
+)
+
+var (
+	tarMagic             = []byte("ustar")
+	tarMagicOffset int64 = 257
+
+	valueAttrRegexp        = regexp.MustCompile(`\sovf:value="[^"]*"`)
+	hardwareSectionRegexp  = regexp.MustCompile(`<(?:ovf:)?VirtualHardwareSection\b[^>]*>`)
+	virtualSystemEndRegexp = regexp.MustCompile(`</(?:ovf:)?VirtualSystem>`)
+	manifestLineRegexp     = regexp.MustCompile(`^(SHA1|SHA256)\((.+)\)\s*=\s*[0-9a-fA-F]+$`)
+)
+
+func isOVA(imagePath string) (bool, error) {
+	f, err := os.Open(imagePath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(tarMagic))
+	if _, err := f.ReadAt(magic, tarMagicOffset); err != nil && err != io.EOF {
+		return false, errors.Wrapf(err, "failed to read disk image %s", imagePath)
+	}
+	return bytes.Equal(magic, tarMagic), nil
+}
+
+// embedIgnitionInOVA writes a copy of the OVA with the ignition set as the default value of the
+// guestinfo properties of its OVF, passed to the VM through the OVF environment. The manifest is
+// updated and the certificate, which no longer matches, is removed. The disks aren't modified.
+func embedIgnitionInOVA(ovaPath, outputPath string, ignition []byte) error {
+	in, err := os.Open(ovaPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	reader := tar.NewReader(in)
+	writer := tar.NewWriter(out)
+	var ovfName string
+	var ovf []byte
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read OVA %s", ovaPath)
+		}
+
+		switch path.Ext(header.Name) {
+		case ".ovf":
+			content, err := io.ReadAll(reader)
+			if err != nil {
+				return err
+			}
+			if ovf, err = setOVFIgnition(content, ignition); err != nil {
+				return err
+			}
+			ovfName = header.Name
+			if err = writeTarFile(writer, header, ovf); err != nil {
+				return err
+			}
+		case ".mf":
+			if ovf == nil {
+				return fmt.Errorf("the manifest of OVA %s precedes its OVF", ovaPath)
+			}
+			content, err := io.ReadAll(reader)
+			if err != nil {
+				return err
+			}
+			if err = writeTarFile(writer, header, updateManifest(content, ovfName, ovf)); err != nil {
+				return err
+			}
+		case ".cert":
+			continue
+		default:
+			if err = writer.WriteHeader(header); err != nil {
+				return err
+			}
+			if _, err = io.Copy(writer, reader); err != nil {
+				return err
+			}
+		}
+	}
+	if ovf == nil {
+		return fmt.Errorf("no OVF descriptor found in OVA %s", ovaPath)
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func writeTarFile(writer *tar.Writer, header *tar.Header, content []byte) error {
+	header.Size = int64(len(content))
+	if err := writer.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := writer.Write(content)
+	return err
+}
+
+// setOVFIgnition sets the base64 encoded ignition as the value of the ignition properties of the
+// OVF, adding them when it doesn't have them, and the guestinfo transport of the OVF environment
+func setOVFIgnition(ovf, ignition []byte) ([]byte, error) {
+	descriptor := string(ovf)
+	properties := []struct{ key, value string }{
+		{ignitionDataKey, base64.StdEncoding.EncodeToString(ignition)},
+		{ignitionEncodingKey, "base64"},
+	}
+	var missing []string
+	for _, p := range properties {
+		propertyRegexp := regexp.MustCompile(`<(?:ovf:)?Property\b[^>]*\sovf:key="` + regexp.QuoteMeta(p.key) + `"[^>]*>`)
+		element := propertyRegexp.FindString(descriptor)
+		if element == "" {
+			missing = append(missing, fmt.Sprintf(`<Property ovf:key="%s" ovf:type="string" ovf:userConfigurable="true" ovf:value="%s"/>`, p.key, p.value))
+			continue
+		}
+		value := fmt.Sprintf(` ovf:value="%s"`, p.value)
+		updated := valueAttrRegexp.ReplaceAllLiteralString(element, value)
+		if updated == element {
+			updated = strings.Replace(element, "Property", "Property"+value, 1)
+		}
+		descriptor = strings.Replace(descriptor, element, updated, 1)
+	}
+
+	if len(missing) > 0 {
+		loc := virtualSystemEndRegexp.FindStringIndex(descriptor)
+		if loc == nil {
+			return nil, fmt.Errorf("no VirtualSystem found in the OVF descriptor")
+		}
+		section := fmt.Sprintf("<ProductSection ovf:required=\"false\">\n<Info>Ignition</Info>\n%s\n</ProductSection>\n", strings.Join(missing, "\n"))
+		descriptor = descriptor[:loc[0]] + section + descriptor[loc[0]:]
+	}
+
+	hardwareSection := hardwareSectionRegexp.FindString(descriptor)
+	if hardwareSection != "" && !strings.Contains(hardwareSection, "ovf:transport=") {
+		withTransport := strings.TrimSuffix(hardwareSection, ">") + fmt.Sprintf(` ovf:transport="%s">`, guestInfoTransport)
+		descriptor = strings.Replace(descriptor, hardwareSection, withTransport, 1)
+	}
+	return []byte(descriptor), nil
+}
+
+// updateManifest updates the digest of the OVF in the manifest, with the algorithm it was listed with
+func updateManifest(manifest []byte, ovfName string, ovf []byte) []byte {
+	lines := strings.Split(string(manifest), "\n")
+	for i, line := range lines {
+		match := manifestLineRegexp.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil || match[2] != path.Base(ovfName) {
+			continue
+		}
+		var h hash.Hash
+		if match[1] == "SHA1" {
+			h = sha1.New() //nolint:gosec
+		} else {
+			h = sha256.New()
+		}
+		h.Write(ovf)
+		lines[i] = fmt.Sprintf("%s(%s)= %s", match[1], match[2], hex.EncodeToString(h.Sum(nil)))
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
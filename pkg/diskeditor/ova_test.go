@@ -0,0 +1,152 @@
+package diskeditor
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+var _ = Describe("EmbedIgnition in an OVA", func() {
+	const (
+		ovfWithProperties = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1">
+  <VirtualSystem ovf:id="rhcos">
+    <VirtualHardwareSection ovf:transport="com.vmware.guestInfo">
+      <Info>Virtual hardware requirements</Info>
+    </VirtualHardwareSection>
+    <ProductSection ovf:required="false">
+      <Info>Ignition</Info>
+      <Property ovf:userConfigurable="true" ovf:type="string" ovf:key="guestinfo.ignition.config.data" ovf:value="">
+        <Label>Ignition config data</Label>
+      </Property>
+      <Property ovf:userConfigurable="true" ovf:type="string" ovf:key="guestinfo.ignition.config.data.encoding">
+        <Label>Ignition config data encoding</Label>
+      </Property>
+    </ProductSection>
+  </VirtualSystem>
+</Envelope>
+`
+		ovfWithoutProperties = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1">
+  <VirtualSystem ovf:id="rhcos">
+    <VirtualHardwareSection>
+      <Info>Virtual hardware requirements</Info>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>
+`
+	)
+	var (
+		workDir  string
+		ovaPath  string
+		vmdk     = []byte("KDMVstreamoptimizeddisk")
+		ignition = []byte(`{"ignition": {"version": "3.2.0"}}`)
+	)
+
+	BeforeEach(func() {
+		var err error
+		workDir, err = os.MkdirTemp("", "diskeditor")
+		Expect(err).NotTo(HaveOccurred())
+		ovaPath = filepath.Join(workDir, "rhcos.ova")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(workDir)).To(Succeed())
+	})
+
+	writeOVA := func(ovf string) {
+		f, err := os.Create(ovaPath)
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+		w := tar.NewWriter(f)
+		vmdkSum := sha256.Sum256(vmdk)
+		ovfSum := sha256.Sum256([]byte(ovf))
+		manifest := fmt.Sprintf("SHA256(coreos.ovf)= %s\nSHA256(disk.vmdk)= %s\n", hex.EncodeToString(ovfSum[:]), hex.EncodeToString(vmdkSum[:]))
+		for _, file := range []struct {
+			name    string
+			content []byte
+		}{
+			{"coreos.ovf", []byte(ovf)},
+			{"coreos.mf", []byte(manifest)},
+			{"coreos.cert", []byte("certificate")},
+			{"disk.vmdk", vmdk},
+		} {
+			Expect(w.WriteHeader(&tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.content))})).To(Succeed())
+			_, err = w.Write(file.content)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(w.Close()).To(Succeed())
+	}
+
+	readOVA := func(path string) map[string][]byte {
+		f, err := os.Open(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+		files := map[string][]byte{}
+		r := tar.NewReader(f)
+		for {
+			header, err := r.Next()
+			if err == io.EOF {
+				return files
+			}
+			Expect(err).NotTo(HaveOccurred())
+			files[header.Name], err = io.ReadAll(r)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	}
+
+	embed := func() map[string][]byte {
+		outputPath := filepath.Join(workDir, "output.ova")
+		editor := NewEditor(workDir, &isoeditor.CommonExecuter{})
+		Expect(editor.EmbedIgnition(ovaPath, outputPath, ignition)).To(Succeed())
+		return readOVA(outputPath)
+	}
+
+	encodedIgnition := base64.StdEncoding.EncodeToString(ignition)
+
+	It("sets the ignition properties of the OVF", func() {
+		writeOVA(ovfWithProperties)
+		files := embed()
+
+		ovf := string(files["coreos.ovf"])
+		Expect(ovf).To(ContainSubstring(fmt.Sprintf(`ovf:key="guestinfo.ignition.config.data" ovf:value="%s">`, encodedIgnition)))
+		Expect(ovf).To(ContainSubstring(`<Property ovf:value="base64" ovf:userConfigurable="true" ovf:type="string" ovf:key="guestinfo.ignition.config.data.encoding">`))
+		Expect(files["disk.vmdk"]).To(Equal(vmdk))
+		Expect(files).NotTo(HaveKey("coreos.cert"))
+
+		ovfSum := sha256.Sum256(files["coreos.ovf"])
+		Expect(string(files["coreos.mf"])).To(HavePrefix(fmt.Sprintf("SHA256(coreos.ovf)= %s\n", hex.EncodeToString(ovfSum[:]))))
+	})
+
+	It("adds the ignition properties and the guestinfo transport", func() {
+		writeOVA(ovfWithoutProperties)
+		ovf := string(embed()["coreos.ovf"])
+		Expect(ovf).To(ContainSubstring(`<VirtualHardwareSection ovf:transport="com.vmware.guestInfo">`))
+		Expect(ovf).To(ContainSubstring(fmt.Sprintf(`<Property ovf:key="guestinfo.ignition.config.data" ovf:type="string" ovf:userConfigurable="true" ovf:value="%s"/>`, encodedIgnition)))
+		Expect(ovf).To(ContainSubstring(`ovf:key="guestinfo.ignition.config.data.encoding" ovf:type="string" ovf:userConfigurable="true" ovf:value="base64"/>`))
+		Expect(ovf).To(MatchRegexp(`</ProductSection>\n</VirtualSystem>`))
+	})
+
+	It("fails without an OVF descriptor", func() {
+		f, err := os.Create(ovaPath)
+		Expect(err).NotTo(HaveOccurred())
+		w := tar.NewWriter(f)
+		Expect(w.WriteHeader(&tar.Header{Name: "disk.vmdk", Mode: 0644, Size: int64(len(vmdk))})).To(Succeed())
+		_, err = w.Write(vmdk)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Close()).To(Succeed())
+		Expect(f.Close()).To(Succeed())
+
+		editor := NewEditor(workDir, &isoeditor.CommonExecuter{})
+		Expect(editor.EmbedIgnition(ovaPath, filepath.Join(workDir, "output.ova"), ignition)).To(MatchError(ContainSubstring("no OVF descriptor")))
+	})
+})
@@ -0,0 +1,238 @@
+package imagestore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/google/renameio"
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+	"github.com/openshift/assisted-image-service/pkg/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// baseISOsFile is the file of the data directory persisting the versions added with AddBaseISO
+const baseISOsFile = "base-isos.json"
+
+var (
+	// ErrInvalidBaseISO is returned by AddBaseISO when the ISO can't be customized
+	ErrInvalidBaseISO = errors.New("invalid base ISO")
+	// ErrVersionExists is returned by AddBaseISO when the version is already configured
+	ErrVersionExists = errors.New("version already exists")
+
+	baseISOVersionRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// AddBaseISO registers the ISO uploaded as iso, or downloaded from isoURL when it isn't empty,
+// as the version openshiftVersion of arch, after checking that it can be customized. It returns
+// the version of the image, derived from its checksum.
+func (s *rhcosStore) AddBaseISO(ctx context.Context, openshiftVersion, arch, isoURL string, iso io.Reader) (string, error) {
+	if !baseISOVersionRegexp.MatchString(openshiftVersion) {
+		return "", fmt.Errorf("%w: version %q must only contain letters, digits, '.', '_' and '-'", ErrInvalidBaseISO, openshiftVersion)
+	}
+	if s.versionEntry(openshiftVersion, arch) != nil {
+		return "", fmt.Errorf("%w: %s-%s", ErrVersionExists, openshiftVersion, arch)
+	}
+	if isoURL != "" && registry.IsReference(isoURL) {
+		if _, err := registry.ParseReference(isoURL); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidBaseISO, err)
+		}
+	}
+
+	// Prune would remove the files of the ISO before it is registered
+	s.pruneLock.RLock()
+	defer s.pruneLock.RUnlock()
+
+	tmp, err := os.CreateTemp(s.dataDir, ".base-iso-*")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+	if isoURL == "" {
+		_, err = io.Copy(tmp, iso)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to receive the ISO: %w", err)
+	}
+	if isoURL != "" {
+		defer os.Remove(tmpPath + partialSuffix)
+		defer os.Remove(tmpPath + stateSuffix)
+		if err := s.downloadISO(ctx, isoURL, arch, tmpPath); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidBaseISO, err)
+		}
+	}
+
+	if err := validateBaseISO(tmpPath, arch); err != nil {
+		return "", err
+	}
+	sha256sum, err := fileSHA256(tmpPath)
+	if err != nil {
+		return "", err
+	}
+	entry := map[string]string{
+		"openshift_version": openshiftVersion,
+		"cpu_architecture":  arch,
+		"version":           "custom-" + sha256sum[:12],
+		"url":               isoURL,
+		"sha256":            sha256sum,
+	}
+	fullPath := filepath.Join(s.dataDir, versionKey(entry))
+	if err := os.Rename(tmpPath, fullPath); err != nil {
+		return "", err
+	}
+	if err := s.addVersion(entry); err != nil {
+		os.Remove(fullPath)
+		return "", err
+	}
+	cacheFileOffsets(fullPath)
+	s.markUsed(entry)
+
+	if err := s.publishToObjectStore(ctx, "", fullPath); err == nil {
+		err = s.populateMinimalISO(ctx, entry)
+	}
+	if err != nil {
+		s.removeVersion(entry)
+		for _, name := range versionFiles(entry) {
+			os.Remove(filepath.Join(s.dataDir, name))
+		}
+		return "", err
+	}
+	log.Infof("Added base ISO %s-%s (%s)", openshiftVersion, arch, entry["version"])
+	return entry["version"], nil
+}
+
+// validateBaseISO checks that the ISO is of arch, and has the boot files and embed areas
+// required by the customizations
+func validateBaseISO(path, arch string) error {
+	info, err := isoeditor.Inspect(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBaseISO, err)
+	}
+	if isoeditor.NormalizeArchitecture(info.Architecture) != isoeditor.NormalizeArchitecture(arch) {
+		return fmt.Errorf("%w: the ISO is for %s, not %s", ErrInvalidBaseISO, info.Architecture, arch)
+	}
+	if _, err := isoeditor.ExtractBootArtifacts(path); err != nil {
+		return fmt.Errorf("%w: missing boot files: %v", ErrInvalidBaseISO, err)
+	}
+	if info.IgnitionArea == nil {
+		return fmt.Errorf("%w: missing the ignition embed area", ErrInvalidBaseISO)
+	}
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// addVersion adds the version of a base ISO and persists the base ISOs
+func (s *rhcosStore) addVersion(entry map[string]string) error {
+	s.versionsLock.Lock()
+	defer s.versionsLock.Unlock()
+	for _, existing := range s.versions {
+		if existing["openshift_version"] == entry["openshift_version"] && existing["cpu_architecture"] == entry["cpu_architecture"] {
+			return fmt.Errorf("%w: %s-%s", ErrVersionExists, entry["openshift_version"], entry["cpu_architecture"])
+		}
+	}
+	baseISOs := append(append([]map[string]string{}, s.baseISOs...), entry)
+	if err := s.saveBaseISOs(baseISOs); err != nil {
+		return err
+	}
+	s.versions = append(append([]map[string]string{}, s.versions...), entry)
+	s.baseISOs = baseISOs
+	return nil
+}
+
+// removeVersion removes the version of a base ISO that couldn't be populated
+func (s *rhcosStore) removeVersion(entry map[string]string) {
+	s.versionsLock.Lock()
+	defer s.versionsLock.Unlock()
+	remove := func(versions []map[string]string) []map[string]string {
+		var kept []map[string]string
+		for _, existing := range versions {
+			if versionKey(existing) != versionKey(entry) {
+				kept = append(kept, existing)
+			}
+		}
+		return kept
+	}
+	s.versions = remove(s.versions)
+	s.baseISOs = remove(s.baseISOs)
+	if err := s.saveBaseISOs(s.baseISOs); err != nil {
+		log.WithError(err).Errorf("Failed to remove base ISO %s", versionKey(entry))
+	}
+}
+
+func (s *rhcosStore) saveBaseISOs(baseISOs []map[string]string) error {
+	data, err := json.Marshal(baseISOs)
+	if err != nil {
+		return err
+	}
+	return renameio.WriteFile(filepath.Join(s.dataDir, baseISOsFile), data, 0600)
+}
+
+// loadBaseISOs adds the base ISOs persisted in the data directory to the versions. The uploaded
+// ones whose ISO is lost, which can't be downloaded again, are dropped.
+func (s *rhcosStore) loadBaseISOs() error {
+	data, err := os.ReadFile(filepath.Join(s.dataDir, baseISOsFile))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var baseISOs []map[string]string
+	if err := json.Unmarshal(data, &baseISOs); err != nil {
+		return fmt.Errorf("invalid %s: %w", baseISOsFile, err)
+	}
+
+	s.versionsLock.Lock()
+	defer s.versionsLock.Unlock()
+	versions := append([]map[string]string{}, s.versions...)
+	var loaded []map[string]string
+	for _, entry := range baseISOs {
+		duplicate := false
+		for _, existing := range versions {
+			if existing["openshift_version"] == entry["openshift_version"] && existing["cpu_architecture"] == entry["cpu_architecture"] {
+				duplicate = true
+			}
+		}
+		if duplicate {
+			log.Warnf("Ignoring base ISO %s-%s, the version is configured", entry["openshift_version"], entry["cpu_architecture"])
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(s.dataDir, versionKey(entry))); err != nil && !s.restorable(entry) {
+			log.Warnf("Dropping base ISO %s-%s, its ISO is missing", entry["openshift_version"], entry["cpu_architecture"])
+			continue
+		}
+		versions = append(versions, entry)
+		loaded = append(loaded, entry)
+	}
+	s.versions = versions
+	s.baseISOs = loaded
+	return nil
+}
+
+// restorable returns true when the images of the version can be populated again after they
+// are removed from the data directory
+func (s *rhcosStore) restorable(entry map[string]string) bool {
+	return entry["url"] != "" || entry[mirrorURLsKey] != "" || s.objectStore != nil
+}
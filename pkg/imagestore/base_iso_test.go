@@ -0,0 +1,163 @@
+package imagestore
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("AddBaseISO", func() {
+	var (
+		dataDir  string
+		isoPath  string
+		versions []map[string]string
+		ctx      = context.Background()
+	)
+
+	// createBaseISO creates an s390x live ISO, without the ignition embed area if noIgnition
+	createBaseISO := func(noIgnition bool) string {
+		filesDir, err := os.MkdirTemp("", "baseISOFiles")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(filesDir)
+		Expect(os.MkdirAll(filepath.Join(filesDir, "images/pxeboot"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(filesDir, "generic.ins"), []byte("ins"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(filesDir, "images/pxeboot/kernel.img"), []byte("kernel"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(filesDir, "images/pxeboot/initrd.img"), []byte("initrd"), 0600)).To(Succeed())
+		if !noIgnition {
+			Expect(os.WriteFile(filepath.Join(filesDir, "images/ignition.img"), make([]byte, 256*1024), 0600)).To(Succeed())
+		}
+		path := dataDir + "-base.iso"
+		if noIgnition {
+			path = dataDir + "-no-ignition.iso"
+		}
+		Expect(exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", "rhcos-custom", "-o", path, filesDir).Run()).To(Succeed())
+		return path
+	}
+
+	newStore := func() *rhcosStore {
+		is, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: versions})
+		Expect(err).NotTo(HaveOccurred())
+		return is.(*rhcosStore)
+	}
+
+	addUpload := func(store *rhcosStore, openshiftVersion, arch, path string) (string, error) {
+		content, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		return store.AddBaseISO(ctx, openshiftVersion, arch, "", bytes.NewReader(content))
+	}
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = os.MkdirTemp("", "baseISOTest")
+		Expect(err).NotTo(HaveOccurred())
+		isoPath = createBaseISO(false)
+
+		// the configured version is already downloaded
+		versions = []map[string]string{{
+			"openshift_version": "4.8",
+			"cpu_architecture":  "s390x",
+			"version":           "48.84.202109241901-0",
+			"url":               "http://example.com/rhcos.iso",
+		}}
+		isoContent := make([]byte, 32840)
+		copy(isoContent[32808:], "rhcos-411.86.202210041459-0")
+		Expect(os.WriteFile(filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-s390x.iso"), isoContent, 0600)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dataDir)
+		os.Remove(dataDir + "-base.iso")
+		os.Remove(dataDir + "-no-ignition.iso")
+	})
+
+	It("adds an uploaded ISO as a version", func() {
+		store := newStore()
+		version, err := addUpload(store, "custom-4.16", "s390x", isoPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(HavePrefix("custom-"))
+
+		Expect(store.HaveVersion("custom-4.16", "s390x")).To(BeTrue())
+		Expect(store.Available("custom-4.16", "s390x")).To(BeTrue())
+		content, err := os.ReadFile(isoPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.ReadFile(store.PathForParams(ImageTypeFull, "custom-4.16", "s390x"))).To(Equal(content))
+	})
+
+	It("keeps the added versions across restarts", func() {
+		_, err := addUpload(newStore(), "custom-4.16", "s390x", isoPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		store := newStore()
+		Expect(store.Populate(ctx)).To(Succeed())
+		Expect(store.HaveVersion("custom-4.16", "s390x")).To(BeTrue())
+		Expect(store.PathForParams(ImageTypeFull, "custom-4.16", "s390x")).To(BeAnExistingFile())
+	})
+
+	It("never prunes the uploaded ISOs", func() {
+		store := newStore()
+		_, err := addUpload(store, "custom-4.16", "s390x", isoPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Prune(1, false)).To(Succeed())
+		Expect(store.PathForParams(ImageTypeFull, "custom-4.16", "s390x")).To(BeAnExistingFile())
+		Expect(filepath.Join(dataDir, baseISOsFile)).To(BeAnExistingFile())
+	})
+
+	It("downloads the ISO from a URL", func() {
+		content, err := os.ReadFile(isoPath)
+		Expect(err).NotTo(HaveOccurred())
+		ts := ghttp.NewServer()
+		defer ts.Close()
+		ts.RouteToHandler("GET", "/custom.iso", ghttp.RespondWith(http.StatusOK, content,
+			http.Header{"Content-Length": []string{strconv.Itoa(len(content))}}))
+
+		store := newStore()
+		_, err = store.AddBaseISO(ctx, "custom-4.16", "s390x", ts.URL()+"/custom.iso", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.ReadFile(store.PathForParams(ImageTypeFull, "custom-4.16", "s390x"))).To(Equal(content))
+		Expect(store.versionEntry("custom-4.16", "s390x")["url"]).To(Equal(ts.URL() + "/custom.iso"))
+	})
+
+	It("rejects the ISOs of another architecture", func() {
+		_, err := addUpload(newStore(), "custom-4.16", "x86_64", isoPath)
+		Expect(err).To(MatchError(ErrInvalidBaseISO))
+	})
+
+	It("rejects the ISOs without the ignition embed area", func() {
+		_, err := addUpload(newStore(), "custom-4.16", "s390x", createBaseISO(true))
+		Expect(err).To(MatchError(ContainSubstring("missing the ignition embed area")))
+		Expect(err).To(MatchError(ErrInvalidBaseISO))
+	})
+
+	It("rejects the files that aren't ISOs", func() {
+		store := newStore()
+		_, err := store.AddBaseISO(ctx, "custom-4.16", "s390x", "", bytes.NewReader([]byte("not an ISO")))
+		Expect(err).To(MatchError(ErrInvalidBaseISO))
+		Expect(store.HaveVersion("custom-4.16", "s390x")).To(BeFalse())
+		entries, err := os.ReadDir(dataDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("rejects the existing versions", func() {
+		store := newStore()
+		_, err := addUpload(store, "4.8", "s390x", isoPath)
+		Expect(err).To(MatchError(ErrVersionExists))
+		_, err = addUpload(store, "custom-4.16", "s390x", isoPath)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = addUpload(store, "custom-4.16", "s390x", isoPath)
+		Expect(err).To(MatchError(ErrVersionExists))
+	})
+
+	It("rejects the versions that aren't file name safe", func() {
+		_, err := addUpload(newStore(), "../4.16", "s390x", isoPath)
+		Expect(err).To(MatchError(ErrInvalidBaseISO))
+	})
+})
@@ -0,0 +1,68 @@
+package imagestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// channelURLKey is the version key of the CoreOS stream metadata (stream.json) the url, version
+// and sha256 of the version are resolved from, e.g. to track nightly builds
+const channelURLKey = "channel_url"
+
+// streamMetadata is the part of the CoreOS stream metadata describing the live ISOs
+type streamMetadata struct {
+	Stream        string `json:"stream"`
+	Architectures map[string]struct {
+		Artifacts map[string]struct {
+			Release string `json:"release"`
+			Formats map[string]map[string]struct {
+				Location string `json:"location"`
+				Sha256   string `json:"sha256"`
+			} `json:"formats"`
+		} `json:"artifacts"`
+	} `json:"architectures"`
+}
+
+// streamArchitecture returns the architecture of the stream metadata of the cpu architecture of
+// the versions
+func streamArchitecture(arch string) string {
+	if arch == "arm64" {
+		return "aarch64"
+	}
+	return arch
+}
+
+// resolveChannels sets the url, version and sha256 of the versions with a channel to the latest
+// build of the channel
+func (s *rhcosStore) resolveChannels(ctx context.Context) error {
+	for _, entry := range s.configuredVersions() {
+		channelURL := entry[channelURLKey]
+		if channelURL == "" {
+			continue
+		}
+		data, err := s.downloadSmallFile(ctx, channelURL)
+		if err != nil {
+			return fmt.Errorf("failed to fetch channel %s: %w", channelURL, err)
+		}
+		stream := &streamMetadata{}
+		if err := json.Unmarshal(data, stream); err != nil {
+			return fmt.Errorf("invalid stream metadata %s: %w", channelURL, err)
+		}
+		arch := streamArchitecture(entry["cpu_architecture"])
+		metal := stream.Architectures[arch].Artifacts["metal"]
+		iso := metal.Formats["iso"]["disk"]
+		if metal.Release == "" || iso.Location == "" {
+			return fmt.Errorf("channel %s has no live ISO for %s", channelURL, arch)
+		}
+		if entry["version"] != metal.Release {
+			log.Infof("Resolved %s-%s of channel %s to build %s", entry["openshift_version"], entry["cpu_architecture"], channelURL, metal.Release)
+		}
+		entry["version"] = metal.Release
+		entry["url"] = iso.Location
+		entry["sha256"] = iso.Sha256
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package imagestore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("channels", func() {
+	var (
+		dataDir    string
+		ts         *ghttp.Server
+		isoContent []byte
+		version    map[string]string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = os.MkdirTemp("", "channelTest")
+		Expect(err).NotTo(HaveOccurred())
+
+		isoContent = make([]byte, 32840)
+		copy(isoContent[32808:], "rhcos-411.86.202210041459-0")
+		ts = ghttp.NewServer()
+		ts.RouteToHandler("GET", "/rhcos-live.s390x.iso", ghttp.RespondWith(http.StatusOK, isoContent,
+			http.Header{"Content-Length": []string{strconv.Itoa(len(isoContent))}}))
+		version = map[string]string{
+			"openshift_version": "4.16",
+			"cpu_architecture":  "s390x",
+			channelURLKey:       ts.URL() + "/stream.json",
+		}
+	})
+
+	AfterEach(func() {
+		ts.Close()
+		os.RemoveAll(dataDir)
+	})
+
+	serveStream := func(release, sha256 string) {
+		ts.RouteToHandler("GET", "/stream.json", ghttp.RespondWith(http.StatusOK, fmt.Sprintf(`{
+			"stream": "4.16-nightly",
+			"architectures": {"s390x": {"artifacts": {"metal": {
+				"release": "%s",
+				"formats": {"iso": {"disk": {"location": "%s/rhcos-live.s390x.iso", "sha256": "%s"}}}
+			}}}}
+		}`, release, ts.URL(), sha256)))
+	}
+
+	populate := func() error {
+		is, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}})
+		Expect(err).NotTo(HaveOccurred())
+		return is.Populate(context.Background())
+	}
+
+	It("downloads the latest build of the channel", func() {
+		serveStream("416.94.202405291527-0", sha256Of(isoContent))
+		Expect(populate()).To(Succeed())
+		Expect(version["version"]).To(Equal("416.94.202405291527-0"))
+		Expect(os.ReadFile(filepath.Join(dataDir, "rhcos-full-iso-4.16-416.94.202405291527-0-s390x.iso"))).To(Equal(isoContent))
+
+		serveStream("416.94.202406041004-0", sha256Of(isoContent))
+		Expect(populate()).To(Succeed())
+		Expect(filepath.Join(dataDir, "rhcos-full-iso-4.16-416.94.202406041004-0-s390x.iso")).To(BeAnExistingFile())
+		Expect(filepath.Join(dataDir, "rhcos-full-iso-4.16-416.94.202405291527-0-s390x.iso")).NotTo(BeAnExistingFile())
+	})
+
+	It("verifies the checksum of the channel", func() {
+		serveStream("416.94.202405291527-0", sha256Of([]byte("other")))
+		Expect(populate()).To(MatchError(ContainSubstring("doesn't match the expected")))
+	})
+
+	It("fails when the channel has no ISO for the architecture", func() {
+		version["cpu_architecture"] = "x86_64"
+		serveStream("416.94.202405291527-0", sha256Of(isoContent))
+		Expect(populate()).To(MatchError(ContainSubstring("has no live ISO for x86_64")))
+	})
+
+	It("maps the architectures of the versions to the ones of the streams", func() {
+		Expect(streamArchitecture("arm64")).To(Equal("aarch64"))
+		Expect(streamArchitecture("x86_64")).To(Equal("x86_64"))
+	})
+})
@@ -0,0 +1,82 @@
+package imagestore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/openshift/assisted-image-service/pkg/registry"
+	log "github.com/sirupsen/logrus"
+)
+
+// upstreamChecksumsName is the checksums file published next to the RHCOS ISOs
+const upstreamChecksumsName = "sha256sum.txt"
+
+// findChecksum returns the sha256 of the file name in the sha256sum output checksums, empty if
+// it isn't listed
+func findChecksum(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		// sha256sum lines are "<sha256>  <name>", or "<sha256> *<name>" in binary mode
+		sum, file, ok := strings.Cut(scanner.Text(), " ")
+		file = strings.TrimPrefix(strings.TrimLeft(file, " "), "*")
+		if ok && path.Base(file) == name {
+			if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != 32 {
+				return "", fmt.Errorf("invalid sha256 of %s", name)
+			}
+			return strings.ToLower(sum), nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// upstreamChecksumsURL returns the sha256sum_url of the image, or else the checksums file next to
+// its ISO. It is empty for registry references, which are verified by their digests.
+func upstreamChecksumsURL(imageInfo map[string]string) string {
+	if checksumsURL := imageInfo["sha256sum_url"]; checksumsURL != "" {
+		return checksumsURL
+	}
+	if registry.IsReference(imageInfo["url"]) {
+		return ""
+	}
+	u, err := url.Parse(imageInfo["url"])
+	if err != nil {
+		return ""
+	}
+	u.Path = path.Join(path.Dir(u.Path), upstreamChecksumsName)
+	u.RawQuery = ""
+	return u.String()
+}
+
+// upstreamChecksum returns the sha256 of the ISO of the image listed in its checksums file, empty
+// when the file is unavailable or doesn't list the ISO, so that images published without
+// checksums are still downloaded
+func (s *rhcosStore) upstreamChecksum(ctx context.Context, imageInfo map[string]string) string {
+	checksumsURL := upstreamChecksumsURL(imageInfo)
+	if checksumsURL == "" {
+		return ""
+	}
+	name, err := isoName(imageInfo["url"])
+	if err != nil {
+		return ""
+	}
+	checksums, err := s.downloadSmallFile(ctx, checksumsURL)
+	if err != nil {
+		log.WithError(err).Infof("No checksums to verify %s", imageInfo["url"])
+		return ""
+	}
+	sum, err := findChecksum(checksums, name)
+	if err != nil {
+		log.WithError(err).Warnf("Ignoring the invalid checksums %s", checksumsURL)
+		return ""
+	}
+	if sum == "" {
+		log.Infof("%s doesn't list %s", checksumsURL, name)
+	}
+	return sum
+}
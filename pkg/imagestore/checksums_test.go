@@ -0,0 +1,103 @@
+package imagestore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+func sha256Of(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+var _ = Describe("upstream checksums", func() {
+	It("finds the checksum of a file", func() {
+		checksums := []byte(fmt.Sprintf("%s  rhcos-live.x86_64.iso\n%s *images/rhcos-live.s390x.iso\n", sha256Of([]byte("a")), sha256Of([]byte("b"))))
+		Expect(findChecksum(checksums, "rhcos-live.s390x.iso")).To(Equal(sha256Of([]byte("b"))))
+		Expect(findChecksum(checksums, "rhcos-live.aarch64.iso")).To(BeEmpty())
+		_, err := findChecksum([]byte("abc  rhcos-live.x86_64.iso\n"), "rhcos-live.x86_64.iso")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("looks for the checksums next to the ISO", func() {
+		Expect(upstreamChecksumsURL(map[string]string{"url": "https://mirror.example.com/rhcos/4.8/rhcos-live.x86_64.iso?token=abc"})).To(
+			Equal("https://mirror.example.com/rhcos/4.8/sha256sum.txt"))
+		Expect(upstreamChecksumsURL(map[string]string{"url": "https://mirror.example.com/rhcos.iso", "sha256sum_url": "https://example.com/sums"})).To(
+			Equal("https://example.com/sums"))
+		Expect(upstreamChecksumsURL(map[string]string{"url": "oci://quay.io/org/rhcos:4.8"})).To(BeEmpty())
+	})
+
+	Context("Populate", func() {
+		var (
+			dataDir    string
+			ts         *ghttp.Server
+			isoContent []byte
+			version    map[string]string
+			fullISO    string
+		)
+
+		BeforeEach(func() {
+			var err error
+			dataDir, err = os.MkdirTemp("", "checksumsTest")
+			Expect(err).NotTo(HaveOccurred())
+			fullISO = filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-s390x.iso")
+
+			isoContent = make([]byte, 32840)
+			copy(isoContent[32808:], "rhcos-411.86.202210041459-0")
+			ts = ghttp.NewServer()
+			ts.RouteToHandler("GET", "/4.8/rhcos-live.s390x.iso", ghttp.RespondWith(http.StatusOK, isoContent,
+				http.Header{"Content-Length": []string{strconv.Itoa(len(isoContent))}}))
+			version = map[string]string{
+				"openshift_version": "4.8",
+				"cpu_architecture":  "s390x",
+				"version":           "48.84.202109241901-0",
+				"url":               ts.URL() + "/4.8/rhcos-live.s390x.iso",
+			}
+		})
+
+		AfterEach(func() {
+			ts.Close()
+			os.RemoveAll(dataDir)
+		})
+
+		populate := func() error {
+			is, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}})
+			Expect(err).NotTo(HaveOccurred())
+			return is.Populate(context.Background())
+		}
+
+		It("admits the ISOs matching the upstream checksums", func() {
+			ts.RouteToHandler("GET", "/4.8/sha256sum.txt", ghttp.RespondWith(http.StatusOK, sha256Of(isoContent)+"  rhcos-live.s390x.iso\n"))
+			Expect(populate()).To(Succeed())
+			Expect(os.ReadFile(fullISO)).To(Equal(isoContent))
+		})
+
+		It("rejects the ISOs that don't match the upstream checksums", func() {
+			ts.RouteToHandler("GET", "/4.8/sha256sum.txt", ghttp.RespondWith(http.StatusOK, sha256Of([]byte("other"))+"  rhcos-live.s390x.iso\n"))
+			Expect(populate()).To(MatchError(ContainSubstring("doesn't match the expected")))
+			Expect(fullISO).NotTo(BeAnExistingFile())
+		})
+
+		It("admits the ISOs without upstream checksums", func() {
+			ts.RouteToHandler("GET", "/4.8/sha256sum.txt", ghttp.RespondWith(http.StatusNotFound, nil))
+			Expect(populate()).To(Succeed())
+			Expect(os.ReadFile(fullISO)).To(Equal(isoContent))
+		})
+
+		It("prefers the configured checksum", func() {
+			version["sha256"] = sha256Of(isoContent)
+			Expect(populate()).To(Succeed())
+			Expect(ts.ReceivedRequests()).To(HaveLen(1))
+		})
+	})
+})
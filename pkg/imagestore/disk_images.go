@@ -0,0 +1,155 @@
+package imagestore
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/renameio"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ImageTypeQcow2 is the RHCOS qcow2 disk image of a version, downloaded from the qcow2_url of
+	// the version when it has one
+	ImageTypeQcow2 = "qcow2"
+	// ImageTypeRaw is the RHCOS metal raw disk image of a version, downloaded from the raw_url of
+	// the version when it has one
+	ImageTypeRaw = "raw"
+	// ImageTypeOVA is the RHCOS vmware OVA of a version, downloaded from the ova_url of the version
+	// when it has one
+	ImageTypeOVA = "ova"
+)
+
+// diskImageTypes are the disk images a version may have, each downloaded from the <type>_url of
+// the version, gunzipped when the URL ends with .gz, and checked against its <type>_sha256
+var diskImageTypes = []string{ImageTypeQcow2, ImageTypeRaw, ImageTypeOVA}
+
+// compressedSuffix is the suffix of the URLs of the gzip compressed disk images
+const compressedSuffix = ".gz"
+
+func isDiskImageType(imageType string) bool {
+	for _, t := range diskImageTypes {
+		if t == imageType {
+			return true
+		}
+	}
+	return false
+}
+
+func diskImageSHA256Keys() []string {
+	keys := make([]string, len(diskImageTypes))
+	for i, imageType := range diskImageTypes {
+		keys[i] = imageType + "_sha256"
+	}
+	return keys
+}
+
+func diskImageFileName(imageType, openshiftVersion, version, arch string) string {
+	return fmt.Sprintf("rhcos-%s-%s-%s-%s.%s", imageType, openshiftVersion, version, arch, imageType)
+}
+
+// diskImageFiles returns the names of the files of the disk images of the version
+func diskImageFiles(entry map[string]string) []string {
+	var files []string
+	for _, imageType := range diskImageTypes {
+		name := diskImageFileName(imageType, entry["openshift_version"], entry["version"], entry["cpu_architecture"])
+		files = append(files, name, name+compressedSuffix, name+compressedSuffix+partialSuffix, name+compressedSuffix+stateSuffix)
+	}
+	return files
+}
+
+// populateDiskImages downloads the missing disk images of the version
+func (s *rhcosStore) populateDiskImages(ctx context.Context, imageInfo map[string]string) error {
+	for _, imageType := range diskImageTypes {
+		imageURL := imageInfo[imageType+"_url"]
+		if imageURL == "" {
+			continue
+		}
+		path := filepath.Join(s.dataDir, diskImageFileName(imageType, imageInfo["openshift_version"], imageInfo["version"], imageInfo["cpu_architecture"]))
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		_, err, _ := s.downloads.Do(path, func() (interface{}, error) {
+			return nil, s.downloadDiskImage(ctx, imageURL, path, imageInfo[imageType+"_sha256"])
+		})
+		if err != nil {
+			return fmt.Errorf("failed to download the %s image of %s-%s: %w", imageType, imageInfo["openshift_version"], imageInfo["cpu_architecture"], err)
+		}
+		log.Infof("Finished downloading the %s image of %s-%s (%s)", imageType, imageInfo["openshift_version"], imageInfo["cpu_architecture"], imageInfo["version"])
+	}
+	return nil
+}
+
+// downloadDiskImage downloads the disk image to path, checking the sha256 of the downloaded
+// file when it isn't empty
+func (s *rhcosStore) downloadDiskImage(ctx context.Context, imageURL, path, sha256sum string) error {
+	log.Infof("Downloading disk image from %s to %s", imageURL, path)
+	downloadPath := path
+	if strings.HasSuffix(imageURL, compressedSuffix) {
+		downloadPath = path + compressedSuffix
+	}
+	if err := s.downloadURLToFile(ctx, imageURL, downloadPath); err != nil {
+		return err
+	}
+	if sha256sum != "" {
+		if err := checkSHA256(downloadPath, sha256sum); err != nil {
+			os.Remove(downloadPath)
+			return err
+		}
+	}
+	if downloadPath == path {
+		return nil
+	}
+	defer os.Remove(downloadPath)
+	return gunzipFile(downloadPath, path)
+}
+
+func checkSHA256(path, sha256sum string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != strings.ToLower(sha256sum) {
+		return fmt.Errorf("sha256 of %s (%s) doesn't match the expected %s", path, actual, sha256sum)
+	}
+	return nil
+}
+
+func gunzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("failed to decompress %s: %w", src, err)
+	}
+	defer gz.Close()
+
+	t, err := renameio.TempFile("", dst)
+	if err != nil {
+		return fmt.Errorf("unable to create a temp file for %s: %v", dst, err)
+	}
+	defer func() {
+		if err1 := t.Cleanup(); err1 != nil {
+			log.WithError(err1).Errorf("Unable to clean up temp file %s", t.Name())
+		}
+	}()
+	if _, err = io.Copy(t, gz); err != nil {
+		return fmt.Errorf("failed to decompress %s: %w", src, err)
+	}
+	return t.CloseAtomicallyReplace()
+}
@@ -0,0 +1,117 @@
+package imagestore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("disk images", func() {
+	var (
+		dataDir      string
+		ts           *ghttp.Server
+		version      map[string]string
+		qcow2Content = []byte("QFI\xfbsomeqcow2content")
+		qcow2Path    string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = os.MkdirTemp("", "diskImagesTest")
+		Expect(err).NotTo(HaveOccurred())
+		qcow2Path = filepath.Join(dataDir, "rhcos-qcow2-4.8-48.84.202109241901-0-s390x.qcow2")
+
+		isoContent := make([]byte, 32840)
+		copy(isoContent[32808:], "rhcos-411.86.202210041459-0")
+		ts = ghttp.NewServer()
+		ts.RouteToHandler("GET", "/sha256sum.txt", ghttp.RespondWith(http.StatusNotFound, nil))
+		ts.RouteToHandler("GET", "/rhcos.iso", ghttp.RespondWith(http.StatusOK, isoContent,
+			http.Header{"Content-Length": []string{strconv.Itoa(len(isoContent))}}))
+		ts.RouteToHandler("GET", "/rhcos.qcow2", ghttp.RespondWith(http.StatusOK, qcow2Content,
+			http.Header{"Content-Length": []string{strconv.Itoa(len(qcow2Content))}}))
+		compressed := new(bytes.Buffer)
+		gz := gzip.NewWriter(compressed)
+		_, err = gz.Write(qcow2Content)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gz.Close()).To(Succeed())
+		ts.RouteToHandler("GET", "/rhcos.qcow2.gz", ghttp.RespondWith(http.StatusOK, compressed.Bytes(),
+			http.Header{"Content-Length": []string{strconv.Itoa(compressed.Len())}}))
+
+		version = map[string]string{
+			"openshift_version": "4.8",
+			"cpu_architecture":  "s390x",
+			"version":           "48.84.202109241901-0",
+			"url":               ts.URL() + "/rhcos.iso",
+		}
+	})
+
+	AfterEach(func() {
+		ts.Close()
+		os.RemoveAll(dataDir)
+	})
+
+	populate := func() error {
+		store, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.PathForParams(ImageTypeQcow2, "4.8", "s390x")).To(Equal(qcow2Path))
+		return store.Populate(context.Background())
+	}
+
+	It("downloads the qcow2 image of the version", func() {
+		version["qcow2_url"] = ts.URL() + "/rhcos.qcow2"
+		Expect(populate()).To(Succeed())
+		Expect(os.ReadFile(qcow2Path)).To(Equal(qcow2Content))
+	})
+
+	It("decompresses the gzipped images", func() {
+		version["qcow2_url"] = ts.URL() + "/rhcos.qcow2.gz"
+		Expect(populate()).To(Succeed())
+		Expect(os.ReadFile(qcow2Path)).To(Equal(qcow2Content))
+		Expect(qcow2Path + compressedSuffix).NotTo(BeAnExistingFile())
+	})
+
+	It("fails when the image doesn't match its sha256", func() {
+		version["qcow2_url"] = ts.URL() + "/rhcos.qcow2"
+		version["qcow2_sha256"] = hex.EncodeToString(make([]byte, sha256.Size))
+		Expect(populate()).To(MatchError(ContainSubstring("doesn't match")))
+		Expect(qcow2Path).NotTo(BeAnExistingFile())
+	})
+
+	It("checks the sha256 of the image", func() {
+		sum := sha256.Sum256(qcow2Content)
+		version["qcow2_url"] = ts.URL() + "/rhcos.qcow2"
+		version["qcow2_sha256"] = hex.EncodeToString(sum[:])
+		Expect(populate()).To(Succeed())
+		Expect(os.ReadFile(qcow2Path)).To(Equal(qcow2Content))
+	})
+
+	It("doesn't download the images of the versions without their URL", func() {
+		Expect(populate()).To(Succeed())
+		Expect(qcow2Path).NotTo(BeAnExistingFile())
+	})
+
+	It("downloads the raw image of the version", func() {
+		rawPath := filepath.Join(dataDir, "rhcos-raw-4.8-48.84.202109241901-0-s390x.raw")
+		version["raw_url"] = ts.URL() + "/rhcos.qcow2.gz"
+		Expect(populate()).To(Succeed())
+		Expect(os.ReadFile(rawPath)).To(Equal(qcow2Content))
+		Expect(qcow2Path).NotTo(BeAnExistingFile())
+	})
+
+	It("keeps the images of the configured versions", func() {
+		version["qcow2_url"] = ts.URL() + "/rhcos.qcow2"
+		Expect(os.WriteFile(qcow2Path, []byte("QFI\xfbalreadythere"), 0600)).To(Succeed())
+		Expect(populate()).To(Succeed())
+		Expect(os.ReadFile(qcow2Path)).To(Equal([]byte("QFI\xfbalreadythere")))
+	})
+})
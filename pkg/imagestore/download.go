@@ -0,0 +1,368 @@
+package imagestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/renameio"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// downloadSegments is the number of ranged requests downloading an image in parallel
+	downloadSegments = 4
+	// downloadAttempts is the number of requests made for a segment before giving up
+	downloadAttempts = 5
+	// downloadStateInterval is the number of bytes downloaded between two saves of the state
+	downloadStateInterval = 16 << 20
+
+	partialSuffix = ".part"
+	stateSuffix   = ".part.json"
+)
+
+var (
+	// minDownloadSegmentSize keeps small images in a single segment
+	minDownloadSegmentSize int64 = 32 << 20
+	downloadRetryDelay           = 2 * time.Second
+)
+
+// errDownloadChanged is returned when the image changed on the server during the download
+var errDownloadChanged = errors.New("the image changed on the server")
+
+// downloadState is saved next to a partial download, so that it resumes after an interruption
+type downloadState struct {
+	URL          string            `json:"url"`
+	Size         int64             `json:"size"`
+	ETag         string            `json:"etag,omitempty"`
+	LastModified string            `json:"last_modified,omitempty"`
+	Segments     []downloadSegment `json:"segments"`
+}
+
+type downloadSegment struct {
+	Start   int64 `json:"start"`
+	End     int64 `json:"end"`
+	Written int64 `json:"written"`
+}
+
+func newDownloadState(url string, resp *http.Response) *downloadState {
+	state := &downloadState{
+		URL:          url,
+		Size:         resp.ContentLength,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	count := state.Size / minDownloadSegmentSize
+	if count < 1 {
+		count = 1
+	} else if count > downloadSegments {
+		count = downloadSegments
+	}
+	segmentSize := state.Size / count
+	for i := int64(0); i < count; i++ {
+		segment := downloadSegment{Start: i * segmentSize, End: (i + 1) * segmentSize}
+		if i == count-1 {
+			segment.End = state.Size
+		}
+		state.Segments = append(state.Segments, segment)
+	}
+	return state
+}
+
+// resumes returns true if the state of a previous download is of the same image
+func (d *downloadState) resumes(previous *downloadState) bool {
+	return previous.URL == d.URL && previous.Size == d.Size && previous.ETag == d.ETag && previous.LastModified == d.LastModified
+}
+
+// validator returns the value of the If-Range header, empty if the server didn't send a strong validator
+func (d *downloadState) validator() string {
+	if d.ETag != "" && !strings.HasPrefix(d.ETag, "W/") {
+		return d.ETag
+	}
+	return d.LastModified
+}
+
+func loadDownloadState(path string) (*downloadState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	state := &downloadState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s *rhcosStore) doHttpRequest(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make http request due to error: %s", err.Error())
+	}
+	for key, value := range s.osImageDownloadHeadersMap {
+		req.Header.Set(key, value)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if len(s.osImageDownloadQueryParamsMap) > 0 {
+		query := req.URL.Query()
+		for key, value := range s.osImageDownloadQueryParamsMap {
+			query.Add(key, value)
+		}
+		req.URL.RawQuery = query.Encode()
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to make http request due to error: %s", err.Error())
+	}
+	return resp, nil
+}
+
+// downloadURLToFile downloads url to path. When the server supports ranges, the image is
+// downloaded in parallel segments, retried and resumed from a partial download next to path.
+func (s *rhcosStore) downloadURLToFile(ctx context.Context, url string, path string) error {
+	resp, err := s.doHttpRequest(ctx, url, nil)
+	if err != nil {
+		return fmt.Errorf("http request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("request to %s returned error code %d", url, resp.StatusCode)
+	}
+
+	if resp.Header.Get("Accept-Ranges") == "bytes" && resp.ContentLength > 0 {
+		return s.downloadSegmentsToFile(ctx, url, path, resp)
+	}
+
+	t, err := renameio.TempFile("", path)
+	if err != nil {
+		return fmt.Errorf("unable to create a temp file for %s: %v", path, err)
+	}
+
+	defer func() {
+		if err1 := t.Cleanup(); err1 != nil {
+			log.WithError(err1).Errorf("Unable to clean up temp file %s", t.Name())
+		}
+	}()
+
+	count, err := io.Copy(t, resp.Body)
+	if err != nil {
+		return err
+	} else if count != resp.ContentLength {
+		return fmt.Errorf("wrote %d bytes, but expected to write %d", count, resp.ContentLength)
+	}
+
+	if err := t.CloseAtomicallyReplace(); err != nil {
+		return fmt.Errorf("unable to atomically replace %s with temp file %s: %v", path, t.Name(), err)
+	}
+
+	return nil
+}
+
+// segmentedDownload writes the segments of an image to the partial download
+type segmentedDownload struct {
+	store     *rhcosStore
+	url       string
+	file      *os.File
+	statePath string
+
+	lock    sync.Mutex
+	state   *downloadState
+	unsaved int64
+}
+
+func (s *rhcosStore) downloadSegmentsToFile(ctx context.Context, url, path string, resp *http.Response) error {
+	partPath, statePath := path+partialSuffix, path+stateSuffix
+	state := newDownloadState(url, resp)
+	previous, err := loadDownloadState(statePath)
+	if info, statErr := os.Stat(partPath); err == nil && statErr == nil && state.resumes(previous) && info.Size() == state.Size {
+		state = previous
+		log.Infof("Resuming the download of %s to %s", url, path)
+	} else {
+		os.Remove(statePath)
+		if err := os.WriteFile(partPath, nil, 0600); err != nil {
+			return fmt.Errorf("unable to create %s: %w", partPath, err)
+		}
+		if err := os.Truncate(partPath, state.Size); err != nil {
+			return fmt.Errorf("unable to allocate %s: %w", partPath, err)
+		}
+	}
+
+	f, err := os.OpenFile(partPath, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %w", partPath, err)
+	}
+	d := &segmentedDownload{store: s, url: url, file: f, statePath: statePath, state: state}
+	if err := d.save(); err != nil {
+		f.Close()
+		return err
+	}
+
+	errs, segmentsCtx := errgroup.WithContext(ctx)
+	for i := range state.Segments {
+		i := i
+		// the first segment of a new download continues the initial response
+		var body io.ReadCloser
+		if i == 0 && state.Segments[0].Written == 0 {
+			body = resp.Body
+		}
+		errs.Go(func() error {
+			return d.fetchSegment(segmentsCtx, i, body)
+		})
+	}
+	err = errs.Wait()
+	if saveErr := d.save(); err == nil {
+		err = saveErr
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if errors.Is(err, errDownloadChanged) {
+		os.Remove(partPath)
+		os.Remove(statePath)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(partPath, path); err != nil {
+		return fmt.Errorf("unable to rename %s to %s: %w", partPath, path, err)
+	}
+	if err := os.Remove(statePath); err != nil {
+		log.WithError(err).Warnf("Unable to remove %s", statePath)
+	}
+	return nil
+}
+
+// fetchSegment downloads the rest of segment i, starting with body if it isn't nil, retrying
+// from where the previous request stopped
+func (d *segmentedDownload) fetchSegment(ctx context.Context, i int, body io.ReadCloser) error {
+	for attempt := 1; ; attempt++ {
+		start, end := d.remaining(i)
+		if start == end {
+			if body != nil {
+				body.Close()
+			}
+			return nil
+		}
+		var err error
+		if body == nil {
+			body, err = d.requestRange(ctx, start, end)
+		}
+		if err == nil {
+			err = d.copySegment(i, io.LimitReader(body, end-start))
+			body.Close()
+			body = nil
+		}
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil || errors.Is(err, errDownloadChanged) || attempt == downloadAttempts {
+			return fmt.Errorf("failed to download bytes %d-%d of %s: %w", start, end-1, d.url, err)
+		}
+		log.WithError(err).Warnf("Download of %s failed at byte %d, retrying", d.url, start)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(downloadRetryDelay * time.Duration(attempt)):
+		}
+	}
+}
+
+// remaining returns the range of segment i that is left to download
+func (d *segmentedDownload) remaining(i int) (int64, int64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	segment := d.state.Segments[i]
+	return segment.Start + segment.Written, segment.End
+}
+
+func (d *segmentedDownload) requestRange(ctx context.Context, start, end int64) (io.ReadCloser, error) {
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end-1))
+	if validator := d.state.validator(); validator != "" {
+		header.Set("If-Range", validator)
+	}
+	resp, err := d.store.doHttpRequest(ctx, d.url, header)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", start)):
+		return resp.Body, nil
+	case resp.StatusCode == http.StatusOK:
+		// the server ignores the range when If-Range doesn't match
+		resp.Body.Close()
+		return nil, errDownloadChanged
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("range request returned status %d", resp.StatusCode)
+	}
+}
+
+func (d *segmentedDownload) copySegment(i int, r io.Reader) error {
+	start, end := d.remaining(i)
+	buf := make([]byte, 1<<20)
+	for offset := start; offset < end; {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, err := d.file.WriteAt(buf[:n], offset); err != nil {
+				return err
+			}
+			offset += int64(n)
+			if err := d.advance(i, int64(n)); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF && offset < end {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+	}
+	return nil
+}
+
+// advance records n more bytes written to segment i, saving the state periodically
+func (d *segmentedDownload) advance(i int, n int64) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.state.Segments[i].Written += n
+	d.unsaved += n
+	if d.unsaved < downloadStateInterval {
+		return nil
+	}
+	return d.saveLocked()
+}
+
+func (d *segmentedDownload) save() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.saveLocked()
+}
+
+func (d *segmentedDownload) saveLocked() error {
+	data, err := json.Marshal(d.state)
+	if err != nil {
+		return err
+	}
+	if err := renameio.WriteFile(d.statePath, data, 0600); err != nil {
+		return fmt.Errorf("unable to save the download state %s: %w", d.statePath, err)
+	}
+	d.unsaved = 0
+	return nil
+}
@@ -0,0 +1,156 @@
+package imagestore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("downloadURLToFile", func() {
+	var (
+		dataDir    string
+		content    []byte
+		server     *httptest.Server
+		store      *rhcosStore
+		lock       sync.Mutex
+		ranges     []string
+		failAfter  int
+		rangeError bool
+		etag       string
+		ctx        = context.Background()
+		isoPath    string
+		savedDelay time.Duration
+		savedSize  int64
+	)
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = os.MkdirTemp("", "download")
+		Expect(err).NotTo(HaveOccurred())
+		isoPath = filepath.Join(dataDir, "rhcos.iso")
+
+		content = make([]byte, 10000)
+		for i := range content {
+			content[i] = byte(i % 251)
+		}
+		ranges = nil
+		failAfter = 0
+		rangeError = false
+		etag = `"v1"`
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			ranges = append(ranges, r.Header.Get("Range"))
+			fail := failAfter > 0
+			failAfter--
+			lock.Unlock()
+			w.Header().Set("ETag", etag)
+			if rangeError && r.Header.Get("Range") != "" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			if fail {
+				// cut the response in the middle
+				w.Header().Set("Accept-Ranges", "bytes")
+				w.Header().Set("Content-Length", "10000")
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write(content[:100])
+				return
+			}
+			http.ServeContent(w, r, "rhcos.iso", time.Time{}, bytes.NewReader(content))
+		}))
+
+		i, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: DefaultVersions})
+		Expect(err).NotTo(HaveOccurred())
+		store = i.(*rhcosStore)
+
+		savedDelay, savedSize = downloadRetryDelay, minDownloadSegmentSize
+		downloadRetryDelay, minDownloadSegmentSize = time.Millisecond, 2000
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dataDir)
+		downloadRetryDelay, minDownloadSegmentSize = savedDelay, savedSize
+	})
+
+	It("downloads the segments in parallel", func() {
+		Expect(store.downloadURLToFile(ctx, server.URL, isoPath)).To(Succeed())
+		Expect(os.ReadFile(isoPath)).To(Equal(content))
+		Expect(ranges).To(ConsistOf("", "bytes=2500-4999", "bytes=5000-7499", "bytes=7500-9999"))
+
+		_, err := os.Stat(isoPath + partialSuffix)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		_, err = os.Stat(isoPath + stateSuffix)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("retries the interrupted segments from where they stopped", func() {
+		minDownloadSegmentSize = 20000
+		failAfter = 1
+		Expect(store.downloadURLToFile(ctx, server.URL, isoPath)).To(Succeed())
+		Expect(os.ReadFile(isoPath)).To(Equal(content))
+		Expect(ranges).To(Equal([]string{"", "bytes=100-9999"}))
+	})
+
+	It("resumes a previous partial download", func() {
+		state := &downloadState{URL: server.URL, Size: 10000, ETag: `"v1"`, Segments: []downloadSegment{
+			{Start: 0, End: 5000, Written: 5000}, {Start: 5000, End: 10000, Written: 1000},
+		}}
+		partial := append(append([]byte{}, content[:6000]...), make([]byte, 4000)...)
+		Expect(os.WriteFile(isoPath+partialSuffix, partial, 0600)).To(Succeed())
+		data, err := json.Marshal(state)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(isoPath+stateSuffix, data, 0600)).To(Succeed())
+
+		Expect(store.downloadURLToFile(ctx, server.URL, isoPath)).To(Succeed())
+		Expect(os.ReadFile(isoPath)).To(Equal(content))
+		Expect(ranges).To(Equal([]string{"", "bytes=6000-9999"}))
+	})
+
+	It("restarts the download when the image changed", func() {
+		state := &downloadState{URL: server.URL, Size: 10000, ETag: `"v0"`, Segments: []downloadSegment{
+			{Start: 0, End: 10000, Written: 6000},
+		}}
+		Expect(os.WriteFile(isoPath+partialSuffix, make([]byte, 10000), 0600)).To(Succeed())
+		data, err := json.Marshal(state)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(isoPath+stateSuffix, data, 0600)).To(Succeed())
+
+		Expect(store.downloadURLToFile(ctx, server.URL, isoPath)).To(Succeed())
+		Expect(os.ReadFile(isoPath)).To(Equal(content))
+		Expect(ranges).To(HaveLen(4))
+	})
+
+	It("fails and keeps the partial download when the retries are exhausted", func() {
+		minDownloadSegmentSize = 20000
+		failAfter = 1
+		rangeError = true
+		Expect(store.downloadURLToFile(ctx, server.URL, isoPath)).To(MatchError(ContainSubstring("range request returned status 503")))
+		Expect(ranges).To(HaveLen(downloadAttempts))
+		state, err := loadDownloadState(isoPath + stateSuffix)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.Segments[0].Written).To(Equal(int64(100)))
+		_, err = os.Stat(isoPath + partialSuffix)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("fails and drops the partial download when the image changes", func() {
+		minDownloadSegmentSize = 20000
+		failAfter = 2
+		// the ranged request gets a 200 response, as when If-Range doesn't match
+		Expect(store.downloadURLToFile(ctx, server.URL, isoPath)).To(MatchError(ContainSubstring(errDownloadChanged.Error())))
+		_, err := os.Stat(isoPath + partialSuffix)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		_, err = os.Stat(isoPath)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+})
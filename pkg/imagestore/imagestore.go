@@ -2,8 +2,10 @@ package imagestore
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,13 +14,18 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/renameio"
 	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+	"github.com/openshift/assisted-image-service/pkg/objectstore"
+	"github.com/openshift/assisted-image-service/pkg/registry"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/thoas/go-funk"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 )
 
 var DefaultVersions = []map[string]string{
@@ -72,22 +79,57 @@ var DefaultVersions = []map[string]string{
 	},
 }
 
+// ImageStore keeps the base images of the versions. PathForParams and Available record that the
+// version is used, which defers its pruning, so they are only called for authenticated requests
+// of the images that require authentication.
+//
 //go:generate mockgen -package=imagestore -destination=mock_imagestore.go . ImageStore
 type ImageStore interface {
 	Populate(ctx context.Context) error
 	PathForParams(imageType, version, arch string) string
 	HaveVersion(version, arch string) bool
 	NmstatectlPathForParams(openshiftVersion, arch string) (string, error)
+	Available(openshiftVersion, arch string) bool
+	Prune(ttl time.Duration, dryRun bool) error
+	AddBaseISO(ctx context.Context, openshiftVersion, arch, isoURL string, iso io.Reader) (string, error)
+}
+
+// ObjectStore is an S3 compatible storage shared by the replicas of the service, see
+// objectstore.Client. The data directory is then a local cache of the images kept in the storage.
+type ObjectStore interface {
+	Download(ctx context.Context, key, filePath string) error
+	Upload(ctx context.Context, key, filePath string) error
 }
 
 type rhcosStore struct {
+	// versions is replaced, never modified, when base ISOs are added
+	versionsLock                  sync.RWMutex
 	versions                      []map[string]string
+	baseISOs                      []map[string]string
 	isoEditor                     isoeditor.Editor
 	dataDir                       string
 	httpClient                    *http.Client
 	imageServiceBaseURL           string
 	osImageDownloadHeadersMap     map[string]string
 	osImageDownloadQueryParamsMap map[string]string
+	registryClient                *registry.Client
+	signatureKeys                 []byte
+	objectStore                   ObjectStore
+	mirrors                       *mirrorHealth
+	diskBudget                    int64
+	lazy                          bool
+	downloads                     singleflight.Group
+	sharedDataDir                 bool
+	replicaID                     string
+
+	// usage of the versions, keyed by full ISO file name, for Prune and the disk budget. pruned
+	// maps the removed versions to the bytes of their files.
+	usageLock   sync.Mutex
+	lastUsed    map[string]time.Time
+	pruned      map[string]int64
+	restoring   map[string]bool
+	pruneLock   sync.RWMutex
+	restoreLock sync.Mutex
 }
 
 const (
@@ -95,32 +137,77 @@ const (
 	ImageTypeMinimal = "minimal-iso"
 )
 
-func NewImageStore(ed isoeditor.Editor, dataDir, imageServiceBaseURL string, insecureSkipVerify bool, versions []map[string]string,
-	osImageDownloadTrustedCAFile string, osImageDownloadHeadersMap map[string]string, osImageDownloadQueryParamsMap map[string]string) (ImageStore, error) {
+// ImageStoreOptions configure where the images of the versions are downloaded from and how they
+// are kept
+type ImageStoreOptions struct {
+	// Editor creates the minimal ISOs from the full ones
+	Editor              isoeditor.Editor
+	DataDir             string
+	ImageServiceBaseURL string
+	InsecureSkipVerify  bool
+	Versions            []map[string]string
+
+	// OSImageDownloadTrustedCAFile is trusted, in addition to the system CAs, to download the images
+	OSImageDownloadTrustedCAFile string
+	// OSImageDownloadHeaders and OSImageDownloadQueryParams are sent with every image download
+	OSImageDownloadHeaders     map[string]string
+	OSImageDownloadQueryParams map[string]string
+	// OSImagePullSecretFile holds the credentials of the registries of the oci:// URLs
+	OSImagePullSecretFile string
+	// OSImageSignatureKeysFile holds the OpenPGP keys the checksums of the ISOs must be signed with
+	OSImageSignatureKeysFile string
+
+	// ObjectStore shares the images between the replicas when set, DataDir is then a local cache
+	ObjectStore ObjectStore
+	// DiskBudget caps the bytes of the images in DataDir, unless it is 0
+	DiskBudget int64
+	// Lazy defers the download of the images to the first request of their version
+	Lazy bool
+	// SharedDataDir tells that the replicas share DataDir
+	SharedDataDir bool
+}
+
+func NewImageStore(options ImageStoreOptions) (ImageStore, error) {
+	versions := options.Versions
 	if err := validateVersions(versions); err != nil {
 		return nil, err
 	}
+
+	// The checksums of the ISOs must be signed by these keys
+	var signatureKeys []byte
+	if options.OSImageSignatureKeysFile != "" {
+		for _, entry := range versions {
+			if entry["sha256sum_url"] == "" {
+				return nil, fmt.Errorf("invalid version entry %+v: missing sha256sum_url key, required to verify signatures", entry)
+			}
+		}
+		var err error
+		signatureKeys, err = loadSignatureKeys(options.OSImageSignatureKeysFile)
+		if err != nil {
+			return nil, err
+		}
+	}
 	transportConfig, ok := http.DefaultTransport.(*http.Transport)
 	if !ok {
 		return nil, fmt.Errorf("expected http.DefaultTransport to be of type *http.Transport")
 	}
 
 	myTransport := transportConfig.Clone()
-	myTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: insecureSkipVerify} //nolint:gosec // Optionally ignore TLS (G402 error)
+	myTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: options.InsecureSkipVerify} //nolint:gosec // Optionally ignore TLS (G402 error)
 
 	// Add additional TLS certificates (if available) for fetching OS images
-	if osImageDownloadTrustedCAFile != "" {
+	if options.OSImageDownloadTrustedCAFile != "" {
 		// In order to make sure we can use the "built in" CA's in addition to our custom CA, we need to make sure these are loaded in.
 		caCertPool, err := x509.SystemCertPool()
 		if err != nil {
 			return nil, fmt.Errorf("failed to obtain system cert pool: %w", err)
 		}
-		additionalTLSCert, err := os.ReadFile(osImageDownloadTrustedCAFile)
+		additionalTLSCert, err := os.ReadFile(options.OSImageDownloadTrustedCAFile)
 		if err != nil {
-			return nil, fmt.Errorf("failed to open additional certificate file %s, %w", osImageDownloadTrustedCAFile, err)
+			return nil, fmt.Errorf("failed to open additional certificate file %s, %w", options.OSImageDownloadTrustedCAFile, err)
 		}
 		if !caCertPool.AppendCertsFromPEM(additionalTLSCert) {
-			return nil, fmt.Errorf("failed to append additional certificate %s to pool", osImageDownloadTrustedCAFile)
+			return nil, fmt.Errorf("failed to append additional certificate %s to pool", options.OSImageDownloadTrustedCAFile)
 		}
 		myTransport.TLSClientConfig = &tls.Config{
 			RootCAs:    caCertPool,
@@ -130,14 +217,35 @@ func NewImageStore(ed isoeditor.Editor, dataDir, imageServiceBaseURL string, ins
 
 	httpClient := &http.Client{Transport: myTransport}
 
+	// Credentials of the registries of the versions pulled from oci:// URLs
+	var pullSecret *registry.PullSecret
+	if options.OSImagePullSecretFile != "" {
+		var err error
+		pullSecret, err = registry.LoadPullSecret(options.OSImagePullSecretFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &rhcosStore{
 		versions:                      versions,
-		isoEditor:                     ed,
-		dataDir:                       dataDir,
+		isoEditor:                     options.Editor,
+		dataDir:                       options.DataDir,
 		httpClient:                    httpClient,
-		imageServiceBaseURL:           imageServiceBaseURL,
-		osImageDownloadHeadersMap:     osImageDownloadHeadersMap,
-		osImageDownloadQueryParamsMap: osImageDownloadQueryParamsMap,
+		imageServiceBaseURL:           options.ImageServiceBaseURL,
+		osImageDownloadHeadersMap:     options.OSImageDownloadHeaders,
+		osImageDownloadQueryParamsMap: options.OSImageDownloadQueryParams,
+		registryClient:                registry.NewClient(httpClient, pullSecret),
+		signatureKeys:                 signatureKeys,
+		objectStore:                   options.ObjectStore,
+		lastUsed:                      map[string]time.Time{},
+		pruned:                        map[string]int64{},
+		mirrors:                       newMirrorHealth(),
+		diskBudget:                    options.DiskBudget,
+		lazy:                          options.Lazy,
+		restoring:                     map[string]bool{},
+		sharedDataDir:                 options.SharedDataDir,
+		replicaID:                     replicaID(),
 	}, nil
 }
 
@@ -153,48 +261,38 @@ func validateVersions(versions []map[string]string) error {
 		if _, ok := entry["cpu_architecture"]; !ok {
 			return fmt.Errorf(missingKeyFmt, entry, "cpu_architecture")
 		}
-		if _, ok := entry["url"]; !ok {
+		// the url and version of a channel are resolved from the channel
+		_, hasChannel := entry[channelURLKey]
+		if _, ok := entry["url"]; !ok && !hasChannel {
 			return fmt.Errorf(missingKeyFmt, entry, "url")
 		}
-		if _, ok := entry["version"]; !ok {
+		for _, imageURL := range imageURLs(entry) {
+			if registry.IsReference(imageURL) {
+				if _, err := registry.ParseReference(imageURL); err != nil {
+					return fmt.Errorf("invalid version entry %+v: %w", entry, err)
+				}
+			}
+		}
+		if _, ok := entry["version"]; !ok && !hasChannel {
 			return fmt.Errorf(missingKeyFmt, entry, "version")
 		}
+		for _, key := range append([]string{"sha256"}, diskImageSHA256Keys()...) {
+			if sha256sum, ok := entry[key]; ok {
+				if decoded, err := hex.DecodeString(sha256sum); err != nil || len(decoded) != sha256.Size {
+					return fmt.Errorf("invalid version entry %+v: %s must be 64 hexadecimal characters", entry, key)
+				}
+			}
+		}
 	}
 
 	return nil
 }
 
-func (s *rhcosStore) doHttpRequest(url string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to make http request due to error: %s", err.Error())
-	}
-	for key, value := range s.osImageDownloadHeadersMap {
-		req.Header.Set(key, value)
-	}
-	if len(s.osImageDownloadQueryParamsMap) > 0 {
-		query := req.URL.Query()
-		for key, value := range s.osImageDownloadQueryParamsMap {
-			query.Add(key, value)
-		}
-		req.URL.RawQuery = query.Encode()
-	}
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to make http request due to error: %s", err.Error())
-	}
-	return resp, nil
-}
-
-func (s *rhcosStore) downloadURLToFile(url string, path string) error {
-	resp, err := s.doHttpRequest(url)
+// pullImageToFile pulls the ISO of the registry reference imageURL to path
+func (s *rhcosStore) pullImageToFile(ctx context.Context, imageURL, arch, path string) error {
+	ref, err := registry.ParseReference(imageURL)
 	if err != nil {
-		return fmt.Errorf("http request to %s failed: %w", url, err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return fmt.Errorf("request to %s returned error code %d", url, resp.StatusCode)
+		return err
 	}
 
 	t, err := renameio.TempFile("", path)
@@ -208,11 +306,8 @@ func (s *rhcosStore) downloadURLToFile(url string, path string) error {
 		}
 	}()
 
-	count, err := io.Copy(t, resp.Body)
-	if err != nil {
+	if err := s.registryClient.Pull(ctx, ref, arch, t); err != nil {
 		return err
-	} else if count != resp.ContentLength {
-		return fmt.Errorf("wrote %d bytes, but expected to write %d", count, resp.ContentLength)
 	}
 
 	if err := t.CloseAtomicallyReplace(); err != nil {
@@ -222,6 +317,34 @@ func (s *rhcosStore) downloadURLToFile(url string, path string) error {
 	return nil
 }
 
+// validateISO checks the volume identifier of the ISO, and that its checksum matches the
+// non-empty sha256sums
+func validateISO(path string, sha256sums ...string) error {
+	if err := validateISOID(path); err != nil {
+		return err
+	}
+	if strings.Join(sha256sums, "") == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	actual := hex.EncodeToString(h.Sum(nil))
+	for _, sha256sum := range sha256sums {
+		if sha256sum != "" && actual != strings.ToLower(sha256sum) {
+			return fmt.Errorf("ISO sha256 (%s) doesn't match the expected %s", actual, sha256sum)
+		}
+	}
+	return nil
+}
+
 func validateISOID(path string) error {
 	volumeID, err := isoeditor.VolumeIdentifier(path)
 	if err != nil {
@@ -236,40 +359,30 @@ func validateISOID(path string) error {
 }
 
 func (s *rhcosStore) Populate(ctx context.Context) error {
+	if err := s.resolveChannels(ctx); err != nil {
+		return err
+	}
+	if err := s.loadBaseISOs(); err != nil {
+		return err
+	}
 	if err := s.cleanDataDir(); err != nil {
 		return err
 	}
 
+	versions := s.configuredVersions()
+	if s.lazy {
+		versions = s.deferMissingVersions()
+	}
+
 	errs, _ := errgroup.WithContext(ctx)
 
-	for i := range s.versions {
-		imageInfo := s.versions[i]
+	for i := range versions {
+		imageInfo := versions[i]
 		errs.Go(func() error {
-			openshiftVersion := imageInfo["openshift_version"]
-			imageVersion := imageInfo["version"]
-			arch := imageInfo["cpu_architecture"]
-
-			fullPath := filepath.Join(s.dataDir, isoFileName(ImageTypeFull, openshiftVersion, imageVersion, arch))
-			if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-				url := imageInfo["url"]
-				log.Infof("Downloading iso from %s to %s", url, fullPath)
-
-				err = s.downloadURLToFile(url, fullPath)
-				if err != nil {
-					return fmt.Errorf("failed to download %s: %v", url, err)
-				}
-				log.Infof("Finished downloading for %s-%s (%s)", openshiftVersion, arch, imageVersion)
-				if err := validateISOID(fullPath); err != nil {
-					message := fmt.Sprintf("failed to validate %s: %v", fullPath, err)
-					if err = os.Remove(fullPath); err != nil {
-						log.WithError(err).Errorf("failed to remove invalid ISO %s", fullPath)
-					}
-					log.Error(message)
-					return errors.New(message)
-				}
+			if err := s.populateFullISO(ctx, imageInfo); err != nil {
+				return err
 			}
-
-			return nil
+			return s.populateDiskImages(ctx, imageInfo)
 		})
 	}
 
@@ -277,49 +390,191 @@ func (s *rhcosStore) Populate(ctx context.Context) error {
 		return err
 	}
 
-	for i := range s.versions {
-		imageInfo := s.versions[i]
-		openshiftVersion := imageInfo["openshift_version"]
-		imageVersion := imageInfo["version"]
-		arch := imageInfo["cpu_architecture"]
+	for i := range versions {
+		if err := s.populateMinimalISO(ctx, versions[i]); err != nil {
+			return err
+		}
+	}
+
+	s.resetUsage()
+	return s.enforceDiskBudget("", 0)
+}
 
-		// Don't attempt to create a minimal ISO for s390x because there's no easy way to edit the kernel parameters
-		// This means that the rootfs URL can't be added which makes it impossible for us to create a minimal ISO
-		if arch == "s390x" {
-			continue
+// populateFullISO downloads the full ISO of the version if it is missing
+func (s *rhcosStore) populateFullISO(ctx context.Context, imageInfo map[string]string) error {
+	fullPath := filepath.Join(s.dataDir, versionKey(imageInfo))
+	if _, err := os.Stat(fullPath); !os.IsNotExist(err) {
+		cacheFileOffsets(fullPath)
+		return nil
+	}
+	// only one download of an ISO at a time
+	_, err, _ := s.downloads.Do(fullPath, func() (interface{}, error) {
+		if !s.sharedDataDir {
+			return nil, s.fetchFullISO(ctx, imageInfo, fullPath)
 		}
-		minimalPath := filepath.Join(s.dataDir, isoFileName(ImageTypeMinimal, openshiftVersion, imageVersion, arch))
-		if _, err := os.Stat(minimalPath); os.IsNotExist(err) {
-			log.Infof("Creating minimal iso for %s-%s-%s", openshiftVersion, imageVersion, arch)
-
-			fullPath := filepath.Join(s.dataDir, isoFileName(ImageTypeFull, openshiftVersion, imageVersion, arch))
-			rootfsURL, err := buildRootfsURL(s.imageServiceBaseURL, arch, openshiftVersion)
-			if err != nil {
-				return fmt.Errorf("failed to build rootfs URL: %v", err)
-			}
+		// and only by one of the replicas sharing the data directory
+		release, acquired, err := s.acquireLease(ctx, fullPath, func() bool {
+			_, err := os.Stat(fullPath)
+			return err == nil
+		})
+		if err != nil || !acquired {
+			return nil, err
+		}
+		defer release()
+		if _, err := os.Stat(fullPath); err == nil {
+			return nil, nil
+		}
+		return nil, s.fetchFullISO(ctx, imageInfo, fullPath)
+	})
+	if err != nil {
+		return err
+	}
+	cacheFileOffsets(fullPath)
+	return nil
+}
 
-			nmstatectlPath, err := s.NmstatectlPathForParams(openshiftVersion, arch)
-			if err != nil {
+// cacheFileOffsets writes the sidecar file of the offsets of the files of the ISO customized per
+// request. It is only an optimization, the files are located in the ISO without it.
+func cacheFileOffsets(isoPath string) {
+	if err := isoeditor.WriteFileOffsets(isoPath); err != nil {
+		log.WithError(err).Warnf("Failed to cache the file offsets of %s", isoPath)
+	}
+}
+
+// fetchFullISO gets the full ISO of the version to fullPath from the object store or its URLs,
+// checking it against the signed checksums in both cases
+func (s *rhcosStore) fetchFullISO(ctx context.Context, imageInfo map[string]string, fullPath string) error {
+	var listedSHA256 string
+	var err error
+	if s.signatureKeys != nil {
+		listedSHA256, err = s.signedChecksum(ctx, imageInfo)
+		if err != nil {
+			return err
+		}
+	}
+	fetched, err := s.fetchFromObjectStore(ctx, "", fullPath)
+	if err != nil {
+		return err
+	}
+	if fetched {
+		return removeInvalidISO(fullPath, imageInfo["sha256"], listedSHA256)
+	}
+	if s.signatureKeys == nil && imageInfo["sha256"] == "" {
+		listedSHA256 = s.upstreamChecksum(ctx, imageInfo)
+	}
+	if err := s.downloadFromMirrors(ctx, imageInfo, fullPath, imageInfo["sha256"], listedSHA256); err != nil {
+		return err
+	}
+	log.Infof("Finished downloading for %s-%s (%s)", imageInfo["openshift_version"], imageInfo["cpu_architecture"], imageInfo["version"])
+	return s.publishToObjectStore(ctx, "", fullPath)
+}
+
+// populateMinimalISO creates the minimal ISO of the version if it is missing
+func (s *rhcosStore) populateMinimalISO(ctx context.Context, imageInfo map[string]string) error {
+	openshiftVersion := imageInfo["openshift_version"]
+	imageVersion := imageInfo["version"]
+	arch := imageInfo["cpu_architecture"]
+
+	// Don't attempt to create a minimal ISO for s390x because there's no easy way to edit the kernel parameters
+	// This means that the rootfs URL can't be added which makes it impossible for us to create a minimal ISO
+	if arch == "s390x" {
+		return nil
+	}
+	minimalPath := filepath.Join(s.dataDir, isoFileName(ImageTypeMinimal, openshiftVersion, imageVersion, arch))
+	if _, err := os.Stat(minimalPath); os.IsNotExist(err) {
+		log.Infof("Creating minimal iso for %s-%s-%s", openshiftVersion, imageVersion, arch)
+
+		fullPath := filepath.Join(s.dataDir, isoFileName(ImageTypeFull, openshiftVersion, imageVersion, arch))
+		rootfsURL, err := buildRootfsURL(s.imageServiceBaseURL, arch, openshiftVersion)
+		if err != nil {
+			return fmt.Errorf("failed to build rootfs URL: %v", err)
+		}
+
+		nmstatectlPath, err := s.NmstatectlPathForParams(openshiftVersion, arch)
+		if err != nil {
+			return err
+		}
+		// the minimal ISOs depend on the rootfs URL, they are shared by the deployments using the same one
+		prefix := artifactsPrefix(rootfsURL)
+		fetched, err := s.fetchFromObjectStore(ctx, prefix, minimalPath)
+		if err != nil {
+			return err
+		}
+		if fetched {
+			if _, err := s.fetchFromObjectStore(ctx, prefix, nmstatectlPath); err != nil {
 				return err
 			}
-			err = s.isoEditor.CreateMinimalISOTemplate(fullPath, rootfsURL, arch, minimalPath, openshiftVersion, nmstatectlPath)
-			if err != nil {
-				return fmt.Errorf("failed to create minimal iso template for version %s: %v", imageInfo, err)
-			}
+			log.Infof("Fetched minimal iso for %s-%s (%s) from the object store", openshiftVersion, arch, imageVersion)
+			cacheFileOffsets(minimalPath)
+			return nil
+		}
 
-			log.Infof("Finished creating minimal iso for %s-%s (%s)", openshiftVersion, arch, imageVersion)
+		err = s.isoEditor.CreateMinimalISOTemplate(fullPath, rootfsURL, arch, minimalPath, openshiftVersion, nmstatectlPath)
+		if err != nil {
+			return fmt.Errorf("failed to create minimal iso template for version %s: %v", imageInfo, err)
+		}
+		// the minimal ISO is published last, its presence tells that the artifacts are complete
+		if _, err := os.Stat(nmstatectlPath); err == nil {
+			if err := s.publishToObjectStore(ctx, prefix, nmstatectlPath); err != nil {
+				return err
+			}
 		}
+		if err := s.publishToObjectStore(ctx, prefix, minimalPath); err != nil {
+			return err
+		}
+
+		log.Infof("Finished creating minimal iso for %s-%s (%s)", openshiftVersion, arch, imageVersion)
+		cacheFileOffsets(minimalPath)
+	}
+
+	return nil
+}
+
+// fetchFromObjectStore downloads the file of the data directory from the object store, returning
+// false when the store doesn't have it
+func (s *rhcosStore) fetchFromObjectStore(ctx context.Context, prefix, filePath string) (bool, error) {
+	if s.objectStore == nil {
+		return false, nil
+	}
+	key := path.Join(prefix, filepath.Base(filePath))
+	err := s.objectStore.Download(ctx, key, filePath)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch %s from the object store: %w", key, err)
 	}
+	log.Infof("Fetched %s from the object store", key)
+	return true, nil
+}
 
+// publishToObjectStore uploads the file of the data directory to the object store
+func (s *rhcosStore) publishToObjectStore(ctx context.Context, prefix, filePath string) error {
+	if s.objectStore == nil {
+		return nil
+	}
+	key := path.Join(prefix, filepath.Base(filePath))
+	if err := s.objectStore.Upload(ctx, key, filePath); err != nil {
+		return fmt.Errorf("failed to upload %s to the object store: %w", key, err)
+	}
+	log.Infof("Uploaded %s to the object store", key)
 	return nil
 }
 
+// artifactsPrefix returns the prefix of the keys of the artifacts generated for rootfsURL
+func artifactsPrefix(rootfsURL string) string {
+	sum := sha256.Sum256([]byte(rootfsURL))
+	return path.Join("artifacts", hex.EncodeToString(sum[:8]))
+}
+
 func (s *rhcosStore) PathForParams(imageType, openshiftVersion, arch string) string {
 	var version string
-	for _, entry := range s.versions {
-		if entry["openshift_version"] == openshiftVersion && entry["cpu_architecture"] == arch {
-			version = entry["version"]
-		}
+	if entry := s.versionEntry(openshiftVersion, arch); entry != nil {
+		version = entry["version"]
+		s.markUsed(entry)
+	}
+	if isDiskImageType(imageType) {
+		return filepath.Join(s.dataDir, diskImageFileName(imageType, openshiftVersion, version, arch))
 	}
 	return filepath.Join(s.dataDir, isoFileName(imageType, openshiftVersion, version, arch))
 }
@@ -348,10 +603,14 @@ func buildRootfsURL(baseURL, arch, version string) (string, error) {
 
 func (s *rhcosStore) cleanDataDir() error {
 	var expectedFiles []string
-	for _, version := range s.versions {
+	for _, version := range s.configuredVersions() {
 		// Only add full isos here as we want to regenerate the minimal image on each deploy
-		expectedFiles = append(expectedFiles, isoFileName(ImageTypeFull, version["openshift_version"], version["version"], version["cpu_architecture"]))
+		fullISO := isoFileName(ImageTypeFull, version["openshift_version"], version["version"], version["cpu_architecture"])
+		// Keep the partial downloads to resume them, and the leases of the other replicas
+		expectedFiles = append(expectedFiles, fullISO, fullISO+partialSuffix, fullISO+stateSuffix, fullISO+leaseSuffix, fullISO+isoeditor.FileOffsetsSuffix)
+		expectedFiles = append(expectedFiles, diskImageFiles(version)...)
 	}
+	expectedFiles = append(expectedFiles, baseISOsFile)
 
 	dataDirFiles, err := os.ReadDir(s.dataDir)
 	if err != nil {
@@ -371,8 +630,15 @@ func (s *rhcosStore) cleanDataDir() error {
 	return nil
 }
 
+// configuredVersions returns the versions, including the added base ISOs
+func (s *rhcosStore) configuredVersions() []map[string]string {
+	s.versionsLock.RLock()
+	defer s.versionsLock.RUnlock()
+	return s.versions
+}
+
 func (s *rhcosStore) HaveVersion(version, arch string) bool {
-	for _, entry := range s.versions {
+	for _, entry := range s.configuredVersions() {
 		v, versionPresent := entry["openshift_version"]
 		a, archPresent := entry["cpu_architecture"]
 		if versionPresent && v == version && archPresent && a == arch {
@@ -382,9 +648,20 @@ func (s *rhcosStore) HaveVersion(version, arch string) bool {
 	return false
 }
 
+// versionEntry returns the configured version, nil if there is none
+func (s *rhcosStore) versionEntry(openshiftVersion, arch string) map[string]string {
+	var versionEntry map[string]string
+	for _, entry := range s.configuredVersions() {
+		if entry["openshift_version"] == openshiftVersion && entry["cpu_architecture"] == arch {
+			versionEntry = entry
+		}
+	}
+	return versionEntry
+}
+
 func (s *rhcosStore) NmstatectlPathForParams(openshiftVersion, arch string) (string, error) {
 	var version string
-	for _, entry := range s.versions {
+	for _, entry := range s.configuredVersions() {
 		if entry["openshift_version"] == openshiftVersion && entry["cpu_architecture"] == arch {
 			version = entry["version"]
 		}
@@ -5,9 +5,11 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/pem"
 	"fmt"
 	"io/fs"
@@ -17,6 +19,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,6 +28,7 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/ghttp"
 	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+	"github.com/openshift/assisted-image-service/pkg/objectstore"
 )
 
 var (
@@ -32,6 +36,26 @@ var (
 	rootfsURL           = "http://images.example.com/boot-artifacts/rootfs?arch=x86_64&version=%s"
 )
 
+// fakeObjectStore keeps the objects in memory
+type fakeObjectStore map[string][]byte
+
+func (f fakeObjectStore) Download(_ context.Context, key, filePath string) error {
+	content, ok := f[key]
+	if !ok {
+		return objectstore.ErrNotFound
+	}
+	return os.WriteFile(filePath, content, 0600)
+}
+
+func (f fakeObjectStore) Upload(_ context.Context, key, filePath string) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	f[key] = content
+	return nil
+}
+
 func TestImageStore(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "imagestore")
@@ -123,6 +147,7 @@ var _ = Context("with a data directory configured", func() {
 			ts = ghttp.NewUnstartedServer()
 			ts.HTTPTestServer.TLS = tlsConfig
 			ts.HTTPTestServer.StartTLS()
+			ts.RouteToHandler("GET", "/sha256sum.txt", ghttp.RespondWith(http.StatusNotFound, nil))
 		})
 
 		AfterEach(func() {
@@ -167,7 +192,7 @@ var _ = Context("with a data directory configured", func() {
 					),
 				)
 				version["url"] = ts.URL() + "/some.iso"
-				is, err := NewImageStore(mockEditor, dataDir, imageServiceBaseURL, false, []map[string]string{version}, caCertFileName, osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadTrustedCAFile: caCertFileName, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 				Expect(err).NotTo(HaveOccurred())
 
 				rootfs := fmt.Sprintf(rootfsURL, version["openshift_version"])
@@ -194,6 +219,7 @@ var _ = Context("with a data directory configured", func() {
 
 		BeforeEach(func() {
 			ts = ghttp.NewServer()
+			ts.RouteToHandler("GET", "/sha256sum.txt", ghttp.RespondWith(http.StatusNotFound, nil))
 			osImageDownloadHeadersMap = map[string]string{}
 			osImageDownloadQueryParamsMap = map[string]string{}
 		})
@@ -245,7 +271,7 @@ var _ = Context("with a data directory configured", func() {
 					),
 				)
 				version["url"] = ts.URL() + "/some.iso"
-				is, err := NewImageStore(mockEditor, dataDir, imageServiceBaseURL, false, []map[string]string{version}, "", osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 				Expect(err).NotTo(HaveOccurred())
 
 				rootfs := fmt.Sprintf(rootfsURL, version["openshift_version"])
@@ -274,7 +300,7 @@ var _ = Context("with a data directory configured", func() {
 					),
 				)
 				version["url"] = ts.URL() + "/some.iso"
-				is, err := NewImageStore(mockEditor, dataDir, imageServiceBaseURL, false, []map[string]string{version}, "", osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 				Expect(err).NotTo(HaveOccurred())
 
 				rootfs := fmt.Sprintf(rootfsURL, version["openshift_version"])
@@ -297,7 +323,7 @@ var _ = Context("with a data directory configured", func() {
 					),
 				)
 				version["url"] = ts.URL() + "/some.iso"
-				is, err := NewImageStore(mockEditor, dataDir, imageServiceBaseURL, false, []map[string]string{version}, "", osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 				Expect(err).NotTo(HaveOccurred())
 
 				rootfs := fmt.Sprintf(rootfsURL, version["openshift_version"])
@@ -319,7 +345,7 @@ var _ = Context("with a data directory configured", func() {
 					),
 				)
 				version["url"] = ts.URL() + "/fail.iso"
-				is, err := NewImageStore(mockEditor, dataDir, imageServiceBaseURL, false, []map[string]string{version}, "", osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(is.Populate(ctx)).NotTo(Succeed())
@@ -334,7 +360,7 @@ var _ = Context("with a data directory configured", func() {
 					),
 				)
 				version["url"] = ts.URL() + "/some.iso"
-				is, err := NewImageStore(mockEditor, dataDir, imageServiceBaseURL, false, []map[string]string{version}, "", osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(is.Populate(ctx)).NotTo(Succeed())
@@ -343,6 +369,40 @@ var _ = Context("with a data directory configured", func() {
 				Expect(err).To(MatchError(fs.ErrNotExist))
 			})
 
+			It("fails and removes the file when the downloaded iso doesn't match its sha256", func() {
+				isoContent, isoHeader := isoInfo(validVolumeID)
+				ts.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("GET", "/some.iso"),
+						ghttp.RespondWith(http.StatusOK, isoContent, isoHeader),
+					),
+				)
+				version["url"] = ts.URL() + "/some.iso"
+				version["sha256"] = strings.Repeat("0", 64)
+				defer delete(version, "sha256")
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(is.Populate(ctx)).To(MatchError(ContainSubstring("doesn't match the expected")))
+
+				_, err = os.Stat(filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-x86_64.iso"))
+				Expect(err).To(MatchError(fs.ErrNotExist))
+			})
+
+			It("keeps the partial downloads of the configured isos", func() {
+				fullPath := filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-x86_64.iso")
+				Expect(os.WriteFile(fullPath+partialSuffix, []byte("partial"), 0600)).To(Succeed())
+				Expect(os.WriteFile(fullPath+stateSuffix, []byte("{}"), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(dataDir, "other.iso.part"), []byte("partial"), 0600)).To(Succeed())
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(is.(*rhcosStore).cleanDataDir()).To(Succeed())
+				Expect(fullPath + partialSuffix).To(BeAnExistingFile())
+				Expect(fullPath + stateSuffix).To(BeAnExistingFile())
+				Expect(filepath.Join(dataDir, "other.iso.part")).NotTo(BeAnExistingFile())
+			})
+
 			It("fails when minimal iso creation fails", func() {
 				ts.AppendHandlers(
 					ghttp.CombineHandlers(
@@ -351,7 +411,7 @@ var _ = Context("with a data directory configured", func() {
 					),
 				)
 				version["url"] = ts.URL() + "/some.iso"
-				is, err := NewImageStore(mockEditor, dataDir, imageServiceBaseURL, false, []map[string]string{version}, "", osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 				Expect(err).NotTo(HaveOccurred())
 
 				rootfs := fmt.Sprintf(rootfsURL, version["openshift_version"])
@@ -369,7 +429,7 @@ var _ = Context("with a data directory configured", func() {
 					),
 				)
 				version["url"] = ts.URL() + "/dontcallthis.iso"
-				is, err := NewImageStore(mockEditor, dataDir, imageServiceBaseURL, false, []map[string]string{version}, "", osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(os.WriteFile(filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-x86_64.iso"), []byte("moreisocontent"), 0600)).To(Succeed())
@@ -382,7 +442,7 @@ var _ = Context("with a data directory configured", func() {
 			})
 
 			It("recreates the minimal iso even when it's already present", func() {
-				is, err := NewImageStore(mockEditor, dataDir, imageServiceBaseURL, false, []map[string]string{version}, "", osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 				Expect(err).NotTo(HaveOccurred())
 
 				fullPath := filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-x86_64.iso")
@@ -399,6 +459,95 @@ var _ = Context("with a data directory configured", func() {
 				Expect(is.Populate(ctx)).To(Succeed())
 			})
 
+			It("fetches the images from the object store", func() {
+				ts.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("GET", "/dontcallthis.iso"),
+						http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { Fail("endpoint should not be queried") }),
+					),
+				)
+				version["url"] = ts.URL() + "/dontcallthis.iso"
+				isoContent, _ := isoInfo(validVolumeID)
+				prefix := artifactsPrefix(fmt.Sprintf(rootfsURL, version["openshift_version"]))
+				store := fakeObjectStore{
+					"rhcos-full-iso-4.8-48.84.202109241901-0-x86_64.iso":              isoContent,
+					prefix + "/rhcos-minimal-iso-4.8-48.84.202109241901-0-x86_64.iso": []byte("minimalisocontent"),
+				}
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap, ObjectStore: store})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(is.Populate(ctx)).To(Succeed())
+
+				content, err := os.ReadFile(filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-x86_64.iso"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(content).To(Equal(isoContent))
+				content, err = os.ReadFile(filepath.Join(dataDir, "rhcos-minimal-iso-4.8-48.84.202109241901-0-x86_64.iso"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(content).To(Equal([]byte("minimalisocontent")))
+			})
+
+			It("publishes the downloaded and created images to the object store", func() {
+				isoContent, isoHeader := isoInfo(validVolumeID)
+				ts.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("GET", "/some.iso"),
+						ghttp.RespondWith(http.StatusOK, isoContent, isoHeader),
+					),
+				)
+				version["url"] = ts.URL() + "/some.iso"
+				store := fakeObjectStore{}
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap, ObjectStore: store})
+				Expect(err).NotTo(HaveOccurred())
+
+				rootfs := fmt.Sprintf(rootfsURL, version["openshift_version"])
+				nmstatectlPath, err := is.NmstatectlPathForParams(version["openshift_version"], version["cpu_architecture"])
+				Expect(err).NotTo(HaveOccurred())
+				mockEditor.EXPECT().CreateMinimalISOTemplate(gomock.Any(), rootfs, "x86_64", gomock.Any(), version["openshift_version"], nmstatectlPath).DoAndReturn(
+					func(_, _, _, minimalPath, _, _ string) error {
+						return os.WriteFile(minimalPath, []byte("minimalisocontent"), 0600)
+					})
+				Expect(is.Populate(ctx)).To(Succeed())
+
+				prefix := artifactsPrefix(rootfs)
+				Expect(store).To(Equal(fakeObjectStore{
+					"rhcos-full-iso-4.8-48.84.202109241901-0-x86_64.iso":              isoContent,
+					prefix + "/rhcos-minimal-iso-4.8-48.84.202109241901-0-x86_64.iso": []byte("minimalisocontent"),
+				}))
+			})
+
+			It("pulls an image from a registry", func() {
+				registryServer := ghttp.NewTLSServer()
+				defer registryServer.Close()
+				isoContent, _ := isoInfo(validVolumeID)
+				layerSum := sha256.Sum256(isoContent)
+				layerDigest := "sha256:" + hex.EncodeToString(layerSum[:])
+				manifest := fmt.Sprintf(`{"mediaType":"application/vnd.oci.image.manifest.v1+json","layers":[{"mediaType":"application/octet-stream","digest":"%s","size":%d}]}`,
+					layerDigest, len(isoContent))
+				registryServer.AppendHandlers(
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("GET", "/v2/org/rhcos/manifests/4.8"),
+						ghttp.RespondWith(http.StatusOK, manifest),
+					),
+					ghttp.CombineHandlers(
+						ghttp.VerifyRequest("GET", "/v2/org/rhcos/blobs/"+layerDigest),
+						ghttp.RespondWith(http.StatusOK, isoContent),
+					),
+				)
+				version["url"] = "oci://" + strings.TrimPrefix(registryServer.URL(), "https://") + "/org/rhcos:4.8"
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, InsecureSkipVerify: true, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
+				Expect(err).NotTo(HaveOccurred())
+
+				rootfs := fmt.Sprintf(rootfsURL, version["openshift_version"])
+				nmstatectlPath, err := is.NmstatectlPathForParams(version["openshift_version"], version["cpu_architecture"])
+				Expect(err).NotTo(HaveOccurred())
+				mockEditor.EXPECT().CreateMinimalISOTemplate(gomock.Any(), rootfs, "x86_64", gomock.Any(), version["openshift_version"], nmstatectlPath).Return(nil)
+				Expect(is.Populate(ctx)).To(Succeed())
+
+				content, err := os.ReadFile(filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-x86_64.iso"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(content).To(Equal(isoContent))
+			})
+
 			It("downloads image with x.y.z openshift_version correctly", func() {
 				isoContent, isoHeader := isoInfo(validVolumeID)
 				ts.AppendHandlers(
@@ -408,7 +557,7 @@ var _ = Context("with a data directory configured", func() {
 					),
 				)
 				versionPatch["url"] = ts.URL() + "/somepatchversion.iso"
-				is, err := NewImageStore(mockEditor, dataDir, imageServiceBaseURL, false, []map[string]string{versionPatch}, "", osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{versionPatch}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 				Expect(err).NotTo(HaveOccurred())
 
 				rootfs := fmt.Sprintf(rootfsURL, versionPatch["openshift_version"])
@@ -433,7 +582,7 @@ var _ = Context("with a data directory configured", func() {
 						),
 					)
 					versionPatch["url"] = ts.URL() + "/somepatchversion.iso"
-					is, err := NewImageStore(mockEditor, dataDir, imageServiceBaseURL, false, []map[string]string{versionPatch}, "", osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+					is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{versionPatch}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 					Expect(err).NotTo(HaveOccurred())
 
 					rootfs := fmt.Sprintf(rootfsURL, versionPatch["openshift_version"])
@@ -456,7 +605,7 @@ var _ = Context("with a data directory configured", func() {
 					),
 				)
 				version["url"] = ts.URL() + "/some.iso"
-				is, err := NewImageStore(mockEditor, dataDir, imageServiceBaseURL, false, []map[string]string{version}, "", osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 				Expect(err).NotTo(HaveOccurred())
 
 				rootfs := fmt.Sprintf(rootfsURL, version["openshift_version"])
@@ -477,7 +626,7 @@ var _ = Context("with a data directory configured", func() {
 					),
 				)
 				version["url"] = ts.URL() + "/some.iso"
-				is, err := NewImageStore(mockEditor, dataDir, imageServiceBaseURL, false, []map[string]string{version}, "", osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 				Expect(err).NotTo(HaveOccurred())
 
 				err = is.Populate(ctx)
@@ -489,7 +638,7 @@ var _ = Context("with a data directory configured", func() {
 			})
 
 			It("fails when imageServiceBaseURL is not set", func() {
-				is, err := NewImageStore(mockEditor, dataDir, "", false, []map[string]string{version}, "", osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 				Expect(err).NotTo(HaveOccurred())
 				nmstatectlPath, err := is.NmstatectlPathForParams(version["openshift_version"], version["cpu_architecture"])
 				Expect(err).NotTo(HaveOccurred())
@@ -507,7 +656,7 @@ var _ = Context("with a data directory configured", func() {
 				)
 				version["url"] = ts.URL() + "/some.iso"
 				baseURL := ":"
-				is, err := NewImageStore(mockEditor, dataDir, baseURL, false, []map[string]string{version}, "", osImageDownloadHeadersMap, osImageDownloadQueryParamsMap)
+				is, err := NewImageStore(ImageStoreOptions{Editor: mockEditor, DataDir: dataDir, ImageServiceBaseURL: baseURL, Versions: []map[string]string{version}, OSImageDownloadHeaders: osImageDownloadHeadersMap, OSImageDownloadQueryParams: osImageDownloadQueryParamsMap})
 				Expect(err).ToNot(HaveOccurred())
 
 				rootfs := fmt.Sprintf("https://images.example.com/api/assisted-images/boot-artifacts/rootfs?arch=x86_64&version=%s", version["openshift_version"])
@@ -530,7 +679,7 @@ var _ = Describe("PathForParams", func() {
 			"url":               "http://example.com/image/x86_64-48.iso",
 			"version":           "48.84.202109241901-0",
 		}}
-		is, err := NewImageStore(nil, "/tmp/some/dir", imageServiceBaseURL, false, versions, "", map[string]string{}, map[string]string{})
+		is, err := NewImageStore(ImageStoreOptions{DataDir: "/tmp/some/dir", ImageServiceBaseURL: imageServiceBaseURL, Versions: versions})
 		Expect(err).NotTo(HaveOccurred())
 		expected := "/tmp/some/dir/rhcos-full-4.8-48.84.202109241901-0-x86_64.iso"
 		Expect(is.PathForParams("full", "4.8", "x86_64")).To(Equal(expected))
@@ -564,7 +713,7 @@ var _ = Describe("HaveVersion", func() {
 
 	BeforeEach(func() {
 		var err error
-		store, err = NewImageStore(nil, "", imageServiceBaseURL, false, versions, "", map[string]string{}, map[string]string{})
+		store, err = NewImageStore(ImageStoreOptions{ImageServiceBaseURL: imageServiceBaseURL, Versions: versions})
 		Expect(err).NotTo(HaveOccurred())
 	})
 	AfterEach(func() {
@@ -595,13 +744,13 @@ var _ = Describe("NewImageStore", func() {
 				"version":           "48.84.202109241901-0",
 			},
 		}
-		_, err := NewImageStore(nil, "", imageServiceBaseURL, false, versions, "", map[string]string{}, map[string]string{})
+		_, err := NewImageStore(ImageStoreOptions{ImageServiceBaseURL: imageServiceBaseURL, Versions: versions})
 		Expect(err).NotTo(HaveOccurred())
 	})
 
 	It("should error when RHCOS_IMAGES are not set i.e. versions is an empty slice", func() {
 		versions := []map[string]string{}
-		_, err := NewImageStore(nil, "", imageServiceBaseURL, false, versions, "", map[string]string{}, map[string]string{})
+		_, err := NewImageStore(ImageStoreOptions{ImageServiceBaseURL: imageServiceBaseURL, Versions: versions})
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(Equal("invalid versions: must not be empty"))
 
@@ -615,7 +764,7 @@ var _ = Describe("NewImageStore", func() {
 				"version":          "48.84.202109241901-0",
 			},
 		}
-		_, err := NewImageStore(nil, "", imageServiceBaseURL, false, versions, "", map[string]string{}, map[string]string{})
+		_, err := NewImageStore(ImageStoreOptions{ImageServiceBaseURL: imageServiceBaseURL, Versions: versions})
 		Expect(err).To(HaveOccurred())
 	})
 
@@ -627,7 +776,7 @@ var _ = Describe("NewImageStore", func() {
 				"version":           "48.84.202109241901-0",
 			},
 		}
-		_, err := NewImageStore(nil, "", imageServiceBaseURL, false, versions, "", map[string]string{}, map[string]string{})
+		_, err := NewImageStore(ImageStoreOptions{ImageServiceBaseURL: imageServiceBaseURL, Versions: versions})
 		Expect(err).To(HaveOccurred())
 	})
 
@@ -639,10 +788,37 @@ var _ = Describe("NewImageStore", func() {
 				"version":           "48.84.202109241901-0",
 			},
 		}
-		_, err := NewImageStore(nil, "", imageServiceBaseURL, false, versions, "", map[string]string{}, map[string]string{})
+		_, err := NewImageStore(ImageStoreOptions{ImageServiceBaseURL: imageServiceBaseURL, Versions: versions})
 		Expect(err).To(HaveOccurred())
 	})
 
+	It("should error when sha256 is invalid", func() {
+		versions := []map[string]string{
+			{
+				"openshift_version": "4.8",
+				"cpu_architecture":  "x86_64",
+				"url":               "http://example.com/image/x86_64-48.iso",
+				"version":           "48.84.202109241901-0",
+				"sha256":            "abc",
+			},
+		}
+		_, err := NewImageStore(ImageStoreOptions{ImageServiceBaseURL: imageServiceBaseURL, Versions: versions})
+		Expect(err).To(MatchError(ContainSubstring("sha256 must be 64 hexadecimal characters")))
+	})
+
+	It("should error when the url is an invalid registry reference", func() {
+		versions := []map[string]string{
+			{
+				"openshift_version": "4.8",
+				"cpu_architecture":  "x86_64",
+				"url":               "oci://quay.io/org/rhcos@sha256:abc",
+				"version":           "48.84.202109241901-0",
+			},
+		}
+		_, err := NewImageStore(ImageStoreOptions{ImageServiceBaseURL: imageServiceBaseURL, Versions: versions})
+		Expect(err).To(MatchError(ContainSubstring("invalid digest")))
+	})
+
 	It("should error when version is not set", func() {
 		versions := []map[string]string{
 			{
@@ -651,7 +827,7 @@ var _ = Describe("NewImageStore", func() {
 				"url":               "http://example.com/image/x86_64-48.iso",
 			},
 		}
-		_, err := NewImageStore(nil, "", imageServiceBaseURL, false, versions, "", map[string]string{}, map[string]string{})
+		_, err := NewImageStore(ImageStoreOptions{ImageServiceBaseURL: imageServiceBaseURL, Versions: versions})
 		Expect(err).To(HaveOccurred())
 	})
 })
@@ -0,0 +1,28 @@
+package imagestore
+
+import (
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// deferMissingVersions marks the versions whose full ISO isn't in the data directory as pruned,
+// so that they are downloaded on their first request instead of at startup, and returns the
+// versions to populate
+func (s *rhcosStore) deferMissingVersions() []map[string]string {
+	s.usageLock.Lock()
+	defer s.usageLock.Unlock()
+	var present []map[string]string
+	for _, entry := range s.configuredVersions() {
+		fullPath := filepath.Join(s.dataDir, versionKey(entry))
+		if _, err := os.Stat(fullPath); err == nil {
+			present = append(present, entry)
+			continue
+		}
+		log.Infof("Deferring the download of %s-%s (%s) to its first request", entry["openshift_version"], entry["cpu_architecture"], entry["version"])
+		s.pruned[versionKey(entry)] = 0
+	}
+	prunedVersions.Store(int64(len(s.pruned)))
+	return present
+}
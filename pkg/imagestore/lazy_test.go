@@ -0,0 +1,86 @@
+package imagestore
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("lazy download", func() {
+	var (
+		dataDir    string
+		ts         *ghttp.Server
+		isoContent []byte
+		store      ImageStore
+		iso48      string
+		iso49      string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = os.MkdirTemp("", "lazyTest")
+		Expect(err).NotTo(HaveOccurred())
+		iso48 = filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-s390x.iso")
+		iso49 = filepath.Join(dataDir, "rhcos-full-iso-4.9-49.84.202110081407-0-s390x.iso")
+
+		isoContent = make([]byte, 32840)
+		copy(isoContent[32808:], "rhcos-411.86.202210041459-0")
+		ts = ghttp.NewServer()
+		ts.RouteToHandler("GET", "/sha256sum.txt", ghttp.RespondWith(http.StatusNotFound, nil))
+		ts.RouteToHandler("GET", "/rhcos.iso", ghttp.RespondWith(http.StatusOK, isoContent,
+			http.Header{"Content-Length": []string{strconv.Itoa(len(isoContent))}}))
+		versions := []map[string]string{
+			{
+				"openshift_version": "4.8",
+				"cpu_architecture":  "s390x",
+				"version":           "48.84.202109241901-0",
+				"url":               ts.URL() + "/rhcos.iso",
+			},
+			{
+				"openshift_version": "4.9",
+				"cpu_architecture":  "s390x",
+				"version":           "49.84.202110081407-0",
+				"url":               ts.URL() + "/rhcos.iso",
+			},
+		}
+		store, err = NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: versions, Lazy: true})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		ts.Close()
+		os.RemoveAll(dataDir)
+	})
+
+	It("downloads the versions on their first request", func() {
+		Expect(store.Populate(context.Background())).To(Succeed())
+		Expect(ts.ReceivedRequests()).To(BeEmpty())
+		Expect(iso48).NotTo(BeAnExistingFile())
+
+		Expect(store.Available("4.8", "s390x")).To(BeFalse())
+		Eventually(func() bool { return store.Available("4.8", "s390x") }).Should(BeTrue())
+		Expect(os.ReadFile(iso48)).To(Equal(isoContent))
+		// the ISO and its checksums
+		Expect(ts.ReceivedRequests()).To(HaveLen(2))
+		Expect(iso49).NotTo(BeAnExistingFile())
+	})
+
+	It("keeps the versions already downloaded", func() {
+		Expect(os.WriteFile(iso49, isoContent, 0600)).To(Succeed())
+		Expect(store.Populate(context.Background())).To(Succeed())
+		Expect(store.Available("4.9", "s390x")).To(BeTrue())
+		Expect(ts.ReceivedRequests()).To(BeEmpty())
+	})
+
+	It("doesn't have unconfigured versions", func() {
+		Expect(store.Populate(context.Background())).To(Succeed())
+		Expect(store.Available("4.7", "s390x")).To(BeFalse())
+		Expect(ts.ReceivedRequests()).To(BeEmpty())
+	})
+})
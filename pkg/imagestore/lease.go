@@ -0,0 +1,146 @@
+package imagestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/renameio"
+	log "github.com/sirupsen/logrus"
+)
+
+// leaseSuffix is the suffix of the lease files next to the images downloaded by a replica, when
+// the replicas share the data directory
+const leaseSuffix = ".lease"
+
+var (
+	// leaseDuration is how long a lease is held without being renewed, after which another
+	// replica takes it over
+	leaseDuration      = 2 * time.Minute
+	leaseRenewInterval = 30 * time.Second
+	leasePollInterval  = 5 * time.Second
+)
+
+// downloadLease is the content of a lease file
+type downloadLease struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+func readLease(leasePath string) (*downloadLease, error) {
+	data, err := os.ReadFile(leasePath)
+	if err != nil {
+		return nil, err
+	}
+	lease := &downloadLease{}
+	if err := json.Unmarshal(data, lease); err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// replicaID identifies the replica in the leases
+func replicaID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
+}
+
+// acquireLease takes the lease of path, waiting while another replica holds it. It returns
+// false, without the lease, as soon as done reports that the other replica produced path.
+// The lease is renewed until release is called.
+func (s *rhcosStore) acquireLease(ctx context.Context, path string, done func() bool) (func(), bool, error) {
+	leasePath := path + leaseSuffix
+	for {
+		if done() {
+			return nil, false, nil
+		}
+		acquired, err := s.tryLease(leasePath)
+		if err != nil {
+			return nil, false, err
+		}
+		if acquired {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-time.After(leasePollInterval):
+		}
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(leaseRenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := s.writeLease(leasePath); err != nil {
+					log.WithError(err).Warnf("Failed to renew the lease %s", leasePath)
+				}
+			}
+		}
+	}()
+	release := func() {
+		close(stop)
+		<-stopped
+		if lease, err := readLease(leasePath); err == nil && lease.Owner == s.replicaID {
+			os.Remove(leasePath)
+		}
+	}
+	return release, true, nil
+}
+
+// tryLease creates the lease file, or takes it over when it expired
+func (s *rhcosStore) tryLease(leasePath string) (bool, error) {
+	data, err := json.Marshal(&downloadLease{Owner: s.replicaID, Expires: time.Now().Add(leaseDuration)})
+	if err != nil {
+		return false, err
+	}
+	f, err := os.OpenFile(leasePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err == nil {
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return err == nil, err
+	}
+	if !os.IsExist(err) {
+		return false, err
+	}
+
+	lease, err := readLease(leasePath)
+	if err != nil {
+		// the lease is being written, unless its owner died meanwhile
+		info, statErr := os.Stat(leasePath)
+		if statErr != nil || time.Since(info.ModTime()) < leaseDuration {
+			return false, nil
+		}
+	} else if lease.Owner != s.replicaID && time.Now().Before(lease.Expires) {
+		return false, nil
+	}
+	log.Infof("Taking over the expired lease %s", leasePath)
+	if err := s.writeLease(leasePath); err != nil {
+		return false, err
+	}
+	// another replica may have taken it over at the same time
+	lease, err = readLease(leasePath)
+	return err == nil && lease.Owner == s.replicaID, nil
+}
+
+func (s *rhcosStore) writeLease(leasePath string) error {
+	data, err := json.Marshal(&downloadLease{Owner: s.replicaID, Expires: time.Now().Add(leaseDuration)})
+	if err != nil {
+		return err
+	}
+	return renameio.WriteFile(leasePath, data, 0600)
+}
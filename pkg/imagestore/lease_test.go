@@ -0,0 +1,125 @@
+package imagestore
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("download coordination", func() {
+	var (
+		dataDir    string
+		ts         *ghttp.Server
+		isoContent []byte
+		versions   []map[string]string
+		fullISO    string
+		unblock    chan struct{}
+		savedPoll  time.Duration
+		ctx        = context.Background()
+	)
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = os.MkdirTemp("", "leaseTest")
+		Expect(err).NotTo(HaveOccurred())
+		fullISO = filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-s390x.iso")
+
+		isoContent = make([]byte, 32840)
+		copy(isoContent[32808:], "rhcos-411.86.202210041459-0")
+		unblock = make(chan struct{})
+		ts = ghttp.NewServer()
+		ts.RouteToHandler("GET", "/rhcos.iso", ghttp.CombineHandlers(
+			func(w http.ResponseWriter, r *http.Request) { <-unblock },
+			ghttp.RespondWith(http.StatusOK, isoContent, http.Header{"Content-Length": []string{strconv.Itoa(len(isoContent))}}),
+		))
+		versions = []map[string]string{{
+			"openshift_version": "4.8",
+			"cpu_architecture":  "s390x",
+			"version":           "48.84.202109241901-0",
+			"url":               ts.URL() + "/rhcos.iso",
+			"sha256":            sha256Of(isoContent),
+		}}
+		savedPoll = leasePollInterval
+		leasePollInterval = 10 * time.Millisecond
+	})
+
+	AfterEach(func() {
+		ts.Close()
+		os.RemoveAll(dataDir)
+		leasePollInterval = savedPoll
+	})
+
+	newStore := func(shared bool) *rhcosStore {
+		is, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: versions, SharedDataDir: shared})
+		Expect(err).NotTo(HaveOccurred())
+		return is.(*rhcosStore)
+	}
+
+	populateConcurrently := func(stores ...*rhcosStore) {
+		var wg sync.WaitGroup
+		for _, store := range stores {
+			wg.Add(1)
+			go func(store *rhcosStore) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(store.populateFullISO(ctx, versions[0])).To(Succeed())
+			}(store)
+		}
+		Eventually(ts.ReceivedRequests).Should(HaveLen(1))
+		Consistently(ts.ReceivedRequests, 100*time.Millisecond).Should(HaveLen(1))
+		close(unblock)
+		wg.Wait()
+		Expect(os.ReadFile(fullISO)).To(Equal(isoContent))
+	}
+
+	It("downloads an ISO once within a replica", func() {
+		store := newStore(false)
+		populateConcurrently(store, store, store)
+	})
+
+	It("downloads an ISO once across the replicas sharing the data directory", func() {
+		populateConcurrently(newStore(true), newStore(true), newStore(true))
+		Expect(fullISO + leaseSuffix).NotTo(BeAnExistingFile())
+	})
+
+	It("waits for the replica holding the lease to download the ISO", func() {
+		close(unblock)
+		data, err := json.Marshal(&downloadLease{Owner: "other", Expires: time.Now().Add(time.Hour)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(fullISO+leaseSuffix, data, 0600)).To(Succeed())
+
+		done := make(chan error)
+		go func() { done <- newStore(true).populateFullISO(ctx, versions[0]) }()
+		Consistently(done, 100*time.Millisecond).ShouldNot(Receive())
+		Expect(os.WriteFile(fullISO, isoContent, 0600)).To(Succeed())
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(ts.ReceivedRequests()).To(BeEmpty())
+	})
+
+	It("takes over an expired lease", func() {
+		close(unblock)
+		data, err := json.Marshal(&downloadLease{Owner: "other", Expires: time.Now().Add(-time.Second)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(fullISO+leaseSuffix, data, 0600)).To(Succeed())
+
+		Expect(newStore(true).populateFullISO(ctx, versions[0])).To(Succeed())
+		Expect(os.ReadFile(fullISO)).To(Equal(isoContent))
+		Expect(fullISO + leaseSuffix).NotTo(BeAnExistingFile())
+	})
+
+	It("keeps the leases of the other replicas when cleaning the data directory", func() {
+		close(unblock)
+		Expect(os.WriteFile(fullISO+leaseSuffix, []byte("{}"), 0600)).To(Succeed())
+		Expect(newStore(true).cleanDataDir()).To(Succeed())
+		Expect(fullISO + leaseSuffix).To(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,141 @@
+package imagestore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openshift/assisted-image-service/pkg/registry"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// mirrorURLsKey is the version key listing, comma separated, the URLs tried in order after url
+const mirrorURLsKey = "mirror_urls"
+
+var (
+	// mirrorBackoff is how long a mirror is tried last after a failure, doubled on each
+	// consecutive failure up to maxMirrorBackoff
+	mirrorBackoff    = time.Minute
+	maxMirrorBackoff = time.Hour
+)
+
+// imageURLs returns the url of the version followed by its mirrors
+func imageURLs(imageInfo map[string]string) []string {
+	urls := []string{imageInfo["url"]}
+	for _, mirror := range strings.Split(imageInfo[mirrorURLsKey], ",") {
+		if mirror = strings.TrimSpace(mirror); mirror != "" {
+			urls = append(urls, mirror)
+		}
+	}
+	return urls
+}
+
+// mirrorStatus is the health of a URL the images are downloaded from
+type mirrorStatus struct {
+	failures    int
+	unhealthyTo time.Time
+}
+
+// mirrorHealth tracks the failures of the URLs, so that the failing mirrors are tried last
+type mirrorHealth struct {
+	lock     sync.Mutex
+	statuses map[string]*mirrorStatus
+}
+
+func newMirrorHealth() *mirrorHealth {
+	return &mirrorHealth{statuses: map[string]*mirrorStatus{}}
+}
+
+// order returns the healthy urls first, keeping the configured order otherwise
+func (h *mirrorHealth) order(urls []string) []string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	now := time.Now()
+	var healthy, unhealthy []string
+	for _, url := range urls {
+		if status, ok := h.statuses[url]; ok && now.Before(status.unhealthyTo) {
+			unhealthy = append(unhealthy, url)
+		} else {
+			healthy = append(healthy, url)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+func (h *mirrorHealth) succeeded(url string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.statuses, url)
+}
+
+func (h *mirrorHealth) failed(url string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	status, ok := h.statuses[url]
+	if !ok {
+		status = &mirrorStatus{}
+		h.statuses[url] = status
+	}
+	status.failures++
+	backoff := mirrorBackoff
+	for i := 1; i < status.failures && backoff < maxMirrorBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxMirrorBackoff {
+		backoff = maxMirrorBackoff
+	}
+	status.unhealthyTo = time.Now().Add(backoff)
+}
+
+// downloadFromMirrors downloads the ISO of the version to path from the first of its URLs
+// serving a valid image
+func (s *rhcosStore) downloadFromMirrors(ctx context.Context, imageInfo map[string]string, path string, sha256sums ...string) error {
+	var err error
+	urls := s.mirrors.order(imageURLs(imageInfo))
+	for i, url := range urls {
+		if err = s.downloadISO(ctx, url, imageInfo["cpu_architecture"], path, sha256sums...); err == nil {
+			s.mirrors.succeeded(url)
+			return nil
+		}
+		s.mirrors.failed(url)
+		if ctx.Err() != nil {
+			return err
+		}
+		if i < len(urls)-1 {
+			log.WithError(err).Warnf("Failed to get the ISO of %s-%s from %s, trying %s", imageInfo["openshift_version"], imageInfo["cpu_architecture"], url, urls[i+1])
+		}
+	}
+	return err
+}
+
+// downloadISO downloads the ISO at url to path and validates it
+func (s *rhcosStore) downloadISO(ctx context.Context, url, arch, path string, sha256sums ...string) error {
+	log.Infof("Downloading iso from %s to %s", url, path)
+	var err error
+	if registry.IsReference(url) {
+		err = s.pullImageToFile(ctx, url, arch, path)
+	} else {
+		err = s.downloadURLToFile(ctx, url, path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %v", url, err)
+	}
+	return removeInvalidISO(path, sha256sums...)
+}
+
+// removeInvalidISO validates the ISO at path, removing it if it is invalid
+func removeInvalidISO(path string, sha256sums ...string) error {
+	if err := validateISO(path, sha256sums...); err != nil {
+		message := fmt.Sprintf("failed to validate %s: %v", path, err)
+		if err = os.Remove(path); err != nil {
+			log.WithError(err).Errorf("failed to remove invalid ISO %s", path)
+		}
+		log.Error(message)
+		return errors.New(message)
+	}
+	return nil
+}
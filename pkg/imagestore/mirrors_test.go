@@ -0,0 +1,115 @@
+package imagestore
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("mirrors", func() {
+	It("lists the url before the mirrors", func() {
+		Expect(imageURLs(map[string]string{"url": "https://a/rhcos.iso"})).To(Equal([]string{"https://a/rhcos.iso"}))
+		Expect(imageURLs(map[string]string{"url": "https://a/rhcos.iso", mirrorURLsKey: "https://b/rhcos.iso, oci://c/rhcos:4.8,"})).To(
+			Equal([]string{"https://a/rhcos.iso", "https://b/rhcos.iso", "oci://c/rhcos:4.8"}))
+	})
+
+	It("tries the failing mirrors last until they recover", func() {
+		h := newMirrorHealth()
+		urls := []string{"a", "b", "c"}
+		h.failed("a")
+		Expect(h.order(urls)).To(Equal([]string{"b", "c", "a"}))
+		h.failed("b")
+		Expect(h.order(urls)).To(Equal([]string{"c", "a", "b"}))
+		h.succeeded("a")
+		Expect(h.order(urls)).To(Equal([]string{"a", "c", "b"}))
+
+		h.statuses["b"].unhealthyTo = time.Now().Add(-time.Second)
+		Expect(h.order(urls)).To(Equal(urls))
+	})
+
+	It("backs off exponentially up to the maximum", func() {
+		h := newMirrorHealth()
+		h.failed("a")
+		Expect(time.Until(h.statuses["a"].unhealthyTo)).To(BeNumerically("~", mirrorBackoff, time.Second))
+		h.failed("a")
+		Expect(time.Until(h.statuses["a"].unhealthyTo)).To(BeNumerically("~", 2*mirrorBackoff, time.Second))
+		for i := 0; i < 20; i++ {
+			h.failed("a")
+		}
+		Expect(time.Until(h.statuses["a"].unhealthyTo)).To(BeNumerically("~", maxMirrorBackoff, time.Second))
+	})
+
+	Context("Populate", func() {
+		var (
+			dataDir    string
+			ts         *ghttp.Server
+			isoContent []byte
+			version    map[string]string
+			fullISO    string
+		)
+
+		BeforeEach(func() {
+			var err error
+			dataDir, err = os.MkdirTemp("", "mirrorsTest")
+			Expect(err).NotTo(HaveOccurred())
+			fullISO = filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-s390x.iso")
+
+			isoContent = make([]byte, 32840)
+			copy(isoContent[32808:], "rhcos-411.86.202210041459-0")
+			ts = ghttp.NewServer()
+			ts.RouteToHandler("GET", "/down/rhcos.iso", ghttp.RespondWith(http.StatusServiceUnavailable, nil))
+			ts.RouteToHandler("GET", "/invalid/rhcos.iso", ghttp.RespondWith(http.StatusOK, make([]byte, 32840),
+				http.Header{"Content-Length": []string{"32840"}}))
+			ts.RouteToHandler("GET", "/mirror/rhcos.iso", ghttp.RespondWith(http.StatusOK, isoContent,
+				http.Header{"Content-Length": []string{strconv.Itoa(len(isoContent))}}))
+			version = map[string]string{
+				"openshift_version": "4.8",
+				"cpu_architecture":  "s390x",
+				"version":           "48.84.202109241901-0",
+				"url":               ts.URL() + "/down/rhcos.iso",
+				mirrorURLsKey:       ts.URL() + "/invalid/rhcos.iso," + ts.URL() + "/mirror/rhcos.iso",
+				"sha256":            sha256Of(isoContent),
+			}
+		})
+
+		AfterEach(func() {
+			ts.Close()
+			os.RemoveAll(dataDir)
+		})
+
+		It("falls back to the mirrors and tries the failed URLs last afterwards", func() {
+			is, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(is.Populate(context.Background())).To(Succeed())
+			Expect(os.ReadFile(fullISO)).To(Equal(isoContent))
+			Expect(ts.ReceivedRequests()).To(HaveLen(3))
+
+			Expect(os.Remove(fullISO)).To(Succeed())
+			Expect(is.Populate(context.Background())).To(Succeed())
+			Expect(os.ReadFile(fullISO)).To(Equal(isoContent))
+			Expect(ts.ReceivedRequests()).To(HaveLen(4))
+			Expect(ts.ReceivedRequests()[3].URL.Path).To(Equal("/mirror/rhcos.iso"))
+		})
+
+		It("fails with the error of the last URL", func() {
+			version[mirrorURLsKey] = ts.URL() + "/invalid/rhcos.iso"
+			is, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(is.Populate(context.Background())).To(MatchError(ContainSubstring("failed to validate")))
+			Expect(fullISO).NotTo(BeAnExistingFile())
+		})
+
+		It("rejects invalid mirror references", func() {
+			version[mirrorURLsKey] = "oci://quay.io/Org/rhcos"
+			_, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
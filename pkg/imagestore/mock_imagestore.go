@@ -6,7 +6,9 @@ package imagestore
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,6 +36,35 @@ func (m *MockImageStore) EXPECT() *MockImageStoreMockRecorder {
 	return m.recorder
 }
 
+// AddBaseISO mocks base method.
+func (m *MockImageStore) AddBaseISO(arg0 context.Context, arg1, arg2, arg3 string, arg4 io.Reader) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddBaseISO", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddBaseISO indicates an expected call of AddBaseISO.
+func (mr *MockImageStoreMockRecorder) AddBaseISO(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBaseISO", reflect.TypeOf((*MockImageStore)(nil).AddBaseISO), arg0, arg1, arg2, arg3, arg4)
+}
+
+// Available mocks base method.
+func (m *MockImageStore) Available(arg0, arg1 string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Available", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Available indicates an expected call of Available.
+func (mr *MockImageStoreMockRecorder) Available(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Available", reflect.TypeOf((*MockImageStore)(nil).Available), arg0, arg1)
+}
+
 // HaveVersion mocks base method.
 func (m *MockImageStore) HaveVersion(arg0, arg1 string) bool {
 	m.ctrl.T.Helper()
@@ -77,6 +108,20 @@ func (mr *MockImageStoreMockRecorder) PathForParams(arg0, arg1, arg2 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PathForParams", reflect.TypeOf((*MockImageStore)(nil).PathForParams), arg0, arg1, arg2)
 }
 
+// Prune mocks base method.
+func (m *MockImageStore) Prune(arg0 time.Duration, arg1 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Prune", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Prune indicates an expected call of Prune.
+func (mr *MockImageStoreMockRecorder) Prune(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Prune", reflect.TypeOf((*MockImageStore)(nil).Prune), arg0, arg1)
+}
+
 // Populate mocks base method.
 func (m *MockImageStore) Populate(arg0 context.Context) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,240 @@
+package imagestore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+	log "github.com/sirupsen/logrus"
+)
+
+// PruneStats reports the files removed from the data directory by Prune
+type PruneStats struct {
+	// Number of files removed
+	RemovedFiles uint64
+	// Number of bytes freed
+	RemovedBytes uint64
+	// Number of files the last run removed, or would have removed in dry-run mode
+	PrunableFiles int64
+	// Number of bytes the last run freed, or would have freed in dry-run mode
+	PrunableBytes int64
+	// Number of versions pruned for not being served within the TTL
+	PrunedVersions int64
+}
+
+var (
+	removedFiles   atomic.Uint64
+	removedBytes   atomic.Uint64
+	prunableFiles  atomic.Int64
+	prunableBytes  atomic.Int64
+	prunedVersions atomic.Int64
+)
+
+// GetPruneStats returns the files removed from the data directory by Prune
+func GetPruneStats() PruneStats {
+	return PruneStats{
+		RemovedFiles:   removedFiles.Load(),
+		RemovedBytes:   removedBytes.Load(),
+		PrunableFiles:  prunableFiles.Load(),
+		PrunableBytes:  prunableBytes.Load(),
+		PrunedVersions: prunedVersions.Load(),
+	}
+}
+
+// StartPruning calls Prune every interval until ctx is done
+func StartPruning(ctx context.Context, is ImageStore, interval, ttl time.Duration, dryRun bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := is.Prune(ttl, dryRun); err != nil {
+				log.WithError(err).Error("Failed to prune the data directory")
+			}
+		}
+	}
+}
+
+// versionKey identifies the version in the usage records
+func versionKey(entry map[string]string) string {
+	return isoFileName(ImageTypeFull, entry["openshift_version"], entry["version"], entry["cpu_architecture"])
+}
+
+// versionFiles returns the names of the files of the version in the data directory
+func versionFiles(entry map[string]string) []string {
+	openshiftVersion, version, arch := entry["openshift_version"], entry["version"], entry["cpu_architecture"]
+	fullISO := isoFileName(ImageTypeFull, openshiftVersion, version, arch)
+	minimalISO := isoFileName(ImageTypeMinimal, openshiftVersion, version, arch)
+	return append([]string{
+		fullISO, fullISO + partialSuffix, fullISO + stateSuffix, fullISO + isoeditor.FileOffsetsSuffix,
+		minimalISO, minimalISO + isoeditor.FileOffsetsSuffix,
+		nmstatectlFileName(openshiftVersion, version, arch),
+	}, diskImageFiles(entry)...)
+}
+
+// resetUsage starts the TTL of the versions
+func (s *rhcosStore) resetUsage() {
+	s.usageLock.Lock()
+	defer s.usageLock.Unlock()
+	now := time.Now()
+	for _, entry := range s.configuredVersions() {
+		s.lastUsed[versionKey(entry)] = now
+	}
+}
+
+// markUsed records that the version is served
+func (s *rhcosStore) markUsed(entry map[string]string) {
+	s.usageLock.Lock()
+	defer s.usageLock.Unlock()
+	s.lastUsed[versionKey(entry)] = time.Now()
+}
+
+// Available returns true when the images of the version are in the data directory. Otherwise,
+// the pruned images are populated again in the background for a later request.
+func (s *rhcosStore) Available(openshiftVersion, arch string) bool {
+	entry := s.versionEntry(openshiftVersion, arch)
+	if entry == nil {
+		return false
+	}
+	key := versionKey(entry)
+	s.usageLock.Lock()
+	defer s.usageLock.Unlock()
+	s.lastUsed[key] = time.Now()
+	if _, pruned := s.pruned[key]; !pruned {
+		return true
+	}
+	if !s.restoring[key] {
+		s.restoring[key] = true
+		go s.restore(entry)
+	}
+	return false
+}
+
+// restore populates the pruned version again
+func (s *rhcosStore) restore(entry map[string]string) {
+	key := versionKey(entry)
+	defer func() {
+		s.usageLock.Lock()
+		defer s.usageLock.Unlock()
+		delete(s.restoring, key)
+	}()
+	s.restoreLock.Lock()
+	defer s.restoreLock.Unlock()
+	s.usageLock.Lock()
+	size, pruned := s.pruned[key]
+	s.usageLock.Unlock()
+	if !pruned {
+		return
+	}
+
+	s.pruneLock.RLock()
+	defer s.pruneLock.RUnlock()
+	log.Infof("Restoring pruned version %s", key)
+	if err := s.enforceDiskBudget(key, size); err != nil {
+		log.WithError(err).Errorf("Failed to make room for pruned version %s", key)
+	}
+	ctx := context.Background()
+	err := s.populateFullISO(ctx, entry)
+	if err == nil {
+		err = s.populateMinimalISO(ctx, entry)
+	}
+	if err == nil {
+		err = s.populateDiskImages(ctx, entry)
+	}
+	if err != nil {
+		log.WithError(err).Errorf("Failed to restore pruned version %s", key)
+		return
+	}
+
+	s.usageLock.Lock()
+	delete(s.pruned, key)
+	s.lastUsed[key] = time.Now()
+	prunedVersions.Store(int64(len(s.pruned)))
+	s.usageLock.Unlock()
+	if err := s.enforceDiskBudget(key, 0); err != nil {
+		log.WithError(err).Error("Failed to enforce the disk budget")
+	}
+}
+
+// Prune removes the files of the data directory that don't belong to the configured versions and,
+// if ttl isn't 0, the files of the versions that weren't served within ttl, which are then
+// populated again when they are requested. In dry-run mode, the files are only logged.
+func (s *rhcosStore) Prune(ttl time.Duration, dryRun bool) error {
+	s.pruneLock.Lock()
+	defer s.pruneLock.Unlock()
+
+	dataDirFiles, err := os.ReadDir(s.dataDir)
+	if err != nil {
+		return err
+	}
+
+	s.usageLock.Lock()
+	defer s.usageLock.Unlock()
+	now := time.Now()
+	expected := map[string]string{}
+	// bytes of the files of the expired versions
+	expired := map[string]int64{}
+	for _, entry := range s.configuredVersions() {
+		key := versionKey(entry)
+		for _, name := range versionFiles(entry) {
+			expected[name] = key
+		}
+		// the lease of another replica downloading the version
+		expected[key+leaseSuffix] = ""
+		if _, pruned := s.pruned[key]; ttl > 0 && !pruned && s.restorable(entry) && now.Sub(s.lastUsed[key]) > ttl {
+			expired[key] = 0
+		}
+	}
+
+	expected[baseISOsFile] = ""
+
+	var files, bytes int64
+	for _, dataDirFile := range dataDirFiles {
+		key, ok := expected[dataDirFile.Name()]
+		reason := "not configured"
+		if ok {
+			if _, isExpired := expired[key]; !isExpired {
+				continue
+			}
+			reason = "not served within " + ttl.String()
+		}
+		fileName := filepath.Join(s.dataDir, dataDirFile.Name())
+		var size int64
+		if info, err := dataDirFile.Info(); err == nil && !info.IsDir() {
+			size = info.Size()
+		}
+		if ok {
+			expired[key] += size
+		}
+		files++
+		bytes += size
+		if dryRun {
+			log.Infof("Would remove %s from data directory (%s)", fileName, reason)
+			continue
+		}
+		log.Infof("Removing %s from data directory (%s)", fileName, reason)
+		if err := os.RemoveAll(fileName); err != nil {
+			return err
+		}
+		removedFiles.Add(1)
+		removedBytes.Add(uint64(size))
+	}
+	prunableFiles.Store(files)
+	prunableBytes.Store(bytes)
+
+	if !dryRun {
+		for key, size := range expired {
+			s.pruned[key] = size
+		}
+		prunedVersions.Store(int64(len(s.pruned)))
+		if used, err := s.dataDirUsage(); err == nil {
+			diskUsed.Store(used)
+		}
+	}
+	return nil
+}
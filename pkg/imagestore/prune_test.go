@@ -0,0 +1,104 @@
+package imagestore
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Prune", func() {
+	var (
+		dataDir    string
+		ts         *ghttp.Server
+		isoContent []byte
+		store      *rhcosStore
+		fullISO    string
+		ctx        = context.Background()
+	)
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = os.MkdirTemp("", "pruneTest")
+		Expect(err).NotTo(HaveOccurred())
+
+		isoContent = make([]byte, 32840)
+		copy(isoContent[32808:], "rhcos-411.86.202210041459-0")
+		ts = ghttp.NewServer()
+		ts.RouteToHandler("GET", "/sha256sum.txt", ghttp.RespondWith(http.StatusNotFound, nil))
+		ts.RouteToHandler("GET", "/rhcos.iso", ghttp.RespondWith(http.StatusOK, isoContent,
+			http.Header{"Content-Length": []string{strconv.Itoa(len(isoContent))}}))
+		versions := []map[string]string{{
+			"openshift_version": "4.8",
+			"cpu_architecture":  "s390x",
+			"version":           "48.84.202109241901-0",
+			"url":               ts.URL() + "/rhcos.iso",
+		}}
+		is, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: versions})
+		Expect(err).NotTo(HaveOccurred())
+		store = is.(*rhcosStore)
+		Expect(store.Populate(ctx)).To(Succeed())
+		fullISO = filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-s390x.iso")
+		Expect(fullISO).To(BeAnExistingFile())
+		Expect(os.WriteFile(filepath.Join(dataDir, "rhcos-full-iso-4.7-47.84.202109241831-0-s390x.iso"), []byte("old"), 0600)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		ts.Close()
+		os.RemoveAll(dataDir)
+	})
+
+	expire := func() {
+		store.usageLock.Lock()
+		defer store.usageLock.Unlock()
+		for key := range store.lastUsed {
+			store.lastUsed[key] = time.Now().Add(-2 * time.Hour)
+		}
+	}
+
+	It("removes the files of the versions that aren't configured", func() {
+		removed := GetPruneStats().RemovedFiles
+		Expect(store.Prune(0, false)).To(Succeed())
+		Expect(filepath.Join(dataDir, "rhcos-full-iso-4.7-47.84.202109241831-0-s390x.iso")).NotTo(BeAnExistingFile())
+		Expect(fullISO).To(BeAnExistingFile())
+		stats := GetPruneStats()
+		Expect(stats.RemovedFiles).To(Equal(removed + 1))
+		Expect(stats.PrunableFiles).To(Equal(int64(1)))
+		Expect(stats.PrunableBytes).To(Equal(int64(3)))
+	})
+
+	It("only logs the files in dry-run mode", func() {
+		expire()
+		Expect(store.Prune(time.Hour, true)).To(Succeed())
+		Expect(filepath.Join(dataDir, "rhcos-full-iso-4.7-47.84.202109241831-0-s390x.iso")).To(BeAnExistingFile())
+		Expect(fullISO).To(BeAnExistingFile())
+		stats := GetPruneStats()
+		Expect(stats.PrunableFiles).To(Equal(int64(2)))
+		Expect(stats.PrunableBytes).To(Equal(int64(len(isoContent) + 3)))
+	})
+
+	It("keeps the versions requested within the TTL", func() {
+		expire()
+		store.PathForParams(ImageTypeFull, "4.8", "s390x")
+		Expect(store.Prune(time.Hour, false)).To(Succeed())
+		Expect(fullISO).To(BeAnExistingFile())
+	})
+
+	It("removes the versions that weren't requested within the TTL and restores them when requested", func() {
+		expire()
+		Expect(store.Prune(time.Hour, false)).To(Succeed())
+		Expect(fullISO).NotTo(BeAnExistingFile())
+		Expect(GetPruneStats().PrunedVersions).To(Equal(int64(1)))
+
+		Expect(store.Available("4.8", "s390x")).To(BeFalse())
+		Eventually(func() bool { return store.Available("4.8", "s390x") }).Should(BeTrue())
+		Expect(os.ReadFile(fullISO)).To(Equal(isoContent))
+		Expect(GetPruneStats().PrunedVersions).To(Equal(int64(0)))
+	})
+})
@@ -0,0 +1,109 @@
+package imagestore
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DiskStats reports the usage of the disk budget of the data directory
+type DiskStats struct {
+	// Configured budget in bytes, 0 when unlimited
+	Budget int64
+	// Bytes of the files of the data directory
+	Used int64
+	// Number of versions evicted to stay within the budget
+	Evictions uint64
+}
+
+var (
+	diskBudget    atomic.Int64
+	diskUsed      atomic.Int64
+	diskEvictions atomic.Uint64
+)
+
+// GetDiskStats returns the usage of the disk budget of the data directory
+func GetDiskStats() DiskStats {
+	return DiskStats{
+		Budget:    diskBudget.Load(),
+		Used:      diskUsed.Load(),
+		Evictions: diskEvictions.Load(),
+	}
+}
+
+// dataDirUsage returns the bytes of the files of the data directory
+func (s *rhcosStore) dataDirUsage() (int64, error) {
+	dataDirFiles, err := os.ReadDir(s.dataDir)
+	if err != nil {
+		return 0, err
+	}
+	var used int64
+	for _, dataDirFile := range dataDirFiles {
+		if info, err := dataDirFile.Info(); err == nil && info.Mode().IsRegular() {
+			used += info.Size()
+		}
+	}
+	return used, nil
+}
+
+// enforceDiskBudget evicts the least recently served versions until the data directory fits in
+// the disk budget with incoming more bytes. The version with the keep key is never evicted, since
+// it is being requested. The evicted versions are populated again when they are requested.
+func (s *rhcosStore) enforceDiskBudget(keep string, incoming int64) error {
+	used, err := s.dataDirUsage()
+	if err != nil {
+		return err
+	}
+	diskBudget.Store(s.diskBudget)
+	diskUsed.Store(used)
+	if s.diskBudget <= 0 || used+incoming <= s.diskBudget {
+		return nil
+	}
+
+	s.usageLock.Lock()
+	defer s.usageLock.Unlock()
+	var candidates []map[string]string
+	for _, entry := range s.configuredVersions() {
+		key := versionKey(entry)
+		if _, pruned := s.pruned[key]; key != keep && !pruned && s.restorable(entry) {
+			candidates = append(candidates, entry)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return s.lastUsed[versionKey(candidates[i])].Before(s.lastUsed[versionKey(candidates[j])])
+	})
+
+	for _, entry := range candidates {
+		if used+incoming <= s.diskBudget {
+			break
+		}
+		key := versionKey(entry)
+		log.Infof("Evicting version %s to stay within the disk budget of %d bytes (%d used)", key, s.diskBudget, used)
+		var size int64
+		for _, name := range versionFiles(entry) {
+			fileName := filepath.Join(s.dataDir, name)
+			info, err := os.Stat(fileName)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			if err := os.Remove(fileName); err != nil {
+				return err
+			}
+			used -= info.Size()
+			size += info.Size()
+		}
+		s.pruned[key] = size
+		diskEvictions.Add(1)
+	}
+	diskUsed.Store(used)
+	prunedVersions.Store(int64(len(s.pruned)))
+	if used+incoming > s.diskBudget {
+		log.Warnf("The %d bytes of the data directory and %d incoming bytes exceed the disk budget of %d bytes", used, incoming, s.diskBudget)
+	}
+	return nil
+}
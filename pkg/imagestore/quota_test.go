@@ -0,0 +1,88 @@
+package imagestore
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("disk budget", func() {
+	var (
+		dataDir    string
+		ts         *ghttp.Server
+		isoContent []byte
+		versions   []map[string]string
+		iso48      string
+		iso49      string
+		ctx        = context.Background()
+	)
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = os.MkdirTemp("", "quotaTest")
+		Expect(err).NotTo(HaveOccurred())
+		iso48 = filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-s390x.iso")
+		iso49 = filepath.Join(dataDir, "rhcos-full-iso-4.9-49.84.202110081407-0-s390x.iso")
+
+		isoContent = make([]byte, 32840)
+		copy(isoContent[32808:], "rhcos-411.86.202210041459-0")
+		ts = ghttp.NewServer()
+		ts.RouteToHandler("GET", "/sha256sum.txt", ghttp.RespondWith(http.StatusNotFound, nil))
+		ts.RouteToHandler("GET", "/rhcos.iso", ghttp.RespondWith(http.StatusOK, isoContent,
+			http.Header{"Content-Length": []string{strconv.Itoa(len(isoContent))}}))
+		versions = []map[string]string{
+			{
+				"openshift_version": "4.8",
+				"cpu_architecture":  "s390x",
+				"version":           "48.84.202109241901-0",
+				"url":               ts.URL() + "/rhcos.iso",
+			},
+			{
+				"openshift_version": "4.9",
+				"cpu_architecture":  "s390x",
+				"version":           "49.84.202110081407-0",
+				"url":               ts.URL() + "/rhcos.iso",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		ts.Close()
+		os.RemoveAll(dataDir)
+	})
+
+	It("keeps every version without a budget", func() {
+		is, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: versions})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(is.Populate(ctx)).To(Succeed())
+		Expect(iso48).To(BeAnExistingFile())
+		Expect(iso49).To(BeAnExistingFile())
+		Expect(GetDiskStats().Used).To(Equal(int64(2 * len(isoContent))))
+	})
+
+	It("evicts the least recently served versions, never the requested one", func() {
+		is, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: versions, DiskBudget: 50000})
+		Expect(err).NotTo(HaveOccurred())
+		evictions := GetDiskStats().Evictions
+		Expect(is.Populate(ctx)).To(Succeed())
+		Expect(iso48).NotTo(BeAnExistingFile())
+		Expect(iso49).To(BeAnExistingFile())
+
+		Expect(is.Available("4.9", "s390x")).To(BeTrue())
+		Expect(is.Available("4.8", "s390x")).To(BeFalse())
+		Eventually(func() bool { return is.Available("4.8", "s390x") }).Should(BeTrue())
+		Expect(os.ReadFile(iso48)).To(Equal(isoContent))
+		Expect(iso49).NotTo(BeAnExistingFile())
+
+		stats := GetDiskStats()
+		Expect(stats.Budget).To(Equal(int64(50000)))
+		Expect(stats.Used).To(Equal(int64(len(isoContent))))
+		Expect(stats.Evictions).To(Equal(evictions + 2))
+	})
+})
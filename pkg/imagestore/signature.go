@@ -0,0 +1,166 @@
+package imagestore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift/assisted-image-service/pkg/registry"
+)
+
+// maxChecksumsSize caps the size of the checksums files and of their signatures
+const maxChecksumsSize = 1 << 20
+
+// loadSignatureKeys reads the OpenPGP public keys of filePath, armored or binary, as a binary
+// keyring since gpgv doesn't read armored keys
+func loadSignatureKeys(filePath string) ([]byte, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature keys: %w", err)
+	}
+	if !bytes.Contains(data, []byte("-----BEGIN PGP")) {
+		return data, nil
+	}
+
+	keyring := &bytes.Buffer{}
+	var block *strings.Builder
+	inHeaders := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "-----BEGIN PGP PUBLIC KEY BLOCK-----"):
+			block = &strings.Builder{}
+			inHeaders = true
+		case block == nil:
+		case strings.HasPrefix(line, "-----END PGP PUBLIC KEY BLOCK-----"):
+			decoded, err := base64.StdEncoding.DecodeString(block.String())
+			if err != nil {
+				return nil, fmt.Errorf("invalid armored key in %s: %w", filePath, err)
+			}
+			keyring.Write(decoded)
+			block = nil
+		case inHeaders:
+			// the armor headers end with an empty line
+			inHeaders = line != "" && strings.Contains(line, ":")
+			if !inHeaders && line != "" {
+				block.WriteString(line)
+			}
+		case strings.HasPrefix(line, "="):
+			// checksum of the block
+		default:
+			block.WriteString(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if keyring.Len() == 0 {
+		return nil, fmt.Errorf("no public key found in %s", filePath)
+	}
+	return keyring.Bytes(), nil
+}
+
+// signedChecksum downloads the checksums file of the image and its signature, verifies the
+// signature with the signature keys and returns the sha256 of the ISO listed in the file
+func (s *rhcosStore) signedChecksum(ctx context.Context, imageInfo map[string]string) (string, error) {
+	checksumsURL := imageInfo["sha256sum_url"]
+	signatureURL := imageInfo["signature_url"]
+	if signatureURL == "" {
+		signatureURL = checksumsURL + ".asc"
+	}
+	checksums, err := s.downloadSmallFile(ctx, checksumsURL)
+	if err != nil {
+		return "", err
+	}
+	signature, err := s.downloadSmallFile(ctx, signatureURL)
+	if err != nil {
+		return "", err
+	}
+	if err := verifySignature(ctx, s.signatureKeys, signature, checksums); err != nil {
+		return "", fmt.Errorf("failed to verify the signature of %s: %w", checksumsURL, err)
+	}
+
+	name, err := isoName(imageInfo["url"])
+	if err != nil {
+		return "", err
+	}
+	sum, err := findChecksum(checksums, name)
+	if err != nil {
+		return "", fmt.Errorf("invalid checksums %s: %w", checksumsURL, err)
+	}
+	if sum == "" {
+		return "", fmt.Errorf("%s doesn't list %s", checksumsURL, name)
+	}
+	return sum, nil
+}
+
+// isoName returns the file name of the ISO in the checksums file
+func isoName(imageURL string) (string, error) {
+	if registry.IsReference(imageURL) {
+		ref, err := registry.ParseReference(imageURL)
+		if err != nil {
+			return "", err
+		}
+		if ref.File == "" {
+			return "", fmt.Errorf("%s has no file name to look up in the checksums", imageURL)
+		}
+		return path.Base(ref.File), nil
+	}
+	u, err := url.Parse(imageURL)
+	if err != nil {
+		return "", err
+	}
+	return path.Base(u.Path), nil
+}
+
+func (s *rhcosStore) downloadSmallFile(ctx context.Context, fileURL string) ([]byte, error) {
+	resp, err := s.doHttpRequest(ctx, fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http request to %s failed: %w", fileURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("request to %s returned error code %d", fileURL, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxChecksumsSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxChecksumsSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", fileURL, maxChecksumsSize)
+	}
+	return data, nil
+}
+
+// verifySignature checks the detached signature of data with gpgv, which only accepts
+// signatures made by the keys of keyring
+func verifySignature(ctx context.Context, keyring, signature, data []byte) error {
+	dir, err := os.MkdirTemp("", "signature")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string][]byte{"keyring.gpg": keyring, "data.asc": signature, "data": data}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0600); err != nil {
+			return err
+		}
+	}
+	cmd := exec.CommandContext(ctx, "gpgv", "--homedir", dir, "--keyring", filepath.Join(dir, "keyring.gpg"),
+		filepath.Join(dir, "data.asc"), filepath.Join(dir, "data"))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("gpgv failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package imagestore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("signature verification", func() {
+	var (
+		dataDir    string
+		gpgHome    string
+		keysFile   string
+		ts         *ghttp.Server
+		isoContent []byte
+		version    map[string]string
+		ctx        = context.Background()
+	)
+
+	gpg := func(args ...string) []byte {
+		cmd := exec.Command("gpg", append([]string{"--homedir", gpgHome, "--batch", "--passphrase", ""}, args...)...)
+		output, err := cmd.Output()
+		Expect(err).NotTo(HaveOccurred())
+		return output
+	}
+
+	sign := func(data []byte) []byte {
+		dataFile := filepath.Join(gpgHome, "data")
+		Expect(os.WriteFile(dataFile, data, 0600)).To(Succeed())
+		return gpg("--armor", "--detach-sign", "--output", "-", dataFile)
+	}
+
+	BeforeEach(func() {
+		if _, err := exec.LookPath("gpg"); err != nil {
+			Skip("gpg is not installed")
+		}
+		var err error
+		dataDir, err = os.MkdirTemp("", "signatureTest")
+		Expect(err).NotTo(HaveOccurred())
+		gpgHome, err = os.MkdirTemp("", "gnupg")
+		Expect(err).NotTo(HaveOccurred())
+		gpg("--quick-gen-key", "Image Service Test <test@example.com>", "ed25519", "sign", "never")
+		keysFile = filepath.Join(gpgHome, "keys.asc")
+		Expect(os.WriteFile(keysFile, gpg("--armor", "--export"), 0600)).To(Succeed())
+
+		isoContent = make([]byte, 32840)
+		copy(isoContent[32808:], "rhcos-411.86.202210041459-0")
+		ts = ghttp.NewServer()
+		ts.RouteToHandler("GET", "/rhcos-live.x86_64.iso", ghttp.RespondWith(http.StatusOK, isoContent,
+			http.Header{"Content-Length": []string{strconv.Itoa(len(isoContent))}}))
+		version = map[string]string{
+			"openshift_version": "4.8",
+			"cpu_architecture":  "s390x",
+			"version":           "48.84.202109241901-0",
+			"url":               ts.URL() + "/rhcos-live.x86_64.iso",
+			"sha256sum_url":     ts.URL() + "/sha256sum.txt",
+		}
+	})
+
+	AfterEach(func() {
+		if ts != nil {
+			ts.Close()
+		}
+		os.RemoveAll(dataDir)
+		os.RemoveAll(gpgHome)
+	})
+
+	serveChecksums := func(checksums, signature []byte) {
+		ts.RouteToHandler("GET", "/sha256sum.txt", ghttp.RespondWith(http.StatusOK, checksums))
+		ts.RouteToHandler("GET", "/sha256sum.txt.asc", ghttp.RespondWith(http.StatusOK, signature))
+	}
+
+	checksumsOf := func(content []byte) []byte {
+		sum := sha256.Sum256(content)
+		return []byte(fmt.Sprintf("%s  rhcos-live.s390x.iso\n%s  rhcos-live.x86_64.iso\n", hex.EncodeToString(make([]byte, 32)), hex.EncodeToString(sum[:])))
+	}
+
+	It("reads armored and binary keys", func() {
+		binary := gpg("--export")
+		armored, err := loadSignatureKeys(keysFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(armored).To(Equal(binary))
+
+		binaryFile := filepath.Join(gpgHome, "keys.gpg")
+		Expect(os.WriteFile(binaryFile, binary, 0600)).To(Succeed())
+		Expect(loadSignatureKeys(binaryFile)).To(Equal(binary))
+	})
+
+	It("admits the ISOs listed in signed checksums", func() {
+		checksums := checksumsOf(isoContent)
+		serveChecksums(checksums, sign(checksums))
+		is, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageSignatureKeysFile: keysFile})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(is.Populate(ctx)).To(Succeed())
+		Expect(os.ReadFile(filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-s390x.iso"))).To(Equal(isoContent))
+	})
+
+	It("rejects checksums that aren't signed by the keys", func() {
+		checksums := checksumsOf(isoContent)
+		signature := sign(checksums)
+		serveChecksums(append(checksums, '\n'), signature)
+		is, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageSignatureKeysFile: keysFile})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(is.Populate(ctx)).To(MatchError(ContainSubstring("failed to verify the signature")))
+		Expect(filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-s390x.iso")).NotTo(BeAnExistingFile())
+	})
+
+	It("rejects and removes ISOs that don't match the signed checksums", func() {
+		checksums := checksumsOf([]byte("another iso"))
+		serveChecksums(checksums, sign(checksums))
+		is, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageSignatureKeysFile: keysFile})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(is.Populate(ctx)).To(MatchError(ContainSubstring("doesn't match the expected")))
+		Expect(filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-s390x.iso")).NotTo(BeAnExistingFile())
+	})
+
+	It("admits the ISOs of the object store listed in signed checksums", func() {
+		checksums := checksumsOf(isoContent)
+		serveChecksums(checksums, sign(checksums))
+		ts.RouteToHandler("GET", "/rhcos-live.x86_64.iso", ghttp.RespondWith(http.StatusNotFound, nil))
+		store := fakeObjectStore{"rhcos-full-iso-4.8-48.84.202109241901-0-s390x.iso": isoContent}
+		is, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageSignatureKeysFile: keysFile, ObjectStore: store})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(is.Populate(ctx)).To(Succeed())
+		Expect(os.ReadFile(filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-s390x.iso"))).To(Equal(isoContent))
+	})
+
+	It("rejects and removes ISOs of the object store that don't match the signed checksums", func() {
+		checksums := checksumsOf(isoContent)
+		serveChecksums(checksums, sign(checksums))
+		tampered := append([]byte{}, isoContent...)
+		tampered[0] = 1
+		store := fakeObjectStore{"rhcos-full-iso-4.8-48.84.202109241901-0-s390x.iso": tampered}
+		is, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageSignatureKeysFile: keysFile, ObjectStore: store})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(is.Populate(ctx)).To(MatchError(ContainSubstring("doesn't match the expected")))
+		Expect(filepath.Join(dataDir, "rhcos-full-iso-4.8-48.84.202109241901-0-s390x.iso")).NotTo(BeAnExistingFile())
+	})
+
+	It("requires the checksums of every version", func() {
+		delete(version, "sha256sum_url")
+		_, err := NewImageStore(ImageStoreOptions{DataDir: dataDir, ImageServiceBaseURL: imageServiceBaseURL, Versions: []map[string]string{version}, OSImageSignatureKeysFile: keysFile})
+		Expect(err).To(MatchError(ContainSubstring("missing sha256sum_url key")))
+	})
+})
@@ -0,0 +1,112 @@
+package isoeditor
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+// Apply returns a stream of the ISO with the files replaced by the given content, such as the
+// FileData returned by NewKargsReader or NewIgnitionImageReader. Files can't grow beyond their
+// size in the ISO, shorter content is padded with zeros. The seekable content, like the isolated
+// ignition images, is read while the stream is, and closed with it. The other FileData are read
+// in memory and closed.
+func Apply(isoPath string, files []FileData) (overlay.OverlayReader, error) {
+	isoReader, err := os.Open(isoPath)
+	if err != nil {
+		closeFileData(files)
+		return nil, err
+	}
+	r, err := applyFileData(isoPath, isoReader, files)
+	if err != nil {
+		isoReader.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// applyFileData overlays the files on base, a stream of the ISO, as Apply does. The files are
+// closed on errors, base is left to the caller.
+func applyFileData(isoPath string, base overlay.BaseStream, files []FileData) (overlay.OverlayReader, error) {
+	var streamed, read []FileData
+	for _, file := range files {
+		if _, ok := file.Data.(io.ReadSeeker); ok {
+			streamed = append(streamed, file)
+		} else {
+			read = append(read, file)
+		}
+	}
+	defer closeFileData(read)
+
+	overlays := make([]overlay.Overlay, 0, len(files))
+	for _, file := range files {
+		offset, length, err := GetISOFileInfo(file.Filename, isoPath)
+		if err != nil {
+			closeFileData(streamed)
+			return nil, err
+		}
+		fileOverlays, err := contentOverlays(file, offset, length)
+		if err != nil {
+			closeFileData(streamed)
+			return nil, err
+		}
+		overlays = append(overlays, fileOverlays...)
+	}
+
+	r, err := overlay.NewMultiOverlayReader(base, overlays...)
+	if err != nil {
+		closeFileData(streamed)
+		return nil, err
+	}
+	if len(streamed) == 0 {
+		return r, nil
+	}
+	return &appliedReader{OverlayReader: r, files: streamed}, nil
+}
+
+// contentOverlays returns the overlays replacing the file at offset with the content of file,
+// padded with zeros up to length
+func contentOverlays(file FileData, offset, length int64) ([]overlay.Overlay, error) {
+	rs, ok := file.Data.(io.ReadSeeker)
+	if !ok {
+		content, err := io.ReadAll(file.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read content of %s: %w", file.Filename, err)
+		}
+		padded, err := padContent(file.Filename, content, length, 0)
+		if err != nil {
+			return nil, err
+		}
+		return []overlay.Overlay{{Reader: padded, Offset: offset, Length: length}}, nil
+	}
+
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content of %s: %w", file.Filename, err)
+	}
+	if size > length {
+		return nil, &ErrContentTooLarge{File: file.Filename, Length: size, Capacity: length}
+	}
+	overlays := []overlay.Overlay{{Reader: rs, Offset: offset, Length: size}}
+	if size < length {
+		overlays = append(overlays, overlay.Overlay{
+			Reader: overlay.NewPaddedReader(nil, length-size, 0),
+			Offset: offset + size,
+			Length: length - size,
+		})
+	}
+	return overlays, nil
+}
+
+// appliedReader closes the content streamed by Apply with the ISO
+type appliedReader struct {
+	overlay.OverlayReader
+	files []FileData
+}
+
+func (r *appliedReader) Close() error {
+	closeFileData(r.files)
+	return r.OverlayReader.Close()
+}
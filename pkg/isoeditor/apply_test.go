@@ -0,0 +1,66 @@
+package isoeditor
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Apply", func() {
+	var (
+		isoFile  string
+		filesDir string
+	)
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+	})
+
+	writeISO := func(r io.Reader) string {
+		f, err := os.CreateTemp(filesDir, "applied*.iso")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.Copy(f, r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		return f.Name()
+	}
+
+	It("applies kargs and ignition edits in a single stream", func() {
+		ignition := []byte(`{"ignition": {"version": "3.2.0"}}`)
+		files, err := NewKargsReader(isoFile, "", " p1 p2", KargsConflictKeepAll)
+		Expect(err).NotTo(HaveOccurred())
+		ignitionFiles, err := NewIgnitionReader(isoFile, ignition)
+		Expect(err).NotTo(HaveOccurred())
+
+		r, err := Apply(isoFile, append(files, ignitionFiles...))
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+		output := writeISO(r)
+
+		kargs, err := ExtractKargs(output)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kargs).To(ContainElements("p1", "p2"))
+		extracted, err := ExtractIgnition(output)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(extracted).To(Equal(ignition))
+	})
+
+	It("fails when the content exceeds the file size", func() {
+		_, length, err := GetISOFileInfo(defaultGrubFilePath, isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = Apply(isoFile, []FileData{{
+			Filename: defaultGrubFilePath,
+			Data:     io.NopCloser(bytes.NewReader([]byte(strings.Repeat("x", int(length)+1)))),
+		}})
+		Expect(err).To(MatchError(ContainSubstring("exceeds the file size in the ISO")))
+	})
+})
@@ -0,0 +1,99 @@
+package isoeditor
+
+import (
+	"fmt"
+)
+
+const (
+	S390XCPUArchitecture   = "s390x"
+	PPC64LECPUArchitecture = "ppc64le"
+)
+
+// ArchCustomizer handles the boot layout of the ISOs of an architecture: how they are recognized,
+// where their boot configs are and how their kernel arguments are edited. The ignition and ramdisk
+// embedding is the same for all the architectures.
+type ArchCustomizer interface {
+	// Arch is the name of the architecture, as used by assisted-service
+	Arch() string
+	// MarkerFiles are files only present in the ISOs built for the architecture
+	MarkerFiles() []string
+	// GrubConfigs returns the grub configs to edit among the existing paths, relative to the root
+	// of the ISO, or an error when none exists
+	GrubConfigs(exists func(path string) bool) ([]string, error)
+	// DefaultKargsFiles returns the boot configs holding the kernel arguments of the ISOs lacking
+	// coreos/kargs.json
+	DefaultKargsFiles(isoPath string) []string
+	// KargsFileData returns a file holding the kernel arguments of the ISO, with the operations
+	// applied to them and the conflicts resolved according to policy
+	KargsFileData(isoPath, file string, kargs KernelArguments, policy KargsConflictPolicy) (FileData, error)
+}
+
+// archCustomizers are the customizers of the supported architectures, in the order the ISOs are
+// checked for their marker files. The ISOs of no other architecture are handled as x86_64 ones.
+var archCustomizers = []ArchCustomizer{
+	&s390xCustomizer{grubCustomizer{arch: S390XCPUArchitecture, markerFiles: []string{S390xInsPath, "/images/cdboot.img"}}},
+	&ppc64leCustomizer{grubCustomizer{arch: PPC64LECPUArchitecture, markerFiles: []string{"/ppc/bootinfo.txt"}}},
+	&grubCustomizer{arch: ARM64CPUArchitecture, markerFiles: []string{"/EFI/BOOT/BOOTAA64.EFI"}},
+	&grubCustomizer{arch: X86CPUArchitecture, markerFiles: []string{defaultIsolinuxFilePath, "/EFI/BOOT/BOOTX64.EFI"}},
+}
+
+// RegisterArchCustomizer adds the support of an architecture, or replaces the customizer of a
+// supported one. The ISOs are checked for the marker files of the registered customizers first.
+// It isn't safe to call while ISOs are customized, it is meant to be called at startup.
+func RegisterArchCustomizer(customizer ArchCustomizer) error {
+	if customizer.Arch() == "" {
+		return fmt.Errorf("architecture customizers must have an architecture")
+	}
+	registered := []ArchCustomizer{customizer}
+	for _, c := range archCustomizers {
+		if c.Arch() != customizer.Arch() {
+			registered = append(registered, c)
+		}
+	}
+	archCustomizers = registered
+	return nil
+}
+
+// customizerFor returns the customizer of the architecture, the x86_64 one for unknown
+// architectures
+func customizerFor(arch string) ArchCustomizer {
+	arch = NormalizeArchitecture(arch)
+	var fallback ArchCustomizer
+	for _, c := range archCustomizers {
+		switch c.Arch() {
+		case arch:
+			return c
+		case X86CPUArchitecture:
+			fallback = c
+		}
+	}
+	return fallback
+}
+
+// DetectArchitecture guesses the CPU architecture of an ISO from the boot files it contains.
+// It defaults to x86_64 when no architecture specific file is found.
+func DetectArchitecture(isoPath string) (string, error) {
+	for _, c := range archCustomizers {
+		for _, file := range c.MarkerFiles() {
+			if _, _, err := GetISOFileInfo(file, isoPath); err == nil {
+				return c.Arch(), nil
+			}
+		}
+	}
+	if _, err := VolumeIdentifier(isoPath); err != nil {
+		return "", err
+	}
+	return X86CPUArchitecture, nil
+}
+
+// NormalizeArchitecture maps the architecture aliases used by the different tools to the names used
+// by assisted-service
+func NormalizeArchitecture(arch string) string {
+	switch arch {
+	case AMD64CPUArchitecture:
+		return X86CPUArchitecture
+	case AARCH64CPUArchitecture:
+		return ARM64CPUArchitecture
+	}
+	return arch
+}
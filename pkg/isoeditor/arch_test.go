@@ -0,0 +1,98 @@
+package isoeditor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DetectArchitecture", func() {
+	It("detects x86_64 ISOs", func() {
+		filesDir, isoFile := createTestFiles("Assisted123")
+		defer func() {
+			Expect(os.RemoveAll(filesDir)).To(Succeed())
+			Expect(os.Remove(isoFile)).To(Succeed())
+		}()
+
+		arch, err := DetectArchitecture(isoFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(arch).To(Equal(X86CPUArchitecture))
+	})
+
+	It("detects s390x ISOs", func() {
+		filesDir, isoFile := createS390TestFiles("Assisted123", 0)
+		defer func() {
+			Expect(os.RemoveAll(filesDir)).To(Succeed())
+			Expect(os.Remove(isoFile)).To(Succeed())
+		}()
+
+		arch, err := DetectArchitecture(isoFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(arch).To(Equal(S390XCPUArchitecture))
+	})
+
+	It("fails for a missing ISO", func() {
+		_, err := DetectArchitecture("/does/not/exist.iso")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("normalizes aliases", func() {
+		Expect(NormalizeArchitecture("amd64")).To(Equal(X86CPUArchitecture))
+		Expect(NormalizeArchitecture("aarch64")).To(Equal(ARM64CPUArchitecture))
+		Expect(NormalizeArchitecture("s390x")).To(Equal(S390XCPUArchitecture))
+	})
+
+	Context("with a registered customizer", func() {
+		var registered []ArchCustomizer
+
+		BeforeEach(func() {
+			registered = archCustomizers
+			Expect(RegisterArchCustomizer(&grubCustomizer{arch: "riscv64", markerFiles: []string{"/EFI/BOOT/BOOTRISCV64.EFI"}})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			archCustomizers = registered
+		})
+
+		It("detects the ISOs of the new architecture", func() {
+			filesDir, isoFile := createTestFiles("Assisted123")
+			defer func() {
+				Expect(os.RemoveAll(filesDir)).To(Succeed())
+				Expect(os.Remove(isoFile)).To(Succeed())
+			}()
+			Expect(os.MkdirAll(filepath.Join(filesDir, "EFI/BOOT"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(filesDir, "EFI/BOOT/BOOTRISCV64.EFI"), []byte("grub"), 0600)).To(Succeed())
+			Expect(os.Remove(isoFile)).To(Succeed())
+			cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", "Assisted123", "-o", isoFile, filesDir)
+			Expect(cmd.Run()).To(Succeed())
+
+			arch, err := DetectArchitecture(isoFile)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(arch).To(Equal("riscv64"))
+
+			files, err := NewKargsReader(isoFile, "", " p1", KargsConflictKeepAll)
+			Expect(err).ToNot(HaveOccurred())
+			defer closeFileData(files)
+			Expect(files).To(HaveLen(2))
+		})
+
+		It("returns the customizers of the architectures", func() {
+			Expect(customizerFor("riscv64").Arch()).To(Equal("riscv64"))
+			Expect(customizerFor("aarch64").Arch()).To(Equal(ARM64CPUArchitecture))
+			Expect(customizerFor("mips64").Arch()).To(Equal(X86CPUArchitecture))
+		})
+
+		It("replaces the customizer of a supported architecture", func() {
+			Expect(RegisterArchCustomizer(&ppc64leCustomizer{grubCustomizer{arch: PPC64LECPUArchitecture}})).To(Succeed())
+			Expect(archCustomizers).To(HaveLen(len(registered) + 1))
+			Expect(customizerFor(PPC64LECPUArchitecture).MarkerFiles()).To(BeEmpty())
+		})
+
+		It("rejects customizers without architecture", func() {
+			Expect(RegisterArchCustomizer(&grubCustomizer{})).NotTo(Succeed())
+		})
+	})
+})
@@ -0,0 +1,104 @@
+package isoeditor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+const (
+	kernelPathInISO      = "/images/pxeboot/vmlinuz"
+	s390xKernelPathInISO = "/images/pxeboot/kernel.img"
+)
+
+// BootArtifact is a file of the ISO needed to PXE boot, read in place from the ISO
+type BootArtifact struct {
+	// Name is the file name of the artifact, e.g. vmlinuz
+	Name string
+	// Path is the path of the artifact in the ISO
+	Path string
+	Size int64
+
+	isoPath string
+	offset  int64
+}
+
+// Open returns a reader of the artifact
+func (a *BootArtifact) Open() (io.ReadSeekCloser, error) {
+	f, err := os.Open(a.isoPath)
+	if err != nil {
+		return nil, err
+	}
+	return &sectionReadCloser{SectionReader: io.NewSectionReader(f, a.offset, a.Size), file: f}, nil
+}
+
+// SHA256 returns the hex encoded SHA-256 of the artifact. It reads the whole artifact.
+func (a *BootArtifact) SHA256() (string, error) {
+	r, err := a.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", a.Path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// BootArtifacts are the kernel, initrd and rootfs of an ISO
+type BootArtifacts struct {
+	Kernel *BootArtifact
+	Initrd *BootArtifact
+	// Rootfs is nil for minimal ISOs
+	Rootfs *BootArtifact
+}
+
+// ExtractBootArtifacts locates the boot artifacts of the ISO, without copying them. The kernel
+// of s390x ISOs lacking images/pxeboot/vmlinuz is images/pxeboot/kernel.img.
+func ExtractBootArtifacts(isoPath string) (*BootArtifacts, error) {
+	kernel, err := bootArtifact(isoPath, kernelPathInISO)
+	if err != nil {
+		var s390xErr error
+		if kernel, s390xErr = bootArtifact(isoPath, s390xKernelPathInISO); s390xErr != nil {
+			return nil, err
+		}
+	}
+	initrd, err := bootArtifact(isoPath, "/"+initrdPathInISO)
+	if err != nil {
+		return nil, err
+	}
+	artifacts := &BootArtifacts{Kernel: kernel, Initrd: initrd}
+	if _, _, err := GetISOFileInfo(rootfsImagePath, isoPath); err == nil {
+		if artifacts.Rootfs, err = bootArtifact(isoPath, rootfsImagePath); err != nil {
+			return nil, err
+		}
+	}
+	return artifacts, nil
+}
+
+func bootArtifact(isoPath, filePath string) (*BootArtifact, error) {
+	offset, size, err := GetISOFileInfo(filePath, isoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find boot artifact %s: %w", filePath, err)
+	}
+	return &BootArtifact{
+		Name:    path.Base(filePath),
+		Path:    filePath,
+		Size:    size,
+		isoPath: isoPath,
+		offset:  offset,
+	}, nil
+}
+
+type sectionReadCloser struct {
+	*io.SectionReader
+	file *os.File
+}
+
+func (r *sectionReadCloser) Close() error {
+	return r.file.Close()
+}
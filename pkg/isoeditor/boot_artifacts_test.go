@@ -0,0 +1,82 @@
+package isoeditor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ExtractBootArtifacts", func() {
+	var (
+		filesDir string
+		isoFile  string
+	)
+
+	createISO := func(files map[string]string) {
+		for name, content := range files {
+			Expect(os.MkdirAll(filepath.Join(filesDir, filepath.Dir(name)), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(filesDir, name), []byte(content), 0600)).To(Succeed())
+		}
+		cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-o", isoFile, filesDir)
+		Expect(cmd.Run()).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		filesDir, err = os.MkdirTemp("", "bootartifacts")
+		Expect(err).NotTo(HaveOccurred())
+		isoFile = filepath.Join(filesDir, "..", filepath.Base(filesDir)+".iso")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		os.Remove(isoFile)
+	})
+
+	expectArtifact := func(artifact *BootArtifact, name, content string) {
+		Expect(artifact.Name).To(Equal(name))
+		Expect(artifact.Size).To(Equal(int64(len(content))))
+		r, err := artifact.Open()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(io.ReadAll(r)).To(Equal([]byte(content)))
+		Expect(r.Close()).To(Succeed())
+		sum := sha256.Sum256([]byte(content))
+		Expect(artifact.SHA256()).To(Equal(hex.EncodeToString(sum[:])))
+	}
+
+	It("returns the kernel, initrd and rootfs", func() {
+		createISO(map[string]string{
+			"images/pxeboot/vmlinuz":    "this is kernel",
+			"images/pxeboot/initrd.img": "this is initrd",
+			"images/pxeboot/rootfs.img": "this is rootfs",
+		})
+		artifacts, err := ExtractBootArtifacts(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		expectArtifact(artifacts.Kernel, "vmlinuz", "this is kernel")
+		expectArtifact(artifacts.Initrd, "initrd.img", "this is initrd")
+		expectArtifact(artifacts.Rootfs, "rootfs.img", "this is rootfs")
+	})
+
+	It("supports the s390x kernel path and minimal ISOs", func() {
+		createISO(map[string]string{
+			"images/pxeboot/kernel.img": "this is kernel",
+			"images/pxeboot/initrd.img": "this is initrd",
+		})
+		artifacts, err := ExtractBootArtifacts(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		expectArtifact(artifacts.Kernel, "kernel.img", "this is kernel")
+		Expect(artifacts.Rootfs).To(BeNil())
+	})
+
+	It("fails without a kernel", func() {
+		createISO(map[string]string{"images/pxeboot/initrd.img": "this is initrd"})
+		_, err := ExtractBootArtifacts(isoFile)
+		Expect(err).To(MatchError(ContainSubstring("failed to find boot artifact /images/pxeboot/vmlinuz")))
+	})
+})
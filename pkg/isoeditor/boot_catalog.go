@@ -0,0 +1,284 @@
+package isoeditor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// BootPlatform is the platform ID of El Torito boot images
+type BootPlatform byte
+
+const (
+	BootPlatformBIOS BootPlatform = 0x00
+	BootPlatformPPC  BootPlatform = 0x01
+	BootPlatformMac  BootPlatform = 0x02
+	BootPlatformEFI  BootPlatform = 0xef
+)
+
+func (p BootPlatform) String() string {
+	switch p {
+	case BootPlatformBIOS:
+		return "BIOS"
+	case BootPlatformPPC:
+		return "PowerPC"
+	case BootPlatformMac:
+		return "Mac"
+	case BootPlatformEFI:
+		return "UEFI"
+	}
+	return fmt.Sprintf("0x%02x", byte(p))
+}
+
+// BootEmulation is the media emulated by El Torito boot images
+type BootEmulation byte
+
+const (
+	BootEmulationNone     BootEmulation = 0
+	BootEmulationHardDisk BootEmulation = 4
+)
+
+const (
+	bootCatalogValidationEntry = 0x01
+	bootCatalogBootable        = 0x88
+	bootCatalogNotBootable     = 0x00
+	bootCatalogSectionHeader   = 0x90
+	bootCatalogFinalHeader     = 0x91
+	bootCatalogExtensionEntry  = 0x44
+	bootCatalogEntrySize       = 32
+)
+
+var ErrNoBootCatalog = errors.New("the ISO has no El Torito boot catalog")
+
+// BootImage is an image of the El Torito boot catalog
+type BootImage struct {
+	Platform    BootPlatform
+	Bootable    bool
+	Emulation   BootEmulation
+	LoadSegment uint16
+	SystemType  byte
+	// SectorCount is the number of 512 bytes sectors loaded by the firmware
+	SectorCount uint16
+	// LBA of the image, in 2048 bytes sectors
+	LBA uint32
+	// Path and Size of the file of the ISO holding the image, empty for hidden images
+	Path string
+	Size int64
+}
+
+// BootCatalog is the El Torito boot catalog, listing the BIOS and UEFI boot images of the ISO
+type BootCatalog struct {
+	LBA uint32
+	// Platform and ID of the validation entry
+	Platform BootPlatform
+	ID       string
+	// Images starting with the default one
+	Images []BootImage
+}
+
+// Image returns the first bootable image of the platform, if any
+func (c *BootCatalog) Image(platform BootPlatform) *BootImage {
+	for i := range c.Images {
+		if c.Images[i].Platform == platform && c.Images[i].Bootable {
+			return &c.Images[i]
+		}
+	}
+	return nil
+}
+
+// Validate checks that the images are within the ISO of isoSize bytes and that the UEFI images
+// are files of the ISO, which firmwares load as FAT filesystems
+func (c *BootCatalog) Validate(isoSize int64) error {
+	if len(c.Images) == 0 {
+		return errors.New("the boot catalog has no boot images")
+	}
+	for _, image := range c.Images {
+		if !image.Bootable {
+			continue
+		}
+		end := int64(image.LBA)*isoSectorSize + int64(image.SectorCount)*512
+		if image.LBA == 0 || end > isoSize {
+			return fmt.Errorf("the %s boot image at sector %d is beyond the end of the ISO", image.Platform, image.LBA)
+		}
+		if image.Platform == BootPlatformEFI && image.Path == "" {
+			return fmt.Errorf("the %s boot image at sector %d isn't a file of the ISO", image.Platform, image.LBA)
+		}
+	}
+	return nil
+}
+
+// ReadBootCatalog returns the El Torito boot catalog of the ISO, or ErrNoBootCatalog
+func ReadBootCatalog(isoPath string) (*BootCatalog, error) {
+	img, err := openISOImage(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer img.Close()
+
+	lba, ok := img.bootCatalogLBA()
+	if !ok {
+		return nil, ErrNoBootCatalog
+	}
+	data, err := img.readAt(lba*isoSectorSize, isoSectorSize)
+	if err != nil {
+		return nil, err
+	}
+	return img.bootCatalog(lba, data)
+}
+
+// ValidateBootCatalog checks the El Torito boot catalog of the ISO
+func ValidateBootCatalog(isoPath string) error {
+	catalog, err := ReadBootCatalog(isoPath)
+	if err != nil {
+		return err
+	}
+	img, err := openISOImage(isoPath)
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+	return catalog.Validate(img.size)
+}
+
+// BootCatalog returns the El Torito boot catalog of the modified ISO, once Reader was called, or
+// of the original ISO
+func (w *ISOWriter) BootCatalog() (*BootCatalog, error) {
+	lba, ok := w.img.bootCatalogLBA()
+	if !ok {
+		return nil, ErrNoBootCatalog
+	}
+	data, err := w.sector(lba)
+	if err != nil {
+		return nil, err
+	}
+	return w.img.bootCatalog(lba, data)
+}
+
+// ReplaceEFIBootImage returns the ISO with the UEFI boot image (typically images/efiboot.img)
+// replaced. The image is written in place when it fits, and the boot catalog is updated and
+// validated.
+func ReplaceEFIBootImage(isoPath string, content io.ReadSeeker) (ImageReader, error) {
+	w, err := NewISOWriter(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	catalog, err := w.BootCatalog()
+	if err != nil {
+		return nil, err
+	}
+	image := catalog.Image(BootPlatformEFI)
+	if image == nil || image.Path == "" {
+		return nil, fmt.Errorf("no UEFI boot image file found in %s", isoPath)
+	}
+	if err = w.ReplaceFile(image.Path, content); err != nil {
+		return nil, err
+	}
+	r, err := w.Reader()
+	if err != nil {
+		return nil, err
+	}
+
+	if catalog, err = w.BootCatalog(); err == nil {
+		err = catalog.Validate(w.size())
+	}
+	if err != nil {
+		r.Close()
+		return nil, fmt.Errorf("invalid boot catalog after replacing %s: %w", image.Path, err)
+	}
+	return r, nil
+}
+
+// bootCatalogLBA returns the sector of the boot catalog from the El Torito boot record
+func (img *isoImage) bootCatalogLBA() (int64, bool) {
+	for _, vd := range img.descriptors {
+		if vd.data[0] == isoVolumeDescriptorBoot && string(bytes.TrimRight(vd.data[7:39], "\x00")) == elToritoSystemID {
+			return int64(binary.LittleEndian.Uint32(vd.data[71:])), true
+		}
+	}
+	return 0, false
+}
+
+// bootCatalog parses the boot catalog and finds the files of its images
+func (img *isoImage) bootCatalog(lba int64, data []byte) (*BootCatalog, error) {
+	validation := data[:bootCatalogEntrySize]
+	if validation[0] != bootCatalogValidationEntry || validation[30] != 0x55 || validation[31] != 0xaa {
+		return nil, fmt.Errorf("invalid boot catalog validation entry in sector %d", lba)
+	}
+	var sum uint16
+	for i := 0; i < bootCatalogEntrySize; i += 2 {
+		sum += binary.LittleEndian.Uint16(validation[i:])
+	}
+	if sum != 0 {
+		return nil, fmt.Errorf("invalid boot catalog checksum in sector %d", lba)
+	}
+	catalog := &BootCatalog{
+		LBA:      uint32(lba),
+		Platform: BootPlatform(validation[1]),
+		ID:       strings.TrimRight(string(validation[4:28]), "\x00 "),
+	}
+
+	catalog.Images = append(catalog.Images, parseBootImage(data[bootCatalogEntrySize:], catalog.Platform))
+	// sections of images follow the default one, the last section has a final header
+	for pos := 2 * bootCatalogEntrySize; pos+bootCatalogEntrySize <= len(data); {
+		header := data[pos : pos+bootCatalogEntrySize]
+		if header[0] != bootCatalogSectionHeader && header[0] != bootCatalogFinalHeader {
+			break
+		}
+		platform := BootPlatform(header[1])
+		count := int(binary.LittleEndian.Uint16(header[2:]))
+		pos += bootCatalogEntrySize
+		for i := 0; i < count && pos+bootCatalogEntrySize <= len(data); pos += bootCatalogEntrySize {
+			if data[pos] == bootCatalogExtensionEntry {
+				continue
+			}
+			catalog.Images = append(catalog.Images, parseBootImage(data[pos:], platform))
+			i++
+		}
+		if header[0] == bootCatalogFinalHeader {
+			break
+		}
+	}
+
+	files := map[int64]*isoRecord{}
+	paths := map[int64]string{}
+	var walk func(dir *isoDirectory, dirPath string)
+	walk = func(dir *isoDirectory, dirPath string) {
+		for _, record := range dir.records {
+			switch {
+			case record.isDot() || record.part:
+			case record.dir != nil:
+				walk(record.dir, dirPath+record.name+"/")
+			case record.symlink == "" && record.dataLength() > 0:
+				if _, ok := files[record.extent()]; !ok {
+					files[record.extent()] = record
+					paths[record.extent()] = dirPath + record.name
+				}
+			}
+		}
+	}
+	walk(img.lookupVolume().root, "/")
+	for i := range catalog.Images {
+		image := &catalog.Images[i]
+		if record, ok := files[int64(image.LBA)]; ok {
+			image.Path, image.Size = paths[int64(image.LBA)], record.length()
+		}
+	}
+	return catalog, nil
+}
+
+func parseBootImage(entry []byte, platform BootPlatform) BootImage {
+	return BootImage{
+		Platform:    platform,
+		Bootable:    entry[0] == bootCatalogBootable,
+		Emulation:   BootEmulation(entry[1] & 0x0f),
+		LoadSegment: binary.LittleEndian.Uint16(entry[2:]),
+		SystemType:  entry[4],
+		SectorCount: binary.LittleEndian.Uint16(entry[6:]),
+		LBA:         binary.LittleEndian.Uint32(entry[8:]),
+	}
+}
@@ -0,0 +1,124 @@
+package isoeditor
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BootCatalog", func() {
+	var (
+		filesDir string
+		isoFile  string
+		bootISO  string
+	)
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+		bootISO = filepath.Join(filesDir, "..", filepath.Base(filesDir)+"-boot.iso")
+		Expect(Create(bootISO, filesDir, "Assisted123")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+		Expect(os.Remove(bootISO)).To(Succeed())
+	})
+
+	writeISO := func(r ImageReader) string {
+		defer r.Close()
+		out, err := os.CreateTemp("", "*edited.iso")
+		Expect(err).NotTo(HaveOccurred())
+		defer out.Close()
+		_, err = io.Copy(out, r)
+		Expect(err).NotTo(HaveOccurred())
+		return out.Name()
+	}
+
+	It("lists the BIOS and UEFI boot images", func() {
+		catalog, err := ReadBootCatalog(bootISO)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(catalog.Images).To(HaveLen(2))
+
+		bios := catalog.Image(BootPlatformBIOS)
+		Expect(bios).NotTo(BeNil())
+		Expect(bios.Path).To(Equal("/isolinux/isolinux.bin"))
+		Expect(bios.Emulation).To(Equal(BootEmulationNone))
+		Expect(bios.SectorCount).To(Equal(uint16(4)))
+
+		efi := catalog.Image(BootPlatformEFI)
+		Expect(efi).NotTo(BeNil())
+		Expect(efi.Path).To(Equal("/images/efiboot.img"))
+		Expect(efi.Size).To(Equal(int64(8184422)))
+		Expect(efi.SectorCount).To(Equal(uint16(15988)))
+		Expect(efi.Platform.String()).To(Equal("UEFI"))
+
+		Expect(ValidateBootCatalog(bootISO)).To(Succeed())
+	})
+
+	It("fails on ISOs without boot catalog", func() {
+		_, err := ReadBootCatalog(isoFile)
+		Expect(err).To(MatchError(ErrNoBootCatalog))
+	})
+
+	It("replaces the UEFI boot image in place", func() {
+		before, err := ReadBootCatalog(bootISO)
+		Expect(err).NotTo(HaveOccurred())
+		efiboot := bytes.Repeat([]byte("efi"), 100000)
+		r, err := ReplaceEFIBootImage(bootISO, bytes.NewReader(efiboot))
+		Expect(err).NotTo(HaveOccurred())
+		edited := writeISO(r)
+		defer os.Remove(edited)
+
+		catalog, err := ReadBootCatalog(edited)
+		Expect(err).NotTo(HaveOccurred())
+		efi := catalog.Image(BootPlatformEFI)
+		Expect(efi.LBA).To(Equal(before.Image(BootPlatformEFI).LBA))
+		Expect(efi.SectorCount).To(Equal(uint16(588)))
+		Expect(efi.Size).To(Equal(int64(len(efiboot))))
+		Expect(catalog.Image(BootPlatformBIOS)).To(Equal(before.Image(BootPlatformBIOS)))
+		Expect(ValidateBootCatalog(edited)).To(Succeed())
+
+		content, err := ReadFileFromISO(edited, "/images/efiboot.img")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content).To(Equal(efiboot))
+	})
+
+	It("moves the UEFI boot image when it grows", func() {
+		before, err := ReadBootCatalog(bootISO)
+		Expect(err).NotTo(HaveOccurred())
+		efiboot := bytes.Repeat([]byte("efiboot"), 1500000)
+		r, err := ReplaceEFIBootImage(bootISO, bytes.NewReader(efiboot))
+		Expect(err).NotTo(HaveOccurred())
+		edited := writeISO(r)
+		defer os.Remove(edited)
+
+		catalog, err := ReadBootCatalog(edited)
+		Expect(err).NotTo(HaveOccurred())
+		efi := catalog.Image(BootPlatformEFI)
+		Expect(efi.LBA).To(BeNumerically(">", before.Image(BootPlatformEFI).LBA))
+		Expect(efi.SectorCount).To(Equal(uint16(20508)))
+		Expect(efi.Path).To(Equal("/images/efiboot.img"))
+		Expect(ValidateBootCatalog(edited)).To(Succeed())
+	})
+
+	It("detects invalid boot catalogs", func() {
+		catalog, err := ReadBootCatalog(bootISO)
+		Expect(err).NotTo(HaveOccurred())
+		f, err := os.OpenFile(bootISO, os.O_RDWR, 0)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.WriteAt([]byte{0xff}, int64(catalog.LBA)*isoSectorSize+4)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		Expect(ValidateBootCatalog(bootISO)).To(MatchError(ContainSubstring("invalid boot catalog checksum")))
+
+		catalog.Images[1].Path = ""
+		Expect(catalog.Validate(1 << 30)).To(MatchError(ContainSubstring("isn't a file of the ISO")))
+		catalog.Images[1].LBA = 1 << 20
+		Expect(catalog.Validate(1 << 30)).To(MatchError(ContainSubstring("beyond the end of the ISO")))
+	})
+})
@@ -0,0 +1,35 @@
+package isoeditor
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/coreos/butane/config"
+	"github.com/coreos/butane/config/common"
+)
+
+// IsButane returns true if the config looks like a Butane config rather than an ignition one
+func IsButane(config []byte) bool {
+	trimmed := bytes.TrimSpace(config)
+	return len(trimmed) > 0 && trimmed[0] != '{'
+}
+
+// ButaneToIgnition translates a Butane config into a validated ignition config. The openshift
+// variant is translated into a raw ignition config rather than a MachineConfig, and local file
+// references are rejected as no files directory is available.
+func ButaneToIgnition(butane []byte) ([]byte, error) {
+	ignition, report, err := config.TranslateBytes(butane, common.TranslateBytesOptions{
+		TranslateOptions: common.TranslateOptions{FilesDir: ""},
+		Raw:              true,
+	})
+	if err != nil {
+		if len(report.Entries) > 0 {
+			return nil, fmt.Errorf("invalid butane config: %w: %s", err, report.String())
+		}
+		return nil, fmt.Errorf("invalid butane config: %w", err)
+	}
+	if err := ValidateIgnition(ignition); err != nil {
+		return nil, err
+	}
+	return ignition, nil
+}
@@ -0,0 +1,76 @@
+package isoeditor
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ButaneToIgnition", func() {
+	It("translates a fcos config", func() {
+		butane := `
+variant: fcos
+version: 1.4.0
+passwd:
+  users:
+    - name: core
+      ssh_authorized_keys:
+        - ssh-ed25519 AAAA
+storage:
+  files:
+    - path: /etc/hostname
+      mode: 0644
+      contents:
+        inline: myhost
+`
+		ignition, err := ButaneToIgnition([]byte(butane))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ignition).To(MatchJSON(`{
+			"ignition": {"version": "3.3.0"},
+			"passwd": {"users": [{"name": "core", "sshAuthorizedKeys": ["ssh-ed25519 AAAA"]}]},
+			"storage": {"files": [{"path": "/etc/hostname", "mode": 420, "contents": {"source": "data:,myhost"}}]}
+		}`))
+	})
+
+	It("translates an openshift config into a raw ignition config", func() {
+		butane := `
+variant: openshift
+version: 4.14.0
+metadata:
+  name: 99-worker-hostname
+  labels:
+    machineconfiguration.openshift.io/role: worker
+storage:
+  files:
+    - path: /etc/hostname
+      mode: 0644
+      contents:
+        inline: myhost
+`
+		ignition, err := ButaneToIgnition([]byte(butane))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ignition).To(MatchJSON(`{
+			"ignition": {"version": "3.4.0"},
+			"storage": {"files": [{"path": "/etc/hostname", "mode": 420, "contents": {"source": "data:,myhost"}}]}
+		}`))
+	})
+
+	It("fails with an unsupported variant", func() {
+		_, err := ButaneToIgnition([]byte("variant: unknown\nversion: 1.0.0\n"))
+		Expect(err).To(MatchError(ContainSubstring("invalid butane config")))
+	})
+
+	It("fails with an unsupported version", func() {
+		_, err := ButaneToIgnition([]byte("variant: openshift\nversion: 4.2.0\n"))
+		Expect(err).To(MatchError(ContainSubstring("invalid butane config")))
+	})
+
+	It("fails with local file references", func() {
+		_, err := ButaneToIgnition([]byte("variant: fcos\nversion: 1.4.0\nstorage:\n  files:\n    - path: /a\n      contents:\n        local: a.txt\n"))
+		Expect(err).To(MatchError(ContainSubstring("files-dir")))
+	})
+
+	It("detects butane configs", func() {
+		Expect(IsButane([]byte("variant: fcos"))).To(BeTrue())
+		Expect(IsButane([]byte(` {"ignition": {}}`))).To(BeFalse())
+	})
+})
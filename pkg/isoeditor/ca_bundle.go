@@ -0,0 +1,94 @@
+package isoeditor
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
+)
+
+// CABundlePath is where the CA bundle is written, both in the live initramfs and by ignition.
+// Certificates in the anchors directory are added to the system trust store by update-ca-trust.
+const CABundlePath = "/etc/pki/ca-trust/source/anchors/assisted-ca-bundle.pem"
+
+// ValidateCABundle checks that the bundle consists of PEM encoded certificates only
+func ValidateCABundle(bundle []byte) error {
+	count := 0
+	for rest := bundle; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return fmt.Errorf("invalid CA bundle: unexpected PEM block of type %s", block.Type)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return fmt.Errorf("invalid CA bundle: certificate %d: %w", count+1, err)
+		}
+		count++
+	}
+	if count == 0 {
+		return errors.New("invalid CA bundle: no PEM encoded certificate found")
+	}
+	return nil
+}
+
+// AddCABundleToIgnition returns the ignition config trusting the CA bundle, both for the
+// resources fetched by ignition and, by writing it to CABundlePath, for the booted system
+func AddCABundleToIgnition(ignition, bundle []byte) ([]byte, error) {
+	if err := ValidateCABundle(bundle); err != nil {
+		return nil, err
+	}
+	return mergeIgnitionOverride(ignition, map[string]interface{}{
+		"ignition": map[string]interface{}{
+			"security": map[string]interface{}{
+				"tls": map[string]interface{}{
+					"certificateAuthorities": []interface{}{
+						map[string]interface{}{
+							"source": "data:text/plain;charset=utf-8;base64," + base64.StdEncoding.EncodeToString(bundle),
+						},
+					},
+				},
+			},
+		},
+		"storage": map[string]interface{}{
+			"files": []interface{}{
+				ignitionDataFile(CABundlePath, bundle, 0o644),
+			},
+		},
+	})
+}
+
+// CABundleEntries returns the initramfs files adding the CA bundle to the trust store of the
+// live environment
+func CABundleEntries(bundle []byte) ([]cpio.Entry, error) {
+	if err := ValidateCABundle(bundle); err != nil {
+		return nil, err
+	}
+	return []cpio.Entry{{Path: CABundlePath, Mode: 0o644, Content: bundle}}, nil
+}
+
+// NewCABundleIgnitionReader returns the files of the ISO that need to be overwritten in order to
+// embed the ignition config and trust the CA bundle, in the live environment as well as in the
+// ignition config, e.g. to go through a TLS intercepting proxy.
+func NewCABundleIgnitionReader(isoPath string, ignition, bundle []byte) ([]FileData, error) {
+	if ignition != nil && IsButane(ignition) {
+		var err error
+		if ignition, err = ButaneToIgnition(ignition); err != nil {
+			return nil, err
+		}
+	}
+	withCA, err := AddCABundleToIgnition(ignition, bundle)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := CABundleEntries(bundle)
+	if err != nil {
+		return nil, err
+	}
+	return NewIgnitionImageReaderWithFiles(isoPath, &IgnitionContent{Config: withCA}, entries...)
+}
@@ -0,0 +1,139 @@
+package isoeditor
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/cavaliercoder/go-cpio"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func testCABundle() []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "proxy-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+var _ = Describe("CA bundle", func() {
+	var bundle []byte
+
+	BeforeEach(func() {
+		bundle = testCABundle()
+	})
+
+	It("validates the bundle", func() {
+		Expect(ValidateCABundle(append(bundle, testCABundle()...))).To(Succeed())
+		Expect(ValidateCABundle([]byte("not a certificate"))).To(MatchError(ContainSubstring("no PEM encoded certificate found")))
+		key := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")})
+		Expect(ValidateCABundle(append(bundle, key...))).To(MatchError(ContainSubstring("unexpected PEM block of type PRIVATE KEY")))
+		garbage := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")})
+		Expect(ValidateCABundle(garbage)).To(MatchError(ContainSubstring("certificate 1")))
+	})
+
+	It("adds the bundle to the ignition config", func() {
+		ignition := []byte(`{"ignition": {"version": "3.1.0"}, "storage": {"files": [{"path": "/etc/motd", "contents": {"source": "data:,hi"}}]}}`)
+		withCA, err := AddCABundleToIgnition(ignition, bundle)
+		Expect(err).NotTo(HaveOccurred())
+
+		var config struct {
+			Ignition struct {
+				Version  string `json:"version"`
+				Security struct {
+					TLS struct {
+						CertificateAuthorities []struct {
+							Source string `json:"source"`
+						} `json:"certificateAuthorities"`
+					} `json:"tls"`
+				} `json:"security"`
+			} `json:"ignition"`
+			Storage struct {
+				Files []struct {
+					Path string `json:"path"`
+				} `json:"files"`
+			} `json:"storage"`
+		}
+		Expect(json.Unmarshal(withCA, &config)).To(Succeed())
+		Expect(config.Ignition.Version).To(Equal("3.1.0"))
+		Expect(config.Ignition.Security.TLS.CertificateAuthorities).To(HaveLen(1))
+		Expect(config.Ignition.Security.TLS.CertificateAuthorities[0].Source).To(HavePrefix("data:text/plain;charset=utf-8;base64,"))
+		Expect(config.Storage.Files).To(HaveLen(2))
+		Expect(config.Storage.Files[1].Path).To(Equal(CABundlePath))
+	})
+
+	It("embeds the bundle in the ignition image and the live initramfs", func() {
+		filesDir, isoFile := createTestFiles("Assisted123")
+		defer os.RemoveAll(filesDir)
+		defer os.Remove(isoFile)
+
+		files, err := NewCABundleIgnitionReader(isoFile, nil, bundle)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		image, err := io.ReadAll(files[0].Data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files[0].Data.Close()).To(Succeed())
+
+		// the ignition archive is followed by the one of the files
+		br := bufio.NewReader(bytes.NewReader(image))
+		gz, err := gzip.NewReader(br)
+		Expect(err).NotTo(HaveOccurred())
+		gz.Multistream(false)
+		header, err := cpio.NewReader(gz).Next()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(header.Name).To(Equal("config.ign"))
+		_, err = io.Copy(io.Discard, gz)
+		Expect(err).NotTo(HaveOccurred())
+
+		// skip the padding of the ignition archive
+		for {
+			b, err := br.ReadByte()
+			Expect(err).NotTo(HaveOccurred())
+			if b != 0 {
+				Expect(br.UnreadByte()).To(Succeed())
+				break
+			}
+		}
+		Expect(gz.Reset(br)).To(Succeed())
+		filesReader := cpio.NewReader(gz)
+		var names []string
+		for {
+			header, err := filesReader.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			names = append(names, header.Name)
+			if header.Name == "etc/pki/ca-trust/source/anchors/assisted-ca-bundle.pem" {
+				Expect(io.ReadAll(filesReader)).To(Equal(bundle))
+			}
+		}
+		Expect(names).To(ContainElement("etc/pki/ca-trust/source/anchors/assisted-ca-bundle.pem"))
+	})
+
+	It("rejects an invalid bundle", func() {
+		_, err := NewCABundleIgnitionReader("unused.iso", nil, []byte("invalid"))
+		Expect(err).To(MatchError(ContainSubstring("invalid CA bundle")))
+	})
+})
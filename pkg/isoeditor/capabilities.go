@@ -0,0 +1,74 @@
+package isoeditor
+
+import (
+	"strings"
+
+	"github.com/thoas/go-funk"
+)
+
+// ISOCapabilities are the boot methods of an ISO and the customizations it supports, found from
+// the files it actually contains
+type ISOCapabilities struct {
+	// BIOS boot through isolinux
+	BIOS bool `json:"bios"`
+	// UEFI boot through grub
+	UEFI bool `json:"uefi"`
+	// Petitboot boot of the OPAL firmware, or grub boot on PowerVM
+	Petitboot bool `json:"petitboot"`
+	// S390xIPL boot from the boot image or from the generic.ins
+	S390xIPL bool `json:"s390x_ipl"`
+	Ignition bool `json:"ignition"`
+	Ramdisk  bool `json:"ramdisk"`
+	// KargsFiles are the boot configs whose kernel arguments can be edited
+	KargsFiles []string `json:"kargs_files,omitempty"`
+}
+
+// Capabilities returns the boot methods and the customizations supported by an ISO
+func Capabilities(isoPath string) (*ISOCapabilities, error) {
+	arch, err := DetectArchitecture(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	exists := func(path string) bool {
+		_, _, err := GetISOFileInfo(path, isoPath)
+		return err == nil
+	}
+
+	c := &ISOCapabilities{
+		BIOS:     exists(defaultIsolinuxFilePath),
+		S390xIPL: arch == S390XCPUArchitecture,
+		Ramdisk:  exists(ramDiskImagePath),
+	}
+	if grubFiles, err := FindISOFiles(isoPath, grubFilePattern); err == nil {
+		c.UEFI = len(grubFiles) > 0
+	}
+	if arch == PPC64LECPUArchitecture {
+		_, err := customizerFor(arch).GrubConfigs(func(path string) bool { return exists("/" + path) })
+		c.Petitboot = err == nil
+	}
+	ibf := &ignitionBoundaryFinder{}
+	if _, _, err := ibf.findBoundaries(ignitionImagePath, isoPath); err == nil {
+		c.Ignition = true
+	}
+
+	files, err := KargsFiles(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	var signed []string
+	if arch == S390XCPUArchitecture {
+		if signed, err = S390xSignedFiles(isoPath); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range files {
+		path := "/" + strings.TrimPrefix(file, "/")
+		if !exists(path) || funk.ContainsString(signed, path) {
+			continue
+		}
+		if _, err := findKargsEmbedArea(isoPath, file); err == nil || isS390xParmFile(file) {
+			c.KargsFiles = append(c.KargsFiles, file)
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,65 @@
+package isoeditor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Capabilities", func() {
+	var (
+		filesDir string
+		isoFile  string
+	)
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+	})
+
+	It("reports the BIOS and UEFI boots of x86_64 ISOs", func() {
+		c, err := Capabilities(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c).To(Equal(&ISOCapabilities{
+			BIOS:       true,
+			UEFI:       true,
+			Ignition:   true,
+			Ramdisk:    true,
+			KargsFiles: []string{"/EFI/redhat/grub.cfg", defaultIsolinuxFilePath},
+		}))
+	})
+
+	Context("with an arm64 ISO", func() {
+		BeforeEach(func() {
+			Expect(os.RemoveAll(filepath.Join(filesDir, "isolinux"))).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(filesDir, "EFI/BOOT"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(filesDir, "EFI/BOOT/BOOTAA64.EFI"), []byte("shim"), 0600)).To(Succeed())
+			Expect(os.Remove(isoFile)).To(Succeed())
+			cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", "Assisted123", "-o", isoFile, filesDir)
+			Expect(cmd.Run()).To(Succeed())
+		})
+
+		It("reports the UEFI boot only", func() {
+			c, err := Capabilities(isoFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.BIOS).To(BeFalse())
+			Expect(c.UEFI).To(BeTrue())
+			Expect(c.KargsFiles).To(Equal([]string{"/EFI/redhat/grub.cfg"}))
+		})
+
+		It("only edits the kernel arguments of the grub config", func() {
+			files, err := NewKargsReader(isoFile, ARM64CPUArchitecture, " p1", KargsConflictKeepAll)
+			Expect(err).NotTo(HaveOccurred())
+			defer closeFileData(files)
+			Expect(files).To(HaveLen(1))
+			Expect(files[0].Filename).To(Equal("/EFI/redhat/grub.cfg"))
+		})
+	})
+})
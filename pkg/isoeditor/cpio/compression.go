@@ -0,0 +1,224 @@
+package cpio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	gocpio "github.com/cavaliercoder/go-cpio"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Compression is the compression format of an initramfs archive
+type Compression string
+
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	CompressionXZ   Compression = "xz"
+	CompressionZstd Compression = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	xzMagic   = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	newcMagic = []byte("070701")
+)
+
+const (
+	newcHeaderLength = 110
+	newcTrailer      = "TRAILER!!!"
+)
+
+// ErrUnknownCompression is returned by DetectCompression for content that is neither a CPIO
+// archive nor compressed with a supported format
+var ErrUnknownCompression = errors.New("unknown initramfs compression format")
+
+// ParseCompression returns the compression format with the given name
+func ParseCompression(name string) (Compression, error) {
+	switch c := Compression(name); c {
+	case CompressionNone, CompressionGzip, CompressionXZ, CompressionZstd:
+		return c, nil
+	}
+	return "", fmt.Errorf("unsupported compression format %q, expected one of %s, %s, %s or %s",
+		name, CompressionNone, CompressionGzip, CompressionXZ, CompressionZstd)
+}
+
+// NewWriter returns a writer compressing to w with the given format. The xz output uses CRC32
+// checksums, the only ones the kernel decompressor supports.
+func NewWriter(w io.Writer, compression Compression) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionNone:
+		return nopWriteCloser{w}, nil
+	case CompressionGzip, "":
+		return gzip.NewWriter(w), nil
+	case CompressionXZ:
+		return xz.WriterConfig{CheckSum: xz.CRC32}.NewWriter(w)
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported compression format %q", compression)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// NewReader returns a reader decompressing r with the given format
+func NewReader(r io.Reader, compression Compression) (io.ReadCloser, error) {
+	switch compression {
+	case CompressionNone:
+		return io.NopCloser(r), nil
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionXZ:
+		xzReader, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xzReader), nil
+	case CompressionZstd:
+		zstdReader, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zstdReader.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unsupported compression format %q", compression)
+}
+
+// DetectCompression returns the compression format of the main archive of an initramfs. Leading
+// uncompressed archives, such as the early microcode one of RHCOS, are skipped and r is left at
+// the start of the compressed archive.
+func DetectCompression(r io.ReadSeeker) (Compression, error) {
+	magic := make([]byte, 6)
+	for {
+		n, err := io.ReadFull(r, magic)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return "", err
+		}
+		magic := magic[:n]
+		var compression Compression
+		switch {
+		case bytes.HasPrefix(magic, gzipMagic):
+			compression = CompressionGzip
+		case bytes.HasPrefix(magic, xzMagic):
+			compression = CompressionXZ
+		case bytes.HasPrefix(magic, zstdMagic):
+			compression = CompressionZstd
+		case !bytes.Equal(magic, newcMagic):
+			return "", ErrUnknownCompression
+		}
+		if compression != "" {
+			if _, err := r.Seek(int64(-n), io.SeekCurrent); err != nil {
+				return "", err
+			}
+			return compression, nil
+		}
+
+		last, err := skipNewcArchive(r)
+		if err != nil {
+			return "", err
+		}
+		if last {
+			return CompressionNone, nil
+		}
+	}
+}
+
+// skipNewcArchive moves r, positioned after the magic of the first header, past the end of the
+// archive and the zero padding that follows it. It returns true when nothing follows.
+func skipNewcArchive(r io.ReadSeeker) (bool, error) {
+	header := make([]byte, newcHeaderLength)
+	copy(header, newcMagic)
+	for first := true; ; first = false {
+		start := len(newcMagic)
+		if !first {
+			start = 0
+		}
+		if _, err := io.ReadFull(r, header[start:]); err != nil {
+			return false, fmt.Errorf("failed to read CPIO header: %w", err)
+		}
+		if !bytes.Equal(header[:len(newcMagic)], newcMagic) {
+			return false, fmt.Errorf("invalid CPIO header")
+		}
+		fileSize, err := strconv.ParseUint(string(header[54:62]), 16, 32)
+		if err != nil {
+			return false, fmt.Errorf("invalid CPIO file size: %w", err)
+		}
+		nameSize, err := strconv.ParseUint(string(header[94:102]), 16, 32)
+		if err != nil {
+			return false, fmt.Errorf("invalid CPIO name size: %w", err)
+		}
+		name := make([]byte, pad4(newcHeaderLength+int64(nameSize))-newcHeaderLength)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return false, fmt.Errorf("failed to read CPIO file name: %w", err)
+		}
+		if string(bytes.TrimRight(name[:nameSize], "\x00")) == newcTrailer {
+			break
+		}
+		if _, err := r.Seek(pad4(int64(fileSize)), io.SeekCurrent); err != nil {
+			return false, err
+		}
+	}
+
+	// archives are padded with zeros, usually to 512 bytes
+	b := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(r, b); err != nil {
+			if errors.Is(err, io.EOF) {
+				return true, nil
+			}
+			return false, err
+		}
+		if b[0] != 0 {
+			_, err := r.Seek(-1, io.SeekCurrent)
+			return false, err
+		}
+	}
+}
+
+func pad4(n int64) int64 {
+	return (n + 3) &^ 3
+}
+
+// ListFiles returns the paths of the entries of the main archive of an initramfs, the first
+// compressed one. Initramfs images that aren't compressed are listed whole.
+func ListFiles(r io.ReadSeeker) ([]string, error) {
+	compression, err := DetectCompression(r)
+	if err != nil {
+		return nil, err
+	}
+	if compression == CompressionNone {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	decompressed, err := NewReader(r, compression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s archive: %w", compression, err)
+	}
+	defer decompressed.Close()
+
+	var names []string
+	cpioReader := gocpio.NewReader(decompressed)
+	for {
+		header, err := cpioReader.Next()
+		if errors.Is(err, io.EOF) {
+			return names, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s archive: %w", compression, err)
+		}
+		names = append(names, header.Name)
+	}
+}
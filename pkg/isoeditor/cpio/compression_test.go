@@ -0,0 +1,102 @@
+package cpio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	gocpio "github.com/cavaliercoder/go-cpio"
+	"github.com/klauspost/compress/zstd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/ulikunitz/xz"
+)
+
+func decompress(archive []byte, compression Compression) io.Reader {
+	switch compression {
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(archive))
+		Expect(err).NotTo(HaveOccurred())
+		r.Multistream(false)
+		return r
+	case CompressionXZ:
+		r, err := xz.NewReader(bytes.NewReader(archive))
+		Expect(err).NotTo(HaveOccurred())
+		return r
+	case CompressionZstd:
+		r, err := zstd.NewReader(bytes.NewReader(bytes.TrimRight(archive, "\x00")))
+		Expect(err).NotTo(HaveOccurred())
+		return r
+	}
+	return bytes.NewReader(archive)
+}
+
+var _ = Describe("ArchiveWithCompression", func() {
+	for _, compression := range []Compression{CompressionGzip, CompressionXZ, CompressionZstd, CompressionNone} {
+		compression := compression
+		It("archives with "+string(compression), func() {
+			archive, err := ArchiveWithCompression(compression, Entry{Path: "config.ign", Content: []byte("{}")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(archive) % 4).To(Equal(0))
+
+			header, err := gocpio.NewReader(decompress(archive, compression)).Next()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(header.Name).To(Equal("config.ign"))
+
+			detected, err := DetectCompression(bytes.NewReader(archive))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(detected).To(Equal(compression))
+		})
+	}
+
+	It("uses CRC32 checks for xz", func() {
+		archive, err := ArchiveWithCompression(CompressionXZ, Entry{Path: "config.ign"})
+		Expect(err).NotTo(HaveOccurred())
+		// stream flags of the header, 0x01 is CRC32
+		Expect(archive[6:8]).To(Equal([]byte{0x00, 0x01}))
+	})
+})
+
+var _ = Describe("DetectCompression", func() {
+	It("skips the leading uncompressed archives", func() {
+		early, err := ArchiveWithCompression(CompressionNone, Entry{Path: "kernel/x86/microcode/GenuineIntel.bin", Content: []byte("microcode")})
+		Expect(err).NotTo(HaveOccurred())
+		main, err := ArchiveWithCompression(CompressionZstd, Entry{Path: "init", Content: []byte("#!/bin/sh")})
+		Expect(err).NotTo(HaveOccurred())
+
+		initrd := append(append(early, make([]byte, 512-len(early)%512)...), main...)
+		Expect(DetectCompression(bytes.NewReader(initrd))).To(Equal(CompressionZstd))
+	})
+
+	It("fails for unknown formats", func() {
+		_, err := DetectCompression(bytes.NewReader([]byte("BZh91AY&SY")))
+		Expect(err).To(Equal(ErrUnknownCompression))
+	})
+})
+
+var _ = Describe("ListFiles", func() {
+	It("lists the files of the main archive", func() {
+		early, err := ArchiveWithCompression(CompressionNone, Entry{Path: "kernel/x86/microcode/GenuineIntel.bin", Content: []byte("microcode")})
+		Expect(err).NotTo(HaveOccurred())
+		for _, compression := range []Compression{CompressionGzip, CompressionXZ, CompressionZstd} {
+			main, err := ArchiveWithCompression(compression, Entry{Path: "usr/sbin/iscsistart", Content: []byte("binary")})
+			Expect(err).NotTo(HaveOccurred())
+			initrd := append(append(append([]byte{}, early...), make([]byte, 512-len(early)%512)...), main...)
+			Expect(ListFiles(bytes.NewReader(initrd))).To(Equal([]string{"usr", "usr/sbin", "usr/sbin/iscsistart"}), string(compression))
+		}
+	})
+
+	It("lists uncompressed initramfs images", func() {
+		initrd, err := ArchiveWithCompression(CompressionNone, Entry{Path: "init", Content: []byte("#!/bin/sh")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ListFiles(bytes.NewReader(initrd))).To(Equal([]string{"init"}))
+	})
+})
+
+var _ = Describe("ParseCompression", func() {
+	It("parses the supported formats", func() {
+		Expect(ParseCompression("zstd")).To(Equal(CompressionZstd))
+		_, err := ParseCompression("lz4")
+		Expect(err).To(MatchError(ContainSubstring("unsupported compression format \"lz4\"")))
+	})
+})
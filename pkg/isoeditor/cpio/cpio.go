@@ -0,0 +1,145 @@
+// Package cpio builds compressed CPIO archives of arbitrary files to be added to the live
+// initramfs. The kernel unpacks concatenated archives, so the result can be appended to the
+// ignition image or to the ramdisk content of minimal ISOs.
+package cpio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	gocpio "github.com/cavaliercoder/go-cpio"
+)
+
+const defaultFileMode = 0o644
+
+// Entry is a file or directory of an archive
+type Entry struct {
+	// Path of the file in the initramfs, relative to its root
+	Path string
+	// Mode holds the permissions, and os.ModeDir or os.ModeSymlink for directories and symbolic
+	// links. Files default to 0644.
+	Mode os.FileMode
+	// Content of regular files, or the target of symbolic links
+	Content []byte
+}
+
+func (e Entry) cpioMode() gocpio.FileMode {
+	if e.Mode.IsDir() {
+		return gocpio.ModeDir | gocpio.FileMode(e.Mode.Perm())
+	}
+	if e.Mode&os.ModeSymlink != 0 {
+		return gocpio.ModeSymlink | 0o777
+	}
+	perm := e.Mode.Perm()
+	if perm == 0 {
+		perm = defaultFileMode
+	}
+	return gocpio.ModeRegular | gocpio.FileMode(perm)
+}
+
+// Archive returns a gzip compressed archive of the entries in the newc format
+func Archive(entries ...Entry) ([]byte, error) {
+	return ArchiveWithLevel(gzip.DefaultCompression, entries...)
+}
+
+// ArchiveWithLevel is like Archive with the given gzip compression level
+func ArchiveWithLevel(level int, entries ...Entry) ([]byte, error) {
+	return archive(func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, level)
+	}, entries)
+}
+
+// ArchiveWithCompression is like Archive with the given compression format, for initramfs
+// images that are not compressed with gzip
+func ArchiveWithCompression(compression Compression, entries ...Entry) ([]byte, error) {
+	return archive(func(w io.Writer) (io.WriteCloser, error) {
+		return NewWriter(w, compression)
+	}, entries)
+}
+
+// archive writes the entries through the compressor. The missing parent directories of the
+// entries are added, and the archive is padded to a multiple of 4 bytes as required to
+// concatenate it with other archives.
+func archive(newCompressor func(io.Writer) (io.WriteCloser, error), entries []Entry) ([]byte, error) {
+	entries, err := withParentDirs(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	compressor, err := newCompressor(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compressor: %w", err)
+	}
+	cpioWriter := gocpio.NewWriter(compressor)
+	for _, entry := range entries {
+		header := &gocpio.Header{
+			Name: entry.Path,
+			Mode: entry.cpioMode(),
+		}
+		if !entry.Mode.IsDir() {
+			header.Size = int64(len(entry.Content))
+		}
+		if err := cpioWriter.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("failed to write CPIO header for %s: %w", entry.Path, err)
+		}
+		if header.Size > 0 {
+			if _, err := cpioWriter.Write(entry.Content); err != nil {
+				return nil, fmt.Errorf("failed to write %s to CPIO archive: %w", entry.Path, err)
+			}
+		}
+	}
+	if err := cpioWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close CPIO archive: %w", err)
+	}
+	if err := compressor.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress CPIO archive: %w", err)
+	}
+
+	buf.Write(make([]byte, (4-buf.Len()%4)%4))
+	return buf.Bytes(), nil
+}
+
+// withParentDirs normalizes the paths of the entries and adds the directories that contain
+// them, so they are created before the files are unpacked
+func withParentDirs(entries []Entry) ([]Entry, error) {
+	present := map[string]bool{}
+	normalized := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		entry.Path = strings.TrimPrefix(path.Clean("/"+entry.Path), "/")
+		if entry.Path == "" {
+			return nil, fmt.Errorf("invalid path for CPIO entry")
+		}
+		if present[entry.Path] {
+			return nil, fmt.Errorf("duplicate CPIO entry %s", entry.Path)
+		}
+		present[entry.Path] = true
+		normalized = append(normalized, entry)
+	}
+
+	var dirs []Entry
+	for _, entry := range normalized {
+		for dir := path.Dir(entry.Path); dir != "."; dir = path.Dir(dir) {
+			if present[dir] {
+				continue
+			}
+			present[dir] = true
+			dirs = append(dirs, Entry{Path: dir, Mode: os.ModeDir | 0o755})
+		}
+	}
+	// directories go first, parents before their subdirectories
+	all := append(dirs, normalized...)
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].Mode.IsDir() != all[j].Mode.IsDir() {
+			return all[i].Mode.IsDir()
+		}
+		return all[i].Mode.IsDir() && strings.Count(all[i].Path, "/") < strings.Count(all[j].Path, "/")
+	})
+	return all, nil
+}
@@ -0,0 +1,103 @@
+package cpio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"testing"
+
+	gocpio "github.com/cavaliercoder/go-cpio"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCPIO(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "cpio")
+}
+
+type unpacked struct {
+	name    string
+	mode    gocpio.FileMode
+	content string
+}
+
+func unpack(archive []byte) []unpacked {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+	Expect(err).NotTo(HaveOccurred())
+	gzipReader.Multistream(false)
+	cpioReader := gocpio.NewReader(gzipReader)
+	var files []unpacked
+	for {
+		header, err := cpioReader.Next()
+		if err == io.EOF {
+			return files
+		}
+		Expect(err).NotTo(HaveOccurred())
+		content, err := io.ReadAll(cpioReader)
+		Expect(err).NotTo(HaveOccurred())
+		files = append(files, unpacked{header.Name, header.Mode, string(content)})
+	}
+}
+
+var _ = Describe("Archive", func() {
+	It("archives the files after their parent directories", func() {
+		archive, err := Archive(
+			Entry{Path: "/etc/pki/ca-trust/source/anchors/ca.crt", Content: []byte("cert")},
+			Entry{Path: "usr/local/bin/setup.sh", Mode: 0o755, Content: []byte("#!/bin/sh")},
+			Entry{Path: "etc", Mode: os.ModeDir | 0o700},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(len(archive) % 4).To(Equal(0))
+
+		Expect(unpack(archive)).To(Equal([]unpacked{
+			{"usr", gocpio.ModeDir | 0o755, ""},
+			{"etc", gocpio.ModeDir | 0o700, ""},
+			{"etc/pki", gocpio.ModeDir | 0o755, ""},
+			{"usr/local", gocpio.ModeDir | 0o755, ""},
+			{"etc/pki/ca-trust", gocpio.ModeDir | 0o755, ""},
+			{"usr/local/bin", gocpio.ModeDir | 0o755, ""},
+			{"etc/pki/ca-trust/source", gocpio.ModeDir | 0o755, ""},
+			{"etc/pki/ca-trust/source/anchors", gocpio.ModeDir | 0o755, ""},
+			{"etc/pki/ca-trust/source/anchors/ca.crt", gocpio.ModeRegular | 0o644, "cert"},
+			{"usr/local/bin/setup.sh", gocpio.ModeRegular | 0o755, "#!/bin/sh"},
+		}))
+	})
+
+	It("archives symbolic links", func() {
+		archive, err := Archive(
+			Entry{Path: "/etc/motd", Content: []byte("hello")},
+			Entry{Path: "/etc/issue", Mode: os.ModeSymlink, Content: []byte("/etc/motd")},
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+		Expect(err).NotTo(HaveOccurred())
+		cpioReader := gocpio.NewReader(gzipReader)
+		var link *gocpio.Header
+		for {
+			header, err := cpioReader.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			if header.Name == "etc/issue" {
+				link = header
+			}
+		}
+		Expect(link).NotTo(BeNil())
+		Expect(link.Mode).To(Equal(gocpio.FileMode(gocpio.ModeSymlink | 0o777)))
+		Expect(link.Linkname).To(Equal("/etc/motd"))
+	})
+
+	It("fails on duplicate entries", func() {
+		_, err := Archive(Entry{Path: "/etc/motd"}, Entry{Path: "etc/motd"})
+		Expect(err).To(MatchError("duplicate CPIO entry etc/motd"))
+	})
+
+	It("fails on an empty path", func() {
+		_, err := Archive(Entry{Path: "/"})
+		Expect(err).To(HaveOccurred())
+	})
+})
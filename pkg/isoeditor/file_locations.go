@@ -0,0 +1,126 @@
+package isoeditor
+
+import (
+	"container/list"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// isoFileLocation is where the content of a file lies in an ISO
+type isoFileLocation struct {
+	offset int64
+	length int64
+	// the ranges of the ISO holding the content when the file has several extents that aren't
+	// contiguous, nil otherwise
+	sections []isoSection
+	dir      bool
+}
+
+// reader returns a reader of the content of the file in iso
+func (l *isoFileLocation) reader(iso io.ReaderAt) *io.SectionReader {
+	if len(l.sections) > 1 {
+		return io.NewSectionReader(&sectionsReaderAt{ReaderAt: iso, sections: l.sections}, 0, l.length)
+	}
+	return io.NewSectionReader(iso, l.offset, l.length)
+}
+
+type isoFileLookup struct {
+	location *isoFileLocation
+	err      error
+}
+
+// isoFileLookups are the lookups of the files of a version of an ISO
+type isoFileLookups struct {
+	key   isoFileLookupsKey
+	files map[string]isoFileLookup
+	// whether the files carry components signed for s390x secure IPL
+	signed map[string]bool
+}
+
+// isoFileLookupsKey identifies a version of an ISO, the lookups of the previous versions aren't
+// used anymore and age out of the cache
+type isoFileLookupsKey struct {
+	path    string
+	size    int64
+	modTime int64
+}
+
+// maxISOFileLookups bounds the ISOs whose lookups are kept, the least recently used are dropped
+const maxISOFileLookups = 128
+
+// isoFileLocations holds the files looked up in the ISOs, so that the directories of the ISOs are
+// walked once for each file rather than on every request
+var isoFileLocations = struct {
+	sync.Mutex
+	// isos holds the elements of lru, the most recently used lookups first
+	isos map[isoFileLookupsKey]*list.Element
+	lru  *list.List
+}{isos: map[isoFileLookupsKey]*list.Element{}, lru: list.New()}
+
+// locateISOFile resolves the file in the ISO as GetFileFromISO does, from the memory cache when
+// the ISO didn't change since the file was last looked up
+func locateISOFile(isoPath, filePath string) (*isoFileLocation, error) {
+	lookups, err := isoLookups(isoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	isoFileLocations.Lock()
+	lookup, ok := lookups.files[filePath]
+	isoFileLocations.Unlock()
+	if ok {
+		return lookup.location, lookup.err
+	}
+
+	img, err := openISOImage(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer img.Close()
+	record, err := img.lookupVolume().resolve(filePath)
+	if err == nil {
+		lookup.location = &isoFileLocation{
+			offset: record.extent() * isoSectorSize,
+			length: record.length(),
+			dir:    record.isDir(),
+		}
+		if sections := record.sections(); len(sections) > 1 {
+			lookup.location.sections = sections
+		}
+	}
+	lookup.err = err
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	isoFileLocations.Lock()
+	lookups.files[filePath] = lookup
+	isoFileLocations.Unlock()
+	return lookup.location, lookup.err
+}
+
+// isoLookups returns the lookups of the current version of the ISO
+func isoLookups(isoPath string) (*isoFileLookups, error) {
+	info, err := os.Stat(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	key := isoFileLookupsKey{path: isoPath, size: info.Size(), modTime: info.ModTime().UnixNano()}
+
+	isoFileLocations.Lock()
+	defer isoFileLocations.Unlock()
+	if element, ok := isoFileLocations.isos[key]; ok {
+		isoFileLocations.lru.MoveToFront(element)
+		return element.Value.(*isoFileLookups), nil
+	}
+	if isoFileLocations.lru.Len() >= maxISOFileLookups {
+		oldest := isoFileLocations.lru.Back()
+		isoFileLocations.lru.Remove(oldest)
+		delete(isoFileLocations.isos, oldest.Value.(*isoFileLookups).key)
+	}
+	lookups := &isoFileLookups{key: key, files: map[string]isoFileLookup{}, signed: map[string]bool{}}
+	isoFileLocations.isos[key] = isoFileLocations.lru.PushFront(lookups)
+	return lookups, nil
+}
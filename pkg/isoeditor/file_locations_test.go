@@ -0,0 +1,92 @@
+package isoeditor
+
+import (
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("locateISOFile", func() {
+	var (
+		filesDir string
+		isoFile  string
+	)
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+	})
+
+	// zeroVolume blanks the volume descriptors of the ISO without changing its modification time,
+	// so that only the cached lookups still succeed
+	zeroVolume := func() {
+		info, err := os.Stat(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		f, err := os.OpenFile(isoFile, os.O_WRONLY, 0)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.WriteAt(make([]byte, 4*isoSectorSize), 16*isoSectorSize)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		Expect(os.Chtimes(isoFile, info.ModTime(), info.ModTime())).To(Succeed())
+	}
+
+	It("looks up the files once while the ISO is unchanged", func() {
+		offset, length, err := GetISOFileInfo(rootfsImagePath, isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = GetISOFileInfo(s390xKernelPathInISO, isoFile)
+		Expect(err).To(MatchError(os.ErrNotExist))
+		content, err := ReadFileFromISO(isoFile, defaultGrubFilePath)
+		Expect(err).NotTo(HaveOccurred())
+
+		zeroVolume()
+		cachedOffset, cachedLength, err := GetISOFileInfo(rootfsImagePath, isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cachedOffset).To(Equal(offset))
+		Expect(cachedLength).To(Equal(length))
+		_, _, err = GetISOFileInfo(s390xKernelPathInISO, isoFile)
+		Expect(err).To(MatchError(os.ErrNotExist))
+		Expect(isoFileSections(isoFile, rootfsImagePath)).To(Equal([]isoSection{{offset: offset, length: length}}))
+		Expect(ReadFileFromISO(isoFile, defaultGrubFilePath)).To(Equal(content))
+	})
+
+	It("looks up the files again once the ISO changes", func() {
+		_, _, err := GetISOFileInfo(rootfsImagePath, isoFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		zeroVolume()
+		later := time.Now().Add(time.Hour)
+		Expect(os.Chtimes(isoFile, later, later)).To(Succeed())
+		_, _, err = GetISOFileInfo(rootfsImagePath, isoFile)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("drops the lookups of the least recently used ISOs", func() {
+		info, err := os.Stat(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		first, err := isoLookups(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isoLookups(isoFile)).To(BeIdenticalTo(first))
+
+		// each modification time is a version of the ISO
+		for i := 1; i <= maxISOFileLookups; i++ {
+			modTime := info.ModTime().Add(time.Duration(i) * time.Second)
+			Expect(os.Chtimes(isoFile, modTime, modTime)).To(Succeed())
+			_, err = isoLookups(isoFile)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		isoFileLocations.Lock()
+		Expect(isoFileLocations.isos).To(HaveLen(maxISOFileLookups))
+		Expect(isoFileLocations.lru.Len()).To(Equal(maxISOFileLookups))
+		Expect(isoFileLocations.isos).NotTo(HaveKey(first.key))
+		isoFileLocations.Unlock()
+
+		Expect(os.Chtimes(isoFile, info.ModTime(), info.ModTime())).To(Succeed())
+		Expect(isoLookups(isoFile)).NotTo(BeIdenticalTo(first))
+	})
+})
@@ -0,0 +1,185 @@
+package isoeditor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/google/renameio"
+	"github.com/pkg/errors"
+)
+
+// FileOffsetsSuffix is the suffix of the sidecar file of an ISO caching the offsets of the files
+// customized per request, see WriteFileOffsets
+const FileOffsetsSuffix = ".offsets.json"
+
+// offsetsFilePaths are the files whose offsets are cached, in addition to the kernel arguments
+// files and the files telling the architecture
+var offsetsFilePaths = []string{
+	kernelPathInISO, s390xKernelPathInISO, "/" + initrdPathInISO, rootfsImagePath,
+	ignitionImagePath, ignitionInfoPath, ramDiskImagePath, kargsConfigFilePath,
+}
+
+type fileExtent struct {
+	Offset int64 `json:"offset"`
+	Length int64 `json:"length"`
+}
+
+// cachedKargsArea is a kargsEmbedArea
+type cachedKargsArea struct {
+	Offset    int64    `json:"offset"`
+	Length    int64    `json:"length"`
+	Separator string   `json:"separator,omitempty"`
+	End       byte     `json:"end"`
+	Pad       byte     `json:"pad"`
+	Kargs     []string `json:"kargs"`
+}
+
+// fileOffsets is the content of the sidecar file. The files missing from the ISO are null.
+type fileOffsets struct {
+	// Size and ModTime of the ISO, the offsets are ignored once it changes
+	Size       int64                       `json:"size"`
+	ModTime    time.Time                   `json:"mod_time"`
+	Files      map[string]*fileExtent      `json:"files"`
+	KargsAreas map[string]*cachedKargsArea `json:"kargs_areas,omitempty"`
+}
+
+func (o *fileOffsets) matches(info os.FileInfo) bool {
+	return o.Size == info.Size() && o.ModTime.Equal(info.ModTime())
+}
+
+// fileOffsetsCache holds the sidecar files read, by ISO path
+var fileOffsetsCache = struct {
+	sync.Mutex
+	offsets map[string]*fileOffsets
+}{offsets: map[string]*fileOffsets{}}
+
+// WriteFileOffsets locates the boot files, embed areas and kernel arguments areas of the ISO and
+// writes them to its sidecar file, so that customizing it doesn't walk its directories. Nothing
+// is written when the sidecar file is current.
+func WriteFileOffsets(isoPath string) error {
+	if cachedFileOffsets(isoPath) != nil {
+		return nil
+	}
+	info, err := os.Stat(isoPath)
+	if err != nil {
+		return err
+	}
+	kargsFiles, err := KargsFiles(isoPath)
+	if err != nil {
+		return err
+	}
+	offsets := &fileOffsets{
+		Size:       info.Size(),
+		ModTime:    info.ModTime(),
+		Files:      map[string]*fileExtent{},
+		KargsAreas: map[string]*cachedKargsArea{},
+	}
+	paths := append([]string{}, offsetsFilePaths...)
+	for _, c := range archCustomizers {
+		paths = append(paths, c.MarkerFiles()...)
+	}
+	for _, filePath := range append(paths, kargsFiles...) {
+		offset, length, err := GetISOFileInfo(filePath, isoPath)
+		if errors.Is(err, os.ErrNotExist) {
+			offsets.Files[offsetsKey(filePath)] = nil
+			continue
+		} else if err != nil {
+			return err
+		}
+		offsets.Files[offsetsKey(filePath)] = &fileExtent{Offset: offset, Length: length}
+	}
+	for _, filePath := range kargsFiles {
+		area, err := kargsEmbedAreaFinder(isoPath, filePath, GetISOFileInfo, ReadFileFromISO)
+		if err != nil {
+			continue
+		}
+		offsets.KargsAreas[offsetsKey(filePath)] = &cachedKargsArea{
+			Offset:    area.offset,
+			Length:    area.length,
+			Separator: area.separator,
+			End:       area.end,
+			Pad:       area.pad,
+			Kargs:     area.kargs,
+		}
+	}
+
+	data, err := json.Marshal(offsets)
+	if err != nil {
+		return err
+	}
+	if err := renameio.WriteFile(isoPath+FileOffsetsSuffix, data, 0600); err != nil {
+		return fmt.Errorf("failed to write the file offsets of %s: %w", isoPath, err)
+	}
+	fileOffsetsCache.Lock()
+	fileOffsetsCache.offsets[isoPath] = offsets
+	fileOffsetsCache.Unlock()
+	return nil
+}
+
+func offsetsKey(filePath string) string {
+	return path.Join("/", filePath)
+}
+
+// cachedFileOffsets returns the content of the sidecar file of the ISO, nil if it has none or
+// the ISO changed since it was written
+func cachedFileOffsets(isoPath string) *fileOffsets {
+	info, err := os.Stat(isoPath)
+	if err != nil {
+		return nil
+	}
+	fileOffsetsCache.Lock()
+	defer fileOffsetsCache.Unlock()
+	if offsets := fileOffsetsCache.offsets[isoPath]; offsets != nil && offsets.matches(info) {
+		return offsets
+	}
+	delete(fileOffsetsCache.offsets, isoPath)
+	data, err := os.ReadFile(isoPath + FileOffsetsSuffix)
+	if err != nil {
+		return nil
+	}
+	offsets := &fileOffsets{}
+	if err := json.Unmarshal(data, offsets); err != nil || !offsets.matches(info) {
+		return nil
+	}
+	fileOffsetsCache.offsets[isoPath] = offsets
+	return offsets
+}
+
+// cachedFileInfo is GetISOFileInfo from the sidecar file, ok is false when the file isn't cached
+func cachedFileInfo(filePath, isoPath string) (offset, length int64, ok bool, err error) {
+	offsets := cachedFileOffsets(isoPath)
+	if offsets == nil {
+		return 0, 0, false, nil
+	}
+	extent, ok := offsets.Files[offsetsKey(filePath)]
+	if !ok {
+		return 0, 0, false, nil
+	}
+	if extent == nil {
+		return 0, 0, true, errors.Wrapf(fmt.Errorf("%s: %w", filePath, os.ErrNotExist), "Failed to open file %s", filePath)
+	}
+	return extent.Offset, extent.Length, true, nil
+}
+
+// findKargsEmbedArea locates the kernel arguments area of the file of the ISO, from the sidecar
+// file when it has it
+func findKargsEmbedArea(isoPath, filePath string) (*kargsEmbedArea, error) {
+	if offsets := cachedFileOffsets(isoPath); offsets != nil {
+		if area, ok := offsets.KargsAreas[offsetsKey(filePath)]; ok {
+			return &kargsEmbedArea{
+				offset:    area.Offset,
+				length:    area.Length,
+				separator: area.Separator,
+				end:       area.End,
+				pad:       area.Pad,
+				// the callers append to the arguments
+				kargs: append([]string{}, area.Kargs...),
+			}, nil
+		}
+	}
+	return kargsEmbedAreaFinder(isoPath, filePath, GetISOFileInfo, ReadFileFromISO)
+}
@@ -0,0 +1,79 @@
+package isoeditor
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WriteFileOffsets", func() {
+	var (
+		filesDir string
+		isoFile  string
+	)
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+		Expect(os.Remove(isoFile + FileOffsetsSuffix)).To(Succeed())
+	})
+
+	It("caches the offsets of the files of the ISO", func() {
+		offset, length, err := GetISOFileInfo(rootfsImagePath, isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		area, err := kargsEmbedAreaFinder(isoFile, defaultGrubFilePath, GetISOFileInfo, ReadFileFromISO)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(WriteFileOffsets(isoFile)).To(Succeed())
+		Expect(isoFile + FileOffsetsSuffix).To(BeAnExistingFile())
+
+		cachedOffset, cachedLength, ok, err := cachedFileInfo("images/pxeboot/rootfs.img", isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(cachedOffset).To(Equal(offset))
+		Expect(cachedLength).To(Equal(length))
+
+		_, _, ok, err = cachedFileInfo(s390xKernelPathInISO, isoFile)
+		Expect(ok).To(BeTrue())
+		Expect(err).To(MatchError(os.ErrNotExist))
+
+		Expect(findKargsEmbedArea(isoFile, defaultGrubFilePath)).To(Equal(area))
+	})
+
+	It("locates the files with the sidecar file", func() {
+		Expect(WriteFileOffsets(isoFile)).To(Succeed())
+		data, err := os.ReadFile(isoFile + FileOffsetsSuffix)
+		Expect(err).NotTo(HaveOccurred())
+		offsets := &fileOffsets{}
+		Expect(json.Unmarshal(data, offsets)).To(Succeed())
+		offsets.Files[rootfsImagePath].Offset = 2048
+		data, err = json.Marshal(offsets)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(isoFile+FileOffsetsSuffix, data, 0600)).To(Succeed())
+		fileOffsetsCache.Lock()
+		delete(fileOffsetsCache.offsets, isoFile)
+		fileOffsetsCache.Unlock()
+
+		offset, _, err := GetISOFileInfo(rootfsImagePath, isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(offset).To(Equal(int64(2048)))
+	})
+
+	It("ignores the sidecar file once the ISO changes", func() {
+		Expect(WriteFileOffsets(isoFile)).To(Succeed())
+		Expect(cachedFileOffsets(isoFile)).NotTo(BeNil())
+		later := time.Now().Add(time.Hour)
+		Expect(os.Chtimes(isoFile, later, later)).To(Succeed())
+		Expect(cachedFileOffsets(isoFile)).To(BeNil())
+
+		Expect(WriteFileOffsets(isoFile)).To(Succeed())
+		Expect(cachedFileOffsets(isoFile)).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,108 @@
+package isoeditor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+// grubCustomizer handles the ISOs booting with grub on UEFI and, when they have an isolinux
+// config, with isolinux on BIOS, like the x86_64 and arm64 ones. The kernel arguments are
+// written to the embed area of the boot configs.
+type grubCustomizer struct {
+	arch        string
+	markerFiles []string
+}
+
+var _ ArchCustomizer = &grubCustomizer{}
+
+func (c *grubCustomizer) Arch() string {
+	return c.arch
+}
+
+func (c *grubCustomizer) MarkerFiles() []string {
+	return c.markerFiles
+}
+
+// GrubConfigs returns the first grub config found, the ISOs having a single one
+func (c *grubCustomizer) GrubConfigs(exists func(path string) bool) ([]string, error) {
+	return findGrubConfigs(grubConfigPaths, false, exists)
+}
+
+// DefaultKargsFiles returns the grub configs and the isolinux config found in the ISO. The arm64
+// ISOs only boot with UEFI so they have no isolinux config.
+func (c *grubCustomizer) DefaultKargsFiles(isoPath string) []string {
+	var ret []string
+	if grubFiles, err := FindISOFiles(isoPath, grubFilePattern); err == nil {
+		for _, file := range grubFiles {
+			ret = append(ret, file.Path)
+		}
+	}
+	if _, _, err := GetISOFileInfo(defaultIsolinuxFilePath, isoPath); err == nil {
+		ret = append(ret, defaultIsolinuxFilePath)
+	}
+	return ret
+}
+
+// KargsFileData writes the appended kernel arguments at the start of the embed area, leaving the
+// arguments of the ISO untouched, unless they have to be edited by the operations or the policy
+func (c *grubCustomizer) KargsFileData(isoPath, file string, kargs KernelArguments, policy KargsConflictPolicy) (FileData, error) {
+	if kargs.AppendOnly() && policy == KargsConflictKeepAll {
+		content := bytes.NewReader([]byte(kargs.appendString()))
+		return kargsOverlayFileData(isoPath, file, func(base io.ReadSeeker) (overlay.OverlayReader, error) {
+			return readerForKargsContent(isoPath, file, base, content)
+		})
+	}
+	return kargsOperationsFileData(isoPath, file, kargs, policy)
+}
+
+// findGrubConfigs returns the existing grub configs among the paths, all of them or the first one
+func findGrubConfigs(paths []string, all bool, exists func(path string) bool) ([]string, error) {
+	var found []string
+	for _, path := range paths {
+		if exists(path) {
+			found = append(found, path)
+			if !all {
+				break
+			}
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("no grub.cfg found, possible paths are %v", paths)
+	}
+	return found, nil
+}
+
+// kargsOperationsFileData returns the file of the ISO with the operations applied to the kernel
+// arguments of its embed area
+func kargsOperationsFileData(isoPath, file string, kargs KernelArguments, policy KargsConflictPolicy) (FileData, error) {
+	return kargsOverlayFileData(isoPath, file, func(base io.ReadSeeker) (overlay.OverlayReader, error) {
+		return readerForKargsOperations(isoPath, file, base, kargs, policy)
+	})
+}
+
+// kargsOverlayFileData returns the file of the ISO with the kernel arguments written in place by
+// newReader
+func kargsOverlayFileData(isoPath, file string, newReader func(base io.ReadSeeker) (overlay.OverlayReader, error)) (FileData, error) {
+	baseISO, err := os.Open(isoPath)
+	if err != nil {
+		return FileData{}, err
+	}
+
+	iso, err := newReader(baseISO)
+	if err != nil {
+		baseISO.Close()
+		return FileData{}, err
+	}
+
+	fileData, _, err := isolateISOFile(isoPath, file, iso, 0)
+	if err != nil {
+		iso.Close()
+		return FileData{}, err
+	}
+
+	return fileData, nil
+}
@@ -0,0 +1,170 @@
+package isoeditor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
+)
+
+const (
+	// HostOverlayDir is the directory of the initramfs holding a subdirectory per host, named
+	// after the lower case MAC address of the host, e.g. 52:54:00:aa:bb:01. The first MAC
+	// address of a host keys its directory, the others are symbolic links to it.
+	HostOverlayDir = "/etc/assisted/hosts"
+	// HostnameFile holds the hostname of the host
+	HostnameFile = "hostname"
+	// HostKargsFile holds the additional kernel arguments of the host, on a single line
+	HostKargsFile = "kargs"
+	// HostNetworkDir holds the NetworkManager keyfiles of the host
+	HostNetworkDir = "network"
+
+	// files shared by several hosts are stored once, named after their digest
+	hostOverlaySharedDir = HostOverlayDir + "/.shared"
+)
+
+var hostnameRegexp = regexp.MustCompile(`(?i)^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?(\.[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?)*$`)
+
+// HostOverlay is the identity of a host booting a shared ISO
+type HostOverlay struct {
+	// MACAddresses of the host, at least one
+	MACAddresses []string
+	Hostname     string
+	// Keyfiles are the NetworkManager keyfiles setting the static IPs of the host, by file name
+	Keyfiles map[string]string
+	Kargs    string
+}
+
+type hostOverlayFile struct {
+	path    string
+	mode    os.FileMode
+	content []byte
+}
+
+func (f hostOverlayFile) digest() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%o\x00", f.mode)
+	h.Write(f.content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HostOverlayEntries returns the initramfs files of the per-host directories, to be archived
+// with cpio.Archive and used as ramdisk content. Files with the same content in several hosts,
+// typically keyfiles of hosts on the same network, are stored once in a shared directory and
+// linked from the host directories.
+func HostOverlayEntries(hosts []HostOverlay) ([]cpio.Entry, error) {
+	seenMACs := map[string]bool{}
+	hostFiles := make([][]hostOverlayFile, len(hosts))
+	var links []cpio.Entry
+	for i, host := range hosts {
+		if len(host.MACAddresses) == 0 {
+			return nil, fmt.Errorf("no MAC address for host %d", i)
+		}
+		var hostDir string
+		for _, mac := range host.MACAddresses {
+			hw, err := net.ParseMAC(mac)
+			if err != nil {
+				return nil, fmt.Errorf("invalid MAC address of host %d: %w", i, err)
+			}
+			if seenMACs[hw.String()] {
+				return nil, fmt.Errorf("MAC address %s is used by several hosts", hw.String())
+			}
+			seenMACs[hw.String()] = true
+			if hostDir == "" {
+				hostDir = path.Join(HostOverlayDir, hw.String())
+				continue
+			}
+			links = append(links, cpio.Entry{
+				Path:    path.Join(HostOverlayDir, hw.String()),
+				Mode:    os.ModeSymlink,
+				Content: []byte(path.Base(hostDir)),
+			})
+		}
+
+		files, err := host.files(hostDir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid overlay of host %d: %w", i, err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("empty overlay for host %d", i)
+		}
+		hostFiles[i] = files
+	}
+
+	count := map[string]int{}
+	for _, files := range hostFiles {
+		for _, f := range files {
+			count[f.digest()]++
+		}
+	}
+
+	var entries []cpio.Entry
+	shared := map[string]bool{}
+	for _, files := range hostFiles {
+		for _, f := range files {
+			digest := f.digest()
+			if count[digest] == 1 {
+				entries = append(entries, cpio.Entry{Path: f.path, Mode: f.mode, Content: f.content})
+				continue
+			}
+			sharedPath := path.Join(hostOverlaySharedDir, digest)
+			if !shared[digest] {
+				shared[digest] = true
+				entries = append(entries, cpio.Entry{Path: sharedPath, Mode: f.mode, Content: f.content})
+			}
+			entries = append(entries, cpio.Entry{Path: f.path, Mode: os.ModeSymlink, Content: []byte(sharedPath)})
+		}
+	}
+	return append(entries, links...), nil
+}
+
+// NewHostOverlayRamDisk returns the ramdisk content with the per-host directories
+func NewHostOverlayRamDisk(hosts []HostOverlay) ([]byte, error) {
+	entries, err := HostOverlayEntries(hosts)
+	if err != nil {
+		return nil, err
+	}
+	return cpio.Archive(entries...)
+}
+
+func (h HostOverlay) files(hostDir string) ([]hostOverlayFile, error) {
+	var files []hostOverlayFile
+	if h.Hostname != "" {
+		if !hostnameRegexp.MatchString(h.Hostname) || len(h.Hostname) > 253 {
+			return nil, fmt.Errorf("invalid hostname %q", h.Hostname)
+		}
+		files = append(files, hostOverlayFile{path.Join(hostDir, HostnameFile), 0o644, []byte(h.Hostname + "\n")})
+	}
+	if kargs := strings.TrimSpace(h.Kargs); kargs != "" {
+		if strings.ContainsAny(kargs, "\n\r") {
+			return nil, fmt.Errorf("kernel arguments must fit on a single line")
+		}
+		files = append(files, hostOverlayFile{path.Join(hostDir, HostKargsFile), 0o644, []byte(kargs + "\n")})
+	}
+
+	names := make([]string, 0, len(h.Keyfiles))
+	for name := range h.Keyfiles {
+		if name != filepath.Base(name) || name == "." || name == ".." {
+			return nil, fmt.Errorf("invalid keyfile name %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// NetworkManager ignores keyfiles readable by other users
+		files = append(files, hostOverlayFile{
+			path.Join(hostDir, HostNetworkDir, strings.TrimSuffix(name, nmconnectionSuffix)+nmconnectionSuffix),
+			0o600,
+			[]byte(h.Keyfiles[name]),
+		})
+	}
+	return files, nil
+}
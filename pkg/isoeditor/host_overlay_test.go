@@ -0,0 +1,71 @@
+package isoeditor
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
+)
+
+var _ = Describe("HostOverlayEntries", func() {
+	It("builds a directory per host and dedupes shared files", func() {
+		sharedKeyfile := "[connection]\nid=bond0\n"
+		entries, err := HostOverlayEntries([]HostOverlay{
+			{
+				MACAddresses: []string{"52:54:00:AA:BB:01", "52:54:00:aa:bb:11"},
+				Hostname:     "master-0",
+				Keyfiles:     map[string]string{"bond0": sharedKeyfile, "eth0.nmconnection": "[connection]\nid=eth0\n"},
+				Kargs:        " console=ttyS0 ",
+			},
+			{
+				MACAddresses: []string{"52:54:00:aa:bb:02"},
+				Hostname:     "master-1",
+				Keyfiles:     map[string]string{"bond0.nmconnection": sharedKeyfile},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		sharedPath := "/etc/assisted/hosts/.shared/" + hostOverlayFile{mode: 0o600, content: []byte(sharedKeyfile)}.digest()
+		Expect(entries).To(Equal([]cpio.Entry{
+			{Path: "/etc/assisted/hosts/52:54:00:aa:bb:01/hostname", Mode: 0o644, Content: []byte("master-0\n")},
+			{Path: "/etc/assisted/hosts/52:54:00:aa:bb:01/kargs", Mode: 0o644, Content: []byte("console=ttyS0\n")},
+			{Path: sharedPath, Mode: 0o600, Content: []byte(sharedKeyfile)},
+			{Path: "/etc/assisted/hosts/52:54:00:aa:bb:01/network/bond0.nmconnection", Mode: os.ModeSymlink, Content: []byte(sharedPath)},
+			{Path: "/etc/assisted/hosts/52:54:00:aa:bb:01/network/eth0.nmconnection", Mode: 0o600, Content: []byte("[connection]\nid=eth0\n")},
+			{Path: "/etc/assisted/hosts/52:54:00:aa:bb:02/hostname", Mode: 0o644, Content: []byte("master-1\n")},
+			{Path: "/etc/assisted/hosts/52:54:00:aa:bb:02/network/bond0.nmconnection", Mode: os.ModeSymlink, Content: []byte(sharedPath)},
+			{Path: "/etc/assisted/hosts/52:54:00:aa:bb:11", Mode: os.ModeSymlink, Content: []byte("52:54:00:aa:bb:01")},
+		}))
+
+		ramdisk, err := NewHostOverlayRamDisk([]HostOverlay{{MACAddresses: []string{"52:54:00:aa:bb:01"}, Hostname: "worker-0"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ramdisk).NotTo(BeEmpty())
+	})
+
+	It("rejects invalid hosts", func() {
+		for _, tc := range []struct {
+			host HostOverlay
+			err  string
+		}{
+			{HostOverlay{Hostname: "master-0"}, "no MAC address for host 0"},
+			{HostOverlay{MACAddresses: []string{"not-a-mac"}, Hostname: "master-0"}, "invalid MAC address of host 0"},
+			{HostOverlay{MACAddresses: []string{"52:54:00:aa:bb:01"}, Hostname: "master_0"}, "invalid hostname"},
+			{HostOverlay{MACAddresses: []string{"52:54:00:aa:bb:01"}, Kargs: "a\nb"}, "single line"},
+			{HostOverlay{MACAddresses: []string{"52:54:00:aa:bb:01"}, Keyfiles: map[string]string{"../eth0": ""}}, "invalid keyfile name"},
+			{HostOverlay{MACAddresses: []string{"52:54:00:aa:bb:01"}}, "empty overlay for host 0"},
+		} {
+			_, err := HostOverlayEntries([]HostOverlay{tc.host})
+			Expect(err).To(MatchError(ContainSubstring(tc.err)))
+		}
+	})
+
+	It("rejects MAC addresses used by several hosts", func() {
+		_, err := HostOverlayEntries([]HostOverlay{
+			{MACAddresses: []string{"52:54:00:aa:bb:01"}, Hostname: "master-0"},
+			{MACAddresses: []string{"52:54:00:AA:BB:01"}, Hostname: "master-1"},
+		})
+		Expect(err).To(MatchError("MAC address 52:54:00:aa:bb:01 is used by several hosts"))
+	})
+})
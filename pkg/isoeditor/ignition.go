@@ -2,16 +2,68 @@ package isoeditor
 
 import (
 	"bytes"
+	"compress/gzip"
+	"fmt"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
 )
 
 type IgnitionContent struct {
 	Config []byte
 }
 
+// ErrIgnitionTooLarge is returned when the ignition config doesn't fit in the embed area
+// of the ISO even with the best compression.
+type ErrIgnitionTooLarge struct {
+	ConfigSize     int64
+	CompressedSize int64
+	Capacity       int64
+}
+
+func (e *ErrIgnitionTooLarge) Error() string {
+	return fmt.Sprintf("ignition config of %d bytes (%d bytes compressed) exceeds embed area size (%d)",
+		e.ConfigSize, e.CompressedSize, e.Capacity)
+}
+
 func (ic *IgnitionContent) Archive() (*bytes.Reader, error) {
-	compressedCpio, err := generateCompressedCPIO(ic.Config, "config.ign", 0o100_644)
+	return ic.archiveWithLevel(gzip.DefaultCompression)
+}
+
+func (ic *IgnitionContent) archiveWithLevel(level int) (*bytes.Reader, error) {
+	compressedCpio, err := generateCompressedCPIOWithLevel(ic.Config, "config.ign", 0o100_644, level)
 	if err != nil {
 		return nil, err
 	}
 	return bytes.NewReader(compressedCpio), nil
 }
+
+// ArchiveWithCompression returns the archive of the ignition config compressed with the given
+// format, to match the compression of the initramfs it is appended to
+func (ic *IgnitionContent) ArchiveWithCompression(compression cpio.Compression) (*bytes.Reader, error) {
+	archive, err := cpio.ArchiveWithCompression(compression, cpio.Entry{Path: "config.ign", Content: ic.Config})
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(archive), nil
+}
+
+// archiveWithin returns the archive of the ignition config, retrying with the best
+// compression when the default one doesn't fit in the given capacity.
+func (ic *IgnitionContent) archiveWithin(capacity int64) (*bytes.Reader, error) {
+	archive, err := ic.Archive()
+	if err != nil || archive.Size() <= capacity {
+		return archive, err
+	}
+	archive, err = ic.archiveWithLevel(gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if archive.Size() > capacity {
+		return nil, &ErrIgnitionTooLarge{
+			ConfigSize:     int64(len(ic.Config)),
+			CompressedSize: archive.Size(),
+			Capacity:       capacity,
+		}
+	}
+	return archive, nil
+}
@@ -0,0 +1,75 @@
+package isoeditor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// Path in the live environment where the ignition config for the installed system is written
+	DestIgnitionPath = "/etc/coreos/installer.d/mnt/dest.ign"
+	// coreos-installer config file pointing the installation at the destination ignition
+	DestInstallerConfigPath = "/etc/coreos/installer.d/0000-dest-ignition.yaml"
+
+	defaultLiveIgnition = `{"ignition": {"version": "3.2.0"}}`
+)
+
+// BuildLiveIgnition returns the ignition config of the live environment, extended when the
+// destination ignition is set so that coreos-installer applies it to the installed system,
+// as done by 'coreos-installer iso customize --dest-ignition'.
+func BuildLiveIgnition(live, dest []byte) ([]byte, error) {
+	if live == nil {
+		live = []byte(defaultLiveIgnition)
+	}
+	if dest == nil {
+		if err := ValidateIgnition(live); err != nil {
+			return nil, fmt.Errorf("live %w", err)
+		}
+		return live, nil
+	}
+	if err := ValidateIgnition(dest); err != nil {
+		return nil, fmt.Errorf("dest %w", err)
+	}
+	var liveSpec ignitionSpec
+	if err := json.Unmarshal(live, &liveSpec); err != nil {
+		return nil, fmt.Errorf("live invalid ignition config: %w", err)
+	}
+
+	installerConfig := fmt.Sprintf("ignition-file: %s\n", DestIgnitionPath)
+	destFiles := map[string]interface{}{
+		"ignition": map[string]interface{}{"version": liveSpec.Ignition.Version},
+		"storage": map[string]interface{}{
+			"files": []interface{}{
+				ignitionDataFile(DestIgnitionPath, dest, 0o600),
+				ignitionDataFile(DestInstallerConfigPath, []byte(installerConfig), 0o644),
+			},
+		},
+	}
+	destFilesBytes, err := json.Marshal(destFiles)
+	if err != nil {
+		return nil, err
+	}
+	return MergeIgnition(live, destFilesBytes)
+}
+
+func ignitionDataFile(path string, content []byte, mode int) map[string]interface{} {
+	return map[string]interface{}{
+		"path":      path,
+		"mode":      mode,
+		"overwrite": true,
+		"contents": map[string]interface{}{
+			"source": "data:;base64," + base64.StdEncoding.EncodeToString(content),
+		},
+	}
+}
+
+// NewLiveDestIgnitionReader returns the files of the ISO that need to be overwritten in order
+// to configure both the live environment and the installed system.
+func NewLiveDestIgnitionReader(isoPath string, live, dest []byte) ([]FileData, error) {
+	ignition, err := BuildLiveIgnition(live, dest)
+	if err != nil {
+		return nil, err
+	}
+	return NewIgnitionReader(isoPath, ignition)
+}
@@ -0,0 +1,61 @@
+package isoeditor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BuildLiveIgnition", func() {
+	type file struct {
+		Path     string `json:"path"`
+		Contents struct {
+			Source string `json:"source"`
+		} `json:"contents"`
+	}
+	type config struct {
+		Ignition struct {
+			Version string `json:"version"`
+		} `json:"ignition"`
+		Storage struct {
+			Files []file `json:"files"`
+		} `json:"storage"`
+	}
+
+	It("returns the live ignition without dest ignition", func() {
+		live := []byte(`{"ignition": {"version": "3.1.0"}}`)
+		ignition, err := BuildLiveIgnition(live, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ignition).To(Equal(live))
+	})
+
+	It("embeds the dest ignition in the live one", func() {
+		dest := []byte(`{"ignition": {"version": "3.2.0"}, "passwd": {}}`)
+		ignition, err := BuildLiveIgnition([]byte(`{"ignition": {"version": "3.2.0"}, "storage": {"files": [{"path": "/etc/live"}]}}`), dest)
+		Expect(err).NotTo(HaveOccurred())
+
+		var c config
+		Expect(json.Unmarshal(ignition, &c)).To(Succeed())
+		Expect(c.Storage.Files).To(HaveLen(3))
+		Expect(c.Storage.Files[0].Path).To(Equal("/etc/live"))
+		Expect(c.Storage.Files[1].Path).To(Equal(DestIgnitionPath))
+		Expect(c.Storage.Files[1].Contents.Source).To(Equal("data:;base64," + base64.StdEncoding.EncodeToString(dest)))
+		Expect(c.Storage.Files[2].Path).To(Equal(DestInstallerConfigPath))
+	})
+
+	It("uses a default live ignition", func() {
+		ignition, err := BuildLiveIgnition(nil, []byte(`{"ignition": {"version": "3.2.0"}}`))
+		Expect(err).NotTo(HaveOccurred())
+		var c config
+		Expect(json.Unmarshal(ignition, &c)).To(Succeed())
+		Expect(c.Ignition.Version).To(Equal("3.2.0"))
+		Expect(c.Storage.Files).To(HaveLen(2))
+	})
+
+	It("fails with an invalid dest ignition", func() {
+		_, err := BuildLiveIgnition(nil, []byte(`{}`))
+		Expect(err).To(MatchError(ContainSubstring("dest invalid ignition config")))
+	})
+})
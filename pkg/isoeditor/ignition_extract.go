@@ -0,0 +1,64 @@
+package isoeditor
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/cavaliercoder/go-cpio"
+	"github.com/pkg/errors"
+)
+
+// ErrNoIgnition is returned by ExtractIgnition when the ISO has no embedded ignition config
+var ErrNoIgnition = errors.New("no ignition config embedded in the ISO")
+
+// ExtractIgnition returns the ignition config currently embedded in the ISO
+func ExtractIgnition(isoPath string) ([]byte, error) {
+	start, length, err := (&ignitionBoundaryFinder{}).findBoundaries(ignitionImagePath, isoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	isoReader, err := os.Open(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer isoReader.Close()
+
+	area := bufio.NewReader(io.NewSectionReader(isoReader, start, length))
+	magic, err := area.Peek(2)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read ignition embed area")
+	}
+	if bytes.Equal(magic, []byte{0, 0}) {
+		return nil, ErrNoIgnition
+	}
+
+	var archive io.Reader = area
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gzipReader, err := gzip.NewReader(area)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decompress ignition archive")
+		}
+		// the archive is followed by zero padding up to the end of the embed area
+		gzipReader.Multistream(false)
+		defer gzipReader.Close()
+		archive = gzipReader
+	}
+
+	cpioReader := cpio.NewReader(archive)
+	for {
+		header, err := cpioReader.Next()
+		if err == io.EOF {
+			return nil, ErrNoIgnition
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read ignition archive")
+		}
+		if header.Name == "config.ign" {
+			return io.ReadAll(cpioReader)
+		}
+	}
+}
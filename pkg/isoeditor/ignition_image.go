@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
 )
 
 // NewIgnitionImageReader returns the filename of the ignition image in the ISO,
@@ -11,7 +13,21 @@ import (
 // This can be used to overwrite the ignition image file of an ISO previously
 // unpacked by Extract() in order to embed ignition data.
 func NewIgnitionImageReader(isoPath string, ignitionContent *IgnitionContent) ([]FileData, error) {
-	info, iso, err := ignitionOverlay(isoPath, ignitionContent, true)
+	return NewIgnitionImageReaderWithFiles(isoPath, ignitionContent)
+}
+
+// NewIgnitionImageReaderWithFiles is like NewIgnitionImageReader, and also adds the files to the
+// live initramfs in an archive appended to the one of the ignition config.
+func NewIgnitionImageReaderWithFiles(isoPath string, ignitionContent *IgnitionContent, files ...cpio.Entry) ([]FileData, error) {
+	var extra []byte
+	if len(files) > 0 {
+		var err error
+		if extra, err = cpio.Archive(files...); err != nil {
+			return nil, err
+		}
+	}
+
+	info, iso, err := ignitionOverlay(isoPath, ignitionContent, extra, true)
 	if err != nil {
 		return nil, err
 	}
@@ -41,3 +57,19 @@ func NewIgnitionImageReader(isoPath string, ignitionContent *IgnitionContent) ([
 
 	return output, nil
 }
+
+// NewIgnitionReader validates the ignition config and returns the files of the ISO
+// that need to be overwritten in order to embed it, in the same way NewKargsReader
+// does for kernel arguments. Butane configs are translated to ignition first.
+func NewIgnitionReader(isoPath string, ignition []byte) ([]FileData, error) {
+	if IsButane(ignition) {
+		var err error
+		if ignition, err = ButaneToIgnition(ignition); err != nil {
+			return nil, err
+		}
+	}
+	if err := ValidateIgnition(ignition); err != nil {
+		return nil, err
+	}
+	return NewIgnitionImageReader(isoPath, &IgnitionContent{Config: ignition})
+}
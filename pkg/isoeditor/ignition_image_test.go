@@ -4,6 +4,8 @@ import (
 	"io"
 	"os"
 
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -48,4 +50,37 @@ var _ = Describe("IgnitionContent.Archive", func() {
 		}
 		Expect(outputs[0].Filename).To(Equal("images/ignition.img"))
 	})
+
+	It("appends the files archive to the ignition image", func() {
+		content := IgnitionContent{ignitionContent}
+		files := []cpio.Entry{{Path: "etc/motd", Content: []byte("hello")}}
+
+		outputs, err := NewIgnitionImageReaderWithFiles(isoFile, &content, files...)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(len(outputs)).To(Equal(1))
+		imgBytes, err := io.ReadAll(outputs[0].Data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outputs[0].Data.Close()).To(Succeed())
+
+		ignitionArchive, err := content.Archive()
+		Expect(err).NotTo(HaveOccurred())
+		filesArchive, err := cpio.Archive(files...)
+		Expect(err).NotTo(HaveOccurred())
+		archiveLength := int(ignitionArchive.Size())
+		Expect(imgBytes[archiveLength : archiveLength+len(filesArchive)]).To(Equal(filesArchive))
+		Expect(len(imgBytes)).To(Equal(256 * 1024))
+	})
+
+	It("embeds a valid ignition config", func() {
+		outputs, err := NewIgnitionReader(isoFile, []byte(`{"ignition": {"version": "3.2.0"}}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(len(outputs)).To(Equal(1))
+		Expect(outputs[0].Filename).To(Equal("images/ignition.img"))
+		Expect(outputs[0].Data.Close()).To(Succeed())
+	})
+
+	It("rejects an invalid ignition config", func() {
+		_, err := NewIgnitionReader(isoFile, []byte(`{"ignition": {"version": "2.2.0"}}`))
+		Expect(err).To(HaveOccurred())
+	})
 })
@@ -0,0 +1,134 @@
+package isoeditor
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Fields identifying the entries of ignition lists, e.g. the path of a file or the name of a
+// systemd unit. Entries of the override config with the same key as an entry of the base config
+// are merged into it, the others are appended.
+var ignitionListKeys = []string{"path", "name", "device", "label", "number", "id", "source"}
+
+// MergeIgnition merges the override ignition config into the base one following the ignition
+// merge semantics: objects are merged recursively, list entries are merged by their identifying
+// field and any other value of the override config replaces the base one.
+func MergeIgnition(base, override []byte) ([]byte, error) {
+	if err := ValidateIgnition(base); err != nil {
+		return nil, fmt.Errorf("base %w", err)
+	}
+	if err := ValidateIgnition(override); err != nil {
+		return nil, fmt.Errorf("override %w", err)
+	}
+
+	var baseConfig, overrideConfig interface{}
+	if err := json.Unmarshal(base, &baseConfig); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(override, &overrideConfig); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeIgnitionValue(baseConfig, overrideConfig))
+}
+
+func mergeIgnitionValue(base, override interface{}) interface{} {
+	switch overrideValue := override.(type) {
+	case map[string]interface{}:
+		baseValue, ok := base.(map[string]interface{})
+		if !ok {
+			return override
+		}
+		merged := make(map[string]interface{}, len(baseValue))
+		for k, v := range baseValue {
+			merged[k] = v
+		}
+		for k, v := range overrideValue {
+			merged[k] = mergeIgnitionValue(baseValue[k], v)
+		}
+		return merged
+	case []interface{}:
+		baseValue, ok := base.([]interface{})
+		if !ok {
+			return override
+		}
+		return mergeIgnitionList(baseValue, overrideValue)
+	default:
+		return override
+	}
+}
+
+func mergeIgnitionList(base, override []interface{}) []interface{} {
+	merged := append([]interface{}{}, base...)
+	for _, entry := range override {
+		if i := indexOfIgnitionEntry(merged, entry); i >= 0 {
+			merged[i] = mergeIgnitionValue(merged[i], entry)
+		} else {
+			merged = append(merged, entry)
+		}
+	}
+	return merged
+}
+
+// indexOfIgnitionEntry returns the index of the entry of the list identified in the same way as
+// the given one, or -1. Plain values such as ssh keys are identified by their value.
+func indexOfIgnitionEntry(list []interface{}, entry interface{}) int {
+	object, isObject := entry.(map[string]interface{})
+	for i, candidate := range list {
+		if !isObject {
+			if candidate == entry {
+				return i
+			}
+			continue
+		}
+		candidateObject, ok := candidate.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range ignitionListKeys {
+			value, ok := object[key]
+			if !ok {
+				continue
+			}
+			if candidateValue, ok := candidateObject[key]; ok && candidateValue == value {
+				return i
+			}
+			break
+		}
+	}
+	return -1
+}
+
+// NewMergedIgnitionReader merges the override ignition config into the base one and returns
+// the files of the ISO that need to be overwritten in order to embed the result.
+func NewMergedIgnitionReader(isoPath string, base, override []byte) ([]FileData, error) {
+	merged, err := MergeIgnition(base, override)
+	if err != nil {
+		return nil, err
+	}
+	return NewIgnitionReader(isoPath, merged)
+}
+
+// mergeIgnitionOverride merges the override config, built by the caller, into the ignition config
+// or an empty one when nil, using the spec version of the ignition config for the override
+func mergeIgnitionOverride(ignition []byte, override map[string]interface{}) ([]byte, error) {
+	if ignition == nil {
+		ignition = []byte(defaultLiveIgnition)
+	}
+	var spec ignitionSpec
+	if err := json.Unmarshal(ignition, &spec); err != nil {
+		return nil, fmt.Errorf("invalid ignition config: %w", err)
+	}
+
+	ignitionSection, _ := override["ignition"].(map[string]interface{})
+	if ignitionSection == nil {
+		ignitionSection = map[string]interface{}{}
+		override["ignition"] = ignitionSection
+	}
+	ignitionSection["version"] = spec.Ignition.Version
+
+	overrideBytes, err := json.Marshal(override)
+	if err != nil {
+		return nil, err
+	}
+	return MergeIgnition(ignition, overrideBytes)
+}
@@ -0,0 +1,41 @@
+package isoeditor
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MergeIgnition", func() {
+	It("merges objects and overrides values", func() {
+		merged, err := MergeIgnition(
+			[]byte(`{"ignition": {"version": "3.1.0", "timeouts": {"httpTotal": 10}}}`),
+			[]byte(`{"ignition": {"version": "3.2.0"}, "systemd": {}}`),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(merged).To(MatchJSON(`{"ignition": {"version": "3.2.0", "timeouts": {"httpTotal": 10}}, "systemd": {}}`))
+	})
+
+	It("merges list entries by their identifying field", func() {
+		merged, err := MergeIgnition(
+			[]byte(`{"ignition": {"version": "3.2.0"}, "storage": {"files": [{"path": "/etc/a", "mode": 420}, {"path": "/etc/b"}]}}`),
+			[]byte(`{"ignition": {"version": "3.2.0"}, "storage": {"files": [{"path": "/etc/a", "mode": 384}, {"path": "/etc/c"}]}}`),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(merged).To(MatchJSON(`{"ignition": {"version": "3.2.0"}, "storage": {"files": [
+			{"path": "/etc/a", "mode": 384}, {"path": "/etc/b"}, {"path": "/etc/c"}]}}`))
+	})
+
+	It("appends plain list values only once", func() {
+		merged, err := MergeIgnition(
+			[]byte(`{"ignition": {"version": "3.2.0"}, "passwd": {"users": [{"name": "core", "sshAuthorizedKeys": ["key1"]}]}}`),
+			[]byte(`{"ignition": {"version": "3.2.0"}, "passwd": {"users": [{"name": "core", "sshAuthorizedKeys": ["key1", "key2"]}]}}`),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(merged).To(MatchJSON(`{"ignition": {"version": "3.2.0"}, "passwd": {"users": [{"name": "core", "sshAuthorizedKeys": ["key1", "key2"]}]}}`))
+	})
+
+	It("fails with an invalid override", func() {
+		_, err := MergeIgnition([]byte(`{"ignition": {"version": "3.2.0"}}`), []byte(`{"ignition": {}}`))
+		Expect(err).To(MatchError(ContainSubstring("override invalid ignition config")))
+	})
+})
@@ -1,7 +1,12 @@
 package isoeditor
 
 import (
+	"compress/gzip"
+	"errors"
+	"fmt"
 	"io"
+	"os"
+	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -32,3 +37,82 @@ var _ = Describe("IgnitionContent.Archive", func() {
 		Expect(len(ignitionBytes) % 4).To(Equal(0))
 	})
 })
+
+var _ = Describe("IgnitionContent.archiveWithin", func() {
+	var content IgnitionContent
+
+	BeforeEach(func() {
+		var config strings.Builder
+		for i := 0; i < 2000; i++ {
+			fmt.Fprintf(&config, `{"path": "/etc/file%d", "contents": {"source": "data:,%x"}}`, i, i*i*7919)
+		}
+		content = IgnitionContent{[]byte(config.String())}
+	})
+
+	It("uses the default compression when the archive fits", func() {
+		archive, err := content.archiveWithin(1 << 20)
+		Expect(err).NotTo(HaveOccurred())
+		defaultArchive, err := content.Archive()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(archive.Size()).To(Equal(defaultArchive.Size()))
+	})
+
+	It("falls back to the best compression", func() {
+		defaultArchive, err := content.Archive()
+		Expect(err).NotTo(HaveOccurred())
+		bestArchive, err := content.archiveWithLevel(gzip.BestCompression)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bestArchive.Size()).To(BeNumerically("<", defaultArchive.Size()))
+
+		archive, err := content.archiveWithin(bestArchive.Size())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(archive.Size()).To(Equal(bestArchive.Size()))
+	})
+
+	It("reports both sizes when the compressed archive does not fit", func() {
+		_, err := content.archiveWithin(16)
+		var tooLarge *ErrIgnitionTooLarge
+		Expect(errors.As(err, &tooLarge)).To(BeTrue())
+		Expect(tooLarge.ConfigSize).To(Equal(int64(len(content.Config))))
+		Expect(tooLarge.CompressedSize).To(BeNumerically(">", 16))
+		Expect(tooLarge.Capacity).To(Equal(int64(16)))
+	})
+})
+
+var _ = Describe("ExtractIgnition", func() {
+	var (
+		isoFile  string
+		filesDir string
+	)
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+	})
+
+	It("returns the embedded ignition", func() {
+		ignition := []byte(`{"ignition": {"version": "3.2.0"}}`)
+		streamReader, err := NewRHCOSStreamReader(isoFile, &IgnitionContent{ignition}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer streamReader.Close()
+
+		f, err := os.CreateTemp(filesDir, "streamed*.iso")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.Copy(f, streamReader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		extracted, err := ExtractIgnition(f.Name())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(extracted).To(Equal(ignition))
+	})
+
+	It("fails when no ignition is embedded", func() {
+		_, err := ExtractIgnition(isoFile)
+		Expect(err).To(Equal(ErrNoIgnition))
+	})
+})
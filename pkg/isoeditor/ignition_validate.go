@@ -0,0 +1,51 @@
+package isoeditor
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/thoas/go-funk"
+)
+
+// Ignition spec versions that the live ISO is able to consume
+var supportedIgnitionSpecVersions = regexp.MustCompile(`^3\.[0-4]\.0(-experimental)?$`)
+
+// Top level sections defined by the ignition spec
+var ignitionSections = []string{"ignition", "kernelArguments", "passwd", "storage", "systemd"}
+
+type ignitionSpec struct {
+	Ignition struct {
+		Version string `json:"version"`
+	} `json:"ignition"`
+}
+
+// ValidateIgnition checks that the ignition config is a JSON object using a supported
+// spec version and only containing sections defined by the spec.
+func ValidateIgnition(ignition []byte) error {
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(ignition, &sections); err != nil {
+		return fmt.Errorf("invalid ignition config: %w", err)
+	}
+	for name, section := range sections {
+		if !funk.ContainsString(ignitionSections, name) {
+			return fmt.Errorf("invalid ignition config: unknown section %q", name)
+		}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(section, &object); err != nil {
+			return fmt.Errorf("invalid ignition config: section %q is not an object", name)
+		}
+	}
+
+	var spec ignitionSpec
+	if err := json.Unmarshal(ignition, &spec); err != nil {
+		return fmt.Errorf("invalid ignition config: %w", err)
+	}
+	if spec.Ignition.Version == "" {
+		return fmt.Errorf("invalid ignition config: missing ignition.version")
+	}
+	if !supportedIgnitionSpecVersions.MatchString(spec.Ignition.Version) {
+		return fmt.Errorf("invalid ignition config: unsupported spec version %s", spec.Ignition.Version)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package isoeditor
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateIgnition", func() {
+	It("accepts a supported spec version", func() {
+		Expect(ValidateIgnition([]byte(`{"ignition": {"version": "3.2.0"}, "storage": {}}`))).To(Succeed())
+	})
+
+	It("fails with invalid JSON", func() {
+		Expect(ValidateIgnition([]byte(`{"ignition":`))).ToNot(Succeed())
+	})
+
+	It("fails without a spec version", func() {
+		Expect(ValidateIgnition([]byte(`{"ignition": {}}`))).To(MatchError(ContainSubstring("missing ignition.version")))
+	})
+
+	It("fails with an unsupported spec version", func() {
+		Expect(ValidateIgnition([]byte(`{"ignition": {"version": "2.2.0"}}`))).To(MatchError(ContainSubstring("unsupported spec version 2.2.0")))
+	})
+
+	It("fails with an unknown section", func() {
+		Expect(ValidateIgnition([]byte(`{"ignition": {"version": "3.2.0"}, "foo": {}}`))).To(MatchError(ContainSubstring(`unknown section "foo"`)))
+	})
+
+	It("fails when a section is not an object", func() {
+		Expect(ValidateIgnition([]byte(`{"ignition": {"version": "3.2.0"}, "storage": []}`))).To(MatchError(ContainSubstring(`section "storage" is not an object`)))
+	})
+})
@@ -1,10 +1,12 @@
 package isoeditor
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
 
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
 	"github.com/openshift/assisted-image-service/pkg/overlay"
 )
 
@@ -26,8 +28,48 @@ func NewInitRamFSStreamReaderFromISO(isoPath string, ignitionContent *IgnitionCo
 	return newInitRamFSStreamReaderFromStream(irfsReader, ignitionContent)
 }
 
+// NewInitRamFSStreamReaderFromISOWithCompression is like NewInitRamFSStreamReaderFromISO with the
+// ignition archive compressed with the given format. When compression is empty the format of the
+// initrd of the ISO is used, falling back to gzip when it can't be detected.
+func NewInitRamFSStreamReaderFromISOWithCompression(isoPath string, ignitionContent *IgnitionContent, compression cpio.Compression) (overlay.OverlayReader, error) {
+	irfsReader, err := GetFileFromISO(isoPath, initrdPathInISO)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open base initrd from ISO: %w", err)
+	}
+	if compression == "" {
+		if compression, err = detectInitrdCompression(irfsReader); err != nil {
+			irfsReader.Close()
+			return nil, err
+		}
+	}
+	return newInitRamFSStreamReaderWithCompression(irfsReader, ignitionContent, compression)
+}
+
+func detectInitrdCompression(irfsReader io.ReadSeeker) (cpio.Compression, error) {
+	compression, err := cpio.DetectCompression(irfsReader)
+	if err != nil || compression == cpio.CompressionNone {
+		log.Debugf("Using gzip for the initrd archives, detected compression %q: %v", compression, err)
+		compression = cpio.CompressionGzip
+	}
+	if _, err := irfsReader.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return compression, nil
+}
+
 func newInitRamFSStreamReaderFromStream(irfsReader io.ReadSeekCloser, ignitionContent *IgnitionContent) (overlay.OverlayReader, error) {
-	ignitionReader, err := ignitionContent.Archive()
+	return newInitRamFSStreamReaderWithCompression(irfsReader, ignitionContent, cpio.CompressionGzip)
+}
+
+func newInitRamFSStreamReaderWithCompression(irfsReader io.ReadSeekCloser, ignitionContent *IgnitionContent, compression cpio.Compression) (overlay.OverlayReader, error) {
+	var ignitionReader *bytes.Reader
+	var err error
+	// keep the exact gzip output of earlier versions
+	if compression == cpio.CompressionGzip {
+		ignitionReader, err = ignitionContent.Archive()
+	} else {
+		ignitionReader, err = ignitionContent.ArchiveWithCompression(compression)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ignition archive: %w", err)
 	}
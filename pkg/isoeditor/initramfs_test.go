@@ -1,13 +1,17 @@
 package isoeditor
 
 import (
+	"bytes"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
 )
 
 var _ = Describe("NewInitRamFSStreamReader", func() {
@@ -46,3 +50,48 @@ var _ = Describe("NewInitRamFSStreamReader", func() {
 		Expect(output.String()).To(Equal(expected.String()))
 	})
 })
+
+var _ = Describe("NewInitRamFSStreamReaderFromISOWithCompression", func() {
+	var (
+		filesDir string
+		isoFile  string
+		initrd   []byte
+	)
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+		early, err := cpio.ArchiveWithCompression(cpio.CompressionNone, cpio.Entry{Path: "kernel/x86/microcode/GenuineIntel.bin"})
+		Expect(err).NotTo(HaveOccurred())
+		main, err := cpio.ArchiveWithCompression(cpio.CompressionZstd, cpio.Entry{Path: "init"})
+		Expect(err).NotTo(HaveOccurred())
+		initrd = append(early, main...)
+		Expect(os.WriteFile(filepath.Join(filesDir, initrdPathInISO), initrd, 0600)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+		cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", "Assisted123", "-o", isoFile, filesDir)
+		Expect(cmd.Run()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+	})
+
+	It("uses the compression of the initrd", func() {
+		r, err := NewInitRamFSStreamReaderFromISOWithCompression(isoFile, &IgnitionContent{[]byte("{}")}, "")
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+		content, err := io.ReadAll(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content[:len(initrd)]).To(Equal(initrd))
+		Expect(cpio.DetectCompression(bytes.NewReader(content[len(initrd):]))).To(Equal(cpio.CompressionZstd))
+	})
+
+	It("uses the requested compression", func() {
+		r, err := NewInitRamFSStreamReaderFromISOWithCompression(isoFile, &IgnitionContent{[]byte("{}")}, cpio.CompressionXZ)
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+		content, err := io.ReadAll(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cpio.DetectCompression(bytes.NewReader(content[len(initrd):]))).To(Equal(cpio.CompressionXZ))
+	})
+})
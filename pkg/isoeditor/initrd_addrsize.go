@@ -48,3 +48,25 @@ func NewInitrdAddrsizeReaderFromStream(irfsReader io.ReadSeekCloser, initrdFile
 
 	return bytes.NewReader(append(initrdPSW, addrsizeBytes.Bytes()...)), nil
 }
+
+// initrdAddrsizeFileData returns the initrd.addrsize of an s390x ISO regenerated for the initrd it
+// carries, as the boot media load them with the regenerated parameter files. ISOs of the other
+// architectures have none.
+func initrdAddrsizeFileData(isoPath string) ([]FileData, error) {
+	if _, _, err := GetISOFileInfo("/"+initrdAddrsizePathInISO, isoPath); err != nil {
+		return nil, nil
+	}
+	_, initrdSize, err := GetISOFileInfo("/"+initrdPathInISO, isoPath)
+	if err != nil {
+		return nil, nil
+	}
+	addrsize, err := ReadFileFromISO(isoPath, initrdAddrsizePathInISO)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read initrd.addrsize: %w", err)
+	}
+	if len(addrsize) < 16 {
+		return nil, fmt.Errorf("initrd.addrsize is %d bytes long, expected 16", len(addrsize))
+	}
+	binary.BigEndian.PutUint64(addrsize[8:16], uint64(initrdSize))
+	return []FileData{{Filename: initrdAddrsizePathInISO, Data: io.NopCloser(bytes.NewReader(addrsize))}}, nil
+}
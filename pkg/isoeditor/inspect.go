@@ -0,0 +1,195 @@
+package isoeditor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ISOInfo is the metadata of an ISO returned by Inspect
+type ISOInfo struct {
+	VolumeID     string     `json:"volume_id"`
+	CreationDate *time.Time `json:"creation_date,omitempty"`
+	Size         int64      `json:"size"`
+	// Architecture guessed from the boot files of the ISO
+	Architecture string `json:"architecture"`
+	// PartitionTables of hybrid ISOs: MBR, GPT and APM
+	PartitionTables []string       `json:"partition_tables,omitempty"`
+	BootEntries     []ISOBootEntry `json:"boot_entries,omitempty"`
+	// embed areas of the customizations, missing ones are omitted
+	IgnitionArea *ISOEmbedArea    `json:"ignition_area,omitempty"`
+	RamdiskArea  *ISOEmbedArea    `json:"ramdisk_area,omitempty"`
+	KargsAreas   []ISOKargsArea   `json:"kargs_areas,omitempty"`
+	KargsConfig  *ISOKargsConfig  `json:"kargs_config,omitempty"`
+	ImplantedMD5 *ISOMD5          `json:"implanted_md5,omitempty"`
+	Capabilities *ISOCapabilities `json:"capabilities"`
+	// S390xSignedFiles carry the components signed for secure IPL, their kernel arguments can't be edited
+	S390xSignedFiles []string `json:"s390x_signed_files,omitempty"`
+}
+
+// ISOBootEntry is a boot image of the El Torito boot catalog
+type ISOBootEntry struct {
+	Platform    string `json:"platform"`
+	Path        string `json:"path,omitempty"`
+	LBA         uint32 `json:"lba"`
+	SectorCount uint16 `json:"sector_count"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+// ISOEmbedArea is a region of a file of the ISO where customizations are embedded
+type ISOEmbedArea struct {
+	File string `json:"file"`
+	// Offset of the area in the ISO
+	Offset int64 `json:"offset"`
+	Length int64 `json:"length"`
+}
+
+// ISOKargsArea is the embed area of the kernel arguments of a boot config file
+type ISOKargsArea struct {
+	ISOEmbedArea
+	Kargs []string `json:"kargs"`
+}
+
+// ISOKargsConfig is the content of coreos/kargs.json
+type ISOKargsConfig struct {
+	Default string `json:"default"`
+	Files   []struct {
+		Path   string `json:"path"`
+		Offset int64  `json:"offset"`
+		End    string `json:"end,omitempty"`
+		Pad    string `json:"pad,omitempty"`
+	} `json:"files"`
+	Size int64 `json:"size"`
+}
+
+// Inspect returns the metadata of an ISO: its volume, boot entries and customization embed areas
+func Inspect(isoPath string) (*ISOInfo, error) {
+	img, err := openISOImage(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer img.Close()
+
+	pvd := img.volumes[0].descriptor.data
+	info := &ISOInfo{
+		// genisoimage pads the identifier with spaces, go-diskfs with NULs
+		VolumeID:     strings.TrimRight(string(pvd[40:72]), " \x00"),
+		Size:         img.size,
+		CreationDate: parseISODate(pvd[813:830]),
+	}
+	if info.Architecture, err = DetectArchitecture(isoPath); err != nil {
+		return nil, err
+	}
+
+	area := make([]byte, isoSystemAreaSize)
+	if _, err := img.file.ReadAt(area, 0); err != nil {
+		return nil, err
+	}
+	for _, table := range []struct {
+		name    string
+		present bool
+	}{{"MBR", hasMBR(area)}, {"GPT", hasGPT(area)}, {"APM", hasAPM(area)}} {
+		if table.present {
+			info.PartitionTables = append(info.PartitionTables, table.name)
+		}
+	}
+
+	catalog, err := ReadBootCatalog(isoPath)
+	if err != nil && !errors.Is(err, ErrNoBootCatalog) {
+		return nil, err
+	}
+	if catalog != nil {
+		for _, image := range catalog.Images {
+			if image.Bootable {
+				info.BootEntries = append(info.BootEntries, ISOBootEntry{
+					Platform:    image.Platform.String(),
+					Path:        image.Path,
+					LBA:         image.LBA,
+					SectorCount: image.SectorCount,
+					Size:        image.Size,
+				})
+			}
+		}
+	}
+
+	ibf := &ignitionBoundaryFinder{}
+	if offset, length, err := ibf.findBoundaries(ignitionImagePath, isoPath); err == nil {
+		info.IgnitionArea = &ISOEmbedArea{File: path.Join("/", ibf.info.File), Offset: offset, Length: length}
+	}
+	if offset, length, err := GetISOFileInfo(ramDiskImagePath, isoPath); err == nil {
+		info.RamdiskArea = &ISOEmbedArea{File: ramDiskImagePath, Offset: offset, Length: length}
+	}
+
+	if data, err := ReadFileFromISO(isoPath, kargsConfigFilePath); err == nil {
+		info.KargsConfig = &ISOKargsConfig{}
+		if err := json.Unmarshal(data, info.KargsConfig); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %w", kargsConfigFilePath, err)
+		}
+	}
+	files, err := KargsFiles(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		area, err := findKargsEmbedArea(isoPath, file)
+		if err != nil {
+			continue
+		}
+		info.KargsAreas = append(info.KargsAreas, ISOKargsArea{
+			ISOEmbedArea: ISOEmbedArea{File: file, Offset: area.offset, Length: area.length},
+			Kargs:        area.kargs,
+		})
+	}
+
+	if info.Capabilities, err = Capabilities(isoPath); err != nil {
+		return nil, err
+	}
+	if info.Architecture == S390XCPUArchitecture {
+		if info.S390xSignedFiles, err = S390xSignedFiles(isoPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if info.ImplantedMD5, err = ReadISOMD5(img.file); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// InspectJSON returns the metadata of an ISO as JSON
+func InspectJSON(isoPath string) ([]byte, error) {
+	info, err := Inspect(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(info, "", "  ")
+}
+
+// parseISODate parses the dec-datetime fields of volume descriptors, in local time with an
+// offset from GMT in 15 minutes intervals. Unset dates are all zeros.
+func parseISODate(b []byte) *time.Time {
+	// year, month, day, hour, minute, second and hundredths of second
+	var fields [7]int
+	for i, start := 0, 0; i < len(fields); i++ {
+		width := 2
+		if i == 0 {
+			width = 4
+		}
+		v, err := strconv.Atoi(string(b[start : start+width]))
+		if err != nil {
+			return nil
+		}
+		fields[i] = v
+		start += width
+	}
+	if fields[0] == 0 {
+		return nil
+	}
+	zone := time.FixedZone("", int(int8(b[16]))*15*60)
+	t := time.Date(fields[0], time.Month(fields[1]), fields[2], fields[3], fields[4], fields[5], fields[6]*10*int(time.Millisecond), zone)
+	return &t
+}
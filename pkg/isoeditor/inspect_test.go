@@ -0,0 +1,92 @@
+package isoeditor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Inspect", func() {
+	var (
+		filesDir string
+		isoFile  string
+		bootISO  string
+	)
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+		bootISO = filepath.Join(filesDir, "..", filepath.Base(filesDir)+"-inspect.iso")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+		Expect(os.Remove(bootISO)).To(Succeed())
+	})
+
+	It("returns the metadata of the ISO", func() {
+		// Create removes its work directory
+		Expect(Create(bootISO, filesDir, "Assisted123")).To(Succeed())
+		info, err := Inspect(bootISO)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.VolumeID).To(Equal("Assisted123"))
+		Expect(info.Architecture).To(Equal(X86CPUArchitecture))
+		Expect(info.CreationDate).NotTo(BeNil())
+		Expect(info.PartitionTables).To(BeEmpty())
+		Expect(info.ImplantedMD5).To(BeNil())
+
+		Expect(info.BootEntries).To(HaveLen(2))
+		Expect(info.BootEntries[0].Platform).To(Equal("BIOS"))
+		Expect(info.BootEntries[0].Path).To(Equal("/isolinux/isolinux.bin"))
+		Expect(info.BootEntries[1].Platform).To(Equal("UEFI"))
+		Expect(info.BootEntries[1].Size).To(Equal(int64(8184422)))
+
+		offset, _, err := GetISOFileInfo(ignitionImagePath, bootISO)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.IgnitionArea).To(Equal(&ISOEmbedArea{File: ignitionImagePath, Offset: offset, Length: ignitionPaddingLength}))
+		Expect(info.RamdiskArea.Length).To(Equal(int64(RamDiskPaddingLength)))
+
+		Expect(info.KargsConfig).To(BeNil())
+		Expect(info.KargsAreas).To(HaveLen(2))
+		Expect(info.KargsAreas[0].File).To(Equal(defaultGrubFilePath))
+		Expect(info.KargsAreas[1].File).To(Equal(defaultIsolinuxFilePath))
+		Expect(info.KargsAreas[0].Length).To(BeNumerically(">", 0))
+
+		data, err := InspectJSON(bootISO)
+		Expect(err).NotTo(HaveOccurred())
+		var decoded map[string]interface{}
+		Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+		Expect(decoded).To(HaveKeyWithValue("volume_id", "Assisted123"))
+		Expect(decoded).To(HaveKey("boot_entries"))
+		Expect(decoded).NotTo(HaveKey("implanted_md5"))
+	})
+
+	It("reports kargs.json, partition tables and implanted checksums", func() {
+		kargsConfig := `{"default": "coreos.liveiso=test", "files": [{"path": "EFI/redhat/grub.cfg", "offset": 10}], "size": 20}`
+		Expect(os.WriteFile(filepath.Join(filesDir, "coreos/kargs.json"), []byte(kargsConfig), 0600)).To(Succeed())
+		Expect(Create(bootISO, filesDir, "Assisted123")).To(Succeed())
+		makeHybrid(bootISO)
+
+		info, err := Inspect(bootISO)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.PartitionTables).To(Equal([]string{"MBR", "GPT", "APM"}))
+		Expect(info.KargsConfig).NotTo(BeNil())
+		Expect(info.KargsConfig.Default).To(Equal("coreos.liveiso=test"))
+		Expect(info.KargsConfig.Files).To(HaveLen(1))
+		Expect(info.KargsConfig.Size).To(Equal(int64(20)))
+		Expect(info.KargsAreas).To(HaveLen(1))
+		Expect(info.KargsAreas[0].Length).To(Equal(int64(20)))
+	})
+})
+
+var _ = Describe("parseISODate", func() {
+	It("parses the dates of volume descriptors", func() {
+		date := parseISODate(append([]byte("2024031512304550"), 8))
+		Expect(date).NotTo(BeNil())
+		Expect(date.UTC().Format("2006-01-02T15:04:05.00Z")).To(Equal("2024-03-15T10:30:45.50Z"))
+		Expect(parseISODate(append([]byte("0000000000000000"), 0))).To(BeNil())
+	})
+})
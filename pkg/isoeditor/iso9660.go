@@ -0,0 +1,615 @@
+package isoeditor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	isoVolumeDescriptorBoot          = 0
+	isoVolumeDescriptorPrimary       = 1
+	isoVolumeDescriptorSupplementary = 2
+	isoVolumeDescriptorTerminator    = 255
+
+	isoRecordFlagDirectory   = 0x02
+	isoRecordFlagMultiExtent = 0x80
+	// limit of nested directories and SUSP continuation areas followed, against loops
+	isoMaxDepth = 64
+	// limit of symbolic links followed when resolving a path, as in Linux
+	isoMaxSymlinks = 40
+)
+
+// isoImage is the metadata of an ISO read with the native ISO9660 parser. Unlike go-diskfs it
+// keeps the raw records, so they can be patched by ISOWriter.
+type isoImage struct {
+	file isoSource
+	// size of the file, and of the volume in sectors
+	size        int64
+	volumeSize  int64
+	descriptors []*isoVolumeDescriptor
+	// the primary volume first, then the Joliet one if any
+	volumes []*isoVolume
+}
+
+type isoVolumeDescriptor struct {
+	lba  int64
+	data []byte
+}
+
+type isoVolume struct {
+	descriptor *isoVolumeDescriptor
+	joliet     bool
+	rockRidge  bool
+	// bytes to skip at the start of the system use areas, from the SUSP SP entry
+	suspSkip int
+	root     *isoDirectory
+}
+
+type isoDirectory struct {
+	volume *isoVolume
+	parent *isoDirectory
+	lba    int64
+	length int64
+	// the records of the directory, starting with . and ..
+	records []*isoRecord
+}
+
+type isoRecord struct {
+	raw []byte
+	// name of the record, from Rock Ridge or Joliet if available
+	name string
+	// target of Rock Ridge symbolic links
+	symlink string
+	// subdirectory the record points to
+	dir *isoDirectory
+	// records of the following extents of multi-extent files, listed by the first record
+	parts []*isoRecord
+	// part is true for the records of the following extents, skipped by lookups
+	part bool
+}
+
+// isoSection is a range of the ISO holding the content of a file
+type isoSection struct {
+	offset int64
+	length int64
+}
+
+func (r *isoRecord) extent() int64 {
+	return int64(binary.LittleEndian.Uint32(r.raw[2:]))
+}
+
+func (r *isoRecord) dataLength() int64 {
+	return int64(binary.LittleEndian.Uint32(r.raw[10:]))
+}
+
+// length returns the length of the file, including all the extents of multi-extent files
+func (r *isoRecord) length() int64 {
+	length := r.dataLength()
+	for _, part := range r.parts {
+		length += part.dataLength()
+	}
+	return length
+}
+
+// sections returns the ranges of the ISO holding the content of the file, merging contiguous
+// extents. Multi-extent files larger than 4GiB usually have a single section.
+func (r *isoRecord) sections() []isoSection {
+	var sections []isoSection
+	for _, record := range append([]*isoRecord{r}, r.parts...) {
+		offset, length := record.extent()*isoSectorSize, record.dataLength()
+		if length == 0 {
+			continue
+		}
+		if last := len(sections) - 1; last >= 0 && sections[last].offset+sections[last].length == offset {
+			sections[last].length += length
+			continue
+		}
+		sections = append(sections, isoSection{offset: offset, length: length})
+	}
+	return sections
+}
+
+// reader returns the content of the file in the ISO read from iso
+func (r *isoRecord) reader(iso io.ReaderAt) *io.SectionReader {
+	sections := r.sections()
+	if len(sections) <= 1 {
+		return io.NewSectionReader(iso, r.extent()*isoSectorSize, r.length())
+	}
+	return io.NewSectionReader(&sectionsReaderAt{ReaderAt: iso, sections: sections}, 0, r.length())
+}
+
+func (r *isoRecord) setExtent(lba, length int64) {
+	putBothEndian32(r.raw[2:], uint32(lba))
+	putBothEndian32(r.raw[10:], uint32(length))
+}
+
+func (r *isoRecord) multiExtent() bool {
+	return r.raw[25]&isoRecordFlagMultiExtent != 0
+}
+
+func (r *isoRecord) isDir() bool {
+	return r.raw[25]&isoRecordFlagDirectory != 0
+}
+
+func (r *isoRecord) identifier() []byte {
+	return r.raw[33 : 33+int(r.raw[32])]
+}
+
+// isDot returns true for the . and .. records
+func (r *isoRecord) isDot() bool {
+	id := r.identifier()
+	return len(id) == 1 && id[0] <= 1
+}
+
+// systemUse returns the system use area of the record
+func (r *isoRecord) systemUse() []byte {
+	start := 33 + int(r.raw[32])
+	start += 1 - int(r.raw[32])%2
+	if start > len(r.raw) {
+		return nil
+	}
+	return r.raw[start:]
+}
+
+func putBothEndian32(b []byte, v uint32) {
+	binary.LittleEndian.PutUint32(b, v)
+	binary.BigEndian.PutUint32(b[4:], v)
+}
+
+func putBothEndian16(b []byte, v uint16) {
+	binary.LittleEndian.PutUint16(b, v)
+	binary.BigEndian.PutUint16(b[2:], v)
+}
+
+func sectorsFor(length int64) int64 {
+	return (length + isoSectorSize - 1) / isoSectorSize
+}
+
+// openISOImage parses the volume descriptors and the directory trees of the ISO
+// isoSource is the content of the ISO, a local file or e.g. a remote object read with ranged GETs
+type isoSource interface {
+	io.ReaderAt
+	io.Closer
+}
+
+func openISOImage(isoPath string) (*isoImage, error) {
+	f, err := os.Open(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	img := &isoImage{file: f, size: info.Size()}
+	if err := img.parse(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to parse ISO %s: %w", isoPath, err)
+	}
+	return img, nil
+}
+
+// openISOReaderAt parses the ISO of size bytes read from r, which isn't closed with the image
+func openISOReaderAt(r io.ReaderAt, size int64) (*isoImage, error) {
+	img := &isoImage{file: nopCloserReaderAt{r}, size: size}
+	if err := img.parse(); err != nil {
+		return nil, fmt.Errorf("failed to parse ISO: %w", err)
+	}
+	return img, nil
+}
+
+type nopCloserReaderAt struct {
+	io.ReaderAt
+}
+
+func (nopCloserReaderAt) Close() error {
+	return nil
+}
+
+func (img *isoImage) Close() error {
+	return img.file.Close()
+}
+
+func (img *isoImage) readAt(offset, length int64) ([]byte, error) {
+	data := make([]byte, length)
+	n, err := img.file.ReadAt(data, offset)
+	if err != nil && !(errors.Is(err, io.EOF) && int64(n) == length) {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (img *isoImage) parse() error {
+	var joliet *isoVolume
+	for lba := int64(isoVolumeDescriptorStart); ; lba++ {
+		if lba >= isoVolumeDescriptorStart+isoMaxVolumeDescriptors {
+			return errors.New("volume descriptor set terminator not found")
+		}
+		data, err := img.readAt(lba*isoSectorSize, isoSectorSize)
+		if err != nil {
+			return err
+		}
+		if string(data[1:6]) != "CD001" {
+			return fmt.Errorf("invalid volume descriptor at sector %d", lba)
+		}
+		vd := &isoVolumeDescriptor{lba: lba, data: data}
+		img.descriptors = append(img.descriptors, vd)
+
+		switch data[0] {
+		case isoVolumeDescriptorTerminator:
+			if len(img.volumes) == 0 {
+				return errors.New("no primary volume descriptor")
+			}
+			if joliet != nil {
+				img.volumes = append(img.volumes, joliet)
+			}
+			return nil
+		case isoVolumeDescriptorPrimary:
+			if len(img.volumes) > 0 {
+				continue
+			}
+			img.volumeSize = int64(binary.LittleEndian.Uint32(data[80:]))
+			volume := &isoVolume{descriptor: vd}
+			if err := img.parseVolume(volume); err != nil {
+				return err
+			}
+			img.volumes = append(img.volumes, volume)
+		case isoVolumeDescriptorSupplementary:
+			escape := data[88:91]
+			if joliet != nil || escape[0] != '%' || escape[1] != '/' || !bytes.ContainsRune([]byte("@CE"), rune(escape[2])) {
+				continue
+			}
+			joliet = &isoVolume{descriptor: vd, joliet: true}
+			if err := img.parseVolume(joliet); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (img *isoImage) parseVolume(volume *isoVolume) error {
+	rootRecord := &isoRecord{raw: append([]byte{}, volume.descriptor.data[156:190]...)}
+	root, err := img.parseDirectory(volume, nil, rootRecord.extent(), rootRecord.dataLength(), 0)
+	if err != nil {
+		return err
+	}
+	volume.root = root
+	return nil
+}
+
+func (img *isoImage) parseDirectory(volume *isoVolume, parent *isoDirectory, lba, length int64, depth int) (*isoDirectory, error) {
+	if depth > isoMaxDepth {
+		return nil, errors.New("too many nested directories")
+	}
+	data, err := img.readAt(lba*isoSectorSize, length)
+	if err != nil {
+		return nil, err
+	}
+	dir := &isoDirectory{volume: volume, parent: parent, lba: lba, length: length}
+	for pos := 0; pos < len(data); {
+		recordLength := int(data[pos])
+		if recordLength == 0 {
+			// records don't cross sector boundaries, the rest of the sector is padding
+			pos = (pos/isoSectorSize + 1) * isoSectorSize
+			continue
+		}
+		if recordLength < 34 || pos+recordLength > len(data) || 33+int(data[pos+32]) > recordLength {
+			return nil, fmt.Errorf("invalid directory record in sector %d", lba+int64(pos/isoSectorSize))
+		}
+		record := &isoRecord{raw: append([]byte{}, data[pos:pos+recordLength]...)}
+		pos += recordLength
+
+		// the SUSP SP entry of the first record of the root tells whether Rock Ridge is used
+		if parent == nil && len(dir.records) == 0 && !volume.joliet {
+			if su := record.systemUse(); len(su) >= 7 && string(su[0:2]) == "SP" && su[4] == 0xbe && su[5] == 0xef {
+				volume.rockRidge = true
+				volume.suspSkip = int(su[6])
+			}
+		}
+		if err := img.nameRecord(volume, record); err != nil {
+			return nil, err
+		}
+		dir.records = append(dir.records, record)
+	}
+
+	// the extents of multi-extent files are consecutive records with the same identifier, all
+	// flagged but the last one
+	var file, prev *isoRecord
+	for _, record := range dir.records {
+		if prev != nil && prev.multiExtent() && !prev.isDir() && bytes.Equal(prev.identifier(), record.identifier()) {
+			file.parts = append(file.parts, record)
+			record.part = true
+		} else {
+			file = record
+		}
+		prev = record
+	}
+
+	for _, record := range dir.records {
+		if !record.isDir() || record.isDot() {
+			continue
+		}
+		if record.extent() == lba || (parent != nil && record.extent() == parent.lba) {
+			return nil, fmt.Errorf("directory loop in sector %d", lba)
+		}
+		if record.dir, err = img.parseDirectory(volume, dir, record.extent(), record.dataLength(), depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return dir, nil
+}
+
+// nameRecord sets the name of the record from its Rock Ridge NM entries, its Joliet identifier or
+// its ISO9660 identifier without version
+func (img *isoImage) nameRecord(volume *isoVolume, record *isoRecord) error {
+	if record.isDot() {
+		return nil
+	}
+	id := record.identifier()
+	if volume.joliet {
+		units := make([]uint16, len(id)/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(id[2*i:])
+		}
+		record.name = stripISOVersion(string(utf16.Decode(units)))
+		return nil
+	}
+	if volume.rockRidge {
+		entries, err := img.systemUseEntries(volume, record)
+		if err != nil {
+			return err
+		}
+		record.symlink = symlinkTarget(entries)
+		var name []byte
+		found := false
+		for _, entry := range entries {
+			if entry.signature() == "NM" && len(entry) >= 5 && entry[4]&0x06 == 0 {
+				name = append(name, entry[5:]...)
+				found = true
+			}
+		}
+		if found {
+			record.name = string(name)
+			return nil
+		}
+	}
+	record.name = stripISOVersion(string(id))
+	return nil
+}
+
+// symlinkTarget returns the target of the Rock Ridge SL entries, made of path components that can
+// span several entries
+func symlinkTarget(entries []suspEntry) string {
+	var target strings.Builder
+	separate := false
+	for _, entry := range entries {
+		if entry.signature() != "SL" || len(entry) < 5 {
+			continue
+		}
+		components := entry[5:]
+		for pos := 0; pos+2 <= len(components); {
+			flags, length := components[pos], int(components[pos+1])
+			if pos+2+length > len(components) {
+				break
+			}
+			component := string(components[pos+2 : pos+2+length])
+			pos += 2 + length
+			switch {
+			case flags&0x08 != 0:
+				target.Reset()
+				target.WriteString("/")
+				separate = false
+				continue
+			case flags&0x02 != 0:
+				component = "."
+			case flags&0x04 != 0:
+				component = ".."
+			}
+			if separate {
+				target.WriteString("/")
+			}
+			target.WriteString(component)
+			// the component continues in the next one
+			separate = flags&0x01 == 0
+		}
+	}
+	return target.String()
+}
+
+func stripISOVersion(name string) string {
+	if i := strings.LastIndexByte(name, ';'); i >= 0 {
+		name = name[:i]
+	}
+	if strings.HasSuffix(name, ".") && name != "." {
+		name = strings.TrimSuffix(name, ".")
+	}
+	return name
+}
+
+// suspEntry is a System Use Sharing Protocol entry, starting with its signature
+type suspEntry []byte
+
+func (e suspEntry) signature() string {
+	return string(e[0:2])
+}
+
+// systemUseEntries returns the SUSP entries of a record, including the ones of its continuation
+// areas
+func (img *isoImage) systemUseEntries(volume *isoVolume, record *isoRecord) ([]suspEntry, error) {
+	area := record.systemUse()
+	if len(area) < volume.suspSkip {
+		return nil, nil
+	}
+	var entries []suspEntry
+	area = area[volume.suspSkip:]
+	for depth := 0; area != nil; depth++ {
+		if depth > isoMaxDepth {
+			return nil, errors.New("too many SUSP continuation areas")
+		}
+		var next []byte
+		for pos := 0; pos+4 <= len(area); {
+			entryLength := int(area[pos+2])
+			if entryLength < 4 || pos+entryLength > len(area) {
+				break
+			}
+			entry := suspEntry(area[pos : pos+entryLength])
+			pos += entryLength
+			if entry.signature() == "ST" {
+				break
+			}
+			if entry.signature() == "CE" && entryLength >= 28 {
+				lba := int64(binary.LittleEndian.Uint32(entry[4:]))
+				offset := int64(binary.LittleEndian.Uint32(entry[12:]))
+				length := int64(binary.LittleEndian.Uint32(entry[20:]))
+				if offset+length > isoSectorSize {
+					return nil, fmt.Errorf("invalid SUSP continuation area in sector %d", lba)
+				}
+				var err error
+				if next, err = img.readAt(lba*isoSectorSize+offset, length); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		area = next
+	}
+	return entries, nil
+}
+
+// volume returns the volume used to look up files: the primary one when it has Rock Ridge names
+// or no Joliet alternative
+func (img *isoImage) lookupVolume() *isoVolume {
+	if !img.volumes[0].rockRidge && len(img.volumes) > 1 {
+		return img.volumes[1]
+	}
+	return img.volumes[0]
+}
+
+// find returns the record of child name in dir. Names are compared exactly, then regardless of
+// case for volumes without Rock Ridge names.
+func (dir *isoDirectory) find(name string) *isoRecord {
+	for _, record := range dir.records {
+		if !record.isDot() && !record.part && record.name == name {
+			return record
+		}
+	}
+	if dir.volume.rockRidge {
+		return nil
+	}
+	for _, record := range dir.records {
+		if !record.isDot() && !record.part && strings.EqualFold(record.name, name) {
+			return record
+		}
+	}
+	return nil
+}
+
+// lookup returns the record of filePath in the volume and the directory containing it
+func (volume *isoVolume) lookup(filePath string) (*isoRecord, *isoDirectory, error) {
+	dir := volume.root
+	components := strings.Split(strings.Trim(path.Clean("/"+filePath), "/"), "/")
+	for i, component := range components {
+		record := dir.find(component)
+		if record == nil {
+			return nil, nil, fmt.Errorf("%s: %w", filePath, os.ErrNotExist)
+		}
+		if i == len(components)-1 {
+			return record, dir, nil
+		}
+		if record.dir == nil {
+			return nil, nil, fmt.Errorf("%s is not a directory", path.Join(components[:i+1]...))
+		}
+		dir = record.dir
+	}
+	return nil, nil, fmt.Errorf("%s: %w", filePath, os.ErrNotExist)
+}
+
+// resolve returns the record of filePath in the volume, following symbolic links. Paths of
+// directories resolve to their . record.
+func (volume *isoVolume) resolve(filePath string) (*isoRecord, error) {
+	record, _, err := volume.resolveDirectory(filePath)
+	return record, err
+}
+
+// resolveDirectory resolves filePath as resolve does, also returning the directory when filePath
+// is one
+func (volume *isoVolume) resolveDirectory(filePath string) (*isoRecord, *isoDirectory, error) {
+	dir := volume.root
+	components := strings.Split(filePath, "/")
+	for links := 0; len(components) > 0; {
+		name := components[0]
+		components = components[1:]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if dir.parent != nil {
+				dir = dir.parent
+			}
+			continue
+		}
+
+		record := dir.find(name)
+		if record == nil {
+			return nil, nil, fmt.Errorf("%s: %w", filePath, os.ErrNotExist)
+		}
+		if record.symlink != "" {
+			if links++; links > isoMaxSymlinks {
+				return nil, nil, fmt.Errorf("%s: too many levels of symbolic links", filePath)
+			}
+			if strings.HasPrefix(record.symlink, "/") {
+				dir = volume.root
+			}
+			components = append(strings.Split(record.symlink, "/"), components...)
+			continue
+		}
+		if record.dir != nil {
+			dir = record.dir
+			continue
+		}
+		if len(components) > 0 && strings.Join(components, "") != "" {
+			return nil, nil, fmt.Errorf("%s: %s is not a directory", filePath, name)
+		}
+		return record, nil, nil
+	}
+	return dir.records[0], dir, nil
+}
+
+// sectionsReaderAt reads the sections of a file as a contiguous stream
+type sectionsReaderAt struct {
+	io.ReaderAt
+	sections []isoSection
+}
+
+func (s *sectionsReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for _, section := range s.sections {
+		if n == len(p) {
+			break
+		}
+		if off >= section.length {
+			off -= section.length
+			continue
+		}
+		count := min(int64(len(p)-n), section.length-off)
+		read, err := s.ReaderAt.ReadAt(p[n:n+int(count)], section.offset+off)
+		n += read
+		if err != nil && !(errors.Is(err, io.EOF) && int64(read) == count) {
+			return n, err
+		}
+		off = 0
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
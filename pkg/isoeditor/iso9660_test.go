@@ -0,0 +1,213 @@
+package isoeditor
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+var _ = Describe("symlinkTarget", func() {
+	It("joins the components of the SL entries", func() {
+		sl := func(flags byte, components ...byte) suspEntry {
+			return append(suspEntry{'S', 'L', byte(5 + len(components)), 1, flags}, components...)
+		}
+		Expect(symlinkTarget([]suspEntry{sl(0, 0, 6, 'k', 'a', 'r', 'g', 's', '2')})).To(Equal("kargs2"))
+		Expect(symlinkTarget([]suspEntry{sl(0, 0x08, 0, 0, 6, 'c', 'o', 'r', 'e', 'o', 's', 0, 1, 'a')})).To(Equal("/coreos/a"))
+		Expect(symlinkTarget([]suspEntry{sl(0, 0x04, 0, 0, 1, 'b')})).To(Equal("../b"))
+		// components and entries continued by the next ones
+		Expect(symlinkTarget([]suspEntry{
+			sl(1, 0x01, 3, 'l', 'o', 'n'),
+			sl(0, 0, 1, 'g', 0, 1, 'c'),
+		})).To(Equal("long/c"))
+		Expect(symlinkTarget([]suspEntry{{'N', 'M', 6, 1, 0, 'x'}})).To(BeEmpty())
+	})
+})
+
+var _ = Describe("isoVolume.resolve", func() {
+	var volume *isoVolume
+
+	record := func(name string, flags byte, id byte) *isoRecord {
+		raw := make([]byte, 34)
+		raw[0], raw[25], raw[32], raw[33] = 34, flags, 1, id
+		return &isoRecord{raw: raw, name: name}
+	}
+	dir := func(parent *isoDirectory, name string) *isoDirectory {
+		d := &isoDirectory{volume: volume, parent: parent, records: []*isoRecord{record("", isoRecordFlagDirectory, 0), record("", isoRecordFlagDirectory, 1)}}
+		if parent != nil {
+			r := record(name, isoRecordFlagDirectory, 'D')
+			r.dir = d
+			parent.records = append(parent.records, r)
+		}
+		return d
+	}
+	file := func(parent *isoDirectory, name, symlink string) *isoRecord {
+		r := record(name, 0, 'F')
+		r.symlink = symlink
+		parent.records = append(parent.records, r)
+		return r
+	}
+
+	It("follows symbolic links", func() {
+		volume = &isoVolume{rockRidge: true}
+		volume.root = dir(nil, "")
+		coreos := dir(volume.root, "coreos")
+		images := dir(volume.root, "images")
+		kargs := file(coreos, "kargs.json", "")
+		file(coreos, "kargs-link.json", "kargs.json")
+		file(images, "absolute-link", "/coreos/kargs-link.json")
+		file(images, "coreos-link", "../coreos")
+		file(volume.root, "loop-a", "loop-b")
+		file(volume.root, "loop-b", "loop-a")
+
+		for _, filePath := range []string{"/coreos/kargs.json", "/coreos/kargs-link.json", "/images/absolute-link", "/images/coreos-link/kargs.json", "images/coreos-link/../coreos/kargs-link.json"} {
+			r, err := volume.resolve(filePath)
+			Expect(err).NotTo(HaveOccurred(), filePath)
+			Expect(r).To(BeIdenticalTo(kargs), filePath)
+		}
+		r, err := volume.resolve("/images/coreos-link")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r).To(BeIdenticalTo(coreos.records[0]))
+
+		_, err = volume.resolve("/loop-a")
+		Expect(err).To(MatchError(ContainSubstring("too many levels of symbolic links")))
+		_, err = volume.resolve("/coreos/Kargs.json")
+		Expect(err).To(MatchError(os.ErrNotExist))
+	})
+})
+
+var _ = Describe("multi-extent files", func() {
+	var (
+		filesDir string
+		isoFile  string
+		content  []byte
+	)
+
+	// splitExtents rewrites the record of filePath as the records of a multi-extent file with
+	// the given extents, of the form {lba offset from the original extent, length}
+	splitExtents := func(filePath string, extents [][2]int64) {
+		w, err := NewISOWriter(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		defer w.Close()
+		for _, volume := range w.img.volumes {
+			record, dir, err := volume.lookup(filePath)
+			Expect(err).NotTo(HaveOccurred())
+			lba := record.extent()
+			var records []*isoRecord
+			for i, extent := range extents {
+				part := &isoRecord{raw: append([]byte{}, record.raw...), name: record.name}
+				part.setExtent(lba+extent[0], extent[1])
+				if i < len(extents)-1 {
+					part.raw[25] |= isoRecordFlagMultiExtent
+				}
+				records = append(records, part)
+			}
+			for i, r := range dir.records {
+				if r == record {
+					dir.records = append(dir.records[:i], append(records, dir.records[i+1:]...)...)
+					break
+				}
+			}
+			w.dirtyDirs[dir] = true
+		}
+		out, err := os.CreateTemp("", "*multi.iso")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = w.WriteTo(out)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.Close()).To(Succeed())
+		Expect(os.Rename(out.Name(), isoFile)).To(Succeed())
+	}
+
+	isolate := func(filePath string) []byte {
+		f, err := os.Open(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		iso, err := overlay.NewMultiOverlayReader(f)
+		Expect(err).NotTo(HaveOccurred())
+		fileData, _, err := isolateISOFile(isoFile, filePath, iso, 0)
+		Expect(err).NotTo(HaveOccurred())
+		defer fileData.Data.Close()
+		data, err := io.ReadAll(fileData.Data)
+		Expect(err).NotTo(HaveOccurred())
+		return data
+	}
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+		content = append(bytes.Repeat([]byte("A"), isoSectorSize), bytes.Repeat([]byte("B"), isoSectorSize)...)
+		content = append(content, []byte("the last extent")...)
+		Expect(os.WriteFile(filepath.Join(filesDir, "images/big.img"), content, 0600)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+		Expect(Create(isoFile, filesDir, "Assisted123")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+	})
+
+	It("reads contiguous extents as a single file", func() {
+		offset, _, err := GetISOFileInfo("/images/big.img", isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		splitExtents("/images/big.img", [][2]int64{{0, isoSectorSize}, {1, isoSectorSize}, {2, 15}})
+
+		newOffset, size, err := GetISOFileInfo("/images/big.img", isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newOffset).To(Equal(offset))
+		Expect(size).To(Equal(int64(len(content))))
+		data, err := ReadFileFromISO(isoFile, "/images/big.img")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(content))
+		Expect(isolate("/images/big.img")).To(Equal(content))
+
+		files, err := FindISOFiles(isoFile, "/images/big.img")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].Size).To(Equal(int64(len(content))))
+
+		w, err := NewISOWriter(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		defer w.Close()
+		Expect(w.ReplaceFile("/images/big.img", bytes.NewReader(content))).To(MatchError(ContainSubstring("has multiple extents")))
+	})
+
+	It("reads fragmented extents in order", func() {
+		splitExtents("/images/big.img", [][2]int64{{1, isoSectorSize}, {0, isoSectorSize}})
+		expected := append(bytes.Repeat([]byte("B"), isoSectorSize), bytes.Repeat([]byte("A"), isoSectorSize)...)
+
+		_, _, err := GetISOFileInfo("/images/big.img", isoFile)
+		Expect(err).To(MatchError(ContainSubstring("fragmented in 2 sections")))
+		data, err := ReadFileFromISO(isoFile, "/images/big.img")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(expected))
+		Expect(isolate("/images/big.img")).To(Equal(expected))
+	})
+
+	It("distributes relocated files over their extents", func() {
+		splitExtents("/images/big.img", [][2]int64{{0, isoSectorSize}, {1, isoSectorSize}, {2, 15}})
+		for _, length := range []int64{isoSectorSize + 10, 3*isoSectorSize + 5000} {
+			reloc, err := newISORelocation(isoFile, "/images/big.img", length)
+			Expect(err).NotTo(HaveOccurred())
+			r, err := reloc.reader([]byte("relocated"))
+			Expect(err).NotTo(HaveOccurred())
+			out, err := os.CreateTemp("", "*relocated.iso")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = io.Copy(out, r)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out.Close()).To(Succeed())
+			Expect(r.Close()).To(Succeed())
+
+			data, err := ReadFileFromISO(out.Name(), "/images/big.img")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(HaveLen(int(length)))
+			Expect(string(data[:9])).To(Equal("relocated"))
+			rootfs, err := ReadFileFromISO(out.Name(), "/images/pxeboot/rootfs.img")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(rootfs)).To(Equal("this is rootfs"))
+			Expect(os.Remove(out.Name())).To(Succeed())
+		}
+	})
+})
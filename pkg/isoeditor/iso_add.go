@@ -0,0 +1,31 @@
+package isoeditor
+
+import (
+	"io"
+)
+
+// AddFileToISO returns the ISO with filePath added, e.g. /custom/manifests.tar, creating its parent
+// directories. Unlike the customizations embedded in padded files, the file can be of any size.
+// The content is read when reading the returned ISO.
+func AddFileToISO(isoPath, filePath string, content io.ReadSeeker) (ImageReader, error) {
+	w, err := NewISOWriter(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	if err := w.AddFile(filePath, content); err != nil {
+		return nil, err
+	}
+	return w.Reader()
+}
+
+// WriteTo writes the modified ISO to out. No changes can be made after calling it.
+func (w *ISOWriter) WriteTo(out io.Writer) (int64, error) {
+	r, err := w.Reader()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	return io.Copy(out, r)
+}
@@ -0,0 +1,234 @@
+package isoeditor
+
+import (
+	"archive/tar"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// isoAttributes are the Rock Ridge POSIX attributes of a record, or defaults for volumes
+// without Rock Ridge
+type isoAttributes struct {
+	mode    os.FileMode
+	uid     int
+	gid     int
+	modTime time.Time
+}
+
+// ExtractISODirectory extracts dirPath of the ISO, e.g. /EFI, to destDir, keeping the Rock Ridge
+// modes, modification times and symbolic links. destDir is created if needed.
+func ExtractISODirectory(isoPath, dirPath, destDir string) error {
+	img, dir, err := openISODirectory(isoPath, dirPath)
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+	// the attributes of directories are set last, once their content is written
+	type extractedDir struct {
+		path  string
+		attrs isoAttributes
+	}
+	var dirs []extractedDir
+	err = img.walkFiles(dir, "", func(filePath string, record *isoRecord, attrs isoAttributes) error {
+		target := filepath.Join(destDir, filepath.FromSlash(filePath))
+		switch {
+		case record.symlink != "":
+			return os.Symlink(record.symlink, target)
+		case record.dir != nil:
+			dirs = append(dirs, extractedDir{path: target, attrs: attrs})
+			return os.Mkdir(target, 0700)
+		}
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, record.reader(img.file)); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to extract %s: %w", filePath, err)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return setAttributes(target, attrs)
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := setAttributes(dirs[i].path, dirs[i].attrs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExtractISODirectoryTar writes dirPath of the ISO to out as a tar archive, with paths relative to
+// dirPath and the Rock Ridge modes, owners, modification times and symbolic links
+func ExtractISODirectoryTar(isoPath, dirPath string, out io.Writer) error {
+	img, dir, err := openISODirectory(isoPath, dirPath)
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+
+	tw := tar.NewWriter(out)
+	err = img.walkFiles(dir, "", func(filePath string, record *isoRecord, attrs isoAttributes) error {
+		header := &tar.Header{
+			Name:    filePath,
+			Mode:    int64(attrs.mode.Perm()),
+			Uid:     attrs.uid,
+			Gid:     attrs.gid,
+			ModTime: attrs.modTime,
+			Format:  tar.FormatPAX,
+		}
+		switch {
+		case record.symlink != "":
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = record.symlink
+		case record.dir != nil:
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+		default:
+			header.Typeflag = tar.TypeReg
+			header.Size = record.length()
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil
+		}
+		if _, err := io.Copy(tw, record.reader(img.file)); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", filePath, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+func openISODirectory(isoPath, dirPath string) (*isoImage, *isoDirectory, error) {
+	img, err := openISOImage(isoPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, dir, err := img.lookupVolume().resolveDirectory(dirPath)
+	if err == nil && dir == nil {
+		err = fmt.Errorf("%s is not a directory", dirPath)
+	}
+	if err != nil {
+		img.Close()
+		return nil, nil, err
+	}
+	return img, dir, nil
+}
+
+// walkFiles calls fn for the files and directories under dir, parents first, with their path
+// relative to dir
+func (img *isoImage) walkFiles(dir *isoDirectory, dirPath string, fn func(filePath string, record *isoRecord, attrs isoAttributes) error) error {
+	for _, record := range dir.records {
+		if record.isDot() || record.part {
+			continue
+		}
+		if record.name == "." || record.name == ".." || path.Base(record.name) != record.name {
+			return fmt.Errorf("invalid file name %q in %s", record.name, dirPath)
+		}
+		filePath := path.Join(dirPath, record.name)
+		if err := fn(filePath, record, img.attributes(dir.volume, record)); err != nil {
+			return err
+		}
+		if record.dir != nil && record.symlink == "" {
+			if err := img.walkFiles(record.dir, filePath, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// attributes returns the attributes of the record from its Rock Ridge PX and TF entries
+func (img *isoImage) attributes(volume *isoVolume, record *isoRecord) isoAttributes {
+	attrs := isoAttributes{mode: 0644}
+	if record.isDir() {
+		attrs.mode = os.ModeDir | 0755
+	}
+	if date := parseRecordDate(record.raw[18:25]); date != nil {
+		attrs.modTime = *date
+	}
+	if !volume.rockRidge {
+		return attrs
+	}
+	entries, err := img.systemUseEntries(volume, record)
+	if err != nil {
+		return attrs
+	}
+	for _, entry := range entries {
+		switch {
+		case entry.signature() == "PX" && len(entry) >= 36:
+			attrs.mode = attrs.mode.Type() | os.FileMode(binary.LittleEndian.Uint32(entry[4:])&0777)
+			attrs.uid = int(binary.LittleEndian.Uint32(entry[20:]))
+			attrs.gid = int(binary.LittleEndian.Uint32(entry[28:]))
+		case entry.signature() == "TF" && len(entry) >= 5:
+			if date := modificationTime(entry); date != nil {
+				attrs.modTime = *date
+			}
+		}
+	}
+	return attrs
+}
+
+// modificationTime returns the modification time of a Rock Ridge TF entry, which lists the
+// times flagged in the order creation, modification, access...
+func modificationTime(entry suspEntry) *time.Time {
+	flags := entry[4]
+	if flags&0x02 == 0 {
+		return nil
+	}
+	size := 7
+	if flags&0x80 != 0 {
+		size = 17
+	}
+	start := 5
+	if flags&0x01 != 0 {
+		start += size
+	}
+	if len(entry) < start+size {
+		return nil
+	}
+	if size == 17 {
+		return parseISODate(entry[start : start+size])
+	}
+	return parseRecordDate(entry[start : start+size])
+}
+
+// parseRecordDate parses the 7 bytes dates of directory records: years since 1900, month, day,
+// hour, minute, second and offset from GMT in 15 minutes intervals
+func parseRecordDate(b []byte) *time.Time {
+	if b[1] == 0 {
+		return nil
+	}
+	zone := time.FixedZone("", int(int8(b[6]))*15*60)
+	t := time.Date(1900+int(b[0]), time.Month(b[1]), int(b[2]), int(b[3]), int(b[4]), int(b[5]), 0, zone)
+	return &t
+}
+
+func setAttributes(filePath string, attrs isoAttributes) error {
+	if err := os.Chmod(filePath, attrs.mode.Perm()); err != nil {
+		return err
+	}
+	if attrs.modTime.IsZero() {
+		return nil
+	}
+	return os.Chtimes(filePath, attrs.modTime, attrs.modTime)
+}
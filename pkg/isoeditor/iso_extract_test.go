@@ -0,0 +1,103 @@
+package isoeditor
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ExtractISODirectory", func() {
+	var (
+		workDir string
+		isoFile string
+		modTime = time.Date(2024, 3, 15, 10, 30, 45, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		var err error
+		workDir, err = os.MkdirTemp("", "isoextract")
+		Expect(err).NotTo(HaveOccurred())
+		filesDir := filepath.Join(workDir, "files")
+		Expect(os.MkdirAll(filepath.Join(filesDir, "EFI/redhat/fonts"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(filesDir, "EFI/redhat/grub.cfg"), []byte(testGrubConfig), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(filesDir, "EFI/redhat/shimx64.efi"), []byte("shim"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(filesDir, "EFI/redhat/fonts/unicode.pf2"), []byte("font"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(filesDir, "README"), []byte("readme"), 0644)).To(Succeed())
+		Expect(os.Chtimes(filepath.Join(filesDir, "EFI/redhat/grub.cfg"), modTime, modTime)).To(Succeed())
+
+		isoFile = filepath.Join(workDir, "test.iso")
+		Expect(Create(isoFile, filesDir, "Assisted123")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(workDir)).To(Succeed())
+	})
+
+	It("extracts the directory tree to a path", func() {
+		dest := filepath.Join(workDir, "dest")
+		Expect(ExtractISODirectory(isoFile, "/EFI", dest)).To(Succeed())
+
+		content, err := os.ReadFile(filepath.Join(dest, "redhat/grub.cfg"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal(testGrubConfig))
+		content, err = os.ReadFile(filepath.Join(dest, "redhat/fonts/unicode.pf2"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("font"))
+		Expect(filepath.Join(dest, "README")).NotTo(BeAnExistingFile())
+
+		info, err := os.Stat(filepath.Join(dest, "redhat/shimx64.efi"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+		info, err = os.Stat(filepath.Join(dest, "redhat/grub.cfg"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0644)))
+		Expect(info.ModTime().Equal(modTime)).To(BeTrue(), info.ModTime().String())
+	})
+
+	It("extracts the directory tree to a tar stream", func() {
+		var out bytes.Buffer
+		Expect(ExtractISODirectoryTar(isoFile, "EFI/redhat", &out)).To(Succeed())
+
+		headers := map[string]*tar.Header{}
+		contents := map[string]string{}
+		tr := tar.NewReader(&out)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			headers[header.Name] = header
+			content, err := io.ReadAll(tr)
+			Expect(err).NotTo(HaveOccurred())
+			contents[header.Name] = string(content)
+		}
+		Expect(headers).To(HaveLen(4))
+		Expect(headers).To(HaveKey("fonts/"))
+		Expect(headers["fonts/"].Typeflag).To(Equal(byte(tar.TypeDir)))
+		Expect(contents["grub.cfg"]).To(Equal(testGrubConfig))
+		Expect(contents["fonts/unicode.pf2"]).To(Equal("font"))
+		Expect(headers["shimx64.efi"].Mode).To(Equal(int64(0755)))
+		Expect(headers["grub.cfg"].ModTime.Equal(modTime)).To(BeTrue())
+	})
+
+	It("fails for files and missing directories", func() {
+		Expect(ExtractISODirectory(isoFile, "/README", filepath.Join(workDir, "dest"))).To(MatchError(ContainSubstring("not a directory")))
+		Expect(ExtractISODirectoryTar(isoFile, "/missing", io.Discard)).To(MatchError(os.ErrNotExist))
+	})
+})
+
+var _ = Describe("parseRecordDate", func() {
+	It("parses the dates of directory records", func() {
+		date := parseRecordDate([]byte{124, 3, 15, 12, 30, 45, 8})
+		Expect(date).NotTo(BeNil())
+		Expect(date.UTC()).To(Equal(time.Date(2024, 3, 15, 10, 30, 45, 0, time.UTC)))
+		Expect(parseRecordDate(make([]byte, 7))).To(BeNil())
+	})
+})
@@ -0,0 +1,71 @@
+package isoeditor
+
+import (
+	"path"
+	"sort"
+)
+
+// ISOFileInfo describes a file or a directory of an ISO
+type ISOFileInfo struct {
+	// Path of the file, from the root of the ISO, using the Rock Ridge or Joliet names
+	Path string
+	// Offset and Size of the content of the file in the ISO, or of the directory records
+	Offset int64
+	Size   int64
+	IsDir  bool
+	// Symlink is the target of Rock Ridge symbolic links
+	Symlink string
+}
+
+// ListISOFiles returns the files and directories of the ISO, sorted by path
+func ListISOFiles(isoPath string) ([]ISOFileInfo, error) {
+	img, err := openISOImage(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer img.Close()
+
+	var files []ISOFileInfo
+	var walk func(dir *isoDirectory, dirPath string)
+	walk = func(dir *isoDirectory, dirPath string) {
+		for _, record := range dir.records {
+			if record.isDot() || record.part {
+				continue
+			}
+			info := ISOFileInfo{
+				Path:    path.Join(dirPath, record.name),
+				Offset:  record.extent() * isoSectorSize,
+				Size:    record.length(),
+				IsDir:   record.isDir(),
+				Symlink: record.symlink,
+			}
+			files = append(files, info)
+			if record.dir != nil {
+				walk(record.dir, info.Path)
+			}
+		}
+	}
+	walk(img.lookupVolume().root, "/")
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// FindISOFiles returns the regular files of the ISO with a path matching pattern, using the
+// syntax of path.Match, e.g. /EFI/*/grub.cfg
+func FindISOFiles(isoPath, pattern string) ([]ISOFileInfo, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	files, err := ListISOFiles(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	var ret []ISOFileInfo
+	for _, file := range files {
+		if matched, _ := path.Match(pattern, file.Path); matched && !file.IsDir && file.Symlink == "" {
+			ret = append(ret, file)
+		}
+	}
+	return ret, nil
+}
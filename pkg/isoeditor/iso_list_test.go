@@ -0,0 +1,70 @@
+package isoeditor
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ListISOFiles", func() {
+	var (
+		filesDir string
+		isoFile  string
+	)
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+		Expect(os.MkdirAll(filepath.Join(filesDir, "EFI/fedora"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(filesDir, "EFI/fedora/grub.cfg"), []byte("fedora"), 0600)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+		Expect(Create(isoFile, filesDir, "Assisted123")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+	})
+
+	It("lists the files and directories with their offsets", func() {
+		files, err := ListISOFiles(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		paths := map[string]ISOFileInfo{}
+		for i, file := range files {
+			if i > 0 {
+				Expect(file.Path >= files[i-1].Path).To(BeTrue())
+			}
+			paths[file.Path] = file
+		}
+		Expect(paths).To(HaveKey("/EFI"))
+		Expect(paths["/EFI"].IsDir).To(BeTrue())
+		Expect(paths).To(HaveKey("/images/pxeboot"))
+
+		rootfs := paths["/images/pxeboot/rootfs.img"]
+		Expect(rootfs.IsDir).To(BeFalse())
+		Expect(rootfs.Size).To(Equal(int64(len("this is rootfs"))))
+		offset, size, err := GetISOFileInfo(rootfs.Path, isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rootfs.Offset).To(Equal(offset))
+		Expect(rootfs.Size).To(Equal(size))
+	})
+
+	It("finds the files matching a pattern", func() {
+		files, err := FindISOFiles(isoFile, "/EFI/*/grub.cfg")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(2))
+		Expect(files[0].Path).To(Equal("/EFI/fedora/grub.cfg"))
+		Expect(files[1].Path).To(Equal(defaultGrubFilePath))
+
+		_, err = FindISOFiles(isoFile, "/EFI/[")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("uses the grub configs of the ISO when there is no kargs.json", func() {
+		files, err := KargsFiles(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(Equal([]string{"/EFI/fedora/grub.cfg", defaultGrubFilePath, defaultIsolinuxFilePath}))
+	})
+})
@@ -0,0 +1,280 @@
+package isoeditor
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+// ISOMD5Mode tells what to do with the media checksum implanted by implantisomd5, which no longer
+// matches once the ISO is customized and makes checkisomd5 (rd.live.check) fail
+type ISOMD5Mode string
+
+const (
+	// ISOMD5Keep leaves the implanted checksum as is
+	ISOMD5Keep ISOMD5Mode = "keep"
+	// ISOMD5Implant recomputes the checksum of the customized ISO, reading it entirely first
+	ISOMD5Implant ISOMD5Mode = "implant"
+	// ISOMD5Blank removes the checksum, checkisomd5 then reports that it can't verify the media
+	ISOMD5Blank ISOMD5Mode = "blank"
+)
+
+const (
+	// the checksum is written in the application use area of the primary volume descriptor
+	isoMD5AppDataOffset = 883
+	isoMD5AppDataSize   = 512
+	isoMD5SkipSectors   = 15
+	isoMD5FragmentCount = 20
+	isoMD5FragmentSize  = 3
+	// the checksum is computed in chunks of this size, fragment sums are taken between chunks
+	isoMD5BufferSize = isoVolumeDescriptorStart * isoSectorSize
+)
+
+// ParseISOMD5Mode parses an ISOMD5Mode, the empty string meaning ISOMD5Keep
+func ParseISOMD5Mode(mode string) (ISOMD5Mode, error) {
+	switch m := ISOMD5Mode(strings.ToLower(mode)); m {
+	case "":
+		return ISOMD5Keep, nil
+	case ISOMD5Keep, ISOMD5Implant, ISOMD5Blank:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid ISO MD5 mode %q, expected one of %s, %s or %s", mode, ISOMD5Keep, ISOMD5Implant, ISOMD5Blank)
+}
+
+// ISOMD5StreamGenerator returns a StreamGeneratorFunc that handles the implanted checksum of the
+// ISOs generated by generator according to mode
+func ISOMD5StreamGenerator(generator StreamGeneratorFunc, mode ISOMD5Mode) StreamGeneratorFunc {
+	if mode == ISOMD5Keep || mode == "" {
+		return generator
+	}
+	return func(isoPath string, ignitionContent *IgnitionContent, ramdiskContent []byte, kargs KernelArguments) (ImageReader, error) {
+		r, err := generator(isoPath, ignitionContent, ramdiskContent, kargs)
+		if err != nil {
+			return nil, err
+		}
+		var ret ImageReader
+		if mode == ISOMD5Implant {
+			ret, err = ImplantISOMD5(r)
+		} else {
+			ret, err = BlankISOMD5(r)
+		}
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		return ret, nil
+	}
+}
+
+// ISOMD5 is the media checksum implanted by implantisomd5
+type ISOMD5 struct {
+	MD5           string `json:"md5"`
+	SkipSectors   int64  `json:"skip_sectors"`
+	Supported     bool   `json:"supported"`
+	FragmentSums  string `json:"fragment_sums"`
+	FragmentCount int    `json:"fragment_count"`
+}
+
+// ReadISOMD5 returns the checksum implanted in the ISO, or nil if there is none
+func ReadISOMD5(iso io.ReaderAt) (*ISOMD5, error) {
+	pvdOffset, _, err := primaryVolume(iso)
+	if err != nil {
+		return nil, err
+	}
+	appData := make([]byte, isoMD5AppDataSize)
+	if _, err := iso.ReadAt(appData, pvdOffset+isoMD5AppDataOffset); err != nil {
+		return nil, err
+	}
+
+	var ret ISOMD5
+	found := false
+	for _, field := range strings.Split(string(appData), ";") {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "ISO MD5SUM":
+			ret.MD5, found = value, true
+		case "SKIPSECTORS":
+			ret.SkipSectors, err = strconv.ParseInt(value, 10, 64)
+		case "RHLISOSTATUS":
+			ret.Supported = value == "1"
+		case "FRAGMENT SUMS":
+			ret.FragmentSums = value
+		case "FRAGMENT COUNT":
+			ret.FragmentCount, err = strconv.Atoi(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid ISO checksum field %q: %w", field, err)
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	return &ret, nil
+}
+
+// CheckISOMD5 verifies the checksum implanted in the ISO as checkisomd5 does
+func CheckISOMD5(iso io.ReaderAt) error {
+	implanted, err := ReadISOMD5(iso)
+	if err != nil {
+		return err
+	}
+	if implanted == nil {
+		return errors.New("the ISO has no implanted checksum")
+	}
+	if implanted.SkipSectors != isoMD5SkipSectors || implanted.FragmentCount != isoMD5FragmentCount {
+		return fmt.Errorf("unsupported ISO checksum with %d skipped sectors and %d fragments", implanted.SkipSectors, implanted.FragmentCount)
+	}
+	computed, err := computeISOMD5(iso)
+	if err != nil {
+		return err
+	}
+	if computed.FragmentSums != implanted.FragmentSums {
+		return errors.New("the ISO fragment checksums don't match")
+	}
+	if computed.MD5 != implanted.MD5 {
+		return fmt.Errorf("the ISO checksum %s doesn't match the implanted one %s", computed.MD5, implanted.MD5)
+	}
+	return nil
+}
+
+// ImplantISOMD5 returns the ISO with the checksum recomputed and implanted as implantisomd5
+// does, keeping the supported flag of the original checksum. The whole ISO is read first.
+func ImplantISOMD5(r ImageReader) (ImageReader, error) {
+	implanted, err := ReadISOMD5(r)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := computeISOMD5(r)
+	if err != nil {
+		return nil, err
+	}
+	checksum.Supported = implanted != nil && implanted.Supported
+	return overlayISOMD5AppData(r, checksum.appData())
+}
+
+// BlankISOMD5 returns the ISO without implanted checksum
+func BlankISOMD5(r ImageReader) (ImageReader, error) {
+	return overlayISOMD5AppData(r, bytes.Repeat([]byte(" "), isoMD5AppDataSize))
+}
+
+func overlayISOMD5AppData(r ImageReader, appData []byte) (ImageReader, error) {
+	pvdOffset, _, err := primaryVolume(r)
+	if err != nil {
+		return nil, err
+	}
+	return overlay.NewOverlayReader(r, overlay.Overlay{
+		Reader: bytes.NewReader(appData),
+		Offset: pvdOffset + isoMD5AppDataOffset,
+		Length: isoMD5AppDataSize,
+	})
+}
+
+// primaryVolume returns the offset of the primary volume descriptor and the size of the volume
+func primaryVolume(iso io.ReaderAt) (int64, int64, error) {
+	vd := make([]byte, isoSectorSize)
+	for lba := int64(isoVolumeDescriptorStart); lba < isoVolumeDescriptorStart+isoMaxVolumeDescriptors; lba++ {
+		if _, err := iso.ReadAt(vd, lba*isoSectorSize); err != nil {
+			return 0, 0, err
+		}
+		if string(vd[1:6]) != "CD001" || vd[0] == isoVolumeDescriptorTerminator {
+			break
+		}
+		if vd[0] == isoVolumeDescriptorPrimary {
+			return lba * isoSectorSize, int64(binary.LittleEndian.Uint32(vd[80:])) * isoSectorSize, nil
+		}
+	}
+	return 0, 0, errors.New("no primary volume descriptor")
+}
+
+// computeISOMD5 computes the checksum of the ISO with a blank application use area, as
+// implantisomd5 does: the sums of the fragments are the first hex digits of the checksum of the
+// data read so far, each time the reading goes past a fragment boundary
+func computeISOMD5(iso io.ReaderAt) (*ISOMD5, error) {
+	pvdOffset, volumeSize, err := primaryVolume(iso)
+	if err != nil {
+		return nil, err
+	}
+	total := volumeSize - isoMD5SkipSectors*isoSectorSize
+	if total <= 0 {
+		return nil, fmt.Errorf("the ISO volume is too small (%d bytes) to be checksummed", volumeSize)
+	}
+	fragmentSize := total / (isoMD5FragmentCount + 1)
+	appDataStart := pvdOffset + isoMD5AppDataOffset
+
+	h := md5.New()
+	buffer := make([]byte, isoMD5BufferSize)
+	var fragmentSums strings.Builder
+	previous := int64(0)
+	for offset := int64(0); offset < total; {
+		chunk := buffer[:min(total-offset, isoMD5BufferSize)]
+		n, err := iso.ReadAt(chunk, offset)
+		if err != nil && !(errors.Is(err, io.EOF) && n == len(chunk)) {
+			return nil, err
+		}
+		for i := max(appDataStart, offset); i < min(appDataStart+isoMD5AppDataSize, offset+int64(n)); i++ {
+			chunk[i-offset] = ' '
+		}
+		h.Write(chunk)
+
+		if fragmentSize > 0 {
+			if current := offset / fragmentSize; current != previous {
+				sum, err := partialSum(h)
+				if err != nil {
+					return nil, err
+				}
+				for _, b := range sum[:isoMD5FragmentSize] {
+					fragmentSums.WriteString(strconv.FormatUint(uint64(b), 16)[:1])
+				}
+				previous = current
+			}
+		}
+		offset += int64(n)
+	}
+	return &ISOMD5{
+		MD5:           hex.EncodeToString(h.Sum(nil)),
+		SkipSectors:   isoMD5SkipSectors,
+		FragmentSums:  fragmentSums.String(),
+		FragmentCount: isoMD5FragmentCount,
+	}, nil
+}
+
+// partialSum returns the checksum of the data written so far to h, which can be written further
+func partialSum(h hash.Hash) ([]byte, error) {
+	state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	clone := md5.New()
+	if err := clone.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		return nil, err
+	}
+	return clone.Sum(nil), nil
+}
+
+// appData returns the application use area holding the checksum, as written by implantisomd5
+func (c *ISOMD5) appData() []byte {
+	status := 0
+	if c.Supported {
+		status = 1
+	}
+	text := fmt.Sprintf("ISO MD5SUM = %s;SKIPSECTORS = %d;RHLISOSTATUS=%d;FRAGMENT SUMS = %s;FRAGMENT COUNT = %d;THIS IS NOT THE SAME AS RUNNING MD5SUM ON THIS ISO!!",
+		c.MD5, c.SkipSectors, status, c.FragmentSums, c.FragmentCount)
+	appData := bytes.Repeat([]byte(" "), isoMD5AppDataSize)
+	// implantisomd5 keeps the last byte blank
+	copy(appData[:isoMD5AppDataSize-1], text)
+	return appData
+}
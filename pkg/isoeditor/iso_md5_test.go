@@ -0,0 +1,126 @@
+package isoeditor
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+var _ = Describe("ISO MD5", func() {
+	var (
+		filesDir string
+		isoFile  string
+	)
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+	})
+
+	openISO := func() ImageReader {
+		f, err := os.Open(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		r, err := overlay.NewMultiOverlayReader(f)
+		Expect(err).NotTo(HaveOccurred())
+		return r
+	}
+
+	// customize overwrites the ignition image, as the stream generators do
+	customize := func(isoPath string, _ *IgnitionContent, _ []byte, _ KernelArguments) (ImageReader, error) {
+		offset, _, err := GetISOFileInfo(ignitionImagePath, isoPath)
+		Expect(err).NotTo(HaveOccurred())
+		return overlay.NewOverlayReader(openISO(), overlay.Overlay{Reader: bytes.NewReader([]byte("custom")), Offset: offset, Length: 6})
+	}
+
+	readAll := func(r ImageReader) []byte {
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		Expect(err).NotTo(HaveOccurred())
+		return data
+	}
+
+	implant := func() {
+		r, err := ImplantISOMD5(openISO())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(isoFile, readAll(r), 0600)).To(Succeed())
+	}
+
+	It("implants the checksum as implantisomd5", func() {
+		implant()
+		data, err := os.ReadFile(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		checksum, err := ReadISOMD5(bytes.NewReader(data))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checksum).NotTo(BeNil())
+		Expect(checksum.SkipSectors).To(Equal(int64(15)))
+		Expect(checksum.FragmentCount).To(Equal(20))
+		Expect(checksum.FragmentSums).To(HaveLen(60))
+		Expect(checksum.Supported).To(BeFalse())
+
+		pvdOffset, volumeSize, err := primaryVolume(bytes.NewReader(data))
+		Expect(err).NotTo(HaveOccurred())
+		appData := pvdOffset + isoMD5AppDataOffset
+		Expect(string(data[appData : appData+13])).To(Equal("ISO MD5SUM = "))
+		blanked := append([]byte{}, data[:volumeSize-15*isoSectorSize]...)
+		copy(blanked[appData:], bytes.Repeat([]byte(" "), isoMD5AppDataSize))
+		sum := md5.Sum(blanked)
+		Expect(checksum.MD5).To(Equal(hex.EncodeToString(sum[:])))
+
+		Expect(CheckISOMD5(bytes.NewReader(data))).To(Succeed())
+	})
+
+	It("recomputes the checksum of customized ISOs", func() {
+		implant()
+		data := readAll(openISO())
+		pvdOffset, _, err := primaryVolume(bytes.NewReader(data))
+		Expect(err).NotTo(HaveOccurred())
+		// keeps the supported flag of the original checksum
+		data = bytes.Replace(data, []byte("RHLISOSTATUS=0"), []byte("RHLISOSTATUS=1"), 1)
+		Expect(os.WriteFile(isoFile, data, 0600)).To(Succeed())
+
+		customized, err := customize(isoFile, nil, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(CheckISOMD5(bytes.NewReader(readAll(customized)))).To(MatchError(ContainSubstring("match")))
+
+		r, err := ISOMD5StreamGenerator(customize, ISOMD5Implant)(isoFile, nil, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		repacked := readAll(r)
+		Expect(CheckISOMD5(bytes.NewReader(repacked))).To(Succeed())
+		checksum, err := ReadISOMD5(bytes.NewReader(repacked))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checksum.Supported).To(BeTrue())
+		Expect(repacked[:pvdOffset]).To(Equal(data[:pvdOffset]))
+	})
+
+	It("blanks the checksum", func() {
+		implant()
+		r, err := ISOMD5StreamGenerator(customize, ISOMD5Blank)(isoFile, nil, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		blanked := readAll(r)
+		checksum, err := ReadISOMD5(bytes.NewReader(blanked))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checksum).To(BeNil())
+		Expect(CheckISOMD5(bytes.NewReader(blanked))).To(MatchError("the ISO has no implanted checksum"))
+	})
+
+	It("parses the modes", func() {
+		for value, mode := range map[string]ISOMD5Mode{"": ISOMD5Keep, "keep": ISOMD5Keep, "Implant": ISOMD5Implant, "blank": ISOMD5Blank} {
+			parsed, err := ParseISOMD5Mode(value)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parsed).To(Equal(mode))
+		}
+		_, err := ParseISOMD5Mode("recompute")
+		Expect(err).To(HaveOccurred())
+	})
+})
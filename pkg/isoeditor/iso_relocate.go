@@ -0,0 +1,423 @@
+package isoeditor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+const (
+	isoSectorSize            = 2048
+	isoVolumeDescriptorStart = 16
+	// limit of volume descriptors read, to avoid looping over a malformed ISO without terminator
+	isoMaxVolumeDescriptors = 64
+	elToritoSystemID        = "EL TORITO SPECIFICATION"
+)
+
+// isoRelocation resizes the extent of a file in an ISO, shifting the sectors that follow it. The
+// structures referencing shifted sectors (volume descriptors, directory records, path tables,
+// Rock Ridge continuation areas, the El Torito catalog and the hybrid partition tables) are
+// patched, and the result is streamed without writing a new image.
+type isoRelocation struct {
+	iso *os.File
+	// sectors of the resized extent in the original ISO
+	start, end int64
+	// new size of the file and change in the number of sectors following the extent
+	length int64
+	delta  int64
+	// patched sectors, by sector of the original ISO
+	sectors map[int64][]byte
+	// directories already patched, by sector
+	visited  map[int64]bool
+	suspSkip int
+}
+
+// newISORelocation prepares the relocation of filePath within isoPath so it is length bytes long
+func newISORelocation(isoPath, filePath string, length int64) (*isoRelocation, error) {
+	offset, fileLength, err := GetISOFileInfo(filePath, isoPath)
+	if err != nil {
+		return nil, err
+	}
+	if offset%isoSectorSize != 0 {
+		return nil, fmt.Errorf("%s doesn't start on a sector boundary", filePath)
+	}
+
+	iso, err := os.Open(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	r := &isoRelocation{
+		iso:     iso,
+		start:   offset / isoSectorSize,
+		end:     (offset + fileLength + isoSectorSize - 1) / isoSectorSize,
+		length:  length,
+		sectors: map[int64][]byte{},
+		visited: map[int64]bool{},
+	}
+	r.delta = (length+isoSectorSize-1)/isoSectorSize - (r.end - r.start)
+
+	if err := r.patch(); err != nil {
+		iso.Close()
+		return nil, fmt.Errorf("failed to relocate %s in %s: %w", filePath, isoPath, err)
+	}
+	return r, nil
+}
+
+// mapOffset returns the offset in the relocated ISO of an offset in the original ISO
+func (r *isoRelocation) mapOffset(offset int64) int64 {
+	if offset >= r.end*isoSectorSize {
+		return offset + r.delta*isoSectorSize
+	}
+	return offset
+}
+
+func (r *isoRelocation) mapLBA(lba uint32) uint32 {
+	if int64(lba) >= r.end {
+		return uint32(int64(lba) + r.delta)
+	}
+	return lba
+}
+
+// reader returns the relocated ISO, with the resized extent filled by content and the given
+// overlays, in offsets of the relocated ISO, applied on top
+func (r *isoRelocation) reader(content []byte, overlays ...overlay.Overlay) (ImageReader, error) {
+	if int64(len(content)) > r.length {
+		return nil, fmt.Errorf("content length (%d) exceeds the file size (%d)", len(content), r.length)
+	}
+	spliced, err := overlay.NewSpliceReader(r.iso, overlay.Splice{
+		Reader: overlay.NewPaddedReader(content, (r.end-r.start+r.delta)*isoSectorSize, 0),
+		Offset: r.start * isoSectorSize,
+		Length: (r.end - r.start) * isoSectorSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for lba, sector := range r.sectors {
+		if lba >= r.start && lba < r.end {
+			spliced.Close()
+			return nil, fmt.Errorf("sector %d of the resized extent is referenced by the ISO structures", lba)
+		}
+		overlays = append(overlays, overlay.Overlay{
+			Reader: bytes.NewReader(sector),
+			Offset: r.mapOffset(lba * isoSectorSize),
+			Length: isoSectorSize,
+		})
+	}
+
+	ret, err := overlay.NewMultiOverlayReader(spliced, overlays...)
+	if err != nil {
+		spliced.Close()
+		return nil, err
+	}
+	if err := CompareSystemAreas(r.iso, ret, spliced.Size()); err != nil {
+		ret.Close()
+		return nil, fmt.Errorf("invalid system area after relocation: %w", err)
+	}
+	return ret, nil
+}
+
+func (r *isoRelocation) Close() error {
+	return r.iso.Close()
+}
+
+// sector returns the content of a sector, patches to the returned slice are kept
+func (r *isoRelocation) sector(lba int64) ([]byte, error) {
+	if s, ok := r.sectors[lba]; ok {
+		return s, nil
+	}
+	s := make([]byte, isoSectorSize)
+	if _, err := r.iso.ReadAt(s, lba*isoSectorSize); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	r.sectors[lba] = s
+	return s, nil
+}
+
+// readExtent returns a copy of the content of the sectors starting at lba, to be stored back with
+// writeExtent
+func (r *isoRelocation) readExtent(lba int64, length int64) ([]byte, error) {
+	data := make([]byte, 0, length)
+	for i := lba; int64(len(data)) < length; i++ {
+		s, err := r.sector(i)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, s[:min(isoSectorSize, length-int64(len(data)))]...)
+	}
+	return data, nil
+}
+
+func (r *isoRelocation) writeExtent(lba int64, data []byte) {
+	for i := 0; i < len(data); i += isoSectorSize {
+		copy(r.sectors[lba+int64(i/isoSectorSize)], data[i:])
+	}
+}
+
+// patchBothEndian maps the sector number stored in both byte orders at the start of b
+func (r *isoRelocation) patchBothEndian(b []byte) {
+	lba := r.mapLBA(binary.LittleEndian.Uint32(b))
+	binary.LittleEndian.PutUint32(b, lba)
+	binary.BigEndian.PutUint32(b[4:], lba)
+}
+
+func (r *isoRelocation) patch() error {
+	if err := r.patchSystemArea(); err != nil {
+		return err
+	}
+
+	for lba := int64(isoVolumeDescriptorStart); lba < isoVolumeDescriptorStart+isoMaxVolumeDescriptors; lba++ {
+		vd, err := r.sector(lba)
+		if err != nil {
+			return err
+		}
+		if string(vd[1:6]) != "CD001" {
+			return fmt.Errorf("invalid volume descriptor at sector %d", lba)
+		}
+		switch vd[0] {
+		case 255:
+			return nil
+		case 0:
+			if string(bytes.TrimRight(vd[7:39], "\x00")) == elToritoSystemID {
+				if err := r.patchBootCatalog(vd); err != nil {
+					return err
+				}
+			}
+		case 1, 2:
+			if err := r.patchVolume(vd); err != nil {
+				return err
+			}
+		}
+	}
+	return errors.New("volume descriptor set terminator not found")
+}
+
+// patchSystemArea updates the partition tables of hybrid ISOs. The backup GPT at the end of the
+// ISO is shifted with the sectors following the extent, it is patched in place.
+func (r *isoRelocation) patchSystemArea() error {
+	info, err := r.iso.Stat()
+	if err != nil {
+		return err
+	}
+	area, err := readSectors(r.sector, 0, isoSystemAreaSize)
+	if err != nil {
+		return err
+	}
+	backup, err := patchSystemArea(area, info.Size()+r.delta*isoSectorSize, r.mapRange)
+	if err != nil {
+		return err
+	}
+	if err := writeSectors(r.sector, 0, area); err != nil {
+		return err
+	}
+	if backup != nil {
+		return writeSectors(r.sector, info.Size()-int64(len(backup)), backup)
+	}
+	return nil
+}
+
+// mapRange maps the partitions following the extent, and resizes the ones containing it
+func (r *isoRelocation) mapRange(start, count int64) (int64, int64) {
+	switch {
+	case start >= r.end*blocksPerSector:
+		return start + r.delta*blocksPerSector, count
+	case start < r.start*blocksPerSector && start+count >= r.end*blocksPerSector:
+		return start, count + r.delta*blocksPerSector
+	}
+	return start, count
+}
+
+func (r *isoRelocation) patchBootCatalog(vd []byte) error {
+	catalogLBA := binary.LittleEndian.Uint32(vd[71:])
+	binary.LittleEndian.PutUint32(vd[71:], r.mapLBA(catalogLBA))
+
+	catalog, err := r.sector(int64(catalogLBA))
+	if err != nil {
+		return err
+	}
+	// the first entry is the validation entry, boot entries follow, possibly after section headers
+	for i := 32; i < isoSectorSize; i += 32 {
+		entry := catalog[i : i+32]
+		if entry[0] == 0x88 || entry[0] == 0x00 {
+			binary.LittleEndian.PutUint32(entry[8:], r.mapLBA(binary.LittleEndian.Uint32(entry[8:])))
+		}
+	}
+	return nil
+}
+
+func (r *isoRelocation) patchVolume(vd []byte) error {
+	size := int64(binary.LittleEndian.Uint32(vd[80:])) + r.delta
+	binary.LittleEndian.PutUint32(vd[80:], uint32(size))
+	binary.BigEndian.PutUint32(vd[84:], uint32(size))
+
+	root := vd[156:190]
+	rootLBA := int64(binary.LittleEndian.Uint32(root[2:]))
+	rootLength := int64(binary.LittleEndian.Uint32(root[10:]))
+	r.patchBothEndian(root[2:])
+	if err := r.patchDirectory(rootLBA, rootLength); err != nil {
+		return err
+	}
+
+	pathTableLength := int64(binary.LittleEndian.Uint32(vd[132:]))
+	for _, pt := range []struct {
+		offset int
+		order  binary.ByteOrder
+	}{{140, binary.LittleEndian}, {144, binary.LittleEndian}, {148, binary.BigEndian}, {152, binary.BigEndian}} {
+		lba := pt.order.Uint32(vd[pt.offset:])
+		if lba == 0 {
+			continue
+		}
+		pt.order.PutUint32(vd[pt.offset:], r.mapLBA(lba))
+		if err := r.patchPathTable(int64(lba), pathTableLength, pt.order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *isoRelocation) patchPathTable(lba, length int64, order binary.ByteOrder) error {
+	data, err := r.readExtent(lba, length)
+	if err != nil {
+		return err
+	}
+	for pos := 0; pos+8 <= len(data); {
+		nameLength := int(data[pos])
+		if nameLength == 0 {
+			break
+		}
+		order.PutUint32(data[pos+2:], r.mapLBA(order.Uint32(data[pos+2:])))
+		pos += 8 + nameLength + nameLength%2
+	}
+	r.writeExtent(lba, data)
+	return nil
+}
+
+func (r *isoRelocation) patchDirectory(lba, length int64) error {
+	if r.visited[lba] {
+		return nil
+	}
+	r.visited[lba] = true
+
+	data, err := r.readExtent(lba, length)
+	if err != nil {
+		return err
+	}
+	var subdirs [][2]int64
+	// bytes of the resized file left for the following extents of multi-extent files, or -1
+	resizedLeft, resizedLBA := int64(-1), r.start
+	for pos := 0; pos < len(data); {
+		recordLength := int(data[pos])
+		if recordLength == 0 {
+			// records don't cross sector boundaries, the rest of the sector is padding
+			pos = (pos/isoSectorSize + 1) * isoSectorSize
+			continue
+		}
+		if recordLength < 34 || pos+recordLength > len(data) {
+			return fmt.Errorf("invalid directory record in sector %d", lba+int64(pos/isoSectorSize))
+		}
+		record := data[pos : pos+recordLength]
+		pos += recordLength
+
+		extent := int64(binary.LittleEndian.Uint32(record[2:]))
+		extentLength := int64(binary.LittleEndian.Uint32(record[10:]))
+		isDir := record[25]&0x02 != 0
+		nameLength := int(record[32])
+		dotEntry := nameLength == 1 && record[33] <= 1
+
+		r.patchBothEndian(record[2:])
+		if !isDir && (resizedLeft >= 0 || (extent == r.start && extentLength > 0)) {
+			if resizedLeft < 0 {
+				resizedLeft = r.length
+			}
+			// the extents of multi-extent files keep their length but the last one
+			length := resizedLeft
+			if record[25]&isoRecordFlagMultiExtent != 0 {
+				length = min(resizedLeft, extentLength)
+			} else if length > math.MaxUint32 {
+				return fmt.Errorf("the resized file doesn't fit in the last extent in sector %d", extent)
+			}
+			putBothEndian32(record[2:], uint32(resizedLBA))
+			putBothEndian32(record[10:], uint32(length))
+			resizedLeft -= length
+			resizedLBA += sectorsFor(length)
+			if record[25]&isoRecordFlagMultiExtent == 0 {
+				resizedLeft = -1
+			}
+		}
+		if isDir && !dotEntry {
+			subdirs = append(subdirs, [2]int64{extent, extentLength})
+		}
+
+		systemUse := 33 + nameLength + (1 - nameLength%2)
+		if systemUse < len(record) {
+			if extent == lba && record[33] == 0 && r.isSUSPIndicator(record[systemUse:]) {
+				r.suspSkip = int(record[systemUse+6])
+			} else {
+				systemUse += r.suspSkip
+			}
+			if systemUse < len(record) {
+				if err := r.patchSystemUse(record[systemUse:], 0); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	r.writeExtent(lba, data)
+
+	for _, subdir := range subdirs {
+		if err := r.patchDirectory(subdir[0], subdir[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *isoRelocation) isSUSPIndicator(b []byte) bool {
+	return len(b) >= 7 && string(b[0:2]) == "SP" && b[4] == 0xbe && b[5] == 0xef
+}
+
+// patchSystemUse patches the continuation entries of a SUSP area, following them to patch the
+// continuation areas as well
+func (r *isoRelocation) patchSystemUse(area []byte, depth int) error {
+	if depth > 16 {
+		return errors.New("too many SUSP continuation areas")
+	}
+	for pos := 0; pos+4 <= len(area); {
+		entryLength := int(area[pos+2])
+		if entryLength < 4 || pos+entryLength > len(area) {
+			return nil
+		}
+		entry := area[pos : pos+entryLength]
+		pos += entryLength
+
+		switch string(entry[0:2]) {
+		case "ST":
+			return nil
+		case "CE":
+			if entryLength < 28 {
+				continue
+			}
+			lba := int64(binary.LittleEndian.Uint32(entry[4:]))
+			offset := int(binary.LittleEndian.Uint32(entry[12:]))
+			length := int(binary.LittleEndian.Uint32(entry[20:]))
+			r.patchBothEndian(entry[4:])
+			if offset+length > isoSectorSize {
+				return fmt.Errorf("invalid SUSP continuation area in sector %d", lba)
+			}
+			sector, err := r.sector(lba)
+			if err != nil {
+				return err
+			}
+			if err := r.patchSystemUse(sector[offset:offset+length], depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
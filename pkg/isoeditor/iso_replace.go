@@ -0,0 +1,47 @@
+package isoeditor
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+// ErrContentTooLarge is returned when the new content of a file doesn't fit in its extent in the ISO
+type ErrContentTooLarge struct {
+	File     string
+	Length   int64
+	Capacity int64
+}
+
+func (e *ErrContentTooLarge) Error() string {
+	return fmt.Sprintf("content of %s (%d bytes) exceeds the file size in the ISO (%d bytes) by %d bytes", e.File, e.Length, e.Capacity, e.Shortfall())
+}
+
+// Shortfall returns the number of bytes missing in the ISO to hold the content
+func (e *ErrContentTooLarge) Shortfall() int64 {
+	return e.Length - e.Capacity
+}
+
+// ReplaceFileInISO returns the content of filePath to stream with Apply, padded with pad up to the
+// size of the file in the ISO so that the replaced file keeps its extent. It fails with
+// ErrContentTooLarge when the content doesn't fit.
+func ReplaceFileInISO(isoPath, filePath string, content []byte, pad byte) (FileData, error) {
+	_, length, err := GetISOFileInfo(filePath, isoPath)
+	if err != nil {
+		return FileData{}, err
+	}
+	padded, err := padContent(filePath, content, length, pad)
+	if err != nil {
+		return FileData{}, err
+	}
+	return FileData{Filename: filePath, Data: io.NopCloser(padded)}, nil
+}
+
+// padContent pads content with pad up to capacity bytes
+func padContent(filePath string, content []byte, capacity int64, pad byte) (io.ReadSeeker, error) {
+	if int64(len(content)) > capacity {
+		return nil, &ErrContentTooLarge{File: filePath, Length: int64(len(content)), Capacity: capacity}
+	}
+	return overlay.NewPaddedReader(content, capacity, pad), nil
+}
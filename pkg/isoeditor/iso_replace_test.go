@@ -0,0 +1,68 @@
+package isoeditor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReplaceFileInISO", func() {
+	var (
+		isoFile  string
+		filesDir string
+	)
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+	})
+
+	It("pads the content to the size of the file", func() {
+		_, length, err := GetISOFileInfo(defaultGrubFilePath, isoFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		file, err := ReplaceFileInISO(isoFile, defaultGrubFilePath, []byte("set timeout=5"), '#')
+		Expect(err).NotTo(HaveOccurred())
+		Expect(file.Filename).To(Equal(defaultGrubFilePath))
+
+		r, err := Apply(isoFile, []FileData{file})
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+		output, err := os.CreateTemp(filesDir, "replaced*.iso")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.Copy(output, r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Close()).To(Succeed())
+
+		content, err := ReadFileFromISO(output.Name(), defaultGrubFilePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content).To(HaveLen(int(length)))
+		Expect(content).To(Equal(append([]byte("set timeout=5"), bytes.Repeat([]byte("#"), int(length)-13)...)))
+	})
+
+	It("reports the shortfall when the content doesn't fit", func() {
+		_, length, err := GetISOFileInfo(defaultGrubFilePath, isoFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = ReplaceFileInISO(isoFile, defaultGrubFilePath, make([]byte, length+10), 0)
+		var tooLarge *ErrContentTooLarge
+		Expect(errors.As(err, &tooLarge)).To(BeTrue())
+		Expect(tooLarge.File).To(Equal(defaultGrubFilePath))
+		Expect(tooLarge.Capacity).To(Equal(length))
+		Expect(tooLarge.Shortfall()).To(Equal(int64(10)))
+		Expect(err).To(MatchError(ContainSubstring("by 10 bytes")))
+	})
+
+	It("fails for missing files", func() {
+		_, err := ReplaceFileInISO(isoFile, "/missing.cfg", []byte("x"), 0)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,824 @@
+package isoeditor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+)
+
+const (
+	// Rock Ridge modes of the files and directories added to the ISO
+	isoWriterFileMode = 0o100444
+	isoWriterDirMode  = 0o40555
+	// length of the ISO9660 identifiers generated for added files, without version
+	isoMaxIdentifierLength = 30
+)
+
+// ISOWriter rebuilds an ISO with added, replaced or resized files, without external tools and
+// without writing the result to disk. The original sectors are kept in place: content that fits in
+// the extent of the file it replaces is written over it, the rest is appended after the end of the
+// image. The directories, path tables, volume descriptors, the El Torito boot catalog and the
+// hybrid partition tables are patched accordingly. Directories that outgrow their extent are moved
+// to the appended area, after the files they list, which readers streaming the image in a single
+// pass don't support.
+type ISOWriter struct {
+	isoPath string
+	img     *isoImage
+	// patched sectors, by sector
+	sectors map[int64][]byte
+	// content of the extents of the replaced and added files, by sector
+	extents map[int64]*isoExtent
+	// original sector of the extents moved by ReplaceFile, by current sector
+	origins map[int64]int64
+	// replaced extents of the original ISO, by original sector
+	replaced map[int64]replacedExtent
+	// directories to be written, and whether the path tables need to be regenerated
+	dirtyDirs       map[*isoDirectory]bool
+	dirtyPathTables bool
+	// first sector of the ISO and of the appended area that isn't allocated
+	next     int64
+	appended int64
+	// next Rock Ridge file serial number, which readers use as inode number
+	serial uint32
+	done   bool
+}
+
+type isoExtent struct {
+	content io.ReadSeeker
+	length  int64
+	// sectors allocated to the extent
+	sectors int64
+}
+
+type replacedExtent struct {
+	lba       int64
+	oldLength int64
+	length    int64
+}
+
+// NewISOWriter parses isoPath in order to modify it
+func NewISOWriter(isoPath string) (*ISOWriter, error) {
+	img, err := openISOImage(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	// the appended area starts after the end of the file, so that data following the volume
+	// (e.g. partitions of hybrid ISOs) is kept
+	next := max(img.volumeSize, sectorsFor(img.size))
+	w := &ISOWriter{
+		isoPath:   isoPath,
+		img:       img,
+		sectors:   map[int64][]byte{},
+		extents:   map[int64]*isoExtent{},
+		origins:   map[int64]int64{},
+		replaced:  map[int64]replacedExtent{},
+		dirtyDirs: map[*isoDirectory]bool{},
+		next:      next,
+		appended:  next,
+	}
+	w.serial = w.maxSerial(img.volumes[0].root) + 1
+	return w, nil
+}
+
+// maxSerial returns the highest Rock Ridge file serial number of the records of dir and its
+// subdirectories
+func (w *ISOWriter) maxSerial(dir *isoDirectory) uint32 {
+	var serial uint32
+	for _, record := range dir.records {
+		if px := w.posixEntry(dir.volume, record); len(px) >= 44 {
+			serial = max(serial, binary.LittleEndian.Uint32(px[36:]))
+		}
+		if record.dir != nil && !record.isDot() {
+			serial = max(serial, w.maxSerial(record.dir))
+		}
+	}
+	return serial
+}
+
+func (w *ISOWriter) Close() error {
+	return w.img.Close()
+}
+
+// ReplaceFile replaces the content of filePath. The content is read when reading the ISO returned
+// by Reader, and should implement io.ReaderAt for the ISO to support it.
+func (w *ISOWriter) ReplaceFile(filePath string, content io.ReadSeeker) error {
+	if w.done {
+		return errors.New("the ISO was already written")
+	}
+	length, err := contentLength(content)
+	if err != nil {
+		return err
+	}
+
+	var records []*isoRecord
+	var dirs []*isoDirectory
+	for _, volume := range w.img.volumes {
+		record, dir, err := volume.lookup(filePath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if record.isDir() {
+			return fmt.Errorf("%s is a directory", filePath)
+		}
+		if len(record.parts) > 0 {
+			return fmt.Errorf("%s has multiple extents and can't be replaced", filePath)
+		}
+		records = append(records, record)
+		dirs = append(dirs, dir)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%s: %w", filePath, fs.ErrNotExist)
+	}
+
+	lba, oldLength := records[0].extent(), records[0].dataLength()
+	allocated := sectorsFor(oldLength)
+	if ext, ok := w.extents[lba]; ok {
+		allocated = ext.sectors
+		delete(w.extents, lba)
+	}
+	origin, ok := w.origins[lba]
+	if !ok {
+		origin = lba
+	}
+	delete(w.origins, lba)
+
+	newLBA := lba
+	if allocated == 0 || sectorsFor(length) > allocated || w.extentShared(lba, records) {
+		allocated = sectorsFor(length)
+		newLBA = w.allocate(allocated)
+	}
+	w.extents[newLBA] = &isoExtent{content: content, length: length, sectors: allocated}
+	if origin < w.appended {
+		w.origins[newLBA] = origin
+		replaced, ok := w.replaced[origin]
+		if !ok {
+			replaced.oldLength = oldLength
+		}
+		replaced.lba, replaced.length = newLBA, length
+		w.replaced[origin] = replaced
+	}
+
+	for i, record := range records {
+		record.setExtent(newLBA, length)
+		w.dirtyDirs[dirs[i]] = true
+	}
+	return nil
+}
+
+// AddFile adds filePath to the ISO, creating its parent directories. The content is read when
+// reading the ISO returned by Reader, and should implement io.ReaderAt for the ISO to support it.
+func (w *ISOWriter) AddFile(filePath string, content io.ReadSeeker) error {
+	if w.done {
+		return errors.New("the ISO was already written")
+	}
+	length, err := contentLength(content)
+	if err != nil {
+		return err
+	}
+	for _, volume := range w.img.volumes {
+		if _, _, err := volume.lookup(filePath); err == nil {
+			return fmt.Errorf("%s: %w", filePath, fs.ErrExist)
+		}
+	}
+
+	dirPath, name := path.Split(path.Clean("/" + filePath))
+	if name == "" {
+		return fmt.Errorf("invalid file path %s", filePath)
+	}
+	// the directories are allocated before the content, as some readers expect the directories
+	// to precede the files they list
+	dirs := make([]*isoDirectory, len(w.img.volumes))
+	for i, volume := range w.img.volumes {
+		if dirs[i], err = w.mkdirAll(volume, dirPath); err != nil {
+			return err
+		}
+	}
+	lba := w.allocate(sectorsFor(length))
+	w.extents[lba] = &isoExtent{content: content, length: length, sectors: sectorsFor(length)}
+	serial := w.nextSerial()
+	for _, dir := range dirs {
+		if err := w.addRecord(dir, name, lba, length, false, serial); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reader returns the modified ISO. No changes can be made after calling it.
+func (w *ISOWriter) Reader() (ImageReader, error) {
+	if w.done {
+		return nil, errors.New("the ISO was already written")
+	}
+	w.done = true
+
+	if err := w.writeDirectories(); err != nil {
+		return nil, err
+	}
+	if w.dirtyPathTables {
+		for _, volume := range w.img.volumes {
+			if err := w.writePathTables(volume); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, vd := range w.img.descriptors {
+		switch vd.data[0] {
+		case isoVolumeDescriptorPrimary, isoVolumeDescriptorSupplementary:
+			putBothEndian32(vd.data[80:], uint32(w.next))
+		case isoVolumeDescriptorBoot:
+			if string(bytes.TrimRight(vd.data[7:39], "\x00")) == elToritoSystemID {
+				if err := w.patchBootCatalog(int64(binary.LittleEndian.Uint32(vd.data[71:]))); err != nil {
+					return nil, err
+				}
+			}
+		}
+		w.sectors[vd.lba] = vd.data
+	}
+	if err := w.patchSystemArea(); err != nil {
+		return nil, err
+	}
+	r, err := w.reader()
+	if err != nil {
+		return nil, err
+	}
+	if err := CompareSystemAreas(w.img.file, r, w.size()); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("invalid system area after repacking: %w", err)
+	}
+	return r, nil
+}
+
+// size returns the size of the modified ISO
+func (w *ISOWriter) size() int64 {
+	return max(w.img.size, w.next*isoSectorSize)
+}
+
+func (w *ISOWriter) reader() (ImageReader, error) {
+	length := w.size()
+	var overlays []overlay.Overlay
+	for lba, sector := range w.sectors {
+		overlays = append(overlays, overlay.Overlay{Reader: bytes.NewReader(sector), Offset: lba * isoSectorSize, Length: isoSectorSize})
+	}
+	for lba, ext := range w.extents {
+		offset := lba * isoSectorSize
+		overlays = append(overlays,
+			overlay.Overlay{Reader: ext.content, Offset: offset, Length: ext.length},
+			overlay.Overlay{Reader: zeroReader(ext.sectors*isoSectorSize - ext.length), Offset: offset + ext.length, Length: ext.sectors*isoSectorSize - ext.length},
+		)
+	}
+
+	iso, err := os.Open(w.isoPath)
+	if err != nil {
+		return nil, err
+	}
+	// the appended area is read as zeros where no extent was written
+	base, err := overlay.NewSpliceReader(iso, overlay.Splice{Reader: zeroReader(length - w.img.size), Offset: w.img.size})
+	if err != nil {
+		iso.Close()
+		return nil, err
+	}
+	ret, err := overlay.NewMultiOverlayReader(base, overlays...)
+	if err != nil {
+		base.Close()
+		return nil, err
+	}
+	return ret, nil
+}
+
+// allocate returns the first of count sectors appended to the ISO
+func (w *ISOWriter) allocate(count int64) int64 {
+	lba := w.next
+	w.next += count
+	return lba
+}
+
+// extentShared returns true if records other than the given ones refer to the extent at lba
+func (w *ISOWriter) extentShared(lba int64, records []*isoRecord) bool {
+	var walk func(dir *isoDirectory) bool
+	walk = func(dir *isoDirectory) bool {
+		for _, record := range dir.records {
+			if record.isDot() {
+				continue
+			}
+			if record.dir != nil {
+				if walk(record.dir) {
+					return true
+				}
+				continue
+			}
+			if record.extent() == lba && !containsRecord(records, record) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, volume := range w.img.volumes {
+		if walk(volume.root) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsRecord(records []*isoRecord, record *isoRecord) bool {
+	for _, r := range records {
+		if r == record {
+			return true
+		}
+	}
+	return false
+}
+
+// mkdirAll returns the directory dirPath of the volume, creating it and its parents if needed
+func (w *ISOWriter) mkdirAll(volume *isoVolume, dirPath string) (*isoDirectory, error) {
+	dir := volume.root
+	for _, name := range strings.Split(strings.Trim(dirPath, "/"), "/") {
+		if name == "" {
+			continue
+		}
+		record := dir.find(name)
+		if record == nil {
+			lba := w.allocate(1)
+			if err := w.addRecord(dir, name, lba, isoSectorSize, true, w.nextSerial()); err != nil {
+				return nil, err
+			}
+			record = dir.find(name)
+		}
+		if record.dir == nil {
+			return nil, fmt.Errorf("%s is not a directory", path.Join(dirPath, name))
+		}
+		dir = record.dir
+	}
+	return dir, nil
+}
+
+// addRecord adds the record of a file or a new directory to dir, keeping the records sorted
+func (w *ISOWriter) addRecord(dir *isoDirectory, name string, lba, length int64, isDir bool, serial uint32) error {
+	identifier, err := w.identifier(dir, name, isDir)
+	if err != nil {
+		return err
+	}
+	record, err := w.newRecord(dir, identifier, name, lba, length, isDir, serial)
+	if err != nil {
+		return err
+	}
+	record.name = name
+
+	if isDir {
+		record.dir = &isoDirectory{volume: dir.volume, parent: dir, lba: lba, length: length}
+		var parentSerial uint32
+		if px := w.posixEntry(dir.volume, dir.records[0]); len(px) >= 44 {
+			parentSerial = binary.LittleEndian.Uint32(px[36:])
+		}
+		for i, id := range [][]byte{{0}, {1}} {
+			dot, err := w.newRecord(dir, id, "", lba, length, true, []uint32{serial, parentSerial}[i])
+			if err != nil {
+				return err
+			}
+			record.dir.records = append(record.dir.records, dot)
+		}
+		record.dir.records[1].setExtent(dir.lba, dir.length)
+		w.dirtyDirs[record.dir] = true
+		w.dirtyPathTables = true
+	}
+
+	i := 2
+	for ; i < len(dir.records); i++ {
+		if bytes.Compare(dir.records[i].identifier(), identifier) > 0 {
+			break
+		}
+	}
+	dir.records = append(dir.records[:i], append([]*isoRecord{record}, dir.records[i:]...)...)
+	w.dirtyDirs[dir] = true
+	return nil
+}
+
+// identifier returns the identifier of a new record of dir: the UCS-2 name in Joliet volumes, an
+// unique name made of d-characters otherwise
+func (w *ISOWriter) identifier(dir *isoDirectory, name string, isDir bool) ([]byte, error) {
+	version := ";1"
+	if isDir {
+		version = ""
+	}
+	if dir.volume.joliet {
+		units := utf16.Encode([]rune(name + version))
+		id := make([]byte, 2*len(units))
+		for i, u := range units {
+			binary.BigEndian.PutUint16(id[2*i:], u)
+		}
+		return id, nil
+	}
+
+	mapped := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	base, ext := mapped, ""
+	if i := strings.LastIndexByte(mapped, '.'); i >= 0 && !isDir {
+		base, ext = mapped[:i], mapped[i+1:]
+	}
+	base = strings.ReplaceAll(base, ".", "_")
+	ext = strings.ReplaceAll(ext, ".", "_")
+	if len(ext) > 3 {
+		ext = ext[:3]
+	}
+	if !isDir {
+		ext = "." + ext
+	}
+	for n := 0; ; n++ {
+		suffix := ""
+		if n > 0 {
+			suffix = fmt.Sprintf("~%d", n)
+		}
+		candidate := base
+		if limit := isoMaxIdentifierLength - len(ext) - len(suffix); len(candidate) > limit {
+			candidate = candidate[:limit]
+		}
+		id := []byte(candidate + suffix + ext + version)
+		taken := false
+		for _, record := range dir.records {
+			if bytes.Equal(record.identifier(), id) {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return id, nil
+		}
+	}
+}
+
+// newRecord returns a record of dir with the Rock Ridge entries of the volume
+func (w *ISOWriter) newRecord(dir *isoDirectory, identifier []byte, name string, lba, length int64, isDir bool, serial uint32) (*isoRecord, error) {
+	var systemUse []byte
+	if dir.volume.rockRidge {
+		mode := uint32(isoWriterFileMode)
+		links := uint32(1)
+		if isDir {
+			mode, links = isoWriterDirMode, 2
+		}
+		px := make([]byte, w.posixEntryLength(dir.volume))
+		copy(px, []byte{'P', 'X', byte(len(px)), 1})
+		putBothEndian32(px[4:], mode)
+		putBothEndian32(px[12:], links)
+		if len(px) >= 44 {
+			putBothEndian32(px[36:], serial)
+		}
+		systemUse = append(systemUse, px...)
+		if name != "" {
+			systemUse = append(systemUse, append([]byte{'N', 'M', byte(5 + len(name)), 1, 0}, name...)...)
+		}
+	}
+
+	systemUseStart := 33 + len(identifier)
+	if len(identifier)%2 == 0 {
+		systemUseStart++
+	}
+	recordLength := systemUseStart + len(systemUse)
+	recordLength += recordLength % 2
+	if recordLength > 255 {
+		return nil, fmt.Errorf("name %s is too long", name)
+	}
+	raw := make([]byte, recordLength)
+	raw[0] = byte(recordLength)
+	// the recording date of the directory is used for the new records
+	copy(raw[18:25], dir.records[0].raw[18:25])
+	if isDir {
+		raw[25] = isoRecordFlagDirectory
+	}
+	putBothEndian16(raw[28:], 1)
+	raw[32] = byte(len(identifier))
+	copy(raw[33:], identifier)
+	copy(raw[systemUseStart:], systemUse)
+
+	record := &isoRecord{raw: raw}
+	record.setExtent(lba, length)
+	return record, nil
+}
+
+// posixEntryLength returns the length of the Rock Ridge PX entries of the volume, which depends on
+// the version of the specification
+func (w *ISOWriter) posixEntryLength(volume *isoVolume) int {
+	if px := w.posixEntry(volume, volume.root.records[0]); px != nil {
+		return len(px)
+	}
+	return 36
+}
+
+// posixEntry returns the Rock Ridge PX entry of the record, if any
+func (w *ISOWriter) posixEntry(volume *isoVolume, record *isoRecord) suspEntry {
+	if !volume.rockRidge {
+		return nil
+	}
+	entries, err := w.img.systemUseEntries(volume, record)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.signature() == "PX" && len(entry) >= 36 {
+			return entry
+		}
+	}
+	return nil
+}
+
+func (w *ISOWriter) nextSerial() uint32 {
+	w.serial++
+	return w.serial - 1
+}
+
+// writeDirectories resizes the modified directories, relocating the ones that outgrew their
+// extent, then writes them
+func (w *ISOWriter) writeDirectories() error {
+	for relocated := true; relocated; {
+		relocated = false
+		for dir := range w.dirtyDirs {
+			needed := directorySectors(dir)
+			if needed*isoSectorSize == dir.length {
+				continue
+			}
+			grown := needed > sectorsFor(dir.length)
+			dir.length = needed * isoSectorSize
+			if grown {
+				w.moveDirectory(dir)
+			} else {
+				w.relocateDirectory(dir)
+			}
+			relocated = true
+		}
+	}
+
+	for dir := range w.dirtyDirs {
+		data := make([]byte, sectorsFor(dir.length)*isoSectorSize)
+		pos := 0
+		for _, record := range dir.records {
+			if pos%isoSectorSize+len(record.raw) > isoSectorSize {
+				pos = (pos/isoSectorSize + 1) * isoSectorSize
+			}
+			pos += copy(data[pos:], record.raw)
+		}
+		for i := int64(0); i < sectorsFor(dir.length); i++ {
+			w.sectors[dir.lba+i] = data[i*isoSectorSize : (i+1)*isoSectorSize]
+		}
+	}
+	return nil
+}
+
+// moveDirectory moves a directory and its subdirectories to the appended area, as some readers
+// expect directories to precede their subdirectories. Files are kept in place.
+func (w *ISOWriter) moveDirectory(dir *isoDirectory) {
+	dir.lba = w.allocate(sectorsFor(dir.length))
+	w.relocateDirectory(dir)
+	for _, record := range dir.records {
+		if record.dir != nil && !record.isDot() {
+			w.moveDirectory(record.dir)
+		}
+	}
+}
+
+// relocateDirectory updates the records referring to a directory resized or moved to another
+// extent
+func (w *ISOWriter) relocateDirectory(dir *isoDirectory) {
+	dir.records[0].setExtent(dir.lba, dir.length)
+	if dir.parent == nil {
+		dir.records[1].setExtent(dir.lba, dir.length)
+		root := &isoRecord{raw: dir.volume.descriptor.data[156:190]}
+		root.setExtent(dir.lba, dir.length)
+	} else {
+		for _, record := range dir.parent.records {
+			if record.dir == dir {
+				record.setExtent(dir.lba, dir.length)
+			}
+		}
+		w.dirtyDirs[dir.parent] = true
+	}
+	for _, record := range dir.records {
+		if record.dir != nil && !record.isDot() {
+			record.dir.records[1].setExtent(dir.lba, dir.length)
+			w.dirtyDirs[record.dir] = true
+		}
+	}
+	w.dirtyPathTables = true
+}
+
+// directorySectors returns the number of sectors needed by the records of dir, which can't cross
+// sector boundaries
+func directorySectors(dir *isoDirectory) int64 {
+	sectors, pos := int64(1), 0
+	for _, record := range dir.records {
+		if pos+len(record.raw) > isoSectorSize {
+			sectors++
+			pos = 0
+		}
+		pos += len(record.raw)
+	}
+	return sectors
+}
+
+// writePathTables regenerates the path tables of the volume, in place if they still fit
+func (w *ISOWriter) writePathTables(volume *isoVolume) error {
+	var dirs []*isoDirectory
+	parents := map[*isoDirectory]int{}
+	dirs = append(dirs, volume.root)
+	for i := 0; i < len(dirs); i++ {
+		parents[dirs[i]] = i + 1
+		for _, record := range dirs[i].records {
+			if record.dir != nil && !record.isDot() {
+				dirs = append(dirs, record.dir)
+			}
+		}
+	}
+
+	tables := map[binary.ByteOrder][]byte{}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		var table []byte
+		for _, dir := range dirs {
+			identifier := []byte{0}
+			parent := 1
+			if dir.parent != nil {
+				parent = parents[dir.parent]
+				for _, record := range dir.parent.records {
+					if record.dir == dir {
+						identifier = record.identifier()
+					}
+				}
+			}
+			if parent > 0xffff {
+				return errors.New("too many directories for the path table")
+			}
+			entry := make([]byte, 8+len(identifier)+len(identifier)%2)
+			entry[0] = byte(len(identifier))
+			order.PutUint32(entry[2:], uint32(dir.lba))
+			order.PutUint16(entry[6:], uint16(parent))
+			copy(entry[8:], identifier)
+			table = append(table, entry...)
+		}
+		tables[order] = table
+	}
+
+	vd := volume.descriptor.data
+	oldSectors := sectorsFor(int64(binary.LittleEndian.Uint32(vd[132:])))
+	tableLength := len(tables[binary.LittleEndian])
+	sectors := sectorsFor(int64(tableLength))
+	putBothEndian32(vd[132:], uint32(tableLength))
+	for _, location := range []struct {
+		offset   int
+		order    binary.ByteOrder
+		optional bool
+	}{
+		{140, binary.LittleEndian, false},
+		{144, binary.LittleEndian, true},
+		{148, binary.BigEndian, false},
+		{152, binary.BigEndian, true},
+	} {
+		lba := int64(location.order.Uint32(vd[location.offset:]))
+		switch {
+		case location.optional && (lba == 0 || sectors > oldSectors):
+			location.order.PutUint32(vd[location.offset:], 0)
+			continue
+		case sectors > oldSectors:
+			lba = w.allocate(sectors)
+			location.order.PutUint32(vd[location.offset:], uint32(lba))
+		}
+		data := make([]byte, sectors*isoSectorSize)
+		copy(data, tables[location.order])
+		for i := int64(0); i < sectors; i++ {
+			w.sectors[lba+i] = data[i*isoSectorSize : (i+1)*isoSectorSize]
+		}
+	}
+	return nil
+}
+
+// sector returns the content of a sector, patches to the returned slice are kept
+func (w *ISOWriter) sector(lba int64) ([]byte, error) {
+	if s, ok := w.sectors[lba]; ok {
+		return s, nil
+	}
+	// sectors appended to the image are zeroed
+	s := make([]byte, isoSectorSize)
+	if _, err := w.img.file.ReadAt(s, lba*isoSectorSize); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	w.sectors[lba] = s
+	return s, nil
+}
+
+// resizedBlocks returns the number of blocks of a boot image or partition after the file it
+// points to is replaced. Counts are rounded as before, to blocks or to ISO sectors, and capped to
+// limit. Counts not matching the size of the file are kept.
+func resizedBlocks(count int64, replaced replacedExtent, blockSize, limit int64) int64 {
+	for _, unit := range []int64{blockSize, isoSectorSize} {
+		blocks := func(length int64) int64 {
+			return (length + unit - 1) / unit * (unit / blockSize)
+		}
+		if count == min(blocks(replaced.oldLength), limit) {
+			return min(blocks(replaced.length), limit)
+		}
+	}
+	return count
+}
+
+func (w *ISOWriter) patchBootCatalog(lba int64) error {
+	catalog, err := w.sector(lba)
+	if err != nil {
+		return err
+	}
+	for i := 32; i < isoSectorSize; i += 32 {
+		entry := catalog[i : i+32]
+		if entry[0] != bootCatalogBootable && entry[0] != bootCatalogNotBootable {
+			continue
+		}
+		replaced, ok := w.replaced[int64(binary.LittleEndian.Uint32(entry[8:]))]
+		if !ok {
+			continue
+		}
+		binary.LittleEndian.PutUint32(entry[8:], uint32(replaced.lba))
+		count := resizedBlocks(int64(binary.LittleEndian.Uint16(entry[6:])), replaced, 512, math.MaxUint16)
+		binary.LittleEndian.PutUint16(entry[6:], uint16(count))
+	}
+	return nil
+}
+
+// patchSystemArea updates the partition tables of hybrid ISOs: the partitions covering the whole
+// image grow with it, the ones pointing to replaced files follow them. The backup GPT is written
+// at the new end of the image.
+func (w *ISOWriter) patchSystemArea() error {
+	if w.next == w.appended && len(w.replaced) == 0 {
+		return nil
+	}
+	area, err := readSectors(w.sector, 0, isoSystemAreaSize)
+	if err != nil {
+		return err
+	}
+	if length := gptBackupLength(area); length > 0 && w.next > w.appended {
+		w.allocate(sectorsFor(length))
+	}
+
+	oldBlocks := (w.img.size + mbrBlockSize - 1) / mbrBlockSize
+	volumeEnd := w.img.volumeSize * blocksPerSector
+	size := w.size()
+	backup, err := patchSystemArea(area, size, func(start, count int64) (int64, int64) {
+		if replaced, ok := w.replaced[start/blocksPerSector]; ok && start%blocksPerSector == 0 {
+			return replaced.lba * blocksPerSector, resizedBlocks(count, replaced, mbrBlockSize, math.MaxUint32)
+		}
+		if start < volumeEnd && start+count >= volumeEnd {
+			return start, count + size/mbrBlockSize - oldBlocks
+		}
+		return start, count
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeSectors(w.sector, 0, area); err != nil {
+		return err
+	}
+	if backup != nil {
+		return writeSectors(w.sector, size-int64(len(backup)), backup)
+	}
+	return nil
+}
+
+func contentLength(content io.ReadSeeker) (int64, error) {
+	length, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if length > 0xffffffff {
+		return 0, fmt.Errorf("content length (%d) exceeds the maximum file size", length)
+	}
+	_, err = content.Seek(0, io.SeekStart)
+	return length, err
+}
+
+type zeroReaderAt struct{}
+
+func (zeroReaderAt) ReadAt(p []byte, _ int64) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// zeroReader returns a reader of length zeros
+func zeroReader(length int64) *io.SectionReader {
+	return io.NewSectionReader(zeroReaderAt{}, 0, length)
+}
@@ -0,0 +1,192 @@
+package isoeditor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ISOWriter", func() {
+	var (
+		filesDir string
+		isoFile  string
+		outFile  string
+		writer   *ISOWriter
+	)
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+		var err error
+		writer, err = NewISOWriter(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+
+		out, err := os.CreateTemp("", "*written.iso")
+		Expect(err).NotTo(HaveOccurred())
+		outFile = out.Name()
+		Expect(out.Close()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(writer.Close()).To(Succeed())
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+		Expect(os.Remove(outFile)).To(Succeed())
+	})
+
+	write := func() {
+		r, err := writer.Reader()
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+		out, err := os.Create(outFile)
+		Expect(err).NotTo(HaveOccurred())
+		defer out.Close()
+		n, err := io.Copy(out, r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n % isoSectorSize).To(BeZero())
+	}
+
+	expectFile := func(filePath string, content []byte) {
+		data, err := ReadFileFromISO(outFile, filePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(content))
+
+		img, err := openISOImage(outFile)
+		Expect(err).NotTo(HaveOccurred())
+		defer img.Close()
+		for _, volume := range img.volumes {
+			record, _, err := volume.lookup(filePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(record.dataLength()).To(Equal(int64(len(content))))
+			data, err := img.readAt(record.extent()*isoSectorSize, record.dataLength())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(Equal(content))
+		}
+	}
+
+	It("replaces files in place when they fit", func() {
+		offset, _, err := GetISOFileInfo("/images/pxeboot/rootfs.img", isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writer.ReplaceFile("/images/pxeboot/rootfs.img", strings.NewReader("new rootfs"))).To(Succeed())
+		write()
+
+		expectFile("/images/pxeboot/rootfs.img", []byte("new rootfs"))
+		expectFile("/coreos/igninfo.json", []byte(testIgnitionInfo))
+		newOffset, _, err := GetISOFileInfo("/images/pxeboot/rootfs.img", outFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newOffset).To(Equal(offset))
+		info, err := os.Stat(outFile)
+		Expect(err).NotTo(HaveOccurred())
+		original, err := os.Stat(isoFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Size()).To(Equal(original.Size()))
+	})
+
+	It("appends files that outgrow their extent", func() {
+		rootfs := bytes.Repeat([]byte("rootfs"), 2000)
+		Expect(writer.ReplaceFile("/images/pxeboot/rootfs.img", bytes.NewReader([]byte("first")))).To(Succeed())
+		Expect(writer.ReplaceFile("/images/pxeboot/rootfs.img", bytes.NewReader(rootfs))).To(Succeed())
+		Expect(writer.ReplaceFile("/EFI/redhat/grub.cfg", strings.NewReader("grub"))).To(Succeed())
+		write()
+
+		expectFile("/images/pxeboot/rootfs.img", rootfs)
+		expectFile("/EFI/redhat/grub.cfg", []byte("grub"))
+		expectFile("/isolinux/isolinux.cfg", []byte(testISOLinuxConfig))
+
+		img, err := openISOImage(outFile)
+		Expect(err).NotTo(HaveOccurred())
+		defer img.Close()
+		info, err := os.Stat(outFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(img.volumeSize * isoSectorSize).To(Equal(info.Size()))
+	})
+
+	It("adds files and directories", func() {
+		Expect(writer.AddFile("/images/extra.img", strings.NewReader("extra"))).To(Succeed())
+		Expect(writer.AddFile("/new/nested/Long-File.Name.conf", strings.NewReader("nested"))).To(Succeed())
+		Expect(writer.AddFile("/new/empty", strings.NewReader(""))).To(Succeed())
+		write()
+
+		expectFile("/images/extra.img", []byte("extra"))
+		expectFile("/new/nested/Long-File.Name.conf", []byte("nested"))
+		expectFile("/new/empty", []byte{})
+		expectFile("/images/ignition.img", make([]byte, ignitionPaddingLength))
+
+		img, err := openISOImage(outFile)
+		Expect(err).NotTo(HaveOccurred())
+		defer img.Close()
+		record, _, err := img.volumes[0].lookup("/new/nested/Long-File.Name.conf")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(record.identifier())).To(Equal("LONG_FILE_NAME.CON;1"))
+	})
+
+	It("writes the ISO to a writer", func() {
+		Expect(writer.AddFile("/custom/manifests.tar", strings.NewReader("manifests"))).To(Succeed())
+		out, err := os.Create(outFile)
+		Expect(err).NotTo(HaveOccurred())
+		n, err := writer.WriteTo(out)
+		Expect(out.Close()).To(Succeed())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(writer.size()))
+
+		expectFile("/custom/manifests.tar", []byte("manifests"))
+		_, err = writer.WriteTo(io.Discard)
+		Expect(err).To(MatchError("the ISO was already written"))
+	})
+
+	It("adds a file with AddFileToISO", func() {
+		manifests := bytes.Repeat([]byte("manifest"), 100000)
+		r, err := AddFileToISO(isoFile, "/custom/manifests.tar", bytes.NewReader(manifests))
+		Expect(err).NotTo(HaveOccurred())
+		out, err := os.Create(outFile)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.Copy(out, r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.Close()).To(Succeed())
+		Expect(r.Close()).To(Succeed())
+
+		expectFile("/custom/manifests.tar", manifests)
+		expectFile("/images/pxeboot/rootfs.img", []byte("this is rootfs"))
+
+		_, err = AddFileToISO(isoFile, "/images/ignition.img", strings.NewReader("x"))
+		Expect(err).To(MatchError(fs.ErrExist))
+	})
+
+	It("relocates the directories that outgrow their extent", func() {
+		var names []string
+		for i := 0; i < 100; i++ {
+			name := fmt.Sprintf("/images/file-with-a-long-name-%03d.img", i)
+			Expect(writer.AddFile(name, strings.NewReader(name))).To(Succeed())
+			names = append(names, name)
+		}
+		write()
+
+		for _, name := range names {
+			expectFile(name, []byte(name))
+		}
+		expectFile("/images/pxeboot/rootfs.img", []byte("this is rootfs"))
+
+		img, err := openISOImage(outFile)
+		Expect(err).NotTo(HaveOccurred())
+		defer img.Close()
+		images, _, err := img.volumes[0].lookup("/images")
+		Expect(err).NotTo(HaveOccurred())
+		pxeboot, _, err := img.volumes[0].lookup("/images/pxeboot")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pxeboot.extent()).To(BeNumerically(">", images.extent()))
+	})
+
+	It("fails to add existing files and to replace missing ones", func() {
+		Expect(writer.AddFile("/images/ignition.img", strings.NewReader("x"))).To(MatchError(fs.ErrExist))
+		Expect(writer.ReplaceFile("/images/missing.img", strings.NewReader("x"))).To(MatchError(fs.ErrNotExist))
+		Expect(writer.ReplaceFile("/images", strings.NewReader("x"))).To(MatchError(ContainSubstring("is a directory")))
+		write()
+		_, err := writer.Reader()
+		Expect(err).To(MatchError("the ISO was already written"))
+	})
+})
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
@@ -80,6 +81,26 @@ func createTestFiles(volumeID string) (string, string) {
 	return filesDir, isoFile
 }
 
+// createFullTestFiles creates an ISO that resembles a full ISO, with the given rootfs, no ramdisk
+// placeholder and an embed area large enough for the minimal ISO edits
+func createFullTestFiles(volumeID string, rootfs []byte) (string, string) {
+	filesDir, isoFile := createTestFiles(volumeID)
+
+	padding := strings.Repeat("#", 1024)
+	grub := strings.Replace(testGrubConfig, "######################", padding, 1)
+	isolinux := strings.Replace(testISOLinuxConfig, "######################", padding, 1)
+	Expect(os.WriteFile(filepath.Join(filesDir, "EFI/redhat/grub.cfg"), []byte(grub), 0600)).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "isolinux/isolinux.cfg"), []byte(isolinux), 0600)).To(Succeed())
+	Expect(os.Remove(filepath.Join(filesDir, "images/assisted_installer_custom.img"))).To(Succeed())
+	Expect(os.WriteFile(filepath.Join(filesDir, "images/pxeboot/rootfs.img"), rootfs, 0600)).To(Succeed())
+
+	Expect(os.Remove(isoFile)).To(Succeed())
+	cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", volumeID, "-o", isoFile, filesDir)
+	Expect(cmd.Run()).To(Succeed())
+
+	return filesDir, isoFile
+}
+
 // createS390TestFiles creates an ISO that resembles the ones used for the S390 architecture, in
 // particular it contains a '/coreos/inginfo.json' file that indicates that the ignition is
 // embedded in the '/images/cdboot.img' file instead of the usual location '/images/ignition.img'.
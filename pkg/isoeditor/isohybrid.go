@@ -0,0 +1,341 @@
+package isoeditor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+const (
+	// the system area is the first 16 sectors of the ISO, not used by ISO9660. Hybrid ISOs keep
+	// their boot code and partition tables there so they can be written to USB drives.
+	isoSystemAreaSize = isoVolumeDescriptorStart * isoSectorSize
+	mbrBlockSize      = 512
+	blocksPerSector   = isoSectorSize / mbrBlockSize
+	gptSignature      = "EFI PART"
+	apmDriverSig      = "ER"
+	apmEntrySig       = "PM"
+)
+
+// blockMapper returns the start and the count of a partition of the original ISO in the repacked
+// one, in 512 bytes blocks
+type blockMapper func(start, count int64) (int64, int64)
+
+// sectorFunc returns a sector of an ISO being edited, changes to the returned slice are kept
+type sectorFunc func(lba int64) ([]byte, error)
+
+func readSectors(sector sectorFunc, offset, length int64) ([]byte, error) {
+	data := make([]byte, 0, length)
+	for pos := offset; pos < offset+length; {
+		s, err := sector(pos / isoSectorSize)
+		if err != nil {
+			return nil, err
+		}
+		n := min(isoSectorSize-pos%isoSectorSize, offset+length-pos)
+		data = append(data, s[pos%isoSectorSize:pos%isoSectorSize+n]...)
+		pos += n
+	}
+	return data, nil
+}
+
+func writeSectors(sector sectorFunc, offset int64, data []byte) error {
+	for pos := int64(0); pos < int64(len(data)); {
+		s, err := sector((offset + pos) / isoSectorSize)
+		if err != nil {
+			return err
+		}
+		pos += int64(copy(s[(offset+pos)%isoSectorSize:], data[pos:]))
+	}
+	return nil
+}
+
+func hasMBR(area []byte) bool {
+	return area[510] == 0x55 && area[511] == 0xaa
+}
+
+func hasGPT(area []byte) bool {
+	return hasMBR(area) && string(area[mbrBlockSize:mbrBlockSize+8]) == gptSignature
+}
+
+func hasAPM(area []byte) bool {
+	return string(area[0:2]) == apmDriverSig && apmBlockSize(area) > 0
+}
+
+func apmBlockSize(area []byte) int64 {
+	size := int64(binary.BigEndian.Uint16(area[2:]))
+	if size < mbrBlockSize || size%mbrBlockSize != 0 || size > isoSectorSize {
+		return 0
+	}
+	return size
+}
+
+// gptBackupLength returns the length of the backup GPT, made of the partition entries followed
+// by the header, or 0 if the system area has no GPT
+func gptBackupLength(area []byte) int64 {
+	if !hasGPT(area) {
+		return 0
+	}
+	header := area[mbrBlockSize : 2*mbrBlockSize]
+	length := int64(binary.LittleEndian.Uint32(header[80:])) * int64(binary.LittleEndian.Uint32(header[84:]))
+	return (length+mbrBlockSize-1)/mbrBlockSize*mbrBlockSize + mbrBlockSize
+}
+
+// patchSystemArea updates the partition tables of hybrid ISOs (MBR, GPT and APM) for the repacked
+// image of size bytes. It returns the backup GPT to be written at the end of the image, if any.
+func patchSystemArea(area []byte, size int64, mapRange blockMapper) ([]byte, error) {
+	if hasAPM(area) {
+		patchAPM(area, size, mapRange)
+	}
+	if !hasMBR(area) {
+		return nil, nil
+	}
+	for i := 0; i < 4; i++ {
+		// isohybrid marks the partition of the whole image with type 0
+		p := area[446+16*i : 446+16*(i+1)]
+		if bytes.Equal(p, make([]byte, 16)) {
+			continue
+		}
+		start, count := mapRange(int64(binary.LittleEndian.Uint32(p[8:])), int64(binary.LittleEndian.Uint32(p[12:])))
+		binary.LittleEndian.PutUint32(p[8:], uint32(start))
+		binary.LittleEndian.PutUint32(p[12:], uint32(min(count, size/mbrBlockSize-start, math.MaxUint32)))
+	}
+	if !hasGPT(area) {
+		return nil, nil
+	}
+	return patchGPT(area, size, mapRange)
+}
+
+func patchAPM(area []byte, size int64, mapRange blockMapper) {
+	blockSize := apmBlockSize(area)
+	factor := blockSize / mbrBlockSize
+	binary.BigEndian.PutUint32(area[4:], uint32(size/blockSize))
+	for offset := blockSize; offset+blockSize <= int64(len(area)); offset += blockSize {
+		entry := area[offset : offset+blockSize]
+		if string(entry[0:2]) != apmEntrySig {
+			break
+		}
+		oldCount := binary.BigEndian.Uint32(entry[12:])
+		start, count := mapRange(int64(binary.BigEndian.Uint32(entry[8:]))*factor, int64(oldCount)*factor)
+		count = min((count+factor-1)/factor, size/blockSize-start/factor)
+		binary.BigEndian.PutUint32(entry[8:], uint32(start/factor))
+		binary.BigEndian.PutUint32(entry[12:], uint32(count))
+		if binary.BigEndian.Uint32(entry[84:]) == oldCount {
+			binary.BigEndian.PutUint32(entry[84:], uint32(count))
+		}
+	}
+}
+
+func patchGPT(area []byte, size int64, mapRange blockMapper) ([]byte, error) {
+	header := area[mbrBlockSize : 2*mbrBlockSize]
+	headerSize := int(binary.LittleEndian.Uint32(header[12:]))
+	entriesStart := int64(binary.LittleEndian.Uint64(header[72:])) * mbrBlockSize
+	entrySize := int64(binary.LittleEndian.Uint32(header[84:]))
+	entriesLength := int64(binary.LittleEndian.Uint32(header[80:])) * entrySize
+	if headerSize < 92 || headerSize > mbrBlockSize || entrySize < 128 || entriesStart < 2*mbrBlockSize || entriesStart+entriesLength > int64(len(area)) {
+		return nil, errors.New("the GPT partition entries aren't in the system area")
+	}
+	entries := area[entriesStart : entriesStart+entriesLength]
+
+	oldBlocks := int64(binary.LittleEndian.Uint64(header[32:])) + 1
+	oldLastUsable := int64(binary.LittleEndian.Uint64(header[48:]))
+	blocks := size / mbrBlockSize
+	backup := make([]byte, gptBackupLength(area))
+	entryBlocks := int64(len(backup))/mbrBlockSize - 1
+	lastUsable := blocks - 2 - entryBlocks
+
+	for pos := int64(0); pos < entriesLength; pos += entrySize {
+		entry := entries[pos : pos+entrySize]
+		if bytes.Equal(entry[:16], make([]byte, 16)) {
+			continue
+		}
+		first := int64(binary.LittleEndian.Uint64(entry[32:]))
+		last := int64(binary.LittleEndian.Uint64(entry[40:]))
+		count := last - first + 1
+		// partitions up to the end of the usable space keep covering it
+		if last >= oldLastUsable {
+			count = oldBlocks - first
+		}
+		first, count = mapRange(first, count)
+		binary.LittleEndian.PutUint64(entry[32:], uint64(first))
+		binary.LittleEndian.PutUint64(entry[40:], uint64(min(first+count-1, lastUsable)))
+	}
+
+	binary.LittleEndian.PutUint64(header[32:], uint64(blocks-1))
+	binary.LittleEndian.PutUint64(header[48:], uint64(lastUsable))
+	setGPTChecksums(header, entries)
+
+	copy(backup, entries)
+	backupHeader := backup[entryBlocks*mbrBlockSize:]
+	copy(backupHeader, header[:headerSize])
+	binary.LittleEndian.PutUint64(backupHeader[24:], uint64(blocks-1))
+	binary.LittleEndian.PutUint64(backupHeader[32:], 1)
+	binary.LittleEndian.PutUint64(backupHeader[72:], uint64(blocks-1-entryBlocks))
+	setGPTChecksums(backupHeader, entries)
+	return backup, nil
+}
+
+func setGPTChecksums(header, entries []byte) {
+	binary.LittleEndian.PutUint32(header[88:], crc32.ChecksumIEEE(entries))
+	binary.LittleEndian.PutUint32(header[16:], 0)
+	binary.LittleEndian.PutUint32(header[16:], crc32.ChecksumIEEE(header[:binary.LittleEndian.Uint32(header[12:])]))
+}
+
+// CompareSystemAreas checks that a repacked ISO of repackedSize bytes carries over the system area
+// of the original ISO, so it can still be written to USB drives: the boot code must be unchanged,
+// and the MBR, GPT and APM partitions must be the same ones, within the repacked ISO. The GPT
+// headers and their backup at the end of the ISO must be valid.
+func CompareSystemAreas(original, repacked io.ReaderAt, repackedSize int64) error {
+	before := make([]byte, isoSystemAreaSize)
+	if _, err := original.ReadAt(before, 0); err != nil {
+		return fmt.Errorf("failed to read the original system area: %w", err)
+	}
+	after := make([]byte, isoSystemAreaSize)
+	if _, err := repacked.ReadAt(after, 0); err != nil {
+		return fmt.Errorf("failed to read the repacked system area: %w", err)
+	}
+
+	// the partition tables can change, the rest of the system area must be identical
+	mask := make([]bool, isoSystemAreaSize)
+	maskRange := func(start, end int64) {
+		for i := start; i < end && i < int64(len(mask)); i++ {
+			mask[i] = true
+		}
+	}
+	if hasAPM(before) {
+		if !hasAPM(after) {
+			return errors.New("the Apple partition map was removed")
+		}
+		maskRange(4, 8)
+		blockSize := apmBlockSize(before)
+		for offset := blockSize; offset+blockSize <= isoSystemAreaSize && string(before[offset:offset+2]) == apmEntrySig; offset += blockSize {
+			maskRange(offset+8, offset+16)
+			maskRange(offset+84, offset+88)
+		}
+	}
+	if hasMBR(before) {
+		if !hasMBR(after) {
+			return errors.New("the MBR was removed")
+		}
+		maskRange(446, 510)
+	}
+	if hasGPT(before) {
+		if !hasGPT(after) {
+			return errors.New("the GPT was removed")
+		}
+		maskRange(mbrBlockSize, 2*mbrBlockSize)
+		header := before[mbrBlockSize:]
+		entriesStart := int64(binary.LittleEndian.Uint64(header[72:])) * mbrBlockSize
+		maskRange(entriesStart, entriesStart+int64(binary.LittleEndian.Uint32(header[80:]))*int64(binary.LittleEndian.Uint32(header[84:])))
+	}
+	for i := range before {
+		if !mask[i] && before[i] != after[i] {
+			return fmt.Errorf("the system area differs at offset %d", i)
+		}
+	}
+
+	blocks := repackedSize / mbrBlockSize
+	if hasAPM(before) {
+		blockSize := apmBlockSize(before)
+		for offset := blockSize; offset+blockSize <= isoSystemAreaSize && string(before[offset:offset+2]) == apmEntrySig; offset += blockSize {
+			entry := after[offset : offset+blockSize]
+			end := int64(binary.BigEndian.Uint32(entry[8:])) + int64(binary.BigEndian.Uint32(entry[12:]))
+			if end*blockSize > repackedSize {
+				return fmt.Errorf("the Apple partition %d is beyond the end of the ISO", offset/blockSize)
+			}
+		}
+	}
+	if hasMBR(before) {
+		for i := 0; i < 4; i++ {
+			p, q := before[446+16*i:446+16*(i+1)], after[446+16*i:446+16*(i+1)]
+			if p[0] != q[0] || p[4] != q[4] {
+				return fmt.Errorf("the MBR partition %d changed", i+1)
+			}
+			if int64(binary.LittleEndian.Uint32(q[8:]))+int64(binary.LittleEndian.Uint32(q[12:])) > blocks {
+				return fmt.Errorf("the MBR partition %d is beyond the end of the ISO", i+1)
+			}
+		}
+	}
+	if hasGPT(before) {
+		return compareGPT(before, repacked, after, blocks)
+	}
+	return nil
+}
+
+func compareGPT(before []byte, repacked io.ReaderAt, after []byte, blocks int64) error {
+	header := after[mbrBlockSize : 2*mbrBlockSize]
+	entries, err := validGPTEntries(header, after, 1)
+	if err != nil {
+		return fmt.Errorf("invalid primary GPT: %w", err)
+	}
+	if backupLBA := int64(binary.LittleEndian.Uint64(header[32:])); backupLBA != blocks-1 {
+		return fmt.Errorf("the backup GPT is at block %d instead of the last block %d", backupLBA, blocks-1)
+	}
+	lastUsable := int64(binary.LittleEndian.Uint64(header[48:]))
+
+	oldHeader := before[mbrBlockSize:]
+	oldEntriesStart := int64(binary.LittleEndian.Uint64(oldHeader[72:])) * mbrBlockSize
+	oldEntries := before[oldEntriesStart : oldEntriesStart+int64(len(entries))]
+	entrySize := int(binary.LittleEndian.Uint32(header[84:]))
+	for pos := 0; pos < len(entries); pos += entrySize {
+		p, q := oldEntries[pos:pos+entrySize], entries[pos:pos+entrySize]
+		// type and unique GUIDs, attributes and names must be unchanged
+		if !bytes.Equal(p[:32], q[:32]) || !bytes.Equal(p[48:], q[48:]) {
+			return fmt.Errorf("the GPT partition %d changed", pos/entrySize+1)
+		}
+		if !bytes.Equal(q[:16], make([]byte, 16)) && int64(binary.LittleEndian.Uint64(q[40:])) > lastUsable {
+			return fmt.Errorf("the GPT partition %d is beyond the usable space", pos/entrySize+1)
+		}
+	}
+
+	backup := make([]byte, gptBackupLength(after))
+	if _, err := repacked.ReadAt(backup, blocks*mbrBlockSize-int64(len(backup))); err != nil {
+		return fmt.Errorf("failed to read the backup GPT: %w", err)
+	}
+	backupHeader := backup[len(backup)-mbrBlockSize:]
+	if string(backupHeader[:8]) != gptSignature {
+		return errors.New("no backup GPT at the end of the ISO")
+	}
+	backupStart := (blocks - int64(len(backup))/mbrBlockSize) * mbrBlockSize
+	backupEntries, err := validGPTEntries(backupHeader, backup, blocks-1)
+	if err != nil {
+		return fmt.Errorf("invalid backup GPT: %w", err)
+	}
+	if int64(binary.LittleEndian.Uint64(backupHeader[72:]))*mbrBlockSize != backupStart || !bytes.Equal(backupEntries, entries) {
+		return errors.New("the backup GPT doesn't match the primary GPT")
+	}
+	return nil
+}
+
+// validGPTEntries checks the checksums of a GPT header located at block lba, and returns its
+// partition entries, which must be at the start of data
+func validGPTEntries(header, data []byte, lba int64) ([]byte, error) {
+	headerSize := binary.LittleEndian.Uint32(header[12:])
+	if headerSize < 92 || headerSize > mbrBlockSize {
+		return nil, fmt.Errorf("invalid header size %d", headerSize)
+	}
+	check := append([]byte{}, header[:headerSize]...)
+	binary.LittleEndian.PutUint32(check[16:], 0)
+	if crc32.ChecksumIEEE(check) != binary.LittleEndian.Uint32(header[16:]) {
+		return nil, errors.New("invalid header checksum")
+	}
+	if int64(binary.LittleEndian.Uint64(header[24:])) != lba {
+		return nil, fmt.Errorf("header not at block %d", lba)
+	}
+	entriesLength := int64(binary.LittleEndian.Uint32(header[80:])) * int64(binary.LittleEndian.Uint32(header[84:]))
+	start := int64(0)
+	if lba == 1 {
+		start = int64(binary.LittleEndian.Uint64(header[72:])) * mbrBlockSize
+	}
+	if start+entriesLength > int64(len(data)) {
+		return nil, errors.New("partition entries out of range")
+	}
+	entries := data[start : start+entriesLength]
+	if crc32.ChecksumIEEE(entries) != binary.LittleEndian.Uint32(header[88:]) {
+		return nil, errors.New("invalid partition entries checksum")
+	}
+	return entries, nil
+}
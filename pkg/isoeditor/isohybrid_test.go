@@ -0,0 +1,262 @@
+package isoeditor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"unicode/utf16"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// makeHybrid adds the partition tables written by isohybrid/xorriso to an ISO: an MBR with a
+// partition of the whole image and an EFI partition on the UEFI boot image, an APM, and a GPT
+// with its backup appended to the image
+func makeHybrid(isoPath string) {
+	efiOffset, efiSize, err := GetISOFileInfo("/images/efiboot.img", isoPath)
+	Expect(err).NotTo(HaveOccurred())
+	f, err := os.OpenFile(isoPath, os.O_RDWR, 0)
+	Expect(err).NotTo(HaveOccurred())
+	defer f.Close()
+	info, err := f.Stat()
+	Expect(err).NotTo(HaveOccurred())
+
+	const entriesLBA, entriesBlocks = 12, 32
+	isoSize := info.Size()
+	blocks := (isoSize + (entriesBlocks+4)*mbrBlockSize) / mbrBlockSize
+	lastUsable := blocks - 2 - entriesBlocks
+	efiStart, efiCount := efiOffset/mbrBlockSize, (efiSize+mbrBlockSize-1)/mbrBlockSize
+
+	area := make([]byte, isoSystemAreaSize)
+	copy(area, apmDriverSig)
+	binary.BigEndian.PutUint16(area[2:], isoSectorSize)
+	binary.BigEndian.PutUint32(area[4:], uint32(blocks/blocksPerSector))
+	for i, p := range []struct {
+		start, count int64
+		name, kind   string
+	}{
+		{1, 2, "Apple", "Apple_partition_map"},
+		{16, blocks/blocksPerSector - 16, "ISO", "Apple_HFS"},
+	} {
+		entry := area[(i+1)*isoSectorSize:]
+		copy(entry, apmEntrySig)
+		binary.BigEndian.PutUint32(entry[4:], 2)
+		binary.BigEndian.PutUint32(entry[8:], uint32(p.start))
+		binary.BigEndian.PutUint32(entry[12:], uint32(p.count))
+		copy(entry[16:], p.name)
+		copy(entry[48:], p.kind)
+		binary.BigEndian.PutUint32(entry[84:], uint32(p.count))
+	}
+
+	for i, p := range []struct {
+		status, kind byte
+		start, count int64
+	}{
+		{0x80, 0x00, 0, blocks},
+		{0x00, 0xef, efiStart, efiCount},
+	} {
+		entry := area[446+16*i:]
+		entry[0], entry[4] = p.status, p.kind
+		binary.LittleEndian.PutUint32(entry[8:], uint32(p.start))
+		binary.LittleEndian.PutUint32(entry[12:], uint32(p.count))
+	}
+	area[510], area[511] = 0x55, 0xaa
+
+	header := area[mbrBlockSize : 2*mbrBlockSize]
+	copy(header, gptSignature)
+	binary.LittleEndian.PutUint32(header[8:], 0x00010000)
+	binary.LittleEndian.PutUint32(header[12:], 92)
+	binary.LittleEndian.PutUint64(header[24:], 1)
+	binary.LittleEndian.PutUint64(header[32:], uint64(blocks-1))
+	binary.LittleEndian.PutUint64(header[40:], 64)
+	binary.LittleEndian.PutUint64(header[48:], uint64(lastUsable))
+	copy(header[56:72], "disk-guid-000001")
+	binary.LittleEndian.PutUint64(header[72:], entriesLBA)
+	binary.LittleEndian.PutUint32(header[80:], 128)
+	binary.LittleEndian.PutUint32(header[84:], 128)
+	entries := area[entriesLBA*mbrBlockSize : (entriesLBA+entriesBlocks)*mbrBlockSize]
+	for i, p := range []struct {
+		kind, id    string
+		first, last int64
+		name        string
+	}{
+		{"basic-data-guid0", "iso-part-guid-01", 64, lastUsable, "ISO9660"},
+		{"efi-system-guid0", "efi-part-guid-02", efiStart, efiStart + efiCount - 1, "EFI"},
+	} {
+		entry := entries[i*128:]
+		copy(entry[0:16], p.kind)
+		copy(entry[16:32], p.id)
+		binary.LittleEndian.PutUint64(entry[32:], uint64(p.first))
+		binary.LittleEndian.PutUint64(entry[40:], uint64(p.last))
+		for j, c := range utf16.Encode([]rune(p.name)) {
+			binary.LittleEndian.PutUint16(entry[56+2*j:], c)
+		}
+	}
+	setGPTChecksums(header, entries)
+
+	backupHeader := append([]byte{}, header...)
+	binary.LittleEndian.PutUint64(backupHeader[24:], uint64(blocks-1))
+	binary.LittleEndian.PutUint64(backupHeader[32:], 1)
+	binary.LittleEndian.PutUint64(backupHeader[72:], uint64(blocks-1-entriesBlocks))
+	setGPTChecksums(backupHeader, entries)
+
+	_, err = f.WriteAt(area, 0)
+	Expect(err).NotTo(HaveOccurred())
+	_, err = f.WriteAt(entries, (blocks-1-entriesBlocks)*mbrBlockSize)
+	Expect(err).NotTo(HaveOccurred())
+	_, err = f.WriteAt(backupHeader, (blocks-1)*mbrBlockSize)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+var _ = Describe("Hybrid ISOs", func() {
+	var (
+		filesDir string
+		isoFile  string
+		hybrid   string
+	)
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+		hybrid = filepath.Join(filesDir, "..", filepath.Base(filesDir)+"-hybrid.iso")
+		Expect(Create(hybrid, filesDir, "Assisted123")).To(Succeed())
+		makeHybrid(hybrid)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+		Expect(os.Remove(hybrid)).To(Succeed())
+	})
+
+	writeISO := func(r ImageReader) string {
+		defer r.Close()
+		out, err := os.CreateTemp("", "*hybrid.iso")
+		Expect(err).NotTo(HaveOccurred())
+		defer out.Close()
+		_, err = io.Copy(out, r)
+		Expect(err).NotTo(HaveOccurred())
+		return out.Name()
+	}
+
+	type partitions struct {
+		size     int64
+		mbr      [][2]int64
+		gpt      [][2]int64
+		apm      [][2]int64
+		apmTotal int64
+	}
+	readPartitions := func(isoPath string) partitions {
+		f, err := os.Open(isoPath)
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+		info, err := f.Stat()
+		Expect(err).NotTo(HaveOccurred())
+		area := make([]byte, isoSystemAreaSize)
+		_, err = f.ReadAt(area, 0)
+		Expect(err).NotTo(HaveOccurred())
+
+		p := partitions{size: info.Size(), apmTotal: int64(binary.BigEndian.Uint32(area[4:]))}
+		for i := 0; i < 2; i++ {
+			entry := area[446+16*i:]
+			p.mbr = append(p.mbr, [2]int64{int64(binary.LittleEndian.Uint32(entry[8:])), int64(binary.LittleEndian.Uint32(entry[12:]))})
+			entry = area[12*mbrBlockSize+128*i:]
+			p.gpt = append(p.gpt, [2]int64{int64(binary.LittleEndian.Uint64(entry[32:])), int64(binary.LittleEndian.Uint64(entry[40:]))})
+			entry = area[(i+1)*isoSectorSize:]
+			p.apm = append(p.apm, [2]int64{int64(binary.BigEndian.Uint32(entry[8:])), int64(binary.BigEndian.Uint32(entry[12:]))})
+		}
+		return p
+	}
+	expectValid := func(original, repacked string) {
+		before, err := os.Open(original)
+		Expect(err).NotTo(HaveOccurred())
+		defer before.Close()
+		after, err := os.Open(repacked)
+		Expect(err).NotTo(HaveOccurred())
+		defer after.Close()
+		info, err := after.Stat()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(CompareSystemAreas(before, after, info.Size())).To(Succeed())
+	}
+
+	It("grows the partitions of the whole image when files are added", func() {
+		before := readPartitions(hybrid)
+		w, err := NewISOWriter(hybrid)
+		Expect(err).NotTo(HaveOccurred())
+		defer w.Close()
+		Expect(w.AddFile("/extra/data.bin", bytes.NewReader(bytes.Repeat([]byte("data"), 100000)))).To(Succeed())
+		r, err := w.Reader()
+		Expect(err).NotTo(HaveOccurred())
+		edited := writeISO(r)
+		defer os.Remove(edited)
+
+		expectValid(hybrid, edited)
+		after := readPartitions(edited)
+		Expect(after.size).To(BeNumerically(">", before.size))
+		blocks := after.size / mbrBlockSize
+		Expect(after.mbr[0]).To(Equal([2]int64{0, blocks}))
+		Expect(after.mbr[1]).To(Equal(before.mbr[1]))
+		Expect(after.gpt[0]).To(Equal([2]int64{64, blocks - 2 - 32}))
+		Expect(after.gpt[1]).To(Equal(before.gpt[1]))
+		Expect(after.apmTotal).To(Equal(after.size / isoSectorSize))
+		Expect(after.apm[0]).To(Equal(before.apm[0]))
+		Expect(after.apm[1][1]).To(BeNumerically(">", before.apm[1][1]))
+
+		content, err := ReadFileFromISO(edited, "/extra/data.bin")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content).To(HaveLen(400000))
+	})
+
+	It("follows the UEFI boot image when it moves", func() {
+		efiboot := bytes.Repeat([]byte("efiboot"), 1500000)
+		r, err := ReplaceEFIBootImage(hybrid, bytes.NewReader(efiboot))
+		Expect(err).NotTo(HaveOccurred())
+		edited := writeISO(r)
+		defer os.Remove(edited)
+
+		expectValid(hybrid, edited)
+		offset, _, err := GetISOFileInfo("/images/efiboot.img", edited)
+		Expect(err).NotTo(HaveOccurred())
+		start, count := offset/mbrBlockSize, int64(len(efiboot)+mbrBlockSize-1)/mbrBlockSize
+		after := readPartitions(edited)
+		Expect(after.mbr[1]).To(Equal([2]int64{start, count}))
+		Expect(after.gpt[1]).To(Equal([2]int64{start, start + count - 1}))
+	})
+
+	It("keeps the partition tables valid when relocating a file", func() {
+		before := readPartitions(hybrid)
+		reloc, err := newISORelocation(hybrid, "/images/pxeboot/rootfs.img", 1<<20)
+		Expect(err).NotTo(HaveOccurred())
+		defer reloc.Close()
+		r, err := reloc.reader([]byte("rootfs"))
+		Expect(err).NotTo(HaveOccurred())
+		edited := writeISO(r)
+		defer os.Remove(edited)
+
+		expectValid(hybrid, edited)
+		after := readPartitions(edited)
+		Expect(after.size).To(Equal(before.size + reloc.delta*isoSectorSize))
+		Expect(after.mbr[0]).To(Equal([2]int64{0, after.size / mbrBlockSize}))
+	})
+
+	It("detects system areas that weren't carried over", func() {
+		original, err := os.ReadFile(hybrid)
+		Expect(err).NotTo(HaveOccurred())
+		size := int64(len(original))
+		compare := func(edit func(data []byte)) error {
+			data := append([]byte{}, original...)
+			edit(data)
+			return CompareSystemAreas(bytes.NewReader(original), bytes.NewReader(data), size)
+		}
+
+		Expect(compare(func([]byte) {})).To(Succeed())
+		Expect(compare(func(data []byte) { data[100] ^= 0xff })).To(MatchError(ContainSubstring("differs at offset 100")))
+		Expect(compare(func(data []byte) { data[446+16+4] = 0x83 })).To(MatchError(ContainSubstring("MBR partition 2 changed")))
+		Expect(compare(func(data []byte) { data[510] = 0 })).To(MatchError(ContainSubstring("MBR was removed")))
+		Expect(compare(func(data []byte) { data[12*mbrBlockSize+56] = 'X' })).To(MatchError(ContainSubstring("invalid primary GPT")))
+		Expect(compare(func(data []byte) { data[size-mbrBlockSize] = 0 })).To(MatchError(ContainSubstring("no backup GPT")))
+		Expect(CompareSystemAreas(bytes.NewReader(original), bytes.NewReader(original), size-isoSectorSize)).To(HaveOccurred())
+	})
+})
@@ -1,10 +1,10 @@
 package isoeditor
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/openshift/assisted-image-service/pkg/overlay"
-	"github.com/sirupsen/logrus"
 )
 
 type FileData struct {
@@ -13,13 +13,15 @@ type FileData struct {
 }
 
 func isolateISOFile(isoPath, file string, data overlay.OverlayReader, minLength int64) (FileData, bool, error) {
-	fileOffset, fileLength, err := GetISOFileInfo(file, isoPath)
+	sections, err := isoFileSections(isoPath, file)
 	if err != nil {
 		return FileData{}, false, err
 	}
-	logrus.Debug("Isolate AIS filePath Phani ", file)
-	logrus.Debug("Isolate AIS fileOffset Phani ", fileOffset)
-	logrus.Debug("Isolate AIS fileLength Phani ", fileLength)
+	if len(sections) > 1 {
+		return isolateISOFileSections(file, data, sections, minLength)
+	}
+	fileOffset, fileLength := sections[0].offset, sections[0].length
+	log.Debugf("Isolating %s at offset %d with length %d", file, fileOffset, fileLength)
 
 	expanded := false
 	if minLength > fileLength {
@@ -27,16 +29,52 @@ func isolateISOFile(isoPath, file string, data overlay.OverlayReader, minLength
 		expanded = true
 	}
 
-	if _, err := data.Seek(fileOffset, io.SeekStart); err != nil {
-		return FileData{}, false, err
-	}
-	fileData := struct {
-		io.Reader
-		io.Closer
-	}{
-		Reader: io.LimitReader(data, fileLength),
-		Closer: data,
+	fileData := &isolatedFile{
+		SectionReader: io.NewSectionReader(data, fileOffset, fileLength),
+		Closer:        data,
 	}
 
 	return FileData{Filename: file, Data: fileData}, expanded, nil
 }
+
+// isolateISOFileSections isolates a multi-extent file whose extents aren't contiguous, reading
+// them as a single file
+func isolateISOFileSections(file string, data overlay.OverlayReader, sections []isoSection, minLength int64) (FileData, bool, error) {
+	var fileLength int64
+	for _, section := range sections {
+		fileLength += section.length
+	}
+	log.Debugf("Isolating %s in %d sections with length %d", file, len(sections), fileLength)
+	if minLength > fileLength {
+		return FileData{}, false, fmt.Errorf("%s is fragmented in the ISO and can't be expanded to %d bytes", file, minLength)
+	}
+	fileData := &isolatedFile{
+		SectionReader: io.NewSectionReader(&sectionsReaderAt{ReaderAt: data, sections: sections}, 0, fileLength),
+		Closer:        data,
+	}
+	return FileData{Filename: file, Data: fileData}, false, nil
+}
+
+// isoFileSections returns the ranges of the ISO holding the content of a file, a single one
+// unless the file has several extents that aren't contiguous
+func isoFileSections(isoPath, file string) ([]isoSection, error) {
+	location, err := locateISOFile(isoPath, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", file, err)
+	}
+	if len(location.sections) > 1 {
+		return location.sections, nil
+	}
+	return []isoSection{{offset: location.offset, length: location.length}}, nil
+}
+
+// isolatedFile is seekable, so that it can be overlaid without being read in memory
+type isolatedFile struct {
+	*io.SectionReader
+	io.Closer
+}
+
+// WriteTo copies the file using a buffer from the overlay pool
+func (f *isolatedFile) WriteTo(w io.Writer) (int64, error) {
+	return overlay.Copy(w, f.SectionReader)
+}
@@ -282,13 +282,17 @@ func VolumeIdentifier(isoPath string) (string, error) {
 		return "", err
 	}
 	defer iso.Close()
+	return VolumeIdentifierReaderAt(iso)
+}
 
+// VolumeIdentifierReaderAt returns the volume identifier of the ISO read from iso
+func VolumeIdentifierReaderAt(iso io.ReaderAt) (string, error) {
 	// Need a method to identify the ISO provided
 	// The first 32768 bytes are unused by the ISO 9660 standard, typically for bootable media
 	// This is where the data area begins and the 32 byte string representing the volume identifier
 	// is offset 40 bytes into the primary volume descriptor
 	volumeId := make([]byte, 32)
-	_, err = iso.ReadAt(volumeId, 32808)
+	_, err := iso.ReadAt(volumeId, 32808)
 	if err != nil {
 		return "", err
 	}
@@ -296,45 +300,55 @@ func VolumeIdentifier(isoPath string) (string, error) {
 	return strings.TrimSpace(string(volumeId)), nil
 }
 
+// GetISOFileInfo returns the offset and the size of a file in the ISO. Paths are resolved with the
+// Rock Ridge names and symbolic links when available, then with the Joliet names.
 func GetISOFileInfo(filePath, isoPath string) (int64, int64, error) {
-	d, err := diskfs.Open(isoPath, diskfs.WithOpenMode(diskfs.ReadOnly))
-	if err != nil {
-		return 0, 0, err
+	if offset, length, ok, err := cachedFileInfo(filePath, isoPath); ok {
+		return offset, length, err
 	}
-
-	fs, err := GetISO9660FileSystem(d)
-	if err != nil {
-		return 0, 0, err
-	}
-
-	fsFile, err := fs.OpenFile(filePath, os.O_RDONLY)
+	location, err := locateISOFile(isoPath, filePath)
 	if err != nil {
 		return 0, 0, errors.Wrapf(err, "Failed to open file %s", filePath)
 	}
+	// multi-extent files with contiguous extents have a single section and are reported as a
+	// whole, the ones fragmented in several sections can't be described by an offset and a size
+	if len(location.sections) > 1 {
+		return 0, 0, errors.Errorf("%s is fragmented in %d sections of the ISO", filePath, len(location.sections))
+	}
+	return location.offset, location.length, nil
+}
+
+// isoFile is a read only file of an ISO
+type isoFile struct {
+	*io.SectionReader
+	iso *os.File
+}
+
+func (f *isoFile) Write(_ []byte) (int, error) {
+	return 0, errors.New("ISO files are read only")
+}
 
-	defer fsFile.Close()
-	isoFile := fsFile.(*iso9660.File)
-	defaultSectorSize := uint32(2 * 1024)
-	return int64(isoFile.Location() * defaultSectorSize), isoFile.Size(), nil
+func (f *isoFile) Close() error {
+	return f.iso.Close()
 }
 
 // Gets a readWrite seeker of a specific file from the ISO image
 func GetFileFromISO(isoPath, filePath string) (filesystem.File, error) {
-	d, err := diskfs.Open(isoPath, diskfs.WithOpenMode(diskfs.ReadOnly))
+	location, err := locateISOFile(isoPath, filePath)
 	if err != nil {
 		return nil, err
 	}
-
-	fs, err := GetISO9660FileSystem(d)
-	if err != nil {
-		return nil, err
+	if location.dir {
+		return nil, fmt.Errorf("%s is a directory", filePath)
 	}
-
-	file, err := fs.OpenFile(filePath, os.O_RDONLY)
+	iso, err := os.Open(isoPath)
 	if err != nil {
 		return nil, err
 	}
-	return file, nil
+	return &isoFile{
+		SectionReader: location.reader(iso),
+		iso:           iso,
+	}, nil
 }
 
 // Reads a whole specific file from the ISO image
@@ -351,15 +365,39 @@ func ReadFileFromISO(isoPath, filePath string) ([]byte, error) {
 	return ret, nil
 }
 
+// ReadFileFromISOReaderAt reads a whole file of the ISO of size bytes read from iso, such as an
+// object of an object storage read with ranged GETs
+func ReadFileFromISOReaderAt(iso io.ReaderAt, size int64, filePath string) ([]byte, error) {
+	img, err := openISOReaderAt(iso, size)
+	if err != nil {
+		return nil, err
+	}
+	record, err := img.lookupVolume().resolve(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if record.isDir() {
+		return nil, fmt.Errorf("%s is a directory", filePath)
+	}
+	return io.ReadAll(record.reader(img.file))
+}
+
 // Gets directly the ISO 9660 filesystem (equivalent to GetFileSystem(0)).
 func GetISO9660FileSystem(d *disk.Disk) (filesystem.FileSystem, error) {
 	return iso9660.Read(d.File, d.Size, 0, 0)
 }
 
 func generateCompressedCPIO(fileContent []byte, filePath string, mode cpio.FileMode) ([]byte, error) {
+	return generateCompressedCPIOWithLevel(fileContent, filePath, mode, gzip.DefaultCompression)
+}
+
+func generateCompressedCPIOWithLevel(fileContent []byte, filePath string, mode cpio.FileMode, level int) ([]byte, error) {
 	// Run gzip compression
 	compressedBuffer := new(bytes.Buffer)
-	gzipWriter := gzip.NewWriter(compressedBuffer)
+	gzipWriter, err := gzip.NewWriterLevel(compressedBuffer, level)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create gzip writer")
+	}
 	// Create CPIO archive
 	cpioWriter := cpio.NewWriter(gzipWriter)
 
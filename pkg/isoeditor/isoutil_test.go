@@ -1,10 +1,12 @@
 package isoeditor
 
 import (
+	"bytes"
 	"encoding/binary"
 	"io"
 	"math"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -355,6 +357,50 @@ var _ = Context("with test files", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(id).To(Equal(volumeID))
 		})
+
+		It("reads any io.ReaderAt", func() {
+			data, err := os.ReadFile(isoFile)
+			Expect(err).ToNot(HaveOccurred())
+			id, err := VolumeIdentifierReaderAt(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(VolumeIdentifier(isoFile)).To(Equal(id))
+		})
+	})
+
+	Describe("GetISOFileInfo", func() {
+		It("resolves Rock Ridge names", func() {
+			longName := "a-file-name-longer-than-the-iso9660-limits.json"
+			Expect(os.WriteFile(filepath.Join(filesDir, "coreos/kargs.json"), []byte(`{"default":""}`), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(filesDir, "coreos", longName), []byte("long"), 0600)).To(Succeed())
+			Expect(os.Remove(isoFile)).To(Succeed())
+			Expect(exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", volumeID, "-o", isoFile, filesDir).Run()).To(Succeed())
+
+			for filePath, content := range map[string]string{
+				"/coreos/kargs.json":           `{"default":""}`,
+				"coreos/" + longName:           "long",
+				"/images/../coreos/kargs.json": `{"default":""}`,
+			} {
+				data, err := ReadFileFromISO(isoFile, filePath)
+				Expect(err).NotTo(HaveOccurred(), filePath)
+				Expect(string(data)).To(Equal(content), filePath)
+				_, size, err := GetISOFileInfo(filePath, isoFile)
+				Expect(err).NotTo(HaveOccurred(), filePath)
+				Expect(size).To(Equal(int64(len(content))), filePath)
+			}
+
+			iso, err := os.ReadFile(isoFile)
+			Expect(err).NotTo(HaveOccurred())
+			data, err := ReadFileFromISOReaderAt(bytes.NewReader(iso), int64(len(iso)), "/coreos/kargs.json")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal(`{"default":""}`))
+
+			_, _, err = GetISOFileInfo("/coreos/KARGS.JSON", isoFile)
+			Expect(err).To(MatchError(os.ErrNotExist))
+			_, err = ReadFileFromISO(isoFile, "/coreos/kargs.json/file")
+			Expect(err).To(MatchError(ContainSubstring("is not a directory")))
+			_, err = ReadFileFromISO(isoFile, "/coreos")
+			Expect(err).To(MatchError(ContainSubstring("is a directory")))
+		})
 	})
 
 	Describe("efiLoadSectors", func() {
@@ -6,28 +6,44 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"regexp"
 	"strings"
 
 	"github.com/openshift/assisted-image-service/pkg/overlay"
-	"github.com/sirupsen/logrus"
+	"github.com/thoas/go-funk"
 )
 
 const (
 	defaultGrubFilePath     = "/EFI/redhat/grub.cfg"
+	grubFilePattern         = "/EFI/*/grub.cfg"
 	defaultIsolinuxFilePath = "/isolinux/isolinux.cfg"
 	kargsConfigFilePath     = "/coreos/kargs.json"
 )
 
 type FileReader func(isoPath, filePath string) ([]byte, error)
 
+// ErrKargsTooLong is returned when the kernel arguments don't fit in the embed area of a file
+type ErrKargsTooLong struct {
+	File     string
+	Length   int64
+	Capacity int64
+}
+
+func (e *ErrKargsTooLong) Error() string {
+	return fmt.Sprintf("kernel arguments for %s exceed the embed area by %d bytes (%d bytes, %d available)", e.File, e.Excess(), e.Length, e.Capacity)
+}
+
+// Excess returns the number of bytes that don't fit in the embed area
+func (e *ErrKargsTooLong) Excess() int64 {
+	return e.Length - e.Capacity
+}
+
 func kargsFiles(isoPath string, fileReader FileReader) ([]string, error) {
 	kargsData, err := fileReader(isoPath, kargsConfigFilePath)
 	if err != nil {
 		// If the kargs file is not found, it is probably iso for old iso version which the file does not exist.  Therefore,
 		// default is returned
-		return []string{defaultGrubFilePath, defaultIsolinuxFilePath}, nil
+		return defaultKargsFiles(isoPath), nil
 	}
 	var kargsConfig struct {
 		Files []struct {
@@ -46,109 +62,338 @@ func kargsFiles(isoPath string, fileReader FileReader) ([]string, error) {
 	return ret, nil
 }
 
+// defaultKargsFiles returns the boot configs holding the kernel arguments of the architecture of
+// the ISO. The default ones are returned when the ISO can't be read.
+func defaultKargsFiles(isoPath string) []string {
+	arch, err := DetectArchitecture(isoPath)
+	if err != nil {
+		return []string{defaultGrubFilePath, defaultIsolinuxFilePath}
+	}
+	return customizerFor(arch).DefaultKargsFiles(isoPath)
+}
+
 func KargsFiles(isoPath string) ([]string, error) {
 	return kargsFiles(isoPath, ReadFileFromISO)
 }
 
-func readerForKargsS390x(isoPath string, filePath string, base io.ReadSeeker, contentReader *bytes.Reader) (overlay.OverlayReader, error) {
-	// Get the fileOffset in ISO
-	fileOffset, _, err := GetISOFileInfo(filePath, isoPath)
+// NewKargsReader returns the filename within an ISO and the new content of
+// the file(s) containing the kernel arguments, with additional arguments
+// appended. Parameters set more than once in the result are resolved according
+// to policy. The architecture of the ISO is detected from its content when
+// arch is empty.
+func NewKargsReader(isoPath, arch, appendKargs string, policy KargsConflictPolicy) ([]FileData, error) {
+	if appendKargs == "" || appendKargs == "\n" {
+		return nil, nil
+	}
+	files, err := KargsFiles(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no kernel arguments file found in %s", isoPath)
+	}
+	perFile := map[string]string{}
+	for _, f := range files {
+		perFile[f] = appendKargs
+	}
+	return NewKargsReaderForFiles(isoPath, arch, perFile, policy)
+}
+
+// NewKargsReaderForFiles is like NewKargsReader, but appends different arguments
+// to each of the files containing the kernel arguments, for example a serial
+// console only for the BIOS boot entry. The keys of appendKargs are paths of
+// the files within the ISO, files not in the map are left untouched.
+func NewKargsReaderForFiles(isoPath, arch string, appendKargs map[string]string, policy KargsConflictPolicy) ([]FileData, error) {
+	customizer, err := kargsCustomizer(isoPath, arch)
 	if err != nil {
 		return nil, err
 	}
-	logrus.Debug("readerForKargsS390x AIS fileOffset Phani ", fileOffset)
 
-	// Read the kargs.json file content from the ISO
-	kargsData, err := ReadFileFromISO(isoPath, kargsConfigFilePath)
+	files, err := KargsFiles(isoPath)
+	if err != nil {
+		return nil, err
+	}
+	kargsByPath := map[string]string{}
+	for path, kargs := range appendKargs {
+		kargsByPath[strings.TrimPrefix(path, "/")] = kargs
+	}
+	var unknown []string
+	for path := range kargsByPath {
+		if !funk.ContainsString(files, path) && !funk.ContainsString(files, "/"+path) {
+			unknown = append(unknown, path)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%v are not kernel arguments files of %s, expected one of %v", unknown, isoPath, files)
+	}
+
+	output := []FileData{}
+	for _, f := range files {
+		kargs := strings.Fields(kargsByPath[strings.TrimPrefix(f, "/")])
+		if len(kargs) == 0 {
+			continue
+		}
+		data, err := customizer.KargsFileData(isoPath, f, AppendKernelArguments(kargs), policy)
+		if err != nil {
+			closeFileData(output)
+			return nil, err
+		}
+
+		output = append(output, data)
+	}
+	return output, nil
+}
+
+// kargsCustomizer returns the customizer editing the kernel arguments of the ISO, detecting its
+// architecture from its content when arch is empty
+func kargsCustomizer(isoPath, arch string) (ArchCustomizer, error) {
+	if arch == "" {
+		var err error
+		arch, err = DetectArchitecture(isoPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect architecture of %s: %w", isoPath, err)
+		}
+	}
+	return customizerFor(arch), nil
+}
+
+func kargsEmbedAreaBoundariesFinder(isoPath, filePath string, fileBoundariesFinder BoundariesFinder, fileReader FileReader) (int64, int64, error) {
+	start, _, err := fileBoundariesFinder(filePath, isoPath)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	b, err := fileReader(isoPath, filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read kargs config: %w", err)
+		return 0, 0, err
+	}
+
+	re := regexp.MustCompile(`(\n#*)# COREOS_KARG_EMBED_AREA`)
+	submatchIndexes := re.FindSubmatchIndex(b)
+	if len(submatchIndexes) != 4 {
+		return 0, 0, errors.New("failed to find COREOS_KARG_EMBED_AREA")
+	}
+	return start + int64(submatchIndexes[2]), int64(submatchIndexes[3] - submatchIndexes[2]), nil
+}
+
+func createKargsEmbedAreaBoundariesFinder() BoundariesFinder {
+	return func(filePath, isoPath string) (int64, int64, error) {
+		return kargsEmbedAreaBoundariesFinder(isoPath, filePath, GetISOFileInfo, ReadFileFromISO)
 	}
+}
 
-	// Loading the kargs config JSON file
+func readerForKargsContent(isoPath string, filePath string, base io.ReadSeeker, contentReader *bytes.Reader) (overlay.OverlayReader, error) {
+	return readerForContent(isoPath, filePath, base, contentReader, kargsCapacityChecker(createKargsEmbedAreaBoundariesFinder(), contentReader.Size()))
+}
+
+// kargsCapacityChecker wraps a kernel arguments embed area finder to fail with ErrKargsTooLong when the
+// content doesn't fit
+func kargsCapacityChecker(boundariesFinder BoundariesFinder, contentLength int64) BoundariesFinder {
+	return func(filePath, isoPath string) (int64, int64, error) {
+		start, length, err := boundariesFinder(filePath, isoPath)
+		if err != nil {
+			return 0, 0, err
+		}
+		if contentLength > length {
+			return 0, 0, &ErrKargsTooLong{File: filePath, Length: contentLength, Capacity: length}
+		}
+		return start, length, nil
+	}
+}
+
+// kargsEmbedArea is the padded region of a boot config file holding the kernel command line. The region
+// contains the arguments followed by the end character, and is filled up to its length with the pad
+// character.
+type kargsEmbedArea struct {
+	// Offset of the region within the ISO
+	offset int64
+	length int64
+	// Separator written before the arguments, for regions starting right after the kernel path
+	separator string
+	end       byte
+	pad       byte
+	kargs     []string
+}
+
+// render returns the content of the region with the given arguments
+func (a *kargsEmbedArea) render(kargs []string) ([]byte, error) {
+	content := []byte(a.separator + strings.Join(kargs, " "))
+	content = append(content, a.end)
+	if int64(len(content)) > a.length {
+		return nil, &ErrKargsTooLong{Length: int64(len(content)), Capacity: a.length}
+	}
+	return append(content, bytes.Repeat([]byte{a.pad}, int(a.length)-len(content))...), nil
+}
+
+func parseKargsEmbedArea(data []byte, end, pad byte) []string {
+	data = bytes.TrimRight(data, string([]byte{pad}))
+	if i := bytes.IndexByte(data, end); i >= 0 {
+		data = data[:i]
+	}
+	return strings.Fields(string(data))
+}
+
+// kargsConfigChar returns the single character of an end or pad field of kargs.json, or the
+// default when the field is not set
+func kargsConfigChar(value string, defaultChar byte) (byte, error) {
+	switch len(value) {
+	case 0:
+		return defaultChar, nil
+	case 1:
+		return value[0], nil
+	}
+	return 0, fmt.Errorf("expected a single character, got '%s'", value)
+}
+
+var (
+	kargsEmbedAreaRegexp = regexp.MustCompile(`(\n#*)# COREOS_KARG_EMBED_AREA`)
+	kargsLineRegexp      = regexp.MustCompile(`^[ \t]*(?:linux(?:efi)?[ \t]+\S+|append)`)
+)
+
+// kargsEmbedAreaFinder locates the kernel arguments region of filePath. The region is described in
+// kargs.json for recent images, otherwise it is the end of the kernel command line in the config file
+// followed by the COREOS_KARG_EMBED_AREA padding.
+func kargsEmbedAreaFinder(isoPath, filePath string, fileBoundariesFinder BoundariesFinder, fileReader FileReader) (*kargsEmbedArea, error) {
+	start, _, err := fileBoundariesFinder(filePath, isoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := fileReader(isoPath, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if kargsData, err := fileReader(isoPath, kargsConfigFilePath); err == nil {
+		area, err := kargsConfigEmbedArea(kargsData, filePath, b)
+		if err != nil {
+			return nil, err
+		}
+		if area != nil {
+			area.offset += start
+			return area, nil
+		}
+	}
+
+	area, err := configKargsEmbedArea(filePath, b)
+	if err != nil {
+		return nil, err
+	}
+	area.offset += start
+	return area, nil
+}
+
+// kargsConfigEmbedArea returns the kernel arguments region of the content of filePath as described
+// in kargs.json, nil when the file isn't listed there. Its offset is relative to the content.
+func kargsConfigEmbedArea(kargsData []byte, filePath string, b []byte) (*kargsEmbedArea, error) {
 	var kargsConfig struct {
-		Default string `json:"default"`
-		Files   []struct {
+		Files []struct {
 			Path   string `json:"path"`
 			Offset int64  `json:"offset"`
 			End    string `json:"end"`
 			Pad    string `json:"pad"`
 		} `json:"files"`
-		Size int `json:"size"`
+		Size int64 `json:"size"`
 	}
 	if err := json.Unmarshal(kargsData, &kargsConfig); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal kargs config: %w", err)
 	}
-
-	// Finding offset for the target filePath
-	var kargsOffset int64
-	var found bool
 	for _, file := range kargsConfig.Files {
-		if file.Path == filePath {
-			kargsOffset = file.Offset
-			found = true
-			break
+		if strings.TrimPrefix(file.Path, "/") != strings.TrimPrefix(filePath, "/") {
+			continue
 		}
+		if kargsConfig.Size <= 0 || file.Offset < 0 || file.Offset+kargsConfig.Size > int64(len(b)) {
+			return nil, fmt.Errorf("invalid kargs embed area for %s in kargs config", filePath)
+		}
+		end, err := kargsConfigChar(file.End, '\n')
+		if err != nil {
+			return nil, fmt.Errorf("invalid end for %s in kargs config: %w", filePath, err)
+		}
+		pad, err := kargsConfigChar(file.Pad, '#')
+		if err != nil {
+			return nil, fmt.Errorf("invalid pad for %s in kargs config: %w", filePath, err)
+		}
+		return &kargsEmbedArea{
+			offset: file.Offset,
+			length: kargsConfig.Size,
+			end:    end,
+			pad:    pad,
+			kargs:  parseKargsEmbedArea(b[file.Offset:file.Offset+kargsConfig.Size], end, pad),
+		}, nil
 	}
-	if !found {
-		return nil, fmt.Errorf("file %s not found in kargs config", filePath)
-	}
-
-	// Calculate the extraKargsOffset
-	existingKargs := []byte(kargsConfig.Default)
-	appendKargsOffset := fileOffset + kargsOffset + int64(len(existingKargs))
-	logrus.Debug("readerForKargsS390x AIS appendKargsOffset Phani ", fileOffset)
+	return nil, nil
+}
 
-	rdOverlay := overlay.Overlay{
-		Reader: contentReader,
-		Offset: appendKargsOffset,
-		Length: contentReader.Size(),
+// configKargsEmbedArea locates the kernel arguments region in the content of a boot config: the
+// end of the kernel command line followed by the COREOS_KARG_EMBED_AREA padding. Its offset is
+// relative to the content.
+func configKargsEmbedArea(filePath string, b []byte) (*kargsEmbedArea, error) {
+	submatchIndexes := kargsEmbedAreaRegexp.FindSubmatchIndex(b)
+	if len(submatchIndexes) != 4 {
+		return nil, errors.New("failed to find COREOS_KARG_EMBED_AREA")
 	}
-	r, err := overlay.NewOverlayReader(base, rdOverlay)
-	if err != nil {
-		return nil, err
+	lineEnd := submatchIndexes[2]
+	lineStart := bytes.LastIndexByte(b[:lineEnd], '\n') + 1
+	loc := kargsLineRegexp.FindIndex(b[lineStart:lineEnd])
+	if loc == nil {
+		return nil, fmt.Errorf("failed to find kernel command line preceding COREOS_KARG_EMBED_AREA in %s", filePath)
 	}
-
-	return r, nil
+	areaStart := lineStart + loc[1]
+	separator := " "
+	if rest := b[areaStart:lineEnd]; len(rest) > 0 && (rest[0] == ' ' || rest[0] == '\t') {
+		areaStart++
+		separator = ""
+	}
+	return &kargsEmbedArea{
+		offset:    int64(areaStart),
+		length:    int64(submatchIndexes[3] - areaStart),
+		separator: separator,
+		end:       '\n',
+		pad:       '#',
+		kargs:     strings.Fields(string(b[areaStart:lineEnd])),
+	}, nil
 }
 
-func kargsFileData(isoPath string, file string, appendKargs []byte) (FileData, error) {
-	baseISO, err := os.Open(isoPath)
+func readerForKargsOperations(isoPath string, filePath string, base io.ReadSeeker, kargs KernelArguments, policy KargsConflictPolicy) (overlay.OverlayReader, error) {
+	area, err := findKargsEmbedArea(isoPath, filePath)
 	if err != nil {
-		return FileData{}, err
+		return nil, err
 	}
-
-	var iso overlay.OverlayReader
-
-	if strings.Contains(isoPath, "s390x") {
-		iso, err = readerForKargsS390x(isoPath, file, baseISO, bytes.NewReader(appendKargs))
-	} else {
-		iso, err = readerForKargsContent(isoPath, file, baseISO, bytes.NewReader(appendKargs))
+	newKargs, err := kargs.Apply(area.kargs)
+	if err != nil {
+		return nil, err
 	}
+	newKargs, err = ResolveKargsConflicts(newKargs, policy)
 	if err != nil {
-		baseISO.Close()
-		return FileData{}, err
+		return nil, err
 	}
-
-	fileData, _, err := isolateISOFile(isoPath, file, iso, 0)
+	content, err := area.render(newKargs)
 	if err != nil {
-		iso.Close()
-		return FileData{}, err
+		var tooLong *ErrKargsTooLong
+		if errors.As(err, &tooLong) {
+			tooLong.File = filePath
+		}
+		return nil, err
 	}
-	logrus.Debug("AIS fileData Phani ", fileData.Data)
-
-	return fileData, nil
+	return overlay.NewOverlayReader(base, overlay.Overlay{
+		Reader: bytes.NewReader(content),
+		Offset: area.offset,
+		Length: area.length,
+	})
 }
 
-// NewKargsReader returns the filename within an ISO and the new content of
-// the file(s) containing the kernel arguments, with additional arguments
-// appended.
-func NewKargsReader(isoPath string, appendKargs string) ([]FileData, error) {
-	if appendKargs == "" || appendKargs == "\n" {
+// NewKargsOperationsReader returns the filename within an ISO and the new content of
+// the file(s) containing the kernel arguments, after applying the append, replace and
+// delete operations on the existing arguments. Parameters set more than once in the
+// result are resolved according to policy. The architecture of the ISO is detected
+// from its content when arch is empty.
+func NewKargsOperationsReader(isoPath, arch string, kargs KernelArguments, policy KargsConflictPolicy) ([]FileData, error) {
+	if len(kargs) == 0 {
 		return nil, nil
 	}
-	appendData := []byte(appendKargs)
-	if appendData[len(appendData)-1] != '\n' && !strings.Contains(isoPath, "s390x") {
-		appendData = append(appendData, '\n')
+	customizer, err := kargsCustomizer(isoPath, arch)
+	if err != nil {
+		return nil, err
 	}
 
 	files, err := KargsFiles(isoPath)
@@ -157,50 +402,52 @@ func NewKargsReader(isoPath string, appendKargs string) ([]FileData, error) {
 	}
 
 	output := []FileData{}
-	for i, f := range files {
-		data, err := kargsFileData(isoPath, f, appendData)
+	for _, f := range files {
+		data, err := customizer.KargsFileData(isoPath, f, kargs, policy)
 		if err != nil {
-			for _, fd := range output[:i] {
-				fd.Data.Close()
-			}
+			closeFileData(output)
 			return nil, err
 		}
-
 		output = append(output, data)
 	}
 	return output, nil
 }
 
-func kargsEmbedAreaBoundariesFinder(isoPath, filePath string, fileBoundariesFinder BoundariesFinder, fileReader FileReader) (int64, int64, error) {
-	start, _, err := fileBoundariesFinder(filePath, isoPath)
-	if err != nil {
-		return 0, 0, err
-	}
+// ExtractKargs returns the kernel arguments an ISO boots with, as found in the
+// first file listed in kargs.json (the grub config for older images). This includes
+// the arguments added by a previous customization.
+func ExtractKargs(isoPath string) ([]string, error) {
+	return extractKargs(isoPath, GetISOFileInfo, ReadFileFromISO)
+}
 
-	b, err := fileReader(isoPath, filePath)
+func extractKargs(isoPath string, fileBoundariesFinder BoundariesFinder, fileReader FileReader) ([]string, error) {
+	files, err := kargsFiles(isoPath, fileReader)
 	if err != nil {
-		return 0, 0, err
+		return nil, err
 	}
-
-	re := regexp.MustCompile(`(\n#*)# COREOS_KARG_EMBED_AREA`)
-	submatchIndexes := re.FindSubmatchIndex(b)
-	if len(submatchIndexes) != 4 {
-		return 0, 0, errors.New("failed to find COREOS_KARG_EMBED_AREA")
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no kernel arguments file found in %s", isoPath)
 	}
-	return start + int64(submatchIndexes[2]), int64(submatchIndexes[3] - submatchIndexes[2]), nil
+	area, err := kargsEmbedAreaFinder(isoPath, files[0], fileBoundariesFinder, fileReader)
+	if err != nil {
+		return nil, err
+	}
+	return area.kargs, nil
 }
 
-func createKargsEmbedAreaBoundariesFinder() BoundariesFinder {
-	return func(filePath, isoPath string) (int64, int64, error) {
-		return kargsEmbedAreaBoundariesFinder(isoPath, filePath, GetISOFileInfo, ReadFileFromISO)
+func closeFileData(files []FileData) {
+	for _, fd := range files {
+		fd.Data.Close()
 	}
 }
 
-func readerForKargsContent(isoPath string, filePath string, base io.ReadSeeker, contentReader *bytes.Reader) (overlay.OverlayReader, error) {
-	return readerForContent(isoPath, filePath, base, contentReader, createKargsEmbedAreaBoundariesFinder())
-}
+const (
+	KargsOperationAppend  = "append"
+	KargsOperationReplace = "replace"
+	KargsOperationDelete  = "delete"
+)
 
-type kernelArgument struct {
+type KernelArgument struct {
 	// The operation to apply on the kernel argument.
 	// Enum: [append replace delete]
 	Operation string `json:"operation,omitempty"`
@@ -211,19 +458,27 @@ type kernelArgument struct {
 	// isolcpus=1,2,10-20,100-2000:2/25
 	// quiet
 	// The parsing by the command line parser in linux kernel is much looser and this pattern follows it.
+	// For the replace operation the value has the form <parameter>=<old>=<new>, or <parameter>=<new> to
+	// replace every value of the parameter.
 	Value string `json:"value,omitempty"`
 }
 
-type kernelArguments []*kernelArgument
+type KernelArguments []*KernelArgument
 
-func KargsToStr(args []string) (string, error) {
-	var kargs kernelArguments
+// AppendKernelArguments returns the operations appending the kernel arguments
+func AppendKernelArguments(args []string) KernelArguments {
+	var kargs KernelArguments
 	for _, s := range args {
-		kargs = append(kargs, &kernelArgument{
-			Operation: "append",
+		kargs = append(kargs, &KernelArgument{
+			Operation: KargsOperationAppend,
 			Value:     s,
 		})
 	}
+	return kargs
+}
+
+func KargsToStr(args []string) (string, error) {
+	kargs := AppendKernelArguments(args)
 	b, err := json.Marshal(&kargs)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal kernel arguments %v", err)
@@ -231,17 +486,113 @@ func KargsToStr(args []string) (string, error) {
 	return string(b), nil
 }
 
-func StrToKargs(kargsStr string) ([]string, error) {
-	var kargs kernelArguments
+// ParseKernelArguments unmarshals the kernel arguments JSON used by assisted-service and validates the
+// operation of every entry.
+func ParseKernelArguments(kargsStr string) (KernelArguments, error) {
+	var kargs KernelArguments
 	if err := json.Unmarshal([]byte(kargsStr), &kargs); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal kernel arguments %v", err)
 	}
+	for _, arg := range kargs {
+		switch arg.Operation {
+		case KargsOperationAppend, KargsOperationDelete:
+		case KargsOperationReplace:
+			if !strings.Contains(arg.Value, "=") {
+				return nil, fmt.Errorf("replace operation requires a value of the form key=new or key=old=new, got '%s'", arg.Value)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported kernel argument operation '%s'", arg.Operation)
+		}
+		if arg.Value == "" {
+			return nil, fmt.Errorf("empty value for kernel argument operation '%s'", arg.Operation)
+		}
+	}
+	return kargs, nil
+}
+
+// StrToKargs returns the values of a kernel arguments JSON consisting only of append operations.
+// Use ParseKernelArguments and NewKargsOperationsReader for replace and delete support.
+func StrToKargs(kargsStr string) ([]string, error) {
+	kargs, err := ParseKernelArguments(kargsStr)
+	if err != nil {
+		return nil, err
+	}
 	var args []string
 	for _, arg := range kargs {
-		if arg.Operation != "append" {
+		if arg.Operation != KargsOperationAppend {
 			return nil, fmt.Errorf("only 'append' operation is allowed.  got %s", arg.Operation)
 		}
 		args = append(args, arg.Value)
 	}
 	return args, nil
 }
+
+// AppendOnly returns true if all the operations are append operations
+func (k KernelArguments) AppendOnly() bool {
+	for _, arg := range k {
+		if arg.Operation != KargsOperationAppend {
+			return false
+		}
+	}
+	return true
+}
+
+// appendString returns the values of the operations as appended to a kernel command line
+func (k KernelArguments) appendString() string {
+	values := make([]string, 0, len(k))
+	for _, arg := range k {
+		values = append(values, arg.Value)
+	}
+	return " " + strings.Join(values, " ") + "\n"
+}
+
+// Apply returns the result of applying the operations, in order, on the given kernel arguments.
+// Deleting or replacing an argument that is not present is not an error.
+func (k KernelArguments) Apply(current []string) ([]string, error) {
+	result := append([]string{}, current...)
+	for _, arg := range k {
+		switch arg.Operation {
+		case KargsOperationAppend:
+			result = append(result, arg.Value)
+		case KargsOperationDelete:
+			var kept []string
+			for _, r := range result {
+				if !kargMatches(r, arg.Value) {
+					kept = append(kept, r)
+				}
+			}
+			result = kept
+		case KargsOperationReplace:
+			parts := strings.SplitN(arg.Value, "=", 3)
+			switch len(parts) {
+			case 3:
+				old := parts[0] + "=" + parts[1]
+				for i, r := range result {
+					if r == old {
+						result[i] = parts[0] + "=" + parts[2]
+					}
+				}
+			case 2:
+				for i, r := range result {
+					if kargMatches(r, parts[0]) {
+						result[i] = arg.Value
+					}
+				}
+			default:
+				return nil, fmt.Errorf("invalid value '%s' for replace operation", arg.Value)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported kernel argument operation '%s'", arg.Operation)
+		}
+	}
+	return result, nil
+}
+
+// kargMatches returns true if arg is equal to value or, when value is a bare parameter name,
+// if arg sets that parameter
+func kargMatches(arg, value string) bool {
+	if arg == value {
+		return true
+	}
+	return !strings.Contains(value, "=") && strings.HasPrefix(arg, value+"=")
+}
@@ -0,0 +1,108 @@
+package isoeditor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/thoas/go-funk"
+)
+
+// KargsConflictPolicy defines how kernel arguments setting the same parameter more than once are handled
+type KargsConflictPolicy string
+
+const (
+	// KargsConflictKeepAll leaves the kernel arguments untouched
+	KargsConflictKeepAll KargsConflictPolicy = ""
+	// KargsConflictKeepLast keeps only the last value of each parameter, which is the one the kernel uses
+	// for most parameters
+	KargsConflictKeepLast KargsConflictPolicy = "keep-last"
+	// KargsConflictKeepFirst keeps only the first value of each parameter
+	KargsConflictKeepFirst KargsConflictPolicy = "keep-first"
+	// KargsConflictError fails when a parameter is set to different values
+	KargsConflictError KargsConflictPolicy = "error"
+)
+
+// KargsConflictErr is returned by ResolveKargsConflicts when a parameter is set more than once with
+// different values and the policy is KargsConflictError
+type KargsConflictErr struct {
+	Key    string
+	Values []string
+}
+
+func (e *KargsConflictErr) Error() string {
+	return fmt.Sprintf("conflicting values for kernel argument %s: %s", e.Key, strings.Join(e.Values, ", "))
+}
+
+// ParseKargsConflictPolicy returns the policy named by policy, "keep-all" or an empty string
+// for KargsConflictKeepAll
+func ParseKargsConflictPolicy(policy string) (KargsConflictPolicy, error) {
+	switch p := KargsConflictPolicy(strings.ToLower(policy)); p {
+	case "", "keep-all":
+		return KargsConflictKeepAll, nil
+	case KargsConflictKeepLast, KargsConflictKeepFirst, KargsConflictError:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid kernel arguments conflict policy %q, expected one of keep-all, %s, %s or %s",
+		policy, KargsConflictKeepLast, KargsConflictKeepFirst, KargsConflictError)
+}
+
+func kargKey(arg string) string {
+	key, _, _ := strings.Cut(arg, "=")
+	return key
+}
+
+// ResolveKargsConflicts removes the kernel arguments that set a parameter already set by another
+// argument, according to the given policy. Exact duplicates are always removed, except with
+// KargsConflictKeepAll.
+func ResolveKargsConflicts(kargs []string, policy KargsConflictPolicy) ([]string, error) {
+	switch policy {
+	case KargsConflictKeepAll:
+		return kargs, nil
+	case KargsConflictKeepFirst, KargsConflictKeepLast, KargsConflictError:
+	default:
+		return nil, fmt.Errorf("unsupported kernel arguments conflict policy '%s'", policy)
+	}
+
+	values := map[string][]string{}
+	for _, arg := range kargs {
+		key := kargKey(arg)
+		if !funk.ContainsString(values[key], arg) {
+			values[key] = append(values[key], arg)
+		}
+	}
+
+	var result []string
+	seen := map[string]bool{}
+	for i, arg := range kargs {
+		key := kargKey(arg)
+		candidates := values[key]
+		switch policy {
+		case KargsConflictError:
+			if len(candidates) > 1 {
+				return nil, &KargsConflictErr{Key: key, Values: candidates}
+			}
+			if !seen[key] {
+				result = append(result, arg)
+			}
+		case KargsConflictKeepFirst:
+			if !seen[key] {
+				result = append(result, arg)
+			}
+		case KargsConflictKeepLast:
+			if lastIndexOfKey(kargs, key) == i {
+				result = append(result, arg)
+			}
+		}
+		seen[key] = true
+	}
+	return result, nil
+}
+
+func lastIndexOfKey(kargs []string, key string) int {
+	for i := len(kargs) - 1; i >= 0; i-- {
+		if kargKey(kargs[i]) == key {
+			return i
+		}
+	}
+	return -1
+}
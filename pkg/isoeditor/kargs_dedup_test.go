@@ -0,0 +1,59 @@
+package isoeditor
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResolveKargsConflicts", func() {
+	kargs := []string{"quiet", "console=tty0", "ip=dhcp", "console=ttyS0", "quiet"}
+
+	It("keeps everything by default", func() {
+		result, err := ResolveKargsConflicts(kargs, KargsConflictKeepAll)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(kargs))
+	})
+	It("keeps the last value", func() {
+		result, err := ResolveKargsConflicts(kargs, KargsConflictKeepLast)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal([]string{"ip=dhcp", "console=ttyS0", "quiet"}))
+	})
+	It("keeps the first value", func() {
+		result, err := ResolveKargsConflicts(kargs, KargsConflictKeepFirst)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal([]string{"quiet", "console=tty0", "ip=dhcp"}))
+	})
+	It("fails on conflicting values", func() {
+		_, err := ResolveKargsConflicts(kargs, KargsConflictError)
+		Expect(err).To(MatchError(&KargsConflictErr{Key: "console", Values: []string{"console=tty0", "console=ttyS0"}}))
+	})
+	It("removes exact duplicates when failing on conflicts", func() {
+		result, err := ResolveKargsConflicts([]string{"quiet", "ip=dhcp", "quiet"}, KargsConflictError)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal([]string{"quiet", "ip=dhcp"}))
+	})
+	It("rejects unknown policies", func() {
+		_, err := ResolveKargsConflicts(kargs, "keep-some")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("ParseKargsConflictPolicy", func() {
+	It("parses the policies", func() {
+		for name, expected := range map[string]KargsConflictPolicy{
+			"":           KargsConflictKeepAll,
+			"keep-all":   KargsConflictKeepAll,
+			"Keep-Last":  KargsConflictKeepLast,
+			"keep-first": KargsConflictKeepFirst,
+			"error":      KargsConflictError,
+		} {
+			policy, err := ParseKargsConflictPolicy(name)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy).To(Equal(expected))
+		}
+	})
+	It("rejects unknown policies", func() {
+		_, err := ParseKargsConflictPolicy("keep-some")
+		Expect(err).To(HaveOccurred())
+	})
+})
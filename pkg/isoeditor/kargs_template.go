@@ -0,0 +1,59 @@
+package isoeditor
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Variables available to kernel arguments templates, such as {{.InfraEnvID}}
+const (
+	KargsVarInfraEnvID       = "InfraEnvID"
+	KargsVarRootfsURL        = "RootfsURL"
+	KargsVarOpenshiftVersion = "OpenshiftVersion"
+	KargsVarCPUArchitecture  = "CPUArchitecture"
+)
+
+// RenderKargsTemplate resolves the template placeholders of kernel arguments with the given variables.
+// It fails if a placeholder references a missing variable, if a value would split or terminate the
+// kernel command line, or if any placeholder is left in the result.
+func RenderKargsTemplate(kargs string, vars map[string]string) (string, error) {
+	if !strings.Contains(kargs, "{{") && !strings.Contains(kargs, "}}") {
+		return kargs, nil
+	}
+	for name, value := range vars {
+		if strings.IndexFunc(value, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) || r == '#' }) >= 0 {
+			return "", fmt.Errorf("value of kernel arguments variable %s contains invalid characters: %q", name, value)
+		}
+	}
+
+	tmpl, err := template.New("kargs").Option("missingkey=error").Parse(kargs)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse kernel arguments template: %w", err)
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, vars); err != nil {
+		return "", fmt.Errorf("failed to render kernel arguments template: %w", err)
+	}
+
+	result := rendered.String()
+	if strings.Contains(result, "{{") || strings.Contains(result, "}}") {
+		return "", fmt.Errorf("unresolved placeholder in kernel arguments %q", result)
+	}
+	return result, nil
+}
+
+// Render returns a copy of the kernel arguments with the template placeholders of every value resolved
+func (k KernelArguments) Render(vars map[string]string) (KernelArguments, error) {
+	rendered := make(KernelArguments, 0, len(k))
+	for _, arg := range k {
+		value, err := RenderKargsTemplate(arg.Value, vars)
+		if err != nil {
+			return nil, err
+		}
+		rendered = append(rendered, &KernelArgument{Operation: arg.Operation, Value: value})
+	}
+	return rendered, nil
+}
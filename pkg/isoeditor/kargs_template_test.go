@@ -0,0 +1,55 @@
+package isoeditor
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RenderKargsTemplate", func() {
+	vars := map[string]string{
+		KargsVarInfraEnvID: "4b2c5d1e-7c3a-4f8e-9d6b-1a2b3c4d5e6f",
+		KargsVarRootfsURL:  "https://example.com/rootfs?arch=x86_64",
+	}
+
+	It("resolves the variables", func() {
+		rendered, err := RenderKargsTemplate(" coreos.live.rootfs_url={{.RootfsURL}} infraenv={{.InfraEnvID}}\n", vars)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rendered).To(Equal(" coreos.live.rootfs_url=https://example.com/rootfs?arch=x86_64 infraenv=4b2c5d1e-7c3a-4f8e-9d6b-1a2b3c4d5e6f\n"))
+	})
+
+	It("leaves kargs without placeholders untouched", func() {
+		rendered, err := RenderKargsTemplate(" a=<b>\n", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rendered).To(Equal(" a=<b>\n"))
+	})
+
+	It("fails for missing variables", func() {
+		_, err := RenderKargsTemplate("a={{.OpenshiftVersion}}", vars)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails for malformed placeholders", func() {
+		_, err := RenderKargsTemplate("a={{.InfraEnvID", vars)
+		Expect(err).To(HaveOccurred())
+		_, err = RenderKargsTemplate("a=.InfraEnvID}}", vars)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails when a value would reintroduce a placeholder", func() {
+		_, err := RenderKargsTemplate("a={{.InfraEnvID}}", map[string]string{KargsVarInfraEnvID: "{{.Other}}"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails when a value would split the argument", func() {
+		_, err := RenderKargsTemplate("a={{.InfraEnvID}}", map[string]string{KargsVarInfraEnvID: "x y"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("renders kernel arguments operations", func() {
+		kargs := KernelArguments{{Operation: KargsOperationAppend, Value: "id={{.InfraEnvID}}"}}
+		rendered, err := kargs.Render(vars)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rendered[0].Value).To(Equal("id=4b2c5d1e-7c3a-4f8e-9d6b-1a2b3c4d5e6f"))
+		Expect(kargs[0].Value).To(Equal("id={{.InfraEnvID}}"))
+	})
+})
@@ -2,6 +2,12 @@ package isoeditor
 
 import (
 	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -152,4 +158,455 @@ menuentry 'Fedora CoreOS (Live)' --class fedora --class gnu-linux --class gnu --
 			Expect(length).To(Equal(int64(1024)))
 		})
 	})
+	Describe("kargsEmbedAreaFinder", func() {
+		It("finds the area following the kernel command line", func() {
+			fileReader := func(_, filePath string) ([]byte, error) {
+				if filePath == kargsConfigFilePath {
+					return nil, errors.New("not found")
+				}
+				return []byte(grubFileWithEmbedArea), nil
+			}
+			area, err := kargsEmbedAreaFinder("isoPath", "filePath", mockBoundariesFinderSuccess(1000, int64(len(grubFileWithEmbedArea))), fileReader)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(area.kargs).To(Equal([]string{"mitigations=auto,nosmt", "coreos.liveiso=fedora-coreos-35.20220103.3.0", "ignition.firstboot", "ignition.platform.id=metal"}))
+			Expect(area.offset).To(Equal(int64(1000 + strings.Index(grubFileWithEmbedArea, "mitigations"))))
+			Expect(area.offset + area.length).To(Equal(int64(1375 + 1024)))
+		})
+		It("uses the kargs config offsets", func() {
+			config := `{"files": [{"path": "EFI/fedora/grub.cfg", "offset": 4}], "size": 16}`
+			fileReader := func(_, filePath string) ([]byte, error) {
+				if filePath == kargsConfigFilePath {
+					return []byte(config), nil
+				}
+				return []byte("....a b=1\n##########..."), nil
+			}
+			area, err := kargsEmbedAreaFinder("isoPath", "/EFI/fedora/grub.cfg", mockBoundariesFinderSuccess(100, 30), fileReader)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(area.offset).To(Equal(int64(104)))
+			Expect(area.length).To(Equal(int64(16)))
+			Expect(area.kargs).To(Equal([]string{"a", "b=1"}))
+		})
+		It("no embed area found", func() {
+			_, err := kargsEmbedAreaFinder("isoPath", "filePath", mockBoundariesFinderSuccess(100, 100), mockFileReaderSuccess(grubFileWithoutEmbedArea))
+			Expect(err).To(HaveOccurred())
+		})
+		It("fails when the arguments do not fit", func() {
+			area := &kargsEmbedArea{length: 8, end: '\n', pad: '#'}
+			_, err := area.render([]string{"a", "b"})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = area.render([]string{"toolongargument"})
+			Expect(err).To(MatchError(&ErrKargsTooLong{Length: 16, Capacity: 8}))
+		})
+	})
+	Describe("ParseKernelArguments", func() {
+		It("accepts all operations", func() {
+			kargs, err := ParseKernelArguments(`[{"operation":"append","value":"a"},{"operation":"replace","value":"b=2"},{"operation":"delete","value":"c"}]`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kargs).To(HaveLen(3))
+			Expect(kargs.AppendOnly()).To(BeFalse())
+		})
+		It("rejects unknown operations", func() {
+			_, err := ParseKernelArguments(`[{"operation":"prepend","value":"a"}]`)
+			Expect(err).To(HaveOccurred())
+		})
+		It("rejects replace without a value", func() {
+			_, err := ParseKernelArguments(`[{"operation":"replace","value":"a"}]`)
+			Expect(err).To(HaveOccurred())
+		})
+		It("StrToKargs rejects non append operations", func() {
+			_, err := StrToKargs(`[{"operation":"delete","value":"a"}]`)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("KernelArguments.Apply", func() {
+		current := []string{"quiet", "console=tty0", "console=ttyS0,115200n8", "ip=dhcp"}
+		It("appends, replaces and deletes arguments", func() {
+			kargs := KernelArguments{
+				{Operation: KargsOperationDelete, Value: "quiet"},
+				{Operation: KargsOperationReplace, Value: "console=ttyS0,115200n8=ttyS1,9600"},
+				{Operation: KargsOperationReplace, Value: "ip=none"},
+				{Operation: KargsOperationAppend, Value: "nomodeset"},
+			}
+			result, err := kargs.Apply(current)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal([]string{"console=tty0", "console=ttyS1,9600", "ip=none", "nomodeset"}))
+			Expect(current[0]).To(Equal("quiet"))
+		})
+		It("deletes all values of a parameter", func() {
+			result, err := KernelArguments{{Operation: KargsOperationDelete, Value: "console"}}.Apply(current)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal([]string{"quiet", "ip=dhcp"}))
+		})
+		It("deletes only the exact value", func() {
+			result, err := KernelArguments{{Operation: KargsOperationDelete, Value: "console=tty0"}}.Apply(current)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal([]string{"quiet", "console=ttyS0,115200n8", "ip=dhcp"}))
+		})
+	})
+	Describe("NewKargsOperationsReader", func() {
+		var (
+			isoFile  string
+			filesDir string
+		)
+
+		BeforeEach(func() {
+			filesDir, isoFile = createTestFiles("Assisted123")
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(filesDir)).To(Succeed())
+			Expect(os.Remove(isoFile)).To(Succeed())
+		})
+
+		It("rewrites the kernel arguments of all config files", func() {
+			kargs := KernelArguments{
+				{Operation: KargsOperationDelete, Value: "rd.luks.options"},
+				{Operation: KargsOperationReplace, Value: "ignition.platform.id=qemu"},
+				{Operation: KargsOperationAppend, Value: "p1"},
+			}
+			files, err := NewKargsOperationsReader(isoFile, "", kargs, KargsConflictKeepAll)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(2))
+			for _, f := range files {
+				content, err := io.ReadAll(f.Data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(f.Data.Close()).To(Succeed())
+				Expect(string(content)).To(ContainSubstring("random.trust_cpu=on coreos.liveiso=rhcos-46.82.202010091720-0 ignition.firstboot ignition.platform.id=qemu p1\n###"))
+				Expect(string(content)).To(ContainSubstring("# COREOS_KARG_EMBED_AREA"))
+				Expect(string(content)).ToNot(ContainSubstring("rd.luks.options"))
+			}
+			original, err := ReadFileFromISO(isoFile, defaultGrubFilePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(original)).To(Equal(len(testGrubConfig)))
+		})
+
+		It("resolves conflicting arguments", func() {
+			kargs := KernelArguments{{Operation: KargsOperationAppend, Value: "rd.luks.options=none"}}
+			_, err := NewKargsOperationsReader(isoFile, "", kargs, KargsConflictError)
+			Expect(err).To(BeAssignableToTypeOf(&KargsConflictErr{}))
+
+			files, err := NewKargsOperationsReader(isoFile, "", kargs, KargsConflictKeepLast)
+			Expect(err).ToNot(HaveOccurred())
+			content, err := io.ReadAll(files[0].Data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("ignition.platform.id=metal rd.luks.options=none\n"))
+			Expect(string(content)).ToNot(ContainSubstring("rd.luks.options=discard"))
+			closeFileData(files)
+		})
+
+		It("keeps the original files untouched", func() {
+			original, err := ReadFileFromISO(isoFile, defaultGrubFilePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(original)).To(Equal(len(testGrubConfig)))
+		})
+	})
+	Describe("ExtractKargs", func() {
+		It("returns the arguments of the grub config", func() {
+			fileReader := func(_, filePath string) ([]byte, error) {
+				if filePath == kargsConfigFilePath {
+					return nil, errors.New("not found")
+				}
+				return []byte(grubFileWithEmbedArea), nil
+			}
+			kargs, err := extractKargs("isoPath", mockBoundariesFinderSuccess(0, int64(len(grubFileWithEmbedArea))), fileReader)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kargs).To(Equal([]string{"mitigations=auto,nosmt", "coreos.liveiso=fedora-coreos-35.20220103.3.0", "ignition.firstboot", "ignition.platform.id=metal"}))
+		})
+		It("fails when kargs.json lists no files", func() {
+			_, err := extractKargs("isoPath", mockBoundariesFinderSuccess(0, 0), mockFileReaderSuccess(`{"files": []}`))
+			Expect(err).To(HaveOccurred())
+		})
+		It("returns the arguments of a customized ISO", func() {
+			filesDir, isoFile := createTestFiles("Assisted123")
+			defer func() {
+				Expect(os.RemoveAll(filesDir)).To(Succeed())
+				Expect(os.Remove(isoFile)).To(Succeed())
+			}()
+
+			reader, err := NewRHCOSStreamReader(isoFile, &IgnitionContent{Config: []byte("{}")}, nil, AppendKernelArguments([]string{"p1", "p2"}))
+			Expect(err).ToNot(HaveOccurred())
+			customized, err := os.CreateTemp(filesDir, "customized*.iso")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.Copy(customized, reader)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(customized.Close()).To(Succeed())
+			Expect(reader.Close()).To(Succeed())
+
+			kargs, err := ExtractKargs(customized.Name())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kargs).To(Equal([]string{"random.trust_cpu=on", "rd.luks.options=discard", "coreos.liveiso=rhcos-46.82.202010091720-0",
+				"ignition.firstboot", "ignition.platform.id=metal", "p1", "p2"}))
+		})
+	})
+	Describe("kargsCapacityChecker", func() {
+		It("accepts content that fits", func() {
+			start, length, err := kargsCapacityChecker(mockBoundariesFinderSuccess(100, 10), 10)("file", "isoPath")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(start).To(Equal(int64(100)))
+			Expect(length).To(Equal(int64(10)))
+		})
+		It("reports how many bytes are over", func() {
+			_, _, err := kargsCapacityChecker(mockBoundariesFinderSuccess(100, 10), 14)("file", "isoPath")
+			var tooLong *ErrKargsTooLong
+			Expect(errors.As(err, &tooLong)).To(BeTrue())
+			Expect(tooLong.Excess()).To(Equal(int64(4)))
+			Expect(tooLong.File).To(Equal("file"))
+		})
+		It("propagates finder errors", func() {
+			_, _, err := kargsCapacityChecker(mockBoundariesFinderFailure(), 1)("file", "isoPath")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("NewKargsReader for s390x", func() {
+		const (
+			prmDefault = "coreos.liveiso=rhcos-415 ignition.firstboot ignition.platform.id=metal"
+			prmSize    = 128
+		)
+		var (
+			isoFile  string
+			filesDir string
+		)
+
+		BeforeEach(func() {
+			var err error
+			filesDir, err = os.MkdirTemp("", "isotest")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(filesDir, "coreos"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(filesDir, "images"), 0755)).To(Succeed())
+			prm := prmDefault + "\n" + strings.Repeat(" ", prmSize-len(prmDefault)-1)
+			Expect(os.WriteFile(filepath.Join(filesDir, "images/cdboot.img"), []byte("rd.neednet=1 "+prm), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(filesDir, "images/generic.prm"), []byte(prm), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(filesDir, "generic.ins"), []byte("someinscontent"), 0600)).To(Succeed())
+			kargsConfig := `{"default": "` + prmDefault + `", "files": [{"path": "images/cdboot.img", "offset": 13, "end": "\n", "pad": " "}, {"path": "images/generic.prm", "end": "\n", "pad": " "}], "size": 128}`
+			Expect(os.WriteFile(filepath.Join(filesDir, "coreos/kargs.json"), []byte(kargsConfig), 0600)).To(Succeed())
+
+			temp, err := os.CreateTemp("", "*test.iso")
+			Expect(err).ToNot(HaveOccurred())
+			isoFile = temp.Name()
+			Expect(temp.Close()).To(Succeed())
+			Expect(os.Remove(isoFile)).To(Succeed())
+			cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", "s390x", "-o", isoFile, filesDir)
+			Expect(cmd.Run()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(filesDir)).To(Succeed())
+			Expect(os.Remove(isoFile)).To(Succeed())
+		})
+
+		It("writes the end marker and pads the area", func() {
+			files, err := NewKargsReader(isoFile, "", " p1 p2", KargsConflictKeepAll)
+			Expect(err).ToNot(HaveOccurred())
+			defer closeFileData(files)
+			Expect(files).To(HaveLen(2))
+			Expect(files[0].Filename).To(Equal("images/cdboot.img"))
+			content, err := io.ReadAll(files[0].Data)
+			Expect(err).ToNot(HaveOccurred())
+
+			expected := prmDefault + " p1 p2\n"
+			Expect(string(content)).To(Equal("rd.neednet=1 " + expected + strings.Repeat(" ", prmSize-len(expected))))
+		})
+
+		It("regenerates the parameter file within its kargs.json area", func() {
+			files, err := NewKargsReader(isoFile, "", " p1 p2", KargsConflictKeepAll)
+			Expect(err).ToNot(HaveOccurred())
+			defer closeFileData(files)
+			Expect(files).To(HaveLen(2))
+			Expect(files[1].Filename).To(Equal("images/generic.prm"))
+			content, err := io.ReadAll(files[1].Data)
+			Expect(err).ToNot(HaveOccurred())
+
+			expected := prmDefault + " p1 p2\n"
+			Expect(string(content)).To(Equal(expected + strings.Repeat(" ", prmSize-len(expected))))
+		})
+
+		It("wraps the arguments of the parameter file in its area", func() {
+			long := strings.Repeat("x", 40)
+			files, err := NewKargsReaderForFiles(isoFile, S390XCPUArchitecture, map[string]string{"images/generic.prm": long}, KargsConflictKeepAll)
+			Expect(err).ToNot(HaveOccurred())
+			defer closeFileData(files)
+			content, err := io.ReadAll(files[0].Data)
+			Expect(err).ToNot(HaveOccurred())
+
+			expected := prmDefault + "\n" + long + "\n"
+			Expect(string(content)).To(Equal(expected + strings.Repeat(" ", prmSize-len(expected))))
+			Expect(ParseS390xParmFile(content)).To(Equal(append(strings.Fields(prmDefault), long)))
+		})
+
+		It("regenerates the parameter files kargs.json doesn't describe within their size", func() {
+			kargsConfig := `{"default": "` + prmDefault + `", "files": [{"path": "images/cdboot.img", "offset": 13, "end": "\n", "pad": " "}], "size": 128}`
+			Expect(os.WriteFile(filepath.Join(filesDir, "coreos/kargs.json"), []byte(kargsConfig), 0600)).To(Succeed())
+			Expect(os.Remove(isoFile)).To(Succeed())
+			cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", "s390x", "-o", isoFile, filesDir)
+			Expect(cmd.Run()).To(Succeed())
+
+			file, err := s390xParmFileData(isoFile, "images/generic.prm", AppendKernelArguments([]string{"p1"}), KargsConflictKeepAll)
+			Expect(err).ToNot(HaveOccurred())
+			defer file.Data.Close()
+			content, err := io.ReadAll(file.Data)
+			Expect(err).ToNot(HaveOccurred())
+			expected := prmDefault + " p1\n"
+			Expect(string(content)).To(Equal(expected + strings.Repeat(" ", prmSize-len(expected))))
+
+			_, err = s390xParmFileData(isoFile, "images/generic.prm", AppendKernelArguments([]string{strings.Repeat("x", prmSize)}), KargsConflictKeepAll)
+			var tooLong *ErrKargsTooLong
+			Expect(errors.As(err, &tooLong)).To(BeTrue())
+			Expect(tooLong.File).To(Equal("images/generic.prm"))
+			Expect(tooLong.Capacity).To(Equal(int64(prmSize)))
+		})
+
+		It("applies the operations to the boot image and the parameter file", func() {
+			kargs := KernelArguments{
+				{Operation: KargsOperationReplace, Value: "ignition.platform.id=qemu"},
+				{Operation: KargsOperationDelete, Value: "ignition.firstboot"},
+				{Operation: KargsOperationAppend, Value: "p1"},
+			}
+			files, err := NewKargsOperationsReader(isoFile, S390XCPUArchitecture, kargs, KargsConflictKeepAll)
+			Expect(err).ToNot(HaveOccurred())
+			defer closeFileData(files)
+			Expect(files).To(HaveLen(2))
+
+			expected := "coreos.liveiso=rhcos-415 ignition.platform.id=qemu p1\n"
+			content, err := io.ReadAll(files[0].Data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal("rd.neednet=1 " + expected + strings.Repeat(" ", prmSize-len(expected))))
+			content, err = io.ReadAll(files[1].Data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal(expected + strings.Repeat(" ", prmSize-len(expected))))
+		})
+
+		It("fails when the arguments exceed the kargs.json size", func() {
+			_, err := NewKargsReader(isoFile, S390XCPUArchitecture, " "+strings.Repeat("x", prmSize), KargsConflictKeepAll)
+			var tooLong *ErrKargsTooLong
+			Expect(errors.As(err, &tooLong)).To(BeTrue())
+			Expect(tooLong.Capacity).To(Equal(int64(prmSize)))
+		})
+
+		Context("built for secure IPL", func() {
+			BeforeEach(func() {
+				prm := prmDefault + "\n" + strings.Repeat(" ", prmSize-len(prmDefault)-1)
+				cdboot := "rd.neednet=1 " + prm + "signedkernel" + s390xModuleSignatureMagic + "initrd"
+				Expect(os.WriteFile(filepath.Join(filesDir, "images/cdboot.img"), []byte(cdboot), 0600)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(filesDir, "images/ignition.img"), make([]byte, ignitionPaddingLength), 0600)).To(Succeed())
+				Expect(os.Remove(isoFile)).To(Succeed())
+				cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", "s390x", "-o", isoFile, filesDir)
+				Expect(cmd.Run()).To(Succeed())
+			})
+
+			It("detects the signed files", func() {
+				Expect(S390xSignedFiles(isoFile)).To(Equal([]string{"/images/cdboot.img"}))
+			})
+
+			It("searches the signatures again when the ISO changes", func() {
+				Expect(S390xSignedFiles(isoFile)).To(HaveLen(1))
+
+				prm := prmDefault + "\n" + strings.Repeat(" ", prmSize-len(prmDefault)-1)
+				Expect(os.WriteFile(filepath.Join(filesDir, "images/cdboot.img"), []byte("rd.neednet=1 "+prm), 0600)).To(Succeed())
+				Expect(os.Remove(isoFile)).To(Succeed())
+				cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", "s390x", "-o", isoFile, filesDir)
+				Expect(cmd.Run()).To(Succeed())
+				later := time.Now().Add(time.Minute)
+				Expect(os.Chtimes(isoFile, later, later)).To(Succeed())
+
+				Expect(S390xSignedFiles(isoFile)).To(BeEmpty())
+			})
+
+			It("refuses to stream the ISO with the kernel arguments of the signed boot image edited", func() {
+				_, err := NewRHCOSStreamReader(isoFile, &IgnitionContent{Config: []byte("someignitioncontent")}, nil, AppendKernelArguments([]string{"p1"}))
+				var secureIPL *ErrS390xSecureIPL
+				Expect(errors.As(err, &secureIPL)).To(BeTrue())
+				Expect(secureIPL.File).To(Equal("images/cdboot.img"))
+			})
+
+			It("refuses to edit the kernel arguments of the signed boot image", func() {
+				_, err := NewKargsReader(isoFile, S390XCPUArchitecture, " p1 p2", KargsConflictKeepAll)
+				var secureIPL *ErrS390xSecureIPL
+				Expect(errors.As(err, &secureIPL)).To(BeTrue())
+				Expect(secureIPL.File).To(Equal("images/cdboot.img"))
+				Expect(err.Error()).To(ContainSubstring("parameter files"))
+			})
+
+			It("still edits the parameter files", func() {
+				files, err := NewKargsReaderForFiles(isoFile, S390XCPUArchitecture, map[string]string{"images/generic.prm": " p1 p2"}, KargsConflictKeepAll)
+				Expect(err).ToNot(HaveOccurred())
+				defer closeFileData(files)
+				Expect(files).To(HaveLen(1))
+				content, err := io.ReadAll(files[0].Data)
+				Expect(err).ToNot(HaveOccurred())
+				expected := prmDefault + " p1 p2\n"
+				Expect(string(content)).To(Equal(expected + strings.Repeat(" ", prmSize-len(expected))))
+			})
+		})
+	})
+	Describe("GenerateS390xParmFile", func() {
+		It("wraps lines at 80 columns", func() {
+			kargs := []string{strings.Repeat("a", 50), strings.Repeat("b", 29), "c"}
+			content, err := GenerateS390xParmFile(kargs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal(kargs[0] + " " + kargs[1] + "\n" + kargs[2] + "\n"))
+			Expect(ParseS390xParmFile(content)).To(Equal(kargs))
+		})
+
+		It("fails when the command line exceeds the zipl limit", func() {
+			_, err := GenerateS390xParmFile([]string{strings.Repeat("x", s390xMaxCmdlineLength+1)})
+			var tooLong *ErrKargsTooLong
+			Expect(errors.As(err, &tooLong)).To(BeTrue())
+			Expect(tooLong.Capacity).To(Equal(int64(s390xMaxCmdlineLength)))
+		})
+	})
+	Describe("NewKargsReaderForFiles", func() {
+		var (
+			isoFile  string
+			filesDir string
+		)
+
+		BeforeEach(func() {
+			filesDir, isoFile = createTestFiles("Assisted123")
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(filesDir)).To(Succeed())
+			Expect(os.Remove(isoFile)).To(Succeed())
+		})
+
+		It("appends different arguments to each file", func() {
+			files, err := NewKargsReaderForFiles(isoFile, X86CPUArchitecture, map[string]string{
+				defaultGrubFilePath: " p1",
+				strings.TrimPrefix(defaultIsolinuxFilePath, "/"): " console=ttyS0",
+			}, KargsConflictKeepAll)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(2))
+			expected := map[string]string{defaultGrubFilePath: " p1\n#", defaultIsolinuxFilePath: " console=ttyS0\n#"}
+			for _, f := range files {
+				content, err := io.ReadAll(f.Data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(f.Data.Close()).To(Succeed())
+				Expect(string(content)).To(ContainSubstring("ignition.platform.id=metal" + expected[f.Filename]))
+			}
+		})
+
+		It("skips files without arguments", func() {
+			files, err := NewKargsReaderForFiles(isoFile, X86CPUArchitecture, map[string]string{defaultIsolinuxFilePath: " console=ttyS0"}, KargsConflictKeepAll)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+			Expect(files[0].Filename).To(Equal(defaultIsolinuxFilePath))
+			closeFileData(files)
+		})
+
+		It("resolves the conflicts with the appended arguments", func() {
+			files, err := NewKargsReaderForFiles(isoFile, X86CPUArchitecture, map[string]string{defaultGrubFilePath: " ignition.platform.id=qemu"}, KargsConflictKeepLast)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+			content, err := io.ReadAll(files[0].Data)
+			Expect(err).ToNot(HaveOccurred())
+			closeFileData(files)
+			Expect(string(content)).To(ContainSubstring("ignition.firstboot ignition.platform.id=qemu\n###"))
+			Expect(string(content)).ToNot(ContainSubstring("ignition.platform.id=metal"))
+		})
+
+		It("rejects unknown files", func() {
+			_, err := NewKargsReaderForFiles(isoFile, X86CPUArchitecture, map[string]string{"/boot/grub/grub.cfg": " p1"}, KargsConflictKeepAll)
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
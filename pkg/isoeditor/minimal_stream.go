@@ -0,0 +1,205 @@
+package isoeditor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/openshift/assisted-image-service/pkg/overlay"
+	"github.com/pkg/errors"
+)
+
+const rootfsImagePath = "/images/pxeboot/rootfs.img"
+
+// MinimalISOStreamGenerator returns a StreamGeneratorFunc that generates minimal ISOs from the
+// full ISOs given as isoPath, see NewMinimalISOStreamReader. The parameters set more than once
+// in the kernel arguments are resolved according to policy.
+func MinimalISOStreamGenerator(rootFSURL, arch string, policy KargsConflictPolicy) StreamGeneratorFunc {
+	return func(isoPath string, ignitionContent *IgnitionContent, ramdiskContent []byte, kargs KernelArguments) (ImageReader, error) {
+		return newMinimalISOStreamReader(isoPath, rootFSURL, arch, ignitionContent, ramdiskContent, kargs, policy)
+	}
+}
+
+// NewMinimalISOStreamReader returns the minimal ISO for a full ISO, with the ignition, ramdisk and
+// kernel arguments embedded, as a chain of overlays over the full ISO so no file is written.
+//
+// Unlike CreateMinimalISO the rootfs is not removed from the directory tree, its extent is
+// shrunk to hold the ramdisk and it is loaded as an additional initrd. The boot configs fetch
+// the rootfs from rootFSURL, their changes take the room of the kernel arguments embed area.
+// The nmstate ramdisk isn't included.
+func NewMinimalISOStreamReader(fullISOPath, rootFSURL, arch string, ignitionContent *IgnitionContent, ramdiskContent []byte, kargs KernelArguments) (ImageReader, error) {
+	return newMinimalISOStreamReader(fullISOPath, rootFSURL, arch, ignitionContent, ramdiskContent, kargs, KargsConflictKeepAll)
+}
+
+func newMinimalISOStreamReader(fullISOPath, rootFSURL, arch string, ignitionContent *IgnitionContent, ramdiskContent []byte, kargs KernelArguments, policy KargsConflictPolicy) (ImageReader, error) {
+	reloc, err := newISORelocation(fullISOPath, rootfsImagePath, int64(RamDiskPaddingLength))
+	if err != nil {
+		return nil, err
+	}
+
+	overlays, err := minimalISOConfigOverlays(fullISOPath, rootFSURL, arch, kargs, policy, reloc)
+	if err != nil {
+		reloc.Close()
+		return nil, err
+	}
+
+	if ignitionContent != nil {
+		o, err := minimalISOIgnitionOverlay(fullISOPath, ignitionContent, reloc)
+		if err != nil {
+			reloc.Close()
+			return nil, errors.Wrap(err, "failed to create overwrite reader for ignition")
+		}
+		overlays = append(overlays, o)
+	}
+
+	r, err := reloc.reader(ramdiskContent, overlays...)
+	if err != nil {
+		reloc.Close()
+		return nil, errors.Wrap(err, "failed to create overwrite reader for ramdisk")
+	}
+	return r, nil
+}
+
+func minimalISOConfigOverlays(isoPath, rootFSURL, arch string, kargs KernelArguments, policy KargsConflictPolicy, reloc *isoRelocation) ([]overlay.Overlay, error) {
+	ramDisks := []string{rootfsImagePath}
+
+	grubPaths, err := customizerFor(arch).GrubConfigs(func(path string) bool {
+		_, _, err := GetISOFileInfo(path, isoPath)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var overlays []overlay.Overlay
+	for _, grubPath := range grubPaths {
+		grub, err := minimalISOConfigOverlay(isoPath, grubPath, kargs, policy, reloc, func(content string) string {
+			return editGrubConfig(content, rootFSURL, ramDisks)
+		})
+		if err != nil {
+			return nil, err
+		}
+		overlays = append(overlays, grub)
+	}
+
+	// only the ISOs booting with BIOS have an isolinux config, unlike the arm64 and ppc64le ones
+	if _, _, err := GetISOFileInfo(defaultIsolinuxFilePath, isoPath); err == nil {
+		isolinux, err := minimalISOConfigOverlay(isoPath, defaultIsolinuxFilePath, kargs, policy, reloc, func(content string) string {
+			return editIsolinuxConfig(content, rootFSURL, ramDisks)
+		})
+		if err != nil {
+			return nil, err
+		}
+		overlays = append(overlays, isolinux)
+	}
+	return overlays, nil
+}
+
+// minimalISOConfigOverlay edits a boot config, keeping its length by growing or shrinking the
+// padding of the kernel arguments embed area, and applies the kernel arguments operations
+func minimalISOConfigOverlay(isoPath, filePath string, kargs KernelArguments, policy KargsConflictPolicy, reloc *isoRelocation, edit func(string) string) (overlay.Overlay, error) {
+	offset, _, err := GetISOFileInfo(filePath, isoPath)
+	if err != nil {
+		return overlay.Overlay{}, err
+	}
+	original, err := ReadFileFromISO(isoPath, filePath)
+	if err != nil {
+		return overlay.Overlay{}, err
+	}
+
+	edited, err := fitKargsEmbedArea(filePath, original, []byte(edit(string(original))))
+	if err != nil {
+		return overlay.Overlay{}, err
+	}
+
+	if len(kargs) > 0 {
+		if edited, err = applyConfigKargs(filePath, edited, kargs, policy); err != nil {
+			return overlay.Overlay{}, err
+		}
+	}
+
+	return overlay.Overlay{
+		Reader: bytes.NewReader(edited),
+		Offset: reloc.mapOffset(offset),
+		Length: int64(len(edited)),
+	}, nil
+}
+
+// applyConfigKargs applies the kernel arguments operations to the content of a boot config,
+// within its kernel arguments embed area, and resolves the conflicts according to policy
+func applyConfigKargs(filePath string, content []byte, kargs KernelArguments, policy KargsConflictPolicy) ([]byte, error) {
+	if kargs.AppendOnly() && policy == KargsConflictKeepAll {
+		// the area starts at the newline preceding the padding, as for readerForKargsContent
+		appended := kargs.appendString()
+		indexes := kargsEmbedAreaRegexp.FindSubmatchIndex(content)
+		areaStart := indexes[2]
+		capacity := int64(indexes[3] - areaStart)
+		if int64(len(appended)) > capacity {
+			return nil, &ErrKargsTooLong{File: filePath, Length: int64(len(appended)), Capacity: capacity}
+		}
+		copy(content[areaStart:], appended)
+		return content, nil
+	}
+
+	area, err := configKargsEmbedArea(filePath, content)
+	if err != nil {
+		return nil, err
+	}
+	newKargs, err := kargs.Apply(area.kargs)
+	if err != nil {
+		return nil, err
+	}
+	newKargs, err = ResolveKargsConflicts(newKargs, policy)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := area.render(newKargs)
+	if err != nil {
+		var tooLong *ErrKargsTooLong
+		if errors.As(err, &tooLong) {
+			tooLong.File = filePath
+		}
+		return nil, err
+	}
+	copy(content[area.offset:], rendered)
+	return content, nil
+}
+
+func minimalISOIgnitionOverlay(isoPath string, ignitionContent *IgnitionContent, reloc *isoRelocation) (overlay.Overlay, error) {
+	start, capacity, err := (&ignitionBoundaryFinder{}).findBoundaries(ignitionImagePath, isoPath)
+	if err != nil {
+		return overlay.Overlay{}, err
+	}
+	archive, err := ignitionContent.archiveWithin(capacity)
+	if err != nil {
+		return overlay.Overlay{}, err
+	}
+	content, err := io.ReadAll(archive)
+	if err != nil {
+		return overlay.Overlay{}, err
+	}
+	return overlay.Overlay{
+		Reader: overlay.NewPaddedReader(content, capacity, 0),
+		Offset: reloc.mapOffset(start),
+		Length: capacity,
+	}, nil
+}
+
+// fitKargsEmbedArea returns the edited content of a boot config with the same length as the
+// original, by growing or shrinking the padding of the kernel arguments embed area
+func fitKargsEmbedArea(filePath string, original, content []byte) ([]byte, error) {
+	indexes := kargsEmbedAreaRegexp.FindSubmatchIndex(content)
+	if len(indexes) != 4 {
+		return nil, fmt.Errorf("failed to find COREOS_KARG_EMBED_AREA in %s", filePath)
+	}
+	// the padding excludes the leading newline and the last '#', which marks the area
+	paddingEnd := indexes[3]
+	padding := int64(paddingEnd - indexes[2] - 1)
+	excess := int64(len(content) - len(original))
+	if excess > padding {
+		return nil, &ErrKargsTooLong{File: filePath, Length: excess, Capacity: padding}
+	}
+	var edited []byte
+	edited = append(edited, content[:paddingEnd-int(max(excess, 0))]...)
+	edited = append(edited, bytes.Repeat([]byte{'#'}, int(max(-excess, 0)))...)
+	return append(edited, content[paddingEnd:]...), nil
+}
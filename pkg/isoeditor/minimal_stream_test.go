@@ -0,0 +1,144 @@
+package isoeditor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewMinimalISOStreamReader", func() {
+	const rootFSURL = "https://example.com/rhcos-live-rootfs.x86_64.img"
+
+	var (
+		isoFile  string
+		filesDir string
+	)
+
+	writeISO := func(r io.Reader) string {
+		f, err := os.CreateTemp(filesDir, "minimal*.iso")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.Copy(f, r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		return f.Name()
+	}
+
+	BeforeEach(func() {
+		filesDir, isoFile = createTestFiles("Assisted123")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+	})
+
+	for _, size := range []int{14, 3*1024*1024 + 5} {
+		size := size
+		It(fmt.Sprintf("streams a minimal ISO replacing a rootfs of %d bytes", size), func() {
+			rootfs := make([]byte, size)
+			rand.New(rand.NewSource(int64(size))).Read(rootfs)
+			Expect(os.RemoveAll(filesDir)).To(Succeed())
+			Expect(os.Remove(isoFile)).To(Succeed())
+			filesDir, isoFile = createFullTestFiles("Assisted123", rootfs)
+			ignition := []byte(`{"ignition": {"version": "3.2.0"}}`)
+			ramdisk := []byte("someramdiskcontent")
+
+			r, err := NewMinimalISOStreamReader(isoFile, rootFSURL, "x86_64", &IgnitionContent{Config: ignition}, ramdisk, AppendKernelArguments([]string{"p1", "p2"}))
+			Expect(err).NotTo(HaveOccurred())
+			defer r.Close()
+			output := writeISO(r)
+
+			info, err := os.Stat(output)
+			Expect(err).NotTo(HaveOccurred())
+			fullInfo, err := os.Stat(isoFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Size() - fullInfo.Size()).To(Equal(int64(RamDiskPaddingLength) - int64((size+2047)/2048*2048)))
+
+			_, length, err := GetISOFileInfo(rootfsImagePath, output)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(length).To(Equal(int64(RamDiskPaddingLength)))
+			content, err := ReadFileFromISO(output, rootfsImagePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(content[:len(ramdisk)]).To(Equal(ramdisk))
+			Expect(bytes.Count(content[len(ramdisk):], []byte{0})).To(Equal(len(content) - len(ramdisk)))
+
+			for _, file := range []string{defaultGrubFilePath, defaultIsolinuxFilePath} {
+				original, err := ReadFileFromISO(isoFile, file)
+				Expect(err).NotTo(HaveOccurred())
+				edited, err := ReadFileFromISO(output, file)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(edited).To(HaveLen(len(original)))
+				Expect(string(edited)).To(ContainSubstring("coreos.live.rootfs_url=" + rootFSURL))
+				Expect(string(edited)).To(ContainSubstring(rootfsImagePath))
+				Expect(string(edited)).NotTo(ContainSubstring("coreos.liveiso"))
+				Expect(string(edited)).To(MatchRegexp(" p1 p2\n#+ COREOS_KARG_EMBED_AREA"))
+			}
+			grub, err := ReadFileFromISO(output, defaultGrubFilePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(grub)).To(ContainSubstring("initrd /images/pxeboot/initrd.img /images/ignition.img " + rootfsImagePath))
+
+			extracted, err := ExtractIgnition(output)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(extracted).To(Equal(ignition))
+
+			for _, file := range []string{"/images/efiboot.img", "/isolinux/isolinux.bin", "/coreos/igninfo.json"} {
+				expected, err := ReadFileFromISO(isoFile, file)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ReadFileFromISO(output, file)).To(Equal(expected))
+			}
+		})
+	}
+
+	It("applies the replace and delete kargs operations", func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+		filesDir, isoFile = createFullTestFiles("Assisted123", []byte("rootfs"))
+		kargs := KernelArguments{
+			{Operation: KargsOperationDelete, Value: "rd.luks.options"},
+			{Operation: KargsOperationReplace, Value: "ignition.platform.id=qemu"},
+			{Operation: KargsOperationAppend, Value: "p1"},
+		}
+
+		r, err := NewMinimalISOStreamReader(isoFile, rootFSURL, "x86_64", nil, nil, kargs)
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+		output := writeISO(r)
+
+		for _, file := range []string{defaultGrubFilePath, defaultIsolinuxFilePath} {
+			original, err := ReadFileFromISO(isoFile, file)
+			Expect(err).NotTo(HaveOccurred())
+			edited, err := ReadFileFromISO(output, file)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(edited).To(HaveLen(len(original)))
+			Expect(string(edited)).To(ContainSubstring("coreos.live.rootfs_url=" + rootFSURL))
+			Expect(string(edited)).To(MatchRegexp(" ignition.platform.id=qemu .* p1\n#+ COREOS_KARG_EMBED_AREA"))
+			Expect(string(edited)).NotTo(ContainSubstring("rd.luks.options"))
+		}
+	})
+
+	It("fails when the boot config edits don't fit in the embed area", func() {
+		_, err := NewMinimalISOStreamReader(isoFile, rootFSURL, "x86_64", nil, nil, nil)
+		var tooLong *ErrKargsTooLong
+		Expect(errors.As(err, &tooLong)).To(BeTrue())
+		Expect(tooLong.File).To(Equal("EFI/redhat/grub.cfg"))
+	})
+
+	It("is usable as a stream generator", func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+		filesDir, isoFile = createFullTestFiles("Assisted123", []byte("this is rootfs"))
+		r, err := MinimalISOStreamGenerator(rootFSURL, "x86_64", KargsConflictKeepAll)(isoFile, nil, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		output := writeISO(r)
+		Expect(r.Close()).To(Succeed())
+		grub, err := ReadFileFromISO(output, defaultGrubFilePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(grub)).To(ContainSubstring(rootFSURL))
+	})
+})
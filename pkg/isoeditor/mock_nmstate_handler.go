@@ -46,3 +46,18 @@ func (mr *MockNmstateHandlerMockRecorder) CreateNmstateRamDisk(arg0, arg1, arg2
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNmstateRamDisk", reflect.TypeOf((*MockNmstateHandler)(nil).CreateNmstateRamDisk), arg0, arg1, arg2)
 }
+
+// GenerateKeyfiles mocks base method.
+func (m *MockNmstateHandler) GenerateKeyfiles(arg0 string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateKeyfiles", arg0)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateKeyfiles indicates an expected call of GenerateKeyfiles.
+func (mr *MockNmstateHandlerMockRecorder) GenerateKeyfiles(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateKeyfiles", reflect.TypeOf((*MockNmstateHandler)(nil).GenerateKeyfiles), arg0)
+}
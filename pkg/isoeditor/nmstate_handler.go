@@ -10,12 +10,12 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 )
 
 //go:generate mockgen -package=isoeditor -destination=mock_nmstate_handler.go . NmstateHandler
 type NmstateHandler interface {
 	CreateNmstateRamDisk(rootfsPath, ramDiskPath, nmstatectlPath string) error
+	GenerateKeyfiles(nmstateYAML string) (map[string]string, error)
 }
 
 type nmstateHandler struct {
@@ -63,7 +63,7 @@ func (n *nmstateHandler) buildNmstateCpioArchive(rootfsPath string) ([]byte, err
 	defer func() {
 		removeErr := os.RemoveAll(nmstateDir)
 		if removeErr != nil {
-			log.WithError(removeErr).Error("failed to remove nmstate temp dir")
+			log.Errorf("failed to remove nmstate temp dir: %v", removeErr)
 		}
 	}()
 
@@ -139,7 +139,7 @@ func (e *CommonExecuter) Execute(command, workDir string) (string, error) {
 	cmd := exec.Command("bash", "-c", command)
 	cmd.Stdout = &stdoutBytes
 	cmd.Stderr = &stderrBytes
-	log.Infof(fmt.Sprintf("Running cmd: %s\n", command))
+	log.Infof("Running cmd: %s", command)
 	cmd.Dir = workDir
 	err := cmd.Run()
 	if err != nil {
@@ -0,0 +1,32 @@
+package isoeditor
+
+// ppc64leGrubConfigPaths are the grub configs that petitboot, the bootloader of the OPAL firmware,
+// looks for on the boot media, in its order. The ppc64le ISOs have neither EFI nor isolinux
+// configs, and the grub loaded through /ppc/bootinfo.txt on PowerVM reads /boot/grub/grub.cfg.
+var ppc64leGrubConfigPaths = []string{"grub.cfg", "grub/grub.cfg", "grub2/grub.cfg", "boot/grub/grub.cfg", "boot/grub2/grub.cfg"}
+
+// ppc64leCustomizer handles the ppc64le ISOs, booted by petitboot or by grub
+type ppc64leCustomizer struct {
+	grubCustomizer
+}
+
+// GrubConfigs returns all the grub configs found, since the ISOs may be booted by petitboot or by grub
+func (c *ppc64leCustomizer) GrubConfigs(exists func(path string) bool) ([]string, error) {
+	return findGrubConfigs(ppc64leGrubConfigPaths, true, exists)
+}
+
+// DefaultKargsFiles returns the grub configs read by petitboot
+func (c *ppc64leCustomizer) DefaultKargsFiles(isoPath string) []string {
+	paths, err := c.GrubConfigs(func(path string) bool {
+		_, _, err := GetISOFileInfo("/"+path, isoPath)
+		return err == nil
+	})
+	if err != nil {
+		return []string{"/boot/grub/grub.cfg"}
+	}
+	var files []string
+	for _, path := range paths {
+		files = append(files, "/"+path)
+	}
+	return files
+}
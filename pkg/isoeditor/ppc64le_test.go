@@ -0,0 +1,93 @@
+package isoeditor
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ppc64le ISOs", func() {
+	var (
+		isoFile  string
+		filesDir string
+	)
+
+	// the layout of the RHCOS ppc64le ISOs, booted by petitboot or by grub through bootinfo.txt
+	createISO := func() {
+		Expect(os.Remove(isoFile)).To(Or(Succeed(), MatchError(os.ErrNotExist)))
+		cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", "rhcos-ppc64le", "-o", isoFile, filesDir)
+		Expect(cmd.Run()).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		filesDir, err = os.MkdirTemp("", "isotest")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(filesDir, "ppc"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(filesDir, "boot/grub"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(filesDir, "images/pxeboot"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(filesDir, "ppc/bootinfo.txt"), []byte("<chrp-boot></chrp-boot>"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(filesDir, "boot/grub/grub.cfg"), []byte(testGrubConfig), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(filesDir, "images/pxeboot/rootfs.img"), []byte("this is rootfs"), 0600)).To(Succeed())
+
+		temp, err := os.CreateTemp("", "*test.iso")
+		Expect(err).ToNot(HaveOccurred())
+		isoFile = temp.Name()
+		Expect(temp.Close()).To(Succeed())
+		createISO()
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+	})
+
+	It("finds the grub config read by petitboot without kargs.json", func() {
+		files, err := KargsFiles(isoFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(Equal([]string{"/boot/grub/grub.cfg"}))
+
+		kargs, err := ExtractKargs(isoFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kargs).To(ContainElement("ignition.platform.id=metal"))
+	})
+
+	It("appends the kernel arguments to every petitboot config", func() {
+		Expect(os.MkdirAll(filepath.Join(filesDir, "grub2"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(filesDir, "grub2/grub.cfg"), []byte(testGrubConfig), 0600)).To(Succeed())
+		createISO()
+
+		files, err := NewKargsReader(isoFile, "", " p1\n", KargsConflictKeepAll)
+		Expect(err).ToNot(HaveOccurred())
+		defer closeFileData(files)
+		Expect(files).To(HaveLen(2))
+		Expect(files[0].Filename).To(Equal("/grub2/grub.cfg"))
+		Expect(files[1].Filename).To(Equal("/boot/grub/grub.cfg"))
+		for _, file := range files {
+			content, err := io.ReadAll(file.Data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("ignition.platform.id=metal p1\n"))
+		}
+	})
+
+	It("edits every petitboot config of the minimal ISOs", func() {
+		Expect(os.MkdirAll(filepath.Join(filesDir, "grub2"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(filesDir, "grub2/grub.cfg"), []byte(testGrubConfig), 0600)).To(Succeed())
+
+		Expect(fixGrubConfig(testRootFSURL, filesDir, PPC64LECPUArchitecture, false)).To(Succeed())
+		for _, path := range []string{"grub2/grub.cfg", "boot/grub/grub.cfg"} {
+			content, err := os.ReadFile(filepath.Join(filesDir, path))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("'coreos.live.rootfs_url=" + testRootFSURL + "'"))
+		}
+	})
+
+	It("fails without petitboot config", func() {
+		Expect(os.Remove(filepath.Join(filesDir, "boot/grub/grub.cfg"))).To(Succeed())
+		Expect(fixGrubConfig(testRootFSURL, filesDir, PPC64LECPUArchitecture, false)).To(MatchError(ContainSubstring("no grub.cfg found")))
+	})
+})
@@ -0,0 +1,166 @@
+package isoeditor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
+	"gopkg.in/yaml.v3"
+)
+
+// RegistriesConfPath is the containers-registries.conf file of the live environment
+const RegistriesConfPath = "/etc/containers/registries.conf"
+
+const (
+	MirrorSourcePolicyAllowContactingSource = "AllowContactingSource"
+	MirrorSourcePolicyNeverContactSource    = "NeverContactSource"
+)
+
+// RegistryMirror redirects the pulls of the images of Source to the Mirrors, in order
+type RegistryMirror struct {
+	Source  string
+	Mirrors []string
+	// MirrorSourcePolicy is NeverContactSource to block pulls from the source
+	MirrorSourcePolicy string
+	// MirrorByDigestOnly restricts the mirrors to pulls by digest, as for ImageDigestMirrorSets
+	MirrorByDigestOnly bool
+}
+
+// ParseMirrorSets returns the mirrors of ImageDigestMirrorSet, ImageTagMirrorSet and
+// ImageContentSourcePolicy manifests. Several manifests can be given as a multi-document YAML.
+func ParseMirrorSets(manifests []byte) ([]RegistryMirror, error) {
+	type mirrorEntry struct {
+		Source             string   `yaml:"source"`
+		Mirrors            []string `yaml:"mirrors"`
+		MirrorSourcePolicy string   `yaml:"mirrorSourcePolicy"`
+	}
+	type manifest struct {
+		Kind string `yaml:"kind"`
+		Spec struct {
+			ImageDigestMirrors      []mirrorEntry `yaml:"imageDigestMirrors"`
+			ImageTagMirrors         []mirrorEntry `yaml:"imageTagMirrors"`
+			RepositoryDigestMirrors []mirrorEntry `yaml:"repositoryDigestMirrors"`
+		} `yaml:"spec"`
+	}
+
+	var mirrors []RegistryMirror
+	decoder := yaml.NewDecoder(bytes.NewReader(manifests))
+	for {
+		var m manifest
+		if err := decoder.Decode(&m); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("invalid mirror set manifest: %w", err)
+		}
+		var entries []mirrorEntry
+		byDigest := true
+		switch m.Kind {
+		case "ImageDigestMirrorSet":
+			entries = m.Spec.ImageDigestMirrors
+		case "ImageTagMirrorSet":
+			entries = m.Spec.ImageTagMirrors
+			byDigest = false
+		case "ImageContentSourcePolicy":
+			entries = m.Spec.RepositoryDigestMirrors
+		case "":
+			continue
+		default:
+			return nil, fmt.Errorf("unsupported mirror set kind %s", m.Kind)
+		}
+		for _, e := range entries {
+			mirrors = append(mirrors, RegistryMirror{
+				Source:             e.Source,
+				Mirrors:            e.Mirrors,
+				MirrorSourcePolicy: e.MirrorSourcePolicy,
+				MirrorByDigestOnly: byDigest,
+			})
+		}
+	}
+	if len(mirrors) == 0 {
+		return nil, fmt.Errorf("no mirrors found in the mirror set manifests")
+	}
+	return mirrors, nil
+}
+
+// RegistriesConf returns the containers-registries.conf (version 2) configuring the mirrors
+func RegistriesConf(mirrors []RegistryMirror) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("unqualified-search-registries = [\"registry.access.redhat.com\", \"docker.io\"]\n")
+	for _, m := range mirrors {
+		if err := validateRegistryLocation(m.Source); err != nil {
+			return nil, err
+		}
+		switch m.MirrorSourcePolicy {
+		case "", MirrorSourcePolicyAllowContactingSource, MirrorSourcePolicyNeverContactSource:
+		default:
+			return nil, fmt.Errorf("invalid mirror source policy %q for %s", m.MirrorSourcePolicy, m.Source)
+		}
+		if len(m.Mirrors) == 0 && m.MirrorSourcePolicy != MirrorSourcePolicyNeverContactSource {
+			return nil, fmt.Errorf("no mirrors for %s", m.Source)
+		}
+
+		fmt.Fprintf(&b, "\n[[registry]]\n  prefix = \"\"\n  location = %s\n", strconv.Quote(m.Source))
+		if m.MirrorByDigestOnly {
+			b.WriteString("  mirror-by-digest-only = true\n")
+		}
+		if m.MirrorSourcePolicy == MirrorSourcePolicyNeverContactSource {
+			b.WriteString("  blocked = true\n")
+		}
+		for _, mirror := range m.Mirrors {
+			if err := validateRegistryLocation(mirror); err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&b, "\n  [[registry.mirror]]\n    location = %s\n", strconv.Quote(mirror))
+			if !m.MirrorByDigestOnly {
+				b.WriteString("    pull-from-mirror = \"tag-only\"\n")
+			}
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+func validateRegistryLocation(location string) error {
+	if location == "" || strings.Contains(location, "://") || strings.ContainsAny(location, " \t\n\"\\") || !utf8.ValidString(location) {
+		return fmt.Errorf("invalid registry location %q", location)
+	}
+	return nil
+}
+
+// AddRegistriesConfToIgnition returns the ignition config writing the registries.conf, so the
+// mirrors are used by the installed system too
+func AddRegistriesConfToIgnition(ignition, registriesConf []byte) ([]byte, error) {
+	if len(registriesConf) == 0 || !utf8.Valid(registriesConf) {
+		return nil, fmt.Errorf("invalid registries.conf")
+	}
+	return mergeIgnitionOverride(ignition, map[string]interface{}{
+		"storage": map[string]interface{}{
+			"files": []interface{}{
+				ignitionDataFile(RegistriesConfPath, registriesConf, 0o644),
+			},
+		},
+	})
+}
+
+// NewRegistriesConfIgnitionReader returns the files of the ISO that need to be overwritten in
+// order to embed the ignition config and the registries.conf, in the live initramfs as well as
+// in the ignition config, for disconnected environments pulling the release from a mirror.
+func NewRegistriesConfIgnitionReader(isoPath string, ignition, registriesConf []byte) ([]FileData, error) {
+	if ignition != nil && IsButane(ignition) {
+		var err error
+		if ignition, err = ButaneToIgnition(ignition); err != nil {
+			return nil, err
+		}
+	}
+	withRegistries, err := AddRegistriesConfToIgnition(ignition, registriesConf)
+	if err != nil {
+		return nil, err
+	}
+	return NewIgnitionImageReaderWithFiles(isoPath, &IgnitionContent{Config: withRegistries},
+		cpio.Entry{Path: RegistriesConfPath, Mode: 0o644, Content: registriesConf})
+}
@@ -0,0 +1,150 @@
+package isoeditor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const testMirrorSets = `apiVersion: config.openshift.io/v1
+kind: ImageDigestMirrorSet
+metadata:
+  name: release
+spec:
+  imageDigestMirrors:
+  - source: quay.io/openshift-release-dev/ocp-release
+    mirrors:
+    - mirror.example.com:5000/ocp/release
+    mirrorSourcePolicy: NeverContactSource
+---
+apiVersion: config.openshift.io/v1
+kind: ImageTagMirrorSet
+metadata:
+  name: tools
+spec:
+  imageTagMirrors:
+  - source: registry.redhat.io/ubi9
+    mirrors:
+    - mirror.example.com:5000/ubi9
+---
+apiVersion: operator.openshift.io/v1alpha1
+kind: ImageContentSourcePolicy
+metadata:
+  name: art
+spec:
+  repositoryDigestMirrors:
+  - source: quay.io/openshift-release-dev/ocp-v4.0-art-dev
+    mirrors:
+    - mirror.example.com:5000/ocp/art
+    - backup.example.com/ocp/art
+`
+
+var _ = Describe("Registries", func() {
+	It("parses mirror set manifests", func() {
+		mirrors, err := ParseMirrorSets([]byte(testMirrorSets))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mirrors).To(Equal([]RegistryMirror{
+			{
+				Source:             "quay.io/openshift-release-dev/ocp-release",
+				Mirrors:            []string{"mirror.example.com:5000/ocp/release"},
+				MirrorSourcePolicy: MirrorSourcePolicyNeverContactSource,
+				MirrorByDigestOnly: true,
+			},
+			{
+				Source:  "registry.redhat.io/ubi9",
+				Mirrors: []string{"mirror.example.com:5000/ubi9"},
+			},
+			{
+				Source:             "quay.io/openshift-release-dev/ocp-v4.0-art-dev",
+				Mirrors:            []string{"mirror.example.com:5000/ocp/art", "backup.example.com/ocp/art"},
+				MirrorByDigestOnly: true,
+			},
+		}))
+	})
+
+	It("rejects unsupported manifests", func() {
+		_, err := ParseMirrorSets([]byte("kind: ConfigMap\n"))
+		Expect(err).To(MatchError("unsupported mirror set kind ConfigMap"))
+		_, err = ParseMirrorSets([]byte("kind: ImageDigestMirrorSet\n"))
+		Expect(err).To(MatchError(ContainSubstring("no mirrors found")))
+	})
+
+	It("generates registries.conf", func() {
+		mirrors, err := ParseMirrorSets([]byte(testMirrorSets))
+		Expect(err).NotTo(HaveOccurred())
+		conf, err := RegistriesConf(mirrors)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(conf)).To(Equal(`unqualified-search-registries = ["registry.access.redhat.com", "docker.io"]
+
+[[registry]]
+  prefix = ""
+  location = "quay.io/openshift-release-dev/ocp-release"
+  mirror-by-digest-only = true
+  blocked = true
+
+  [[registry.mirror]]
+    location = "mirror.example.com:5000/ocp/release"
+
+[[registry]]
+  prefix = ""
+  location = "registry.redhat.io/ubi9"
+
+  [[registry.mirror]]
+    location = "mirror.example.com:5000/ubi9"
+    pull-from-mirror = "tag-only"
+
+[[registry]]
+  prefix = ""
+  location = "quay.io/openshift-release-dev/ocp-v4.0-art-dev"
+  mirror-by-digest-only = true
+
+  [[registry.mirror]]
+    location = "mirror.example.com:5000/ocp/art"
+
+  [[registry.mirror]]
+    location = "backup.example.com/ocp/art"
+`))
+	})
+
+	It("rejects invalid mirrors", func() {
+		_, err := RegistriesConf([]RegistryMirror{{Source: "quay.io/ocp", Mirrors: []string{"https://mirror.example.com"}}})
+		Expect(err).To(MatchError(ContainSubstring("invalid registry location")))
+		_, err = RegistriesConf([]RegistryMirror{{Source: "quay.io/ocp"}})
+		Expect(err).To(MatchError("no mirrors for quay.io/ocp"))
+		_, err = RegistriesConf([]RegistryMirror{{Source: "quay.io/ocp", Mirrors: []string{"m.example.com"}, MirrorSourcePolicy: "Sometimes"}})
+		Expect(err).To(MatchError(ContainSubstring("invalid mirror source policy")))
+	})
+
+	It("adds registries.conf to the ignition config", func() {
+		conf := []byte("[[registry]]\n  location = \"quay.io\"\n")
+		withRegistries, err := AddRegistriesConfToIgnition([]byte(`{"ignition": {"version": "3.2.0"}}`), conf)
+		Expect(err).NotTo(HaveOccurred())
+
+		var config struct {
+			Ignition struct {
+				Version string `json:"version"`
+			} `json:"ignition"`
+			Storage struct {
+				Files []struct {
+					Path     string `json:"path"`
+					Contents struct {
+						Source string `json:"source"`
+					} `json:"contents"`
+				} `json:"files"`
+			} `json:"storage"`
+		}
+		Expect(json.Unmarshal(withRegistries, &config)).To(Succeed())
+		Expect(config.Ignition.Version).To(Equal("3.2.0"))
+		Expect(config.Storage.Files).To(HaveLen(1))
+		Expect(config.Storage.Files[0].Path).To(Equal(RegistriesConfPath))
+		content, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(config.Storage.Files[0].Contents.Source, "data:;base64,"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content).To(Equal(conf))
+
+		_, err = AddRegistriesConfToIgnition(nil, nil)
+		Expect(err).To(MatchError("invalid registries.conf"))
+	})
+})
@@ -5,9 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/openshift/assisted-image-service/internal/common"
-	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -42,7 +42,7 @@ func CreateMinimalISO(extractDir, volumeID, rootFSURL, arch, minimalISOPath stri
 	}
 
 	if err := embedInitrdPlaceholders(extractDir); err != nil {
-		log.WithError(err).Warnf("Failed to embed initrd placeholders")
+		log.Warnf("Failed to embed initrd placeholders: %v", err)
 		return err
 	}
 
@@ -51,15 +51,15 @@ func CreateMinimalISO(extractDir, volumeID, rootFSURL, arch, minimalISOPath stri
 		includeNmstateRamDisk = true
 	}
 
-	if err := fixGrubConfig(rootFSURL, extractDir, includeNmstateRamDisk); err != nil {
-		log.WithError(err).Warnf("Failed to edit grub config")
+	if err := fixGrubConfig(rootFSURL, extractDir, arch, includeNmstateRamDisk); err != nil {
+		log.Warnf("Failed to edit grub config: %v", err)
 		return err
 	}
 
-	// ignore isolinux.cfg for ppc64le because it doesn't exist
-	if arch != "ppc64le" {
+	// only the ISOs booting with BIOS have an isolinux config, unlike the arm64 and ppc64le ones
+	if _, err := os.Stat(filepath.Join(extractDir, defaultIsolinuxFilePath)); err == nil {
 		if err := fixIsolinuxConfig(rootFSURL, extractDir, includeNmstateRamDisk); err != nil {
-			log.WithError(err).Warnf("Failed to edit isolinux config")
+			log.Warnf("Failed to edit isolinux config: %v", err)
 			return err
 		}
 	}
@@ -116,10 +116,10 @@ func embedInitrdPlaceholders(extractDir string) error {
 	}
 	defer func() {
 		if deferErr := f.Sync(); deferErr != nil {
-			log.WithError(deferErr).Error("Failed to sync disk image placeholder file")
+			log.Errorf("Failed to sync disk image placeholder file: %v", deferErr)
 		}
 		if deferErr := f.Close(); deferErr != nil {
-			log.WithError(deferErr).Error("Failed to close disk image placeholder file")
+			log.Errorf("Failed to close disk image placeholder file: %v", deferErr)
 		}
 	}()
 
@@ -131,78 +131,70 @@ func embedInitrdPlaceholders(extractDir string) error {
 	return nil
 }
 
-func fixGrubConfig(rootFSURL, extractDir string, includeNmstateRamDisk bool) error {
-	availableGrubPaths := []string{"EFI/redhat/grub.cfg", "EFI/fedora/grub.cfg", "boot/grub/grub.cfg", "EFI/centos/grub.cfg"}
-	var foundGrubPath string
-	for _, pathSection := range availableGrubPaths {
-		path := filepath.Join(extractDir, pathSection)
-		if _, err := os.Stat(path); err == nil {
-			foundGrubPath = path
-			break
-		}
-	}
-	if len(foundGrubPath) == 0 {
-		return fmt.Errorf("no grub.cfg found, possible paths are %v", availableGrubPaths)
-	}
+// grubConfigPaths are the locations of the grub config in the ISOs of the supported distributions
+var grubConfigPaths = []string{"EFI/redhat/grub.cfg", "EFI/fedora/grub.cfg", "boot/grub/grub.cfg", "EFI/centos/grub.cfg"}
 
-	// Add the rootfs url
-	replacement := fmt.Sprintf("$1 $2 'coreos.live.rootfs_url=%s'", rootFSURL)
-	if err := editFile(foundGrubPath, `(?m)^(\s+linux) (.+| )+$`, replacement); err != nil {
-		return err
-	}
-
-	// Remove the coreos.liveiso parameter
-	if err := editFile(foundGrubPath, ` coreos.liveiso=\S+`, ""); err != nil {
+func fixGrubConfig(rootFSURL, extractDir, arch string, includeNmstateRamDisk bool) error {
+	paths, err := customizerFor(arch).GrubConfigs(func(path string) bool {
+		_, err := os.Stat(filepath.Join(extractDir, path))
+		return err == nil
+	})
+	if err != nil {
 		return err
 	}
-
-	// Edit config to add custom ramdisk image to initrd
-	if includeNmstateRamDisk {
-		if err := editFile(foundGrubPath, `(?m)^(\s+initrd) (.+| )+$`, fmt.Sprintf("$1 $2 %s %s", ramDiskImagePath, nmstateDiskImagePath)); err != nil {
-			return err
-		}
-	} else {
-		if err := editFile(foundGrubPath, `(?m)^(\s+initrd) (.+| )+$`, fmt.Sprintf("$1 $2 %s", ramDiskImagePath)); err != nil {
+	for _, path := range paths {
+		err = editFile(filepath.Join(extractDir, path), func(content string) string {
+			return editGrubConfig(content, rootFSURL, minimalISORamDisks(includeNmstateRamDisk))
+		})
+		if err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
 
 func fixIsolinuxConfig(rootFSURL, extractDir string, includeNmstateRamDisk bool) error {
-	replacement := fmt.Sprintf("$1 $2 coreos.live.rootfs_url=%s", rootFSURL)
-	if err := editFile(filepath.Join(extractDir, "isolinux/isolinux.cfg"), `(?m)^(\s+append) (.+| )+$`, replacement); err != nil {
-		return err
-	}
-
-	if err := editFile(filepath.Join(extractDir, "isolinux/isolinux.cfg"), ` coreos.liveiso=\S+`, ""); err != nil {
-		return err
-	}
+	return editFile(filepath.Join(extractDir, "isolinux/isolinux.cfg"), func(content string) string {
+		return editIsolinuxConfig(content, rootFSURL, minimalISORamDisks(includeNmstateRamDisk))
+	})
+}
 
+func minimalISORamDisks(includeNmstateRamDisk bool) []string {
 	if includeNmstateRamDisk {
-		if err := editFile(filepath.Join(extractDir, "isolinux/isolinux.cfg"), `(?m)^(\s+append.*initrd=\S+) (.*)$`, fmt.Sprintf("${1},%s,%s ${2}", ramDiskImagePath, nmstateDiskImagePath)); err != nil {
-			return err
-		}
-	} else {
-		if err := editFile(filepath.Join(extractDir, "isolinux/isolinux.cfg"), `(?m)^(\s+append.*initrd=\S+) (.*)$`, fmt.Sprintf("${1},%s ${2}", ramDiskImagePath)); err != nil {
-			return err
-		}
+		return []string{ramDiskImagePath, nmstateDiskImagePath}
 	}
+	return []string{ramDiskImagePath}
+}
 
-	return nil
+// editGrubConfig adds the rootfs url and the additional ramdisks to the grub config of a full ISO, and
+// removes the coreos.liveiso parameter
+func editGrubConfig(content, rootFSURL string, ramDisks []string) string {
+	// Add the rootfs url
+	content = regexp.MustCompile(`(?m)^(\s+linux) (.+| )+$`).ReplaceAllString(content, fmt.Sprintf("$1 $2 'coreos.live.rootfs_url=%s'", rootFSURL))
+
+	// Remove the coreos.liveiso parameter
+	content = regexp.MustCompile(` coreos.liveiso=\S+`).ReplaceAllString(content, "")
+
+	// Edit config to add custom ramdisk images to initrd
+	return regexp.MustCompile(`(?m)^(\s+initrd) (.+| )+$`).ReplaceAllString(content, fmt.Sprintf("$1 $2 %s", strings.Join(ramDisks, " ")))
 }
 
-func editFile(fileName string, reString string, replacement string) error {
+// editIsolinuxConfig is the equivalent of editGrubConfig for the isolinux config
+func editIsolinuxConfig(content, rootFSURL string, ramDisks []string) string {
+	content = regexp.MustCompile(`(?m)^(\s+append) (.+| )+$`).ReplaceAllString(content, fmt.Sprintf("$1 $2 coreos.live.rootfs_url=%s", rootFSURL))
+
+	content = regexp.MustCompile(` coreos.liveiso=\S+`).ReplaceAllString(content, "")
+
+	return regexp.MustCompile(`(?m)^(\s+append.*initrd=\S+) (.*)$`).ReplaceAllString(content, fmt.Sprintf("${1},%s ${2}", strings.Join(ramDisks, ",")))
+}
+
+func editFile(fileName string, edit func(string) string) error {
 	content, err := os.ReadFile(fileName)
 	if err != nil {
 		return err
 	}
 
-	re := regexp.MustCompile(reString)
-	newContent := re.ReplaceAllString(string(content), replacement)
-
-	if err := os.WriteFile(fileName, []byte(newContent), 0600); err != nil {
+	if err := os.WriteFile(fileName, []byte(edit(string(content))), 0600); err != nil {
 		return err
 	}
 
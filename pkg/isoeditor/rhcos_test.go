@@ -96,7 +96,7 @@ var _ = Context("with test files", func() {
 	Describe("Fix Config", func() {
 		Context("with including nmstate disk image", func() {
 			It("fixGrubConfig alters the kernel parameters correctly", func() {
-				err := fixGrubConfig(testRootFSURL, filesDir, true)
+				err := fixGrubConfig(testRootFSURL, filesDir, "x86_64", true)
 				Expect(err).ToNot(HaveOccurred())
 
 				newLine := "	linux /images/pxeboot/vmlinuz random.trust_cpu=on rd.luks.options=discard ignition.firstboot ignition.platform.id=metal 'coreos.live.rootfs_url=%s'"
@@ -120,7 +120,7 @@ var _ = Context("with test files", func() {
 
 		Context("without including nmstate disk image", func() {
 			It("fixGrubConfig alters the kernel parameters correctly", func() {
-				err := fixGrubConfig(testRootFSURL, filesDir, false)
+				err := fixGrubConfig(testRootFSURL, filesDir, "x86_64", false)
 				Expect(err).ToNot(HaveOccurred())
 
 				newLine := "	linux /images/pxeboot/vmlinuz random.trust_cpu=on rd.luks.options=discard ignition.firstboot ignition.platform.id=metal 'coreos.live.rootfs_url=%s'"
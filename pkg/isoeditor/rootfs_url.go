@@ -0,0 +1,86 @@
+package isoeditor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/thoas/go-funk"
+)
+
+// ErrNoRootfsURL is returned by SetRootfsURL when the boot configs of the ISO don't set
+// coreos.live.rootfs_url, which is the case of full ISOs
+var ErrNoRootfsURL = errors.New("no coreos.live.rootfs_url kernel argument found, not a minimal ISO")
+
+var rootfsURLKargRegexp = regexp.MustCompile(`coreos\.live\.rootfs_url=[^\s']*`)
+
+// rootfsURLConfigPaths are the boot configs of the ISOs of all the architectures that may set the
+// rootfs URL
+var rootfsURLConfigPaths = funk.UniqString(append(append(append([]string{}, grubConfigPaths...), ppc64leGrubConfigPaths...), defaultIsolinuxFilePath))
+
+// SetRootfsURL returns the boot configs of a minimal ISO with the coreos.live.rootfs_url kernel
+// argument pointing to rootFSURL. The configs keep their length, the difference with the
+// previous URL is taken from the padding of the kernel arguments embed area. The result can be
+// streamed with Apply.
+func SetRootfsURL(isoPath, rootFSURL string) ([]FileData, error) {
+	u, err := url.Parse(rootFSURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rootfs URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.ContainsAny(rootFSURL, " \t\n'\"") {
+		return nil, fmt.Errorf("invalid rootfs URL %q, expected an http or https URL", rootFSURL)
+	}
+
+	output := []FileData{}
+	for _, filePath := range rootfsURLConfigPaths {
+		if _, _, err := GetISOFileInfo(filePath, isoPath); err != nil {
+			continue
+		}
+		original, err := ReadFileFromISO(isoPath, filePath)
+		if err != nil {
+			closeFileData(output)
+			return nil, err
+		}
+		if !rootfsURLKargRegexp.Match(original) {
+			continue
+		}
+
+		replacement := []byte("coreos.live.rootfs_url=" + rootFSURL)
+		content, err := fitKargsEmbedArea(filePath, original, rootfsURLKargRegexp.ReplaceAllLiteral(original, replacement))
+		if err != nil {
+			closeFileData(output)
+			return nil, err
+		}
+		output = append(output, FileData{
+			Filename: filePath,
+			Data:     io.NopCloser(bytes.NewReader(content)),
+		})
+	}
+
+	if len(output) == 0 {
+		return nil, ErrNoRootfsURL
+	}
+	return output, nil
+}
+
+// GetRootfsURL returns the coreos.live.rootfs_url kernel argument of the boot configs of a
+// minimal ISO, or ErrNoRootfsURL
+func GetRootfsURL(isoPath string) (string, error) {
+	for _, filePath := range rootfsURLConfigPaths {
+		if _, _, err := GetISOFileInfo(filePath, isoPath); err != nil {
+			continue
+		}
+		content, err := ReadFileFromISO(isoPath, filePath)
+		if err != nil {
+			return "", err
+		}
+		if match := rootfsURLKargRegexp.Find(content); match != nil {
+			return strings.TrimPrefix(string(match), "coreos.live.rootfs_url="), nil
+		}
+	}
+	return "", ErrNoRootfsURL
+}
@@ -0,0 +1,87 @@
+package isoeditor
+
+import (
+	"io"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SetRootfsURL", func() {
+	var (
+		isoFile  string
+		filesDir string
+	)
+
+	BeforeEach(func() {
+		filesDir, isoFile = createFullTestFiles("Assisted123", []byte("this is rootfs"))
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+	})
+
+	minimalISO := func(rootFSURL string) string {
+		r, err := NewMinimalISOStreamReader(isoFile, rootFSURL, "x86_64", nil, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+		f, err := os.CreateTemp(filesDir, "minimal*.iso")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.Copy(f, r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		return f.Name()
+	}
+
+	It("rewrites the rootfs URL of the boot configs", func() {
+		minimal := minimalISO("https://example.com/rootfs.img")
+		newURL := "https://mirror.example.org/pub/openshift-v4/dependencies/rhcos/rootfs.img"
+
+		files, err := SetRootfsURL(minimal, newURL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(2))
+		r, err := Apply(minimal, files)
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+		f, err := os.CreateTemp(filesDir, "retargeted*.iso")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.Copy(f, r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		for _, file := range []string{defaultGrubFilePath, defaultIsolinuxFilePath} {
+			original, err := ReadFileFromISO(minimal, file)
+			Expect(err).NotTo(HaveOccurred())
+			content, err := ReadFileFromISO(f.Name(), file)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(content).To(HaveLen(len(original)))
+			Expect(string(content)).To(ContainSubstring("coreos.live.rootfs_url=" + newURL))
+			Expect(string(content)).NotTo(ContainSubstring("https://example.com/rootfs.img"))
+			Expect(string(content)).To(MatchRegexp("\n#+ COREOS_KARG_EMBED_AREA"))
+		}
+		grub, err := ReadFileFromISO(f.Name(), defaultGrubFilePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(grub)).To(ContainSubstring("'coreos.live.rootfs_url=" + newURL + "'"))
+	})
+
+	It("fails for full ISOs", func() {
+		_, err := SetRootfsURL(isoFile, "https://example.com/rootfs.img")
+		Expect(err).To(Equal(ErrNoRootfsURL))
+		_, err = GetRootfsURL(isoFile)
+		Expect(err).To(Equal(ErrNoRootfsURL))
+	})
+
+	It("returns the rootfs URL of minimal ISOs", func() {
+		minimal := minimalISO("https://example.com/rootfs.img?arch=x86_64")
+		Expect(GetRootfsURL(minimal)).To(Equal("https://example.com/rootfs.img?arch=x86_64"))
+	})
+
+	It("rejects invalid URLs", func() {
+		for _, u := range []string{"ftp://example.com/rootfs.img", "https://example.com/root fs.img", "rootfs.img"} {
+			_, err := SetRootfsURL(isoFile, u)
+			Expect(err).To(MatchError(ContainSubstring("invalid rootfs URL")))
+		}
+	})
+})
@@ -0,0 +1,30 @@
+package isoeditor
+
+import (
+	"strings"
+)
+
+// s390xCustomizer handles the s390x ISOs, whose kernel arguments are embedded in the boot images
+// and in the parameter files rather than in grub configs
+type s390xCustomizer struct {
+	grubCustomizer
+}
+
+func (c *s390xCustomizer) KargsFileData(isoPath, file string, kargs KernelArguments, policy KargsConflictPolicy) (FileData, error) {
+	// s390x parameter files are regenerated as a whole rather than patched in place, since
+	// zipl reads the complete file as the kernel command line
+	if isS390xParmFile(file) {
+		return s390xParmFileData(isoPath, file, kargs, policy)
+	}
+	// the boot images of secure IPL embed the signed kernel, and the signatures cover the image
+	signed, err := hasS390xSignature(isoPath, "/"+strings.TrimPrefix(file, "/"))
+	if err != nil {
+		return FileData{}, err
+	}
+	if signed {
+		return FileData{}, &ErrS390xSecureIPL{File: file}
+	}
+	// the embed area of the boot images is rewritten with the end marker and the padding
+	// coreos-installer expects
+	return kargsOperationsFileData(isoPath, file, kargs, policy)
+}
@@ -0,0 +1,23 @@
+package isoeditor
+
+import (
+	"strings"
+)
+
+// S390xInsPath is the path of the .ins file of the s390x ISOs, listing the files loaded by the
+// LPAR FTP and z/VM reader boots with their load addresses
+const S390xInsPath = "/generic.ins"
+
+// S390xInsFiles returns the files loaded by an s390x .ins file, relative to its location, in the
+// order they are listed. The lines starting with * are comments.
+func S390xInsFiles(data []byte) []string {
+	var files []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "*") {
+			continue
+		}
+		files = append(files, fields[0])
+	}
+	return files
+}
@@ -0,0 +1,105 @@
+package isoeditor
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+const (
+	// z/VM punches files in 80 bytes records, so lines of the parameter file must not be longer
+	s390xParmFileLineLength = 80
+	// Maximum length of the kernel command line read by zipl from a parameter file
+	s390xMaxCmdlineLength = 896
+)
+
+func isS390xParmFile(filePath string) bool {
+	return strings.HasSuffix(filePath, ".prm")
+}
+
+// ParseS390xParmFile returns the kernel arguments of an s390x parameter file. The kernel joins the
+// lines of the file, and any padding is ignored.
+func ParseS390xParmFile(data []byte) []string {
+	data = bytes.TrimRight(data, "\x00")
+	return strings.Fields(string(data))
+}
+
+// GenerateS390xParmFile returns an s390x parameter file with the given kernel arguments, wrapped
+// so that every line fits in a z/VM punch record.
+func GenerateS390xParmFile(kargs []string) ([]byte, error) {
+	cmdline := strings.Join(kargs, " ")
+	if len(cmdline) > s390xMaxCmdlineLength {
+		return nil, &ErrKargsTooLong{Length: int64(len(cmdline)), Capacity: s390xMaxCmdlineLength}
+	}
+
+	var content bytes.Buffer
+	lineLength := 0
+	for _, arg := range kargs {
+		if lineLength > 0 && lineLength+1+len(arg) > s390xParmFileLineLength {
+			content.WriteByte('\n')
+			lineLength = 0
+		}
+		if lineLength > 0 {
+			content.WriteByte(' ')
+			lineLength++
+		}
+		content.WriteString(arg)
+		lineLength += len(arg)
+	}
+	content.WriteByte('\n')
+	return content.Bytes(), nil
+}
+
+// s390xParmFileData regenerates a parameter file of the ISO with the operations applied to the
+// kernel arguments it currently holds. The arguments are written within the embed area kargs.json
+// describes for the file, or over the whole file otherwise, padded so that the file keeps its size.
+func s390xParmFileData(isoPath, filePath string, kargs KernelArguments, policy KargsConflictPolicy) (FileData, error) {
+	current, err := ReadFileFromISO(isoPath, filePath)
+	if err != nil {
+		return FileData{}, err
+	}
+	area, err := s390xParmEmbedArea(isoPath, filePath, current)
+	if err != nil {
+		return FileData{}, err
+	}
+	areaContent := bytes.TrimRight(current[area.offset:area.offset+area.length], string([]byte{area.pad}))
+	// the arguments span several lines, only another end character ends them
+	if i := bytes.IndexByte(areaContent, area.end); area.end != '\n' && i >= 0 {
+		areaContent = areaContent[:i]
+	}
+	newKargs, err := kargs.Apply(ParseS390xParmFile(areaContent))
+	if err != nil {
+		return FileData{}, err
+	}
+	newKargs, err = ResolveKargsConflicts(newKargs, policy)
+	if err != nil {
+		return FileData{}, err
+	}
+	content, err := GenerateS390xParmFile(newKargs)
+	if err == nil && int64(len(content)) > area.length {
+		err = &ErrKargsTooLong{Length: int64(len(content)), Capacity: area.length}
+	}
+	if err != nil {
+		if tooLong, ok := err.(*ErrKargsTooLong); ok {
+			tooLong.File = filePath
+		}
+		return FileData{}, err
+	}
+	content[len(content)-1] = area.end
+	copy(current[area.offset:], content)
+	copy(current[area.offset+int64(len(content)):area.offset+area.length], bytes.Repeat([]byte{area.pad}, int(area.length)-len(content)))
+	return FileData{Filename: filePath, Data: io.NopCloser(bytes.NewReader(current))}, nil
+}
+
+// s390xParmEmbedArea returns the region of the parameter file holding the kernel arguments, relative
+// to its content: the one kargs.json describes, or the whole file padded with spaces, which the
+// kernel ignores
+func s390xParmEmbedArea(isoPath, filePath string, content []byte) (*kargsEmbedArea, error) {
+	if kargsData, err := ReadFileFromISO(isoPath, kargsConfigFilePath); err == nil {
+		area, err := kargsConfigEmbedArea(kargsData, filePath, content)
+		if err != nil || area != nil {
+			return area, err
+		}
+	}
+	return &kargsEmbedArea{length: int64(len(content)), end: '\n', pad: ' '}, nil
+}
@@ -0,0 +1,99 @@
+package isoeditor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// s390xModuleSignatureMagic ends the components signed for secure IPL, like the kernel modules
+const s390xModuleSignatureMagic = "~Module signature appended~\n"
+
+// s390xSignedFileCandidates are the files of the s390x ISOs that carry the kernel, signed when the
+// ISO is built for secure IPL
+var s390xSignedFileCandidates = []string{s390xKernelPathInISO, kernelPathInISO, "/images/cdboot.img"}
+
+// ErrS390xSecureIPL is returned when a customization would change a file of an s390x ISO carrying
+// components signed for secure IPL
+type ErrS390xSecureIPL struct {
+	File string
+}
+
+func (e *ErrS390xSecureIPL) Error() string {
+	return fmt.Sprintf("%s carries components signed for s390x secure IPL that editing its kernel arguments would invalidate: "+
+		"append the kernel arguments to the parameter files (.prm) only, or use an ISO built without secure IPL", e.File)
+}
+
+// S390xSignedFiles returns the files of the ISO carrying components signed for secure IPL, none
+// when the ISO isn't built for secure IPL
+func S390xSignedFiles(isoPath string) ([]string, error) {
+	var signed []string
+	for _, file := range s390xSignedFileCandidates {
+		ok, err := hasS390xSignature(isoPath, file)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			signed = append(signed, file)
+		}
+	}
+	return signed, nil
+}
+
+// hasS390xSignature tells whether the file of the ISO contains a component with an appended
+// signature, missing files having none. The boot images embed the kernel followed by other
+// components, so the whole file is searched, once until the ISO changes.
+func hasS390xSignature(isoPath, filePath string) (bool, error) {
+	if _, _, err := GetISOFileInfo(filePath, isoPath); err != nil {
+		return false, nil
+	}
+	lookups, err := isoLookups(isoPath)
+	if err != nil {
+		return false, err
+	}
+	isoFileLocations.Lock()
+	signed, ok := lookups.signed[filePath]
+	isoFileLocations.Unlock()
+	if ok {
+		return signed, nil
+	}
+
+	signed, err = searchS390xSignature(isoPath, filePath)
+	if err != nil {
+		return false, err
+	}
+	isoFileLocations.Lock()
+	lookups.signed[filePath] = signed
+	isoFileLocations.Unlock()
+	return signed, nil
+}
+
+// searchS390xSignature searches the whole file of the ISO for the signature magic
+func searchS390xSignature(isoPath, filePath string) (bool, error) {
+	f, err := GetFileFromISO(isoPath, filePath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	magic := []byte(s390xModuleSignatureMagic)
+	buf := make([]byte, 1<<20)
+	// the end of the previous chunk, in case the magic spans two chunks
+	kept := 0
+	for {
+		n, err := f.Read(buf[kept:])
+		if bytes.Contains(buf[:kept+n], magic) {
+			return true, nil
+		}
+		if err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if kept+n >= len(magic) {
+			kept = copy(buf, buf[kept+n-len(magic)+1:kept+n])
+		} else {
+			kept += n
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package isoeditor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
+	"github.com/thoas/go-funk"
+)
+
+const (
+	iscsiInitiatorNamePath = "/etc/iscsi/initiatorname.iscsi"
+	multipathConfPath      = "/etc/multipath.conf"
+	// multipath.conf as written by mpathconf --enable, which rd.multipath=default runs
+	defaultMultipathConf = `defaults {
+    user_friendly_names yes
+    find_multipaths yes
+}
+`
+)
+
+var iscsiIQNRegexp = regexp.MustCompile(`^iqn\.[0-9]{4}-[0-9]{2}\.[a-z0-9][-a-z0-9.]*(:[^\s]+)?$`)
+
+// SANBoot configures the discovery environment of hosts booting from a SAN
+type SANBoot struct {
+	// ISCSI logs in to the targets of the iSCSI Boot Firmware Table
+	ISCSI bool
+	// ISCSIInitiatorName overrides the initiator name of the firmware, e.g. iqn.2024-01.com.example:host0
+	ISCSIInitiatorName string
+	// Multipath assembles multipath devices in the initramfs
+	Multipath bool
+}
+
+// Validate checks that at least one mode is enabled and that the initiator name is an IQN
+func (s SANBoot) Validate() error {
+	if !s.ISCSI && !s.Multipath {
+		return fmt.Errorf("neither iSCSI nor multipath is enabled")
+	}
+	if s.ISCSIInitiatorName != "" {
+		if !s.ISCSI {
+			return fmt.Errorf("iSCSI initiator name set without enabling iSCSI")
+		}
+		if !iscsiIQNRegexp.MatchString(s.ISCSIInitiatorName) {
+			return fmt.Errorf("invalid iSCSI initiator name %q, expected an IQN", s.ISCSIInitiatorName)
+		}
+	}
+	return nil
+}
+
+// Kargs returns the dracut kernel arguments enabling the SAN boot
+func (s SANBoot) Kargs() []string {
+	var kargs []string
+	if s.ISCSI {
+		kargs = append(kargs, "rd.iscsi.firmware=1")
+		if s.ISCSIInitiatorName != "" {
+			kargs = append(kargs, "rd.iscsi.initiator="+s.ISCSIInitiatorName)
+		}
+	}
+	if s.Multipath {
+		kargs = append(kargs, "rd.multipath=default")
+	}
+	return kargs
+}
+
+// Entries returns the configuration files of the SAN boot to be added to the live initramfs
+func (s SANBoot) Entries() ([]cpio.Entry, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	var entries []cpio.Entry
+	if s.ISCSIInitiatorName != "" {
+		entries = append(entries, cpio.Entry{Path: iscsiInitiatorNamePath, Mode: 0o644, Content: []byte(fmt.Sprintf("InitiatorName=%s\n", s.ISCSIInitiatorName))})
+	}
+	if s.Multipath {
+		entries = append(entries, cpio.Entry{Path: multipathConfPath, Mode: 0o644, Content: []byte(defaultMultipathConf)})
+	}
+	return entries, nil
+}
+
+// requiredInitrdFiles returns files of the initramfs installed by the dracut modules the SAN boot
+// relies on, by module name
+func (s SANBoot) requiredInitrdFiles() map[string]string {
+	files := map[string]string{}
+	if s.ISCSI {
+		files["iscsi"] = "usr/sbin/iscsistart"
+	}
+	if s.Multipath {
+		files["multipath"] = "usr/sbin/multipathd"
+	}
+	return files
+}
+
+// CheckSANBootSupport checks that the initramfs of the ISO was built with the dracut modules
+// needed by the SAN boot. It decompresses the whole initramfs.
+func CheckSANBootSupport(isoPath string, s SANBoot) error {
+	initrd, err := bootArtifact(isoPath, "/"+initrdPathInISO)
+	if err != nil {
+		return err
+	}
+	r, err := initrd.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	names, err := cpio.ListFiles(r)
+	if err != nil {
+		return fmt.Errorf("failed to list the files of the initramfs: %w", err)
+	}
+
+	var missing []string
+	for module, file := range s.requiredInitrdFiles() {
+		if !funk.ContainsString(names, file) {
+			missing = append(missing, module)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("the initramfs of %s lacks the dracut modules %s", isoPath, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// NewSANBootReader returns the files of the ISO that need to be overwritten in order to embed
+// the ignition config and boot hosts from a SAN: the kernel arguments are appended to the boot
+// configs and the configuration files are added to the live initramfs. The architecture of the
+// ISO is detected from its content when arch is empty.
+func NewSANBootReader(isoPath, arch string, ignitionContent *IgnitionContent, s SANBoot) ([]FileData, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return nil, err
+	}
+	if err = CheckSANBootSupport(isoPath, s); err != nil {
+		return nil, err
+	}
+
+	kargsFiles, err := NewKargsReader(isoPath, arch, " "+strings.Join(s.Kargs(), " "), KargsConflictKeepAll)
+	if err != nil {
+		return nil, err
+	}
+	ignitionFiles, err := NewIgnitionImageReaderWithFiles(isoPath, ignitionContent, entries...)
+	if err != nil {
+		closeFileData(kargsFiles)
+		return nil, err
+	}
+	return append(kargsFiles, ignitionFiles...), nil
+}
@@ -0,0 +1,81 @@
+package isoeditor
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
+)
+
+var _ = Describe("SANBoot", func() {
+	It("returns the kernel arguments and configuration files", func() {
+		sanBoot := SANBoot{ISCSI: true, ISCSIInitiatorName: "iqn.2024-01.com.example:host0", Multipath: true}
+		Expect(sanBoot.Kargs()).To(Equal([]string{"rd.iscsi.firmware=1", "rd.iscsi.initiator=iqn.2024-01.com.example:host0", "rd.multipath=default"}))
+		Expect(sanBoot.Entries()).To(Equal([]cpio.Entry{
+			{Path: "/etc/iscsi/initiatorname.iscsi", Mode: 0o644, Content: []byte("InitiatorName=iqn.2024-01.com.example:host0\n")},
+			{Path: "/etc/multipath.conf", Mode: 0o644, Content: []byte(defaultMultipathConf)},
+		}))
+	})
+
+	It("validates the configuration", func() {
+		Expect(SANBoot{}.Validate()).To(MatchError("neither iSCSI nor multipath is enabled"))
+		Expect(SANBoot{Multipath: true, ISCSIInitiatorName: "iqn.2024-01.com.example:host0"}.Validate()).To(MatchError(ContainSubstring("without enabling iSCSI")))
+		Expect(SANBoot{ISCSI: true, ISCSIInitiatorName: "host0"}.Validate()).To(MatchError(ContainSubstring("expected an IQN")))
+	})
+
+	Context("with an ISO", func() {
+		var (
+			filesDir string
+			isoFile  string
+		)
+
+		createISO := func(initrdFiles ...string) {
+			filesDir, isoFile = createFullTestFiles("Assisted123", []byte("this is rootfs"))
+			var entries []cpio.Entry
+			for _, f := range initrdFiles {
+				entries = append(entries, cpio.Entry{Path: f, Mode: 0o755, Content: []byte("binary")})
+			}
+			initrd, err := cpio.Archive(entries...)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(filesDir, "images/pxeboot/initrd.img"), initrd, 0600)).To(Succeed())
+			Expect(os.Remove(isoFile)).To(Succeed())
+			cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", "Assisted123", "-o", isoFile, filesDir)
+			Expect(cmd.Run()).To(Succeed())
+		}
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(filesDir)).To(Succeed())
+			Expect(os.Remove(isoFile)).To(Succeed())
+		})
+
+		It("appends the kernel arguments and embeds the configuration", func() {
+			createISO("usr/sbin/iscsistart", "usr/sbin/multipathd")
+			files, err := NewSANBootReader(isoFile, "", &IgnitionContent{Config: []byte(`{"ignition":{"version":"3.2.0"}}`)}, SANBoot{ISCSI: true, Multipath: true})
+			Expect(err).NotTo(HaveOccurred())
+			defer closeFileData(files)
+
+			var names []string
+			for _, f := range files {
+				names = append(names, f.Filename)
+				if f.Filename == defaultGrubFilePath {
+					content, err := io.ReadAll(f.Data)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(content)).To(ContainSubstring("rd.iscsi.firmware=1 rd.multipath=default"))
+				}
+			}
+			Expect(names).To(ContainElements(defaultGrubFilePath, defaultIsolinuxFilePath, "images/ignition.img"))
+		})
+
+		It("fails when the initramfs lacks the dracut modules", func() {
+			createISO("usr/sbin/iscsistart")
+			Expect(CheckSANBootSupport(isoFile, SANBoot{ISCSI: true})).To(Succeed())
+			_, err := NewSANBootReader(isoFile, "", &IgnitionContent{Config: []byte(`{"ignition":{"version":"3.2.0"}}`)}, SANBoot{ISCSI: true, Multipath: true})
+			Expect(err).To(MatchError(ContainSubstring("lacks the dracut modules multipath")))
+		})
+	})
+})
@@ -0,0 +1,142 @@
+package isoeditor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	// sectors of the empty volume: the system area, the primary volume descriptor, the terminator,
+	// the two path tables and the root directory
+	seedPVDSector        = isoVolumeDescriptorStart
+	seedLPathTableSector = isoVolumeDescriptorStart + 2
+	seedMPathTableSector = isoVolumeDescriptorStart + 3
+	seedRootSector       = isoVolumeDescriptorStart + 4
+	seedVolumeSectors    = isoVolumeDescriptorStart + 5
+	// Rock Ridge extension announced in the root directory, so that readers use the names
+	seedRockRidgeID          = "RRIP_1991A"
+	seedRockRidgeDescription = "THE ROCK RIDGE INTERCHANGE PROTOCOL PROVIDES SUPPORT FOR POSIX FILE SYSTEM SEMANTICS"
+)
+
+// CreateSeedISO returns an ISO labeled volumeLabel holding the given files, by path relative to its
+// root, e.g. the NoCloud or config drive seeds read by cloud-init and ignition. An empty Rock Ridge
+// volume is written to workDir and the files are added with ISOWriter, so the names are kept as is.
+func CreateSeedISO(workDir, volumeLabel string, files map[string][]byte, modTime time.Time) (ImageReader, error) {
+	if len(volumeLabel) > 32 {
+		return nil, fmt.Errorf("volume label %s is too long", volumeLabel)
+	}
+	f, err := os.CreateTemp(workDir, "seed*.iso")
+	if err != nil {
+		return nil, err
+	}
+	// the returned ISO keeps the empty volume open
+	defer os.Remove(f.Name())
+	_, err = f.Write(emptyISO(volumeLabel, modTime))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := NewISOWriter(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := w.AddFile(name, bytes.NewReader(files[name])); err != nil {
+			return nil, err
+		}
+	}
+	return w.Reader()
+}
+
+// emptyISO returns an ISO9660 volume with Rock Ridge extensions and an empty root directory
+func emptyISO(volumeLabel string, modTime time.Time) []byte {
+	iso := make([]byte, seedVolumeSectors*isoSectorSize)
+	modTime = modTime.UTC()
+
+	pvd := iso[seedPVDSector*isoSectorSize:]
+	pvd[0] = isoVolumeDescriptorPrimary
+	copy(pvd[1:6], "CD001")
+	pvd[6] = 1
+	for _, field := range [][2]int{{8, 40}, {40, 72}, {190, 813}} {
+		copy(pvd[field[0]:field[1]], bytes.Repeat([]byte{' '}, field[1]-field[0]))
+	}
+	copy(pvd[40:72], volumeLabel)
+	putBothEndian32(pvd[80:], seedVolumeSectors)
+	putBothEndian16(pvd[120:], 1)
+	putBothEndian16(pvd[124:], 1)
+	putBothEndian16(pvd[128:], isoSectorSize)
+	// the root directory is the only entry of the path tables
+	putBothEndian32(pvd[132:], 10)
+	binary.LittleEndian.PutUint32(pvd[140:], seedLPathTableSector)
+	binary.BigEndian.PutUint32(pvd[148:], seedMPathTableSector)
+	copy(pvd[156:190], seedDirectoryRecord([]byte{0}, modTime, nil))
+	date := []byte(modTime.Format("20060102150405") + "00\x00")
+	copy(pvd[813:], date)
+	copy(pvd[830:], date)
+	copy(pvd[847:], "0000000000000000\x00")
+	copy(pvd[864:], "0000000000000000\x00")
+	pvd[881] = 1
+
+	terminator := iso[(seedPVDSector+1)*isoSectorSize:]
+	terminator[0] = isoVolumeDescriptorTerminator
+	copy(terminator[1:6], "CD001")
+	terminator[6] = 1
+
+	for _, table := range []struct {
+		lba   int64
+		order binary.ByteOrder
+	}{{seedLPathTableSector, binary.LittleEndian}, {seedMPathTableSector, binary.BigEndian}} {
+		entry := iso[table.lba*isoSectorSize:]
+		entry[0] = 1
+		table.order.PutUint32(entry[2:], seedRootSector)
+		table.order.PutUint16(entry[6:], 1)
+	}
+
+	// the SUSP SP entry of . tells the readers that Rock Ridge is used, the PX entries give the
+	// serial number 1 to the root, so that ISOWriter numbers the added files after it
+	px := make([]byte, 44)
+	copy(px, []byte{'P', 'X', byte(len(px)), 1})
+	putBothEndian32(px[4:], isoWriterDirMode)
+	putBothEndian32(px[12:], 2)
+	putBothEndian32(px[36:], 1)
+	er := append([]byte{'E', 'R', byte(8 + len(seedRockRidgeID) + len(seedRockRidgeDescription)), 1,
+		byte(len(seedRockRidgeID)), byte(len(seedRockRidgeDescription)), 0, 1}, seedRockRidgeID+seedRockRidgeDescription...)
+	dot := seedDirectoryRecord([]byte{0}, modTime, append(append([]byte{'S', 'P', 7, 1, 0xbe, 0xef, 0}, px...), er...))
+	dotdot := seedDirectoryRecord([]byte{1}, modTime, px)
+	root := iso[seedRootSector*isoSectorSize:]
+	copy(root, dot)
+	copy(root[len(dot):], dotdot)
+	return iso
+}
+
+// seedDirectoryRecord returns a record of the root directory of the empty volume
+func seedDirectoryRecord(identifier []byte, modTime time.Time, systemUse []byte) []byte {
+	systemUseStart := 33 + len(identifier) + (len(identifier)+1)%2
+	length := systemUseStart + len(systemUse)
+	length += length % 2
+	raw := make([]byte, length)
+	raw[0] = byte(length)
+	putBothEndian32(raw[2:], seedRootSector)
+	putBothEndian32(raw[10:], isoSectorSize)
+	copy(raw[18:25], []byte{byte(modTime.Year() - 1900), byte(modTime.Month()), byte(modTime.Day()),
+		byte(modTime.Hour()), byte(modTime.Minute()), byte(modTime.Second()), 0})
+	raw[25] = isoRecordFlagDirectory
+	putBothEndian16(raw[28:], 1)
+	raw[32] = byte(len(identifier))
+	copy(raw[33:], identifier)
+	copy(raw[systemUseStart:], systemUse)
+	return raw
+}
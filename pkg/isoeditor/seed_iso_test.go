@@ -0,0 +1,72 @@
+package isoeditor
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CreateSeedISO", func() {
+	var workDir string
+
+	BeforeEach(func() {
+		var err error
+		workDir, err = os.MkdirTemp("", "seedISOTest")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(workDir)).To(Succeed())
+	})
+
+	writeSeed := func(label string, files map[string][]byte) string {
+		r, err := CreateSeedISO(workDir, label, files, time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+		isoPath := filepath.Join(workDir, "received.iso")
+		content, err := io.ReadAll(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(isoPath, content, 0600)).To(Succeed())
+		return isoPath
+	}
+
+	It("builds a labeled ISO with the files and their names", func() {
+		isoPath := writeSeed("config-2", map[string][]byte{
+			"openstack/latest/user_data":      []byte(`{"ignition": {"version": "3.2.0"}}`),
+			"openstack/latest/meta_data.json": []byte(`{"uuid": "abc"}`),
+		})
+
+		Expect(VolumeIdentifier(isoPath)).To(Equal("config-2"))
+		Expect(ReadFileFromISO(isoPath, "/openstack/latest/user_data")).To(Equal([]byte(`{"ignition": {"version": "3.2.0"}}`)))
+		Expect(ReadFileFromISO(isoPath, "/openstack/latest/meta_data.json")).To(Equal([]byte(`{"uuid": "abc"}`)))
+		files, err := ListISOFiles(isoPath)
+		Expect(err).NotTo(HaveOccurred())
+		var paths []string
+		for _, file := range files {
+			paths = append(paths, file.Path)
+		}
+		Expect(paths).To(Equal([]string{"/openstack", "/openstack/latest", "/openstack/latest/meta_data.json", "/openstack/latest/user_data"}))
+
+		// the empty volume isn't kept
+		entries, err := os.ReadDir(workDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("builds an ISO without files", func() {
+		isoPath := writeSeed("cidata", nil)
+		Expect(VolumeIdentifier(isoPath)).To(Equal("cidata"))
+		files, err := ListISOFiles(isoPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(BeEmpty())
+	})
+
+	It("rejects labels longer than the volume identifier", func() {
+		_, err := CreateSeedISO(workDir, "a-label-longer-than-thirty-two-bytes", nil, time.Now())
+		Expect(err).To(HaveOccurred())
+	})
+})
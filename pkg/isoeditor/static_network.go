@@ -0,0 +1,147 @@
+package isoeditor
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// StaticNetworkConfigDir is the directory of the initramfs holding a subdirectory per host,
+	// as expected by the pre-network-manager-config script of assisted-service
+	StaticNetworkConfigDir = "/etc/assisted/network"
+	// MACInterfaceMapFile maps the MAC addresses of a host to the interface names used in its
+	// keyfiles, one "mac=interface" line per NIC
+	MACInterfaceMapFile = "mac_interface.ini"
+	nmconnectionSuffix  = ".nmconnection"
+)
+
+// HostStaticNetworkConfig is the static network configuration of a host, given either as nmstate
+// YAML or as NetworkManager keyfiles
+type HostStaticNetworkConfig struct {
+	// NetworkYAML is converted to keyfiles with nmstatectl
+	NetworkYAML string
+	// Keyfiles by file name, used when NetworkYAML is empty
+	Keyfiles map[string]string
+	// MACInterfaceMap maps the MAC addresses of the host to the interface names of the config
+	MACInterfaceMap map[string]string
+}
+
+// StaticNetworkEntries returns the initramfs files configuring the network of the hosts, to be
+// archived with cpio.Archive and used as ramdisk content. Each host gets a hostN directory.
+func StaticNetworkEntries(hosts []HostStaticNetworkConfig, nmstateHandler NmstateHandler) ([]cpio.Entry, error) {
+	var entries []cpio.Entry
+	for i, host := range hosts {
+		hostDir := path.Join(StaticNetworkConfigDir, fmt.Sprintf("host%d", i))
+
+		mapping, err := macInterfaceMapContent(host.MACInterfaceMap)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAC to interface mapping of host %d: %w", i, err)
+		}
+
+		keyfiles := host.Keyfiles
+		if host.NetworkYAML != "" {
+			if keyfiles, err = nmstateHandler.GenerateKeyfiles(host.NetworkYAML); err != nil {
+				return nil, fmt.Errorf("failed to generate keyfiles of host %d: %w", i, err)
+			}
+		}
+		if len(keyfiles) == 0 {
+			return nil, fmt.Errorf("no network configuration for host %d", i)
+		}
+
+		names := make([]string, 0, len(keyfiles))
+		for name := range keyfiles {
+			if name != filepath.Base(name) || name == "." || name == ".." {
+				return nil, fmt.Errorf("invalid keyfile name %q for host %d", name, i)
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			// NetworkManager ignores keyfiles readable by other users
+			entries = append(entries, cpio.Entry{
+				Path:    path.Join(hostDir, strings.TrimSuffix(name, nmconnectionSuffix)+nmconnectionSuffix),
+				Mode:    0o600,
+				Content: []byte(keyfiles[name]),
+			})
+		}
+		entries = append(entries, cpio.Entry{
+			Path:    path.Join(hostDir, MACInterfaceMapFile),
+			Content: mapping,
+		})
+	}
+	return entries, nil
+}
+
+// NewStaticNetworkRamDisk returns the ramdisk content with the static network configuration of
+// the hosts
+func NewStaticNetworkRamDisk(hosts []HostStaticNetworkConfig, nmstateHandler NmstateHandler) ([]byte, error) {
+	entries, err := StaticNetworkEntries(hosts, nmstateHandler)
+	if err != nil {
+		return nil, err
+	}
+	return cpio.Archive(entries...)
+}
+
+func macInterfaceMapContent(macInterfaceMap map[string]string) ([]byte, error) {
+	if len(macInterfaceMap) == 0 {
+		return nil, fmt.Errorf("no MAC addresses")
+	}
+	lines := make([]string, 0, len(macInterfaceMap))
+	for mac, iface := range macInterfaceMap {
+		hw, err := net.ParseMAC(mac)
+		if err != nil {
+			return nil, err
+		}
+		if iface == "" || strings.ContainsAny(iface, "=\n /") {
+			return nil, fmt.Errorf("invalid interface name %q for %s", iface, mac)
+		}
+		lines = append(lines, fmt.Sprintf("%s=%s\n", hw.String(), iface))
+	}
+	sort.Strings(lines)
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// GenerateKeyfiles converts the nmstate YAML to NetworkManager keyfiles with nmstatectl gc
+func (n *nmstateHandler) GenerateKeyfiles(nmstateYAML string) (map[string]string, error) {
+	f, err := os.CreateTemp(n.workDir, "nmstate*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(nmstateYAML)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := n.executer.Execute(fmt.Sprintf("nmstatectl gc %s", f.Name()), n.workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// the output lists the keyfiles as [name, content] pairs
+	var generated struct {
+		NetworkManager [][]string `yaml:"NetworkManager"`
+	}
+	if err := yaml.Unmarshal([]byte(output), &generated); err != nil {
+		return nil, fmt.Errorf("failed to parse nmstatectl output: %w", err)
+	}
+	keyfiles := map[string]string{}
+	for _, keyfile := range generated.NetworkManager {
+		if len(keyfile) != 2 {
+			return nil, fmt.Errorf("unexpected keyfile entry in nmstatectl output: %v", keyfile)
+		}
+		keyfiles[keyfile[0]] = keyfile[1]
+	}
+	return keyfiles, nil
+}
@@ -0,0 +1,122 @@
+package isoeditor
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
+)
+
+const testNmstateYAML = `interfaces:
+- name: eth0
+  type: ethernet
+  state: up
+  ipv4:
+    enabled: true
+    address:
+    - ip: 192.0.2.10
+      prefix-length: 24
+`
+
+var _ = Describe("StaticNetworkEntries", func() {
+	var (
+		ctrl           *gomock.Controller
+		nmstateHandler *MockNmstateHandler
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		nmstateHandler = NewMockNmstateHandler(ctrl)
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	It("converts nmstate YAML and keeps pre-generated keyfiles", func() {
+		nmstateHandler.EXPECT().GenerateKeyfiles(testNmstateYAML).Return(map[string]string{"eth0.nmconnection": "[connection]\nid=eth0\n"}, nil)
+
+		entries, err := StaticNetworkEntries([]HostStaticNetworkConfig{
+			{
+				NetworkYAML:     testNmstateYAML,
+				MACInterfaceMap: map[string]string{"52:54:00:AA:BB:01": "eth0"},
+			},
+			{
+				Keyfiles: map[string]string{"eth1": "[connection]\nid=eth1\n", "bond0.nmconnection": "[connection]\nid=bond0\n"},
+				MACInterfaceMap: map[string]string{
+					"52:54:00:aa:bb:03": "eth2",
+					"52:54:00:aa:bb:02": "eth1",
+				},
+			},
+		}, nmstateHandler)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(Equal([]cpio.Entry{
+			{Path: "/etc/assisted/network/host0/eth0.nmconnection", Mode: 0o600, Content: []byte("[connection]\nid=eth0\n")},
+			{Path: "/etc/assisted/network/host0/mac_interface.ini", Content: []byte("52:54:00:aa:bb:01=eth0\n")},
+			{Path: "/etc/assisted/network/host1/bond0.nmconnection", Mode: 0o600, Content: []byte("[connection]\nid=bond0\n")},
+			{Path: "/etc/assisted/network/host1/eth1.nmconnection", Mode: 0o600, Content: []byte("[connection]\nid=eth1\n")},
+			{Path: "/etc/assisted/network/host1/mac_interface.ini", Content: []byte("52:54:00:aa:bb:02=eth1\n52:54:00:aa:bb:03=eth2\n")},
+		}))
+	})
+
+	It("fails when the nmstate conversion fails", func() {
+		nmstateHandler.EXPECT().GenerateKeyfiles(gomock.Any()).Return(nil, errors.New("invalid YAML"))
+		_, err := StaticNetworkEntries([]HostStaticNetworkConfig{{
+			NetworkYAML:     "interfaces: [",
+			MACInterfaceMap: map[string]string{"52:54:00:aa:bb:01": "eth0"},
+		}}, nmstateHandler)
+		Expect(err).To(MatchError(ContainSubstring("invalid YAML")))
+	})
+
+	It("rejects invalid MAC addresses", func() {
+		_, err := StaticNetworkEntries([]HostStaticNetworkConfig{{
+			Keyfiles:        map[string]string{"eth0": ""},
+			MACInterfaceMap: map[string]string{"not-a-mac": "eth0"},
+		}}, nmstateHandler)
+		Expect(err).To(MatchError(ContainSubstring("invalid MAC to interface mapping of host 0")))
+	})
+
+	It("rejects keyfile names with a path", func() {
+		_, err := StaticNetworkEntries([]HostStaticNetworkConfig{{
+			Keyfiles:        map[string]string{"../eth0": ""},
+			MACInterfaceMap: map[string]string{"52:54:00:aa:bb:01": "eth0"},
+		}}, nmstateHandler)
+		Expect(err).To(MatchError(ContainSubstring("invalid keyfile name")))
+	})
+
+	It("requires a network configuration", func() {
+		_, err := NewStaticNetworkRamDisk([]HostStaticNetworkConfig{{
+			MACInterfaceMap: map[string]string{"52:54:00:aa:bb:01": "eth0"},
+		}}, nmstateHandler)
+		Expect(err).To(MatchError("no network configuration for host 0"))
+	})
+})
+
+var _ = Describe("GenerateKeyfiles", func() {
+	It("parses the keyfiles generated by nmstatectl", func() {
+		workDir, err := os.MkdirTemp("", "nmstate")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(workDir)
+
+		ctrl := gomock.NewController(GinkgoT())
+		defer ctrl.Finish()
+		executer := NewMockExecuter(ctrl)
+		executer.EXPECT().Execute(gomock.Any(), workDir).DoAndReturn(func(command, _ string) (string, error) {
+			Expect(command).To(HavePrefix("nmstatectl gc "))
+			content, err := os.ReadFile(strings.TrimPrefix(command, "nmstatectl gc "))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal(testNmstateYAML))
+			return "---\nNetworkManager:\n- - eth0.nmconnection\n  - |\n    [connection]\n    id=eth0\n", nil
+		})
+
+		keyfiles, err := NewNmstateHandler(workDir, executer).GenerateKeyfiles(testNmstateYAML)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keyfiles).To(Equal(map[string]string{"eth0.nmconnection": "[connection]\nid=eth0\n"}))
+		Expect(os.ReadDir(workDir)).To(BeEmpty())
+	})
+})
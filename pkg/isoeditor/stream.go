@@ -18,7 +18,7 @@ type ImageReader = overlay.OverlayReader
 
 type BoundariesFinder func(filePath, isoPath string) (int64, int64, error)
 
-type StreamGeneratorFunc func(isoPath string, ignitionContent *IgnitionContent, ramdiskContent, kargs []byte) (ImageReader, error)
+type StreamGeneratorFunc func(isoPath string, ignitionContent *IgnitionContent, ramdiskContent []byte, kargs KernelArguments) (ImageReader, error)
 
 type ignitionInfo struct {
 	File   string `json:"file,omitempty"`
@@ -26,8 +26,22 @@ type ignitionInfo struct {
 	Offset int64  `json:"offset,omitempty"`
 }
 
-func NewRHCOSStreamReader(isoPath string, ignitionContent *IgnitionContent, ramdiskContent []byte, kargs []byte) (ImageReader, error) {
-	_, r, err := ignitionOverlay(isoPath, ignitionContent, false)
+// NewRHCOSStreamReader returns the ISO with the ignition and ramdisk embedded, and the kernel
+// arguments operations applied to its boot configs
+func NewRHCOSStreamReader(isoPath string, ignitionContent *IgnitionContent, ramdiskContent []byte, kargs KernelArguments) (ImageReader, error) {
+	return newRHCOSStreamReader(isoPath, ignitionContent, ramdiskContent, kargs, KargsConflictKeepAll)
+}
+
+// RHCOSStreamGenerator returns a StreamGeneratorFunc generating the ISOs as NewRHCOSStreamReader,
+// with the parameters set more than once in the kernel arguments resolved according to policy
+func RHCOSStreamGenerator(policy KargsConflictPolicy) StreamGeneratorFunc {
+	return func(isoPath string, ignitionContent *IgnitionContent, ramdiskContent []byte, kargs KernelArguments) (ImageReader, error) {
+		return newRHCOSStreamReader(isoPath, ignitionContent, ramdiskContent, kargs, policy)
+	}
+}
+
+func newRHCOSStreamReader(isoPath string, ignitionContent *IgnitionContent, ramdiskContent []byte, kargs KernelArguments, policy KargsConflictPolicy) (ImageReader, error) {
+	_, r, err := ignitionOverlay(isoPath, ignitionContent, nil, false)
 	if err != nil {
 		return nil, err
 	}
@@ -39,32 +53,62 @@ func NewRHCOSStreamReader(isoPath string, ignitionContent *IgnitionContent, ramd
 		}
 	}
 
-	if kargs != nil {
-		files, err := KargsFiles(isoPath)
+	if len(kargs) > 0 {
+		// the boot configs are edited by the customizer of the architecture of the ISO, as
+		// NewKargsOperationsReader does
+		files, err := NewKargsOperationsReader(isoPath, "", kargs, policy)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to read files to patch for kernel arguments")
+			r.Close()
+			return nil, errors.Wrap(err, "failed to create overwrite reader for kernel arguments")
+		}
+		// the s390x boot media load the initrd with its address and size next to the parameter files
+		addrsize, err := initrdAddrsizeFileData(isoPath)
+		if err != nil {
+			closeFileData(files)
+			r.Close()
+			return nil, errors.Wrap(err, "failed to create overwrite reader for initrd.addrsize")
 		}
-		for _, file := range files {
-			r, err = readerForKargsContent(isoPath, file, r, bytes.NewReader(kargs))
-			if err != nil {
-				return nil, errors.Wrapf(err, "failed to create overwrite reader for kernel arguments in file \"%s\"", file)
-			}
+		files = append(files, addrsize...)
+		applied, err := applyFileData(isoPath, r, files)
+		if err != nil {
+			r.Close()
+			return nil, errors.Wrap(err, "failed to create overwrite reader for kernel arguments")
 		}
+		r = applied
 	}
 
 	return r, nil
 }
 
-func ignitionOverlay(isoPath string, ignitionContent *IgnitionContent, allowOverflow bool) (*ignitionInfo, overlay.OverlayReader, error) {
+// ignitionOverlay embeds the ignition archive in the ISO, followed by the extra archive if any
+func ignitionOverlay(isoPath string, ignitionContent *IgnitionContent, extra []byte, allowOverflow bool) (*ignitionInfo, overlay.OverlayReader, error) {
 	isoReader, err := os.Open(isoPath)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	ignitionReader, err := ignitionContent.Archive()
+	var ignitionReader *bytes.Reader
+	if allowOverflow {
+		ignitionReader, err = ignitionContent.Archive()
+	} else {
+		var capacity int64
+		_, capacity, err = (&ignitionBoundaryFinder{}).findBoundaries(ignitionImagePath, isoPath)
+		if err == nil {
+			ignitionReader, err = ignitionContent.archiveWithin(capacity - int64(len(extra)))
+		}
+	}
 	if err != nil {
+		isoReader.Close()
 		return nil, nil, err
 	}
+	if len(extra) > 0 {
+		archive, err := io.ReadAll(ignitionReader)
+		if err != nil {
+			isoReader.Close()
+			return nil, nil, err
+		}
+		ignitionReader = bytes.NewReader(append(archive, extra...))
+	}
 
 	ibf := &ignitionBoundaryFinder{
 		allowOverflow: allowOverflow,
@@ -84,7 +128,7 @@ func ignitionOverlay(isoPath string, ignitionContent *IgnitionContent, allowOver
 		}
 		paddingLen := ibf.info.Length - ibf.dataSize
 		paddingOverlay := overlay.Overlay{
-			Reader: bytes.NewReader(bytes.Repeat([]byte{0}, int(paddingLen))),
+			Reader: overlay.NewPaddedReader(nil, paddingLen, 0),
 			Offset: offset + ibf.info.Offset + ibf.dataSize,
 			Length: paddingLen,
 		}
@@ -0,0 +1,117 @@
+package isoeditor
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// streamMemoryLimit is the most a customized stream may allocate, whatever the size of the
+// files of the ISO: the pooled copy buffer and the customization, but none of the files
+const streamMemoryLimit = 4 * 1024 * 1024
+
+// largeFileSize is the size of the files of the ISOs of the tests, beyond streamMemoryLimit so
+// that any path buffering a whole file fails them
+const largeFileSize = 16 * 1024 * 1024
+
+var _ = Describe("stream memory", func() {
+	var (
+		filesDir string
+		isoFile  string
+	)
+
+	// enlarge grows the files of the ISO that are customized or read by the streams
+	enlarge := func() {
+		for _, file := range []string{"images/ignition.img", "images/pxeboot/initrd.img", "images/pxeboot/rootfs.img"} {
+			f, err := os.OpenFile(filepath.Join(filesDir, file), os.O_CREATE|os.O_WRONLY, 0600)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.Truncate(largeFileSize)).To(Succeed())
+			Expect(f.Close()).To(Succeed())
+		}
+		Expect(os.Remove(isoFile)).To(Succeed())
+		cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", "Assisted123", "-o", isoFile, filesDir)
+		Expect(cmd.Run()).To(Succeed())
+	}
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filesDir)).To(Succeed())
+		Expect(os.Remove(isoFile)).To(Succeed())
+	})
+
+	// expectBoundedStream fails when generating and reading the stream allocates more than
+	// streamMemoryLimit
+	expectBoundedStream := func(generate func() (io.ReadCloser, error)) {
+		runtime.GC()
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+
+		r, err := generate()
+		Expect(err).NotTo(HaveOccurred())
+		n, err := io.Copy(io.Discard, r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Close()).To(Succeed())
+
+		runtime.ReadMemStats(&after)
+		Expect(n).To(BeNumerically(">=", largeFileSize))
+		Expect(after.TotalAlloc - before.TotalAlloc).To(BeNumerically("<", streamMemoryLimit))
+	}
+
+	ignition := &IgnitionContent{Config: []byte(`{"ignition": {"version": "3.2.0"}}`)}
+
+	Context("with a full ISO", func() {
+		BeforeEach(func() {
+			filesDir, isoFile = createTestFiles("Assisted123")
+			enlarge()
+		})
+
+		It("streams the ISO with the ignition, ramdisk and kernel arguments", func() {
+			expectBoundedStream(func() (io.ReadCloser, error) {
+				return NewRHCOSStreamReader(isoFile, ignition, []byte("ramdisk"), AppendKernelArguments([]string{"p1"}))
+			})
+		})
+
+		It("streams the initrd with the ignition", func() {
+			expectBoundedStream(func() (io.ReadCloser, error) {
+				return NewInitRamFSStreamReaderFromISO(isoFile, ignition)
+			})
+		})
+
+		It("streams the ignition image", func() {
+			expectBoundedStream(func() (io.ReadCloser, error) {
+				files, err := NewIgnitionImageReader(isoFile, ignition)
+				if err != nil {
+					return nil, err
+				}
+				return files[0].Data, nil
+			})
+		})
+
+		It("applies the ignition image", func() {
+			expectBoundedStream(func() (io.ReadCloser, error) {
+				files, err := NewIgnitionImageReader(isoFile, ignition)
+				if err != nil {
+					return nil, err
+				}
+				return Apply(isoFile, files)
+			})
+		})
+	})
+
+	Context("with a full ISO streamed as a minimal ISO", func() {
+		BeforeEach(func() {
+			filesDir, isoFile = createFullTestFiles("Assisted123", []byte("this is rootfs"))
+			enlarge()
+		})
+
+		It("streams the minimal ISO with the ignition, ramdisk and kernel arguments", func() {
+			expectBoundedStream(func() (io.ReadCloser, error) {
+				return NewMinimalISOStreamReader(isoFile, "https://example.com/rootfs.img", "x86_64", ignition, []byte("ramdisk"), AppendKernelArguments([]string{"p1"}))
+			})
+		})
+	})
+})
@@ -3,8 +3,11 @@ package isoeditor
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
 
 	diskfs "github.com/diskfs/go-diskfs"
 	. "github.com/onsi/ginkgo"
@@ -83,7 +86,7 @@ var _ = Describe("NewRHCOSStreamReader", func() {
 		Expect(isoFileContent(f.Name(), ramDiskImagePath)).To(Equal(initrdContent))
 	})
 	It("embeds the ignition and kargs content", func() {
-		kargs := []byte(" p1 p2 p3 p4\n")
+		kargs := AppendKernelArguments([]string{"p1", "p2", "p3", "p4"})
 		streamReader, err := NewRHCOSStreamReader(isoFile, &IgnitionContent{ignitionContent}, nil, kargs)
 		Expect(err).NotTo(HaveOccurred())
 
@@ -98,10 +101,107 @@ var _ = Describe("NewRHCOSStreamReader", func() {
 		grubFileContent := string(isoFileContent(f.Name(), defaultGrubFilePath))
 		isolinuxContent := string(isoFileContent(f.Name(), defaultIsolinuxFilePath))
 		for _, content := range []string{grubFileContent, isolinuxContent} {
-			Expect(content).To(MatchRegexp(string(kargs) + "#+ COREOS_KARG_EMBED_AREA"))
+			Expect(content).To(MatchRegexp(kargs.appendString() + "#+ COREOS_KARG_EMBED_AREA"))
 		}
 	})
 
+	It("applies the replace and delete kargs operations", func() {
+		kargs := KernelArguments{
+			{Operation: KargsOperationDelete, Value: "rd.luks.options"},
+			{Operation: KargsOperationReplace, Value: "ignition.platform.id=qemu"},
+			{Operation: KargsOperationAppend, Value: "p1"},
+		}
+		streamReader, err := NewRHCOSStreamReader(isoFile, &IgnitionContent{ignitionContent}, nil, kargs)
+		Expect(err).NotTo(HaveOccurred())
+
+		f, err := os.CreateTemp(filesDir, "streamed*.iso")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.Copy(f, streamReader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		Expect(streamReader.Close()).To(Succeed())
+
+		for _, file := range []string{defaultGrubFilePath, defaultIsolinuxFilePath} {
+			content := string(isoFileContent(f.Name(), file))
+			Expect(content).To(ContainSubstring("random.trust_cpu=on coreos.liveiso=rhcos-46.82.202010091720-0 ignition.firstboot ignition.platform.id=qemu p1\n###"))
+			Expect(content).NotTo(ContainSubstring("rd.luks.options"))
+		}
+	})
+
+	It("resolves the conflicts with the appended kargs", func() {
+		kargs := AppendKernelArguments([]string{"ignition.platform.id=qemu"})
+		streamReader, err := RHCOSStreamGenerator(KargsConflictKeepLast)(isoFile, &IgnitionContent{ignitionContent}, nil, kargs)
+		Expect(err).NotTo(HaveOccurred())
+
+		f, err := os.CreateTemp(filesDir, "streamed*.iso")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.Copy(f, streamReader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		Expect(streamReader.Close()).To(Succeed())
+
+		for _, file := range []string{defaultGrubFilePath, defaultIsolinuxFilePath} {
+			content := string(isoFileContent(f.Name(), file))
+			Expect(content).To(ContainSubstring("ignition.firstboot ignition.platform.id=qemu\n###"))
+			Expect(content).NotTo(ContainSubstring("ignition.platform.id=metal"))
+		}
+	})
+
+	Context("with the ISO of another architecture", func() {
+		var (
+			registered []ArchCustomizer
+			customizer *recordingCustomizer
+		)
+
+		BeforeEach(func() {
+			registered = archCustomizers
+			customizer = &recordingCustomizer{grubCustomizer: grubCustomizer{arch: "riscv64", markerFiles: []string{"/EFI/BOOT/BOOTRISCV64.EFI"}}}
+			Expect(RegisterArchCustomizer(customizer)).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(filesDir, "EFI/BOOT"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(filesDir, "EFI/BOOT/BOOTRISCV64.EFI"), []byte("grub"), 0600)).To(Succeed())
+			Expect(os.Remove(isoFile)).To(Succeed())
+			cmd := exec.Command("genisoimage", "-rational-rock", "-J", "-joliet-long", "-V", "Assisted123", "-o", isoFile, filesDir)
+			Expect(cmd.Run()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			archCustomizers = registered
+		})
+
+		It("edits the kargs through the customizer of the architecture", func() {
+			kargs := KernelArguments{
+				{Operation: KargsOperationDelete, Value: "rd.luks.options"},
+				{Operation: KargsOperationAppend, Value: "p1"},
+			}
+			streamReader, err := NewRHCOSStreamReader(isoFile, &IgnitionContent{ignitionContent}, nil, kargs)
+			Expect(err).NotTo(HaveOccurred())
+
+			f, err := os.CreateTemp(filesDir, "streamed*.iso")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = io.Copy(f, streamReader)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.Close()).To(Succeed())
+			Expect(streamReader.Close()).To(Succeed())
+
+			files, err := KargsFiles(isoFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(customizer.files).To(Equal(files))
+			for _, file := range []string{defaultGrubFilePath, defaultIsolinuxFilePath} {
+				content := string(isoFileContent(f.Name(), file))
+				Expect(content).To(ContainSubstring(" p1\n###"))
+				Expect(content).NotTo(ContainSubstring("rd.luks.options"))
+			}
+		})
+
+		It("fails when the customizer can't edit the kargs", func() {
+			customizer.err = &ErrS390xSecureIPL{File: defaultGrubFilePath}
+			_, err := NewRHCOSStreamReader(isoFile, &IgnitionContent{ignitionContent}, nil, AppendKernelArguments([]string{"p1"}))
+			var secureIPL *ErrS390xSecureIPL
+			Expect(errors.As(err, &secureIPL)).To(BeTrue())
+		})
+	})
+
 	It("Embeds the ignition in a ISO that uses the 'igninfo.json' file", func() {
 		// Create input ISO:
 		tmpDir, inputFile := createS390TestFiles("Assisted123", 0)
@@ -143,3 +243,18 @@ var _ = Describe("NewRHCOSStreamReader", func() {
 		Expect(ignitionBytes).To(Equal(ignitionArchiveBytes))
 	})
 })
+
+// recordingCustomizer records the files whose kernel arguments it edits
+type recordingCustomizer struct {
+	grubCustomizer
+	files []string
+	err   error
+}
+
+func (c *recordingCustomizer) KargsFileData(isoPath, file string, kargs KernelArguments, policy KargsConflictPolicy) (FileData, error) {
+	c.files = append(c.files, file)
+	if c.err != nil {
+		return FileData{}, c.err
+	}
+	return c.grubCustomizer.KargsFileData(isoPath, file, kargs, policy)
+}
@@ -0,0 +1,120 @@
+package isoeditor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
+)
+
+// SystemdUnitDir is the directory of the initramfs the units are written to
+const SystemdUnitDir = "/etc/systemd/system"
+
+var systemdUnitSuffixes = []string{".service", ".timer", ".target", ".path", ".socket", ".mount"}
+
+// SystemdUnit is a unit added to the live initramfs. Units run before switching to the live
+// root filesystem, so they are typically wanted by initrd.target.
+type SystemdUnit struct {
+	// Name of the unit, e.g. firmware-check.service
+	Name     string
+	Contents string
+	// Enabled units are linked from the .wants and .requires directories of the targets listed
+	// by WantedBy and RequiredBy in their [Install] section
+	Enabled bool
+}
+
+// SystemdUnitEntries returns the initramfs files of the units and of their enablement links, to
+// be archived with cpio.Archive
+func SystemdUnitEntries(units []SystemdUnit) ([]cpio.Entry, error) {
+	var entries []cpio.Entry
+	var links []cpio.Entry
+	for _, unit := range units {
+		if err := validateSystemdUnitName(unit.Name); err != nil {
+			return nil, err
+		}
+		unitPath := path.Join(SystemdUnitDir, unit.Name)
+		entries = append(entries, cpio.Entry{Path: unitPath, Mode: 0o644, Content: []byte(unit.Contents)})
+		if !unit.Enabled {
+			continue
+		}
+
+		if strings.HasSuffix(strings.SplitN(unit.Name, ".", 2)[0], "@") {
+			return nil, fmt.Errorf("cannot enable template unit %s", unit.Name)
+		}
+		install := systemdInstallSection(unit.Contents)
+		if len(install["WantedBy"])+len(install["RequiredBy"]) == 0 {
+			return nil, fmt.Errorf("cannot enable unit %s: no WantedBy or RequiredBy in its [Install] section", unit.Name)
+		}
+		for key, suffix := range map[string]string{"WantedBy": ".wants", "RequiredBy": ".requires"} {
+			for _, target := range install[key] {
+				if err := validateSystemdUnitName(target); err != nil {
+					return nil, fmt.Errorf("invalid %s of unit %s: %w", key, unit.Name, err)
+				}
+				links = append(links, cpio.Entry{
+					Path:    path.Join(SystemdUnitDir, target+suffix, unit.Name),
+					Mode:    os.ModeSymlink,
+					Content: []byte(unitPath),
+				})
+			}
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].Path < links[j].Path })
+	return append(entries, links...), nil
+}
+
+// NewSystemdUnitsRamDisk returns the ramdisk content adding the units to the live initramfs
+func NewSystemdUnitsRamDisk(units []SystemdUnit) ([]byte, error) {
+	entries, err := SystemdUnitEntries(units)
+	if err != nil {
+		return nil, err
+	}
+	return cpio.Archive(entries...)
+}
+
+// NewSystemdUnitsIgnitionReader returns the files of the ISO that need to be overwritten in order
+// to embed the ignition config and add the units to the live initramfs
+func NewSystemdUnitsIgnitionReader(isoPath string, ignitionContent *IgnitionContent, units []SystemdUnit) ([]FileData, error) {
+	entries, err := SystemdUnitEntries(units)
+	if err != nil {
+		return nil, err
+	}
+	return NewIgnitionImageReaderWithFiles(isoPath, ignitionContent, entries...)
+}
+
+func validateSystemdUnitName(name string) error {
+	if name == "" || strings.ContainsAny(name, "/ \t\n") || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid systemd unit name %q", name)
+	}
+	for _, suffix := range systemdUnitSuffixes {
+		if strings.HasSuffix(name, suffix) && len(name) > len(suffix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid systemd unit name %q, expected one of the suffixes %s", name, strings.Join(systemdUnitSuffixes, ", "))
+}
+
+// systemdInstallSection returns the space separated values of the keys of the [Install] section
+func systemdInstallSection(contents string) map[string][]string {
+	values := map[string][]string{}
+	inInstall := false
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "["):
+			inInstall = line == "[Install]"
+		case inInstall:
+			key, value, found := strings.Cut(line, "=")
+			if found {
+				key = strings.TrimSpace(key)
+				values[key] = append(values[key], strings.Fields(value)...)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,59 @@
+package isoeditor
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor/cpio"
+)
+
+const testFirmwareCheckUnit = `[Unit]
+Description=Check the firmware version
+Before=initrd.target
+
+[Service]
+Type=oneshot
+ExecStart=/usr/local/bin/firmware-check
+
+[Install]
+WantedBy=initrd.target
+RequiredBy=initrd-switch-root.target
+`
+
+var _ = Describe("SystemdUnitEntries", func() {
+	It("adds the units and the links enabling them", func() {
+		entries, err := SystemdUnitEntries([]SystemdUnit{
+			{Name: "firmware-check.service", Contents: testFirmwareCheckUnit, Enabled: true},
+			{Name: "manual.service", Contents: "[Service]\nExecStart=/bin/true\n"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(Equal([]cpio.Entry{
+			{Path: "/etc/systemd/system/firmware-check.service", Mode: 0o644, Content: []byte(testFirmwareCheckUnit)},
+			{Path: "/etc/systemd/system/manual.service", Mode: 0o644, Content: []byte("[Service]\nExecStart=/bin/true\n")},
+			{Path: "/etc/systemd/system/initrd-switch-root.target.requires/firmware-check.service", Mode: os.ModeSymlink, Content: []byte("/etc/systemd/system/firmware-check.service")},
+			{Path: "/etc/systemd/system/initrd.target.wants/firmware-check.service", Mode: os.ModeSymlink, Content: []byte("/etc/systemd/system/firmware-check.service")},
+		}))
+
+		ramdisk, err := NewSystemdUnitsRamDisk([]SystemdUnit{{Name: "firmware-check.service", Contents: testFirmwareCheckUnit, Enabled: true}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ramdisk).NotTo(BeEmpty())
+	})
+
+	It("rejects invalid units", func() {
+		for _, tc := range []struct {
+			unit SystemdUnit
+			err  string
+		}{
+			{SystemdUnit{Name: "../firmware-check.service"}, "invalid systemd unit name"},
+			{SystemdUnit{Name: "firmware-check"}, "expected one of the suffixes"},
+			{SystemdUnit{Name: "firmware-check.service", Contents: "[Service]\nExecStart=/bin/true\n", Enabled: true}, "no WantedBy or RequiredBy"},
+			{SystemdUnit{Name: "check@.service", Contents: testFirmwareCheckUnit, Enabled: true}, "cannot enable template unit"},
+			{SystemdUnit{Name: "check.service", Contents: "[Install]\nWantedBy=../target\n", Enabled: true}, "invalid WantedBy of unit check.service"},
+		} {
+			_, err := SystemdUnitEntries([]SystemdUnit{tc.unit})
+			Expect(err).To(MatchError(ContainSubstring(tc.err)))
+		}
+	})
+})
@@ -0,0 +1,144 @@
+package kargspresets
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+const (
+	SerialConsole = "serial-console"
+	FIPS          = "fips"
+	HTTPProxy     = "http-proxy"
+	HTTPSProxy    = "https-proxy"
+	DualStack     = "dual-stack"
+	NoModeset     = "nomodeset"
+	Multipath     = "multipath"
+	ISCSI         = "iscsi"
+)
+
+// expander returns the kernel arguments of a preset for the given architecture and preset value
+type expander func(arch, value string) ([]string, error)
+
+var serialConsoles = map[string]string{
+	isoeditor.X86CPUArchitecture:     "console=ttyS0,115200n8",
+	isoeditor.ARM64CPUArchitecture:   "console=ttyAMA0,115200n8",
+	isoeditor.S390XCPUArchitecture:   "console=ttysclp0",
+	isoeditor.PPC64LECPUArchitecture: "console=hvc0",
+}
+
+var presets = map[string]expander{
+	SerialConsole: func(arch, _ string) ([]string, error) {
+		console, ok := serialConsoles[isoeditor.NormalizeArchitecture(arch)]
+		if !ok {
+			return nil, fmt.Errorf("no serial console known for architecture %s", arch)
+		}
+		// keep the graphical console as well, the last console is used for /dev/console
+		return []string{"console=tty0", console}, nil
+	},
+	FIPS:      fixed("fips=1"),
+	DualStack: fixed("ip=dhcp,dhcp6"),
+	NoModeset: fixed("nomodeset"),
+	Multipath: fixed("rd.multipath=default"),
+	// The value optionally overrides the initiator name of the iSCSI Boot Firmware Table
+	ISCSI: func(_, value string) ([]string, error) {
+		sanBoot := isoeditor.SANBoot{ISCSI: true, ISCSIInitiatorName: value}
+		if err := sanBoot.Validate(); err != nil {
+			return nil, err
+		}
+		return sanBoot.Kargs(), nil
+	},
+	// The kernel passes unknown arguments containing '=' to init as environment variables
+	HTTPProxy:  proxy("http_proxy"),
+	HTTPSProxy: proxy("https_proxy"),
+}
+
+func fixed(args ...string) expander {
+	return func(_, value string) ([]string, error) {
+		if value != "" {
+			return nil, fmt.Errorf("preset does not take a value")
+		}
+		return args, nil
+	}
+}
+
+func proxy(variable string) expander {
+	return func(_, value string) ([]string, error) {
+		if value == "" {
+			return nil, fmt.Errorf("preset requires a proxy URL value")
+		}
+		u, err := url.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %s: expected http(s)://host[:port]", value)
+		}
+		return []string{fmt.Sprintf("%s=%s", variable, value)}, nil
+	}
+}
+
+// Names returns the names of all the available presets
+func Names() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Expand returns the kernel arguments of the given presets for the architecture. Presets
+// taking a value, such as http-proxy, are written as <name>=<value>.
+func Expand(arch string, names ...string) ([]string, error) {
+	var args []string
+	for _, preset := range names {
+		name, value, _ := strings.Cut(preset, "=")
+		expand, ok := presets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown kernel arguments preset %s, expected one of %v", name, Names())
+		}
+		presetArgs, err := expand(arch, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kernel arguments preset %s: %w", name, err)
+		}
+		for _, arg := range presetArgs {
+			if err := Validate(arg); err != nil {
+				return nil, fmt.Errorf("invalid kernel arguments preset %s: %w", name, err)
+			}
+		}
+		args = append(args, presetArgs...)
+	}
+	return args, nil
+}
+
+// Validate checks that a kernel argument can be written to a boot config file as is
+func Validate(arg string) error {
+	if arg == "" {
+		return fmt.Errorf("empty kernel argument")
+	}
+	for _, r := range arg {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || r == '"' || r == '\'' || r == '#' {
+			return fmt.Errorf("kernel argument %q contains invalid character %q", arg, r)
+		}
+	}
+	return nil
+}
+
+// AppendString formats kernel arguments as expected by isoeditor.NewKargsReader
+func AppendString(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return " " + strings.Join(args, " ")
+}
+
+// KernelArguments returns append operations for the kernel arguments, as expected by
+// isoeditor.NewKargsOperationsReader
+func KernelArguments(args []string) isoeditor.KernelArguments {
+	return isoeditor.AppendKernelArguments(args)
+}
@@ -0,0 +1,86 @@
+package kargspresets
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+func TestKargsPresets(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "kargspresets")
+}
+
+var _ = Describe("Expand", func() {
+	It("expands the serial console per architecture", func() {
+		args, err := Expand("x86_64", SerialConsole)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(args).To(Equal([]string{"console=tty0", "console=ttyS0,115200n8"}))
+
+		args, err = Expand("aarch64", SerialConsole)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(args).To(Equal([]string{"console=tty0", "console=ttyAMA0,115200n8"}))
+
+		args, err = Expand("s390x", SerialConsole)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(args).To(Equal([]string{"console=tty0", "console=ttysclp0"}))
+	})
+
+	It("fails for an unknown architecture", func() {
+		_, err := Expand("riscv64", SerialConsole)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("combines presets", func() {
+		args, err := Expand("x86_64", FIPS, NoModeset, DualStack, Multipath, "https-proxy=http://proxy.example.com:3128")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(args).To(Equal([]string{"fips=1", "nomodeset", "ip=dhcp,dhcp6", "rd.multipath=default", "https_proxy=http://proxy.example.com:3128"}))
+	})
+
+	It("expands the iSCSI preset with an optional initiator name", func() {
+		Expect(Expand("x86_64", ISCSI)).To(Equal([]string{"rd.iscsi.firmware=1"}))
+		Expect(Expand("x86_64", "iscsi=iqn.2024-01.com.example:host0")).To(Equal([]string{"rd.iscsi.firmware=1", "rd.iscsi.initiator=iqn.2024-01.com.example:host0"}))
+		_, err := Expand("x86_64", "iscsi=host0")
+		Expect(err).To(MatchError(ContainSubstring("expected an IQN")))
+	})
+
+	It("validates proxy values", func() {
+		_, err := Expand("x86_64", HTTPProxy)
+		Expect(err).To(HaveOccurred())
+		_, err = Expand("x86_64", "http-proxy=ftp://proxy")
+		Expect(err).To(HaveOccurred())
+		_, err = Expand("x86_64", "http-proxy=http://proxy/a b")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects values for fixed presets", func() {
+		_, err := Expand("x86_64", "fips=0")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects unknown presets", func() {
+		_, err := Expand("x86_64", "turbo")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("formatting", func() {
+	It("formats for NewKargsReader", func() {
+		Expect(AppendString([]string{"a", "b=1"})).To(Equal(" a b=1"))
+		Expect(AppendString(nil)).To(Equal(""))
+	})
+
+	It("formats for NewKargsOperationsReader", func() {
+		Expect(KernelArguments([]string{"a"})).To(Equal(isoeditor.KernelArguments{{Operation: isoeditor.KargsOperationAppend, Value: "a"}}))
+	})
+
+	It("validates kernel arguments", func() {
+		Expect(Validate("a=b")).To(Succeed())
+		Expect(Validate("")).ToNot(Succeed())
+		Expect(Validate("a\nb")).ToNot(Succeed())
+		Expect(Validate("a#b")).ToNot(Succeed())
+	})
+})
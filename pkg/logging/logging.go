@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is the logging interface used by the library packages, logrus loggers satisfy it
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// StandardLogger returns a redacting logger writing to the logrus standard logger
+func StandardLogger() Logger {
+	return NewRedactingLogger(logrus.StandardLogger())
+}
+
+const redacted = "<redacted>"
+
+var redactions = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	// ignition configs, which may contain credentials, pull secrets and ssh keys
+	{regexp.MustCompile(`\{\s*"ignition"\s*:.*\}`), redacted},
+	// data URLs used for embedded file contents
+	{regexp.MustCompile(`data:[^,\s]*,\S+`), "data:" + redacted},
+	// JSON web tokens
+	{regexp.MustCompile(`eyJ[\w-]+\.[\w-]+\.[\w-]*`), redacted},
+	// tokens passed as parameters or headers
+	{regexp.MustCompile(`(?i)((?:api_key|image_token|token|password|secret)["']?\s*[=:]\s*["']?)[^\s&"',]+`), "${1}" + redacted},
+	{regexp.MustCompile(`(?i)(bearer\s+)\S+`), "${1}" + redacted},
+	// tokens passed as path segments
+	{regexp.MustCompile(`(/(?:bytoken|byapikey)/)[^/\s?]+`), "${1}" + redacted},
+}
+
+// Redact removes ignition content and tokens from the message
+func Redact(message string) string {
+	for _, r := range redactions {
+		message = r.re.ReplaceAllString(message, r.replacement)
+	}
+	return message
+}
+
+type redactingLogger struct {
+	logger Logger
+}
+
+// NewRedactingLogger returns a logger redacting the messages before passing them to the given one
+func NewRedactingLogger(logger Logger) Logger {
+	return &redactingLogger{logger: logger}
+}
+
+func (l *redactingLogger) Debugf(format string, args ...interface{}) {
+	l.logger.Debugf("%s", redactArgs(format, args))
+}
+
+func (l *redactingLogger) Infof(format string, args ...interface{}) {
+	l.logger.Infof("%s", redactArgs(format, args))
+}
+
+func (l *redactingLogger) Warnf(format string, args ...interface{}) {
+	l.logger.Warnf("%s", redactArgs(format, args))
+}
+
+func (l *redactingLogger) Errorf(format string, args ...interface{}) {
+	l.logger.Errorf("%s", redactArgs(format, args))
+}
+
+func redactArgs(format string, args []interface{}) string {
+	for i, arg := range args {
+		// never dump raw file data
+		if data, ok := arg.([]byte); ok {
+			args[i] = fmt.Sprintf("<%d bytes>", len(data))
+		}
+	}
+	return Redact(fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+func TestLogging(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "logging")
+}
+
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) record(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) { l.record(format, args...) }
+func (l *recordingLogger) Infof(format string, args ...interface{})  { l.record(format, args...) }
+func (l *recordingLogger) Warnf(format string, args ...interface{})  { l.record(format, args...) }
+func (l *recordingLogger) Errorf(format string, args ...interface{}) { l.record(format, args...) }
+
+var _ = Describe("Redact", func() {
+	DescribeTable("redacts sensitive content",
+		func(message, expected string) {
+			Expect(Redact(message)).To(Equal(expected))
+		},
+		Entry("ignition", `config: {"ignition": {"version": "3.2.0"}, "passwd": {}}`, "config: <redacted>"),
+		Entry("data URL", "source data:;base64,c2VjcmV0 done", "source data:<redacted> done"),
+		Entry("JWT", "token eyJhbGciOiJI.eyJzdWIiOiIx.c2ln", "token <redacted>"),
+		Entry("query parameter", "GET /images/1?api_key=abc&arch=x86_64", "GET /images/1?api_key=<redacted>&arch=x86_64"),
+		Entry("bearer", "Authorization: Bearer abc", "Authorization: Bearer <redacted>"),
+		Entry("path segment", "GET /byapikey/abc/4.11/x86_64/minimal.iso", "GET /byapikey/<redacted>/4.11/x86_64/minimal.iso"),
+		Entry("nothing sensitive", "file /images/ignition.img at 1024", "file /images/ignition.img at 1024"),
+	)
+})
+
+var _ = Describe("NewRedactingLogger", func() {
+	It("redacts messages and file data", func() {
+		recorder := &recordingLogger{}
+		logger := NewRedactingLogger(recorder)
+		logger.Debugf("content %s with image_token=%s", []byte("secret"), "abc")
+		logger.Errorf("failed: %v", fmt.Errorf("bad config"))
+		Expect(recorder.messages).To(Equal([]string{"content <6 bytes> with image_token=<redacted>", "failed: bad config"}))
+	})
+})
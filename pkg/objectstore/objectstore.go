@@ -0,0 +1,257 @@
+// Package objectstore is a minimal client of S3 compatible object storages, enough to keep the
+// images of the image store in a bucket and to read them with ranged GETs.
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/renameio"
+)
+
+// ErrNotFound is returned when an object doesn't exist
+var ErrNotFound = errors.New("object not found")
+
+// Config is the location and the credentials of a bucket
+type Config struct {
+	// Endpoint is the URL of the storage, e.g. https://s3.us-east-1.amazonaws.com or the URL
+	// of a MinIO or Ceph RGW service
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Prefix is prepended to the keys of the objects
+	Prefix string
+	// VirtualHosted addresses the bucket as a subdomain of the endpoint instead of the first
+	// component of the path, which most S3 compatible storages expect
+	VirtualHosted bool
+}
+
+// Client reads and writes the objects of a bucket
+type Client struct {
+	config     Config
+	endpoint   *url.URL
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewClient returns a client of the bucket of config. httpClient defaults to http.DefaultClient.
+func NewClient(config Config, httpClient *http.Client) (*Client, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object storage endpoint: %w", err)
+	}
+	if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid object storage endpoint %q, expected an http or https URL", config.Endpoint)
+	}
+	if config.Bucket == "" {
+		return nil, errors.New("missing object storage bucket")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{config: config, endpoint: endpoint, httpClient: httpClient, now: time.Now}, nil
+}
+
+// objectURL returns the URL of the object, with its key escaped as SigV4 expects
+func (c *Client) objectURL(key string) *url.URL {
+	u := *c.endpoint
+	key = path.Join(c.config.Prefix, key)
+	segments := strings.Split(strings.TrimPrefix(key, "/"), "/")
+	if c.config.VirtualHosted {
+		u.Host = c.config.Bucket + "." + u.Host
+	} else {
+		segments = append([]string{c.config.Bucket}, segments...)
+	}
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = uriEncode(segment)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.Join(segments, "/")
+	u.RawPath = strings.TrimSuffix(c.endpoint.EscapedPath(), "/") + "/" + strings.Join(escaped, "/")
+	return &u
+}
+
+func (c *Client) do(ctx context.Context, method, key string, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.objectURL(key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	payloadHash := emptyPayload
+	if body != nil {
+		req.ContentLength = size
+		payloadHash = unsignedPayload
+	}
+	signV4(req, c.config.AccessKeyID, c.config.SecretAccessKey, c.config.Region, payloadHash, c.now())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", method, key, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s %s returned status %d: %s", method, key, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+// Stat returns the size of the object
+func (c *Client) Stat(ctx context.Context, key string) (int64, error) {
+	resp, err := c.do(ctx, http.MethodHead, key, nil, nil, 0)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.ContentLength < 0 {
+		return 0, fmt.Errorf("HEAD %s returned no content length", key)
+	}
+	return resp.ContentLength, nil
+}
+
+// Get returns the content of the object
+func (c *Client) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, key, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// GetRange returns length bytes of the object starting at offset
+func (c *Client) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	resp, err := c.do(ctx, http.MethodGet, key, header, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s with range returned status %d instead of %d", key, resp.StatusCode, http.StatusPartialContent)
+	}
+	return resp.Body, nil
+}
+
+// Put uploads size bytes of body as the object
+func (c *Client) Put(ctx context.Context, key string, body io.Reader, size int64) error {
+	resp, err := c.do(ctx, http.MethodPut, key, nil, body, size)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Delete removes the object
+func (c *Client) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Download writes the object to filePath, atomically replacing it
+func (c *Client) Download(ctx context.Context, key, filePath string) error {
+	body, err := c.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	t, err := renameio.TempFile("", filePath)
+	if err != nil {
+		return fmt.Errorf("unable to create a temp file for %s: %w", filePath, err)
+	}
+	defer func() {
+		_ = t.Cleanup()
+	}()
+	if _, err := io.Copy(t, body); err != nil {
+		return fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	return t.CloseAtomicallyReplace()
+}
+
+// Upload uploads the file as the object
+func (c *Client) Upload(ctx context.Context, key, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return c.Put(ctx, key, f, info.Size())
+}
+
+// ReaderAt is an io.ReaderAt reading an object with ranged GETs
+type ReaderAt struct {
+	client *Client
+	ctx    context.Context
+	key    string
+	size   int64
+}
+
+// NewReaderAt returns a ReaderAt of the object, which can be passed to the isoeditor functions
+// taking an io.ReaderAt
+func (c *Client) NewReaderAt(ctx context.Context, key string) (*ReaderAt, error) {
+	size, err := c.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return &ReaderAt{client: c, ctx: ctx, key: key, size: size}, nil
+}
+
+// Size returns the size of the object
+func (r *ReaderAt) Size() int64 {
+	return r.size
+}
+
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	length := min(int64(len(p)), r.size-off)
+	if length == 0 {
+		return 0, nil
+	}
+	body, err := r.client.GetRange(r.ctx, r.key, off, length)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	n, err := io.ReadFull(body, p[:length])
+	if err != nil {
+		return n, fmt.Errorf("failed to read %d bytes of %s at offset %d: %w", length, r.key, off, err)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,58 @@
+package overlay
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// BufferPoolStats reports the usage of the pool of copy buffers
+type BufferPoolStats struct {
+	// Number of buffers taken from the pool
+	Gets uint64
+	// Number of buffers allocated because the pool was empty
+	Allocations uint64
+	// Number of buffers currently in use
+	InUse int64
+}
+
+var (
+	bufferGets        atomic.Uint64
+	bufferAllocations atomic.Uint64
+	buffersInUse      atomic.Int64
+
+	copyBuffers = sync.Pool{
+		New: func() interface{} {
+			bufferAllocations.Add(1)
+			buf := make([]byte, copyBufferSize)
+			return &buf
+		},
+	}
+)
+
+func getBuffer() *[]byte {
+	bufferGets.Add(1)
+	buffersInUse.Add(1)
+	return copyBuffers.Get().(*[]byte)
+}
+
+func putBuffer(buf *[]byte) {
+	buffersInUse.Add(-1)
+	copyBuffers.Put(buf)
+}
+
+// GetBufferPoolStats returns the usage of the pool of copy buffers
+func GetBufferPoolStats() BufferPoolStats {
+	return BufferPoolStats{
+		Gets:        bufferGets.Load(),
+		Allocations: bufferAllocations.Load(),
+		InUse:       buffersInUse.Load(),
+	}
+}
+
+// Copy is like io.Copy but uses a buffer from the shared pool
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	return io.CopyBuffer(dst, src, *buf)
+}
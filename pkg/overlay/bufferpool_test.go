@@ -0,0 +1,27 @@
+package overlay
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Copy", func() {
+	It("copies using pooled buffers", func() {
+		before := GetBufferPoolStats()
+
+		for i := 0; i < 3; i++ {
+			var dst bytes.Buffer
+			n, err := Copy(&dst, strings.NewReader("abcdefghij"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(int64(10)))
+			Expect(dst.String()).To(Equal("abcdefghij"))
+		}
+
+		after := GetBufferPoolStats()
+		Expect(after.Gets - before.Gets).To(Equal(uint64(3)))
+		Expect(after.InUse).To(Equal(before.InUse))
+	})
+})
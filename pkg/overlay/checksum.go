@@ -0,0 +1,85 @@
+package overlay
+
+import (
+	"crypto/md5" //nolint:gosec
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+)
+
+// ErrChecksumNotReady is returned when the checksums are requested before the stream was read to EOF
+var ErrChecksumNotReady = errors.New("checksum is only available once the stream was read to EOF")
+
+// ChecksumReader computes the checksums of a stream while it is being read
+type ChecksumReader struct {
+	reader io.Reader
+	sha256 hash.Hash
+	md5    hash.Hash
+	done   bool
+}
+
+// NewChecksumReader returns a reader computing the SHA-256 of the stream and, if withMD5 is set,
+// its MD5 as implantisomd5 does.
+func NewChecksumReader(reader io.Reader, withMD5 bool) *ChecksumReader {
+	cr := &ChecksumReader{
+		reader: reader,
+		sha256: sha256.New(),
+	}
+	if withMD5 {
+		cr.md5 = md5.New() //nolint:gosec
+	}
+	return cr
+}
+
+func (cr *ChecksumReader) Read(p []byte) (int, error) {
+	n, err := cr.reader.Read(p)
+	cr.sha256.Write(p[:n])
+	if cr.md5 != nil {
+		cr.md5.Write(p[:n])
+	}
+	if err == io.EOF {
+		cr.done = true
+	}
+	return n, err
+}
+
+func (cr *ChecksumReader) Close() error {
+	if closer, hasClose := cr.reader.(io.Closer); hasClose {
+		return closer.Close()
+	}
+	return nil
+}
+
+// SHA256 returns the SHA-256 of the stream
+func (cr *ChecksumReader) SHA256() ([]byte, error) {
+	if !cr.done {
+		return nil, ErrChecksumNotReady
+	}
+	return cr.sha256.Sum(nil), nil
+}
+
+// MD5 returns the MD5 of the stream, or nil if it wasn't requested
+func (cr *ChecksumReader) MD5() ([]byte, error) {
+	if !cr.done {
+		return nil, ErrChecksumNotReady
+	}
+	if cr.md5 == nil {
+		return nil, nil
+	}
+	return cr.md5.Sum(nil), nil
+}
+
+// Digest returns the checksums of the stream formatted for a Digest header (RFC 3230)
+func (cr *ChecksumReader) Digest() (string, error) {
+	sum, err := cr.SHA256()
+	if err != nil {
+		return "", err
+	}
+	digest := "sha-256=" + base64.StdEncoding.EncodeToString(sum)
+	if cr.md5 != nil {
+		digest += ",md5=" + base64.StdEncoding.EncodeToString(cr.md5.Sum(nil))
+	}
+	return digest, nil
+}
@@ -0,0 +1,62 @@
+package overlay
+
+import (
+	"crypto/md5" //nolint:gosec
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ChecksumReader", func() {
+	const base = "abcdefghij"
+
+	It("computes the checksums of an overlay stream", func() {
+		reader, err := NewOverlayReader(strings.NewReader(base), Overlay{Reader: strings.NewReader("12"), Offset: 3, Length: 2})
+		Expect(err).NotTo(HaveOccurred())
+		cr := NewChecksumReader(reader, true)
+
+		output, err := io.ReadAll(cr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(output)).To(Equal("abc12fghij"))
+		Expect(cr.Close()).To(Succeed())
+
+		expectedSHA := sha256.Sum256(output)
+		expectedMD5 := md5.Sum(output) //nolint:gosec
+		sum, err := cr.SHA256()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sum).To(Equal(expectedSHA[:]))
+		sum, err = cr.MD5()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sum).To(Equal(expectedMD5[:]))
+
+		digest, err := cr.Digest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(digest).To(Equal("sha-256=" + base64.StdEncoding.EncodeToString(expectedSHA[:]) +
+			",md5=" + base64.StdEncoding.EncodeToString(expectedMD5[:])))
+	})
+
+	It("only computes MD5 when requested", func() {
+		cr := NewChecksumReader(strings.NewReader(base), false)
+		_, err := io.ReadAll(cr)
+		Expect(err).NotTo(HaveOccurred())
+		sum, err := cr.MD5()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sum).To(BeNil())
+		digest, err := cr.Digest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(digest).To(HavePrefix("sha-256="))
+		Expect(digest).NotTo(ContainSubstring("md5"))
+	})
+
+	It("fails before EOF", func() {
+		cr := NewChecksumReader(strings.NewReader(base), false)
+		_, err := cr.Read(make([]byte, 3))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = cr.SHA256()
+		Expect(err).To(Equal(ErrChecksumNotReady))
+	})
+})
@@ -0,0 +1,107 @@
+package overlay
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+type contextReader struct {
+	OverlayReader
+	ctx context.Context
+
+	closeOnce sync.Once
+	closeErr  error
+	stop      func() bool
+}
+
+// WithContext returns a reader failing with the context error once the context is done. The
+// reader is closed as soon as the context is cancelled, releasing the underlying file
+// descriptors without waiting for the read loop to notice.
+func WithContext(ctx context.Context, r OverlayReader) OverlayReader {
+	cr := &contextReader{OverlayReader: r, ctx: ctx}
+	cr.stop = context.AfterFunc(ctx, func() {
+		_ = cr.close()
+	})
+	return cr
+}
+
+// NewOverlayReaderContext is like NewOverlayReader but the reader is bound to the context
+func NewOverlayReaderContext(ctx context.Context, base BaseStream, overlay Overlay) (OverlayReader, error) {
+	r, err := NewOverlayReader(base, overlay)
+	if err != nil {
+		return nil, err
+	}
+	return WithContext(ctx, r), nil
+}
+
+func (cr *contextReader) close() error {
+	cr.closeOnce.Do(func() {
+		cr.closeErr = cr.OverlayReader.Close()
+	})
+	return cr.closeErr
+}
+
+func (cr *contextReader) Close() error {
+	cr.stop()
+	return cr.close()
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.OverlayReader.Read(p)
+}
+
+func (cr *contextReader) ReadAt(p []byte, off int64) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.OverlayReader.ReadAt(p, off)
+}
+
+func (cr *contextReader) Seek(offset int64, whence int) (int64, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.OverlayReader.Seek(offset, whence)
+}
+
+func (cr *contextReader) WriteTo(w io.Writer) (int64, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.OverlayReader.WriteTo(&contextWriter{Writer: w, ctx: cr.ctx})
+}
+
+func (cr *contextReader) writeRange(w io.Writer, off, length int64) (int64, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return writeSource(&contextWriter{Writer: w, ctx: cr.ctx}, cr.OverlayReader, off, length)
+}
+
+// contextWriter stops the copies of WriteTo between two writes once the context is done
+type contextWriter struct {
+	io.Writer
+	ctx context.Context
+}
+
+func (cw *contextWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.Writer.Write(p)
+}
+
+// ReadFrom lets the writer copy the regions of the base files with sendfile when it supports it
+func (cw *contextWriter) ReadFrom(r io.Reader) (int64, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if rf, ok := cw.Writer.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return Copy(struct{ io.Writer }{cw}, r)
+}
@@ -0,0 +1,75 @@
+package overlay
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type closeRecorder struct {
+	io.ReadSeeker
+	closed int32
+}
+
+func (c *closeRecorder) Close() error {
+	atomic.AddInt32(&c.closed, 1)
+	return nil
+}
+
+var _ = Describe("NewOverlayReaderContext", func() {
+	const base = "abcdefghij"
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		rec    *closeRecorder
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		rec = &closeRecorder{ReadSeeker: strings.NewReader(base)}
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	newReader := func() OverlayReader {
+		r, err := NewOverlayReaderContext(ctx, rec, Overlay{Reader: strings.NewReader("12"), Offset: 2, Length: 2})
+		Expect(err).NotTo(HaveOccurred())
+		return r
+	}
+
+	It("reads while the context is active", func() {
+		r := newReader()
+		output, err := io.ReadAll(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(output)).To(Equal("ab12efghij"))
+		Expect(r.Close()).To(Succeed())
+		Expect(atomic.LoadInt32(&rec.closed)).To(Equal(int32(1)))
+	})
+
+	It("fails and closes the base once cancelled", func() {
+		r := newReader()
+		buf := make([]byte, 3)
+		_, err := r.Read(buf)
+		Expect(err).NotTo(HaveOccurred())
+
+		cancel()
+		Eventually(func() int32 { return atomic.LoadInt32(&rec.closed) }).Should(Equal(int32(1)))
+		_, err = r.Read(buf)
+		Expect(err).To(Equal(context.Canceled))
+		_, err = r.ReadAt(buf, 0)
+		Expect(err).To(Equal(context.Canceled))
+		_, err = r.WriteTo(&bytes.Buffer{})
+		Expect(err).To(Equal(context.Canceled))
+
+		Expect(r.Close()).To(Succeed())
+		Expect(atomic.LoadInt32(&rec.closed)).To(Equal(int32(1)))
+	})
+})
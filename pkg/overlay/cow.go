@@ -0,0 +1,267 @@
+package overlay
+
+import (
+	"errors"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+)
+
+// CowStore keeps the data written to a CowOverlay
+type CowStore interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+type memoryStore struct {
+	data []byte
+}
+
+// NewMemoryStore returns a CowStore keeping the written data in memory
+func NewMemoryStore() CowStore {
+	return &memoryStore{}
+}
+
+func (ms *memoryStore) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(ms.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, ms.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (ms *memoryStore) WriteAt(p []byte, off int64) (int, error) {
+	if end := off + int64(len(p)); end > int64(len(ms.data)) {
+		ms.data = append(ms.data, make([]byte, end-int64(len(ms.data)))...)
+	}
+	return copy(ms.data[off:], p), nil
+}
+
+type tempFileStore struct {
+	*os.File
+}
+
+// NewTempFileStore returns a CowStore keeping the written data in a temporary file in dir,
+// which is removed when the overlay is closed
+func NewTempFileStore(dir string) (CowStore, error) {
+	f, err := os.CreateTemp(dir, "cow")
+	if err != nil {
+		return nil, err
+	}
+	return &tempFileStore{File: f}, nil
+}
+
+func (ts *tempFileStore) Close() error {
+	err := ts.File.Close()
+	if removeErr := os.Remove(ts.File.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// extent is a range of the virtual stream that was written to the store
+type extent struct {
+	offset      int64
+	length      int64
+	storeOffset int64
+}
+
+func (e extent) end() int64 {
+	return e.offset + e.length
+}
+
+// zeroSource provides the content of the holes left by writes past the end of the base
+type zeroSource struct{}
+
+func (zeroSource) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func (zeroSource) ReadAt(p []byte, _ int64) (int, error) {
+	return zeroSource{}.Read(p)
+}
+
+func (zeroSource) Seek(offset int64, _ int) (int64, error) {
+	return offset, nil
+}
+
+// CowOverlay is a writable view of a base stream. Writes are kept in the store and the base is
+// never modified, so customization steps can be composed as writes and the result streamed once.
+type CowOverlay struct {
+	Base BaseStream
+
+	store       CowStore
+	storeReader io.ReadSeeker
+	storeLength int64
+	extents     []extent
+	baseLength  int64
+
+	mutex       sync.RWMutex
+	readIndex   int64
+	totalLength int64
+}
+
+var _ OverlayReader = &CowOverlay{}
+
+// NewCowOverlay returns a writable overlay of the base stream. The base must implement io.ReaderAt.
+func NewCowOverlay(base BaseStream, store CowStore) (*CowOverlay, error) {
+	if _, ok := base.(io.ReaderAt); !ok {
+		return nil, errors.New("base stream does not support ReadAt")
+	}
+	length, err := base.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	return &CowOverlay{
+		Base:        base,
+		store:       store,
+		storeReader: io.NewSectionReader(store, 0, math.MaxInt64),
+		baseLength:  length,
+		totalLength: length,
+	}, nil
+}
+
+// WriteAt writes to the virtual stream, growing it if written past its end
+func (co *CowOverlay) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+
+	n, err := co.store.WriteAt(p, co.storeLength)
+	if err != nil {
+		return n, err
+	}
+	co.addExtent(extent{offset: off, length: int64(n), storeOffset: co.storeLength})
+	co.storeLength += int64(n)
+	if end := off + int64(n); end > co.totalLength {
+		co.totalLength = end
+	}
+	return n, nil
+}
+
+// addExtent adds the extent, trimming the parts of existing extents it overwrites
+func (co *CowOverlay) addExtent(added extent) {
+	extents := make([]extent, 0, len(co.extents)+2)
+	for _, e := range co.extents {
+		if e.end() <= added.offset || e.offset >= added.end() {
+			extents = append(extents, e)
+			continue
+		}
+		if e.offset < added.offset {
+			extents = append(extents, extent{offset: e.offset, length: added.offset - e.offset, storeOffset: e.storeOffset})
+		}
+		if e.end() > added.end() {
+			cut := added.end() - e.offset
+			extents = append(extents, extent{offset: added.end(), length: e.end() - added.end(), storeOffset: e.storeOffset + cut})
+		}
+	}
+	extents = append(extents, added)
+	sort.Slice(extents, func(i, j int) bool {
+		return extents[i].offset < extents[j].offset
+	})
+	co.extents = extents
+}
+
+func (co *CowOverlay) regionAt(index int64) (io.ReadSeeker, int64, int64) {
+	i := sort.Search(len(co.extents), func(i int) bool {
+		return co.extents[i].end() > index
+	})
+	next := co.totalLength
+	if i < len(co.extents) {
+		e := co.extents[i]
+		if e.offset <= index {
+			return co.storeReader, e.storeOffset + index - e.offset, e.end() - index
+		}
+		next = e.offset
+	}
+	if index < co.baseLength {
+		if next > co.baseLength {
+			next = co.baseLength
+		}
+		return co.Base, index, next - index
+	}
+	return zeroSource{}, 0, next - index
+}
+
+// Size returns the length of the virtual stream
+func (co *CowOverlay) Size() int64 {
+	co.mutex.RLock()
+	defer co.mutex.RUnlock()
+	return co.totalLength
+}
+
+func (co *CowOverlay) ReadAt(p []byte, off int64) (int, error) {
+	co.mutex.RLock()
+	defer co.mutex.RUnlock()
+	return readAtRegions(p, off, co.totalLength, co.regionAt)
+}
+
+func (co *CowOverlay) Read(p []byte) (int, error) {
+	n, err := co.ReadAt(p, co.readIndex)
+	co.readIndex += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (co *CowOverlay) Seek(offset int64, whence int) (int64, error) {
+	var start int64
+	switch whence {
+	case io.SeekStart:
+		start = 0
+	case io.SeekCurrent:
+		start = co.readIndex
+	case io.SeekEnd:
+		start = co.Size()
+	}
+	if start+offset < 0 {
+		return co.readIndex, errors.New("negative position")
+	}
+	co.readIndex = start + offset
+	return co.readIndex, nil
+}
+
+func (co *CowOverlay) writeRange(w io.Writer, off, length int64) (int64, error) {
+	co.mutex.RLock()
+	defer co.mutex.RUnlock()
+	return writeRegions(w, off, length, co.regionAt)
+}
+
+func (co *CowOverlay) WriteTo(w io.Writer) (int64, error) {
+	size := co.Size()
+	if co.readIndex >= size {
+		return 0, nil
+	}
+	n, err := co.writeRange(w, co.readIndex, size-co.readIndex)
+	co.readIndex += n
+	return n, err
+}
+
+// Close closes the store and the base stream
+func (co *CowOverlay) Close() error {
+	var err error
+	if closer, hasClose := co.store.(io.Closer); hasClose {
+		err = closer.Close()
+	}
+	if closer, hasClose := co.Base.(io.Closer); hasClose {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
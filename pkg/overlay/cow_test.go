@@ -0,0 +1,110 @@
+package overlay
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CowOverlay", func() {
+	const base = "abcdefghij"
+
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "overlay")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	stores := func() map[string]CowStore {
+		fileStore, err := NewTempFileStore(tmpDir)
+		Expect(err).NotTo(HaveOccurred())
+		return map[string]CowStore{
+			"memory":    NewMemoryStore(),
+			"temp file": fileStore,
+		}
+	}
+
+	write := func(co *CowOverlay, content string, off int64) {
+		n, err := co.WriteAt([]byte(content), off)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(len(content)))
+	}
+
+	It("applies overlapping writes", func() {
+		for name, store := range stores() {
+			By(name)
+			co, err := NewCowOverlay(strings.NewReader(base), store)
+			Expect(err).NotTo(HaveOccurred())
+
+			write(co, "1234", 2)
+			write(co, "XY", 4)
+			write(co, "Z", 1)
+			write(co, "W", 7)
+
+			output, err := io.ReadAll(co)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(output)).To(Equal("aZ12XYgWij"))
+
+			buf := make([]byte, 4)
+			_, err = co.ReadAt(buf, 3)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(buf)).To(Equal("2XYg"))
+
+			Expect(co.Close()).To(Succeed())
+		}
+	})
+
+	It("grows the stream when writing past the end", func() {
+		co, err := NewCowOverlay(strings.NewReader(base), NewMemoryStore())
+		Expect(err).NotTo(HaveOccurred())
+
+		write(co, "end", 12)
+		Expect(co.Size()).To(Equal(int64(15)))
+
+		var output bytes.Buffer
+		_, err = co.WriteTo(&output)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.String()).To(Equal(base + "\x00\x00end"))
+	})
+
+	It("supports seeking", func() {
+		co, err := NewCowOverlay(strings.NewReader(base), NewMemoryStore())
+		Expect(err).NotTo(HaveOccurred())
+		write(co, "12", 5)
+
+		offset, err := co.Seek(-6, io.SeekEnd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(offset).To(Equal(int64(4)))
+		output, err := io.ReadAll(co)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(output)).To(Equal("e12hij"))
+	})
+
+	It("removes the temp file store on close", func() {
+		store, err := NewTempFileStore(tmpDir)
+		Expect(err).NotTo(HaveOccurred())
+		co, err := NewCowOverlay(strings.NewReader(base), store)
+		Expect(err).NotTo(HaveOccurred())
+		write(co, "12", 5)
+		Expect(co.Close()).To(Succeed())
+
+		entries, err := os.ReadDir(tmpDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("requires a base supporting ReadAt", func() {
+		_, err := NewCowOverlay(&earlyEOFReader{data: []byte(base)}, NewMemoryStore())
+		Expect(err).To(HaveOccurred())
+	})
+})
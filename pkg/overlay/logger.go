@@ -0,0 +1,12 @@
+package overlay
+
+import (
+	"github.com/openshift/assisted-image-service/pkg/logging"
+)
+
+var log logging.Logger = logging.StandardLogger()
+
+// SetLogger sets the logger used by the package. Messages are redacted before being passed to it.
+func SetLogger(logger logging.Logger) {
+	log = logging.NewRedactingLogger(logger)
+}
@@ -0,0 +1,135 @@
+package overlay
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+type multiOverlayReader struct {
+	Base     BaseStream
+	Overlays []Overlay
+
+	readIndex   int64
+	totalLength int64
+}
+
+// NewMultiOverlayReader returns a reader applying all the overlays to the base stream in a
+// single pass. The overlays can be given in any order but must not overlap.
+func NewMultiOverlayReader(base BaseStream, overlays ...Overlay) (OverlayReader, error) {
+	length, err := base.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]Overlay, 0, len(overlays))
+	for _, ol := range overlays {
+		if ol.Offset < 0 || ol.Offset > length {
+			return nil, fmt.Errorf("Overlay offset %d is beyond end of base", ol.Offset)
+		}
+		if ol.Length > 0 {
+			sorted = append(sorted, ol)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Offset < sorted[j].Offset
+	})
+
+	totalLength := length
+	for i, ol := range sorted {
+		if i > 0 && sorted[i-1].end() > ol.Offset {
+			return nil, fmt.Errorf("Overlays at offsets %d and %d overlap", sorted[i-1].Offset, ol.Offset)
+		}
+		if ol.end() > totalLength {
+			totalLength = ol.end()
+		}
+		if _, err := ol.Reader.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := base.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	log.Debugf("Applying %d overlays to a %d bytes stream", len(sorted), length)
+
+	return &multiOverlayReader{
+		Base:        base,
+		Overlays:    sorted,
+		totalLength: totalLength,
+	}, nil
+}
+
+// overlayAt returns the index of the overlay containing the index, or of the first overlay after
+// it if none does. The second return value is true if the overlay contains the index.
+func (mr *multiOverlayReader) overlayAt(index int64) (int, bool) {
+	i := sort.Search(len(mr.Overlays), func(i int) bool {
+		return mr.Overlays[i].end() > index
+	})
+	return i, i < len(mr.Overlays) && mr.Overlays[i].contains(index)
+}
+
+func (mr *multiOverlayReader) seek(index int64) (err error) {
+	if i, ok := mr.overlayAt(index); ok {
+		_, err = mr.Overlays[i].Reader.Seek(index-mr.Overlays[i].Offset, io.SeekStart)
+	} else {
+		_, err = mr.Base.Seek(index, io.SeekStart)
+	}
+	mr.readIndex = index
+	return err
+}
+
+func (mr *multiOverlayReader) Seek(offset int64, whence int) (int64, error) {
+	var start int64
+	switch whence {
+	case io.SeekStart:
+		start = 0
+	case io.SeekCurrent:
+		start = mr.readIndex
+	case io.SeekEnd:
+		start = mr.totalLength
+	}
+
+	err := mr.seek(start + offset)
+	return mr.readIndex, err
+}
+
+func (mr *multiOverlayReader) Read(p []byte) (int, error) {
+	if mr.readIndex >= mr.totalLength {
+		return 0, io.EOF
+	}
+
+	reader := mr.Base
+	buffer := p
+
+	i, inOverlay := mr.overlayAt(mr.readIndex)
+	switch {
+	case inOverlay:
+		reader = mr.Overlays[i].Reader
+		if overlayBytes := mr.Overlays[i].end() - mr.readIndex; int64(len(buffer)) > overlayBytes {
+			buffer = p[:overlayBytes]
+		}
+	case i < len(mr.Overlays):
+		// before the next overlay
+		if baseBytes := mr.Overlays[i].Offset - mr.readIndex; int64(len(buffer)) > baseBytes {
+			buffer = p[:baseBytes]
+		}
+	default:
+		// after the last overlay
+	}
+
+	bytesRead, readErr := reader.Read(buffer)
+
+	seekErr := mr.seek(mr.readIndex + int64(bytesRead))
+
+	if readErr != nil && readErr != io.EOF {
+		return bytesRead, readErr
+	}
+	return bytesRead, seekErr
+}
+
+func (mr *multiOverlayReader) Close() error {
+	if closer, hasClose := mr.Base.(io.Closer); hasClose {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package overlay
+
+import (
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MultiOverlayReader", func() {
+	const base = "abcdefghij"
+
+	overlay := func(content string, offset int64) Overlay {
+		return Overlay{Reader: strings.NewReader(content), Offset: offset, Length: int64(len(content))}
+	}
+
+	It("applies all overlays in a single pass", func() {
+		reader, err := NewMultiOverlayReader(strings.NewReader(base), overlay("XY", 8), overlay("12", 0), overlay("", 5), overlay("345", 3))
+		Expect(err).NotTo(HaveOccurred())
+
+		output, err := io.ReadAll(reader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(output)).To(Equal("12c345ghXY"))
+
+		newOffset, err := reader.Seek(1, io.SeekStart)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newOffset).To(Equal(int64(1)))
+		rangeOutput := make([]byte, 8)
+		_, err = io.ReadFull(reader, rangeOutput)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(rangeOutput)).To(Equal("2c345ghX"))
+	})
+
+	It("extends the stream past the end of the base", func() {
+		reader, err := NewMultiOverlayReader(strings.NewReader(base), overlay("over", 8), overlay("A", 1))
+		Expect(err).NotTo(HaveOccurred())
+
+		output, err := io.ReadAll(reader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(output)).To(Equal("aAcdefghover"))
+	})
+
+	It("matches chained overlay readers", func() {
+		chained, err := NewOverlayReader(strings.NewReader(base), overlay("12", 1))
+		Expect(err).NotTo(HaveOccurred())
+		chained, err = NewOverlayReader(chained, overlay("34", 6))
+		Expect(err).NotTo(HaveOccurred())
+		expected, err := io.ReadAll(chained)
+		Expect(err).NotTo(HaveOccurred())
+
+		reader, err := NewMultiOverlayReader(strings.NewReader(base), overlay("12", 1), overlay("34", 6))
+		Expect(err).NotTo(HaveOccurred())
+		output, err := io.ReadAll(reader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output).To(Equal(expected))
+	})
+
+	It("fails with overlapping overlays", func() {
+		_, err := NewMultiOverlayReader(strings.NewReader(base), overlay("1234", 2), overlay("56", 5))
+		Expect(err).To(MatchError("Overlays at offsets 2 and 5 overlap"))
+	})
+
+	It("fails with an overlay beyond the end of the base", func() {
+		_, err := NewMultiOverlayReader(strings.NewReader(base), overlay("12", 11))
+		Expect(err).To(HaveOccurred())
+	})
+})
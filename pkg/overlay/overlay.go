@@ -10,6 +10,8 @@ type BaseStream = io.ReadSeeker
 type OverlayReader interface {
 	BaseStream
 	io.ReadSeekCloser
+	io.ReaderAt
+	io.WriterTo
 }
 
 type Overlay struct {
@@ -63,6 +65,7 @@ func NewOverlayReader(base BaseStream, overlay Overlay) (OverlayReader, error) {
 	if overlay.Offset < 0 || overlay.Offset > length {
 		return nil, errors.New("Overlay offset is beyond end of base")
 	}
+	log.Debugf("Overlaying %d bytes at offset %d of a %d bytes stream", overlay.Length, overlay.Offset, length)
 	return newReader(base, overlay, length)
 }
 
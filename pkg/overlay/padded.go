@@ -0,0 +1,38 @@
+package overlay
+
+import (
+	"io"
+)
+
+// paddedReaderAt reads content followed by pad bytes up to length
+type paddedReaderAt struct {
+	content []byte
+	length  int64
+	pad     byte
+}
+
+func (pr *paddedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= pr.length {
+		return 0, io.EOF
+	}
+	n := 0
+	if off < int64(len(pr.content)) {
+		n = copy(p, pr.content[off:])
+	}
+	for ; n < len(p) && off+int64(n) < pr.length; n++ {
+		p[n] = pr.pad
+	}
+	if off+int64(n) >= pr.length {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// NewPaddedReader returns a reader of content followed by pad bytes up to length bytes, such as
+// the content of a file of an ISO keeping its extent, without allocating the padding
+func NewPaddedReader(content []byte, length int64, pad byte) *io.SectionReader {
+	if int64(len(content)) > length {
+		content = content[:length]
+	}
+	return io.NewSectionReader(&paddedReaderAt{content: content, length: length, pad: pad}, 0, length)
+}
@@ -0,0 +1,28 @@
+package overlay
+
+import (
+	"io"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewPaddedReader", func() {
+	It("pads the content up to the length", func() {
+		r := NewPaddedReader([]byte("abc"), 8, '#')
+		Expect(r.Size()).To(Equal(int64(8)))
+		Expect(io.ReadAll(r)).To(Equal([]byte("abc#####")))
+
+		p := make([]byte, 4)
+		n, err := r.ReadAt(p, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(p[:n])).To(Equal("bc##"))
+		n, err = r.ReadAt(p, 6)
+		Expect(err).To(Equal(io.EOF))
+		Expect(string(p[:n])).To(Equal("##"))
+	})
+
+	It("is only padding without content", func() {
+		Expect(io.ReadAll(NewPaddedReader(nil, 3, 0))).To(Equal([]byte{0, 0, 0}))
+	})
+})
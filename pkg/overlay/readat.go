@@ -0,0 +1,86 @@
+package overlay
+
+import (
+	"errors"
+	"io"
+)
+
+// regionFinder returns the source of the byte at index of a stream, the offset of that byte in
+// the source and the number of contiguous bytes that can be read from it
+type regionFinder func(index int64) (source io.ReadSeeker, sourceOffset int64, length int64)
+
+// readAtRegions implements io.ReaderAt for the streams composed of regions of other streams.
+// The sources are read using their own ReadAt, so concurrent calls don't share a read cursor.
+func readAtRegions(p []byte, off, totalLength int64, regionAt regionFinder) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	n := 0
+	for n < len(p) {
+		index := off + int64(n)
+		if index >= totalLength {
+			return n, io.EOF
+		}
+		source, sourceOffset, length := regionAt(index)
+		buffer := p[n:]
+		if int64(len(buffer)) > length {
+			buffer = buffer[:length]
+		}
+
+		readerAt, ok := source.(io.ReaderAt)
+		if !ok {
+			return n, errors.New("stream does not support ReadAt")
+		}
+		bytesRead, err := readerAt.ReadAt(buffer, sourceOffset)
+		n += bytesRead
+		if bytesRead == len(buffer) {
+			continue
+		}
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return n, err
+	}
+	return n, nil
+}
+
+func (or *overlayReader) regionAt(index int64) (io.ReadSeeker, int64, int64) {
+	switch {
+	case or.Overlay.contains(index):
+		return or.Overlay.Reader, index - or.Overlay.Offset, or.Overlay.end() - index
+	case index < or.Overlay.Offset:
+		return or.Base, index, or.Overlay.Offset - index
+	default:
+		return or.Base, index, or.totalLength - index
+	}
+}
+
+func (or *overlayReader) ReadAt(p []byte, off int64) (int, error) {
+	return readAtRegions(p, off, or.totalLength, or.regionAt)
+}
+
+func (mr *multiOverlayReader) regionAt(index int64) (io.ReadSeeker, int64, int64) {
+	i, inOverlay := mr.overlayAt(index)
+	switch {
+	case inOverlay:
+		return mr.Overlays[i].Reader, index - mr.Overlays[i].Offset, mr.Overlays[i].end() - index
+	case i < len(mr.Overlays):
+		return mr.Base, index, mr.Overlays[i].Offset - index
+	default:
+		return mr.Base, index, mr.totalLength - index
+	}
+}
+
+func (mr *multiOverlayReader) ReadAt(p []byte, off int64) (int, error) {
+	return readAtRegions(p, off, mr.totalLength, mr.regionAt)
+}
+
+func (sr *spliceReader) regionAt(index int64) (io.ReadSeeker, int64, int64) {
+	s, _ := sr.segmentAt(index)
+	return s.reader, s.sourceOffset + index - s.start, s.end() - index
+}
+
+func (sr *spliceReader) ReadAt(p []byte, off int64) (int, error) {
+	return readAtRegions(p, off, sr.totalLength, sr.regionAt)
+}
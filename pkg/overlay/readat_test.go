@@ -0,0 +1,101 @@
+package overlay
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReadAt", func() {
+	const base = "abcdefghij"
+
+	overlay := func(content string, offset int64) Overlay {
+		return Overlay{Reader: strings.NewReader(content), Offset: offset, Length: int64(len(content))}
+	}
+
+	readers := func() map[string]OverlayReader {
+		single, err := NewOverlayReader(strings.NewReader(base), overlay("over", 8))
+		Expect(err).NotTo(HaveOccurred())
+		multi, err := NewMultiOverlayReader(strings.NewReader(base), overlay("12", 1), overlay("34", 5))
+		Expect(err).NotTo(HaveOccurred())
+		splice, err := NewSpliceReader(strings.NewReader(base), Splice{Reader: strings.NewReader("XYZ"), Offset: 3, Length: 1})
+		Expect(err).NotTo(HaveOccurred())
+		chained, err := NewOverlayReader(multi, overlay("ab", 3))
+		Expect(err).NotTo(HaveOccurred())
+		return map[string]OverlayReader{
+			"single":  single,
+			"multi":   multi,
+			"splice":  splice,
+			"chained": chained,
+		}
+	}
+
+	It("reads any range", func() {
+		for name, reader := range readers() {
+			By(name)
+			expected, err := io.ReadAll(reader)
+			Expect(err).NotTo(HaveOccurred())
+
+			for off := 0; off < len(expected); off++ {
+				for end := off; end <= len(expected); end++ {
+					buf := make([]byte, end-off)
+					n, err := reader.ReadAt(buf, int64(off))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(n).To(Equal(end - off))
+					Expect(buf).To(Equal(expected[off:end]))
+				}
+			}
+		}
+	})
+
+	It("returns EOF when reading past the end", func() {
+		for name, reader := range readers() {
+			By(name)
+			length, err := reader.Seek(0, io.SeekEnd)
+			Expect(err).NotTo(HaveOccurred())
+
+			buf := make([]byte, 4)
+			n, err := reader.ReadAt(buf, length-2)
+			Expect(err).To(Equal(io.EOF))
+			Expect(n).To(Equal(2))
+		}
+	})
+
+	It("doesn't move the read cursor", func() {
+		for name, reader := range readers() {
+			By(name)
+			_, err := reader.Seek(2, io.SeekStart)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = reader.ReadAt(make([]byte, 3), 6)
+			Expect(err).NotTo(HaveOccurred())
+			current, err := reader.Seek(0, io.SeekCurrent)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(current).To(Equal(int64(2)))
+		}
+	})
+
+	It("supports concurrent reads", func() {
+		reader := readers()["multi"]
+		expected, err := io.ReadAll(reader)
+		Expect(err).NotTo(HaveOccurred())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(off int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				buf := make([]byte, 3)
+				for j := 0; j < 100; j++ {
+					_, err := reader.ReadAt(buf, int64(off))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(buf).To(Equal(expected[off : off+3]))
+				}
+			}(i % (len(expected) - 3))
+		}
+		wg.Wait()
+	})
+})
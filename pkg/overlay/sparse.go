@@ -0,0 +1,52 @@
+package overlay
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// Block size used to detect holes, matching the usual filesystem block size
+const sparseBlockSize = 4096
+
+var zeroBlock = make([]byte, sparseBlockSize)
+
+// WriteSparse writes the stream to the file, leaving holes in the file instead of writing the
+// blocks only containing zeros, so that a sparse disk image stays sparse once customized.
+func WriteSparse(dst *os.File, src io.Reader) (int64, error) {
+	pooled := getBuffer()
+	defer putBuffer(pooled)
+	buf := *pooled
+	var written int64
+	for {
+		n, readErr := io.ReadFull(src, buf)
+		for start := 0; start < n; start += sparseBlockSize {
+			end := start + sparseBlockSize
+			if end > n {
+				end = n
+			}
+			block := buf[start:end]
+			if bytes.Equal(block, zeroBlock[:len(block)]) {
+				if _, err := dst.Seek(int64(len(block)), io.SeekCurrent); err != nil {
+					return written, err
+				}
+			} else if _, err := dst.Write(block); err != nil {
+				return written, err
+			}
+			written += int64(len(block))
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+
+	// a trailing hole is only allocated by setting the file size
+	offset, err := dst.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return written, err
+	}
+	return written, dst.Truncate(offset)
+}
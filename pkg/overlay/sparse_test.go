@@ -0,0 +1,62 @@
+package overlay
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WriteSparse", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "overlay")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	It("keeps zero blocks as holes", func() {
+		const size = 16 * 1024 * 1024
+		base := make([]byte, size)
+		copy(base[sparseBlockSize:], "data")
+
+		reader, err := NewOverlayReader(bytes.NewReader(base), Overlay{Reader: bytes.NewReader([]byte("over")), Offset: size - 10, Length: 4})
+		Expect(err).NotTo(HaveOccurred())
+
+		dst, err := os.CreateTemp(tmpDir, "sparse")
+		Expect(err).NotTo(HaveOccurred())
+		defer dst.Close()
+		n, err := WriteSparse(dst, reader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(int64(size)))
+
+		content, err := os.ReadFile(dst.Name())
+		Expect(err).NotTo(HaveOccurred())
+		copy(base[size-10:], "over")
+		Expect(content).To(Equal(base))
+
+		info, err := dst.Stat()
+		Expect(err).NotTo(HaveOccurred())
+		// only the two blocks with data are allocated
+		Expect(info.Sys().(*syscall.Stat_t).Blocks * 512).To(BeNumerically("<", size/16))
+	})
+
+	It("preserves a trailing hole", func() {
+		dst, err := os.CreateTemp(tmpDir, "sparse")
+		Expect(err).NotTo(HaveOccurred())
+		defer dst.Close()
+		_, err = WriteSparse(dst, bytes.NewReader(append([]byte("data"), make([]byte, 3*sparseBlockSize+5)...)))
+		Expect(err).NotTo(HaveOccurred())
+
+		info, err := dst.Stat()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Size()).To(Equal(int64(4 + 3*sparseBlockSize + 5)))
+	})
+})
@@ -0,0 +1,165 @@
+package overlay
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Splice replaces Length bytes of the base stream starting at Offset with the whole content of
+// Reader. Unlike an Overlay the content can be longer or shorter than the replaced range, in
+// which case the rest of the stream is shifted.
+type Splice struct {
+	Reader io.ReadSeeker
+	Offset int64
+	Length int64
+}
+
+// segment is a contiguous part of the spliced stream read from a single source
+type segment struct {
+	reader io.ReadSeeker
+	// offset of the segment in the source
+	sourceOffset int64
+	// offset of the segment in the spliced stream
+	start  int64
+	length int64
+}
+
+func (s segment) end() int64 {
+	return s.start + s.length
+}
+
+type SpliceReader interface {
+	OverlayReader
+	// Size returns the length of the spliced stream
+	Size() int64
+}
+
+type spliceReader struct {
+	Base     BaseStream
+	segments []segment
+
+	readIndex   int64
+	totalLength int64
+}
+
+// NewSpliceReader returns a reader applying the splices to the base stream. The splices can be
+// given in any order but the base ranges they replace must not overlap.
+func NewSpliceReader(base BaseStream, splices ...Splice) (SpliceReader, error) {
+	baseLength, err := base.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := append([]Splice{}, splices...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Offset < sorted[j].Offset
+	})
+
+	var segments []segment
+	var baseIndex, start int64
+	addSegment := func(s segment) {
+		if s.length > 0 {
+			segments = append(segments, s)
+			start += s.length
+		}
+	}
+	for _, sp := range sorted {
+		if sp.Offset < 0 || sp.Length < 0 || sp.Offset+sp.Length > baseLength {
+			return nil, fmt.Errorf("Splice at offset %d with length %d is beyond end of base", sp.Offset, sp.Length)
+		}
+		if sp.Offset < baseIndex {
+			return nil, fmt.Errorf("Splice at offset %d overlaps a previous splice", sp.Offset)
+		}
+		contentLength, err := sp.Reader.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+		addSegment(segment{reader: base, sourceOffset: baseIndex, start: start, length: sp.Offset - baseIndex})
+		addSegment(segment{reader: sp.Reader, start: start, length: contentLength})
+		baseIndex = sp.Offset + sp.Length
+	}
+	addSegment(segment{reader: base, sourceOffset: baseIndex, start: start, length: baseLength - baseIndex})
+	log.Debugf("Applying %d splices to a %d bytes stream, resulting in %d bytes", len(sorted), baseLength, start)
+
+	sr := &spliceReader{
+		Base:        base,
+		segments:    segments,
+		totalLength: start,
+	}
+	if err := sr.seek(0); err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
+
+func (sr *spliceReader) Size() int64 {
+	return sr.totalLength
+}
+
+func (sr *spliceReader) segmentAt(index int64) (segment, bool) {
+	i := sort.Search(len(sr.segments), func(i int) bool {
+		return sr.segments[i].end() > index
+	})
+	if i < len(sr.segments) && index >= 0 {
+		return sr.segments[i], true
+	}
+	return segment{}, false
+}
+
+func (sr *spliceReader) seek(index int64) (err error) {
+	if s, ok := sr.segmentAt(index); ok {
+		_, err = s.reader.Seek(s.sourceOffset+index-s.start, io.SeekStart)
+	}
+	sr.readIndex = index
+	return err
+}
+
+func (sr *spliceReader) Seek(offset int64, whence int) (int64, error) {
+	var start int64
+	switch whence {
+	case io.SeekStart:
+		start = 0
+	case io.SeekCurrent:
+		start = sr.readIndex
+	case io.SeekEnd:
+		start = sr.totalLength
+	}
+	if start+offset < 0 {
+		return sr.readIndex, fmt.Errorf("negative position %d", start+offset)
+	}
+
+	err := sr.seek(start + offset)
+	return sr.readIndex, err
+}
+
+func (sr *spliceReader) Read(p []byte) (int, error) {
+	s, ok := sr.segmentAt(sr.readIndex)
+	if !ok {
+		return 0, io.EOF
+	}
+
+	buffer := p
+	if remaining := s.end() - sr.readIndex; int64(len(buffer)) > remaining {
+		buffer = p[:remaining]
+	}
+
+	bytesRead, readErr := s.reader.Read(buffer)
+
+	seekErr := sr.seek(sr.readIndex + int64(bytesRead))
+
+	if readErr != nil && readErr != io.EOF {
+		return bytesRead, readErr
+	}
+	if bytesRead == 0 && readErr == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return bytesRead, seekErr
+}
+
+func (sr *spliceReader) Close() error {
+	if closer, hasClose := sr.Base.(io.Closer); hasClose {
+		return closer.Close()
+	}
+	return nil
+}
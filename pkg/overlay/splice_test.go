@@ -0,0 +1,90 @@
+package overlay
+
+import (
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SpliceReader", func() {
+	const base = "abcdefghij"
+
+	splice := func(content string, offset, length int64) Splice {
+		return Splice{Reader: strings.NewReader(content), Offset: offset, Length: length}
+	}
+
+	testCases := []struct {
+		Name     string
+		Splices  []Splice
+		Expected string
+	}{
+		{
+			Name:     "insertion",
+			Splices:  []Splice{splice("123", 2, 0)},
+			Expected: "ab123cdefghij",
+		},
+		{
+			Name:     "deletion",
+			Splices:  []Splice{splice("", 2, 3)},
+			Expected: "abfghij",
+		},
+		{
+			Name:     "growing replacement",
+			Splices:  []Splice{splice("12345", 0, 2)},
+			Expected: "12345cdefghij",
+		},
+		{
+			Name:     "shrinking replacement at end",
+			Splices:  []Splice{splice("1", 7, 3)},
+			Expected: "abcdefg1",
+		},
+		{
+			Name:     "append",
+			Splices:  []Splice{splice("123", 10, 0)},
+			Expected: "abcdefghij123",
+		},
+		{
+			Name:     "multiple splices out of order",
+			Splices:  []Splice{splice("XYZ", 8, 1), splice("", 1, 2), splice("12", 4, 2)},
+			Expected: "ad12ghXYZj",
+		},
+	}
+
+	It("passes all test cases", func() {
+		for _, tc := range testCases {
+			By(tc.Name)
+
+			reader, err := NewSpliceReader(strings.NewReader(base), tc.Splices...)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reader.Size()).To(Equal(int64(len(tc.Expected))))
+
+			output, err := io.ReadAll(reader)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(output)).To(Equal(tc.Expected))
+
+			end, err := reader.Seek(0, io.SeekEnd)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(end).To(Equal(int64(len(tc.Expected))))
+
+			newOffset, err := reader.Seek(2, io.SeekStart)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newOffset).To(Equal(int64(2)))
+			rangeOutput := make([]byte, 5)
+			_, err = io.ReadFull(reader, rangeOutput)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(rangeOutput)).To(Equal(tc.Expected[2:7]))
+		}
+	})
+
+	It("fails with overlapping splices", func() {
+		_, err := NewSpliceReader(strings.NewReader(base), splice("1", 2, 3), splice("2", 4, 1))
+		Expect(err).To(MatchError("Splice at offset 4 overlaps a previous splice"))
+	})
+
+	It("fails with a splice beyond the end of the base", func() {
+		_, err := NewSpliceReader(strings.NewReader(base), splice("1", 8, 3))
+		Expect(err).To(HaveOccurred())
+	})
+})
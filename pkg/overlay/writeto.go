@@ -0,0 +1,109 @@
+package overlay
+
+import (
+	"io"
+)
+
+// Large copies for the untouched regions of the base stream, which are most of a multi-GB ISO
+const copyBufferSize = 1024 * 1024
+
+// rangeWriter is implemented by the readers of this package so that nested readers are written
+// region by region instead of being read through a generic copy
+type rangeWriter interface {
+	writeRange(w io.Writer, off, length int64) (int64, error)
+}
+
+func writeRegions(w io.Writer, off, length int64, regionAt regionFinder) (int64, error) {
+	var written int64
+	for written < length {
+		source, sourceOffset, regionLength := regionAt(off + written)
+		if regionLength > length-written {
+			regionLength = length - written
+		}
+		n, err := writeSource(w, source, sourceOffset, regionLength)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if n < regionLength {
+			return written, io.ErrUnexpectedEOF
+		}
+	}
+	return written, nil
+}
+
+func writeSource(w io.Writer, source io.ReadSeeker, off, length int64) (int64, error) {
+	if rw, ok := source.(rangeWriter); ok {
+		return rw.writeRange(w, off, length)
+	}
+	if _, err := source.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	// When the source is an *os.File and the destination implements io.ReaderFrom, as network
+	// connections and http.ResponseWriter do, the copy is done by the kernel using sendfile.
+	return Copy(w, &io.LimitedReader{R: source, N: length})
+}
+
+// WriteRange writes length bytes of r from its current position to w, and advances r past them.
+// The readers of this package are written region by region, so that the untouched regions of
+// the base files are copied by the kernel with sendfile when w supports it and only the modified
+// ranges go through a buffer.
+func WriteRange(w io.Writer, r io.ReadSeeker, length int64) (int64, error) {
+	off, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	n, err := writeSource(w, r, off, length)
+	if _, ok := r.(rangeWriter); ok {
+		// the ranges are written without moving the reader
+		if _, seekErr := r.Seek(off+n, io.SeekStart); err == nil {
+			err = seekErr
+		}
+	}
+	return n, err
+}
+
+func (or *overlayReader) writeRange(w io.Writer, off, length int64) (int64, error) {
+	return writeRegions(w, off, length, or.regionAt)
+}
+
+func (or *overlayReader) WriteTo(w io.Writer) (int64, error) {
+	if or.readIndex >= or.totalLength {
+		return 0, nil
+	}
+	n, err := or.writeRange(w, or.readIndex, or.totalLength-or.readIndex)
+	if seekErr := or.seek(or.readIndex + n); err == nil {
+		err = seekErr
+	}
+	return n, err
+}
+
+func (mr *multiOverlayReader) writeRange(w io.Writer, off, length int64) (int64, error) {
+	return writeRegions(w, off, length, mr.regionAt)
+}
+
+func (mr *multiOverlayReader) WriteTo(w io.Writer) (int64, error) {
+	if mr.readIndex >= mr.totalLength {
+		return 0, nil
+	}
+	n, err := mr.writeRange(w, mr.readIndex, mr.totalLength-mr.readIndex)
+	if seekErr := mr.seek(mr.readIndex + n); err == nil {
+		err = seekErr
+	}
+	return n, err
+}
+
+func (sr *spliceReader) writeRange(w io.Writer, off, length int64) (int64, error) {
+	return writeRegions(w, off, length, sr.regionAt)
+}
+
+func (sr *spliceReader) WriteTo(w io.Writer) (int64, error) {
+	if sr.readIndex >= sr.totalLength {
+		return 0, nil
+	}
+	n, err := sr.writeRange(w, sr.readIndex, sr.totalLength-sr.readIndex)
+	if seekErr := sr.seek(sr.readIndex + n); err == nil {
+		err = seekErr
+	}
+	return n, err
+}
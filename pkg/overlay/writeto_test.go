@@ -0,0 +1,156 @@
+package overlay
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WriteTo", func() {
+	const base = "abcdefghij"
+
+	overlay := func(content string, offset int64) Overlay {
+		return Overlay{Reader: strings.NewReader(content), Offset: offset, Length: int64(len(content))}
+	}
+
+	var (
+		tmpDir   string
+		baseFile *os.File
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "overlay")
+		Expect(err).NotTo(HaveOccurred())
+		baseFile, err = os.CreateTemp(tmpDir, "base")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = baseFile.WriteString(base)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(baseFile.Close()).To(Succeed())
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	readers := func() map[string]OverlayReader {
+		single, err := NewOverlayReader(baseFile, overlay("over", 8))
+		Expect(err).NotTo(HaveOccurred())
+		multi, err := NewMultiOverlayReader(baseFile, overlay("12", 1), overlay("34", 5))
+		Expect(err).NotTo(HaveOccurred())
+		splice, err := NewSpliceReader(baseFile, Splice{Reader: strings.NewReader("XYZ"), Offset: 3, Length: 1})
+		Expect(err).NotTo(HaveOccurred())
+		chained, err := NewOverlayReader(multi, overlay("ab", 3))
+		Expect(err).NotTo(HaveOccurred())
+		return map[string]OverlayReader{
+			"single":  single,
+			"multi":   multi,
+			"splice":  splice,
+			"chained": chained,
+		}
+	}
+
+	It("writes the same content as Read", func() {
+		for name, reader := range readers() {
+			By(name)
+			_, err := reader.Seek(0, io.SeekStart)
+			Expect(err).NotTo(HaveOccurred())
+			expected, err := io.ReadAll(struct{ io.Reader }{reader})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = reader.Seek(0, io.SeekStart)
+			Expect(err).NotTo(HaveOccurred())
+			var output bytes.Buffer
+			n, err := reader.WriteTo(&output)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(int64(len(expected))))
+			Expect(output.Bytes()).To(Equal(expected))
+
+			current, err := reader.Seek(0, io.SeekCurrent)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(current).To(Equal(int64(len(expected))))
+		}
+	})
+
+	It("writes from the current position to a file", func() {
+		reader, err := NewMultiOverlayReader(baseFile, overlay("12", 1), overlay("34", 5))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = reader.Seek(3, io.SeekStart)
+		Expect(err).NotTo(HaveOccurred())
+
+		output, err := os.CreateTemp(tmpDir, "output")
+		Expect(err).NotTo(HaveOccurred())
+		defer output.Close()
+		_, err = io.Copy(output, reader)
+		Expect(err).NotTo(HaveOccurred())
+
+		content, err := os.ReadFile(output.Name())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("de34hij"))
+	})
+
+	It("writes the ranges from the current position", func() {
+		all := readers()
+		all["context"] = WithContext(context.Background(), all["multi"])
+		for name, reader := range all {
+			By(name)
+			_, err := reader.Seek(0, io.SeekStart)
+			Expect(err).NotTo(HaveOccurred())
+			expected, err := io.ReadAll(struct{ io.Reader }{reader})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = reader.Seek(2, io.SeekStart)
+			Expect(err).NotTo(HaveOccurred())
+			var output bytes.Buffer
+			n, err := WriteRange(&output, reader, 5)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(int64(5)))
+			Expect(output.String()).To(Equal(string(expected[2:7])))
+
+			current, err := reader.Seek(0, io.SeekCurrent)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(current).To(Equal(int64(7)))
+		}
+
+		reader := strings.NewReader(base)
+		_, err := reader.Seek(4, io.SeekStart)
+		Expect(err).NotTo(HaveOccurred())
+		var output bytes.Buffer
+		Expect(WriteRange(&output, reader, 3)).To(Equal(int64(3)))
+		Expect(output.String()).To(Equal("efg"))
+		Expect(reader.Seek(0, io.SeekCurrent)).To(Equal(int64(7)))
+	})
+
+	It("hands the regions of the base file to the writer", func() {
+		reader, err := NewMultiOverlayReader(baseFile, overlay("12", 1), overlay("34", 5))
+		Expect(err).NotTo(HaveOccurred())
+		reader = WithContext(context.Background(), reader)
+
+		output := &readerFromWriter{}
+		Expect(WriteRange(output, reader, 10)).To(Equal(int64(10)))
+		Expect(output.String()).To(Equal("a12de34hij"))
+		// the base regions could be copied with sendfile, the overlays are written
+		Expect(output.files).To(Equal(3))
+	})
+})
+
+// readerFromWriter counts the copies of files it is given, as the network connections do with
+// sendfile
+type readerFromWriter struct {
+	bytes.Buffer
+	files int
+}
+
+func (w *readerFromWriter) ReadFrom(r io.Reader) (int64, error) {
+	if lr, ok := r.(*io.LimitedReader); ok {
+		if _, ok := lr.R.(*os.File); ok {
+			w.files++
+		}
+	}
+	return w.Buffer.ReadFrom(r)
+}
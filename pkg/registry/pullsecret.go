@@ -0,0 +1,59 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PullSecret holds the registry credentials of a pull secret, in the format of
+// ~/.docker/config.json and of the OpenShift pull secrets
+type PullSecret struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+}
+
+// LoadPullSecret reads the pull secret at filePath
+func LoadPullSecret(filePath string) (*PullSecret, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pull secret: %w", err)
+	}
+	secret := &PullSecret{}
+	if err := json.Unmarshal(data, secret); err != nil {
+		return nil, fmt.Errorf("failed to parse pull secret %s: %w", filePath, err)
+	}
+	return secret, nil
+}
+
+// credentials returns the user and the password of the registry
+func (s *PullSecret) credentials(registry string) (string, string, bool) {
+	if s == nil {
+		return "", "", false
+	}
+	keys := []string{registry, "https://" + registry, "http://" + registry}
+	if registry == dockerHub {
+		keys = append(keys, "https://index.docker.io/v1/", "index.docker.io")
+	}
+	for _, key := range keys {
+		entry, ok := s.Auths[key]
+		if !ok {
+			continue
+		}
+		if entry.Auth == "" {
+			return entry.Username, entry.Password, entry.Username != ""
+		}
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return "", "", false
+		}
+		user, password, ok := strings.Cut(string(decoded), ":")
+		return user, password, ok
+	}
+	return "", "", false
+}
@@ -0,0 +1,105 @@
+package registry
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Scheme prefixes the URLs of the OS images pulled from a registry, e.g.
+// oci://quay.io/org/rhcos@sha256:... for an artifact whose layer is the ISO, or
+// oci://quay.io/org/machine-os-images:4.14#/coreos/coreos-x86_64.iso for a file of the image
+const Scheme = "oci://"
+
+const (
+	dockerHub    = "docker.io"
+	dockerHubAPI = "registry-1.docker.io"
+)
+
+// Reference is an image of a registry, and optionally a file of its layers
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+	// File is the path of the file in the layers of the image, empty when the image is an
+	// artifact whose layer is the ISO
+	File string
+}
+
+// IsReference returns true if imageURL is a registry reference rather than an HTTP URL
+func IsReference(imageURL string) bool {
+	return strings.HasPrefix(imageURL, Scheme)
+}
+
+// ParseReference parses an oci:// URL
+func ParseReference(imageURL string) (Reference, error) {
+	if !IsReference(imageURL) {
+		return Reference{}, fmt.Errorf("%s is not an %s reference", imageURL, Scheme)
+	}
+	name, file, _ := strings.Cut(strings.TrimPrefix(imageURL, Scheme), "#")
+	ref := Reference{}
+	if file != "" {
+		ref.File = path.Clean("/" + file)
+	}
+
+	if name, digest, ok := strings.Cut(name, "@"); ok {
+		if !strings.HasPrefix(digest, "sha256:") || len(digest) != len("sha256:")+64 {
+			return Reference{}, fmt.Errorf("invalid digest %q in %s", digest, imageURL)
+		}
+		ref.Digest = digest
+		ref.Repository = name
+	} else {
+		ref.Repository = name
+		// the tag follows the last colon, unless it is the port of the registry
+		if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+			ref.Repository, ref.Tag = name[:i], name[i+1:]
+		}
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	registry, repository, ok := strings.Cut(ref.Repository, "/")
+	if ok && (strings.ContainsAny(registry, ".:") || registry == "localhost") {
+		ref.Registry, ref.Repository = registry, repository
+	} else {
+		ref.Registry = dockerHub
+		if !ok {
+			ref.Repository = "library/" + ref.Repository
+		}
+	}
+	if ref.Repository == "" || ref.Repository != strings.ToLower(ref.Repository) {
+		return Reference{}, fmt.Errorf("invalid repository in %s", imageURL)
+	}
+	return ref, nil
+}
+
+// manifestReference returns the digest or the tag of the image
+func (r Reference) manifestReference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// apiHost returns the host serving the registry API
+func (r Reference) apiHost() string {
+	if r.Registry == dockerHub {
+		return dockerHubAPI
+	}
+	return r.Registry
+}
+
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	} else {
+		s += ":" + r.Tag
+	}
+	if r.File != "" {
+		s += "#" + r.File
+	}
+	return s
+}
@@ -0,0 +1,389 @@
+// Package registry pulls OS images from container registries, either OCI artifacts whose layer is
+// the ISO or files of the layers of a container image, such as the ISOs of the release payload.
+package registry
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	titleAnnotation = "org.opencontainers.image.title"
+)
+
+// manifest is an image manifest or an index, which lists manifests
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Manifests []descriptor `json:"manifests"`
+	Layers    []descriptor `json:"layers"`
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+	Platform    *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"platform"`
+}
+
+// Client pulls images from registries
+type Client struct {
+	httpClient *http.Client
+	pullSecret *PullSecret
+
+	// tokens caches the bearer tokens by repository
+	tokensLock sync.Mutex
+	tokens     map[string]string
+}
+
+// NewClient returns a client authenticating with the credentials of pullSecret, which may be nil
+func NewClient(httpClient *http.Client, pullSecret *PullSecret) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{httpClient: httpClient, pullSecret: pullSecret, tokens: map[string]string{}}
+}
+
+// Pull writes the ISO of ref to out. arch selects the image of multi-architecture images.
+func (c *Client) Pull(ctx context.Context, ref Reference, arch string, out io.Writer) error {
+	m, err := c.manifest(ctx, ref, ref.manifestReference(), arch)
+	if err != nil {
+		return err
+	}
+	if ref.File == "" {
+		layer, err := isoLayer(m)
+		if err != nil {
+			return fmt.Errorf("%s: %w", ref, err)
+		}
+		return c.blob(ctx, ref, layer, func(r io.Reader) error {
+			_, err := io.Copy(out, r)
+			return err
+		})
+	}
+
+	// the upper layers override the files of the lower ones
+	for i := len(m.Layers) - 1; i >= 0; i-- {
+		found := false
+		err := c.blob(ctx, ref, m.Layers[i], func(r io.Reader) error {
+			var err error
+			found, err = copyLayerFile(r, m.Layers[i].MediaType, ref.File, out)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if found {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: file not found in the layers of the image", ref)
+}
+
+// manifest returns the manifest of the image, resolving the indexes for arch
+func (c *Client) manifest(ctx context.Context, ref Reference, reference, arch string) (*manifest, error) {
+	resp, err := c.get(ctx, ref, "manifests/"+reference,
+		strings.Join([]string{mediaTypeOCIManifest, mediaTypeOCIIndex, mediaTypeDockerManifest, mediaTypeDockerList}, ", "))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the manifest of %s: %w", ref, err)
+	}
+	if strings.HasPrefix(reference, "sha256:") {
+		if sum := sha256.Sum256(data); "sha256:"+hex.EncodeToString(sum[:]) != reference {
+			return nil, fmt.Errorf("the manifest of %s doesn't match its digest %s", ref, reference)
+		}
+	}
+	m := &manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse the manifest of %s: %w", ref, err)
+	}
+	if len(m.Manifests) == 0 {
+		return m, nil
+	}
+
+	platform := platformArchitecture(arch)
+	for _, d := range m.Manifests {
+		if d.Platform != nil && d.Platform.OS == "linux" && d.Platform.Architecture == platform {
+			return c.manifest(ctx, ref, d.Digest, arch)
+		}
+	}
+	if len(m.Manifests) == 1 && m.Manifests[0].Platform == nil {
+		return c.manifest(ctx, ref, m.Manifests[0].Digest, arch)
+	}
+	return nil, fmt.Errorf("%s has no image for architecture %s", ref, arch)
+}
+
+// blob calls fn with the content of the blob, and checks its digest once fn returns
+func (c *Client) blob(ctx context.Context, ref Reference, d descriptor, fn func(io.Reader) error) error {
+	algorithm, expected, _ := strings.Cut(d.Digest, ":")
+	if algorithm != "sha256" {
+		return fmt.Errorf("unsupported digest %s of a layer of %s", d.Digest, ref)
+	}
+	resp, err := c.get(ctx, ref, "blobs/"+d.Digest, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	digest := sha256.New()
+	counter := &countingReader{r: io.TeeReader(resp.Body, digest)}
+	if err := fn(counter); err != nil {
+		return fmt.Errorf("failed to read layer %s of %s: %w", d.Digest, ref, err)
+	}
+	// read what fn left to check the digest
+	if _, err := io.Copy(io.Discard, counter); err != nil {
+		return fmt.Errorf("failed to read layer %s of %s: %w", d.Digest, ref, err)
+	}
+	if d.Size > 0 && counter.n != d.Size {
+		return fmt.Errorf("layer %s of %s has %d bytes instead of %d", d.Digest, ref, counter.n, d.Size)
+	}
+	if actual := hex.EncodeToString(digest.Sum(nil)); actual != expected {
+		return fmt.Errorf("layer %s of %s has digest sha256:%s", d.Digest, ref, actual)
+	}
+	return nil
+}
+
+// get sends a GET request to the registry API of the repository of ref, authenticating
+// as requested by the registry
+func (c *Client) get(ctx context.Context, ref Reference, endpoint, accept string) (*http.Response, error) {
+	u := url.URL{Scheme: "https", Host: ref.apiHost(), Path: path.Join("/v2", ref.Repository, endpoint)}
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		return req, nil
+	}
+
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	key := ref.Registry + "/" + ref.Repository
+	c.tokensLock.Lock()
+	token := c.tokens[key]
+	c.tokensLock.Unlock()
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", u.String(), err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		req, err = newRequest()
+		if err != nil {
+			return nil, err
+		}
+		if err := c.authenticate(ctx, ref, challenge, req); err != nil {
+			return nil, err
+		}
+		resp, err = c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("request to %s failed: %w", u.String(), err)
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("request to %s returned status %d", u.String(), resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// authenticate answers the challenge of the registry on req
+func (c *Client) authenticate(ctx context.Context, ref Reference, challenge string, req *http.Request) error {
+	user, password, haveCredentials := c.pullSecret.credentials(ref.Registry)
+	scheme, params := parseChallenge(challenge)
+	switch scheme {
+	case "basic":
+		if !haveCredentials {
+			return fmt.Errorf("%s requires credentials, none found in the pull secret", ref.Registry)
+		}
+		req.SetBasicAuth(user, password)
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("unsupported authentication challenge %q of %s", challenge, ref.Registry)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || (realm.Scheme != "https" && realm.Scheme != "http") {
+		return fmt.Errorf("invalid token realm %q of %s", params["realm"], ref.Registry)
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", ref.Repository)
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	tokenReq, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if haveCredentials {
+		tokenReq.SetBasicAuth(user, password)
+	}
+	resp, err := c.httpClient.Do(tokenReq)
+	if err != nil {
+		return fmt.Errorf("failed to get a token for %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("token request for %s returned status %d", ref, resp.StatusCode)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("failed to parse the token for %s: %w", ref, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return fmt.Errorf("the token response for %s has no token", ref)
+	}
+
+	c.tokensLock.Lock()
+	c.tokens[ref.Registry+"/"+ref.Repository] = token.Token
+	c.tokensLock.Unlock()
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	return nil
+}
+
+// parseChallenge parses a WWW-Authenticate header such as
+// Bearer realm="https://auth.example.com/token",service="registry",scope="repository:a:pull"
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var name, value string
+		name, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			params[name] = value
+		}
+	}
+	return strings.ToLower(scheme), params
+}
+
+// isoLayer returns the layer of an artifact holding the ISO
+func isoLayer(m *manifest) (descriptor, error) {
+	for _, layer := range m.Layers {
+		if strings.HasSuffix(layer.Annotations[titleAnnotation], ".iso") {
+			return layer, nil
+		}
+	}
+	if len(m.Layers) == 1 {
+		return m.Layers[0], nil
+	}
+	return descriptor{}, errors.New("no layer of the artifact is an ISO, add the path of the ISO in the image as the URL fragment")
+}
+
+// copyLayerFile copies filePath of the tar layer to out, returning false if the layer doesn't have it
+func copyLayerFile(r io.Reader, mediaType, filePath string, out io.Writer) (bool, error) {
+	buffered := bufio.NewReader(r)
+	magic, _ := buffered.Peek(4)
+	var layer io.Reader = buffered
+	switch {
+	case strings.HasSuffix(mediaType, "gzip") || bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return false, err
+		}
+		defer gz.Close()
+		layer = gz
+	case strings.HasSuffix(mediaType, "zstd") || bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zr, err := zstd.NewReader(buffered)
+		if err != nil {
+			return false, err
+		}
+		defer zr.Close()
+		layer = zr
+	}
+
+	name := strings.TrimPrefix(filePath, "/")
+	tr := tar.NewReader(layer)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if strings.TrimPrefix(path.Clean("/"+header.Name), "/") != name {
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			return false, fmt.Errorf("%s is not a regular file in the image", filePath)
+		}
+		_, err = io.Copy(out, tr)
+		return true, err
+	}
+}
+
+// platformArchitecture returns the OCI architecture of the cpu architecture of the versions
+func platformArchitecture(arch string) string {
+	switch arch {
+	case "x86_64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	}
+	return arch
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,227 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRegistry(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "registry")
+}
+
+var _ = Describe("ParseReference", func() {
+	It("parses the registry, the repository, the tag or digest and the file", func() {
+		digest := "sha256:" + strings.Repeat("a", 64)
+		for imageURL, expected := range map[string]Reference{
+			"oci://quay.io/org/rhcos:4.14": {Registry: "quay.io", Repository: "org/rhcos", Tag: "4.14"},
+			"oci://quay.io/org/rhcos":      {Registry: "quay.io", Repository: "org/rhcos", Tag: "latest"},
+			"oci://localhost:5000/rhcos@" + digest + "#coreos/coreos-x86_64.iso": {
+				Registry: "localhost:5000", Repository: "rhcos", Digest: digest, File: "/coreos/coreos-x86_64.iso"},
+			"oci://fedora/coreos:stable": {Registry: "docker.io", Repository: "fedora/coreos", Tag: "stable"},
+			"oci://coreos":               {Registry: "docker.io", Repository: "library/coreos", Tag: "latest"},
+		} {
+			ref, err := ParseReference(imageURL)
+			Expect(err).NotTo(HaveOccurred(), imageURL)
+			Expect(ref).To(Equal(expected), imageURL)
+		}
+		ref, _ := ParseReference("oci://coreos")
+		Expect(ref.apiHost()).To(Equal("registry-1.docker.io"))
+	})
+
+	It("rejects invalid references", func() {
+		for _, imageURL := range []string{
+			"https://quay.io/org/rhcos",
+			"oci://quay.io/org/rhcos@sha256:abc",
+			"oci://quay.io/Org/rhcos",
+		} {
+			_, err := ParseReference(imageURL)
+			Expect(err).To(HaveOccurred(), imageURL)
+		}
+	})
+})
+
+var _ = Describe("parseChallenge", func() {
+	It("parses bearer challenges", func() {
+		scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a/b:pull,push"`)
+		Expect(scheme).To(Equal("bearer"))
+		Expect(params).To(Equal(map[string]string{
+			"realm":   "https://auth.example.com/token",
+			"service": "registry.example.com",
+			"scope":   "repository:a/b:pull,push",
+		}))
+	})
+})
+
+// fakeRegistry serves the manifests and blobs of one repository, requiring a token
+type fakeRegistry struct {
+	server   *httptest.Server
+	user     string
+	password string
+	blobs    map[string][]byte
+	tags     map[string]string
+}
+
+func newFakeRegistry(user, password string) *fakeRegistry {
+	r := &fakeRegistry{user: user, password: password, blobs: map[string][]byte{}, tags: map[string]string{}}
+	r.server = httptest.NewTLSServer(r)
+	return r
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		user, password, _ := req.BasicAuth()
+		if user != r.user || password != r.password || req.URL.Query().Get("scope") != "repository:org/rhcos:pull" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"token":"secret-token"}`))
+		return
+	}
+	if req.Header.Get("Authorization") != "Bearer secret-token" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+r.server.URL+`/token",service="fake"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	name := strings.TrimPrefix(req.URL.Path, "/v2/org/rhcos/")
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "manifests/"), "blobs/")
+	if digest, ok := r.tags[name]; ok {
+		name = digest
+	}
+	data, ok := r.blobs[name]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_, _ = w.Write(data)
+}
+
+// add stores the blob and returns its descriptor
+func (r *fakeRegistry) add(mediaType string, data []byte) descriptor {
+	sum := sha256.Sum256(data)
+	d := descriptor{MediaType: mediaType, Digest: "sha256:" + hex.EncodeToString(sum[:]), Size: int64(len(data))}
+	r.blobs[d.Digest] = data
+	return d
+}
+
+func (r *fakeRegistry) addManifest(tag string, m manifest) descriptor {
+	data, err := json.Marshal(m)
+	Expect(err).NotTo(HaveOccurred())
+	d := r.add(m.MediaType, data)
+	if tag != "" {
+		r.tags[tag] = d.Digest
+	}
+	return d
+}
+
+func (r *fakeRegistry) reference(tag, file string) Reference {
+	return Reference{Registry: strings.TrimPrefix(r.server.URL, "https://"), Repository: "org/rhcos", Tag: tag, File: file}
+}
+
+func tarLayer(files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		Expect(tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})).To(Succeed())
+		_, err := tw.Write([]byte(content))
+		Expect(err).NotTo(HaveOccurred())
+	}
+	Expect(tw.Close()).To(Succeed())
+	Expect(gz.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+var _ = Describe("Client", func() {
+	var (
+		registry *fakeRegistry
+		client   *Client
+		ctx      = context.Background()
+	)
+
+	BeforeEach(func() {
+		registry = newFakeRegistry("user", "password")
+		auth := base64.StdEncoding.EncodeToString([]byte("user:password"))
+		dir, err := os.MkdirTemp("", "registry")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		secretPath := filepath.Join(dir, "pull-secret.json")
+		host := strings.TrimPrefix(registry.server.URL, "https://")
+		Expect(os.WriteFile(secretPath, []byte(`{"auths":{"`+host+`":{"auth":"`+auth+`"}}}`), 0600)).To(Succeed())
+		pullSecret, err := LoadPullSecret(secretPath)
+		Expect(err).NotTo(HaveOccurred())
+		client = NewClient(registry.server.Client(), pullSecret)
+	})
+
+	AfterEach(func() {
+		registry.server.Close()
+	})
+
+	It("pulls the ISO layer of an artifact for the architecture", func() {
+		config := registry.add("application/vnd.oci.empty.v1+json", []byte("{}"))
+		layer := registry.add("application/octet-stream", []byte("iso content"))
+		layer.Annotations = map[string]string{titleAnnotation: "rhcos-live.x86_64.iso"}
+		amd64 := registry.addManifest("", manifest{MediaType: mediaTypeOCIManifest, Layers: []descriptor{config, layer}})
+		amd64.Platform = &struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		}{Architecture: "amd64", OS: "linux"}
+		registry.addManifest("4.14", manifest{MediaType: mediaTypeOCIIndex, Manifests: []descriptor{amd64}})
+
+		out := &bytes.Buffer{}
+		Expect(client.Pull(ctx, registry.reference("4.14", ""), "x86_64", out)).To(Succeed())
+		Expect(out.String()).To(Equal("iso content"))
+
+		err := client.Pull(ctx, registry.reference("4.14", ""), "s390x", out)
+		Expect(err).To(MatchError(ContainSubstring("has no image for architecture s390x")))
+	})
+
+	It("pulls a file of the upper layer having it", func() {
+		lower := registry.add("application/vnd.oci.image.layer.v1.tar+gzip", tarLayer(map[string]string{
+			"coreos/coreos-x86_64.iso": "old iso", "etc/os-release": "rhel"}))
+		upper := registry.add("application/vnd.oci.image.layer.v1.tar+gzip", tarLayer(map[string]string{
+			"./coreos/coreos-x86_64.iso": "new iso"}))
+		top := registry.add("application/vnd.oci.image.layer.v1.tar+gzip", tarLayer(map[string]string{"usr/bin/true": ""}))
+		registry.addManifest("release", manifest{MediaType: mediaTypeDockerManifest, Layers: []descriptor{lower, upper, top}})
+
+		out := &bytes.Buffer{}
+		Expect(client.Pull(ctx, registry.reference("release", "/coreos/coreos-x86_64.iso"), "x86_64", out)).To(Succeed())
+		Expect(out.String()).To(Equal("new iso"))
+
+		err := client.Pull(ctx, registry.reference("release", "/coreos/coreos-s390x.iso"), "x86_64", out)
+		Expect(err).To(MatchError(ContainSubstring("file not found")))
+	})
+
+	It("fails when a layer doesn't match its digest", func() {
+		layer := registry.add("application/octet-stream", []byte("iso content"))
+		registry.blobs[layer.Digest] = []byte("tampered iso")
+		layer.Size = 0
+		registry.addManifest("4.14", manifest{MediaType: mediaTypeOCIManifest, Layers: []descriptor{layer}})
+
+		err := client.Pull(ctx, registry.reference("4.14", ""), "x86_64", &bytes.Buffer{})
+		Expect(err).To(MatchError(ContainSubstring("has digest sha256:")))
+	})
+
+	It("fails without the credentials of the registry", func() {
+		registry.addManifest("4.14", manifest{MediaType: mediaTypeOCIManifest})
+		client = NewClient(registry.server.Client(), nil)
+
+		err := client.Pull(ctx, registry.reference("4.14", ""), "x86_64", &bytes.Buffer{})
+		Expect(err).To(MatchError(ContainSubstring("token request for")))
+	})
+})
@@ -0,0 +1,96 @@
+package servers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// ClientCertTLSConfig returns the TLS config of the listeners requiring client certificates signed
+// by a CA of the caFile bundle. When allowedSANs isn't empty, the certificates must also have one of
+// these subject alternative names: a DNS name, which may start with a "*." wildcard label, an IP
+// address or CIDR, a URI or an email address.
+func ClientCertTLSConfig(caFile string, allowedSANs []string) (*tls.Config, error) {
+	caBundle, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBundle) {
+		return nil, fmt.Errorf("no certificate found in the client CA bundle %s", caFile)
+	}
+
+	var sans []string
+	for _, san := range allowedSANs {
+		if san = strings.TrimSpace(san); san != "" {
+			sans = append(sans, san)
+		}
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(sans) == 0 {
+				return nil
+			}
+			if len(cs.PeerCertificates) == 0 || !matchesSANs(cs.PeerCertificates[0], sans) {
+				return errors.New("the client certificate has none of the allowed subject alternative names")
+			}
+			return nil
+		},
+	}, nil
+}
+
+// matchesSANs tells whether the certificate has one of the subject alternative names
+func matchesSANs(cert *x509.Certificate, sans []string) bool {
+	for _, san := range sans {
+		if _, network, err := net.ParseCIDR(san); err == nil {
+			for _, ip := range cert.IPAddresses {
+				if network.Contains(ip) {
+					return true
+				}
+			}
+			continue
+		}
+		if ip := net.ParseIP(san); ip != nil {
+			for _, certIP := range cert.IPAddresses {
+				if certIP.Equal(ip) {
+					return true
+				}
+			}
+			continue
+		}
+		for _, name := range cert.DNSNames {
+			if matchesDNSName(san, name) {
+				return true
+			}
+		}
+		for _, uri := range cert.URIs {
+			if uri.String() == san {
+				return true
+			}
+		}
+		for _, email := range cert.EmailAddresses {
+			if strings.EqualFold(email, san) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesDNSName tells whether the DNS name matches the pattern, whose "*." wildcard matches a
+// single label
+func matchesDNSName(pattern, name string) bool {
+	pattern, name = strings.ToLower(strings.TrimSuffix(pattern, ".")), strings.ToLower(strings.TrimSuffix(name, "."))
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		label, rest, found := strings.Cut(name, ".")
+		return found && label != "" && rest == suffix
+	}
+	return pattern == name
+}
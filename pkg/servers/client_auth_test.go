@@ -0,0 +1,130 @@
+package servers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClientCertTLSConfig", func() {
+	var (
+		caCert *x509.Certificate
+		caKey  *rsa.PrivateKey
+		caFile string
+	)
+
+	BeforeEach(func() {
+		var err error
+		caKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		template := &x509.Certificate{
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "clients"},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			KeyUsage:              x509.KeyUsageCertSign,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+		Expect(err).NotTo(HaveOccurred())
+		caCert, err = x509.ParseCertificate(der)
+		Expect(err).NotTo(HaveOccurred())
+		caFile = filepath.Join(tmpDir, "client-ca.crt")
+		Expect(os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)).To(Succeed())
+	})
+
+	clientCert := func(template *x509.Certificate) tls.Certificate {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		template.SerialNumber = big.NewInt(2)
+		template.NotBefore = time.Now().Add(-time.Hour)
+		template.NotAfter = time.Now().Add(time.Hour)
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		Expect(err).NotTo(HaveOccurred())
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+
+	get := func(allowedSANs []string, certs ...tls.Certificate) error {
+		config, err := ClientCertTLSConfig(caFile, allowedSANs)
+		Expect(err).NotTo(HaveOccurred())
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.TLS = config
+		server.StartTLS()
+		defer server.Close()
+
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: certs}, //nolint:gosec
+		}}
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	It("requires a client certificate signed by the CA", func() {
+		Expect(get(nil)).NotTo(Succeed())
+		Expect(get(nil, clientCert(&x509.Certificate{Subject: pkix.Name{CommonName: "bmc"}}))).To(Succeed())
+
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		template := &x509.Certificate{SerialNumber: big.NewInt(3), NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &other.PublicKey, other)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(get(nil, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: other})).NotTo(Succeed())
+	})
+
+	It("requires one of the allowed SANs", func() {
+		cert := clientCert(&x509.Certificate{DNSNames: []string{"bmc-1.example.com"}})
+		Expect(get([]string{"*.example.com"}, cert)).To(Succeed())
+		Expect(get([]string{"bmc-2.example.com", "10.0.0.0/8"}, cert)).NotTo(Succeed())
+	})
+
+	It("fails without a certificate in the CA bundle", func() {
+		Expect(os.WriteFile(caFile, []byte("nothing"), 0600)).To(Succeed())
+		_, err := ClientCertTLSConfig(caFile, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("matchesSANs",
+		func(san string, matches bool) {
+			u, err := url.Parse("spiffe://example.com/bmc")
+			Expect(err).NotTo(HaveOccurred())
+			cert := &x509.Certificate{
+				DNSNames:       []string{"bmc-1.lab.example.com"},
+				IPAddresses:    []net.IP{net.ParseIP("192.168.1.10")},
+				URIs:           []*url.URL{u},
+				EmailAddresses: []string{"bmc@example.com"},
+			}
+			Expect(matchesSANs(cert, []string{san})).To(Equal(matches))
+		},
+		Entry("DNS name", "bmc-1.lab.example.com", true),
+		Entry("DNS name in another case", "BMC-1.lab.example.com", true),
+		Entry("wildcard DNS name", "*.lab.example.com", true),
+		Entry("wildcard matching a single label", "*.example.com", false),
+		Entry("other DNS name", "bmc-2.lab.example.com", false),
+		Entry("IP address", "192.168.1.10", true),
+		Entry("other IP address", "192.168.1.11", false),
+		Entry("CIDR", "192.168.1.0/24", true),
+		Entry("other CIDR", "10.0.0.0/8", false),
+		Entry("URI", "spiffe://example.com/bmc", true),
+		Entry("email address", "bmc@example.com", true),
+	)
+})
@@ -0,0 +1,117 @@
+package servers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UnixListener", func() {
+	var socketPath string
+
+	BeforeEach(func() {
+		socketPath = filepath.Join(tmpDir, "image-service.sock")
+	})
+
+	unixClient := func() *http.Client {
+		return &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+			},
+		}}
+	}
+
+	It("serves http on the socket, as a local listener", func() {
+		listener, err := UnixListener(socketPath, 0o660)
+		Expect(err).NotTo(HaveOccurred())
+		info, err := os.Stat(socketPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o660)))
+
+		listeners := NewServer("", "8449", "", "")
+		listeners.AddListeners(listener)
+		Expect(listeners.Local).NotTo(BeNil())
+		localHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, IsLocal(r.Context()))
+		})
+		listeners.Local.Handler = localHandler
+		listeners.HTTP.Handler = localHandler
+		listeners.ListenAndServe()
+
+		resp, err := unixClient().Get("http://localhost/")
+		Expect(err).NotTo(HaveOccurred())
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal("true"))
+
+		Expect(awaitConnection(8449)).To(BeTrue())
+		resp, err = httpClient.Get("http://localhost:8449/")
+		Expect(err).NotTo(HaveOccurred())
+		body, err = io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal("false"))
+
+		Expect(listeners.Shutdown()).To(BeTrue())
+	})
+
+	It("doesn't trust the TCP listeners", func() {
+		listener, err := net.Listen("tcp", "localhost:0")
+		Expect(err).NotTo(HaveOccurred())
+
+		listeners := NewServer("", "", "", "")
+		listeners.HTTP = nil
+		listeners.HTTPS = &http.Server{}
+		listeners.AddListeners(listener)
+		Expect(listeners.HasBothHandlers).To(BeTrue())
+		listeners.HTTPS = nil
+		listeners.Local.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, IsLocal(r.Context()))
+		})
+		listeners.ListenAndServe()
+
+		resp, err := httpClient.Get("http://" + listener.Addr().String() + "/")
+		Expect(err).NotTo(HaveOccurred())
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal("false"))
+
+		Expect(listeners.Shutdown()).To(BeTrue())
+	})
+
+	It("replaces a stale socket", func() {
+		Expect(os.WriteFile(socketPath, nil, 0o600)).To(Succeed())
+		listener, err := UnixListener(socketPath, 0o600)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(listener.Close()).To(Succeed())
+	})
+
+	It("doesn't add a server without listeners", func() {
+		listeners := NewServer("", "8450", "", "")
+		listeners.AddListeners()
+		Expect(listeners.Local).To(BeNil())
+	})
+})
+
+var _ = Describe("ActivatedListeners", func() {
+	It("returns none when the process wasn't socket activated", func() {
+		listeners, err := ActivatedListeners()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(listeners).To(BeEmpty())
+	})
+
+	It("ignores the sockets passed to another process", func() {
+		os.Setenv("LISTEN_PID", fmt.Sprint(os.Getppid()))
+		os.Setenv("LISTEN_FDS", "1")
+		listeners, err := ActivatedListeners()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(listeners).To(BeEmpty())
+		Expect(os.Getenv("LISTEN_FDS")).To(BeEmpty())
+	})
+})
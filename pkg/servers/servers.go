@@ -3,6 +3,7 @@ package servers
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
@@ -16,6 +17,10 @@ type ServerInfo struct {
 	HTTPSCertFile   string
 	HasBothHandlers bool
 	FastShutdown    bool
+	// Local serves plain HTTP on the Listeners, e.g. a Unix socket or the sockets passed by
+	// systemd, when there are any
+	Local     *http.Server
+	Listeners []net.Listener
 }
 
 func New(httpPort, httpsPort, HTTPSKeyFile, HTTPSCertFile string) *ServerInfo {
@@ -59,6 +64,38 @@ func shutdown(name string, server *http.Server) {
 	}
 }
 
+type localConnKey struct{}
+
+// IsLocal tells whether the request of the context was received on one of the Unix socket
+// Listeners, whose clients, such as a reverse proxy terminating TLS, are trusted like the ones of
+// the HTTPS server. The TCP sockets, even when passed by systemd, aren't trusted.
+func IsLocal(ctx context.Context) bool {
+	local, _ := ctx.Value(localConnKey{}).(bool)
+	return local
+}
+
+// AddListeners also serves plain HTTP on the listeners. The TCP ones are served like the HTTP
+// server, so the requests are also filtered when they are added next to the HTTPS server.
+func (s *ServerInfo) AddListeners(listeners ...net.Listener) {
+	if len(listeners) == 0 {
+		return
+	}
+	for _, listener := range listeners {
+		if s.HTTPS != nil && listener.Addr().Network() != "unix" {
+			s.HasBothHandlers = true
+		}
+	}
+	if s.Local == nil {
+		s.Local = &http.Server{
+			ReadHeaderTimeout: 3 * time.Second,
+			ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+				return context.WithValue(ctx, localConnKey{}, conn.LocalAddr().Network() == "unix")
+			},
+		}
+	}
+	s.Listeners = append(s.Listeners, listeners...)
+}
+
 func (s *ServerInfo) ListenAndServe() {
 	if s.HTTP != nil {
 		go s.httpListen()
@@ -67,6 +104,10 @@ func (s *ServerInfo) ListenAndServe() {
 	if s.HTTPS != nil {
 		go s.httpsListen()
 	}
+
+	for _, listener := range s.Listeners {
+		go s.localServe(listener)
+	}
 }
 
 func (s *ServerInfo) Shutdown() bool {
@@ -84,6 +125,13 @@ func (s *ServerInfo) Shutdown() bool {
 			shutdown("HTTP", s.HTTP)
 		}
 	}
+	if s.Local != nil {
+		if s.FastShutdown {
+			s.Local.Close()
+		} else {
+			shutdown("Local", s.Local)
+		}
+	}
 	return true
 }
 
@@ -100,3 +148,10 @@ func (s *ServerInfo) httpsListen() {
 		log.Fatalf("HTTPS listener closed: %v", err)
 	}
 }
+
+func (s *ServerInfo) localServe(listener net.Listener) {
+	log.Infof("Starting http handler on %s %s...", listener.Addr().Network(), listener.Addr())
+	if err := s.Local.Serve(listener); err != http.ErrServerClosed {
+		log.Fatalf("Listener %s closed: %v", listener.Addr(), err)
+	}
+}
@@ -0,0 +1,70 @@
+package uki
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+	"github.com/pkg/errors"
+)
+
+// efiArchitectures are the names ukify gives to the UEFI architectures of the supported CPU
+// architectures
+var efiArchitectures = map[string]string{
+	"x86_64": "x64",
+	"arm64":  "aa64",
+}
+
+//go:generate mockgen -package=uki -destination=mock_builder.go . Builder
+type Builder interface {
+	Build(kernelPath, initrdPath string, kargs []string, arch, outputPath string) error
+}
+
+type builder struct {
+	workDir  string
+	stubPath string
+	executer isoeditor.Executer
+}
+
+// NewBuilder returns a builder of Unified Kernel Images, single PE binaries with the kernel, the
+// initrd and the kernel arguments booted by UEFI firmware. They are assembled by ukify, with the
+// systemd stub at stubPath, or the default stub of ukify when it's empty. The images aren't
+// signed, they can be signed for secure boot with e.g. sbsign.
+func NewBuilder(workDir, stubPath string, executer isoeditor.Executer) Builder {
+	return &builder{workDir: workDir, stubPath: stubPath, executer: executer}
+}
+
+// IsSupported tells whether UKIs can be built for the CPU architecture
+func IsSupported(arch string) bool {
+	_, ok := efiArchitectures[isoeditor.NormalizeArchitecture(arch)]
+	return ok
+}
+
+func (b *builder) Build(kernelPath, initrdPath string, kargs []string, arch, outputPath string) error {
+	efiArch, ok := efiArchitectures[isoeditor.NormalizeArchitecture(arch)]
+	if !ok {
+		return fmt.Errorf("unified kernel images are not supported for %s", arch)
+	}
+
+	tmpDir, err := os.MkdirTemp(b.workDir, "uki")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	cmdlinePath := filepath.Join(tmpDir, "cmdline")
+	if err = os.WriteFile(cmdlinePath, []byte(strings.Join(kargs, " ")), 0600); err != nil {
+		return err
+	}
+
+	command := fmt.Sprintf("ukify build --linux='%s' --initrd='%s' --cmdline=@'%s' --efi-arch=%s --output='%s'",
+		kernelPath, initrdPath, cmdlinePath, efiArch, outputPath)
+	if b.stubPath != "" {
+		command += fmt.Sprintf(" --stub='%s'", b.stubPath)
+	}
+	if _, err = b.executer.Execute(command, tmpDir); err != nil {
+		return errors.Wrap(err, "failed to build the unified kernel image")
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package uki
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestUKI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "uki")
+}
@@ -0,0 +1,52 @@
+package uki
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/assisted-image-service/pkg/isoeditor"
+)
+
+var _ = Describe("Build", func() {
+	var (
+		ctrl     *gomock.Controller
+		executer *isoeditor.MockExecuter
+		workDir  string
+	)
+
+	BeforeEach(func() {
+		var err error
+		workDir, err = os.MkdirTemp("", "uki")
+		Expect(err).NotTo(HaveOccurred())
+		ctrl = gomock.NewController(GinkgoT())
+		executer = isoeditor.NewMockExecuter(ctrl)
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+		Expect(os.RemoveAll(workDir)).To(Succeed())
+	})
+
+	It("runs ukify with the kernel arguments", func() {
+		executer.EXPECT().Execute(gomock.Any(), gomock.Any()).DoAndReturn(func(command, dir string) (string, error) {
+			cmdlinePath := filepath.Join(dir, "cmdline")
+			Expect(command).To(Equal("ukify build --linux='/tmp/vmlinuz' --initrd='/tmp/initrd.img' --cmdline=@'" + cmdlinePath +
+				"' --efi-arch=aa64 --output='/tmp/uki.efi' --stub='/usr/lib/systemd/boot/efi/linuxaa64.efi.stub'"))
+			Expect(os.ReadFile(cmdlinePath)).To(Equal([]byte("rw ignition.firstboot")))
+			return "", nil
+		})
+		builder := NewBuilder(workDir, "/usr/lib/systemd/boot/efi/linuxaa64.efi.stub", executer)
+		Expect(builder.Build("/tmp/vmlinuz", "/tmp/initrd.img", []string{"rw", "ignition.firstboot"}, "aarch64", "/tmp/uki.efi")).To(Succeed())
+		Expect(os.ReadDir(workDir)).To(BeEmpty())
+	})
+
+	It("fails for the architectures without UEFI", func() {
+		Expect(IsSupported("s390x")).To(BeFalse())
+		Expect(IsSupported("x86_64")).To(BeTrue())
+		builder := NewBuilder(workDir, "", executer)
+		Expect(builder.Build("/tmp/vmlinuz", "/tmp/initrd.img", nil, "s390x", "/tmp/uki.efi")).To(MatchError(ContainSubstring("not supported")))
+	})
+})